@@ -27,9 +27,20 @@ const (
 
 // AlertSpec defines an alerting rule for events involving a list of objects
 type AlertSpec struct {
-	// Send events using this provider.
-	// +required
-	ProviderRef meta.LocalObjectReference `json:"providerRef"`
+	// Inherit the provider, exclusion list, summary, metadata and message
+	// template defaults of this cluster scoped alert template.
+	// +optional
+	AlertTemplateRef *meta.LocalObjectReference `json:"alertTemplateRef,omitempty"`
+
+	// Send events using this provider, it can be omitted when
+	// the alert template sets one.
+	// +optional
+	ProviderRef meta.LocalObjectReference `json:"providerRef,omitempty"`
+
+	// Send events using each of these providers whose condition matches the event,
+	// in addition to the providerRef provider, if any.
+	// +optional
+	ProviderRefs []ConditionalProviderReference `json:"providerRefs,omitempty"`
 
 	// Filter events based on severity, defaults to ('info').
 	// If set to 'info' no events will be filtered.
@@ -46,16 +57,151 @@ type AlertSpec struct {
 	// +optional
 	ExclusionList []string `json:"exclusionList,omitempty"`
 
+	// Reference to a list of Golang regular expressions in a ConfigMap, used
+	// for excluding messages in addition to the exclusion list. The changes
+	// of the ConfigMap are applied to the next events.
+	// +optional
+	ExclusionListRef *ExclusionListReference `json:"exclusionListRef,omitempty"`
+
 	// Short description of the impact and affected cluster.
 	// +optional
 	Summary string `json:"summary,omitempty"`
 
+	// Metadata added to the notifications, e.g. the cluster name.
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Copy these labels and annotations of the involved object to the
+	// notification metadata, e.g. the team owning the object.
+	// +optional
+	InvolvedObjectMetadata *InvolvedObjectMetadata `json:"involvedObjectMetadata,omitempty"`
+
+	// Reference to a Go template in a ConfigMap rendering the notification
+	// message, it takes precedence over the template of the provider.
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+
+	// Send the periodic heartbeat events of the controller to this alert provider.
+	// The heartbeat interval is set with the controller '--heartbeat-interval' flag.
+	// +optional
+	Heartbeat bool `json:"heartbeat,omitempty"`
+
+	// Send the events reporting the sustained problems of the controller, e.g. the
+	// notifications discarded by a full dispatch queue or failing to be delivered
+	// to a provider, to the providers of this alert.
+	// +optional
+	HealthEvents bool `json:"healthEvents,omitempty"`
+
+	// Select the maintenance windows in the same namespace
+	// during which the events are not dispatched.
+	// +optional
+	MaintenanceWindowSelector *metav1.LabelSelector `json:"maintenanceWindowSelector,omitempty"`
+
+	// Send a sample of the matching info events, the error events are always sent.
+	// +optional
+	Sampling *EventSampling `json:"sampling,omitempty"`
+
+	// Replace the notifications of the objects flapping between success and
+	// failure with a single flapping notification.
+	// +optional
+	FlapSuppression *FlapSuppression `json:"flapSuppression,omitempty"`
+
+	// Suppress the notifications of the events sharing a fingerprint within an
+	// interval, e.g. to ignore the message of the retries failing with different errors.
+	// +optional
+	Deduplication *EventDeduplication `json:"deduplication,omitempty"`
+
+	// Write the notifications of the matched events to the controller log
+	// instead of sending them to the providers, to validate the alert
+	// before pointing it at a paging system.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
 	// This flag tells the controller to suspend subsequent events dispatching.
 	// Defaults to false.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
 }
 
+// ConditionalProviderReference is a provider used for the events matching a condition.
+type ConditionalProviderReference struct {
+	// Name of the provider, in the namespace of the alert.
+	// +required
+	Name string `json:"name"`
+
+	// CEL expression evaluated with the 'event' variable, e.g. "event.severity == 'error'",
+	// the provider is used when it returns true. The provider is used for all the events
+	// when the condition is empty.
+	// +optional
+	Condition string `json:"condition,omitempty"`
+}
+
+// InvolvedObjectMetadata selects the labels and annotations of the involved object
+// added to the notification metadata, the metadata of the event takes precedence.
+type InvolvedObjectMetadata struct {
+	// The keys of the labels, the values are added under the same keys.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// The keys of the annotations, the values are added under the same keys.
+	// +optional
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// EventSampling defines the share of the info events sent by an alert.
+type EventSampling struct {
+	// Send one of every given number of matching info events, the notifications
+	// report how many events were skipped since the previous one.
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	Every int `json:"every"`
+}
+
+// FlapSuppression defines when an object is considered flapping.
+type FlapSuppression struct {
+	// The stabilization window in which the severity changes
+	// of an object are counted, e.g. '10m'.
+	// +required
+	Window metav1.Duration `json:"window"`
+
+	// The number of severity changes within the window
+	// after which the object is flapping. Defaults to 3.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:default:=3
+	// +optional
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// EventDeduplication defines the fingerprint identifying the duplicate events of an alert.
+type EventDeduplication struct {
+	// The interval during which the events with the same fingerprint
+	// as a notified event are discarded, e.g. '10m'.
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// The event fields the fingerprint is computed from, defaults to the
+	// involved object, the message and the revision, like the controller rate limit.
+	// +optional
+	Fields []DeduplicationField `json:"fields,omitempty"`
+}
+
+// DeduplicationField is an event field of the deduplication fingerprint.
+// +kubebuilder:validation:Enum=involvedObject;severity;reason;message;revision
+type DeduplicationField string
+
+const (
+	// InvolvedObjectField is the kind, name and namespace of the involved object.
+	InvolvedObjectField DeduplicationField = "involvedObject"
+	// SeverityField is the severity of the event.
+	SeverityField DeduplicationField = "severity"
+	// ReasonField is the reason of the event.
+	ReasonField DeduplicationField = "reason"
+	// MessageField is the message of the event.
+	MessageField DeduplicationField = "message"
+	// RevisionField is the revision in the event metadata.
+	RevisionField DeduplicationField = "revision"
+)
+
 // AlertStatus defines the observed state of Alert
 type AlertStatus struct {
 	// +optional
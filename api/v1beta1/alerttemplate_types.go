@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/fluxcd/pkg/apis/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	AlertTemplateKind string = "AlertTemplate"
+)
+
+// AlertTemplateSpec defines the defaults inherited by the alerts
+// referencing the template.
+type AlertTemplateSpec struct {
+	// The provider used by the alerts which don't set one,
+	// the provider is looked up in the namespace of the alert.
+	// +optional
+	ProviderRef *meta.LocalObjectReference `json:"providerRef,omitempty"`
+
+	// A list of Golang regular expressions to be used for excluding messages,
+	// the alerts exclusion lists are appended to it.
+	// +optional
+	ExclusionList []string `json:"exclusionList,omitempty"`
+
+	// Short description of the impact and affected cluster,
+	// used by the alerts which don't set one.
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// Metadata added to the notifications, the alerts metadata
+	// takes precedence for the same keys.
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Reference to a Go template in a ConfigMap rendering the notification
+	// message, used by the alerts which don't set one. The ConfigMap namespace
+	// defaults to the namespace of the alert.
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// AlertTemplate is the Schema for the alerttemplates API
+type AlertTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AlertTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AlertTemplateList contains a list of AlertTemplate
+type AlertTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertTemplate{}, &AlertTemplateList{})
+}
@@ -22,4 +22,31 @@ const (
 
 	// TokenNotFound represents the fact that receiver token can't be found.
 	TokenNotFoundReason string = "TokenNotFound"
+
+	// UnsupportedTypeReason represents the fact that a receiver type is not registered.
+	UnsupportedTypeReason string = "UnsupportedType"
+
+	// InvalidScheduleReason represents the fact that a maintenance window schedule can't be parsed.
+	InvalidScheduleReason string = "InvalidSchedule"
+
+	// InvalidAnnotationReason represents the fact that a receiver annotation is invalid.
+	InvalidAnnotationReason string = "InvalidAnnotation"
+
+	// InvalidFilterReason represents the fact that a receiver filter is invalid.
+	InvalidFilterReason string = "InvalidFilter"
+
+	// InvalidResourcesReason represents the fact that a receiver resource reference is invalid.
+	InvalidResourcesReason string = "InvalidResources"
+
+	// InvalidEventReason represents the fact that a receiver event mapping is invalid.
+	InvalidEventReason string = "InvalidEvent"
+
+	// InvalidClientCAReason represents the fact that the CA of a receiver certSecretRef can't be loaded.
+	InvalidClientCAReason string = "InvalidClientCA"
+
+	// InvalidTemplateReason represents the fact that a message template can't be loaded.
+	InvalidTemplateReason string = "InvalidTemplate"
+
+	// EgressDeniedReason represents the fact that a provider address is not in the egress allowlist.
+	EgressDeniedReason string = "EgressDenied"
 )
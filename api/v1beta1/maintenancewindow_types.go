@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+)
+
+const (
+	MaintenanceWindowKind string = "MaintenanceWindow"
+)
+
+// MaintenanceWindowSpec defines the recurring periods during which
+// the notifications are suppressed and the receiver triggers are held.
+type MaintenanceWindowSpec struct {
+	// A list of recurring windows, the maintenance window is active
+	// when at least one of them is active.
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Windows []RecurringWindow `json:"windows"`
+
+	// The action taken for the receiver triggers during the window,
+	// 'reject' responds with an error while 'defer' queues the trigger
+	// until the window closes.
+	// Defaults to 'reject'.
+	// +kubebuilder:validation:Enum=reject;defer
+	// +kubebuilder:default:=reject
+	// +optional
+	ReceiverAction string `json:"receiverAction,omitempty"`
+
+	// This flag tells the controller to ignore this maintenance window.
+	// Defaults to false.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// RecurringWindow defines a period that starts according to a cron schedule.
+type RecurringWindow struct {
+	// The cron schedule of the window start, e.g. '0 22 * * FRI'.
+	// +required
+	Schedule string `json:"schedule"`
+
+	// The duration of the window, e.g. '2h'.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+
+	// The IANA time zone name of the schedule, e.g. 'Europe/London'.
+	// Defaults to 'UTC'.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+const (
+	RejectReceiverAction string = "reject"
+	DeferReceiverAction  string = "defer"
+)
+
+// MaintenanceWindowStatus defines the observed state of MaintenanceWindow
+type MaintenanceWindowStatus struct {
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Active is true when the maintenance window is in effect.
+	// +optional
+	Active bool `json:"active,omitempty"`
+
+	// NextTransitionTime is the time at which the window
+	// will be activated or deactivated.
+	// +optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+
+	// ObservedGeneration is the last observed generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+func MaintenanceWindowReady(window MaintenanceWindow, reason, message string, active bool, next *metav1.Time) MaintenanceWindow {
+	meta.SetResourceCondition(&window, meta.ReadyCondition, metav1.ConditionTrue, reason, message)
+	window.Status.Active = active
+	window.Status.NextTransitionTime = next
+	return window
+}
+
+func MaintenanceWindowNotReady(window MaintenanceWindow, reason, message string) MaintenanceWindow {
+	meta.SetResourceCondition(&window, meta.ReadyCondition, metav1.ConditionFalse, reason, message)
+	window.Status.Active = false
+	window.Status.NextTransitionTime = nil
+	return window
+}
+
+// GetStatusConditions returns a pointer to the Status.Conditions slice
+func (in *MaintenanceWindow) GetStatusConditions() *[]metav1.Condition {
+	return &in.Status.Conditions
+}
+
+// GetReceiverAction returns the receiver action, defaults to reject.
+func (in *MaintenanceWindow) GetReceiverAction() string {
+	if in.Spec.ReceiverAction == "" {
+		return RejectReceiverAction
+	}
+	return in.Spec.ReceiverAction
+}
+
+// +genclient
+// +genclient:Namespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Active",type="boolean",JSONPath=".status.active",description=""
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// MaintenanceWindow is the Schema for the maintenancewindows API
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MaintenanceWindowSpec   `json:"spec,omitempty"`
+	Status MaintenanceWindowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
@@ -28,30 +28,72 @@ const (
 // ProviderSpec defines the desired state of Provider
 type ProviderSpec struct {
 	// Type of provider
-	// +kubebuilder:validation:Enum=slack;discord;msteams;rocket;generic;github;gitlab;bitbucket;azuredevops;googlechat;webex;sentry
+	// +kubebuilder:validation:Enum=slack;discord;msteams;rocket;generic;generic-hmac;github;gitlab;githubcomment;gitlabcomment;bitbucket;azuredevops;googlechat;webex;sentry;kubernetes;log;redis;cloudwatch;ibm;oci;teamcity;bamboo;keptn;statuspage;instatus;cachet;grafanaoncall;squadcast;xmatters;everbridge;rootly;blameless;opsgenie;icinga;nagios;zabbix;archive;bigquery;clickhouse;postgres;mysql;fanout
 	// +required
 	Type string `json:"type"`
 
-	// Alert channel for this provider
+	// Alert channel for this provider. The slack, discord and rocket channels can
+	// contain Go templates rendered with the event, e.g. '#deploys-{{ .Metadata.team }}'.
 	// +optional
 	Channel string `json:"channel,omitempty"`
 
+	// The channels a templated channel can resolve to, as names or glob
+	// patterns, e.g. '#deploys-*'. Required when the channel is a template.
+	// +optional
+	AllowedChannels []string `json:"allowedChannels,omitempty"`
+
+	// Go templates rendering the contexts of the commit statuses published for
+	// each event, e.g. 'flux/{{ .InvolvedObject.Kind | lower }}/{{ .InvolvedObject.Name }}'
+	// and 'flux/health'. Defaults to the kind and name of the involved object.
+	// Only supported by the github, gitlab, bitbucket and azuredevops providers.
+	// +optional
+	StatusContexts []string `json:"statusContexts,omitempty"`
+
+	// Rules mapping the events to the states of the commit statuses, the first
+	// matching rule applies. The events matching no rule are published with the
+	// default state, 'success' for the info events and 'failure' for the error
+	// events, and the 'Progressing' events are skipped.
+	// Only supported by the github, gitlab, bitbucket and azuredevops providers.
+	// +optional
+	StatusStates []ProviderStatusState `json:"statusStates,omitempty"`
+
 	// Bot username for this provider
 	// +optional
 	Username string `json:"username,omitempty"`
 
-	// HTTP/S webhook address of this provider
-	// +kubebuilder:validation:Pattern="^(http|https)://"
+	// HTTP/S webhook address of this provider, the redis[s]:// address of the
+	// Redis stream provider or the zabbix:// address of the Zabbix provider.
+	// The generic webhook address can contain Go templates rendered with
+	// the event, e.g. '{{ .InvolvedObject.Name }}'.
+	// +kubebuilder:validation:Pattern="^(http|https|redis|rediss|zabbix)://"
 	// +kubebuilder:validation:Optional
 	// +optional
 	Address string `json:"address,omitempty"`
 
+	// HTTP method of the generic webhook requests, defaults to POST.
+	// +kubebuilder:validation:Enum=POST;PUT;PATCH
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Content type of the generic webhook requests, defaults to 'application/json'.
+	// The events are wrapped in CloudEvents for 'application/cloudevents+json',
+	// and the text content types, e.g. 'text/plain', send the event message only.
+	// +optional
+	ContentType string `json:"contentType,omitempty"`
+
 	// HTTP/S address of the proxy
 	// +kubebuilder:validation:Pattern="^(http|https)://"
 	// +kubebuilder:validation:Optional
 	// +optional
 	Proxy string `json:"proxy,omitempty"`
 
+	// The hostnames and CIDRs the provider is permitted to contact, on top of
+	// the egress policy of the controller. A hostname starting with '*.' matches
+	// any of its subdomains. All the addresses permitted by the controller are
+	// permitted when empty.
+	// +optional
+	EgressAllowlist []string `json:"egressAllowlist,omitempty"`
+
 	// Secret reference containing the provider webhook URL
 	// using "address" as data key
 	// +optional
@@ -61,21 +103,233 @@ type ProviderSpec struct {
 	// a PEM-encoded CA certificate (`caFile`)
 	// +optional
 	CertSecretRef *meta.LocalObjectReference `json:"certSecretRef,omitempty"`
+
+	// Reference to a Go template in a ConfigMap rendering the message
+	// of the notifications sent to this provider.
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+
+	// Locale of the messages written by the controller, e.g. the heartbeat and
+	// flapping notifications, and of the message templates, defaults to 'en'.
+	// The template keys suffixed with the locale, e.g. 'message.de', are used
+	// over the key of the template reference.
+	// +kubebuilder:validation:Enum=en;de;es;fr
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// Capture the last requests sent to this provider and their responses,
+	// with the secrets redacted, to troubleshoot the delivery failures.
+	// The captures are served on the metrics endpoint under '/debug/providers/',
+	// guarded by the bearer token of the '--profiling-token-file' flag.
+	// +optional
+	Debug bool `json:"debug,omitempty"`
+
+	// Maintain a periodically updated summary message of the failing objects
+	// and the recent deployments, instead of posting a message per event.
+	// Only supported by the slack provider with a bot token.
+	// +optional
+	StatusBoard *StatusBoard `json:"statusBoard,omitempty"`
+
+	// The providers the notifications are delivered to, required by the fanout provider.
+	// The providers must be in the same namespace and can't be fanout providers.
+	// +optional
+	ProviderRefs []meta.LocalObjectReference `json:"providerRefs,omitempty"`
+
+	// Timeout, retries and idempotency key of the requests sent to this provider.
+	// Only supported by the webhook based providers.
+	// +optional
+	Delivery *ProviderDelivery `json:"delivery,omitempty"`
+
+	// The batches of events written to the object storage.
+	// Only supported by the archive provider.
+	// +optional
+	Archive *ProviderArchive `json:"archive,omitempty"`
+
+	// The algorithm, header and format of the HMAC signatures computed with the
+	// 'token' key of the secret. Only supported by the generic-hmac provider.
+	// +optional
+	HMAC *ProviderHMAC `json:"hmac,omitempty"`
+}
+
+// ProviderStatusState maps the matching events to a commit status state.
+type ProviderStatusState struct {
+	// The reasons of the events, e.g. 'Progressing' or 'HealthCheckFailed'.
+	// Matches all the reasons when empty.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+
+	// The severity of the events, matches all the severities when empty.
+	// +kubebuilder:validation:Enum=info;error
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// The state of the commit status, converted to the equivalent state of the
+	// git provider, or 'skip' to not publish a commit status for the events.
+	// +kubebuilder:validation:Enum=success;failure;pending;error;skip
+	// +required
+	State string `json:"state"`
+}
+
+const (
+	SuccessCommitState string = "success"
+	FailureCommitState string = "failure"
+	PendingCommitState string = "pending"
+	ErrorCommitState   string = "error"
+	SkipCommitState    string = "skip"
+)
+
+// ProviderHMAC configures the signatures of the generic-hmac provider.
+type ProviderHMAC struct {
+	// The hash algorithm of the signatures, defaults to 'sha256'.
+	// The standard-webhooks format requires 'sha256'.
+	// +kubebuilder:validation:Enum=sha1;sha256;sha512
+	// +optional
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// The header of the signatures, defaults to 'X-Signature' for the
+	// hex format and to 'webhook-signature' for the standard-webhooks format.
+	// +optional
+	Header string `json:"header,omitempty"`
+
+	// The format of the signatures, 'hex' for '<algorithm>=<hex digest>' of the payload,
+	// or 'standard-webhooks' for the Standard Webhooks signatures binding the payload
+	// to the 'webhook-id' and 'webhook-timestamp' headers. Defaults to 'hex'.
+	// +kubebuilder:validation:Enum=hex;standard-webhooks
+	// +optional
+	Format string `json:"format,omitempty"`
+}
+
+const (
+	SHA1HMACAlgorithm   string = "sha1"
+	SHA256HMACAlgorithm string = "sha256"
+	SHA512HMACAlgorithm string = "sha512"
+
+	HexHMACFormat              string = "hex"
+	StandardWebhooksHMACFormat string = "standard-webhooks"
+)
+
+// ProviderDelivery tunes the delivery of the notifications to a provider.
+type ProviderDelivery struct {
+	// Timeout of each request attempt, defaults to 15s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Number of retries of the requests failing with a connection error
+	// or a server error, defaults to 4.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	Retries *int `json:"retries,omitempty"`
+
+	// Minimum wait before a retry, the wait doubles after every attempt, defaults to 2s.
+	// +optional
+	RetryWaitMin *metav1.Duration `json:"retryWaitMin,omitempty"`
+
+	// Maximum wait before a retry, defaults to 30s.
+	// +optional
+	RetryWaitMax *metav1.Duration `json:"retryWaitMax,omitempty"`
+
+	// Header set to a key derived from the event identity, e.g. 'Idempotency-Key',
+	// so that the endpoint can discard the retried deliveries of an event.
+	// +optional
+	IdempotencyKeyHeader string `json:"idempotencyKeyHeader,omitempty"`
+}
+
+// ProviderArchive configures the batches of events written by the archive provider.
+type ProviderArchive struct {
+	// The interval at which the received events are written, e.g. '1h', defaults to '15m'.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// The number of events after which a batch is written before the interval ends, defaults to 1000.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxEvents int `json:"maxEvents,omitempty"`
+
+	// The format of the objects, 'ndjson' for gzip compressed JSON lines,
+	// or 'parquet' for Parquet files with a column per event field, defaults to 'ndjson'.
+	// +kubebuilder:validation:Enum=ndjson;parquet
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// The layout of the object names, 'date' for '<prefix>/YYYY/MM/DD/',
+	// or 'hive' for the partitions '<prefix>/dt=YYYY-MM-DD/cluster=<cluster>/'
+	// queried by Athena or BigQuery, defaults to 'date'.
+	// +kubebuilder:validation:Enum=date;hive
+	// +optional
+	Layout string `json:"layout,omitempty"`
+
+	// The cluster partition of the hive layout, the partition is omitted when empty.
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9._-]+$"
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+}
+
+const (
+	NDJSONArchiveFormat  string = "ndjson"
+	ParquetArchiveFormat string = "parquet"
+
+	DateArchiveLayout string = "date"
+	HiveArchiveLayout string = "hive"
+)
+
+// StatusBoard configures the summary message of a provider.
+type StatusBoard struct {
+	// The interval at which the summary message is updated when events are received, e.g. '5m'.
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// The number of recent deployments listed in the summary message, defaults to 10.
+	// +kubebuilder:default:=10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RecentDeployments int `json:"recentDeployments,omitempty"`
 }
 
 const (
-	GenericProvider     string = "generic"
-	SlackProvider       string = "slack"
-	DiscordProvider     string = "discord"
-	MSTeamsProvider     string = "msteams"
-	RocketProvider      string = "rocket"
-	GitHubProvider      string = "github"
-	GitLabProvider      string = "gitlab"
-	BitbucketProvider   string = "bitbucket"
-	AzureDevOpsProvider string = "azuredevops"
-	GoogleChatProvider  string = "googlechat"
-	WebexProvider       string = "webex"
-	SentryProvider      string = "sentry"
+	GenericProvider               string = "generic"
+	GenericHMACProvider           string = "generic-hmac"
+	SlackProvider                 string = "slack"
+	DiscordProvider               string = "discord"
+	MSTeamsProvider               string = "msteams"
+	RocketProvider                string = "rocket"
+	GitHubProvider                string = "github"
+	GitLabProvider                string = "gitlab"
+	GitHubCommentProvider         string = "githubcomment"
+	GitLabCommentProvider         string = "gitlabcomment"
+	BitbucketProvider             string = "bitbucket"
+	AzureDevOpsProvider           string = "azuredevops"
+	TeamCityProvider              string = "teamcity"
+	BambooProvider                string = "bamboo"
+	GoogleChatProvider            string = "googlechat"
+	WebexProvider                 string = "webex"
+	SentryProvider                string = "sentry"
+	KubernetesProvider            string = "kubernetes"
+	LogProvider                   string = "log"
+	RedisProvider                 string = "redis"
+	CloudWatchProvider            string = "cloudwatch"
+	IBMEventNotificationsProvider string = "ibm"
+	OCINotificationsProvider      string = "oci"
+	KeptnProvider                 string = "keptn"
+	StatuspageProvider            string = "statuspage"
+	InstatusProvider              string = "instatus"
+	CachetProvider                string = "cachet"
+	GrafanaOnCallProvider         string = "grafanaoncall"
+	SquadcastProvider             string = "squadcast"
+	XMattersProvider              string = "xmatters"
+	EverbridgeProvider            string = "everbridge"
+	RootlyProvider                string = "rootly"
+	BlamelessProvider             string = "blameless"
+	OpsgenieProvider              string = "opsgenie"
+	IcingaProvider                string = "icinga"
+	NagiosProvider                string = "nagios"
+	ZabbixProvider                string = "zabbix"
+	ArchiveProvider               string = "archive"
+	BigQueryProvider              string = "bigquery"
+	ClickHouseProvider            string = "clickhouse"
+	PostgresProvider              string = "postgres"
+	MySQLProvider                 string = "mysql"
+	FanoutProvider                string = "fanout"
 )
 
 // ProviderStatus defines the observed state of Provider
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+)
+
+const (
+	ProviderBindingKind string = "ProviderBinding"
+)
+
+// ProviderBindingSpec defines the namespaces whose events are dispatched
+// to a provider when no alert of the namespace matches them.
+type ProviderBindingSpec struct {
+	// Select the namespaces by labels, an empty selector selects all the namespaces.
+	// +required
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+
+	// Send events using this provider.
+	// +required
+	ProviderRef meta.NamespacedObjectReference `json:"providerRef"`
+
+	// Filter events based on severity, defaults to ('info').
+	// If set to 'info' no events will be filtered.
+	// +kubebuilder:validation:Enum=info;error
+	// +kubebuilder:default:=info
+	// +optional
+	EventSeverity string `json:"eventSeverity,omitempty"`
+
+	// A list of Golang regular expressions to be used for excluding messages.
+	// +optional
+	ExclusionList []string `json:"exclusionList,omitempty"`
+
+	// Short description of the impact and affected cluster.
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// This flag tells the controller to suspend subsequent events dispatching.
+	// Defaults to false.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// ProviderBindingStatus defines the observed state of ProviderBinding
+type ProviderBindingStatus struct {
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the last observed generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// GetStatusConditions returns a pointer to the Status.Conditions slice
+func (in *ProviderBinding) GetStatusConditions() *[]metav1.Condition {
+	return &in.Status.Conditions
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// ProviderBinding is the Schema for the providerbindings API
+type ProviderBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProviderBindingSpec   `json:"spec,omitempty"`
+	Status ProviderBindingStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProviderBindingList contains a list of ProviderBinding
+type ProviderBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProviderBinding `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProviderBinding{}, &ProviderBindingList{})
+}
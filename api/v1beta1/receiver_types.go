@@ -26,12 +26,14 @@ import (
 type ReceiverSpec struct {
 	// Type of webhook sender, used to determine
 	// the validation procedure and payload deserialization.
-	// +kubebuilder:validation:Enum=generic;generic-hmac;github;gitlab;bitbucket;harbor;dockerhub;quay;gcr;nexus;acr
+	// The type must be registered by the controller build, e.g. 'github'.
+	// +kubebuilder:validation:MinLength=1
 	// +required
 	Type string `json:"type"`
 
 	// A list of events to handle,
-	// e.g. 'push' for GitHub or 'Push Hook' for GitLab.
+	// e.g. 'push' for GitHub or 'Push Hook' for GitLab,
+	// or the patterns of the pushed tags for DockerHub.
 	// +optional
 	Events []string `json:"events"`
 
@@ -39,17 +41,353 @@ type ReceiverSpec struct {
 	// +required
 	Resources []CrossNamespaceObjectReference `json:"resources"`
 
+	// The action applied to the resources, 'reconcile' requests their reconciliation,
+	// 'resume' clears their spec.suspend field and 'resume-and-reconcile' does both.
+	// Defaults to 'reconcile'.
+	// +kubebuilder:validation:Enum=reconcile;resume;resume-and-reconcile
+	// +kubebuilder:default:=reconcile
+	// +optional
+	Action string `json:"action,omitempty"`
+
 	// Secret reference containing the token used
 	// to validate the payload authenticity
 	// +required
 	SecretRef meta.LocalObjectReference `json:"secretRef,omitempty"`
 
+	// Filter the events based on the payload content,
+	// e.g. repository, git ref or changed files.
+	// Only supported by the 'github', 'gitlab', 'gitlab-system', 'harbor', 'quay', 'ecr', 'gar', 'jenkins', 'pubsub'
+	// and 'dependency-bot' receiver types.
+	// +optional
+	Filter *ReceiverFilter `json:"filter,omitempty"`
+
+	// Parse the XML request bodies, sent with the 'application/xml', 'text/xml'
+	// or '+xml' content types, into a map exposed as 'request.body' to the CEL
+	// expressions. Only supported by the 'generic' and 'generic-hmac' receiver types.
+	// +optional
+	ParseXML bool `json:"parseXML,omitempty"`
+
+	// Send a notification using this provider
+	// when the receiver triggers the reconciliation of its resources.
+	// +optional
+	ProviderRef *meta.LocalObjectReference `json:"providerRef,omitempty"`
+
+	// Select the maintenance windows in the same namespace
+	// during which the webhook triggers are rejected or deferred.
+	// +optional
+	MaintenanceWindowSelector *metav1.LabelSelector `json:"maintenanceWindowSelector,omitempty"`
+
+	// The annotation set on the resources to request their reconciliation,
+	// defaults to the 'reconcile.fluxcd.io/requestedAt' key with a timestamp value.
+	// +optional
+	Annotation *ReceiverAnnotation `json:"annotation,omitempty"`
+
+	// Record the receiver, the event type and the payload digest in annotations
+	// of the resources, along with the reconcile request annotation.
+	// +optional
+	Provenance *ReceiverProvenance `json:"provenance,omitempty"`
+
+	// Additional annotations set on the resources, the values are CEL
+	// expressions evaluated over the payload, e.g. 'request.body.push_data.tag'.
+	// The reconcile request annotation can be overridden by its key.
+	// +optional
+	ResourceAnnotations map[string]string `json:"resourceAnnotations,omitempty"`
+
+	// Write the tag and the digest of the pushed image in annotations of the
+	// ImageRepository and ImagePolicy resources, so that the image automation
+	// can react to the pushed image without scanning the tags.
+	// Only supported by the container registry receiver types.
+	// +optional
+	ImageHints bool `json:"imageHints,omitempty"`
+
+	// Verify the projected service account tokens sent by the in-cluster callers
+	// with the Kubernetes TokenReview API.
+	// Only supported by the 'serviceaccount' receiver type.
+	// +optional
+	ServiceAccounts *ReceiverServiceAccounts `json:"serviceAccounts,omitempty"`
+
+	// Verify the JWT bearer tokens sent by the callers with the signing keys
+	// of an OpenID Connect issuer, e.g. the workload identities of a cloud.
+	// Only supported by the 'generic' receiver type.
+	// +optional
+	OIDC *ReceiverOIDC `json:"oidc,omitempty"`
+
+	// The ARNs of the SNS topics allowed to notify the receiver, e.g.
+	// 'arn:aws:sns:us-east-1:123456789012:ecr', the messages of the other
+	// topics are rejected and their subscriptions are not confirmed.
+	// Required by the 'ecr' receiver type.
+	// +optional
+	Topics []string `json:"topics,omitempty"`
+
+	// The emails of the service accounts allowed to push the Pub/Sub messages,
+	// i.e. the service accounts of the push subscriptions, the tokens of the
+	// other accounts are rejected.
+	// Required by the 'gar' and 'pubsub' receiver types.
+	// +optional
+	PushServiceAccounts []string `json:"pushServiceAccounts,omitempty"`
+
+	// Restrict the source addresses of the webhook requests, the requests
+	// from other addresses are rejected before their token is verified.
+	// +optional
+	AccessFrom *ReceiverAccessFrom `json:"accessFrom,omitempty"`
+
+	// Secret reference containing the 'caFile' CA bundle verifying the client
+	// certificates of the callers, the requests without a certificate signed
+	// by the CA are rejected before their token is verified.
+	// Requires the receiver server to request the client certificates.
+	// +optional
+	CertSecretRef *meta.LocalObjectReference `json:"certSecretRef,omitempty"`
+
+	// Restrict the subjects and the subject alternative names
+	// of the client certificates verified with the certSecretRef CA.
+	// +optional
+	ClientCertificate *ReceiverClientCertificate `json:"clientCertificate,omitempty"`
+
+	// Respond with a 202 once the request is verified, the resources are then
+	// annotated by the workers of the receiver server and the outcome is
+	// recorded in the status, for the senders timing out on large receivers.
+	// +optional
+	Async bool `json:"async,omitempty"`
+
+	// Coalesce the triggers of a resource received within the window into a single
+	// update, applied with the annotations of the last trigger once the window
+	// elapses, e.g. for the many events sent by a multi-arch image push.
+	// The coalesced requests are acknowledged with a 202.
+	// +optional
+	CoalesceWindow *metav1.Duration `json:"coalesceWindow,omitempty"`
+
+	// Publish an event mapped from the verified payload to the alerts, so that
+	// the external systems, e.g. CI, can notify through the alert providers.
+	// The involved object of the event is the receiver.
+	// +optional
+	PublishEvent *ReceiverEvent `json:"publishEvent,omitempty"`
+
 	// This flag tells the controller to suspend subsequent events handling.
 	// Defaults to false.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
 }
 
+// ReceiverFilter defines the payload based filters of a Receiver.
+// An event is handled only if it matches all the specified filters.
+type ReceiverFilter struct {
+	// A list of glob patterns matched against the full name of the
+	// repository that sent the event, e.g. 'org/*'.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// A list of glob patterns matched against the git ref of a push event,
+	// e.g. 'refs/heads/main' or 'refs/tags/v*'.
+	// +optional
+	Refs []string `json:"refs,omitempty"`
+
+	// A list of glob patterns matched against the files changed by a push event,
+	// e.g. 'apps/foo/**'. The '**' pattern matches any number of directories.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// A list of GitLab object kinds to handle, e.g. 'push' or 'merge_request'.
+	// +optional
+	ObjectKinds []string `json:"objectKinds,omitempty"`
+
+	// Filter GitLab merge request events based on their state and labels.
+	// +optional
+	MergeRequest *MergeRequestFilter `json:"mergeRequest,omitempty"`
+
+	// A list of glob patterns matched against the full name of the
+	// Jenkins job that sent the event, e.g. 'apps/*'.
+	// +optional
+	Jobs []string `json:"jobs,omitempty"`
+
+	// A list of Jenkins build results to handle,
+	// e.g. 'SUCCESS' or 'UNSTABLE', defaults to 'SUCCESS'.
+	// +optional
+	Results []string `json:"results,omitempty"`
+
+	// A list of glob patterns matched against the package manager of the
+	// dependency updated by Renovate or Dependabot, e.g. 'npm' or 'gomod'.
+	// +optional
+	PackageManagers []string `json:"packageManagers,omitempty"`
+
+	// A CEL expression evaluated with the 'message' variable holding the Pub/Sub
+	// message or the CloudEvent sent to a generic receiver, its attributes and its
+	// decoded data, e.g. "message.attributes.status == 'SUCCESS'", or the Harbor
+	// event, e.g. "message.tag.startsWith('v')". The generic receivers also expose
+	// the 'request' variable holding the decoded 'body' and the 'headers'.
+	// +optional
+	Condition string `json:"condition,omitempty"`
+}
+
+// MergeRequestFilter defines the filters applied to merge request events.
+type MergeRequestFilter struct {
+	// A list of merge request states, e.g. 'opened' or 'merged'.
+	// +optional
+	States []string `json:"states,omitempty"`
+
+	// A list of labels that must be set on the merge request.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+}
+
+// ReceiverAnnotation defines the annotation set on the resources by a Receiver.
+type ReceiverAnnotation struct {
+	// The annotation key, defaults to 'reconcile.fluxcd.io/requestedAt'.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// The source of the annotation value, 'timestamp' is the time of the request,
+	// 'requestID' is the delivery ID sent by the webhook provider and 'payload'
+	// is the result of the expression evaluated over the JSON payload.
+	// Defaults to 'timestamp'.
+	// +kubebuilder:validation:Enum=timestamp;requestID;payload
+	// +kubebuilder:default:=timestamp
+	// +optional
+	ValueFrom string `json:"valueFrom,omitempty"`
+
+	// A CEL expression evaluated over the payload when the value
+	// is taken from the payload, e.g. 'request.body.head_commit.id'.
+	// +optional
+	Expression string `json:"expression,omitempty"`
+}
+
+// ReceiverProvenance defines the provenance annotations set on the resources by a Receiver.
+type ReceiverProvenance struct {
+	// The prefix of the provenance annotation keys,
+	// defaults to 'notification.toolkit.fluxcd.io/'.
+	// +optional
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+}
+
+// ReceiverServiceAccounts defines the service accounts allowed to call a Receiver.
+type ReceiverServiceAccounts struct {
+	// The audience the tokens must be issued for,
+	// defaults to 'notification-controller'.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// A list of glob patterns matched against the service accounts in the
+	// 'namespace/name' format, e.g. 'ci/*'. Defaults to the service accounts
+	// of the receiver namespace.
+	// +optional
+	Names []string `json:"names,omitempty"`
+}
+
+// ReceiverOIDC defines the OpenID Connect issuer of the tokens allowed to call a Receiver.
+type ReceiverOIDC struct {
+	// The issuer of the tokens, e.g. 'https://token.actions.githubusercontent.com'.
+	// +kubebuilder:validation:Pattern="^https://"
+	// +required
+	Issuer string `json:"issuer"`
+
+	// The HTTPS URL of the JSON Web Key Set of the issuer, discovered
+	// from the issuer OpenID configuration when empty.
+	// +kubebuilder:validation:Pattern="^https://"
+	// +optional
+	JWKSURL string `json:"jwksURL,omitempty"`
+
+	// The audience the tokens must be issued for.
+	// +required
+	Audience string `json:"audience"`
+
+	// A list of patterns matched against the subject of the tokens, the '*'
+	// wildcard matches any sequence, e.g. 'repo:org/webapp:*'.
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Subjects []string `json:"subjects"`
+}
+
+// ReceiverAccessFrom defines the source addresses allowed to call a Receiver.
+type ReceiverAccessFrom struct {
+	// A list of CIDR blocks or IP addresses, e.g. '192.30.252.0/22'.
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	IPBlocks []string `json:"ipBlocks"`
+}
+
+// ReceiverClientCertificate defines the client certificates allowed to call a Receiver,
+// a certificate must match one of the subjects and one of the SANs when both are set.
+type ReceiverClientCertificate struct {
+	// The patterns of the certificate subjects, e.g. 'CN=deployer,O=platform',
+	// the '*' wildcard matches any sequence of characters.
+	// +optional
+	Subjects []string `json:"subjects,omitempty"`
+
+	// The patterns of the DNS, email, IP and URI subject alternative names,
+	// e.g. 'spiffe://cluster.local/ns/ci/sa/*'.
+	// +optional
+	SANs []string `json:"sans,omitempty"`
+}
+
+// ReceiverEvent maps the verified payloads of a Receiver to events, with
+// CEL expressions evaluated over the payload, e.g. "'build ' + request.body.build.id + ' failed'".
+type ReceiverEvent struct {
+	// The CEL expression of the event message.
+	// +required
+	Message string `json:"message"`
+
+	// The CEL expression of the event reason, defaults to 'WebhookReceived'.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// The CEL expression of the event severity, the values other than
+	// 'error' are mapped to 'info'. Defaults to 'info'.
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// The CEL expressions of the event metadata, the empty values are omitted.
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ReceiverTrigger is the outcome of a verified webhook request.
+type ReceiverTrigger struct {
+	// The time the request was handled.
+	// +required
+	Time metav1.Time `json:"time"`
+
+	// The event of the request, e.g. 'push'.
+	// +optional
+	Event string `json:"event,omitempty"`
+
+	// The number of resources annotated.
+	// +required
+	Annotated int `json:"annotated"`
+
+	// The number of resources that failed to be annotated.
+	// +required
+	Failed int `json:"failed"`
+
+	// The result of the request, one of 'Succeeded', 'Failed', 'Throttled',
+	// 'Coalesced', or 'Deferred' and 'Rejected' by a maintenance window.
+	// +optional
+	Result string `json:"result,omitempty"`
+}
+
+const (
+	SucceededTriggerResult string = "Succeeded"
+	FailedTriggerResult    string = "Failed"
+	ThrottledTriggerResult string = "Throttled"
+	DeferredTriggerResult  string = "Deferred"
+	RejectedTriggerResult  string = "Rejected"
+	CoalescedTriggerResult string = "Coalesced"
+)
+
+const (
+	ReconcileAction          string = "reconcile"
+	ResumeAction             string = "resume"
+	ResumeAndReconcileAction string = "resume-and-reconcile"
+)
+
+const (
+	TimestampAnnotationValue string = "timestamp"
+	RequestIDAnnotationValue string = "requestID"
+	PayloadAnnotationValue   string = "payload"
+)
+
+// DefaultServiceAccountAudience is the default audience of the
+// service account tokens sent to the receivers.
+const DefaultServiceAccountAudience = "notification-controller"
+
 // ReceiverStatus defines the observed state of Receiver
 type ReceiverStatus struct {
 	// +optional
@@ -60,24 +398,49 @@ type ReceiverStatus struct {
 	// +optional
 	URL string `json:"url,omitempty"`
 
+	// DeferredResources are the resources whose reconciliation was deferred
+	// by an active maintenance window, they are annotated when the window closes.
+	// +optional
+	DeferredResources []CrossNamespaceObjectReference `json:"deferredResources,omitempty"`
+
+	// LastTrigger is the outcome of the last verified request.
+	// +optional
+	LastTrigger *ReceiverTrigger `json:"lastTrigger,omitempty"`
+
+	// History holds the outcomes of the last verified requests, most recent first.
+	// +optional
+	History []ReceiverTrigger `json:"history,omitempty"`
+
 	// ObservedGeneration is the last observed generation.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 const (
-	GenericReceiver     string = "generic"
-	GenericHMACReceiver string = "generic-hmac"
-	GitHubReceiver      string = "github"
-	GitLabReceiver      string = "gitlab"
-	BitbucketReceiver   string = "bitbucket"
-	HarborReceiver      string = "harbor"
-	DockerHubReceiver   string = "dockerhub"
-	QuayReceiver        string = "quay"
-	GCRReceiver         string = "gcr"
-	NexusReceiver       string = "nexus"
-	ReceiverKind        string = "Receiver"
-	ACRReceiver         string = "acr"
+	GenericReceiver         string = "generic"
+	GenericHMACReceiver     string = "generic-hmac"
+	GitHubReceiver          string = "github"
+	GitLabReceiver          string = "gitlab"
+	GitLabSystemReceiver    string = "gitlab-system"
+	GiteaReceiver           string = "gitea"
+	BitbucketReceiver       string = "bitbucket"
+	BitbucketServerReceiver string = "bitbucketserver"
+	BitbucketCloudReceiver  string = "bitbucket-cloud"
+	HarborReceiver          string = "harbor"
+	DockerHubReceiver       string = "dockerhub"
+	QuayReceiver            string = "quay"
+	GCRReceiver             string = "gcr"
+	GARReceiver             string = "gar"
+	NexusReceiver           string = "nexus"
+	ReceiverKind            string = "Receiver"
+	ACRReceiver             string = "acr"
+	ECRReceiver             string = "ecr"
+	JenkinsReceiver         string = "jenkins"
+	PubSubReceiver          string = "pubsub"
+	ServiceAccountReceiver  string = "serviceaccount"
+	DroneReceiver           string = "drone"
+	SlackReceiver           string = "slack"
+	DependencyBotReceiver   string = "dependency-bot"
 )
 
 func ReceiverReady(receiver Receiver, reason, message, url string) Receiver {
@@ -96,6 +459,14 @@ func (in *Receiver) GetStatusConditions() *[]metav1.Condition {
 	return &in.Status.Conditions
 }
 
+// GetAction returns the action applied to the resources, defaults to reconcile.
+func (in *Receiver) GetAction() string {
+	if in.Spec.Action == "" {
+		return ReconcileAction
+	}
+	return in.Spec.Action
+}
+
 // +genclient
 // +genclient:Namespaced
 // +kubebuilder:object:root=true
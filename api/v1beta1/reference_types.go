@@ -16,6 +16,10 @@ limitations under the License.
 
 package v1beta1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // CrossNamespaceObjectReference contains enough information to let you locate the
 // typed referenced object at cluster level
 type CrossNamespaceObjectReference struct {
@@ -23,16 +27,24 @@ type CrossNamespaceObjectReference struct {
 	// +optional
 	APIVersion string `json:"apiVersion,omitempty"`
 
-	// Kind of the referent
-	// +kubebuilder:validation:Enum=Bucket;GitRepository;Kustomization;HelmRelease;HelmChart;HelmRepository;ImageRepository;ImagePolicy;ImageUpdateAutomation
+	// Match the events of the Kustomizations and HelmReleases consuming the
+	// referent, e.g. a GitRepository, along with its own events.
+	// Only supported by the alert event sources.
+	// +optional
+	Consumers bool `json:"consumers,omitempty"`
+
+	// Kind of the referent, the alert event sources can use
+	// the '*' wildcard to match all the kinds
+	// +kubebuilder:validation:Enum=Bucket;GitRepository;Kustomization;HelmRelease;HelmChart;HelmRepository;ImageRepository;ImagePolicy;ImageUpdateAutomation;Receiver;*
 	// +required
 	Kind string `json:"kind,omitempty"`
 
-	// Name of the referent
+	// Name of the referent, the alert event sources can use
+	// the '*' wildcard or omit it to match all the names
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:MaxLength=53
-	// +required
-	Name string `json:"name"`
+	// +optional
+	Name string `json:"name,omitempty"`
 
 	// Namespace of the referent
 	// +kubebuilder:validation:MinLength=1
@@ -40,4 +52,49 @@ type CrossNamespaceObjectReference struct {
 	// +kubebuilder:validation:Optional
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
+
+	// Select the namespaces of the referents by labels, it takes precedence
+	// over the namespace. Only supported by the alert event sources.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// TemplateReference contains enough information to locate a template
+// stored in a ConfigMap, in the same namespace, or in another namespace
+// when the controller allows the cross-namespace template references.
+type TemplateReference struct {
+	// Name of the ConfigMap
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap, defaults to the namespace of the referrer.
+	// Another namespace requires the '--allow-cross-namespace-templates' flag.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the template in the ConfigMap, the other keys
+	// are available as named templates
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Key string `json:"key"`
+}
+
+// ExclusionListReference contains enough information to locate a list of
+// regular expressions stored in a ConfigMap, in the same or in another namespace.
+type ExclusionListReference struct {
+	// Name of the ConfigMap
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap, defaults to the namespace of the referrer
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the list in the ConfigMap, with a regular expression per line,
+	// the empty lines and the lines starting with '#' are ignored
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Key string `json:"key"`
 }
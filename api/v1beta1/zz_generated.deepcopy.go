@@ -88,17 +88,71 @@ func (in *AlertList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertSpec) DeepCopyInto(out *AlertSpec) {
 	*out = *in
+	if in.AlertTemplateRef != nil {
+		in, out := &in.AlertTemplateRef, &out.AlertTemplateRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
 	out.ProviderRef = in.ProviderRef
+	if in.ProviderRefs != nil {
+		in, out := &in.ProviderRefs, &out.ProviderRefs
+		*out = make([]ConditionalProviderReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.EventSources != nil {
 		in, out := &in.EventSources, &out.EventSources
 		*out = make([]CrossNamespaceObjectReference, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.ExclusionList != nil {
 		in, out := &in.ExclusionList, &out.ExclusionList
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExclusionListRef != nil {
+		in, out := &in.ExclusionListRef, &out.ExclusionListRef
+		*out = new(ExclusionListReference)
+		**out = **in
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InvolvedObjectMetadata != nil {
+		in, out := &in.InvolvedObjectMetadata, &out.InvolvedObjectMetadata
+		*out = new(InvolvedObjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(TemplateReference)
+		**out = **in
+	}
+	if in.MaintenanceWindowSelector != nil {
+		in, out := &in.MaintenanceWindowSelector, &out.MaintenanceWindowSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sampling != nil {
+		in, out := &in.Sampling, &out.Sampling
+		*out = new(EventSampling)
+		**out = **in
+	}
+	if in.FlapSuppression != nil {
+		in, out := &in.FlapSuppression, &out.FlapSuppression
+		*out = new(FlapSuppression)
+		**out = **in
+	}
+	if in.Deduplication != nil {
+		in, out := &in.Deduplication, &out.Deduplication
+		*out = new(EventDeduplication)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertSpec.
@@ -134,41 +188,25 @@ func (in *AlertStatus) DeepCopy() *AlertStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CrossNamespaceObjectReference) DeepCopyInto(out *CrossNamespaceObjectReference) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrossNamespaceObjectReference.
-func (in *CrossNamespaceObjectReference) DeepCopy() *CrossNamespaceObjectReference {
-	if in == nil {
-		return nil
-	}
-	out := new(CrossNamespaceObjectReference)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Provider) DeepCopyInto(out *Provider) {
+func (in *AlertTemplate) DeepCopyInto(out *AlertTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provider.
-func (in *Provider) DeepCopy() *Provider {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertTemplate.
+func (in *AlertTemplate) DeepCopy() *AlertTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(Provider)
+	out := new(AlertTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Provider) DeepCopyObject() runtime.Object {
+func (in *AlertTemplate) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -176,31 +214,31 @@ func (in *Provider) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProviderList) DeepCopyInto(out *ProviderList) {
+func (in *AlertTemplateList) DeepCopyInto(out *AlertTemplateList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Provider, len(*in))
+		*out = make([]AlertTemplate, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderList.
-func (in *ProviderList) DeepCopy() *ProviderList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertTemplateList.
+func (in *AlertTemplateList) DeepCopy() *AlertTemplateList {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderList)
+	out := new(AlertTemplateList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProviderList) DeepCopyObject() runtime.Object {
+func (in *AlertTemplateList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -208,54 +246,171 @@ func (in *ProviderList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProviderSpec) DeepCopyInto(out *ProviderSpec) {
+func (in *AlertTemplateSpec) DeepCopyInto(out *AlertTemplateSpec) {
 	*out = *in
-	if in.SecretRef != nil {
-		in, out := &in.SecretRef, &out.SecretRef
+	if in.ProviderRef != nil {
+		in, out := &in.ProviderRef, &out.ProviderRef
 		*out = new(meta.LocalObjectReference)
 		**out = **in
 	}
-	if in.CertSecretRef != nil {
-		in, out := &in.CertSecretRef, &out.CertSecretRef
-		*out = new(meta.LocalObjectReference)
+	if in.ExclusionList != nil {
+		in, out := &in.ExclusionList, &out.ExclusionList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(TemplateReference)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpec.
-func (in *ProviderSpec) DeepCopy() *ProviderSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertTemplateSpec.
+func (in *AlertTemplateSpec) DeepCopy() *AlertTemplateSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderSpec)
+	out := new(AlertTemplateSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProviderStatus) DeepCopyInto(out *ProviderStatus) {
+func (in *ConditionalProviderReference) DeepCopyInto(out *ConditionalProviderReference) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConditionalProviderReference.
+func (in *ConditionalProviderReference) DeepCopy() *ConditionalProviderReference {
+	if in == nil {
+		return nil
 	}
+	out := new(ConditionalProviderReference)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderStatus.
-func (in *ProviderStatus) DeepCopy() *ProviderStatus {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrossNamespaceObjectReference) DeepCopyInto(out *CrossNamespaceObjectReference) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrossNamespaceObjectReference.
+func (in *CrossNamespaceObjectReference) DeepCopy() *CrossNamespaceObjectReference {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderStatus)
+	out := new(CrossNamespaceObjectReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Receiver) DeepCopyInto(out *Receiver) {
+func (in *EventDeduplication) DeepCopyInto(out *EventDeduplication) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]DeduplicationField, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventDeduplication.
+func (in *EventDeduplication) DeepCopy() *EventDeduplication {
+	if in == nil {
+		return nil
+	}
+	out := new(EventDeduplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventSampling) DeepCopyInto(out *EventSampling) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventSampling.
+func (in *EventSampling) DeepCopy() *EventSampling {
+	if in == nil {
+		return nil
+	}
+	out := new(EventSampling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExclusionListReference) DeepCopyInto(out *ExclusionListReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExclusionListReference.
+func (in *ExclusionListReference) DeepCopy() *ExclusionListReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ExclusionListReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlapSuppression) DeepCopyInto(out *FlapSuppression) {
+	*out = *in
+	out.Window = in.Window
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlapSuppression.
+func (in *FlapSuppression) DeepCopy() *FlapSuppression {
+	if in == nil {
+		return nil
+	}
+	out := new(FlapSuppression)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InvolvedObjectMetadata) DeepCopyInto(out *InvolvedObjectMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvolvedObjectMetadata.
+func (in *InvolvedObjectMetadata) DeepCopy() *InvolvedObjectMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(InvolvedObjectMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -263,18 +418,18 @@ func (in *Receiver) DeepCopyInto(out *Receiver) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Receiver.
-func (in *Receiver) DeepCopy() *Receiver {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
 	if in == nil {
 		return nil
 	}
-	out := new(Receiver)
+	out := new(MaintenanceWindow)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Receiver) DeepCopyObject() runtime.Object {
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -282,31 +437,31 @@ func (in *Receiver) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReceiverList) DeepCopyInto(out *ReceiverList) {
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Receiver, len(*in))
+		*out = make([]MaintenanceWindow, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverList.
-func (in *ReceiverList) DeepCopy() *ReceiverList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
 	if in == nil {
 		return nil
 	}
-	out := new(ReceiverList)
+	out := new(MaintenanceWindowList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ReceiverList) DeepCopyObject() runtime.Object {
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -314,33 +469,27 @@ func (in *ReceiverList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReceiverSpec) DeepCopyInto(out *ReceiverSpec) {
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
 	*out = *in
-	if in.Events != nil {
-		in, out := &in.Events, &out.Events
-		*out = make([]string, len(*in))
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]RecurringWindow, len(*in))
 		copy(*out, *in)
 	}
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]CrossNamespaceObjectReference, len(*in))
-		copy(*out, *in)
-	}
-	out.SecretRef = in.SecretRef
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverSpec.
-func (in *ReceiverSpec) DeepCopy() *ReceiverSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ReceiverSpec)
+	out := new(MaintenanceWindowSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReceiverStatus) DeepCopyInto(out *ReceiverStatus) {
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -349,14 +498,854 @@ func (in *ReceiverStatus) DeepCopyInto(out *ReceiverStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverStatus.
-func (in *ReceiverStatus) DeepCopy() *ReceiverStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ReceiverStatus)
+	out := new(MaintenanceWindowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MergeRequestFilter) DeepCopyInto(out *MergeRequestFilter) {
+	*out = *in
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MergeRequestFilter.
+func (in *MergeRequestFilter) DeepCopy() *MergeRequestFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(MergeRequestFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Provider) DeepCopyInto(out *Provider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provider.
+func (in *Provider) DeepCopy() *Provider {
+	if in == nil {
+		return nil
+	}
+	out := new(Provider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Provider) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderBinding) DeepCopyInto(out *ProviderBinding) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderBinding.
+func (in *ProviderBinding) DeepCopy() *ProviderBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProviderBinding) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderBindingList) DeepCopyInto(out *ProviderBindingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProviderBinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderBindingList.
+func (in *ProviderBindingList) DeepCopy() *ProviderBindingList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderBindingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProviderBindingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderBindingSpec) DeepCopyInto(out *ProviderBindingSpec) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	out.ProviderRef = in.ProviderRef
+	if in.ExclusionList != nil {
+		in, out := &in.ExclusionList, &out.ExclusionList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderBindingSpec.
+func (in *ProviderBindingSpec) DeepCopy() *ProviderBindingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderBindingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderBindingStatus) DeepCopyInto(out *ProviderBindingStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderBindingStatus.
+func (in *ProviderBindingStatus) DeepCopy() *ProviderBindingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderBindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderArchive) DeepCopyInto(out *ProviderArchive) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderArchive.
+func (in *ProviderArchive) DeepCopy() *ProviderArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderDelivery) DeepCopyInto(out *ProviderDelivery) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int)
+		**out = **in
+	}
+	if in.RetryWaitMin != nil {
+		in, out := &in.RetryWaitMin, &out.RetryWaitMin
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RetryWaitMax != nil {
+		in, out := &in.RetryWaitMax, &out.RetryWaitMax
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderDelivery.
+func (in *ProviderDelivery) DeepCopy() *ProviderDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderHMAC) DeepCopyInto(out *ProviderHMAC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderHMAC.
+func (in *ProviderHMAC) DeepCopy() *ProviderHMAC {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderHMAC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderList) DeepCopyInto(out *ProviderList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Provider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderList.
+func (in *ProviderList) DeepCopy() *ProviderList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProviderList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSpec) DeepCopyInto(out *ProviderSpec) {
+	*out = *in
+	if in.AllowedChannels != nil {
+		in, out := &in.AllowedChannels, &out.AllowedChannels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StatusContexts != nil {
+		in, out := &in.StatusContexts, &out.StatusContexts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StatusStates != nil {
+		in, out := &in.StatusStates, &out.StatusStates
+		*out = make([]ProviderStatusState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EgressAllowlist != nil {
+		in, out := &in.EgressAllowlist, &out.EgressAllowlist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.CertSecretRef != nil {
+		in, out := &in.CertSecretRef, &out.CertSecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(TemplateReference)
+		**out = **in
+	}
+	if in.StatusBoard != nil {
+		in, out := &in.StatusBoard, &out.StatusBoard
+		*out = new(StatusBoard)
+		**out = **in
+	}
+	if in.ProviderRefs != nil {
+		in, out := &in.ProviderRefs, &out.ProviderRefs
+		*out = make([]meta.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(ProviderDelivery)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(ProviderArchive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HMAC != nil {
+		in, out := &in.HMAC, &out.HMAC
+		*out = new(ProviderHMAC)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpec.
+func (in *ProviderSpec) DeepCopy() *ProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderStatus) DeepCopyInto(out *ProviderStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderStatus.
+func (in *ProviderStatus) DeepCopy() *ProviderStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderStatusState) DeepCopyInto(out *ProviderStatusState) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderStatusState.
+func (in *ProviderStatusState) DeepCopy() *ProviderStatusState {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderStatusState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Receiver) DeepCopyInto(out *Receiver) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Receiver.
+func (in *Receiver) DeepCopy() *Receiver {
+	if in == nil {
+		return nil
+	}
+	out := new(Receiver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Receiver) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverAccessFrom) DeepCopyInto(out *ReceiverAccessFrom) {
+	*out = *in
+	if in.IPBlocks != nil {
+		in, out := &in.IPBlocks, &out.IPBlocks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverAccessFrom.
+func (in *ReceiverAccessFrom) DeepCopy() *ReceiverAccessFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverAccessFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverAnnotation) DeepCopyInto(out *ReceiverAnnotation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverAnnotation.
+func (in *ReceiverAnnotation) DeepCopy() *ReceiverAnnotation {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverAnnotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverClientCertificate) DeepCopyInto(out *ReceiverClientCertificate) {
+	*out = *in
+	if in.Subjects != nil {
+		in, out := &in.Subjects, &out.Subjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SANs != nil {
+		in, out := &in.SANs, &out.SANs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverClientCertificate.
+func (in *ReceiverClientCertificate) DeepCopy() *ReceiverClientCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverClientCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverEvent) DeepCopyInto(out *ReceiverEvent) {
+	*out = *in
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverEvent.
+func (in *ReceiverEvent) DeepCopy() *ReceiverEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverFilter) DeepCopyInto(out *ReceiverFilter) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Refs != nil {
+		in, out := &in.Refs, &out.Refs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObjectKinds != nil {
+		in, out := &in.ObjectKinds, &out.ObjectKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MergeRequest != nil {
+		in, out := &in.MergeRequest, &out.MergeRequest
+		*out = new(MergeRequestFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PackageManagers != nil {
+		in, out := &in.PackageManagers, &out.PackageManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverFilter.
+func (in *ReceiverFilter) DeepCopy() *ReceiverFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverList) DeepCopyInto(out *ReceiverList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Receiver, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverList.
+func (in *ReceiverList) DeepCopy() *ReceiverList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReceiverList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverProvenance) DeepCopyInto(out *ReceiverProvenance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverProvenance.
+func (in *ReceiverProvenance) DeepCopy() *ReceiverProvenance {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverProvenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverOIDC) DeepCopyInto(out *ReceiverOIDC) {
+	*out = *in
+	if in.Subjects != nil {
+		in, out := &in.Subjects, &out.Subjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverOIDC.
+func (in *ReceiverOIDC) DeepCopy() *ReceiverOIDC {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverOIDC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverServiceAccounts) DeepCopyInto(out *ReceiverServiceAccounts) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverServiceAccounts.
+func (in *ReceiverServiceAccounts) DeepCopy() *ReceiverServiceAccounts {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverServiceAccounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverSpec) DeepCopyInto(out *ReceiverSpec) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]CrossNamespaceObjectReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.SecretRef = in.SecretRef
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(ReceiverFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProviderRef != nil {
+		in, out := &in.ProviderRef, &out.ProviderRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.MaintenanceWindowSelector != nil {
+		in, out := &in.MaintenanceWindowSelector, &out.MaintenanceWindowSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Annotation != nil {
+		in, out := &in.Annotation, &out.Annotation
+		*out = new(ReceiverAnnotation)
+		**out = **in
+	}
+	if in.Provenance != nil {
+		in, out := &in.Provenance, &out.Provenance
+		*out = new(ReceiverProvenance)
+		**out = **in
+	}
+	if in.ResourceAnnotations != nil {
+		in, out := &in.ResourceAnnotations, &out.ResourceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = new(ReceiverServiceAccounts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(ReceiverOIDC)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PushServiceAccounts != nil {
+		in, out := &in.PushServiceAccounts, &out.PushServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AccessFrom != nil {
+		in, out := &in.AccessFrom, &out.AccessFrom
+		*out = new(ReceiverAccessFrom)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertSecretRef != nil {
+		in, out := &in.CertSecretRef, &out.CertSecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.ClientCertificate != nil {
+		in, out := &in.ClientCertificate, &out.ClientCertificate
+		*out = new(ReceiverClientCertificate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CoalesceWindow != nil {
+		in, out := &in.CoalesceWindow, &out.CoalesceWindow
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.PublishEvent != nil {
+		in, out := &in.PublishEvent, &out.PublishEvent
+		*out = new(ReceiverEvent)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverSpec.
+func (in *ReceiverSpec) DeepCopy() *ReceiverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverStatus) DeepCopyInto(out *ReceiverStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeferredResources != nil {
+		in, out := &in.DeferredResources, &out.DeferredResources
+		*out = make([]CrossNamespaceObjectReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastTrigger != nil {
+		in, out := &in.LastTrigger, &out.LastTrigger
+		*out = new(ReceiverTrigger)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ReceiverTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverStatus.
+func (in *ReceiverStatus) DeepCopy() *ReceiverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReceiverTrigger) DeepCopyInto(out *ReceiverTrigger) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReceiverTrigger.
+func (in *ReceiverTrigger) DeepCopy() *ReceiverTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(ReceiverTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringWindow) DeepCopyInto(out *RecurringWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringWindow.
+func (in *RecurringWindow) DeepCopy() *RecurringWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatusBoard) DeepCopyInto(out *StatusBoard) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusBoard.
+func (in *StatusBoard) DeepCopy() *StatusBoard {
+	if in == nil {
+		return nil
+	}
+	out := new(StatusBoard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateReference) DeepCopyInto(out *TemplateReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateReference.
+func (in *TemplateReference) DeepCopy() *TemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateReference)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -22,20 +22,29 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/reference"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/metrics"
 	"github.com/fluxcd/pkg/runtime/predicates"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/alerttemplate"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/exclusions"
+	"github.com/fluxcd/notification-controller/internal/templates"
 )
 
 // AlertReconciler reconciles a Alert object
@@ -43,10 +52,17 @@ type AlertReconciler struct {
 	client.Client
 	Scheme          *runtime.Scheme
 	MetricsRecorder *metrics.Recorder
+
+	// AllowCrossNamespaceTemplates permits the template references
+	// to the ConfigMaps of another namespace.
+	AllowCrossNamespaceTemplates bool
 }
 
 // +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=alerts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=alerts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=alerttemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get;list;watch
 
 func (r *AlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reconcileStart := time.Now()
@@ -94,20 +110,118 @@ func (r *AlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 func (r *AlertReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1beta1.Alert{}).
-		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{})).
+		For(&v1beta1.Alert{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
+		)).
+		Watches(
+			&source.Kind{Type: &v1beta1.AlertTemplate{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForAlertTemplate),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForTemplateConfigMap),
+		).
 		Complete(r)
 }
 
+// requestsForTemplateConfigMap enqueues the alerts referencing a changed
+// template ConfigMap, directly or through their alert template, so that
+// the templates are validated again.
+func (r *AlertReconciler) requestsForTemplateConfigMap(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	var alerts v1beta1.AlertList
+	if err := r.List(ctx, &alerts); err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, alert := range alerts.Items {
+		alert, err := alerttemplate.Resolve(ctx, r.Client, alert)
+		if err != nil {
+			continue
+		}
+		if referencesConfigMap(alert.Spec.TemplateRef, alert.Namespace, obj) {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name},
+			})
+		}
+	}
+	return reqs
+}
+
+// requestsForAlertTemplate enqueues the alerts referencing a changed alert template.
+func (r *AlertReconciler) requestsForAlertTemplate(obj client.Object) []reconcile.Request {
+	var alerts v1beta1.AlertList
+	if err := r.List(context.Background(), &alerts); err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, alert := range alerts.Items {
+		if alert.Spec.AlertTemplateRef != nil && alert.Spec.AlertTemplateRef.Name == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name},
+			})
+		}
+	}
+	return reqs
+}
+
 func (r *AlertReconciler) validate(ctx context.Context, alert v1beta1.Alert) error {
-	var provider v1beta1.Provider
-	providerName := types.NamespacedName{Namespace: alert.Namespace, Name: alert.Spec.ProviderRef.Name}
-	if err := r.Get(ctx, providerName, &provider); err != nil {
-		return fmt.Errorf("failed to get provider %s, error: %w", providerName.String(), err)
+	alert, err := alerttemplate.Resolve(ctx, r.Client, alert)
+	if err != nil {
+		return err
+	}
+
+	if alert.Spec.ProviderRef.Name == "" && len(alert.Spec.ProviderRefs) == 0 {
+		return fmt.Errorf("a provider is required, set the providerRef or the providerRefs of the alert, or the providerRef of its alert template")
+	}
+
+	for _, source := range alert.Spec.EventSources {
+		if source.NamespaceSelector == nil {
+			continue
+		}
+		if _, err := metav1.LabelSelectorAsSelector(source.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespace selector of the %s event source: %w", source.Kind, err)
+		}
+	}
+
+	var providerNames []string
+	if alert.Spec.ProviderRef.Name != "" {
+		providerNames = append(providerNames, alert.Spec.ProviderRef.Name)
+	}
+	for _, ref := range alert.Spec.ProviderRefs {
+		if ref.Condition != "" {
+			if _, err := cel.Compile(ref.Condition, "event"); err != nil {
+				return fmt.Errorf("invalid condition of provider %s: %w", ref.Name, err)
+			}
+		}
+		providerNames = append(providerNames, ref.Name)
 	}
 
-	if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, meta.ReadyCondition) {
-		return fmt.Errorf("provider %s is not ready", providerName.String())
+	for _, name := range providerNames {
+		var provider v1beta1.Provider
+		providerName := types.NamespacedName{Namespace: alert.Namespace, Name: name}
+		if err := r.Get(ctx, providerName, &provider); err != nil {
+			return fmt.Errorf("failed to get provider %s, error: %w", providerName.String(), err)
+		}
+
+		if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, meta.ReadyCondition) {
+			return fmt.Errorf("provider %s is not ready", providerName.String())
+		}
+	}
+
+	if alert.Spec.TemplateRef != nil {
+		if _, err := templates.Load(ctx, r.Client, *alert.Spec.TemplateRef, alert.Namespace, r.AllowCrossNamespaceTemplates); err != nil {
+			return err
+		}
+	}
+
+	if alert.Spec.ExclusionListRef != nil {
+		if _, err := exclusions.Load(ctx, r.Client, *alert.Spec.ExclusionListRef, alert.Namespace); err != nil {
+			return err
+		}
 	}
 
 	return nil
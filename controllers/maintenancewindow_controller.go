@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/maintenance"
+)
+
+// MaintenanceWindowReconciler reconciles a MaintenanceWindow object
+type MaintenanceWindowReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=maintenancewindows,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=maintenancewindows/status,verbs=get;update;patch
+
+func (r *MaintenanceWindowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logr.FromContext(ctx)
+
+	var window v1beta1.MaintenanceWindow
+	if err := r.Get(ctx, req.NamespacedName, &window); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if err := maintenance.Validate(window); err != nil {
+		window = v1beta1.MaintenanceWindowNotReady(window, v1beta1.InvalidScheduleReason, err.Error())
+		window.Status.ObservedGeneration = window.Generation
+		if err := r.patchStatus(ctx, req, window.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change is required to fix the schedule
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	active, err := maintenance.IsActive(window, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var next *metav1.Time
+	var requeueAfter time.Duration
+	if !window.Spec.Suspend {
+		t, err := maintenance.NextTransition(window, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		next = &metav1.Time{Time: t}
+		requeueAfter = t.Sub(now)
+	}
+
+	message := "Maintenance window is inactive"
+	if active {
+		message = "Maintenance window is active"
+	}
+	if next != nil {
+		message = fmt.Sprintf("%s until %s", message, next.UTC().Format(time.RFC3339))
+	}
+
+	window = v1beta1.MaintenanceWindowReady(window, v1beta1.InitializedReason, message, active, next)
+	window.Status.ObservedGeneration = window.Generation
+	if err := r.patchStatus(ctx, req, window.Status); err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	log.Info(message)
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *MaintenanceWindowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.MaintenanceWindow{}).
+		Complete(r)
+}
+
+func (r *MaintenanceWindowReconciler) patchStatus(ctx context.Context, req ctrl.Request, newStatus v1beta1.MaintenanceWindowStatus) error {
+	var window v1beta1.MaintenanceWindow
+	if err := r.Get(ctx, req.NamespacedName, &window); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(window.DeepCopy())
+	window.Status = newStatus
+
+	return r.Status().Patch(ctx, &window, patch)
+}
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"time"
 
@@ -30,15 +31,21 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/reference"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/metrics"
 	"github.com/fluxcd/pkg/runtime/predicates"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/egress"
 	"github.com/fluxcd/notification-controller/internal/notifier"
+	"github.com/fluxcd/notification-controller/internal/templates"
 )
 
 // ProviderReconciler reconciles a Provider object
@@ -46,10 +53,20 @@ type ProviderReconciler struct {
 	client.Client
 	Scheme          *runtime.Scheme
 	MetricsRecorder *metrics.Recorder
+
+	// EgressPolicy restricts the addresses the providers may contact,
+	// all addresses are permitted when nil.
+	EgressPolicy *egress.Policy
+
+	// AllowCrossNamespaceTemplates permits the template references
+	// to the ConfigMaps of another namespace.
+	AllowCrossNamespaceTemplates bool
 }
 
 // +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=providers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=providers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reconcileStart := time.Now()
@@ -71,6 +88,19 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	// validate provider spec and credentials
 	if err := r.validate(ctx, provider); err != nil {
+		// a denied address won't be permitted until the spec changes
+		if errors.Is(err, egress.ErrDenied) {
+			meta.SetResourceCondition(&provider, meta.ReadyCondition, metav1.ConditionFalse, v1beta1.EgressDeniedReason, err.Error())
+			meta.SetResourceCondition(&provider, meta.StalledCondition, metav1.ConditionTrue, v1beta1.EgressDeniedReason, err.Error())
+			if err := r.patchStatus(ctx, req, provider.Status); err != nil {
+				return ctrl.Result{Requeue: true}, err
+			}
+			log.Error(err, "Provider address rejected")
+			r.recordReadiness(ctx, provider)
+			return ctrl.Result{}, nil
+		}
+
+		apimeta.RemoveStatusCondition(&provider.Status.Conditions, meta.StalledCondition)
 		meta.SetResourceCondition(&provider, meta.ReadyCondition, metav1.ConditionFalse, meta.ReconciliationFailedReason, err.Error())
 		if err := r.patchStatus(ctx, req, provider.Status); err != nil {
 			return ctrl.Result{Requeue: true}, err
@@ -78,7 +108,9 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, meta.ReadyCondition) {
+	if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, meta.ReadyCondition) ||
+		apimeta.FindStatusCondition(provider.Status.Conditions, meta.StalledCondition) != nil {
+		apimeta.RemoveStatusCondition(&provider.Status.Conditions, meta.StalledCondition)
 		meta.SetResourceCondition(&provider, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, v1beta1.InitializedReason)
 		if err := r.patchStatus(ctx, req, provider.Status); err != nil {
 			return ctrl.Result{Requeue: true}, err
@@ -93,14 +125,57 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 func (r *ProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1beta1.Provider{}).
-		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{})).
+		For(&v1beta1.Provider{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
+		)).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForTemplateConfigMap),
+		).
 		Complete(r)
 }
 
+// requestsForTemplateConfigMap enqueues the providers referencing a changed
+// template ConfigMap, so that the templates are validated again.
+func (r *ProviderReconciler) requestsForTemplateConfigMap(obj client.Object) []reconcile.Request {
+	var providers v1beta1.ProviderList
+	if err := r.List(context.Background(), &providers); err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, provider := range providers.Items {
+		if referencesConfigMap(provider.Spec.TemplateRef, provider.Namespace, obj) {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name},
+			})
+		}
+	}
+	return reqs
+}
+
+// referencesConfigMap returns whether the template reference of an object
+// in the given namespace points to the ConfigMap.
+func referencesConfigMap(ref *v1beta1.TemplateReference, namespace string, cm client.Object) bool {
+	if ref == nil || ref.Name != cm.GetName() {
+		return false
+	}
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	return namespace == cm.GetNamespace()
+}
+
 func (r *ProviderReconciler) validate(ctx context.Context, provider v1beta1.Provider) error {
+	if provider.Spec.Type == v1beta1.FanoutProvider {
+		return r.validateFanout(ctx, provider)
+	}
+
 	address := provider.Spec.Address
 	token := ""
+	var signingKey, signingKeyPassphrase []byte
+	var awsCredentials notifier.AWSCredentials
+	var ociCredentials notifier.OCICredentials
 	if provider.Spec.SecretRef != nil {
 		var secret corev1.Secret
 		secretName := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Spec.SecretRef.Name}
@@ -116,9 +191,23 @@ func (r *ProviderReconciler) validate(ctx context.Context, provider v1beta1.Prov
 		if t, ok := secret.Data["token"]; ok {
 			token = string(t)
 		}
+
+		signingKey = secret.Data["signingKey"]
+		signingKeyPassphrase = secret.Data["signingKeyPassphrase"]
+		awsCredentials = notifier.AWSCredentials{
+			AccessKeyID:     string(secret.Data["awsAccessKeyID"]),
+			SecretAccessKey: string(secret.Data["awsSecretAccessKey"]),
+			SessionToken:    string(secret.Data["awsSessionToken"]),
+		}
+		ociCredentials = notifier.OCICredentials{
+			TenancyID:   string(secret.Data["ociTenancy"]),
+			UserID:      string(secret.Data["ociUser"]),
+			Fingerprint: string(secret.Data["ociFingerprint"]),
+			PrivateKey:  secret.Data["ociPrivateKey"],
+		}
 	}
 
-	if address == "" {
+	if address == "" && provider.Spec.Type != v1beta1.KubernetesProvider && provider.Spec.Type != v1beta1.LogProvider {
 		return fmt.Errorf("no address found in 'spec.address' nor in `spec.secretRef`")
 	}
 
@@ -143,7 +232,77 @@ func (r *ProviderReconciler) validate(ctx context.Context, provider v1beta1.Prov
 		}
 	}
 
+	policy, err := r.EgressPolicy.Restrict(provider.Spec.EgressAllowlist)
+	if err != nil {
+		return fmt.Errorf("invalid egress allowlist: %w", err)
+	}
+	if address != "" {
+		if err := policy.Check(ctx, address); err != nil {
+			return fmt.Errorf("provider address rejected: %w", err)
+		}
+	}
+	if provider.Spec.Proxy != "" {
+		if err := policy.Check(ctx, provider.Spec.Proxy); err != nil {
+			return fmt.Errorf("provider proxy rejected: %w", err)
+		}
+	}
+
+	if provider.Spec.TemplateRef != nil {
+		if _, err := templates.Load(ctx, r.Client, *provider.Spec.TemplateRef, provider.Namespace, r.AllowCrossNamespaceTemplates); err != nil {
+			return err
+		}
+	}
+
 	factory := notifier.NewFactory(address, provider.Spec.Proxy, provider.Spec.Username, provider.Spec.Channel, token, certPool)
+	factory.SigningKey = signingKey
+	factory.SigningKeyPassphrase = signingKeyPassphrase
+	factory.Method = provider.Spec.Method
+	factory.ContentType = provider.Spec.ContentType
+	factory.HMAC = provider.Spec.HMAC
+	factory.Delivery = notifier.NewDelivery(provider.Spec.Delivery)
+	factory.AllowedChannels = provider.Spec.AllowedChannels
+	factory.StatusContexts = provider.Spec.StatusContexts
+	factory.StatusStates = provider.Spec.StatusStates
+	factory.AWSCredentials = awsCredentials
+	factory.OCICredentials = ociCredentials
+	factory.KubeClient = r.Client
+	factory.Namespace = provider.Namespace
+	if d := factory.Delivery; d != nil {
+		if d.Timeout < 0 || d.RetryWaitMin < 0 || d.RetryWaitMax < 0 {
+			return fmt.Errorf("delivery durations cannot be negative")
+		}
+		if d.RetryWaitMax > 0 && d.RetryWaitMin > d.RetryWaitMax {
+			return fmt.Errorf("delivery retryWaitMin cannot exceed retryWaitMax")
+		}
+	}
+	if (provider.Spec.Method != "" || provider.Spec.ContentType != "") &&
+		provider.Spec.Type != v1beta1.GenericProvider && provider.Spec.Type != v1beta1.GenericHMACProvider {
+		return fmt.Errorf("method and content type not supported by the %s provider", provider.Spec.Type)
+	}
+	if provider.Spec.HMAC != nil && provider.Spec.Type != v1beta1.GenericHMACProvider {
+		return fmt.Errorf("hmac not supported by the %s provider", provider.Spec.Type)
+	}
+	if a := provider.Spec.Archive; a != nil {
+		if provider.Spec.Type != v1beta1.ArchiveProvider {
+			return fmt.Errorf("archive not supported by the %s provider", provider.Spec.Type)
+		}
+		if a.Cluster != "" && a.Layout != v1beta1.HiveArchiveLayout {
+			return fmt.Errorf("archive cluster requires the %s layout", v1beta1.HiveArchiveLayout)
+		}
+	}
+	if provider.Spec.StatusBoard != nil {
+		if provider.Spec.Type != v1beta1.SlackProvider {
+			return fmt.Errorf("status board not supported by the %s provider", provider.Spec.Type)
+		}
+		if notifier.IsChannelTemplate(provider.Spec.Channel) {
+			return fmt.Errorf("status board not supported with a templated channel")
+		}
+		if _, err := factory.SlackBoard(); err != nil {
+			return fmt.Errorf("failed to initialise status board, error: %w", err)
+		}
+		return nil
+	}
+
 	if _, err := factory.Notifier(provider.Spec.Type); err != nil {
 		return fmt.Errorf("failed to initialise provider, error: %w", err)
 	}
@@ -151,6 +310,24 @@ func (r *ProviderReconciler) validate(ctx context.Context, provider v1beta1.Prov
 	return nil
 }
 
+// validateFanout checks that the providers referenced by a fanout provider exist.
+func (r *ProviderReconciler) validateFanout(ctx context.Context, provider v1beta1.Provider) error {
+	if len(provider.Spec.ProviderRefs) == 0 {
+		return fmt.Errorf("no providers found in 'spec.providerRefs'")
+	}
+	for _, ref := range provider.Spec.ProviderRefs {
+		var child v1beta1.Provider
+		name := types.NamespacedName{Namespace: provider.Namespace, Name: ref.Name}
+		if err := r.Get(ctx, name, &child); err != nil {
+			return fmt.Errorf("failed to get provider '%s', error: %w", name, err)
+		}
+		if child.Spec.Type == v1beta1.FanoutProvider {
+			return fmt.Errorf("provider '%s' is a fanout provider", name)
+		}
+	}
+	return nil
+}
+
 func (r *ProviderReconciler) recordReadiness(ctx context.Context, provider v1beta1.Provider) {
 	log := logr.FromContext(ctx)
 	if r.MetricsRecorder == nil {
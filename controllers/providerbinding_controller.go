@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/predicates"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// ProviderBindingReconciler reconciles a ProviderBinding object
+type ProviderBindingReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=providerbindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=notification.toolkit.fluxcd.io,resources=providerbindings/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (r *ProviderBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logr.FromContext(ctx)
+
+	var binding v1beta1.ProviderBinding
+	if err := r.Get(ctx, req.NamespacedName, &binding); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// validate binding spec and provider
+	if err := r.validate(ctx, binding); err != nil {
+		meta.SetResourceCondition(&binding, meta.ReadyCondition, metav1.ConditionFalse, meta.ReconciliationFailedReason, err.Error())
+		if err := r.patchStatus(ctx, req, binding.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	if !apimeta.IsStatusConditionTrue(binding.Status.Conditions, meta.ReadyCondition) || binding.Status.ObservedGeneration != binding.Generation {
+		meta.SetResourceCondition(&binding, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, v1beta1.InitializedReason)
+		binding.Status.ObservedGeneration = binding.Generation
+		if err := r.patchStatus(ctx, req, binding.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		log.Info("ProviderBinding initialised")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *ProviderBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.ProviderBinding{}).
+		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{})).
+		Complete(r)
+}
+
+func (r *ProviderBindingReconciler) validate(ctx context.Context, binding v1beta1.ProviderBinding) error {
+	if _, err := metav1.LabelSelectorAsSelector(&binding.Spec.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	for _, exp := range binding.Spec.ExclusionList {
+		if _, err := regexp.Compile(exp); err != nil {
+			return fmt.Errorf("failed to compile regex '%s': %w", exp, err)
+		}
+	}
+
+	if binding.Spec.ProviderRef.Namespace == "" {
+		return fmt.Errorf("the provider namespace is required for cluster scoped bindings")
+	}
+
+	var provider v1beta1.Provider
+	providerName := types.NamespacedName{Namespace: binding.Spec.ProviderRef.Namespace, Name: binding.Spec.ProviderRef.Name}
+	if err := r.Get(ctx, providerName, &provider); err != nil {
+		return fmt.Errorf("failed to get provider %s, error: %w", providerName.String(), err)
+	}
+
+	if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, meta.ReadyCondition) {
+		return fmt.Errorf("provider %s is not ready", providerName.String())
+	}
+
+	return nil
+}
+
+func (r *ProviderBindingReconciler) patchStatus(ctx context.Context, req ctrl.Request, newStatus v1beta1.ProviderBindingStatus) error {
+	var binding v1beta1.ProviderBinding
+	if err := r.Get(ctx, req.NamespacedName, &binding); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(binding.DeepCopy())
+	binding.Status = newStatus
+
+	return r.Status().Patch(ctx, &binding, patch)
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"time"
+
 	"k8s.io/client-go/tools/reference"
 
 	"github.com/go-logr/logr"
@@ -29,11 +31,17 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/metrics"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/maintenance"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
 )
 
 // ReceiverReconciler reconciles a Receiver object
@@ -54,6 +62,7 @@ type ReceiverReconciler struct {
 // +kubebuilder:rbac:groups=image.fluxcd.io,resources=imagerepositories,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=image.fluxcd.io,resources=imagerepositories/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
 
 func (r *ReceiverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logr.FromContext(ctx)
@@ -66,31 +75,141 @@ func (r *ReceiverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// record suspension metrics
 	defer r.recordSuspension(ctx, receiver)
 
+	if err := receivers.Validate(receiver.Spec.Type); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.UnsupportedTypeReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change, or a build registering the type, is required
+		return ctrl.Result{}, nil
+	}
+
 	token, err := r.token(ctx, receiver)
 	if err != nil {
 		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.TokenNotFoundReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if _, err := trigger.ClientCAs(ctx, r.Client, receiver); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.InvalidClientCAReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
 		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
 			return ctrl.Result{Requeue: true}, err
 		}
 		return ctrl.Result{}, err
 	}
 
+	if err := trigger.ValidateResources(receiver); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.InvalidResourcesReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change is required to fix the resources
+		return ctrl.Result{}, nil
+	}
+
+	if err := trigger.ValidateAnnotation(receiver); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.InvalidAnnotationReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change is required to fix the annotation
+		return ctrl.Result{}, nil
+	}
+
+	if err := trigger.ValidateFilter(receiver); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.InvalidFilterReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change is required to fix the filter
+		return ctrl.Result{}, nil
+	}
+
+	if err := trigger.ValidateEvent(receiver); err != nil {
+		receiver = v1beta1.ReceiverNotReady(receiver, v1beta1.InvalidEventReason, err.Error())
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		// a spec change is required to fix the event mapping
+		return ctrl.Result{}, nil
+	}
+
 	isReady := apimeta.IsStatusConditionTrue(receiver.Status.Conditions, meta.ReadyCondition)
 	receiverURL := fmt.Sprintf("/hook/%s", sha256sum(token+receiver.Name+receiver.Namespace))
-	if receiver.Status.URL == receiverURL && isReady && receiver.Status.ObservedGeneration == receiver.Generation {
+	if receiver.Status.URL != receiverURL || !isReady || receiver.Status.ObservedGeneration != receiver.Generation {
+		receiver = v1beta1.ReceiverReady(receiver,
+			v1beta1.InitializedReason,
+			"Receiver initialised with URL: "+receiverURL,
+			receiverURL)
+		receiver.Status.ObservedGeneration = receiver.Generation
+		if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+
+		log.Info("Receiver initialised")
+	}
+
+	if len(receiver.Status.DeferredResources) == 0 || receiver.Spec.Suspend {
 		return ctrl.Result{}, nil
 	}
 
-	receiver = v1beta1.ReceiverReady(receiver,
-		v1beta1.InitializedReason,
-		"Receiver initialised with URL: "+receiverURL,
-		receiverURL)
-	receiver.Status.ObservedGeneration = receiver.Generation
-	if err := r.patchStatus(ctx, req, receiver.Status); err != nil {
+	return r.releaseDeferred(ctx, req, receiver)
+}
+
+// releaseDeferred annotates the resources queued during a maintenance window
+// once none of the selected windows is active.
+func (r *ReceiverReconciler) releaseDeferred(ctx context.Context, req ctrl.Request, receiver v1beta1.Receiver) (ctrl.Result, error) {
+	log := logr.FromContext(ctx)
+
+	now := time.Now()
+	window, err := maintenance.ActiveWindow(ctx, r.Client, receiver.Namespace, receiver.Spec.MaintenanceWindowSelector, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if window != nil {
+		next, err := maintenance.NextTransition(*window, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info(fmt.Sprintf("%d deferred resources waiting for maintenance window '%s' to close",
+			len(receiver.Status.DeferredResources), window.Name))
+		return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+	}
+
+	// the payload of the deferred triggers is not kept, the value is always a timestamp
+	annotationKey := trigger.AnnotationKey(receiver)
+	annotationValue := now.String()
+
+	released := make([]v1beta1.CrossNamespaceObjectReference, 0, len(receiver.Status.DeferredResources))
+	for _, resource := range receiver.Status.DeferredResources {
+		if err := trigger.Apply(ctx, r.Client, resource, receiver.Namespace, receiver.GetAction(),
+			map[string]string{annotationKey: annotationValue}); err != nil {
+			log.Error(err, fmt.Sprintf("unable to annotate deferred resource '%s/%s.%s'",
+				resource.Kind, resource.Name, resource.Namespace))
+			continue
+		}
+		log.Info(fmt.Sprintf("deferred resource '%s/%s.%s' annotated",
+			resource.Kind, resource.Name, resource.Namespace))
+		released = append(released, resource)
+	}
+
+	if err := trigger.Release(ctx, r.Client, req.NamespacedName, released); err != nil {
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	log.Info("Receiver initialised")
+	if failed := len(receiver.Status.DeferredResources) - len(released); failed > 0 {
+		return ctrl.Result{}, fmt.Errorf("unable to annotate %d deferred resources", failed)
+	}
 
 	return ctrl.Result{}, nil
 }
@@ -98,9 +217,58 @@ func (r *ReceiverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 func (r *ReceiverReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1beta1.Receiver{}).
+		Watches(
+			&source.Kind{Type: &v1beta1.MaintenanceWindow{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForMaintenanceWindow),
+		).
 		Complete(r)
 }
 
+// requestsForMaintenanceWindow enqueues the receivers with deferred resources
+// in the namespace of a changed maintenance window, e.g. when a freeze is lifted early.
+func (r *ReceiverReconciler) requestsForMaintenanceWindow(obj client.Object) []reconcile.Request {
+	var receivers v1beta1.ReceiverList
+	if err := r.List(context.Background(), &receivers, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, receiver := range receivers.Items {
+		if len(receiver.Status.DeferredResources) > 0 {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Name},
+			})
+		}
+	}
+	return reqs
+}
+
+// validate checks the receiver token, client CA, resources, annotation and filter.
+func (r *ReceiverReconciler) validate(ctx context.Context, receiver v1beta1.Receiver) error {
+	if err := receivers.Validate(receiver.Spec.Type); err != nil {
+		return err
+	}
+	if _, err := r.token(ctx, receiver); err != nil {
+		return err
+	}
+	if _, err := trigger.ClientCAs(ctx, r.Client, receiver); err != nil {
+		return err
+	}
+	if err := trigger.ValidateResources(receiver); err != nil {
+		return err
+	}
+	if err := trigger.ValidateAnnotation(receiver); err != nil {
+		return err
+	}
+	if err := trigger.ValidateAccessFrom(receiver); err != nil {
+		return err
+	}
+	if err := trigger.ValidateFilter(receiver); err != nil {
+		return err
+	}
+	return trigger.ValidateEvent(receiver)
+}
+
 // token extract the token value from the secret object
 func (r *ReceiverReconciler) token(ctx context.Context, receiver v1beta1.Receiver) (string, error) {
 	token := ""
@@ -155,6 +323,8 @@ func (r *ReceiverReconciler) patchStatus(ctx context.Context, req ctrl.Request,
 	}
 
 	patch := client.MergeFrom(receiver.DeepCopy())
+	// the deferred resources are updated by the webhook handlers concurrently
+	newStatus.DeferredResources = receiver.Status.DeferredResources
 	receiver.Status = newStatus
 
 	return r.Status().Patch(ctx, &receiver, patch)
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	// registers the receiver types
+	_ "github.com/fluxcd/notification-controller/internal/server"
+)
+
+func TestReceiverReconciler_unsupportedType(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	newReceiver := func(name, receiverType string) *v1beta1.Receiver {
+		return &v1beta1.Receiver{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 1},
+			Spec: v1beta1.ReceiverSpec{
+				Type:      receiverType,
+				SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+				Resources: []v1beta1.CrossNamespaceObjectReference{{Kind: "GitRepository", Name: "webapp"}},
+			},
+		}
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newReceiver("unknown", "gitlab-ee"),
+		newReceiver("generic", v1beta1.GenericReceiver),
+	).Build()
+	r := &ReceiverReconciler{Client: kubeClient, Scheme: scheme}
+
+	ctx := context.Background()
+	readyReason := func(name string) string {
+		var receiver v1beta1.Receiver
+		g.Expect(kubeClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &receiver)).To(gomega.Succeed())
+		ready := apimeta.FindStatusCondition(receiver.Status.Conditions, meta.ReadyCondition)
+		g.Expect(ready).NotTo(gomega.BeNil())
+		g.Expect(ready.Status).To(gomega.Equal(metav1.ConditionFalse))
+		return ready.Reason
+	}
+
+	// the unknown types aren't requeued, the spec must change
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "unknown", Namespace: "default"}})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{}))
+	g.Expect(readyReason("unknown")).To(gomega.Equal(v1beta1.UnsupportedTypeReason))
+
+	// the registered types go on with the secret, which is missing
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "generic", Namespace: "default"}})
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(readyReason("generic")).To(gomega.Equal(v1beta1.TokenNotFoundReason))
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/egress"
+)
+
+// SpecValidationPath is the path of the on demand validation
+// endpoint, served on the metrics address.
+const SpecValidationPath = "/validation"
+
+// onDemandInterval is the minimum interval between two on demand validations,
+// the last report is served to the requests received in between.
+const onDemandInterval = time.Minute
+
+// ValidationFailedReason is the reason of the warning events recorded for
+// the objects failing the spec validation.
+const ValidationFailedReason = "ValidationFailed"
+
+// SpecReport is the aggregated result of the validation of the
+// providers, alerts and receivers.
+type SpecReport struct {
+	Time     time.Time        `json:"time"`
+	Checked  map[string]int   `json:"checked"`
+	Failures []SpecValidation `json:"failures"`
+}
+
+// SpecValidation is the validation failure of an object.
+type SpecValidation struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Error     string `json:"error"`
+}
+
+// SpecValidator validates all the providers, alerts and receivers at startup,
+// so that the drift introduced while the controller was down, e.g. a deleted
+// secret or a CEL condition that no longer compiles, is reported at once
+// instead of when the next notification or webhook fails.
+type SpecValidator struct {
+	client       client.Client
+	egressPolicy *egress.Policy
+	crossNSRefs  bool
+	recorder     record.EventRecorder
+	logger       logr.Logger
+	failures     *prometheus.GaugeVec
+
+	// mu serializes the validations started at startup and on demand
+	mu sync.Mutex
+	// last is the report of the last on demand validation
+	last *SpecReport
+}
+
+// NewSpecValidator returns the spec validator, the invalid objects are
+// reported with warning events when the recorder is not nil. The
+// cross-namespace template references are valid when crossNSRefs is set.
+func NewSpecValidator(kubeClient client.Client, egressPolicy *egress.Policy, crossNSRefs bool,
+	recorder record.EventRecorder, logger logr.Logger) *SpecValidator {
+	return &SpecValidator{
+		client:       kubeClient,
+		egressPolicy: egressPolicy,
+		crossNSRefs:  crossNSRefs,
+		recorder:     recorder,
+		logger:       logger.WithName("spec-validator"),
+		failures: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gotk_spec_validation_failures",
+				Help: "The number of objects failing the spec validation, partitioned by kind.",
+			},
+			[]string{"kind"},
+		),
+	}
+}
+
+// Collectors returns the metrics collectors.
+func (v *SpecValidator) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{v.failures}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// the report is published once per cluster.
+func (v *SpecValidator) NeedLeaderElection() bool {
+	return true
+}
+
+// Start validates the objects once the controller is elected.
+func (v *SpecValidator) Start(ctx context.Context) error {
+	if _, err := v.Validate(ctx); err != nil {
+		v.logger.Error(err, "spec validation failed")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// ServeHTTP validates the objects on demand and responds with the report,
+// at most once per interval. The on demand validations don't record events,
+// so that the requests can't flood the events of the invalid objects.
+func (v *SpecValidator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := v.validateOnDemand(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		v.logger.Error(err, "failed to write spec report")
+	}
+}
+
+// Validate checks all the providers, alerts and receivers, records a warning
+// event for each invalid object and publishes the aggregated report.
+func (v *SpecValidator) Validate(ctx context.Context) (*SpecReport, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.validate(ctx, v.recorder)
+}
+
+// validateOnDemand returns the last on demand report when it's recent enough,
+// the objects are validated again without recording events otherwise.
+func (v *SpecValidator) validateOnDemand(ctx context.Context) (*SpecReport, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.last != nil && time.Since(v.last.Time) < onDemandInterval {
+		return v.last, nil
+	}
+	report, err := v.validate(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	v.last = report
+	return report, nil
+}
+
+// validate checks the objects and records the events with the recorder, if any.
+func (v *SpecValidator) validate(ctx context.Context, recorder record.EventRecorder) (*SpecReport, error) {
+
+	report := &SpecReport{
+		Time:     time.Now(),
+		Checked:  make(map[string]int),
+		Failures: []SpecValidation{},
+	}
+	fail := func(obj client.Object, kind string, err error) {
+		report.Failures = append(report.Failures, SpecValidation{
+			Kind:      kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Error:     err.Error(),
+		})
+		if recorder != nil {
+			recorder.Event(obj, corev1.EventTypeWarning, ValidationFailedReason, err.Error())
+		}
+	}
+
+	var providers v1beta1.ProviderList
+	if err := v.client.List(ctx, &providers); err != nil {
+		return nil, fmt.Errorf("unable to list providers: %w", err)
+	}
+	providerReconciler := &ProviderReconciler{
+		Client:                       v.client,
+		EgressPolicy:                 v.egressPolicy,
+		AllowCrossNamespaceTemplates: v.crossNSRefs,
+	}
+	for i := range providers.Items {
+		provider := &providers.Items[i]
+		if err := providerReconciler.validate(ctx, *provider); err != nil {
+			fail(provider, v1beta1.ProviderKind, err)
+		}
+	}
+	report.Checked[v1beta1.ProviderKind] = len(providers.Items)
+
+	var alerts v1beta1.AlertList
+	if err := v.client.List(ctx, &alerts); err != nil {
+		return nil, fmt.Errorf("unable to list alerts: %w", err)
+	}
+	alertReconciler := &AlertReconciler{Client: v.client, AllowCrossNamespaceTemplates: v.crossNSRefs}
+	for i := range alerts.Items {
+		alert := &alerts.Items[i]
+		if err := alertReconciler.validate(ctx, *alert); err != nil {
+			fail(alert, v1beta1.AlertKind, err)
+		}
+	}
+	report.Checked[v1beta1.AlertKind] = len(alerts.Items)
+
+	var receivers v1beta1.ReceiverList
+	if err := v.client.List(ctx, &receivers); err != nil {
+		return nil, fmt.Errorf("unable to list receivers: %w", err)
+	}
+	receiverReconciler := &ReceiverReconciler{Client: v.client}
+	for i := range receivers.Items {
+		receiver := &receivers.Items[i]
+		if err := receiverReconciler.validate(ctx, *receiver); err != nil {
+			fail(receiver, v1beta1.ReceiverKind, err)
+		}
+	}
+	report.Checked[v1beta1.ReceiverKind] = len(receivers.Items)
+
+	sort.Slice(report.Failures, func(i, j int) bool {
+		a, b := report.Failures[i], report.Failures[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	counts := make(map[string]int)
+	for _, failure := range report.Failures {
+		counts[failure.Kind]++
+	}
+	for kind := range report.Checked {
+		v.failures.WithLabelValues(kind).Set(float64(counts[kind]))
+	}
+
+	if len(report.Failures) > 0 {
+		v.logger.Info(fmt.Sprintf("%d invalid objects found", len(report.Failures)),
+			"providers", counts[v1beta1.ProviderKind],
+			"alerts", counts[v1beta1.AlertKind],
+			"receivers", counts[v1beta1.ReceiverKind])
+	} else {
+		v.logger.Info("all objects are valid")
+	}
+
+	return report, nil
+}
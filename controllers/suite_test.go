@@ -143,7 +143,7 @@ var _ = Describe("Event handlers", func() {
 		})
 		Expect(err).ShouldNot(HaveOccurred())
 		// TODO let OS assign port number
-		eventServer := server.NewEventServer("127.0.0.1:56789", logf.Log, k8sClient)
+		eventServer := server.NewEventServer("127.0.0.1:56789", logf.Log, k8sClient, 2, 100)
 		stopCh = make(chan struct{})
 		go eventServer.ListenAndServe(stopCh, eventMdlw, store)
 	})
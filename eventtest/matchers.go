@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventtest provides Gomega matchers asserting on the events
+// emitted by the controllers and consumed by the notification-controller.
+package eventtest
+
+import (
+	"fmt"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+// MatchEventReason succeeds if the reason of the event matches, the expected
+// value is a reason or a matcher, e.g. HavePrefix("Reconciliation").
+func MatchEventReason(reason interface{}) types.GomegaMatcher {
+	return &eventMatcher{
+		field:   "reason",
+		value:   func(e events.Event) interface{} { return e.Reason },
+		matcher: equalOrMatcher(reason),
+	}
+}
+
+// BeSeverity succeeds if the event has the severity, e.g. 'info' or 'error'.
+func BeSeverity(severity string) types.GomegaMatcher {
+	return &eventMatcher{
+		field:   "severity",
+		value:   func(e events.Event) interface{} { return e.Severity },
+		matcher: gomega.Equal(severity),
+	}
+}
+
+// HaveMetadataKey succeeds if the event metadata has the key, the expected
+// value is a key or a matcher, e.g. HavePrefix("kustomize.toolkit.fluxcd.io/").
+func HaveMetadataKey(key interface{}) types.GomegaMatcher {
+	return &eventMatcher{
+		field:   "metadata",
+		value:   func(e events.Event) interface{} { return e.Metadata },
+		matcher: gomega.HaveKey(key),
+	}
+}
+
+// HaveMetadata succeeds if the event metadata has the key with the value,
+// the key and the value can be matchers.
+func HaveMetadata(key, value interface{}) types.GomegaMatcher {
+	return &eventMatcher{
+		field:   "metadata",
+		value:   func(e events.Event) interface{} { return e.Metadata },
+		matcher: gomega.HaveKeyWithValue(key, value),
+	}
+}
+
+// eventMatcher applies the matcher to a field of an events.Event, or of a pointer to one.
+type eventMatcher struct {
+	field   string
+	value   func(events.Event) interface{}
+	matcher types.GomegaMatcher
+}
+
+func (m *eventMatcher) Match(actual interface{}) (bool, error) {
+	e, err := toEvent(actual)
+	if err != nil {
+		return false, err
+	}
+	return m.matcher.Match(m.value(e))
+}
+
+func (m *eventMatcher) FailureMessage(actual interface{}) string {
+	e, _ := toEvent(actual)
+	return fmt.Sprintf("Expected the %s of the event for %s\n%s", m.field, involvedObject(e), m.matcher.FailureMessage(m.value(e)))
+}
+
+func (m *eventMatcher) NegatedFailureMessage(actual interface{}) string {
+	e, _ := toEvent(actual)
+	return fmt.Sprintf("Expected the %s of the event for %s\n%s", m.field, involvedObject(e), m.matcher.NegatedFailureMessage(m.value(e)))
+}
+
+func toEvent(actual interface{}) (events.Event, error) {
+	switch e := actual.(type) {
+	case events.Event:
+		return e, nil
+	case *events.Event:
+		if e != nil {
+			return *e, nil
+		}
+	}
+	return events.Event{}, fmt.Errorf("the matcher expects an events.Event, got:\n%s", format.Object(actual, 1))
+}
+
+func involvedObject(e events.Event) string {
+	return fmt.Sprintf("%s/%s.%s", e.InvolvedObject.Kind, e.InvolvedObject.Name, e.InvolvedObject.Namespace)
+}
+
+func equalOrMatcher(expected interface{}) types.GomegaMatcher {
+	if m, ok := expected.(types.GomegaMatcher); ok {
+		return m
+	}
+	return gomega.Equal(expected)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventtest
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+func TestMatchers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	e := events.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "webapp", Namespace: "apps"},
+		Severity:       events.EventSeverityError,
+		Reason:         "ReconciliationFailed",
+		Metadata:       map[string]string{"revision": "main/731f7ea"},
+	}
+
+	g.Expect(e).To(MatchEventReason("ReconciliationFailed"))
+	g.Expect(&e).To(MatchEventReason(gomega.HavePrefix("Reconciliation")))
+	g.Expect(e).NotTo(MatchEventReason("ReconciliationSucceeded"))
+	g.Expect(e).To(BeSeverity(events.EventSeverityError))
+	g.Expect(e).NotTo(BeSeverity(events.EventSeverityInfo))
+	g.Expect(e).To(HaveMetadataKey("revision"))
+	g.Expect(e).NotTo(HaveMetadataKey("summary"))
+	g.Expect(e).To(HaveMetadata("revision", gomega.HaveSuffix("731f7ea")))
+
+	message := BeSeverity(events.EventSeverityInfo).FailureMessage(e)
+	g.Expect(message).To(gomega.ContainSubstring("Expected the severity of the event for Kustomization/webapp.apps"))
+
+	_, err := BeSeverity(events.EventSeverityInfo).Match("error")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
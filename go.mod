@@ -5,24 +5,38 @@ go 1.15
 replace github.com/fluxcd/notification-controller/api => ./api
 
 require (
+	github.com/alicebob/miniredis/v2 v2.14.3
 	github.com/fluxcd/notification-controller/api v0.13.0
 	github.com/fluxcd/pkg/apis/meta v0.9.0
 	github.com/fluxcd/pkg/runtime v0.11.0
 	github.com/getsentry/sentry-go v0.10.0
 	github.com/go-logr/logr v0.3.0
+	github.com/go-redis/redis/v8 v8.4.11
+	github.com/go-sql-driver/mysql v1.7.1
+	github.com/google/cel-go v0.7.3
 	github.com/google/go-github/v32 v32.1.0
+	github.com/google/uuid v1.1.2
 	github.com/hashicorp/go-retryablehttp v0.6.8
+	github.com/jackc/pgx/v4 v4.13.0
 	github.com/ktrysmt/go-bitbucket v0.6.5
 	github.com/microsoft/azure-devops-go-api/azuredevops v1.0.0-b5
-	github.com/onsi/ginkgo v1.14.1
-	github.com/onsi/gomega v1.10.2
+	github.com/onsi/ginkgo v1.14.2
+	github.com/onsi/gomega v1.10.4
+	github.com/prometheus/client_golang v1.7.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/sethvargo/go-limiter v0.6.0
 	github.com/slok/go-http-metrics v0.9.0
 	github.com/spf13/pflag v1.0.5
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	github.com/whilp/git-urls v1.0.0
 	github.com/xanzy/go-gitlab v0.38.2
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+	golang.org/x/crypto v0.0.0-20210711020723-a769d52b0f97
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	google.golang.org/genproto v0.0.0-20211118181313-81c1377c94b1
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
 	k8s.io/api v0.20.4
 	k8s.io/apimachinery v0.20.4
 	k8s.io/client-go v0.20.4
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerttemplate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// Resolve returns the alert with the defaults of its alert template applied,
+// the alert is returned unchanged when it doesn't reference a template.
+func Resolve(ctx context.Context, kubeClient client.Client, alert v1beta1.Alert) (v1beta1.Alert, error) {
+	if alert.Spec.AlertTemplateRef == nil {
+		return alert, nil
+	}
+
+	var template v1beta1.AlertTemplate
+	name := types.NamespacedName{Name: alert.Spec.AlertTemplateRef.Name}
+	if err := kubeClient.Get(ctx, name, &template); err != nil {
+		return alert, fmt.Errorf("failed to get alert template %s, error: %w", name.Name, err)
+	}
+	return Merge(alert, template), nil
+}
+
+// Merge returns a copy of the alert with the defaults of the template,
+// the fields set by the alert take precedence and the exclusion lists
+// and metadata of both are combined.
+func Merge(alert v1beta1.Alert, template v1beta1.AlertTemplate) v1beta1.Alert {
+	merged := *alert.DeepCopy()
+	defaults := template.Spec
+
+	if merged.Spec.ProviderRef.Name == "" && len(merged.Spec.ProviderRefs) == 0 && defaults.ProviderRef != nil {
+		merged.Spec.ProviderRef = *defaults.ProviderRef
+	}
+
+	if len(defaults.ExclusionList) > 0 {
+		exclusionList := make([]string, 0, len(defaults.ExclusionList)+len(alert.Spec.ExclusionList))
+		exclusionList = append(exclusionList, defaults.ExclusionList...)
+		merged.Spec.ExclusionList = append(exclusionList, alert.Spec.ExclusionList...)
+	}
+
+	if merged.Spec.Summary == "" {
+		merged.Spec.Summary = defaults.Summary
+	}
+
+	if len(defaults.Metadata) > 0 {
+		metadata := make(map[string]string, len(defaults.Metadata)+len(alert.Spec.Metadata))
+		for k, v := range defaults.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range alert.Spec.Metadata {
+			metadata[k] = v
+		}
+		merged.Spec.Metadata = metadata
+	}
+
+	if merged.Spec.TemplateRef == nil && defaults.TemplateRef != nil {
+		ref := *defaults.TemplateRef
+		merged.Spec.TemplateRef = &ref
+	}
+
+	return merged
+}
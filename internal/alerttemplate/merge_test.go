@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerttemplate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func testTemplate() *v1beta1.AlertTemplate {
+	return &v1beta1.AlertTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec: v1beta1.AlertTemplateSpec{
+			ProviderRef:   &meta.LocalObjectReference{Name: "slack"},
+			ExclusionList: []string{"^no-op"},
+			Summary:       "production",
+			Metadata:      map[string]string{"cluster": "prod-eu", "team": "platform"},
+			TemplateRef:   &v1beta1.TemplateReference{Name: "templates", Namespace: "flux-system", Key: "message"},
+		},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "apps"},
+		Spec: v1beta1.AlertSpec{
+			ExclusionList: []string{"waiting.*socket"},
+			Metadata:      map[string]string{"team": "apps"},
+		},
+	}
+
+	merged := Merge(alert, *testTemplate())
+	require.Equal(t, "slack", merged.Spec.ProviderRef.Name)
+	require.Equal(t, []string{"^no-op", "waiting.*socket"}, merged.Spec.ExclusionList)
+	require.Equal(t, "production", merged.Spec.Summary)
+	require.Equal(t, map[string]string{"cluster": "prod-eu", "team": "apps"}, merged.Spec.Metadata)
+	require.Equal(t, "templates", merged.Spec.TemplateRef.Name)
+	require.Equal(t, []string{"waiting.*socket"}, alert.Spec.ExclusionList)
+
+	alert.Spec.ProviderRef = meta.LocalObjectReference{Name: "msteams"}
+	alert.Spec.Summary = "staging"
+	alert.Spec.TemplateRef = &v1beta1.TemplateReference{Name: "apps", Key: "message"}
+	merged = Merge(alert, *testTemplate())
+	require.Equal(t, "msteams", merged.Spec.ProviderRef.Name)
+	require.Equal(t, "staging", merged.Spec.Summary)
+	require.Equal(t, "apps", merged.Spec.TemplateRef.Name)
+}
+
+func TestResolve(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(testTemplate()).Build()
+
+	alert := v1beta1.Alert{ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "apps"}}
+	resolved, err := Resolve(context.TODO(), kubeClient, alert)
+	require.NoError(t, err)
+	require.Equal(t, "", resolved.Spec.ProviderRef.Name)
+
+	alert.Spec.AlertTemplateRef = &meta.LocalObjectReference{Name: "platform"}
+	resolved, err = Resolve(context.TODO(), kubeClient, alert)
+	require.NoError(t, err)
+	require.Equal(t, "slack", resolved.Spec.ProviderRef.Name)
+
+	alert.Spec.AlertTemplateRef = &meta.LocalObjectReference{Name: "missing"}
+	_, err = Resolve(context.TODO(), kubeClient, alert)
+	require.Error(t, err)
+}
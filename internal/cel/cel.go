@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/ext"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Program is a compiled CEL expression.
+type Program struct {
+	expr    string
+	program cel.Program
+}
+
+// Compile compiles the expression and checks that it only refers to the variables.
+func Compile(expr string, variables ...string) (*Program, error) {
+	env, err := newEnv(variables)
+	if err != nil {
+		return nil, err
+	}
+	return compile(env, expr)
+}
+
+// Eval evaluates the expression with the variables, the values of the variables
+// are converted to their JSON representation, e.g. a struct to a map.
+func (p *Program) Eval(variables map[string]interface{}) (interface{}, error) {
+	activation := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		v, err := toJSONValue(value)
+		if err != nil {
+			return nil, err
+		}
+		activation[name] = v
+	}
+	out, _, err := p.program.Eval(activation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate '%s': %w", p.expr, err)
+	}
+	return out.Value(), nil
+}
+
+// EvalBool evaluates an expression returning a bool.
+func (p *Program) EvalBool(variables map[string]interface{}) (bool, error) {
+	out, err := p.Eval(variables)
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression '%s' returned %T instead of a bool", p.expr, out)
+	}
+	return b, nil
+}
+
+// EvalString evaluates an expression returning a string,
+// the numbers and the bools are formatted.
+func (p *Program) EvalString(variables map[string]interface{}) (string, error) {
+	out, err := p.Eval(variables)
+	if err != nil {
+		return "", err
+	}
+	switch v := out.(type) {
+	case string:
+		return v, nil
+	case int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expression '%s' returned %T instead of a string", p.expr, out)
+	}
+}
+
+// Cache holds the programs compiled for the expressions of the objects,
+// the programs of an object are compiled again when its generation changes.
+type Cache struct {
+	variables []string
+
+	mu      sync.Mutex
+	env     *cel.Env
+	objects map[string]*objectPrograms
+}
+
+type objectPrograms struct {
+	generation int64
+	programs   map[string]*Program
+}
+
+// NewCache returns a cache of the programs of the expressions
+// referring to the variables.
+func NewCache(variables ...string) *Cache {
+	return &Cache{
+		variables: variables,
+		objects:   make(map[string]*objectPrograms),
+	}
+}
+
+// Program returns the program of the expression of the object, e.g. 'Alert/ns/name',
+// compiled for the given object generation.
+func (c *Cache) Program(object string, generation int64, expr string) (*Program, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.env == nil {
+		env, err := newEnv(c.variables)
+		if err != nil {
+			return nil, err
+		}
+		c.env = env
+	}
+
+	entry, ok := c.objects[object]
+	if !ok || entry.generation != generation {
+		entry = &objectPrograms{generation: generation, programs: make(map[string]*Program)}
+		c.objects[object] = entry
+	}
+	if p, ok := entry.programs[expr]; ok {
+		return p, nil
+	}
+
+	p, err := compile(c.env, expr)
+	if err != nil {
+		return nil, err
+	}
+	entry.programs[expr] = p
+	return p, nil
+}
+
+func newEnv(variables []string) (*cel.Env, error) {
+	declarations := make([]*exprpb.Decl, 0, len(variables))
+	for _, v := range variables {
+		declarations = append(declarations, decls.NewVar(v, decls.Dyn))
+	}
+	env, err := cel.NewEnv(ext.Strings(), cel.Declarations(declarations...))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the CEL environment: %w", err)
+	}
+	return env, nil
+}
+
+func compile(env *cel.Env, expr string) (*Program, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", expr, issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", expr, err)
+	}
+	return &Program{expr: expr, program: program}, nil
+}
+
+// toJSONValue converts the value to its JSON representation, the integral
+// numbers are kept as int64 so that they compare with the CEL int literals.
+func toJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Retries  int               `json:"retries"`
+	Tags     []string          `json:"tags,omitempty"`
+}
+
+func TestProgram_EvalBool(t *testing.T) {
+	event := testEvent{
+		Severity: "error",
+		Message:  "health check failed",
+		Metadata: map[string]string{"env": "production", "team": "payments"},
+		Retries:  3,
+		Tags:     []string{"db", "critical"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`event.severity == 'error'`, true},
+		{`event.severity != "error"`, false},
+		{`event.severity == 'error' && event.metadata.env == 'production'`, true},
+		{`event.severity == 'info' || event.metadata['team'] in ['payments', 'billing']`, true},
+		{`!(event.severity == 'error')`, false},
+		{`event.message.startsWith('health') && event.message.contains('check')`, true},
+		{`event.message.matches('^health\\s+check')`, true},
+		{`has(event.metadata.env) && !has(event.metadata.owner)`, true},
+		{`'env' in event.metadata`, true},
+		{`size(event.metadata) == 2 && event.message.size() > 10`, true},
+		{`event.metadata.team.upperAscii() == 'PAYMENTS'`, true},
+		{`(event.severity == 'error' ? event.metadata.env : 'none') == 'production'`, true},
+		// the missing key error is ignored when the other side decides
+		{`event.metadata.owner == 'me' || event.severity == 'error'`, true},
+		{`event.severity == 'info' && event.metadata.owner == 'me'`, false},
+		{`event.retries > 2 && event.retries * 2 == 6`, true},
+		{`event.tags.exists(t, t == 'critical')`, true},
+		{`event.tags.all(t, t.size() > 1)`, true},
+		{`event.tags.filter(t, t.startsWith('d')).size() == 1`, true},
+		{`event.tags.map(t, t.upperAscii()) == ['DB', 'CRITICAL']`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr, "event")
+			require.NoError(t, err)
+			got, err := p.EvalBool(map[string]interface{}{"event": event})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProgram_EvalErrors(t *testing.T) {
+	vars := map[string]interface{}{"event": testEvent{Severity: "info"}}
+	for _, expr := range []string{
+		`event.metadata.owner == 'me'`,
+		`event.severity`,
+		`event.severity < 1`,
+		`event.severity.matches('[')`,
+	} {
+		p, err := Compile(expr, "event")
+		require.NoError(t, err, expr)
+		_, err = p.EvalBool(vars)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`event.severity ==`,
+		`event.severity == 'error`,
+		`severity == 'error'`,
+		`event.severity.unknown() == 'error'`,
+		`has(event)`,
+		`event.severity == 'error')`,
+		`[1, 2`,
+	} {
+		_, err := Compile(expr, "event")
+		require.Error(t, err, expr)
+	}
+}
+
+func TestCache_Program(t *testing.T) {
+	c := NewCache("event")
+
+	p1, err := c.Program("Alert/default/webapp", 1, `event.severity == 'error'`)
+	require.NoError(t, err)
+	p2, err := c.Program("Alert/default/webapp", 1, `event.severity == 'error'`)
+	require.NoError(t, err)
+	require.Same(t, p1, p2)
+
+	// a new generation compiles the expressions again
+	p3, err := c.Program("Alert/default/webapp", 2, `event.severity == 'error'`)
+	require.NoError(t, err)
+	require.NotSame(t, p1, p3)
+
+	got, err := p3.EvalBool(map[string]interface{}{"event": testEvent{Severity: "error"}})
+	require.NoError(t, err)
+	require.True(t, got)
+
+	_, err = c.Program("Alert/default/webapp", 2, `message == 'error'`)
+	require.Error(t, err)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrDenied is returned when an address is not permitted by the policy.
+var ErrDenied = errors.New("egress denied")
+
+// DefaultBlocklist contains the link-local ranges and the cloud metadata
+// endpoints, which the providers are not permitted to contact by default.
+var DefaultBlocklist = []string{
+	"169.254.0.0/16",
+	"fe80::/10",
+	"100.100.100.200/32",
+	"fd00:ec2::254/128",
+}
+
+// Resolver looks up the IP addresses of a host.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Policy restricts the addresses the providers are permitted to contact.
+// A nil policy permits every address.
+type Policy struct {
+	hosts    []string
+	networks []*net.IPNet
+	blocked  []*net.IPNet
+	resolver Resolver
+
+	// parent is the policy the addresses must also be permitted by.
+	parent *Policy
+}
+
+// ParsePolicy returns a policy from a list of allowed hostnames and CIDRs,
+// and a list of blocked CIDRs.
+// A hostname starting with '*.' matches any of its subdomains, an IP
+// address without a prefix length matches that address only.
+// The blocked CIDRs take precedence over the allowed hostnames and CIDRs.
+// Empty lists result in a nil policy.
+func ParsePolicy(allow []string, block []string) (*Policy, error) {
+	p := &Policy{resolver: net.DefaultResolver}
+	for _, entry := range block {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.blocked = append(p.blocked, network)
+	}
+
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") || net.ParseIP(entry) != nil {
+			network, err := parseNetwork(entry)
+			if err != nil {
+				return nil, err
+			}
+			p.networks = append(p.networks, network)
+			continue
+		}
+
+		if strings.Contains(strings.TrimPrefix(entry, "*."), "*") {
+			return nil, fmt.Errorf("invalid hostname '%s': wildcards are only supported as the first label", entry)
+		}
+		p.hosts = append(p.hosts, strings.TrimSuffix(entry, "."))
+	}
+
+	if len(p.hosts) == 0 && len(p.networks) == 0 && len(p.blocked) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// Restrict returns a policy permitting the addresses permitted by both
+// the policy and the list of allowed hostnames and CIDRs, the policy is
+// returned as is when the list is empty.
+func (p *Policy) Restrict(allow []string) (*Policy, error) {
+	r, err := ParsePolicy(allow, nil)
+	if err != nil || r == nil {
+		return p, err
+	}
+	r.parent = p
+	if p != nil {
+		r.resolver = p.resolver
+	}
+	return r, nil
+}
+
+// Check returns an error wrapping ErrDenied if the host of the address
+// resolves to a blocked IP, or if an allowlist is set and the host is
+// not in it and does not resolve exclusively to IPs within the allowed
+// networks.
+func (p *Policy) Check(ctx context.Context, address string) error {
+	if p == nil {
+		return nil
+	}
+
+	host := hostname(address)
+	if host == "" {
+		return fmt.Errorf("%w: no host found in address", ErrDenied)
+	}
+
+	// an unresolvable host is permitted here, the dial function
+	// enforces the policy on the IPs it resolves at connection time
+	_, err := p.resolve(ctx, host, false)
+	return err
+}
+
+// DialContext returns a dial function that resolves the host once, checks
+// the resolved IPs against the policy and connects to those IPs only, so
+// that a DNS record changed after the check can't redirect the connection.
+func (p *Policy) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if p == nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := p.resolve(ctx, strings.TrimSuffix(strings.ToLower(host), "."), true)
+		if err != nil {
+			return nil, err
+		}
+
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+// resolve returns the permitted IPs of the host, when strict is false
+// a host allowed by name that can't be resolved is not an error.
+func (p *Policy) resolve(ctx context.Context, host string, strict bool) ([]net.IP, error) {
+	hostAllowed := true
+	for q := p; q != nil; q = q.parent {
+		if q.allowsHost(host) {
+			continue
+		}
+		if len(q.networks) == 0 {
+			return nil, fmt.Errorf("%w: host '%s' is not in the allowlist", ErrDenied, host)
+		}
+		hostAllowed = false
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil && !strict && hostAllowed {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve host '%s', error: %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("failed to resolve host '%s', no addresses found", host)
+	}
+	for q := p; q != nil; q = q.parent {
+		allowed := q.allowsHost(host)
+		for _, ip := range ips {
+			if contains(q.blocked, ip) {
+				return nil, fmt.Errorf("%w: host '%s' resolves to %s which is blocked", ErrDenied, host, ip)
+			}
+			if !allowed && !contains(q.networks, ip) {
+				return nil, fmt.Errorf("%w: host '%s' resolves to %s which is not in the allowlist", ErrDenied, host, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// allowsHost returns true if the policy has no allowlist or the host is in it.
+func (p *Policy) allowsHost(host string) bool {
+	if len(p.hosts) == 0 && len(p.networks) == 0 {
+		return true
+	}
+	for _, h := range p.hosts {
+		if matchHost(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR, or an IP address as a single address network.
+func parseNetwork(entry string) (*net.IPNet, error) {
+	if ip := net.ParseIP(entry); ip != nil {
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(entry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR '%s': %w", entry, err)
+	}
+	return network, nil
+}
+
+// hostname returns the lower case host of a URL, or of a bare 'host[:port]'.
+func hostname(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	}
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		return strings.TrimSuffix(strings.ToLower(h), ".")
+	}
+	return strings.TrimSuffix(strings.ToLower(address), ".")
+}
+
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host")
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]string{"", " "}, nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	p, err = ParsePolicy([]string{"Hooks.Slack.com", "*.example.com", "10.0.0.0/8", "192.168.1.5", "fd00::/8"}, DefaultBlocklist)
+	require.NoError(t, err)
+	require.Equal(t, []string{"hooks.slack.com", "*.example.com"}, p.hosts)
+	require.Len(t, p.networks, 3)
+	require.Len(t, p.blocked, len(DefaultBlocklist))
+
+	p, err = ParsePolicy(nil, DefaultBlocklist)
+	require.NoError(t, err)
+	require.Empty(t, p.hosts)
+	require.Empty(t, p.networks)
+
+	_, err = ParsePolicy([]string{"10.0.0.0/33"}, nil)
+	require.Error(t, err)
+
+	_, err = ParsePolicy([]string{"hooks.*.com"}, nil)
+	require.Error(t, err)
+
+	_, err = ParsePolicy(nil, []string{"metadata.internal"})
+	require.Error(t, err)
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p, err := ParsePolicy([]string{"hooks.slack.com", "*.example.com", "10.0.0.0/8", "192.168.1.5"}, DefaultBlocklist)
+	require.NoError(t, err)
+	p.resolver = fakeResolver{
+		"internal.corp":      {"10.1.2.3"},
+		"mixed.corp":         {"10.1.2.3", "8.8.8.8"},
+		"external.hooks":     {"8.8.8.8"},
+		"example.com":        {"93.184.216.34"},
+		"notexample.com":     {"93.184.216.35"},
+		"hooks.slack.com":    {"54.1.2.3"},
+		"rebind.example.com": {"169.254.169.254"},
+	}
+
+	tests := []struct {
+		address string
+		denied  bool
+		err     bool
+	}{
+		{address: "https://hooks.slack.com/services/x"},
+		{address: "https://HOOKS.slack.com./services/x"},
+		{address: "https://chat.example.com:8443/hook"},
+		{address: "https://example.com/hook", denied: true},
+		{address: "https://notexample.com/hook", denied: true},
+		{address: "http://10.20.30.40/hook"},
+		{address: "http://192.168.1.5:8080"},
+		{address: "http://192.168.1.6:8080", denied: true},
+		{address: "https://internal.corp/hook"},
+		{address: "https://mixed.corp/hook", denied: true},
+		{address: "https://external.hooks/hook", denied: true},
+		{address: "https://unknown.host/hook", err: true},
+		{address: "proxy.example.com:3128"},
+		{address: "", denied: true},
+		{address: "https://rebind.example.com/hook", denied: true},
+		{address: "https://unknown.example.com/hook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := p.Check(context.TODO(), tt.address)
+			switch {
+			case tt.denied:
+				require.True(t, errors.Is(err, ErrDenied), "expected denied, got %v", err)
+			case tt.err:
+				require.Error(t, err)
+				require.False(t, errors.Is(err, ErrDenied))
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Restrict(t *testing.T) {
+	p, err := ParsePolicy([]string{"*.example.com", "10.0.0.0/8"}, DefaultBlocklist)
+	require.NoError(t, err)
+	p.resolver = fakeResolver{
+		"hooks.example.com":  {"93.184.216.34"},
+		"other.example.com":  {"93.184.216.35"},
+		"internal.corp":      {"10.1.2.3"},
+		"rebind.example.com": {"169.254.169.254"},
+	}
+
+	r, err := p.Restrict(nil)
+	require.NoError(t, err)
+	require.Same(t, p, r)
+
+	r, err = p.Restrict([]string{"hooks.example.com", "rebind.example.com", "internal.corp", "8.8.8.8"})
+	require.NoError(t, err)
+	require.NoError(t, r.Check(context.TODO(), "https://hooks.example.com/hook"))
+	require.NoError(t, r.Check(context.TODO(), "https://internal.corp/hook"))
+	require.True(t, errors.Is(r.Check(context.TODO(), "https://other.example.com/hook"), ErrDenied))
+	require.True(t, errors.Is(r.Check(context.TODO(), "https://rebind.example.com/hook"), ErrDenied))
+	require.True(t, errors.Is(r.Check(context.TODO(), "http://8.8.8.8/hook"), ErrDenied))
+
+	var nilPolicy *Policy
+	r, err = nilPolicy.Restrict([]string{"hooks.example.com"})
+	require.NoError(t, err)
+	require.NoError(t, r.Check(context.TODO(), "https://hooks.example.com/hook"))
+	require.True(t, errors.Is(r.Check(context.TODO(), "https://other.example.com/hook"), ErrDenied))
+
+	_, err = p.Restrict([]string{"hooks.*.com"})
+	require.Error(t, err)
+}
+
+func TestPolicy_CheckBlocklist(t *testing.T) {
+	p, err := ParsePolicy(nil, DefaultBlocklist)
+	require.NoError(t, err)
+	p.resolver = fakeResolver{
+		"hooks.slack.com": {"54.1.2.3"},
+		"metadata.local":  {"10.0.0.1", "169.254.169.254"},
+	}
+
+	require.NoError(t, p.Check(context.TODO(), "https://hooks.slack.com/services/x"))
+	require.NoError(t, p.Check(context.TODO(), "http://10.0.0.1:8080"))
+	require.NoError(t, p.Check(context.TODO(), "https://unknown.host/hook"))
+	require.True(t, errors.Is(p.Check(context.TODO(), "http://169.254.169.254/latest"), ErrDenied))
+	require.True(t, errors.Is(p.Check(context.TODO(), "http://[fe80::1]/"), ErrDenied))
+	require.True(t, errors.Is(p.Check(context.TODO(), "https://metadata.local/"), ErrDenied))
+}
+
+func TestPolicy_DialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	p, err := ParsePolicy(nil, DefaultBlocklist)
+	require.NoError(t, err)
+	resolver := fakeResolver{
+		"webhook.local":  {"127.0.0.1"},
+		"metadata.local": {"169.254.169.254"},
+	}
+	p.resolver = resolver
+	dial := p.DialContext(&net.Dialer{Timeout: time.Second})
+
+	conn, err := dial(context.TODO(), "tcp", net.JoinHostPort("webhook.local", port))
+	require.NoError(t, err)
+	require.Equal(t, ln.Addr().String(), conn.RemoteAddr().String())
+	conn.Close()
+
+	// the record changed after the check doesn't bypass the policy
+	resolver["webhook.local"] = []string{"169.254.169.254"}
+	_, err = dial(context.TODO(), "tcp", net.JoinHostPort("webhook.local", port))
+	require.True(t, errors.Is(err, ErrDenied))
+
+	_, err = dial(context.TODO(), "tcp", net.JoinHostPort("unknown.local", port))
+	require.Error(t, err)
+}
+
+func TestPolicy_CheckNil(t *testing.T) {
+	var p *Policy
+	require.NoError(t, p.Check(context.TODO(), "http://169.254.169.254"))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exclusions loads the exclusion lists of the alerts from ConfigMaps,
+// so that the noisy message patterns can be curated in one place.
+package exclusions
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// Library loads the exclusion lists from the ConfigMaps, the compiled
+// expressions are cached until the ConfigMaps change.
+type Library struct {
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+type entry struct {
+	resourceVersion string
+	expressions     []*regexp.Regexp
+}
+
+// NewLibrary returns an empty exclusion list library.
+func NewLibrary() *Library {
+	return &Library{cache: make(map[string]entry)}
+}
+
+// Get returns the expressions of the referenced exclusion list, the
+// namespace is used when the reference has none.
+func (l *Library) Get(ctx context.Context, kubeClient client.Client, ref v1beta1.ExclusionListReference, namespace string) ([]*regexp.Regexp, error) {
+	cm, err := getConfigMap(ctx, kubeClient, ref, namespace)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s/%s/%s", cm.Namespace, cm.Name, ref.Key)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.cache[key]
+	if !ok || e.resourceVersion != cm.ResourceVersion {
+		expressions, err := parse(cm, ref.Key)
+		if err != nil {
+			delete(l.cache, key)
+			return nil, err
+		}
+		e = entry{resourceVersion: cm.ResourceVersion, expressions: expressions}
+		l.cache[key] = e
+	}
+	return e.expressions, nil
+}
+
+// Load returns the expressions of the referenced exclusion list without caching them.
+func Load(ctx context.Context, kubeClient client.Client, ref v1beta1.ExclusionListReference, namespace string) ([]*regexp.Regexp, error) {
+	cm, err := getConfigMap(ctx, kubeClient, ref, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return parse(cm, ref.Key)
+}
+
+// Match returns true if the message matches at least one of the expressions.
+func Match(expressions []*regexp.Regexp, message string) bool {
+	for _, r := range expressions {
+		if r.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+func getConfigMap(ctx context.Context, kubeClient client.Client, ref v1beta1.ExclusionListReference, namespace string) (*corev1.ConfigMap, error) {
+	name := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if name.Namespace == "" {
+		name.Namespace = namespace
+	}
+
+	var cm corev1.ConfigMap
+	if err := kubeClient.Get(ctx, name, &cm); err != nil {
+		return nil, fmt.Errorf("failed to get exclusion list ConfigMap %s, error: %w", name, err)
+	}
+	return &cm, nil
+}
+
+// parse compiles the expressions of the ConfigMap key, one per line,
+// the empty lines and the lines starting with '#' are skipped.
+func parse(cm *corev1.ConfigMap, key string) ([]*regexp.Regexp, error) {
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("exclusion list '%s' not found in ConfigMap %s/%s", key, cm.Namespace, cm.Name)
+	}
+
+	var expressions []*regexp.Regexp
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression on line %d of exclusion list '%s' of ConfigMap %s/%s: %w",
+				i+1, key, cm.Namespace, cm.Name, err)
+		}
+		expressions = append(expressions, r)
+	}
+	return expressions, nil
+}
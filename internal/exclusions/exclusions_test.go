@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exclusions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestLibrary_Get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "noise", Namespace: "flux-system"},
+		Data: map[string]string{
+			"patterns": "# transient API errors\n^waiting.*socket\n\n  etcdserver: request timed out  \n",
+		},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	ctx := context.Background()
+
+	library := NewLibrary()
+	ref := v1beta1.ExclusionListReference{Name: "noise", Namespace: "flux-system", Key: "patterns"}
+	expressions, err := library.Get(ctx, kubeClient, ref, "apps")
+	require.NoError(t, err)
+	require.Len(t, expressions, 2)
+	require.True(t, Match(expressions, "waiting for the socket"))
+	require.True(t, Match(expressions, "apply failed: etcdserver: request timed out"))
+	require.False(t, Match(expressions, "health check failed"))
+
+	// the changes of the ConfigMap are applied to the next lookups
+	cm.Data["patterns"] = "health check"
+	require.NoError(t, kubeClient.Update(ctx, cm))
+	expressions, err = library.Get(ctx, kubeClient, ref, "apps")
+	require.NoError(t, err)
+	require.True(t, Match(expressions, "health check failed"))
+	require.False(t, Match(expressions, "waiting for the socket"))
+
+	cm.Data["patterns"] = "[invalid"
+	require.NoError(t, kubeClient.Update(ctx, cm))
+	_, err = library.Get(ctx, kubeClient, ref, "apps")
+	require.Error(t, err)
+
+	_, err = Load(ctx, kubeClient, v1beta1.ExclusionListReference{Name: "noise", Key: "missing"}, "flux-system")
+	require.Error(t, err)
+	_, err = Load(ctx, kubeClient, v1beta1.ExclusionListReference{Name: "noise", Key: "patterns"}, "apps")
+	require.Error(t, err)
+}
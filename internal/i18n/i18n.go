@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package i18n translates the messages written by the controller itself,
+// e.g. the heartbeat and flapping notifications, to the provider locale.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultLocale is used by the providers without a locale.
+const DefaultLocale = "en"
+
+// Key identifies a built-in message, the messages are fmt formats.
+type Key string
+
+const (
+	// Flapping formats the kind, name, changes and window of a flapping object.
+	Flapping Key = "flapping"
+
+	// Heartbeat formats the heartbeat interval.
+	Heartbeat Key = "heartbeat"
+
+	// Sampling formats the sampling rate of an alert.
+	Sampling Key = "sampling"
+
+	// QueueOverflow formats the number of discarded notifications and the interval.
+	QueueOverflow Key = "queueOverflow"
+
+	// QueueRecovered is sent once the dispatch queue stops discarding notifications.
+	QueueRecovered Key = "queueRecovered"
+
+	// DeliveryFailing formats the number of failed notifications, the provider and the interval.
+	DeliveryFailing Key = "deliveryFailing"
+
+	// DeliveryRecovered formats the provider the notifications are delivered to again.
+	DeliveryRecovered Key = "deliveryRecovered"
+
+	// StatusBoardTitle formats the time the status board was updated.
+	StatusBoardTitle Key = "statusBoardTitle"
+
+	// StatusBoardHealthy is listed when no object is failing.
+	StatusBoardHealthy Key = "statusBoardHealthy"
+
+	// StatusBoardFailing formats the number of failing objects.
+	StatusBoardFailing Key = "statusBoardFailing"
+
+	// StatusBoardDeployments heads the recent deployments.
+	StatusBoardDeployments Key = "statusBoardDeployments"
+
+	// StatusBoardDeployment formats the object, revision and time of a deployment.
+	StatusBoardDeployment Key = "statusBoardDeployment"
+)
+
+var catalogs = map[string]map[Key]string{
+	"en": {
+		Flapping:               "%s/%s is flapping, its status changed %d times within %s, the notifications are suppressed until it is stable",
+		Heartbeat:              "Heartbeat, the next one is expected in %s",
+		Sampling:               "1 of %d info events",
+		QueueOverflow:          "%d notifications were discarded in the last %s, the dispatch queue is full",
+		QueueRecovered:         "The dispatch queue no longer discards notifications",
+		DeliveryFailing:        "%d notifications to the provider %s failed in the last %s",
+		DeliveryRecovered:      "The notifications to the provider %s are delivered again",
+		StatusBoardTitle:       "*Flux status* (updated %s)",
+		StatusBoardHealthy:     "No failing objects",
+		StatusBoardFailing:     "Failing objects (%d)",
+		StatusBoardDeployments: "Recent deployments",
+		StatusBoardDeployment:  "%s %s at %s",
+	},
+	"de": {
+		Flapping:               "%s/%s ist instabil, der Status hat sich %d Mal innerhalb von %s geändert, die Benachrichtigungen werden unterdrückt, bis es stabil ist",
+		Heartbeat:              "Heartbeat, der nächste wird in %s erwartet",
+		Sampling:               "1 von %d Info-Ereignissen",
+		QueueOverflow:          "%d Benachrichtigungen wurden in den letzten %s verworfen, die Versandwarteschlange ist voll",
+		QueueRecovered:         "Die Versandwarteschlange verwirft keine Benachrichtigungen mehr",
+		DeliveryFailing:        "%d Benachrichtigungen an den Provider %s sind in den letzten %s fehlgeschlagen",
+		DeliveryRecovered:      "Die Benachrichtigungen an den Provider %s werden wieder zugestellt",
+		StatusBoardTitle:       "*Flux-Status* (aktualisiert %s)",
+		StatusBoardHealthy:     "Keine fehlerhaften Objekte",
+		StatusBoardFailing:     "Fehlerhafte Objekte (%d)",
+		StatusBoardDeployments: "Letzte Deployments",
+		StatusBoardDeployment:  "%s %s am %s",
+	},
+	"es": {
+		Flapping:               "%s/%s es inestable, su estado cambió %d veces en %s, las notificaciones se suprimen hasta que se estabilice",
+		Heartbeat:              "Heartbeat, el siguiente se espera en %s",
+		Sampling:               "1 de cada %d eventos info",
+		QueueOverflow:          "%d notificaciones fueron descartadas en los últimos %s, la cola de envío está llena",
+		QueueRecovered:         "La cola de envío ya no descarta notificaciones",
+		DeliveryFailing:        "%d notificaciones al proveedor %s fallaron en los últimos %s",
+		DeliveryRecovered:      "Las notificaciones al proveedor %s se entregan de nuevo",
+		StatusBoardTitle:       "*Estado de Flux* (actualizado %s)",
+		StatusBoardHealthy:     "Ningún objeto con errores",
+		StatusBoardFailing:     "Objetos con errores (%d)",
+		StatusBoardDeployments: "Despliegues recientes",
+		StatusBoardDeployment:  "%s %s el %s",
+	},
+	"fr": {
+		Flapping:               "%s/%s est instable, son état a changé %d fois en %s, les notifications sont suspendues jusqu'à ce qu'il soit stable",
+		Heartbeat:              "Heartbeat, le prochain est attendu dans %s",
+		Sampling:               "1 événement info sur %d",
+		QueueOverflow:          "%d notifications ont été ignorées au cours des dernières %s, la file d'envoi est pleine",
+		QueueRecovered:         "La file d'envoi n'ignore plus de notifications",
+		DeliveryFailing:        "%d notifications au fournisseur %s ont échoué au cours des dernières %s",
+		DeliveryRecovered:      "Les notifications au fournisseur %s sont de nouveau délivrées",
+		StatusBoardTitle:       "*Statut Flux* (mis à jour %s)",
+		StatusBoardHealthy:     "Aucun objet en échec",
+		StatusBoardFailing:     "Objets en échec (%d)",
+		StatusBoardDeployments: "Déploiements récents",
+		StatusBoardDeployment:  "%s %s le %s",
+	},
+}
+
+// Supported returns true if the locale has a catalog, the empty locale
+// is the default one.
+func Supported(locale string) bool {
+	if locale == "" {
+		return true
+	}
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Locales returns the supported locales, sorted.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Sprintf formats the message in the locale, the messages of the unsupported
+// locales are formatted in the default locale.
+func Sprintf(locale string, key Key, args ...interface{}) string {
+	format, ok := catalogs[locale][key]
+	if !ok {
+		format = catalogs[DefaultLocale][key]
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogs(t *testing.T) {
+	// every locale translates every message with the same verbs
+	for locale, catalog := range catalogs {
+		require.Len(t, catalog, len(catalogs[DefaultLocale]), locale)
+		for key, format := range catalogs[DefaultLocale] {
+			translated, ok := catalog[key]
+			require.True(t, ok, "%s: %s", locale, key)
+			require.Equal(t, verbs(format), verbs(translated), "%s: %s", locale, key)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	require.Equal(t, "Heartbeat, the next one is expected in 10m0s", Sprintf("", Heartbeat, "10m0s"))
+	require.Equal(t, "Heartbeat, der nächste wird in 10m0s erwartet", Sprintf("de", Heartbeat, "10m0s"))
+	require.Equal(t, "1 of 5 info events", Sprintf("xx", Sampling, 5))
+
+	require.True(t, Supported(""))
+	require.True(t, Supported("fr"))
+	require.False(t, Supported("xx"))
+	require.Equal(t, []string{"de", "en", "es", "fr"}, Locales())
+}
+
+func verbs(format string) []string {
+	var found []string
+	for i := 0; i < len(format)-1; i++ {
+		if format[i] == '%' {
+			found = append(found, format[i:i+2])
+			i++
+		}
+	}
+	return found
+}
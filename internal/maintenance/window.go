@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// recurrence is a parsed recurring window.
+type recurrence struct {
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+func parse(w v1beta1.RecurringWindow) (*recurrence, error) {
+	location := time.UTC
+	if w.TimeZone != "" {
+		loc, err := time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone '%s': %w", w.TimeZone, err)
+		}
+		location = loc
+	}
+
+	schedule, err := cron.ParseStandard(w.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule '%s': %w", w.Schedule, err)
+	}
+
+	if w.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("invalid duration '%s': must be greater than zero", w.Duration.Duration)
+	}
+
+	return &recurrence{
+		schedule: schedule,
+		duration: w.Duration.Duration,
+		location: location,
+	}, nil
+}
+
+// lastStart returns the start of the occurrence in effect at the given time, if any.
+func (r *recurrence) lastStart(now time.Time) (time.Time, bool) {
+	start := r.schedule.Next(now.In(r.location).Add(-r.duration))
+	return start, !start.After(now)
+}
+
+// Validate checks the schedule, duration and time zone of the recurring windows.
+func Validate(window v1beta1.MaintenanceWindow) error {
+	for i, w := range window.Spec.Windows {
+		if _, err := parse(w); err != nil {
+			return fmt.Errorf("windows[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether at least one of the recurring windows is in effect at the given time.
+// A suspended maintenance window is never active.
+func IsActive(window v1beta1.MaintenanceWindow, now time.Time) (bool, error) {
+	if window.Spec.Suspend {
+		return false, nil
+	}
+
+	for _, w := range window.Spec.Windows {
+		r, err := parse(w)
+		if err != nil {
+			return false, err
+		}
+		if _, active := r.lastStart(now); active {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NextTransition returns the earliest time after now at which
+// one of the recurring windows starts or ends.
+func NextTransition(window v1beta1.MaintenanceWindow, now time.Time) (time.Time, error) {
+	var next time.Time
+	for _, w := range window.Spec.Windows {
+		r, err := parse(w)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		t := r.schedule.Next(now.In(r.location))
+		if start, active := r.lastStart(now); active {
+			t = start.Add(r.duration)
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next, nil
+}
+
+// ActiveWindow returns the first maintenance window in the namespace that is
+// matched by the selector and is in effect at the given time, or nil if there is none.
+func ActiveWindow(ctx context.Context, kubeClient client.Client, namespace string,
+	selector *metav1.LabelSelector, now time.Time) (*v1beta1.MaintenanceWindow, error) {
+	if selector == nil {
+		return nil, nil
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window selector: %w", err)
+	}
+
+	var windows v1beta1.MaintenanceWindowList
+	if err := kubeClient.List(ctx, &windows, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: s}); err != nil {
+		return nil, fmt.Errorf("unable to list maintenance windows: %w", err)
+	}
+
+	for i := range windows.Items {
+		active, err := IsActive(windows.Items[i], now)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window '%s': %w", windows.Items[i].Name, err)
+		}
+		if active {
+			return &windows.Items[i], nil
+		}
+	}
+	return nil, nil
+}
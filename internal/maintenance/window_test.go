@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func fridayFreeze() v1beta1.MaintenanceWindow {
+	return v1beta1.MaintenanceWindow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "friday-freeze",
+			Namespace: "default",
+			Labels:    map[string]string{"freeze": "true"},
+		},
+		Spec: v1beta1.MaintenanceWindowSpec{
+			Windows: []v1beta1.RecurringWindow{
+				{
+					// Friday 22:00 to Saturday 02:00
+					Schedule: "0 22 * * FRI",
+					Duration: metav1.Duration{Duration: 4 * time.Hour},
+				},
+			},
+		},
+	}
+}
+
+func TestIsActive(t *testing.T) {
+	window := fridayFreeze()
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		active bool
+	}{
+		{"before", time.Date(2021, 5, 7, 21, 59, 0, 0, time.UTC), false},
+		{"start", time.Date(2021, 5, 7, 22, 0, 0, 0, time.UTC), true},
+		{"after midnight", time.Date(2021, 5, 8, 1, 30, 0, 0, time.UTC), true},
+		{"end", time.Date(2021, 5, 8, 2, 0, 0, 0, time.UTC), false},
+		{"other day", time.Date(2021, 5, 5, 23, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, err := IsActive(window, tt.now)
+			require.NoError(t, err)
+			require.Equal(t, tt.active, active)
+		})
+	}
+
+	window.Spec.Suspend = true
+	active, err := IsActive(window, time.Date(2021, 5, 7, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.False(t, active)
+}
+
+func TestIsActive_TimeZone(t *testing.T) {
+	window := fridayFreeze()
+	window.Spec.Windows[0].TimeZone = "Europe/Berlin"
+
+	// 22:00 in Berlin is 20:00 UTC during summer time
+	active, err := IsActive(window, time.Date(2021, 5, 7, 20, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.True(t, active)
+}
+
+func TestNextTransition(t *testing.T) {
+	window := fridayFreeze()
+
+	next, err := NextTransition(window, time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.True(t, next.Equal(time.Date(2021, 5, 7, 22, 0, 0, 0, time.UTC)))
+
+	next, err = NextTransition(window, time.Date(2021, 5, 7, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.True(t, next.Equal(time.Date(2021, 5, 8, 2, 0, 0, 0, time.UTC)))
+}
+
+func TestValidate(t *testing.T) {
+	window := fridayFreeze()
+	require.NoError(t, Validate(window))
+
+	window.Spec.Windows[0].Schedule = "not a schedule"
+	require.Error(t, Validate(window))
+
+	window = fridayFreeze()
+	window.Spec.Windows[0].TimeZone = "Mars/Olympus"
+	require.Error(t, Validate(window))
+
+	window = fridayFreeze()
+	window.Spec.Windows[0].Duration = metav1.Duration{}
+	require.Error(t, Validate(window))
+}
+
+func TestActiveWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+	window := fridayFreeze()
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&window).Build()
+
+	during := time.Date(2021, 5, 7, 23, 0, 0, 0, time.UTC)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"freeze": "true"}}
+
+	active, err := ActiveWindow(context.TODO(), kubeClient, "default", selector, during)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	require.Equal(t, "friday-freeze", active.Name)
+
+	active, err = ActiveWindow(context.TODO(), kubeClient, "other", selector, during)
+	require.NoError(t, err)
+	require.Nil(t, active)
+
+	active, err = ActiveWindow(context.TODO(), kubeClient, "default", nil, during)
+	require.NoError(t, err)
+	require.Nil(t, active)
+
+	other := &metav1.LabelSelector{MatchLabels: map[string]string{"freeze": "false"}}
+	active, err = ActiveWindow(context.TODO(), kubeClient, "default", other, during)
+	require.NoError(t, err)
+	require.Nil(t, active)
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	archiveS3    = "s3"
+	archiveGCS   = "gcs"
+	archiveAzure = "azure"
+
+	gcsHost             = "storage.googleapis.com"
+	azureBlobHostSuffix = ".blob.core.windows.net"
+	azureStorageVersion = "2021-08-06"
+)
+
+// Archive is an implementation of the notification Interface that writes the
+// events as gzip compressed NDJSON objects to an S3, GCS or Azure Blob bucket.
+type Archive struct {
+	// Endpoint is the address of the bucket or container, e.g.
+	// 'https://s3.eu-west-1.amazonaws.com/audit'.
+	Endpoint string
+
+	// Prefix is prepended to the object names, e.g. 'flux/production'.
+	Prefix string
+
+	// Storage is either 's3', 'gcs' or 'azure'.
+	Storage  string
+	Region   string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Token is the GCS access token or the Azure SAS token, the token
+	// of the controller workload identity is used when empty.
+	Token string
+
+	// Credentials sign the S3 requests.
+	Credentials AWSCredentials
+
+	ArchiveOptions
+	requestConfig
+}
+
+// ArchiveOptions configures the format and the names of the archive objects,
+// the zero value writes gzip compressed NDJSON objects named after the date.
+type ArchiveOptions struct {
+	// Format is either 'ndjson' or 'parquet'.
+	Format string
+
+	// Layout is either 'date' or 'hive'.
+	Layout string
+
+	// Cluster is the cluster partition of the hive layout, if any.
+	Cluster string
+}
+
+// NewArchiveOptions returns the archive options of a provider.
+func NewArchiveOptions(spec *v1beta1.ProviderArchive) ArchiveOptions {
+	if spec == nil {
+		return ArchiveOptions{}
+	}
+	return ArchiveOptions{Format: spec.Format, Layout: spec.Layout, Cluster: spec.Cluster}
+}
+
+// objectName returns the name of an object written at the given time,
+// e.g. '<prefix>/2021/07/01/20210701T120000Z-<id>.ndjson.gz' or
+// '<prefix>/dt=2021-07-01/cluster=prod/20210701T120000Z-<id>.parquet'.
+func (o ArchiveOptions) objectName(prefix string, now time.Time) string {
+	dir := now.Format("2006/01/02")
+	if o.Layout == v1beta1.HiveArchiveLayout {
+		dir = "dt=" + now.Format("2006-01-02")
+		if o.Cluster != "" {
+			dir = path.Join(dir, "cluster="+o.Cluster)
+		}
+	}
+	ext := "ndjson.gz"
+	if o.Format == v1beta1.ParquetArchiveFormat {
+		ext = "parquet"
+	}
+	return path.Join(prefix, dir, fmt.Sprintf("%s-%s.%s", now.Format(awsDateFormat), NewDeliveryID()[:8], ext))
+}
+
+// NewArchive returns a notifier for the bucket address formatted as
+// 'https://<endpoint>/<bucket>[/<prefix>]', the storage is selected by
+// the endpoint host: 'storage.googleapis.com' for GCS,
+// '<account>.blob.core.windows.net' for Azure Blob and S3 otherwise.
+// The region of the S3 endpoints that don't follow the AWS naming,
+// e.g. MinIO, is set with the 'region' query parameter.
+func NewArchive(address, proxyURL, token string, credentials AWSCredentials, certPool *x509.CertPool) (*Archive, error) {
+	u, err := url.ParseRequestURI(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive address %s: %w", address, err)
+	}
+	segments := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if segments[0] == "" {
+		return nil, fmt.Errorf("invalid archive address %s: no bucket", address)
+	}
+
+	a := &Archive{
+		Endpoint: fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, segments[0]),
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Token:    token,
+	}
+	if len(segments) > 1 {
+		a.Prefix = strings.Trim(segments[1], "/")
+	}
+
+	host := u.Hostname()
+	switch {
+	case host == gcsHost:
+		a.Storage = archiveGCS
+	case strings.HasSuffix(host, azureBlobHostSuffix):
+		a.Storage = archiveAzure
+	default:
+		a.Storage = archiveS3
+		if _, a.Region = parseAWSEndpoint(host, archiveS3); a.Region == "" && host == "s3.amazonaws.com" {
+			a.Region = "us-east-1"
+		}
+		if r := u.Query().Get("region"); r != "" {
+			a.Region = r
+		}
+		if a.Region == "" {
+			return nil, fmt.Errorf("invalid archive address %s: no region", address)
+		}
+		if a.Credentials, err = credentials.resolve(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Post writes the event to an object of its own, the event server
+// batches the events of the archive providers and calls Write instead.
+func (a *Archive) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	_, err := a.Write([]events.Event{event}, time.Now())
+	return err
+}
+
+// Write writes the events to an object named after the time and the layout, e.g.
+// '<prefix>/2021/07/01/20210701T120000Z-<id>.ndjson.gz', and returns its name.
+func (a *Archive) Write(batch []events.Event, now time.Time) (string, error) {
+	var body []byte
+	contentType := "application/gzip"
+	if a.Format == v1beta1.ParquetArchiveFormat {
+		var err error
+		if body, err = encodeParquet(batch); err != nil {
+			return "", fmt.Errorf("encoding notification payload failed: %w", err)
+		}
+		contentType = "application/vnd.apache.parquet"
+	} else {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		encoder := json.NewEncoder(gz)
+		for _, event := range batch {
+			if err := encoder.Encode(event); err != nil {
+				return "", fmt.Errorf("marshalling notification payload failed: %w", err)
+			}
+		}
+		if err := gz.Close(); err != nil {
+			return "", fmt.Errorf("compressing notification payload failed: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	now = now.UTC()
+	name := a.objectName(a.Prefix, now)
+	if err := a.put(name, contentType, body, now); err != nil {
+		return "", fmt.Errorf("failed to write the archive object %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// put uploads the object with the authentication of the storage.
+func (a *Archive) put(name, contentType string, body []byte, now time.Time) error {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	address := a.Endpoint + "/" + strings.Join(segments, "/")
+
+	var auth requestOptFunc
+	switch a.Storage {
+	case archiveS3:
+		auth = func(req *retryablehttp.Request) {
+			req.Header.Set("X-Amz-Content-Sha256", sha256Hex(body))
+			signAWSRequest(req.Request, body, a.Credentials, a.Region, archiveS3, now)
+		}
+	case archiveGCS:
+		token := a.Token
+		if token == "" {
+			var err error
+			if token, err = gcpIdentity.get(now); err != nil {
+				return err
+			}
+		}
+		auth = func(req *retryablehttp.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	case archiveAzure:
+		var token string
+		if a.Token != "" {
+			address += "?" + strings.TrimPrefix(a.Token, "?")
+		} else {
+			var err error
+			if token, err = azureIdentity.get(now); err != nil {
+				return err
+			}
+		}
+		auth = func(req *retryablehttp.Request) {
+			req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+			req.Header.Set("X-Ms-Version", azureStorageVersion)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+	}
+
+	withContentType := func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return a.transport.sendRequest(http.MethodPut, address, a.ProxyURL, a.CertPool, body, nil, withContentType, auth, a.withCapture())
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveServer records the paths and the decoded events of the uploaded objects.
+func archiveServer(t *testing.T, check func(r *http.Request, body []byte)) (*httptest.Server, map[string][]events.Event) {
+	objects := make(map[string][]events.Event)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "application/gzip", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		check(r, body)
+
+		gz, err := gzip.NewReader(strings.NewReader(string(body)))
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var e events.Event
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+			objects[r.URL.Path] = append(objects[r.URL.Path], e)
+		}
+		require.NoError(t, scanner.Err())
+	}))
+	t.Cleanup(ts.Close)
+	return ts, objects
+}
+
+func TestArchive_WriteS3(t *testing.T) {
+	ts, objects := archiveServer(t, func(r *http.Request, body []byte) {
+		require.Equal(t, sha256Hex(body), r.Header.Get("X-Amz-Content-Sha256"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20210701/eu-west-1/s3/aws4_request"))
+	})
+
+	archive, err := NewArchive(ts.URL+"/audit/flux/production?region=eu-west-1", "", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Equal(t, archiveS3, archive.Storage)
+	require.Equal(t, ts.URL+"/audit", archive.Endpoint)
+	require.Equal(t, "flux/production", archive.Prefix)
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	name, err := archive.Write([]events.Event{testEvent(), testEvent()}, now)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(name, "flux/production/2021/07/01/20210701T120000Z-"))
+	require.True(t, strings.HasSuffix(name, ".ndjson.gz"))
+	require.Len(t, objects["/audit/"+name], 2)
+	require.Equal(t, "webapp", objects["/audit/"+name][0].InvolvedObject.Name)
+}
+
+func TestArchive_WriteGCS(t *testing.T) {
+	ts, objects := archiveServer(t, func(r *http.Request, body []byte) {
+		require.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+	})
+
+	archive, err := NewArchive("https://storage.googleapis.com/audit", "", "ya29.token", AWSCredentials{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, archiveGCS, archive.Storage)
+	archive.Endpoint = ts.URL + "/audit"
+
+	require.NoError(t, archive.Post(testEvent()))
+	require.Len(t, objects, 1)
+}
+
+func TestArchive_WriteAzure(t *testing.T) {
+	ts, objects := archiveServer(t, func(r *http.Request, body []byte) {
+		require.Equal(t, "BlockBlob", r.Header.Get("X-Ms-Blob-Type"))
+		require.Equal(t, "sig", r.URL.Query().Get("sv"))
+		require.Empty(t, r.Header.Get("Authorization"))
+	})
+
+	archive, err := NewArchive("https://audit.blob.core.windows.net/flux", "", "?sv=sig", AWSCredentials{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, archiveAzure, archive.Storage)
+	archive.Endpoint = ts.URL + "/flux"
+
+	require.NoError(t, archive.Post(testEvent()))
+	require.Len(t, objects, 1)
+}
+
+func TestArchive_WriteFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	archive, err := NewArchive(ts.URL+"/audit?region=eu-west-1", "", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Error(t, archive.Post(testEvent()))
+}
+
+func TestArchiveOptions_objectName(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	name := ArchiveOptions{}.objectName("flux/prod", now)
+	require.Regexp(t, `^flux/prod/2021/07/01/20210701T120000Z-[0-9a-f]{8}\.ndjson\.gz$`, name)
+
+	name = ArchiveOptions{Format: "parquet", Layout: "hive", Cluster: "prod"}.objectName("flux", now)
+	require.Regexp(t, `^flux/dt=2021-07-01/cluster=prod/20210701T120000Z-[0-9a-f]{8}\.parquet$`, name)
+
+	name = ArchiveOptions{Layout: "hive"}.objectName("", now)
+	require.Regexp(t, `^dt=2021-07-01/20210701T120000Z-[0-9a-f]{8}\.ndjson\.gz$`, name)
+}
+
+func TestArchive_WriteParquet(t *testing.T) {
+	var uploaded []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/vnd.apache.parquet", r.Header.Get("Content-Type"))
+		require.Contains(t, r.URL.Path, "/audit/flux/dt=2021-07-01/cluster=prod/")
+		uploaded, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	a, err := NewArchive(ts.URL+"/audit/flux?region=eu-west-1", "", "",
+		AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil)
+	require.NoError(t, err)
+	a.ArchiveOptions = ArchiveOptions{Format: "parquet", Layout: "hive", Cluster: "prod"}
+
+	_, err = a.Write([]events.Event{testEvent()}, time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, "PAR1", string(uploaded[:4]))
+}
+
+func TestNewArchive(t *testing.T) {
+	archive, err := NewArchive("https://s3.eu-central-1.amazonaws.com/audit", "", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Equal(t, "eu-central-1", archive.Region)
+	require.Empty(t, archive.Prefix)
+
+	_, err = NewArchive("https://s3.eu-central-1.amazonaws.com/", "", "", testAWSCredentials, nil)
+	require.Error(t, err)
+	_, err = NewArchive("https://minio.example.com/audit", "", "", testAWSCredentials, nil)
+	require.Error(t, err)
+}
+
+func TestWebIdentityCredentials(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "AssumeRoleWithWebIdentity", r.PostForm.Get("Action"))
+		require.Equal(t, "arn:aws:iam::123456789012:role/flux", r.PostForm.Get("RoleArn"))
+		require.Equal(t, "jwt", r.PostForm.Get("WebIdentityToken"))
+		w.Write([]byte(`<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAEXAMPLE</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2021-07-01T13:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "web-identity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("jwt\n"), 0600))
+
+	w := &webIdentityCredentials{client: ts.Client(), endpoint: ts.URL}
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	credentials, err := w.credentials("arn:aws:iam::123456789012:role/flux", tokenFile, now)
+	require.NoError(t, err)
+	require.Equal(t, AWSCredentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}, credentials)
+
+	// the credentials are cached until shortly before they expire
+	_, err = w.credentials("arn:aws:iam::123456789012:role/flux", tokenFile, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	_, err = w.credentials("arn:aws:iam::123456789012:role/flux", tokenFile, now.Add(56*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
+
+func TestIdentityToken(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+		w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer ts.Close()
+
+	token := &identityToken{client: ts.Client(), fetch: fetchGCPToken, endpoint: ts.URL}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		value, err := token.get(now)
+		require.NoError(t, err)
+		require.Equal(t, "ya29.token", value)
+	}
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	awsAlgorithm  = "AWS4-HMAC-SHA256"
+	awsDateFormat = "20060102T150405Z"
+)
+
+// AWSCredentials are the static credentials used to sign the AWS requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// resolve returns the credentials, or the ones of the controller
+// environment variables when the access key is not set. The controller
+// web identity token, e.g. of an EKS service account, is exchanged for
+// temporary credentials when the access key variables are not set.
+func (c AWSCredentials) resolve() (AWSCredentials, error) {
+	if c.AccessKeyID == "" {
+		c = AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if c.AccessKeyID == "" && roleARN != "" && tokenFile != "" {
+		return awsWebIdentity.credentials(roleARN, tokenFile, time.Now())
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return c, errors.New("AWS access key id and secret access key cannot be empty")
+	}
+	return c, nil
+}
+
+// signAWSRequest signs the request with the AWS Signature Version 4,
+// the host, the content type and the 'X-Amz-' headers are signed.
+func signAWSRequest(req *http.Request, body []byte, credentials AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format(awsDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{amzDate[:8], region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{awsAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsWebIdentity caches the credentials of the controller web identity.
+var awsWebIdentity = &webIdentityCredentials{client: &http.Client{Timeout: 15 * time.Second}}
+
+// webIdentityCredentials exchanges a web identity token for temporary
+// credentials with the STS AssumeRoleWithWebIdentity action, the
+// credentials are cached until shortly before they expire.
+type webIdentityCredentials struct {
+	client *http.Client
+
+	// endpoint overrides the STS endpoint of the AWS_REGION, if set.
+	endpoint string
+
+	mu         sync.Mutex
+	roleARN    string
+	cached     AWSCredentials
+	expiration time.Time
+}
+
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+func (w *webIdentityCredentials) credentials(roleARN, tokenFile string, now time.Time) (AWSCredentials, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.roleARN == roleARN && now.Add(5*time.Minute).Before(w.expiration) {
+		return w.cached, nil
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read the AWS web identity token: %w", err)
+	}
+
+	endpoint := w.endpoint
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com/"
+		if region := os.Getenv("AWS_REGION"); region != "" {
+			endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+		}
+	}
+	query := url.Values{}
+	query.Set("Action", "AssumeRoleWithWebIdentity")
+	query.Set("Version", "2011-06-15")
+	query.Set("RoleArn", roleARN)
+	query.Set("RoleSessionName", "notification-controller")
+	query.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	resp, err := w.client.PostForm(endpoint, query)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to assume the AWS role %s: %w", roleARN, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, fmt.Errorf("failed to assume the AWS role %s: %s", roleARN, resp.Status)
+	}
+	var out assumeRoleWithWebIdentityResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to decode the STS response: %w", err)
+	}
+
+	w.roleARN = roleARN
+	w.cached = AWSCredentials{
+		AccessKeyID:     out.Credentials.AccessKeyID,
+		SecretAccessKey: out.Credentials.SecretAccessKey,
+		SessionToken:    out.Credentials.SessionToken,
+	}
+	w.expiration = out.Credentials.Expiration
+	return w.cached, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
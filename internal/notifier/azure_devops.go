@@ -18,7 +18,6 @@ package notifier
 
 import (
 	"context"
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -29,6 +28,8 @@ import (
 
 	"github.com/microsoft/azure-devops-go-api/azuredevops"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/git"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
 )
 
 const genre string = "fluxcd"
@@ -38,10 +39,13 @@ type AzureDevOps struct {
 	Project string
 	Repo    string
 	Client  git.Client
+
+	statusContexting
+	statusStating
 }
 
 // NewAzureDevOps creates and returns a new AzureDevOps notifier.
-func NewAzureDevOps(addr string, token string, certPool *x509.CertPool) (*AzureDevOps, error) {
+func NewAzureDevOps(addr string, token string, certPool *x509.CertPool, transport *Transport) (*AzureDevOps, error) {
 	if len(token) == 0 {
 		return nil, errors.New("azure devops token cannot be empty")
 	}
@@ -61,10 +65,8 @@ func NewAzureDevOps(addr string, token string, certPool *x509.CertPool) (*AzureD
 
 	orgURL := fmt.Sprintf("%v/%v", host, org)
 	connection := azuredevops.NewPatConnection(orgURL, token)
-	if certPool != nil {
-		connection.TlsConfig = &tls.Config{
-			RootCAs: certPool,
-		}
+	if config := transport.tlsConfig(certPool); config != nil {
+		connection.TlsConfig = config
 	}
 	client := connection.GetClientByUrl(orgURL)
 	gitClient := &git.ClientImpl{
@@ -79,8 +81,12 @@ func NewAzureDevOps(addr string, token string, certPool *x509.CertPool) (*AzureD
 
 // Post Azure DevOps commit status
 func (a AzureDevOps) Post(event events.Event) error {
-	// Skip progressing events
-	if event.Reason == "Progressing" {
+	// the failures are reported with the error state unless mapped otherwise
+	state, err := a.stateFor(event, v1beta1.ErrorCommitState)
+	if err != nil {
+		return err
+	}
+	if state == v1beta1.SkipCommitState {
 		return nil
 	}
 
@@ -92,7 +98,7 @@ func (a AzureDevOps) Post(event events.Event) error {
 	if err != nil {
 		return err
 	}
-	state, err := toAzureDevOpsState(event.Severity)
+	adoState, err := toAzureDevOpsState(state)
 	if err != nil {
 		return err
 	}
@@ -100,22 +106,12 @@ func (a AzureDevOps) Post(event events.Event) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	// Check if the exact status is already set
-	g := genre
-	name, desc := formatNameAndDescription(event)
-	createArgs := git.CreateCommitStatusArgs{
-		Project:      &a.Project,
-		RepositoryId: &a.Repo,
-		CommitId:     &rev,
-		GitCommitStatusToCreate: &git.GitStatus{
-			Description: &desc,
-			State:       &state,
-			Context: &git.GitStatusContext{
-				Genre: &g,
-				Name:  &name,
-			},
-		},
+	_, desc := formatNameAndDescription(event)
+	names, err := a.contextsFor(event)
+	if err != nil {
+		return err
 	}
+
 	getArgs := git.GetStatusesArgs{
 		Project:      &a.Project,
 		RepositoryId: &a.Repo,
@@ -125,23 +121,46 @@ func (a AzureDevOps) Post(event events.Event) error {
 	if err != nil {
 		return fmt.Errorf("could not list commit statuses: %v", err)
 	}
-	if duplicateAzureDevOpsStatus(statuses, createArgs.GitCommitStatusToCreate) {
-		return nil
-	}
 
-	// Create a new status
-	_, err = a.Client.CreateCommitStatus(context.Background(), createArgs)
-	if err != nil {
-		return fmt.Errorf("could not create commit status: %v", err)
+	for _, name := range names {
+		g := genre
+		name := name
+		createArgs := git.CreateCommitStatusArgs{
+			Project:      &a.Project,
+			RepositoryId: &a.Repo,
+			CommitId:     &rev,
+			GitCommitStatusToCreate: &git.GitStatus{
+				Description: &desc,
+				State:       &adoState,
+				Context: &git.GitStatusContext{
+					Genre: &g,
+					Name:  &name,
+				},
+			},
+		}
+		// Check if the exact status is already set
+		if duplicateAzureDevOpsStatus(statuses, createArgs.GitCommitStatusToCreate) {
+			continue
+		}
+
+		// Create a new status
+		_, err = a.Client.CreateCommitStatus(context.Background(), createArgs)
+		if err != nil {
+			return fmt.Errorf("could not create commit status: %v", err)
+		}
 	}
 	return nil
 }
 
-func toAzureDevOpsState(severity string) (git.GitStatusState, error) {
-	switch severity {
-	case events.EventSeverityInfo:
+func toAzureDevOpsState(state string) (git.GitStatusState, error) {
+	switch state {
+	case v1beta1.SuccessCommitState:
 		return git.GitStatusStateValues.Succeeded, nil
-	case events.EventSeverityError:
+	case v1beta1.FailureCommitState:
+		return git.GitStatusStateValues.Failed, nil
+	case v1beta1.PendingCommitState:
+		return git.GitStatusStateValues.Pending, nil
+	case v1beta1.ErrorCommitState:
 		return git.GitStatusStateValues.Error, nil
 	default:
 		return "", errors.New("can't convert to azure devops state")
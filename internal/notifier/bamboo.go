@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// bambooMaxResults is the number of recent build results searched for the revision.
+const bambooMaxResults = 25
+
+// Bamboo is a Bamboo notifier, it comments the build results of a plan
+// that ran on the revision of the event.
+type Bamboo struct {
+	requestConfig
+
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+	PlanKey  string
+	Token    string
+}
+
+// NewBamboo returns a notifier for the server address, the channel is
+// the plan key, e.g. 'PROJ-PLAN', and the token a personal access token.
+func NewBamboo(addr, proxyURL, planKey, token string, certPool *x509.CertPool) (*Bamboo, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Bamboo address %s: %w", addr, err)
+	}
+	if planKey == "" {
+		return nil, errors.New("bamboo plan key cannot be empty")
+	}
+	if token == "" {
+		return nil, errors.New("bamboo token cannot be empty")
+	}
+
+	return &Bamboo{
+		URL:      strings.TrimSuffix(addr, "/"),
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		PlanKey:  planKey,
+		Token:    token,
+	}, nil
+}
+
+// Post comments the recent build results of the revision with the reconciliation outcome.
+func (b *Bamboo) Post(event events.Event) error {
+	// Skip progressing events
+	if event.Reason == "Progressing" {
+		return nil
+	}
+
+	revString, ok := event.Metadata["revision"]
+	if !ok {
+		return errors.New("missing revision metadata")
+	}
+	rev, err := parseRevision(revString)
+	if err != nil {
+		return err
+	}
+	comment, err := formatBuildComment(event)
+	if err != nil {
+		return err
+	}
+
+	var results struct {
+		Results struct {
+			Result []struct {
+				Key         string `json:"buildResultKey"`
+				RevisionKey string `json:"vcsRevisionKey"`
+			} `json:"result"`
+		} `json:"results"`
+	}
+	address := fmt.Sprintf("%s/rest/api/latest/result/%s?expand=results.result&max-results=%d",
+		b.URL, url.PathEscape(b.PlanKey), bambooMaxResults)
+	if err := b.transport.sendRequest(http.MethodGet, address, b.ProxyURL, b.CertPool, nil, &results, b.authorize); err != nil {
+		return fmt.Errorf("could not list Bamboo build results: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{"content": comment})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+	found := false
+	for _, result := range results.Results.Result {
+		if result.RevisionKey != rev {
+			continue
+		}
+		found = true
+		address := fmt.Sprintf("%s/rest/api/latest/result/%s/comment", b.URL, url.PathEscape(result.Key))
+		if err := b.transport.sendRequest(http.MethodPost, address, b.ProxyURL, b.CertPool, body, nil, b.authorize); err != nil {
+			return fmt.Errorf("could not comment Bamboo build result %s: %w", result.Key, err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("no Bamboo build result of %s found for revision %s", b.PlanKey, rev)
+	}
+	return nil
+}
+
+func (b *Bamboo) authorize(req *retryablehttp.Request) {
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+	req.Header.Set("X-Atlassian-Token", "no-check")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBamboo_Post(t *testing.T) {
+	var commented []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/latest/result/APPS-BUILD":
+			fmt.Fprint(w, `{"results":{"result":[
+				{"buildResultKey":"APPS-BUILD-8","vcsRevisionKey":"731f7eaddfb6af01cb2173e18f0f75b0ba780ef1"},
+				{"buildResultKey":"APPS-BUILD-7","vcsRevisionKey":"a1b2c3"}]}}`)
+		case r.Method == http.MethodPost:
+			var comment map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+			require.Equal(t, "flux kustomization/webapp: failure, reconciliation succeeded", comment["content"])
+			commented = append(commented, r.URL.Path)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, err := NewBamboo(ts.URL, "", "APPS-BUILD", "token", nil)
+	require.NoError(t, err)
+
+	event := commitStatusEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, b.Post(event))
+	require.Equal(t, []string{"/rest/api/latest/result/APPS-BUILD-8/comment"}, commented)
+
+	event.Metadata["revision"] = "main/ffffff"
+	require.Error(t, b.Post(event))
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// bigQueryTimeout bounds the connection and the append of a row.
+const bigQueryTimeout = 15 * time.Second
+
+// bigQueryDataset matches the path of the dataset in the BigQuery addresses.
+var bigQueryDataset = regexp.MustCompile(`^/projects/([^/]+)/datasets/([^/]+)$`)
+
+// bigQueryTable matches the BigQuery table names.
+var bigQueryTable = regexp.MustCompile(`^[\p{L}\p{M}\p{N}\p{Pc}\p{Pd} ]+$`)
+
+// bigQueryDescriptor describes the rows of the events, with a field per event
+// column numbered in the columns order, the timestamps are in microseconds.
+var bigQueryDescriptor = func() *descriptorpb.DescriptorProto {
+	d := &descriptorpb.DescriptorProto{Name: proto.String("FluxEvent")}
+	for i, column := range eventColumns {
+		fieldType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+		if column.timestamp != nil {
+			fieldType = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		}
+		d.Field = append(d.Field, &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(column.name),
+			Number: proto.Int32(int32(i + 1)),
+			Type:   fieldType.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		})
+	}
+	return d
+}()
+
+// BigQuery is an implementation of the notification Interface that
+// appends the events to a BigQuery table with the Storage Write API.
+type BigQuery struct {
+	// URL is the dataset address, e.g.
+	// 'https://bigquerystorage.googleapis.com/projects/<project>/datasets/<dataset>'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Table is the name of the table of the dataset.
+	Table string
+
+	// Token is the OAuth 2.0 access token, the token of
+	// the controller workload identity is used when empty.
+	Token string
+
+	// Endpoint is the host and port of the gRPC service, in plain text
+	// when Insecure is set for the 'http' addresses, e.g. of an emulator.
+	Endpoint string
+	Insecure bool
+
+	// Project and Dataset hold the tables.
+	Project string
+	Dataset string
+
+	channelRouting
+	requestConfig
+}
+
+// NewBigQuery returns a notifier for the dataset address, the channel
+// is the name of the table and the token the OAuth 2.0 access token.
+func NewBigQuery(addr, proxyURL, table, token string, certPool *x509.CertPool) (*BigQuery, error) {
+	u, err := url.ParseRequestURI(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BigQuery address %s: %w", addr, err)
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	match := bigQueryDataset.FindStringSubmatch(strings.TrimSuffix(u.Path, "/"))
+	if match == nil {
+		return nil, fmt.Errorf("invalid BigQuery address %s: no project and dataset", addr)
+	}
+	if table == "" {
+		return nil, errors.New("bigquery table cannot be empty")
+	}
+
+	b := &BigQuery{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Table:    table,
+		Token:    token,
+		Endpoint: u.Host,
+		Project:  match[1],
+		Dataset:  match[2],
+	}
+	switch u.Scheme {
+	case "https":
+		if u.Port() == "" {
+			b.Endpoint = net.JoinHostPort(u.Hostname(), "443")
+		}
+	case "http":
+		b.Insecure = true
+		if u.Port() == "" {
+			b.Endpoint = net.JoinHostPort(u.Hostname(), "80")
+		}
+	default:
+		return nil, fmt.Errorf("invalid BigQuery address %s: unsupported scheme '%s'", addr, u.Scheme)
+	}
+	return b, nil
+}
+
+// Post appends a row with the columns of the event to the default stream of the table,
+// the rows are committed when appended.
+func (b *BigQuery) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	table, err := b.channelFor(event, b.Table)
+	if err != nil {
+		return err
+	}
+	if !bigQueryTable.MatchString(table) {
+		return fmt.Errorf("invalid BigQuery table '%s'", table)
+	}
+
+	token := b.Token
+	if token == "" {
+		if token, err = gcpIdentity.get(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	timeout := bigQueryTimeout
+	if b.delivery != nil && b.delivery.Timeout > 0 {
+		timeout = b.delivery.Timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stream := fmt.Sprintf("projects/%s/datasets/%s/tables/%s/streams/_default", b.Project, b.Dataset, table)
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		"authorization", "Bearer "+token,
+		"x-goog-request-params", "write_stream="+url.QueryEscape(stream))
+	if b.deliveryID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(DeliveryIDHeader), b.deliveryID)
+	}
+
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to BigQuery %s: %w", b.Endpoint, err)
+	}
+	defer conn.Close()
+
+	rows, err := storagepb.NewBigQueryWriteClient(conn).AppendRows(ctx)
+	if err != nil {
+		return fmt.Errorf("bigquery append failed: %w", err)
+	}
+	err = rows.Send(&storagepb.AppendRowsRequest{
+		WriteStream: stream,
+		Rows: &storagepb.AppendRowsRequest_ProtoRows{
+			ProtoRows: &storagepb.AppendRowsRequest_ProtoData{
+				WriterSchema: &storagepb.ProtoSchema{ProtoDescriptor: bigQueryDescriptor},
+				Rows:         &storagepb.ProtoRows{SerializedRows: [][]byte{bigQueryRow(event)}},
+			},
+		},
+	})
+	// the stream errors are returned by Recv
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("bigquery append failed: %w", err)
+	}
+	if err := rows.CloseSend(); err != nil {
+		return fmt.Errorf("bigquery append failed: %w", err)
+	}
+	resp, err := rows.Recv()
+	if err != nil {
+		return fmt.Errorf("bigquery append failed: %w", err)
+	}
+	if e := resp.GetError(); e != nil {
+		return fmt.Errorf("bigquery append failed: %s", e.GetMessage())
+	}
+	return nil
+}
+
+// dial connects to the IPs of the endpoint permitted by the egress policy,
+// through the proxy, if any.
+func (b *BigQuery) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	if err := b.transport.Check(ctx, b.Endpoint); err != nil {
+		return nil, err
+	}
+
+	dial := b.transport.dialContext(&net.Dialer{Timeout: bigQueryTimeout})
+	if b.ProxyURL != "" {
+		proxyURL, err := url.Parse(b.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse proxy URL '%s', error: %w", b.ProxyURL, err)
+		}
+		dial = b.transport.dialProxy(proxyURL, &net.Dialer{Timeout: bigQueryTimeout})
+	}
+
+	creds := grpc.WithInsecure()
+	if !b.Insecure {
+		config := b.transport.tlsConfig(b.CertPool)
+		if config == nil {
+			config = &tls.Config{}
+		}
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+	return grpc.DialContext(ctx, b.Endpoint, creds,
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dial(ctx, "tcp", address)
+		}),
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true))
+}
+
+// bigQueryRow returns the event encoded as a message of the bigQueryDescriptor.
+func bigQueryRow(e events.Event) []byte {
+	var row []byte
+	for i, column := range eventColumns {
+		number := protowire.Number(i + 1)
+		if column.timestamp != nil {
+			row = protowire.AppendTag(row, number, protowire.VarintType)
+			row = protowire.AppendVarint(row, uint64(column.timestamp(e)*1000))
+			continue
+		}
+		row = protowire.AppendTag(row, number, protowire.BytesType)
+		row = protowire.AppendString(row, column.text(e))
+	}
+	return row
+}
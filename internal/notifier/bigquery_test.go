@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/fluxcd/notification-controller/internal/egress"
+)
+
+// fakeBigQueryWrite records the appended requests and their authorization.
+type fakeBigQueryWrite struct {
+	storagepb.UnimplementedBigQueryWriteServer
+
+	mu        sync.Mutex
+	requests  []*storagepb.AppendRowsRequest
+	auth      []string
+	appendErr string
+}
+
+func (f *fakeBigQueryWrite) AppendRows(stream storagepb.BigQueryWrite_AppendRowsServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.auth = append(f.auth, md.Get("authorization")...)
+		appendErr := f.appendErr
+		f.mu.Unlock()
+
+		resp := &storagepb.AppendRowsResponse{
+			Response: &storagepb.AppendRowsResponse_AppendResult_{AppendResult: &storagepb.AppendRowsResponse_AppendResult{}},
+		}
+		if appendErr != "" {
+			resp.Response = &storagepb.AppendRowsResponse_Error{Error: &statuspb.Status{Code: 3, Message: appendErr}}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func startBigQueryWrite(t *testing.T) (*fakeBigQueryWrite, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	fake := &fakeBigQueryWrite{}
+	storagepb.RegisterBigQueryWriteServer(server, fake)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return fake, ln.Addr().String()
+}
+
+// decodeBigQueryRow returns the columns of a row encoded by bigQueryRow.
+func decodeBigQueryRow(t *testing.T, row []byte) map[string]interface{} {
+	columns := map[string]interface{}{}
+	for len(row) > 0 {
+		number, wireType, n := protowire.ConsumeTag(row)
+		require.True(t, n > 0)
+		row = row[n:]
+		name := eventColumns[number-1].name
+		switch wireType {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(row)
+			require.True(t, n > 0)
+			columns[name], row = int64(v), row[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeString(row)
+			require.True(t, n > 0)
+			columns[name], row = v, row[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+	}
+	return columns
+}
+
+func TestBigQuery_Post(t *testing.T) {
+	fake, address := startBigQueryWrite(t)
+
+	bq, err := NewBigQuery("http://"+address+"/projects/platform/datasets/flux", "", "events", "s3cr3t", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Metadata["revision"] = "main/6ec1"
+	require.NoError(t, bq.Post(event))
+	require.Len(t, fake.requests, 1)
+	require.Equal(t, []string{"Bearer s3cr3t"}, fake.auth)
+
+	req := fake.requests[0]
+	require.Equal(t, "projects/platform/datasets/flux/tables/events/streams/_default", req.WriteStream)
+	data := req.GetProtoRows()
+	require.Equal(t, "FluxEvent", data.WriterSchema.ProtoDescriptor.GetName())
+	require.Len(t, data.WriterSchema.ProtoDescriptor.Field, len(eventColumns))
+	require.Len(t, data.Rows.SerializedRows, 1)
+	row := decodeBigQueryRow(t, data.Rows.SerializedRows[0])
+	require.Equal(t, "GitRepository", row["kind"])
+	require.Equal(t, "main/6ec1", row["revision"])
+	require.Equal(t, event.Timestamp.UnixNano()/1e6*1e3, row["timestamp"])
+	require.JSONEq(t, `{"revision":"main/6ec1","test":"metadata"}`, row["metadata"].(string))
+
+	fake.mu.Lock()
+	fake.appendErr = "no such field: revision"
+	fake.mu.Unlock()
+	require.EqualError(t, bq.Post(event), "bigquery append failed: no such field: revision")
+}
+
+func TestBigQuery_PostProxy(t *testing.T) {
+	fake, address := startBigQueryWrite(t)
+
+	var tunnels []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodConnect, r.Method)
+		tunnels = append(tunnels, r.Host)
+		target, err := net.Dial("tcp", r.Host)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		go func() {
+			io.Copy(conn, target)
+			conn.Close()
+		}()
+	}))
+	defer proxy.Close()
+
+	bq, err := NewBigQuery("http://"+address+"/projects/platform/datasets/flux", proxy.URL, "events", "s3cr3t", nil)
+	require.NoError(t, err)
+	require.NoError(t, bq.Post(testEvent()))
+	require.Equal(t, []string{address}, tunnels)
+	require.Len(t, fake.requests, 1)
+
+	// the tunnelled address is checked against the egress policy
+	policy, err := egress.ParsePolicy([]string{"127.0.0.1/32"}, nil)
+	require.NoError(t, err)
+	bq.Endpoint = "10.0.0.1:443"
+	bq.setTransport(&Transport{EgressPolicy: policy})
+	err = bq.Post(testEvent())
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
+	require.Len(t, tunnels, 1)
+}
+
+func TestBigQuery_PostEgressDenied(t *testing.T) {
+	fake, address := startBigQueryWrite(t)
+
+	policy, err := egress.ParsePolicy(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	bq, err := NewBigQuery("http://"+address+"/projects/platform/datasets/flux", "", "events", "s3cr3t", nil)
+	require.NoError(t, err)
+	bq.setTransport(&Transport{EgressPolicy: policy})
+
+	err = bq.Post(testEvent())
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
+	require.Empty(t, fake.requests)
+}
+
+func TestNewBigQuery(t *testing.T) {
+	_, err := NewBigQuery("https://bigquerystorage.googleapis.com/projects/platform", "", "events", "", nil)
+	require.Error(t, err)
+	_, err = NewBigQuery("https://bigquerystorage.googleapis.com/projects/platform/datasets/flux", "", "", "", nil)
+	require.Error(t, err)
+	_, err = NewBigQuery("grpc://bigquerystorage.googleapis.com/projects/platform/datasets/flux", "", "events", "", nil)
+	require.Error(t, err)
+
+	bq, err := NewBigQuery("https://bigquerystorage.googleapis.com/projects/platform/datasets/flux/", "", "events", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://bigquerystorage.googleapis.com/projects/platform/datasets/flux", bq.URL)
+	require.Equal(t, "bigquerystorage.googleapis.com:443", bq.Endpoint)
+	require.False(t, bq.Insecure)
+	require.Equal(t, "platform", bq.Project)
+	require.Equal(t, "flux", bq.Dataset)
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package notifier
 
 import (
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -26,6 +25,8 @@ import (
 
 	"github.com/fluxcd/pkg/runtime/events"
 	"github.com/ktrysmt/go-bitbucket"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
 )
 
 // Bitbucket is a Bitbucket Server notifier.
@@ -33,10 +34,13 @@ type Bitbucket struct {
 	Owner  string
 	Repo   string
 	Client *bitbucket.Client
+
+	statusContexting
+	statusStating
 }
 
 // NewBitbucket creates and returns a new Bitbucket notifier.
-func NewBitbucket(addr string, token string, certPool *x509.CertPool) (*Bitbucket, error) {
+func NewBitbucket(addr string, token string, certPool *x509.CertPool, transport *Transport) (*Bitbucket, error) {
 	if len(token) == 0 {
 		return nil, errors.New("bitbucket token cannot be empty")
 	}
@@ -61,11 +65,9 @@ func NewBitbucket(addr string, token string, certPool *x509.CertPool) (*Bitbucke
 	repo := comp[1]
 
 	client := bitbucket.NewBasicAuth(username, password)
-	if certPool != nil {
+	if config := transport.tlsConfig(certPool); config != nil {
 		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: certPool,
-			},
+			TLSClientConfig: config,
 		}
 		hc := &http.Client{Transport: tr}
 		client.HttpClient = hc
@@ -80,8 +82,11 @@ func NewBitbucket(addr string, token string, certPool *x509.CertPool) (*Bitbucke
 
 // Post Bitbucket commit status
 func (b Bitbucket) Post(event events.Event) error {
-	// Skip progressing events
-	if event.Reason == "Progressing" {
+	state, err := b.stateFor(event, v1beta1.FailureCommitState)
+	if err != nil {
+		return err
+	}
+	if state == v1beta1.SkipCommitState {
 		return nil
 	}
 
@@ -93,41 +98,48 @@ func (b Bitbucket) Post(event events.Event) error {
 	if err != nil {
 		return err
 	}
-	state, err := toBitbucketState(event.Severity)
+	bbState, err := toBitbucketState(state)
 	if err != nil {
 		return err
 	}
 
-	name, desc := formatNameAndDescription(event)
-	// key has a limitation of 40 characters in bitbucket api
-	key := sha1String(name)
+	_, desc := formatNameAndDescription(event)
+	names, err := b.contextsFor(event)
+	if err != nil {
+		return err
+	}
 
 	cmo := &bitbucket.CommitsOptions{
 		Owner:    b.Owner,
 		RepoSlug: b.Repo,
 		Revision: rev,
 	}
-	cso := &bitbucket.CommitStatusOptions{
-		State:       state,
-		Key:         key,
-		Name:        name,
-		Description: desc,
-		Url:         "https://bitbucket.org",
-	}
-	_, err = b.Client.Repositories.Commits.CreateCommitStatus(cmo, cso)
-	if err != nil {
-		return err
+	for _, name := range names {
+		cso := &bitbucket.CommitStatusOptions{
+			State: bbState,
+			// key has a limitation of 40 characters in bitbucket api
+			Key:         sha1String(name),
+			Name:        name,
+			Description: desc,
+			Url:         "https://bitbucket.org",
+		}
+		_, err = b.Client.Repositories.Commits.CreateCommitStatus(cmo, cso)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func toBitbucketState(severity string) (string, error) {
-	switch severity {
-	case events.EventSeverityInfo:
+func toBitbucketState(state string) (string, error) {
+	switch state {
+	case v1beta1.SuccessCommitState:
 		return "SUCCESSFUL", nil
-	case events.EventSeverityError:
+	case v1beta1.FailureCommitState, v1beta1.ErrorCommitState:
 		return "FAILED", nil
+	case v1beta1.PendingCommitState:
+		return "INPROGRESS", nil
 	default:
 		return "", errors.New("can't convert to bitbucket state")
 	}
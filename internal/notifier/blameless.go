@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// blamelessIncidentTag prefixes the tag holding the incident key of the
+// Blameless incidents, so that the following events annotate them.
+const blamelessIncidentTag = "flux-incident:"
+
+// Blameless is a Blameless notifier, it opens an incident on the first error
+// event of an object and adds the following error events to its timeline.
+type Blameless struct {
+	// URL is the address of the Blameless instance, e.g. 'https://<org>.blameless.io'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+	Token    string
+
+	// IncidentType is the type of the opened incidents, e.g. 'Deployment',
+	// the Blameless default is used when empty.
+	IncidentType string
+
+	requestConfig
+}
+
+type blamelessIncident struct {
+	ID          int64    `json:"id,omitempty"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Type        string   `json:"type,omitempty"`
+	Status      string   `json:"status"`
+	Tags        []string `json:"tags"`
+}
+
+type blamelessEvent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// NewBlameless returns a notifier for the Blameless instance address, the channel
+// is the type of the incidents and the token an API bearer token.
+func NewBlameless(addr, proxyURL, incidentType, token string, certPool *x509.CertPool) (*Blameless, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Blameless URL %s: %w", addr, err)
+	}
+	if token == "" {
+		return nil, errors.New("blameless token cannot be empty")
+	}
+
+	return &Blameless{
+		URL:          strings.TrimSuffix(addr, "/"),
+		ProxyURL:     proxyURL,
+		CertPool:     certPool,
+		Token:        token,
+		IncidentType: incidentType,
+	}, nil
+}
+
+// Post opens an incident for the involved object, or adds the event to the
+// timeline of its active incident, the events that aren't errors are skipped.
+func (b *Blameless) Post(event events.Event) error {
+	if event.Severity != events.EventSeverityError {
+		return nil
+	}
+
+	tag := blamelessIncidentTag + incidentKey(event)
+	id, err := b.activeIncident(tag, event)
+	if err != nil {
+		return fmt.Errorf("could not list the Blameless incidents: %w", err)
+	}
+	if id == 0 {
+		id, err = b.openIncident(tag, event)
+		if err != nil {
+			return fmt.Errorf("could not open the Blameless incident: %w", err)
+		}
+	}
+
+	body, err := json.Marshal(blamelessEvent{Type: "note", Text: incidentTimelineEntry(event)})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+	address := fmt.Sprintf("%s/api/v2/incidents/%d/events", b.URL, id)
+	if err := b.send(http.MethodPost, address, body, nil, event); err != nil {
+		return fmt.Errorf("could not annotate the Blameless incident %d: %w", id, err)
+	}
+	return nil
+}
+
+// activeIncident returns the ID of the active incident with the tag, if any.
+func (b *Blameless) activeIncident(tag string, event events.Event) (int64, error) {
+	query := url.Values{}
+	query.Set("status", "active")
+	query.Set("tags", tag)
+	var resp struct {
+		Incidents []blamelessIncident `json:"incidents"`
+	}
+	if err := b.send(http.MethodGet, b.URL+"/api/v2/incidents?"+query.Encode(), nil, &resp, event); err != nil {
+		return 0, err
+	}
+	if len(resp.Incidents) == 0 {
+		return 0, nil
+	}
+	return resp.Incidents[0].ID, nil
+}
+
+// openIncident opens an incident tagged with the key and returns its ID.
+func (b *Blameless) openIncident(tag string, event events.Event) (int64, error) {
+	body, err := json.Marshal(blamelessIncident{
+		Title:       incidentTitle(event),
+		Description: incidentMessage(event),
+		Type:        b.IncidentType,
+		Status:      "investigating",
+		Tags:        []string{tag},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+	var resp struct {
+		Incident blamelessIncident `json:"incident"`
+	}
+	if err := b.send(http.MethodPost, b.URL+"/api/v2/incidents", body, &resp, event); err != nil {
+		return 0, err
+	}
+	if resp.Incident.ID == 0 {
+		return 0, errors.New("the response has no incident ID")
+	}
+	return resp.Incident.ID, nil
+}
+
+func (b *Blameless) send(method, address string, body []byte, out interface{}, event events.Event) error {
+	return b.transport.sendRequest(method, address, b.ProxyURL, b.CertPool, body, out, func(req *retryablehttp.Request) {
+		req.Header.Set("Authorization", "Bearer "+b.Token)
+	}, b.withCapture(), b.withDelivery(event))
+}
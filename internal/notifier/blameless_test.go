@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlameless_Post(t *testing.T) {
+	var incidents []blamelessIncident
+	var timeline []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/incidents":
+			require.Equal(t, "active", r.URL.Query().Get("status"))
+			if len(incidents) == 0 {
+				w.Write([]byte(`{"incidents":[]}`))
+				return
+			}
+			w.Write([]byte(`{"incidents":[{"id":42}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/incidents":
+			var incident blamelessIncident
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&incident))
+			incidents = append(incidents, incident)
+			w.Write([]byte(`{"incident":{"id":42}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/incidents/42/events":
+			var e blamelessEvent
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+			timeline = append(timeline, e.Text)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	blameless, err := NewBlameless(ts.URL, "", "Deployment", "token", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, blameless.Post(event))
+	require.NoError(t, blameless.Post(event))
+	require.NoError(t, blameless.Post(testEvent()))
+
+	require.Len(t, incidents, 1)
+	require.Equal(t, "Deployment", incidents[0].Type)
+	require.Equal(t, []string{blamelessIncidentTag + incidentKey(event)}, incidents[0].Tags)
+	require.Equal(t, []string{
+		"GitRepository/gitops-system/webapp: message",
+		"GitRepository/gitops-system/webapp: message",
+	}, timeline)
+}
+
+func TestNewBlameless(t *testing.T) {
+	_, err := NewBlameless("example.blameless.io", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewBlameless("https://example.blameless.io", "", "", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	cachetOperational   = 1
+	cachetPartialOutage = 3
+)
+
+// Cachet is a Cachet notifier, it sets the status of a component to
+// partial outage on the error events and to operational on the other events.
+type Cachet struct {
+	// URL is the Cachet address, e.g. 'https://status.example.com'.
+	URL         string
+	ProxyURL    string
+	CertPool    *x509.CertPool
+	ComponentID string
+	Token       string
+
+	requestConfig
+}
+
+// NewCachet returns a notifier for the Cachet address, the channel
+// is the component ID and the token an API token.
+func NewCachet(addr, proxyURL, componentID, token string, certPool *x509.CertPool) (*Cachet, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Cachet address %s: %w", addr, err)
+	}
+	if componentID == "" {
+		return nil, errors.New("cachet component id cannot be empty")
+	}
+	if token == "" {
+		return nil, errors.New("cachet token cannot be empty")
+	}
+
+	return &Cachet{
+		URL:         strings.TrimSuffix(addr, "/"),
+		ProxyURL:    proxyURL,
+		CertPool:    certPool,
+		ComponentID: componentID,
+		Token:       token,
+	}, nil
+}
+
+// Post updates the status of the component.
+func (c *Cachet) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	status := cachetOperational
+	if event.Severity == events.EventSeverityError {
+		status = cachetPartialOutage
+	}
+	body, err := json.Marshal(map[string]int{"status": status})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	address := fmt.Sprintf("%s/api/v1/components/%s", c.URL, url.PathEscape(c.ComponentID))
+	err = c.transport.sendRequest(http.MethodPut, address, c.ProxyURL, c.CertPool, body, nil, func(req *retryablehttp.Request) {
+		req.Header.Set("X-Cachet-Token", c.Token)
+	}, c.withCapture(), c.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("could not update Cachet component %s: %w", c.ComponentID, err)
+	}
+	return nil
+}
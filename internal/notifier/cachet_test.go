@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachet_Post(t *testing.T) {
+	var statuses []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/api/v1/components/1", r.URL.Path)
+		require.Equal(t, "token", r.Header.Get("X-Cachet-Token"))
+
+		var payload struct {
+			Status int `json:"status"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		statuses = append(statuses, payload.Status)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cachet, err := NewCachet(ts.URL, "", "1", "token", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, cachet.Post(event))
+	require.NoError(t, cachet.Post(testEvent()))
+	require.Equal(t, []int{cachetPartialOutage, cachetOperational}, statuses)
+}
+
+func TestNewCachet(t *testing.T) {
+	_, err := NewCachet("https://status.example.com", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewCachet("https://status.example.com", "", "1", "", nil)
+	require.Error(t, err)
+}
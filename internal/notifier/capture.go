@@ -0,0 +1,268 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// maxCapturedBody is the number of bytes of a request or response body kept in a capture.
+	maxCapturedBody = 4096
+
+	redacted = "REDACTED"
+)
+
+// Exchange is a sanitized outgoing request and the response it received.
+type Exchange struct {
+	Time            time.Time   `json:"time"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     string      `json:"requestBody,omitempty"`
+	StatusCode      int         `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    string      `json:"responseBody,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// Capture is a ring buffer holding the last exchanges of a notifier.
+type Capture struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+	full      bool
+}
+
+// NewCapture returns a capture that keeps the given number of exchanges.
+func NewCapture(size int) *Capture {
+	return &Capture{exchanges: make([]Exchange, size)}
+}
+
+// Exchanges returns the captured exchanges, oldest first.
+func (c *Capture) Exchanges() []Exchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.full {
+		return append([]Exchange(nil), c.exchanges[:c.next]...)
+	}
+	return append(append([]Exchange(nil), c.exchanges[c.next:]...), c.exchanges[:c.next]...)
+}
+
+func (c *Capture) record(e Exchange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.exchanges) == 0 {
+		return
+	}
+	c.exchanges[c.next] = e
+	c.next = (c.next + 1) % len(c.exchanges)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// capturer is implemented by the notifiers that can capture their requests.
+type capturer interface {
+	setCapture(c *Capture)
+}
+
+// requestConfig is embedded by the webhook based notifiers, it holds the
+// transport, the capture, the delivery settings and the delivery ID of
+// their requests.
+type requestConfig struct {
+	transport  *Transport
+	capture    *Capture
+	delivery   *Delivery
+	deliveryID string
+}
+
+func (d *requestConfig) setTransport(t *Transport) {
+	d.transport = t
+}
+
+func (d *requestConfig) setCapture(c *Capture) {
+	d.capture = c
+}
+
+type captureKey struct{}
+
+// withCapture records the request and its response in the capture, if any.
+func (d *requestConfig) withCapture() requestOptFunc {
+	return func(req *retryablehttp.Request) {
+		if d.capture == nil {
+			return
+		}
+		req.Request = req.Request.WithContext(context.WithValue(req.Context(), captureKey{}, d.capture))
+	}
+}
+
+// captureTransport records the exchanges of the requests carrying a capture in their context.
+type captureTransport struct {
+	next http.RoundTripper
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	capture, ok := req.Context().Value(captureKey{}).(*Capture)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	exchange := Exchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Header),
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = truncate(redactBody(req.Header.Get("Content-Type"), body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		exchange.Error = err.Error()
+		capture.record(exchange)
+		return resp, err
+	}
+
+	exchange.StatusCode = resp.StatusCode
+	exchange.ResponseHeaders = redactHeaders(resp.Header)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	exchange.ResponseBody = truncate(redactBody(resp.Header.Get("Content-Type"), body))
+	capture.record(exchange)
+
+	return resp, nil
+}
+
+// redactURL keeps the scheme and host of the URL, the webhook URLs
+// of most chat services hold their secret in the path or the query.
+func redactURL(u *url.URL) string {
+	r := url.URL{Scheme: u.Scheme, Host: u.Host}
+	if u.Path != "" && u.Path != "/" {
+		r.Path = "/" + redacted
+	}
+	return r.String()
+}
+
+// redactHeaders replaces the values of the headers that can hold credentials.
+func redactHeaders(h http.Header) http.Header {
+	r := make(http.Header, len(h))
+	for k, v := range h {
+		name := strings.ToLower(k)
+		if name == "cookie" || name == "set-cookie" || isSecretName(name) {
+			r[k] = []string{redacted}
+			continue
+		}
+		r[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// redactBody replaces the values of the JSON and form fields that can hold
+// credentials, e.g. the routing key of PagerDuty or the token of a login
+// response. The other bodies are kept as they are.
+func redactBody(contentType string, body []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		for k := range values {
+			if isSecretName(strings.ToLower(k)) {
+				values[k] = []string{redacted}
+			}
+		}
+		return []byte(values.Encode())
+	case json.Valid(body):
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return body
+		}
+		out, err := json.Marshal(redactFields(v))
+		if err != nil {
+			return body
+		}
+		return out
+	default:
+		return body
+	}
+}
+
+// redactFields replaces the values of the secret fields of the JSON objects.
+func redactFields(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if isSecretName(strings.ToLower(k)) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactFields(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactFields(v[i])
+		}
+	}
+	return v
+}
+
+// isSecretName returns whether the lower case name of a header
+// or field suggests that its value is a credential.
+func isSecretName(name string) bool {
+	for _, s := range []string{"auth", "token", "secret", "key", "signature", "password", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(body []byte) string {
+	if len(body) > maxCapturedBody {
+		return string(body[:maxCapturedBody]) + "...(truncated)"
+	}
+	return string(body)
+}
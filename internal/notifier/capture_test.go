@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture_Ring(t *testing.T) {
+	c := NewCapture(2)
+	require.Empty(t, c.Exchanges())
+
+	for _, code := range []int{200, 400, 500} {
+		c.record(Exchange{StatusCode: code})
+	}
+
+	exchanges := c.Exchanges()
+	require.Len(t, exchanges, 2)
+	require.Equal(t, 400, exchanges[0].StatusCode)
+	require.Equal(t, 500, exchanges[1].StatusCode)
+}
+
+func TestCapture_Notifier(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Summary or Text is required."))
+	}))
+	defer ts.Close()
+
+	capture := NewCapture(5)
+	factory := NewFactory(ts.URL+"/webhook/secret", "", "", "", "", nil)
+	factory.Capture = capture
+	teams, err := factory.Notifier("msteams")
+	require.NoError(t, err)
+
+	require.NoError(t, teams.Post(testEvent()))
+
+	exchanges := capture.Exchanges()
+	require.Len(t, exchanges, 1)
+	require.Equal(t, http.MethodPost, exchanges[0].Method)
+	require.Equal(t, ts.URL+"/REDACTED", exchanges[0].URL)
+	require.Contains(t, exchanges[0].RequestBody, "webapp")
+	require.Equal(t, http.StatusBadRequest, exchanges[0].StatusCode)
+	require.Equal(t, "Summary or Text is required.", exchanges[0].ResponseBody)
+	require.WithinDuration(t, time.Now(), exchanges[0].Time, time.Minute)
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{
+		"Authorization":   []string{"Bearer abc"},
+		"X-Gitlab-Token":  []string{"abc"},
+		"X-Api-Key":       []string{"abc"},
+		"Content-Type":    []string{"application/json"},
+		"Gotk-Component":  []string{"source-controller"},
+		"X-Hub-Signature": []string{"sha1=abc"},
+	}
+
+	r := redactHeaders(h)
+	for k, v := range r {
+		if strings.HasPrefix(k, "Content") || k == "Gotk-Component" {
+			require.Equal(t, h[k], v)
+			continue
+		}
+		require.Equal(t, []string{"REDACTED"}, v, k)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"routing_key":"abc","payload":{"summary":"failed","Password":"abc"},"items":[{"api_key":"abc"}]}`,
+			want:        `{"items":[{"api_key":"REDACTED"}],"payload":{"Password":"REDACTED","summary":"failed"},"routing_key":"REDACTED"}`,
+		},
+		{
+			name: "json without content type",
+			body: `{"access_token":"abc","expires_in":3600}`,
+			want: `{"access_token":"REDACTED","expires_in":3600}`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			body:        "apikey=abc&grant_type=urn",
+			want:        "apikey=REDACTED&grant_type=urn",
+		},
+		{
+			name:        "text",
+			contentType: "text/plain",
+			body:        "token=abc",
+			want:        "token=abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, string(redactBody(tt.contentType, []byte(tt.body))))
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "ok", truncate([]byte("ok")))
+	require.Len(t, truncate([]byte(strings.Repeat("a", maxCapturedBody+10))), maxCapturedBody+len("...(truncated)"))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+// IsChannelTemplate returns true if the channel is rendered for each event.
+func IsChannelTemplate(channel string) bool {
+	return strings.Contains(channel, "{{")
+}
+
+// channelRoute renders the channel of the notifications from the event,
+// the rendered channels must match one of the allowed channels.
+type channelRoute struct {
+	template *template.Template
+	allowed  []string
+}
+
+// newChannelRoute parses the channel template, the missing metadata keys
+// fail the rendering unless they are looked up with 'index'.
+func newChannelRoute(channel string, allowed []string) (*channelRoute, error) {
+	if len(allowed) == 0 {
+		return nil, errors.New("a templated channel requires allowed channels")
+	}
+	for _, pattern := range allowed {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed channel %s: %w", pattern, err)
+		}
+	}
+
+	t, err := templates.Parse("channel", channel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel template: %w", err)
+	}
+	return &channelRoute{template: t.Option("missingkey=error"), allowed: allowed}, nil
+}
+
+func (r *channelRoute) render(event events.Event) (string, error) {
+	channel, err := templates.Render(r.template, event)
+	if err != nil {
+		return "", err
+	}
+	channel = strings.TrimSpace(channel)
+	if !r.isAllowed(channel) {
+		return "", fmt.Errorf("channel '%s' is not allowed", channel)
+	}
+	return channel, nil
+}
+
+// renderList renders a comma separated list of recipients,
+// each recipient must match one of the allowed channels.
+func (r *channelRoute) renderList(event events.Event) ([]string, error) {
+	list, err := templates.Render(r.template, event)
+	if err != nil {
+		return nil, err
+	}
+	recipients := splitRecipients(list)
+	for _, recipient := range recipients {
+		if !r.isAllowed(recipient) {
+			return nil, fmt.Errorf("recipient '%s' is not allowed", recipient)
+		}
+	}
+	return recipients, nil
+}
+
+func (r *channelRoute) isAllowed(channel string) bool {
+	for _, pattern := range r.allowed {
+		if ok, _ := path.Match(pattern, channel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// channelRouter is implemented by the chat notifiers that can template their channel.
+type channelRouter interface {
+	setChannelRoute(r *channelRoute)
+}
+
+// channelRouting is embedded by the chat notifiers to implement channelRouter.
+type channelRouting struct {
+	route *channelRoute
+}
+
+func (c *channelRouting) setChannelRoute(r *channelRoute) {
+	c.route = r
+}
+
+// channelFor returns the channel of the event, the channel of
+// the notifier is used when it isn't a template.
+func (c *channelRouting) channelFor(event events.Event, channel string) (string, error) {
+	if c.route == nil {
+		return channel, nil
+	}
+	return c.route.render(event)
+}
+
+// recipientsFor returns the recipients of the event, the comma separated
+// recipients of the notifier are used when they aren't a template.
+func (c *channelRouting) recipientsFor(event events.Event, recipients string) ([]string, error) {
+	if c.route == nil {
+		return splitRecipients(recipients), nil
+	}
+	return c.route.renderList(event)
+}
+
+// splitRecipients returns the recipients of a comma separated list.
+func splitRecipients(list string) []string {
+	var recipients []string
+	for _, recipient := range strings.Split(list, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
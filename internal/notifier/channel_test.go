@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestChannelRoute_render(t *testing.T) {
+	route, err := newChannelRoute("#deploys-{{ .Metadata.team }}", []string{"#deploys-payments", "#deploys-web*"})
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Metadata["team"] = "payments"
+	channel, err := route.render(event)
+	require.NoError(t, err)
+	require.Equal(t, "#deploys-payments", channel)
+
+	event.Metadata["team"] = "webapp"
+	channel, err = route.render(event)
+	require.NoError(t, err)
+	require.Equal(t, "#deploys-webapp", channel)
+
+	event.Metadata["team"] = "general"
+	_, err = route.render(event)
+	require.Error(t, err)
+
+	// the missing keys fail the rendering
+	delete(event.Metadata, "team")
+	_, err = route.render(event)
+	require.Error(t, err)
+
+	// unless they are looked up with index
+	route, err = newChannelRoute(`#deploys-{{ index .Metadata "team" | default "payments" }}`, []string{"#deploys-*"})
+	require.NoError(t, err)
+	channel, err = route.render(event)
+	require.NoError(t, err)
+	require.Equal(t, "#deploys-payments", channel)
+
+	_, err = newChannelRoute("#deploys-{{ .Metadata.team }}", nil)
+	require.Error(t, err)
+	_, err = newChannelRoute("#deploys-{{ .Metadata.team }}", []string{"#deploys-["})
+	require.Error(t, err)
+	_, err = newChannelRoute("#deploys-{{ .Metadata.team", []string{"#deploys-*"})
+	require.Error(t, err)
+}
+
+func TestFactory_ChannelTemplate(t *testing.T) {
+	var channels []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload SlackPayload
+		require.NoError(t, json.Unmarshal(b, &payload))
+		channels = append(channels, payload.Channel)
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL, "", "", "#deploys-{{ .Metadata.test }}", "", nil)
+	factory.AllowedChannels = []string{"#deploys-metadata"}
+	slack, err := factory.Notifier(v1beta1.SlackProvider)
+	require.NoError(t, err)
+
+	event := testEvent()
+	require.NoError(t, slack.Post(event))
+	event.Metadata["test"] = "other"
+	require.Error(t, slack.Post(event))
+	require.Equal(t, []string{"#deploys-metadata"}, channels)
+
+	factory.AllowedChannels = nil
+	_, err = factory.Notifier(v1beta1.SlackProvider)
+	require.Error(t, err)
+
+	factory.AllowedChannels = []string{"#deploys-*"}
+	_, err = factory.Notifier(v1beta1.MSTeamsProvider)
+	require.Error(t, err)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// clickHouseTable matches the table names, optionally qualified with the
+// database, which are interpolated in the INSERT query.
+var clickHouseTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ClickHouse is an implementation of the notification Interface that inserts
+// the events into a ClickHouse table with the HTTP interface.
+type ClickHouse struct {
+	// URL is the address of the HTTP interface, e.g. 'https://clickhouse.example.com:8443'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Table is the name of the table, e.g. 'flux.events'.
+	Table    string
+	Username string
+	Password string
+
+	channelRouting
+	requestConfig
+}
+
+// NewClickHouse returns a notifier for the HTTP interface address, the channel
+// is the name of the table, the username and the token authenticate the inserts.
+func NewClickHouse(addr, proxyURL, table, username, password string, certPool *x509.CertPool) (*ClickHouse, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse address %s: %w", addr, err)
+	}
+	if table == "" {
+		return nil, errors.New("clickhouse table cannot be empty")
+	}
+
+	return &ClickHouse{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Table:    table,
+		Username: username,
+		Password: password,
+	}, nil
+}
+
+// Post inserts a row with the columns of the event in the JSONEachRow format.
+func (c *ClickHouse) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	table, err := c.channelFor(event, c.Table)
+	if err != nil {
+		return err
+	}
+	if !clickHouseTable.MatchString(table) {
+		return fmt.Errorf("invalid ClickHouse table '%s'", table)
+	}
+
+	body, err := json.Marshal(eventRow(event))
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("query", fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table))
+	u.RawQuery = q.Encode()
+
+	auth := func(req *retryablehttp.Request) {
+		if c.Username != "" {
+			req.Header.Set("X-ClickHouse-User", c.Username)
+		}
+		if c.Password != "" {
+			req.Header.Set("X-ClickHouse-Key", c.Password)
+		}
+	}
+	return c.transport.sendRequest(http.MethodPost, u.String(), c.ProxyURL, c.CertPool, body, nil, auth, c.withCapture(), c.withDelivery(event))
+}
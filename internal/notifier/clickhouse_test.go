@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickHouse_Post(t *testing.T) {
+	var queries []string
+	var rows []map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "flux", r.Header.Get("X-ClickHouse-User"))
+		require.Equal(t, "s3cr3t", r.Header.Get("X-ClickHouse-Key"))
+		queries = append(queries, r.URL.Query().Get("query"))
+		var row map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&row))
+		rows = append(rows, row)
+	}))
+	defer ts.Close()
+
+	clickhouse, err := NewClickHouse(ts.URL, "", "flux.events", "flux", "s3cr3t", nil)
+	require.NoError(t, err)
+	require.NoError(t, clickhouse.Post(testEvent()))
+	require.Equal(t, []string{"INSERT INTO flux.events FORMAT JSONEachRow"}, queries)
+	require.Equal(t, "webapp", rows[0]["name"])
+	require.Equal(t, "source-controller", rows[0]["controller"])
+
+	// the table names are interpolated in the query
+	route, err := newChannelRoute(`{{ .InvolvedObject.Namespace }}`, []string{"*"})
+	require.NoError(t, err)
+	clickhouse.setChannelRoute(route)
+	require.Error(t, clickhouse.Post(testEvent()))
+	require.Len(t, queries, 1)
+}
+
+func TestNewClickHouse(t *testing.T) {
+	_, err := NewClickHouse("", "", "events", "", "", nil)
+	require.Error(t, err)
+	_, err = NewClickHouse("http://clickhouse:8123", "", "", "", "", nil)
+	require.Error(t, err)
+}
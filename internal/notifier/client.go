@@ -17,10 +17,15 @@ limitations under the License.
 package notifier
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -28,58 +33,158 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/fluxcd/notification-controller/internal/egress"
 )
 
 type requestOptFunc func(*retryablehttp.Request)
 
-func postMessage(address, proxy string, certPool *x509.CertPool, payload interface{}, reqOpts ...requestOptFunc) error {
-	httpClient := retryablehttp.NewClient()
-	if certPool != nil {
-		httpClient.HTTPClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: certPool,
-			},
-		}
+// Transport holds the settings of the connections opened by the notifiers.
+// A nil transport uses the default TLS configuration and permits every address.
+type Transport struct {
+	// TLSConfig sets the minimum TLS version and the cipher suites of the clients.
+	TLSConfig *tls.Config
+
+	// EgressPolicy is enforced on the IPs the notifiers connect to.
+	EgressPolicy *egress.Policy
+}
+
+// Restrict returns a transport whose egress policy also requires the
+// addresses to be in the allowlist, the transport is returned as is
+// when the allowlist is empty.
+func (t *Transport) Restrict(allow []string) (*Transport, error) {
+	policy, err := t.egressPolicy().Restrict(allow)
+	if err != nil {
+		return nil, err
+	}
+	if policy == t.egressPolicy() {
+		return t, nil
+	}
+	r := &Transport{EgressPolicy: policy}
+	if t != nil {
+		r.TLSConfig = t.TLSConfig
 	}
+	return r, nil
+}
 
-	if proxy != "" {
-		proxyURL, err := url.Parse(proxy)
+// transporter is implemented by the notifiers opening their connections
+// with the transport.
+type transporter interface {
+	setTransport(t *Transport)
+}
+
+func (t *Transport) egressPolicy() *egress.Policy {
+	if t == nil {
+		return nil
+	}
+	return t.EgressPolicy
+}
+
+// Check returns an error wrapping egress.ErrDenied if the address
+// is not permitted by the egress policy.
+func (t *Transport) Check(ctx context.Context, address string) error {
+	return t.egressPolicy().Check(ctx, address)
+}
+
+// dialContext returns a dial function connecting only to the IPs
+// permitted by the egress policy.
+func (t *Transport) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return t.egressPolicy().DialContext(dialer)
+}
+
+// dialProxy returns a dial function opening a CONNECT tunnel to the address through
+// the HTTP proxy, the proxy is dialed like dialContext does and the address is checked
+// against the egress policy, like the proxied HTTP requests of newHTTPClient.
+func (t *Transport) dialProxy(proxyURL *url.URL, dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dial := t.dialContext(dialer)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if proxyURL.Scheme != "http" {
+			return nil, fmt.Errorf("unsupported proxy scheme '%s', only HTTP proxies can tunnel the connections", proxyURL.Scheme)
+		}
+		if err := t.Check(ctx, address); err != nil {
+			return nil, err
+		}
+
+		proxyAddress := proxyURL.Host
+		if proxyURL.Port() == "" {
+			proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+		conn, err := dial(ctx, network, proxyAddress)
 		if err != nil {
-			return fmt.Errorf("unable to parse proxy URL '%s', error: %w", proxy, err)
+			return nil, err
 		}
-		var tlsConfig *tls.Config
-		if certPool != nil {
-			tlsConfig = &tls.Config{
-				RootCAs: certPool,
-			}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
 		}
-		httpClient.HTTPClient.Transport = &http.Transport{
-			Proxy:           http.ProxyURL(proxyURL),
-			TLSClientConfig: tlsConfig,
-			DialContext: (&net.Dialer{
-				Timeout:   15 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: address},
+			Host:   address,
+			Header: make(http.Header),
+		}
+		if user := proxyURL.User; user != nil {
+			password, _ := user.Password()
+			credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+credentials)
 		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		// the server doesn't send anything before the client, nothing is lost in the buffer
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy CONNECT to %s failed with status %s", address, resp.Status)
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, nil
 	}
+}
 
-	httpClient.HTTPClient.Timeout = 15 * time.Second
-	httpClient.RetryWaitMin = 2 * time.Second
-	httpClient.RetryWaitMax = 30 * time.Second
-	httpClient.RetryMax = 4
-	httpClient.Logger = nil
+// tlsConfig returns the TLS configuration of the transport with the CA
+// certificates added, it returns nil when neither are set.
+func (t *Transport) tlsConfig(certPool *x509.CertPool) *tls.Config {
+	var base *tls.Config
+	if t != nil {
+		base = t.TLSConfig
+	}
+	if base == nil && certPool == nil {
+		return nil
+	}
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	if certPool != nil {
+		config.RootCAs = certPool
+	}
+	return config
+}
 
+func (t *Transport) postMessage(address, proxy string, certPool *x509.CertPool, payload interface{}, reqOpts ...requestOptFunc) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("marshalling notification payload failed: %w", err)
 	}
 
-	req, err := retryablehttp.NewRequest(http.MethodPost, address, data)
+	return t.sendMessage(http.MethodPost, address, proxy, certPool, data, reqOpts...)
+}
+
+// sendMessage sends the body with the method, as JSON unless the request options
+// set another content type, the response is ignored like in postMessage.
+func (t *Transport) sendMessage(method, address, proxy string, certPool *x509.CertPool, body []byte, reqOpts ...requestOptFunc) error {
+	httpClient, err := t.newHTTPClient(proxy, certPool)
+	if err != nil {
+		return err
+	}
+
+	req, err := retryablehttp.NewRequest(method, address, body)
 	if err != nil {
 		return fmt.Errorf("failed to create a new request: %w", err)
 	}
@@ -87,9 +192,119 @@ func postMessage(address, proxy string, certPool *x509.CertPool, payload interfa
 	for _, o := range reqOpts {
 		o(req)
 	}
+	configureDelivery(httpClient, req)
 	if _, err := httpClient.Do(req); err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
 
 	return nil
 }
+
+// sendRequest sends a JSON request and decodes the JSON response into out, if not nil,
+// the responses with an error status are returned as errors.
+func (t *Transport) sendRequest(method, address, proxy string, certPool *x509.CertPool, body []byte, out interface{}, reqOpts ...requestOptFunc) error {
+	httpClient, err := t.newHTTPClient(proxy, certPool)
+	if err != nil {
+		return err
+	}
+
+	var reqBody interface{}
+	if body != nil {
+		reqBody = body
+	}
+	req, err := retryablehttp.NewRequest(method, address, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create a new request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for _, o := range reqOpts {
+		o(req)
+	}
+	configureDelivery(httpClient, req)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %s", resp.Status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode the response: %w", err)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns a retrying client which connects through the proxy, if any,
+// to the IPs allowed by the egress policy. With a proxy, the pinned dial only covers
+// the proxy address, so the target host is checked before each request is sent to
+// the proxy, which resolves it again.
+func (t *Transport) newHTTPClient(proxy string, certPool *x509.CertPool) (*retryablehttp.Client, error) {
+	httpClient := retryablehttp.NewClient()
+	if config := t.tlsConfig(certPool); config != nil {
+		httpClient.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: config,
+		}
+	}
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse proxy URL '%s', error: %w", proxy, err)
+		}
+		httpClient.HTTPClient.Transport = &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				if err := t.Check(req.Context(), req.URL.Host); err != nil {
+					return nil, err
+				}
+				return proxyURL, nil
+			},
+			TLSClientConfig:       t.tlsConfig(certPool),
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
+		}
+	}
+
+	// connect only to the IPs checked against the egress policy
+	if tr, ok := httpClient.HTTPClient.Transport.(*http.Transport); ok {
+		tr.DialContext = t.dialContext(&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 30 * time.Second,
+		})
+	}
+
+	transport := httpClient.HTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient.HTTPClient.Transport = &captureTransport{next: transport}
+
+	httpClient.HTTPClient.Timeout = 15 * time.Second
+	httpClient.RetryWaitMin = 2 * time.Second
+	httpClient.RetryWaitMax = 30 * time.Second
+	httpClient.RetryMax = 4
+	httpClient.CheckRetry = checkRetry
+	httpClient.Logger = nil
+	return httpClient, nil
+}
+
+// checkRetry doesn't retry the requests denied by the egress policy.
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if errors.Is(err, egress.ErrDenied) {
+		return false, err
+	}
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
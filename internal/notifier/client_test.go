@@ -17,8 +17,11 @@ limitations under the License.
 package notifier
 
 import (
+	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -29,6 +32,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/internal/egress"
 )
 
 func Test_postMessage(t *testing.T) {
@@ -43,10 +48,46 @@ func Test_postMessage(t *testing.T) {
 		require.Equal(t, "success", payload["status"])
 	}))
 	defer ts.Close()
-	err := postMessage(ts.URL, "", nil, map[string]string{"status": "success"})
+	var transport *Transport
+	err := transport.postMessage(ts.URL, "", nil, map[string]string{"status": "success"})
 	require.NoError(t, err)
 }
 
+func Test_postMessageEgressDenied(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request should have been denied")
+	}))
+	defer ts.Close()
+
+	policy, err := egress.ParsePolicy(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	transport := &Transport{EgressPolicy: policy}
+
+	err = transport.postMessage(ts.URL, "", nil, map[string]string{"status": "success"})
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
+}
+
+func Test_postMessageProxyEgressDenied(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+	}))
+	defer proxy.Close()
+
+	// the proxy is permitted, but not the hosts outside the allowlist
+	policy, err := egress.ParsePolicy([]string{"127.0.0.0/8"}, nil)
+	require.NoError(t, err)
+	transport := &Transport{EgressPolicy: policy}
+
+	err = transport.postMessage("http://10.0.0.1/hook", proxy.URL, nil, map[string]string{"status": "success"})
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
+	require.Empty(t, proxied)
+
+	err = transport.postMessage("http://127.0.0.1:8080/hook", proxy.URL, nil, map[string]string{"status": "success"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1:8080"}, proxied)
+}
+
 func Test_postSelfSignedCert(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		b, err := ioutil.ReadAll(r.Body)
@@ -64,8 +105,31 @@ func Test_postSelfSignedCert(t *testing.T) {
 	require.NoError(t, err)
 	certpool := x509.NewCertPool()
 	certpool.AddCert(cert)
-	err = postMessage(ts.URL, "", certpool, map[string]string{"status": "success"})
+	var transport *Transport
+	err = transport.postMessage(ts.URL, "", certpool, map[string]string{"status": "success"})
+	require.NoError(t, err)
+}
+
+func TestTransport_Restrict(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request should have been denied")
+	}))
+	defer ts.Close()
+
+	policy, err := egress.ParsePolicy(nil, egress.DefaultBlocklist)
 	require.NoError(t, err)
+	transport := &Transport{EgressPolicy: policy}
+
+	restricted, err := transport.Restrict(nil)
+	require.NoError(t, err)
+	require.Same(t, transport, restricted)
+
+	restricted, err = transport.Restrict([]string{"hooks.example.com"})
+	require.NoError(t, err)
+	require.True(t, errors.Is(restricted.Check(context.TODO(), ts.URL), egress.ErrDenied))
+
+	err = restricted.postMessage(ts.URL, "", nil, map[string]string{"status": "success"})
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
 }
 
 func testEvent() events.Event {
@@ -86,3 +150,30 @@ func testEvent() events.Event {
 		ReportingInstance:   "source-controller-xyz",
 	}
 }
+
+func Test_postMessageTLSConfig(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, uint16(tls.VersionTLS12), r.TLS.Version)
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cert, err := x509.ParseCertificate(ts.TLS.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	certpool := x509.NewCertPool()
+	certpool.AddCert(cert)
+
+	transport := &Transport{TLSConfig: &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}}
+
+	config := transport.tlsConfig(certpool)
+	require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	require.Equal(t, certpool, config.RootCAs)
+	require.Nil(t, transport.TLSConfig.RootCAs)
+
+	err = transport.postMessage(ts.URL, "", certpool, map[string]string{"status": "success"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcpMetadataTokenURL returns the access token of the service account
+	// of the controller, e.g. bound with GKE workload identity.
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	// azureStorageScope is the scope of the Azure Storage access tokens.
+	azureStorageScope = "https://storage.azure.com/.default"
+)
+
+var (
+	// gcpIdentity and azureIdentity cache the access tokens of the controller
+	// identity, the token endpoints are the controller ones so that they're
+	// not subject to the egress policy of the provider addresses.
+	gcpIdentity   = &identityToken{client: &http.Client{Timeout: 15 * time.Second}, fetch: fetchGCPToken}
+	azureIdentity = &identityToken{client: &http.Client{Timeout: 15 * time.Second}, fetch: fetchAzureToken}
+)
+
+// accessToken is an OAuth 2.0 access token response.
+type accessToken struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// identityToken caches an access token until shortly before it expires.
+type identityToken struct {
+	client *http.Client
+	fetch  func(client *http.Client, endpoint string) (accessToken, error)
+
+	// endpoint overrides the token endpoint, if set.
+	endpoint string
+
+	mu         sync.Mutex
+	token      string
+	expiration time.Time
+}
+
+func (t *identityToken) get(now time.Time) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && now.Add(5*time.Minute).Before(t.expiration) {
+		return t.token, nil
+	}
+	token, err := t.fetch(t.client, t.endpoint)
+	if err != nil {
+		return "", err
+	}
+	expiresIn, err := token.ExpiresIn.Int64()
+	if err != nil || token.AccessToken == "" {
+		return "", errors.New("invalid access token response")
+	}
+	t.token = token.AccessToken
+	t.expiration = now.Add(time.Duration(expiresIn) * time.Second)
+	return t.token, nil
+}
+
+// fetchGCPToken returns the access token of the controller service account
+// from the metadata server.
+func fetchGCPToken(client *http.Client, endpoint string) (accessToken, error) {
+	if endpoint == "" {
+		endpoint = gcpMetadataTokenURL
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return accessToken{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return doTokenRequest(client, req, "GCP metadata server")
+}
+
+// fetchAzureToken exchanges the federated token of the controller, e.g. bound
+// with AKS workload identity, for a Microsoft Entra ID access token.
+func fetchAzureToken(client *http.Client, endpoint string) (accessToken, error) {
+	clientID, tenantID := os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_TENANT_ID")
+	tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	if clientID == "" || tenantID == "" || tokenFile == "" {
+		return accessToken{}, errors.New("the Azure workload identity environment variables are not set")
+	}
+	assertion, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("failed to read the Azure federated token: %w", err)
+	}
+
+	if endpoint == "" {
+		authority := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = "https://login.microsoftonline.com/"
+		}
+		endpoint = strings.TrimSuffix(authority, "/") + "/" + tenantID + "/oauth2/v2.0/token"
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("scope", azureStorageScope)
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return accessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(client, req, "Microsoft Entra ID")
+}
+
+func doTokenRequest(client *http.Client, req *http.Request, issuer string) (accessToken, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("failed to get an access token from the %s: %w", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return accessToken{}, fmt.Errorf("failed to get an access token from the %s: %s", issuer, resp.Status)
+	}
+	var token accessToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return accessToken{}, fmt.Errorf("failed to decode the %s token: %w", issuer, err)
+	}
+	return token, nil
+}
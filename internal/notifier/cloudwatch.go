@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	cloudWatchLogsService   = "logs"
+	cloudWatchEventsService = "events"
+
+	// cloudWatchDefaultSource is the log stream of the log events and
+	// the source of the EventBridge events when the provider has no username.
+	cloudWatchDefaultSource = "fluxcd.notification-controller"
+
+	// cloudWatchDetailType is the detail type of the EventBridge events.
+	cloudWatchDetailType = "Flux Event"
+)
+
+// cloudWatchStreams holds the log streams known to exist, keyed by endpoint,
+// log group and stream name, so that each stream is created once.
+var cloudWatchStreams sync.Map
+
+// awsError is an error response of the AWS JSON protocol.
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+	status  string
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("request failed with status %s: %s: %s", e.status, e.Type, e.Message)
+}
+
+// isAWSError returns true if the error is an AWS error response of the type,
+// the type of the response may be prefixed with the service namespace, e.g.
+// 'com.amazonaws.logs#ResourceAlreadyExistsException'.
+func isAWSError(err error, errorType string) bool {
+	var e *awsError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Type == errorType || strings.HasSuffix(e.Type, "#"+errorType)
+}
+
+// CloudWatch is an implementation of the notification Interface that writes
+// the events to a CloudWatch Logs group, or sends them to an EventBridge
+// (CloudWatch Events) bus, depending on the endpoint of the address.
+type CloudWatch struct {
+	Endpoint string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Service is either 'logs' or 'events'.
+	Service string
+	Region  string
+
+	// Target is the log group or the event bus.
+	Target string
+
+	// Source is the log stream or the event source.
+	Source string
+
+	Credentials AWSCredentials
+
+	requestConfig
+}
+
+// NewCloudWatch returns a notifier for the CloudWatch Logs or EventBridge
+// endpoint of the address, e.g. 'https://logs.eu-west-1.amazonaws.com'.
+// The service and the region of the endpoints that don't follow the AWS naming,
+// e.g. the VPC endpoints, are set with the 'service' and 'region' query parameters.
+func NewCloudWatch(address, proxyURL, channel, username string, credentials AWSCredentials, certPool *x509.CertPool) (*CloudWatch, error) {
+	u, err := url.ParseRequestURI(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CloudWatch endpoint %s: %w", address, err)
+	}
+
+	service, region := parseAWSEndpoint(u.Hostname(), cloudWatchLogsService, cloudWatchEventsService)
+	query := u.Query()
+	if s := query.Get("service"); s != "" {
+		service = s
+	}
+	if r := query.Get("region"); r != "" {
+		region = r
+	}
+	u.RawQuery = ""
+
+	if service != cloudWatchLogsService && service != cloudWatchEventsService {
+		return nil, fmt.Errorf("invalid CloudWatch endpoint %s: the service must be '%s' or '%s'",
+			address, cloudWatchLogsService, cloudWatchEventsService)
+	}
+	if region == "" {
+		return nil, fmt.Errorf("invalid CloudWatch endpoint %s: no region", address)
+	}
+	if service == cloudWatchLogsService && channel == "" {
+		return nil, errors.New("CloudWatch log group cannot be empty")
+	}
+
+	credentials, err = credentials.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if username == "" {
+		username = cloudWatchDefaultSource
+	}
+	return &CloudWatch{
+		Endpoint:    u.String(),
+		ProxyURL:    proxyURL,
+		CertPool:    certPool,
+		Service:     service,
+		Region:      region,
+		Target:      channel,
+		Source:      username,
+		Credentials: credentials,
+	}, nil
+}
+
+// Post writes the event as a JSON log event, or sends it as the detail
+// of an EventBridge event.
+func (c *CloudWatch) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	timestamp := event.Timestamp.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	if c.Service == cloudWatchEventsService {
+		entry := map[string]interface{}{
+			"Source":     c.Source,
+			"DetailType": cloudWatchDetailType,
+			"Detail":     string(detail),
+			"Time":       timestamp.Unix(),
+		}
+		if c.Target != "" {
+			entry["EventBusName"] = c.Target
+		}
+		payload := map[string]interface{}{"Entries": []interface{}{entry}}
+		if err := c.call(event, "AWSEvents.PutEvents", payload); err != nil {
+			return fmt.Errorf("postMessage failed: %w", err)
+		}
+		return nil
+	}
+
+	// the log stream is created once, the stream may have been
+	// created by another controller instance or a previous run
+	key := strings.Join([]string{c.Endpoint, c.Target, c.Source}, "/")
+	if _, ok := cloudWatchStreams.Load(key); !ok {
+		stream := map[string]interface{}{
+			"logGroupName":  c.Target,
+			"logStreamName": c.Source,
+		}
+		err := c.call(event, "Logs_20140328.CreateLogStream", stream)
+		if err != nil && !isAWSError(err, "ResourceAlreadyExistsException") {
+			return fmt.Errorf("postMessage failed: %w", err)
+		}
+		cloudWatchStreams.Store(key, true)
+	}
+
+	payload := map[string]interface{}{
+		"logGroupName":  c.Target,
+		"logStreamName": c.Source,
+		"logEvents": []interface{}{
+			map[string]interface{}{
+				"timestamp": timestamp.UnixNano() / int64(time.Millisecond),
+				"message":   string(detail),
+			},
+		},
+	}
+	if err := c.call(event, "Logs_20140328.PutLogEvents", payload); err != nil {
+		// the stream is created again by the next event when it was deleted
+		if isAWSError(err, "ResourceNotFoundException") {
+			cloudWatchStreams.Delete(key)
+		}
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
+
+// call sends a signed request to an action of the AWS JSON protocol,
+// the error responses are returned as an awsError.
+func (c *CloudWatch) call(event events.Event, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	httpClient, err := c.transport.newHTTPClient(c.ProxyURL, c.CertPool)
+	if err != nil {
+		return err
+	}
+	req, err := retryablehttp.NewRequest(http.MethodPost, c.Endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create a new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	signAWSRequest(req.Request, body, c.Credentials, c.Region, c.Service, time.Now())
+	for _, o := range []requestOptFunc{c.withCapture(), c.withDelivery(event)} {
+		o(req)
+	}
+	configureDelivery(httpClient, req)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response: %w", err)
+	}
+	awsErr := &awsError{status: resp.Status}
+	if err := json.Unmarshal(data, awsErr); err != nil {
+		return fmt.Errorf("request failed with status %s", resp.Status)
+	}
+	return awsErr
+}
+
+// parseAWSEndpoint returns the service, one of services, and the region of hosts
+// formatted as '[<prefix>.]<service>.<region>.<domain>', e.g. 'logs.eu-west-1.amazonaws.com'.
+func parseAWSEndpoint(host string, services ...string) (service, region string) {
+	labels := strings.Split(host, ".")
+	for i := 0; i+2 < len(labels); i++ {
+		for _, s := range services {
+			if labels[i] == s {
+				return labels[i], labels[i+1]
+			}
+		}
+	}
+	return "", ""
+}
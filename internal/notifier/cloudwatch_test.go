@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+var testAWSCredentials = AWSCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// get-vanilla of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signAWSRequest(req, nil, testAWSCredentials, "us-east-1", "service", now)
+
+	require.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	require.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestCloudWatch_PostLogs(t *testing.T) {
+	var targets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/logs/aws4_request")
+		targets = append(targets, r.Header.Get("X-Amz-Target"))
+
+		var payload struct {
+			LogGroupName  string
+			LogStreamName string
+			LogEvents     []struct {
+				Timestamp int64
+				Message   string
+			}
+		}
+		require.NoError(t, json.Unmarshal(b, &payload))
+		require.Equal(t, "/flux/events", payload.LogGroupName)
+		require.Equal(t, cloudWatchDefaultSource, payload.LogStreamName)
+		if r.Header.Get("X-Amz-Target") == "Logs_20140328.PutLogEvents" {
+			require.Len(t, payload.LogEvents, 1)
+			var event events.Event
+			require.NoError(t, json.Unmarshal([]byte(payload.LogEvents[0].Message), &event))
+			require.Equal(t, "webapp", event.InvolvedObject.Name)
+		}
+	}))
+	defer ts.Close()
+
+	cw, err := NewCloudWatch(ts.URL+"?service=logs&region=eu-west-1", "", "/flux/events", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Equal(t, ts.URL, cw.Endpoint)
+
+	require.NoError(t, cw.Post(testEvent()))
+	require.Equal(t, []string{"Logs_20140328.CreateLogStream", "Logs_20140328.PutLogEvents"}, targets)
+
+	// the stream is created once
+	require.NoError(t, cw.Post(testEvent()))
+	require.Equal(t, []string{"Logs_20140328.CreateLogStream", "Logs_20140328.PutLogEvents",
+		"Logs_20140328.PutLogEvents"}, targets)
+}
+
+func TestCloudWatch_PostLogsStreamErrors(t *testing.T) {
+	var targets []string
+	putStatus := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		targets = append(targets, target)
+		switch {
+		case target == "Logs_20140328.CreateLogStream":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceAlreadyExistsException","message":"The specified log stream already exists"}`))
+		case putStatus != http.StatusOK:
+			w.WriteHeader(putStatus)
+			w.Write([]byte(`{"__type":"com.amazonaws.logs#ResourceNotFoundException","message":"The specified log stream does not exist."}`))
+		}
+	}))
+	defer ts.Close()
+
+	cw, err := NewCloudWatch(ts.URL+"?service=logs&region=eu-west-1", "", "/flux/events", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+
+	// the existing stream is not an error
+	require.NoError(t, cw.Post(testEvent()))
+	require.Equal(t, []string{"Logs_20140328.CreateLogStream", "Logs_20140328.PutLogEvents"}, targets)
+
+	// the deleted stream is created again by the next event
+	putStatus = http.StatusBadRequest
+	err = cw.Post(testEvent())
+	require.Error(t, err)
+	require.True(t, isAWSError(err, "ResourceNotFoundException"))
+
+	putStatus = http.StatusOK
+	targets = nil
+	require.NoError(t, cw.Post(testEvent()))
+	require.Equal(t, []string{"Logs_20140328.CreateLogStream", "Logs_20140328.PutLogEvents"}, targets)
+}
+
+func TestCloudWatch_PostEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "AWSEvents.PutEvents", r.Header.Get("X-Amz-Target"))
+
+		var payload struct {
+			Entries []struct {
+				Source       string
+				DetailType   string
+				Detail       string
+				EventBusName string
+			}
+		}
+		require.NoError(t, json.Unmarshal(b, &payload))
+		require.Len(t, payload.Entries, 1)
+		require.Equal(t, "flux", payload.Entries[0].Source)
+		require.Equal(t, cloudWatchDetailType, payload.Entries[0].DetailType)
+		require.Equal(t, "", payload.Entries[0].EventBusName)
+		var event events.Event
+		require.NoError(t, json.Unmarshal([]byte(payload.Entries[0].Detail), &event))
+		require.Equal(t, "metadata", event.Metadata["test"])
+	}))
+	defer ts.Close()
+
+	cw, err := NewCloudWatch(ts.URL+"?service=events&region=eu-west-1", "", "", "flux", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.NoError(t, cw.Post(testEvent()))
+}
+
+func TestNewCloudWatch(t *testing.T) {
+	cw, err := NewCloudWatch("https://logs.eu-west-1.amazonaws.com", "", "flux", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Equal(t, cloudWatchLogsService, cw.Service)
+	require.Equal(t, "eu-west-1", cw.Region)
+
+	cw, err = NewCloudWatch("https://vpce-0123.events.us-east-1.vpce.amazonaws.com", "", "", "", testAWSCredentials, nil)
+	require.NoError(t, err)
+	require.Equal(t, cloudWatchEventsService, cw.Service)
+	require.Equal(t, "us-east-1", cw.Region)
+
+	// the log group is required
+	_, err = NewCloudWatch("https://logs.eu-west-1.amazonaws.com", "", "", "", testAWSCredentials, nil)
+	require.Error(t, err)
+	_, err = NewCloudWatch("https://monitoring.eu-west-1.amazonaws.com", "", "flux", "", testAWSCredentials, nil)
+	require.Error(t, err)
+	_, err = NewCloudWatch("https://cloudwatch.example.com", "", "flux", "", testAWSCredentials, nil)
+	require.Error(t, err)
+
+	_, err = NewCloudWatch("https://logs.eu-west-1.amazonaws.com", "", "flux", "", AWSCredentials{AccessKeyID: "AKIDEXAMPLE"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// DeliveryIDHeader is the header carrying the delivery ID of the requests,
+	// the ID is the same for all the attempts of a delivery.
+	DeliveryIDHeader = "gotk-delivery-id"
+
+	// DeliveryIDMetadataKey is the metadata key of the delivery ID
+	// in the JSON payloads of the generic webhook notifier.
+	DeliveryIDMetadataKey = "deliveryID"
+)
+
+// NewDeliveryID returns a random ID identifying the delivery
+// of an event to a provider.
+func NewDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return sha1String(time.Now().String())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Delivery tunes the requests of the webhook based notifiers,
+// the zero values keep the defaults of the client.
+type Delivery struct {
+	// Timeout of each request attempt.
+	Timeout time.Duration
+
+	// Retries is the number of retries, the requests are
+	// retried 4 times when nil.
+	Retries *int
+
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between the retries.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
+	// IdempotencyKeyHeader is the header carrying the identity of the event, if any.
+	IdempotencyKeyHeader string
+}
+
+// NewDelivery returns the delivery settings of a provider, or nil if it has none.
+func NewDelivery(spec *v1beta1.ProviderDelivery) *Delivery {
+	if spec == nil {
+		return nil
+	}
+	d := &Delivery{
+		Retries:              spec.Retries,
+		IdempotencyKeyHeader: spec.IdempotencyKeyHeader,
+	}
+	if spec.Timeout != nil {
+		d.Timeout = spec.Timeout.Duration
+	}
+	if spec.RetryWaitMin != nil {
+		d.RetryWaitMin = spec.RetryWaitMin.Duration
+	}
+	if spec.RetryWaitMax != nil {
+		d.RetryWaitMax = spec.RetryWaitMax.Duration
+	}
+	return d
+}
+
+// deliverer is implemented by the notifiers supporting the delivery settings.
+type deliverer interface {
+	setDelivery(d *Delivery)
+}
+
+func (d *requestConfig) setDelivery(delivery *Delivery) {
+	d.delivery = delivery
+}
+
+// deliveryTracer is implemented by the notifiers sending the delivery ID.
+type deliveryTracer interface {
+	setDeliveryID(id string)
+}
+
+func (d *requestConfig) setDeliveryID(id string) {
+	d.deliveryID = id
+}
+
+type deliveryKey struct{}
+
+// withDelivery sets the delivery ID and applies the delivery settings,
+// if any, to the request of the event.
+func (d *requestConfig) withDelivery(event events.Event) requestOptFunc {
+	return func(req *retryablehttp.Request) {
+		if d.deliveryID != "" {
+			req.Header.Set(DeliveryIDHeader, d.deliveryID)
+		}
+		if d.delivery == nil {
+			return
+		}
+		if d.delivery.IdempotencyKeyHeader != "" {
+			req.Header.Set(d.delivery.IdempotencyKeyHeader, idempotencyKey(event))
+		}
+		req.Request = req.Request.WithContext(context.WithValue(req.Context(), deliveryKey{}, d.delivery))
+	}
+}
+
+// configureDelivery sets the timeout and the retries of the client sending
+// the request, when the request carries delivery settings.
+func configureDelivery(httpClient *retryablehttp.Client, req *retryablehttp.Request) {
+	delivery, ok := req.Context().Value(deliveryKey{}).(*Delivery)
+	if !ok {
+		return
+	}
+	if delivery.Timeout > 0 {
+		httpClient.HTTPClient.Timeout = delivery.Timeout
+	}
+	if delivery.Retries != nil {
+		httpClient.RetryMax = *delivery.Retries
+	}
+	if delivery.RetryWaitMin > 0 {
+		httpClient.RetryWaitMin = delivery.RetryWaitMin
+	}
+	if delivery.RetryWaitMax > 0 {
+		httpClient.RetryWaitMax = delivery.RetryWaitMax
+	}
+}
+
+// idempotencyKey identifies the event, the key is the same
+// for all the deliveries of an event to a provider.
+func idempotencyKey(event events.Event) string {
+	obj := event.InvolvedObject
+	return sha1String(fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s", obj.Kind, obj.Namespace, obj.Name,
+		event.Severity, event.Reason, event.Message, event.Timestamp.UTC().Format(time.RFC3339Nano)))
+}
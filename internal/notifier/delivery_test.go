@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestDelivery_Retries(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	retries := 1
+	factory := NewFactory(ts.URL, "", "", "", "", nil)
+	factory.Delivery = &Delivery{Retries: &retries, RetryWaitMin: time.Millisecond, RetryWaitMax: time.Millisecond}
+	n, err := factory.Notifier("generic")
+	require.NoError(t, err)
+
+	require.Error(t, n.Post(testEvent()))
+	require.Equal(t, 2, attempts)
+}
+
+func TestDelivery_IdempotencyKey(t *testing.T) {
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL, "", "", "general", "", nil)
+	factory.Delivery = &Delivery{IdempotencyKeyHeader: "Idempotency-Key"}
+	n, err := factory.Notifier("slack")
+	require.NoError(t, err)
+
+	event := testEvent()
+	require.NoError(t, n.Post(event))
+	require.NoError(t, n.Post(event))
+	event.Message = "other"
+	require.NoError(t, n.Post(event))
+
+	require.Len(t, keys, 3)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+	require.NotEqual(t, keys[0], keys[2])
+}
+
+func TestDelivery_DeliveryID(t *testing.T) {
+	var ids []string
+	var payloads []events.Event
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(DeliveryIDHeader))
+		var payload events.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL, "", "", "", "", nil)
+	factory.Delivery = &Delivery{RetryWaitMin: time.Millisecond, RetryWaitMax: time.Millisecond}
+	factory.DeliveryID = NewDeliveryID()
+	n, err := factory.Notifier("generic")
+	require.NoError(t, err)
+
+	event := testEvent()
+	require.NoError(t, n.Post(event))
+
+	// the retries keep the delivery ID
+	require.Equal(t, []string{factory.DeliveryID, factory.DeliveryID}, ids)
+	require.Equal(t, factory.DeliveryID, payloads[1].Metadata[DeliveryIDMetadataKey])
+	require.Equal(t, "metadata", payloads[1].Metadata["test"])
+	require.NotContains(t, event.Metadata, DeliveryIDMetadataKey)
+
+	require.NotEqual(t, factory.DeliveryID, NewDeliveryID())
+}
+
+func TestNewDelivery(t *testing.T) {
+	require.Nil(t, NewDelivery(nil))
+
+	retries := 0
+	d := NewDelivery(&v1beta1.ProviderDelivery{
+		Timeout:              &metav1.Duration{Duration: 5 * time.Second},
+		Retries:              &retries,
+		RetryWaitMax:         &metav1.Duration{Duration: time.Minute},
+		IdempotencyKeyHeader: "Idempotency-Key",
+	})
+	require.Equal(t, 5*time.Second, d.Timeout)
+	require.Equal(t, 0, *d.Retries)
+	require.Equal(t, time.Duration(0), d.RetryWaitMin)
+	require.Equal(t, time.Minute, d.RetryWaitMax)
+	require.Equal(t, "Idempotency-Key", d.IdempotencyKeyHeader)
+}
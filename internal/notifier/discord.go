@@ -32,6 +32,9 @@ type Discord struct {
 	ProxyURL string
 	Username string
 	Channel  string
+
+	requestConfig
+	channelRouting
 }
 
 // NewDiscord validates the URL and returns a Discord object
@@ -71,8 +74,13 @@ func (s *Discord) Post(event events.Event) error {
 		return nil
 	}
 
+	channel, err := s.channelFor(event, s.Channel)
+	if err != nil {
+		return err
+	}
+
 	payload := SlackPayload{
-		Channel:  s.Channel,
+		Channel:  channel,
 		Username: s.Username,
 	}
 	if payload.Username == "" {
@@ -99,7 +107,7 @@ func (s *Discord) Post(event events.Event) error {
 
 	payload.Attachments = []SlackAttachment{a}
 
-	err := postMessage(s.URL, s.ProxyURL, nil, payload)
+	err = s.transport.postMessage(s.URL, s.ProxyURL, nil, payload, s.withCapture(), s.withDelivery(event))
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
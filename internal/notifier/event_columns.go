@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+// eventTimestampFormat is the UTC timestamp format of the JSON warehouse rows,
+// parsed as a DateTime64(3) by ClickHouse.
+const eventTimestampFormat = "2006-01-02 15:04:05.000"
+
+// eventColumn is a column of the events written to the archives and the
+// warehouses, either a timestamp or a string.
+type eventColumn struct {
+	name      string
+	timestamp func(e events.Event) int64
+	text      func(e events.Event) string
+}
+
+// eventColumns are the columns of the Parquet archives and of the warehouse
+// tables, the metadata of the events is stored as a JSON object.
+var eventColumns = []eventColumn{
+	{name: "timestamp", timestamp: func(e events.Event) int64 {
+		t := e.Timestamp.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		return t.UnixNano() / 1e6
+	}},
+	{name: "severity", text: func(e events.Event) string { return e.Severity }},
+	{name: "kind", text: func(e events.Event) string { return e.InvolvedObject.Kind }},
+	{name: "namespace", text: func(e events.Event) string { return e.InvolvedObject.Namespace }},
+	{name: "name", text: func(e events.Event) string { return e.InvolvedObject.Name }},
+	{name: "reason", text: func(e events.Event) string { return e.Reason }},
+	{name: "message", text: func(e events.Event) string { return e.Message }},
+	{name: "revision", text: func(e events.Event) string { return e.Metadata["revision"] }},
+	{name: "controller", text: func(e events.Event) string { return e.ReportingController }},
+	{name: "metadata", text: func(e events.Event) string {
+		if len(e.Metadata) == 0 {
+			return "{}"
+		}
+		b, _ := json.Marshal(e.Metadata)
+		return string(b)
+	}},
+}
+
+// eventRow returns the columns of the event as a JSON object.
+func eventRow(e events.Event) map[string]interface{} {
+	row := make(map[string]interface{}, len(eventColumns))
+	for _, column := range eventColumns {
+		if column.timestamp != nil {
+			row[column.name] = time.Unix(0, column.timestamp(e)*1e6).UTC().Format(eventTimestampFormat)
+			continue
+		}
+		row[column.name] = column.text(e)
+	}
+	return row
+}
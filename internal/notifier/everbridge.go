@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Everbridge is an Everbridge notifier, it sends a notification to the
+// contacts of the organization, the error events with a high priority.
+type Everbridge struct {
+	// URL is the notifications endpoint of the organization, e.g.
+	// 'https://api.everbridge.net/rest/notifications/<organization-id>'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Recipients is the comma separated list of the external IDs
+	// of the contacts notified.
+	Recipients string
+
+	Username string
+	Password string
+
+	channelRouting
+	requestConfig
+}
+
+type everbridgePayload struct {
+	Priority          string                     `json:"priority"`
+	Type              string                     `json:"type"`
+	Message           everbridgeMessage          `json:"message"`
+	BroadcastContacts everbridgeBroadcastContact `json:"broadcastContacts"`
+}
+
+type everbridgeMessage struct {
+	ContentType string `json:"contentType"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+}
+
+type everbridgeBroadcastContact struct {
+	ExternalIDs []string `json:"externalIds"`
+}
+
+// NewEverbridge returns a notifier for the notifications endpoint address, the
+// channel is the list of recipients and the token the password of the username.
+func NewEverbridge(addr, proxyURL, recipients, username, password string, certPool *x509.CertPool) (*Everbridge, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Everbridge notifications URL %s: %w", addr, err)
+	}
+	if recipients == "" {
+		return nil, errors.New("everbridge recipients cannot be empty")
+	}
+	if username == "" || password == "" {
+		return nil, errors.New("everbridge username and password cannot be empty")
+	}
+
+	return &Everbridge{
+		URL:        addr,
+		ProxyURL:   proxyURL,
+		CertPool:   certPool,
+		Recipients: recipients,
+		Username:   username,
+		Password:   password,
+	}, nil
+}
+
+// Post sends the notification to the recipients of the event.
+func (e *Everbridge) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	recipients, err := e.recipientsFor(event, e.Recipients)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no Everbridge recipients for %s/%s", event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+	}
+
+	priority := "NonPriority"
+	if event.Severity == events.EventSeverityError {
+		priority = "Priority"
+	}
+	payload := everbridgePayload{
+		Priority: priority,
+		Type:     "Standard",
+		Message: everbridgeMessage{
+			ContentType: "Text",
+			Title:       incidentTitle(event),
+			Body:        incidentMessage(event),
+		},
+		BroadcastContacts: everbridgeBroadcastContact{ExternalIDs: recipients},
+	}
+
+	err = e.transport.postMessage(e.URL, e.ProxyURL, e.CertPool, payload, func(req *retryablehttp.Request) {
+		req.SetBasicAuth(e.Username, e.Password)
+	}, e.withCapture(), e.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEverbridge_Post(t *testing.T) {
+	var payloads []everbridgePayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/rest/notifications/1234", r.URL.Path)
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "flux", username)
+		require.Equal(t, "s3cr3t", password)
+		var payload everbridgePayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer ts.Close()
+
+	everbridge, err := NewEverbridge(ts.URL+"/rest/notifications/1234", "", "ops-1,ops-2", "flux", "s3cr3t", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, everbridge.Post(event))
+	require.NoError(t, everbridge.Post(testEvent()))
+
+	require.Len(t, payloads, 2)
+	require.Equal(t, "Priority", payloads[0].Priority)
+	require.Equal(t, []string{"ops-1", "ops-2"}, payloads[0].BroadcastContacts.ExternalIDs)
+	require.Equal(t, "gitrepository/webapp: reason", payloads[0].Message.Title)
+	require.Equal(t, "message\n\ntest: metadata", payloads[0].Message.Body)
+	require.Equal(t, "NonPriority", payloads[1].Priority)
+}
+
+func TestNewEverbridge(t *testing.T) {
+	_, err := NewEverbridge("https://api.everbridge.net/rest/notifications/1234", "", "", "flux", "s3cr3t", nil)
+	require.Error(t, err)
+	_, err = NewEverbridge("https://api.everbridge.net/rest/notifications/1234", "", "ops-1", "flux", "", nil)
+	require.Error(t, err)
+}
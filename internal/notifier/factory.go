@@ -20,6 +20,10 @@ import (
 	"crypto/x509"
 	"fmt"
 
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/fluxcd/notification-controller/api/v1beta1"
 )
 
@@ -30,6 +34,66 @@ type Factory struct {
 	Channel  string
 	Token    string
 	CertPool *x509.CertPool
+
+	// Transport holds the TLS configuration and the egress policy of the
+	// connections opened by the notifiers.
+	Transport *Transport
+
+	// AllowedChannels restricts the channels rendered from a templated
+	// channel by the chat notifiers.
+	AllowedChannels []string
+
+	// StatusContexts are the templates of the commit status contexts
+	// published by the git commit status notifiers for each event.
+	StatusContexts []string
+
+	// StatusStates are the rules mapping the events to the states of the
+	// commit statuses published by the git commit status notifiers.
+	StatusStates []v1beta1.ProviderStatusState
+
+	// Capture records the requests of the webhook based notifiers when set.
+	Capture *Capture
+
+	// Delivery tunes the requests of the webhook based notifiers when set.
+	Delivery *Delivery
+
+	// DeliveryID is sent by the webhook based notifiers in the delivery ID
+	// header, and by the generic webhook notifier in the payload.
+	DeliveryID string
+
+	// SigningKey is the OpenPGP or Ed25519 private key used to sign
+	// the generic webhook payloads, the payloads are not signed when empty.
+	SigningKey []byte
+
+	// SigningKeyPassphrase decrypts the OpenPGP signing key.
+	SigningKeyPassphrase []byte
+
+	// HMAC configures the signatures of the generic-hmac notifier,
+	// computed with the token.
+	HMAC *v1beta1.ProviderHMAC
+
+	// Method and ContentType configure the requests of the generic webhook notifier.
+	Method      string
+	ContentType string
+
+	// AWSCredentials sign the requests of the cloudwatch notifier,
+	// the controller environment variables are used when empty.
+	AWSCredentials AWSCredentials
+
+	// OCICredentials sign the requests of the oci notifier.
+	OCICredentials OCICredentials
+
+	// ArchiveOptions configures the objects written by the archive notifier.
+	ArchiveOptions ArchiveOptions
+
+	// KubeClient, Namespace and Alert configure the kubernetes notifier,
+	// which mirrors the notifications as events in the provider namespace.
+	KubeClient client.Client
+	Namespace  string
+	Alert      *corev1.ObjectReference
+
+	// Logger is the logger the log notifier writes the notifications with.
+	Logger logr.Logger
 }
 
 func NewFactory(url string, proxy string, username string, channel string, token string, certPool *x509.CertPool) *Factory {
@@ -43,16 +107,48 @@ func NewFactory(url string, proxy string, username string, channel string, token
 	}
 }
 
+// SlackBoard returns the board maintaining the summary message of a slack provider.
+func (f Factory) SlackBoard() (*SlackBoard, error) {
+	b, err := NewSlackBoard(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	if err != nil {
+		return nil, err
+	}
+	b.setTransport(f.Transport)
+	return b, nil
+}
+
+// Archive returns the archive the event server writes the batches of events of an archive provider to.
+func (f Factory) Archive() (*Archive, error) {
+	a, err := NewArchive(f.URL, f.ProxyURL, f.Token, f.AWSCredentials, f.CertPool)
+	if err != nil {
+		return nil, err
+	}
+	a.ArchiveOptions = f.ArchiveOptions
+	a.setTransport(f.Transport)
+	return a, nil
+}
+
 func (f Factory) Notifier(provider string) (Interface, error) {
-	if f.URL == "" {
+	if f.URL == "" && provider != v1beta1.KubernetesProvider && provider != v1beta1.LogProvider {
 		return &NopNotifier{}, nil
 	}
 
 	var n Interface
 	var err error
 	switch provider {
-	case v1beta1.GenericProvider:
-		n, err = NewForwarder(f.URL, f.ProxyURL, f.CertPool)
+	case v1beta1.GenericProvider, v1beta1.GenericHMACProvider:
+		var fwd *Forwarder
+		fwd, err = NewForwarder(f.URL, f.ProxyURL, f.CertPool)
+		if err == nil {
+			err = fwd.setRequest(f.Method, f.ContentType)
+		}
+		if err == nil && len(f.SigningKey) > 0 {
+			fwd.Signer, err = NewSigner(f.SigningKey, f.SigningKeyPassphrase)
+		}
+		if err == nil && provider == v1beta1.GenericHMACProvider {
+			fwd.HMAC, err = NewHMACSigner(f.Token, f.HMAC)
+		}
+		n = fwd
 	case v1beta1.SlackProvider:
 		n, err = NewSlack(f.URL, f.ProxyURL, f.Username, f.Channel)
 	case v1beta1.DiscordProvider:
@@ -62,25 +158,147 @@ func (f Factory) Notifier(provider string) (Interface, error) {
 	case v1beta1.MSTeamsProvider:
 		n, err = NewMSTeams(f.URL, f.ProxyURL)
 	case v1beta1.GitHubProvider:
-		n, err = NewGitHub(f.URL, f.Token, f.CertPool)
+		n, err = NewGitHub(f.URL, f.Token, f.CertPool, f.Transport)
 	case v1beta1.GitLabProvider:
-		n, err = NewGitLab(f.URL, f.Token, f.CertPool)
+		n, err = NewGitLab(f.URL, f.Token, f.CertPool, f.Transport)
+	case v1beta1.GitHubCommentProvider:
+		n, err = NewGitHubComment(f.URL, f.Token, f.CertPool, f.Transport)
+	case v1beta1.GitLabCommentProvider:
+		n, err = NewGitLabComment(f.URL, f.Token, f.CertPool, f.Transport)
 	case v1beta1.BitbucketProvider:
-		n, err = NewBitbucket(f.URL, f.Token, f.CertPool)
+		n, err = NewBitbucket(f.URL, f.Token, f.CertPool, f.Transport)
 	case v1beta1.AzureDevOpsProvider:
-		n, err = NewAzureDevOps(f.URL, f.Token, f.CertPool)
+		n, err = NewAzureDevOps(f.URL, f.Token, f.CertPool, f.Transport)
+	case v1beta1.TeamCityProvider:
+		n, err = NewTeamCity(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.BambooProvider:
+		n, err = NewBamboo(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
 	case v1beta1.GoogleChatProvider:
 		n, err = NewGoogleChat(f.URL, f.ProxyURL)
 	case v1beta1.WebexProvider:
 		n, err = NewWebex(f.URL, f.ProxyURL, f.CertPool)
 	case v1beta1.SentryProvider:
-		n, err = NewSentry(f.CertPool, f.URL)
+		n, err = NewSentry(f.CertPool, f.URL, f.Transport)
+	case v1beta1.KubernetesProvider:
+		n, err = NewKubernetesEvents(f.KubeClient, f.Namespace, f.Alert)
+	case v1beta1.LogProvider:
+		n, err = NewLog(f.Logger)
+	case v1beta1.CloudWatchProvider:
+		n, err = NewCloudWatch(f.URL, f.ProxyURL, f.Channel, f.Username, f.AWSCredentials, f.CertPool)
+	case v1beta1.IBMEventNotificationsProvider:
+		n, err = NewIBMEventNotifications(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.OCINotificationsProvider:
+		n, err = NewOCINotifications(f.URL, f.ProxyURL, f.OCICredentials, f.CertPool)
+	case v1beta1.KeptnProvider:
+		n, err = NewKeptn(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.StatuspageProvider:
+		n, err = NewStatuspage(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.InstatusProvider:
+		n, err = NewInstatus(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.CachetProvider:
+		n, err = NewCachet(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.GrafanaOnCallProvider:
+		n, err = NewGrafanaOnCall(f.URL, f.ProxyURL, f.CertPool)
+	case v1beta1.SquadcastProvider:
+		n, err = NewSquadcast(f.URL, f.ProxyURL, f.Channel, f.CertPool)
+	case v1beta1.IcingaProvider:
+		n, err = NewIcinga(f.URL, f.ProxyURL, f.Channel, f.Username, f.Token, f.CertPool)
+	case v1beta1.NagiosProvider:
+		n, err = NewNagios(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.ArchiveProvider:
+		n, err = f.Archive()
+	case v1beta1.BigQueryProvider:
+		n, err = NewBigQuery(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.ClickHouseProvider:
+		n, err = NewClickHouse(f.URL, f.ProxyURL, f.Channel, f.Username, f.Token, f.CertPool)
+	case v1beta1.PostgresProvider:
+		n, err = NewPostgres(f.URL, f.Channel, f.Username, f.Token, f.CertPool, f.Transport)
+	case v1beta1.MySQLProvider:
+		n, err = NewMySQL(f.URL, f.Channel, f.Username, f.Token, f.CertPool, f.Transport)
+	case v1beta1.ZabbixProvider:
+		n, err = NewZabbix(f.URL, f.Channel, f.Transport)
+	case v1beta1.OpsgenieProvider:
+		n, err = NewOpsgenie(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.RootlyProvider:
+		n, err = NewRootly(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.BlamelessProvider:
+		n, err = NewBlameless(f.URL, f.ProxyURL, f.Channel, f.Token, f.CertPool)
+	case v1beta1.XMattersProvider:
+		n, err = NewXMatters(f.URL, f.ProxyURL, f.Channel, f.Username, f.Token, f.CertPool)
+	case v1beta1.EverbridgeProvider:
+		n, err = NewEverbridge(f.URL, f.ProxyURL, f.Channel, f.Username, f.Token, f.CertPool)
+	case v1beta1.RedisProvider:
+		n, err = NewRedisStream(f.URL, f.Channel, f.Username, f.Token, f.CertPool, f.Transport)
 	default:
 		err = fmt.Errorf("provider %s not supported", provider)
 	}
 
+	if err == nil && IsChannelTemplate(f.Channel) {
+		err = f.setChannelRoute(provider, n)
+	}
+	if err == nil && len(f.StatusContexts) > 0 {
+		err = f.setStatusContexts(provider, n)
+	}
+	if err == nil && len(f.StatusStates) > 0 {
+		err = f.setStatusStates(provider, n)
+	}
+
 	if err != nil {
 		n = &NopNotifier{}
 	}
+	if t, ok := n.(transporter); ok {
+		t.setTransport(f.Transport)
+	}
+	if c, ok := n.(capturer); ok && f.Capture != nil {
+		c.setCapture(f.Capture)
+	}
+	if d, ok := n.(deliverer); ok && f.Delivery != nil {
+		d.setDelivery(f.Delivery)
+	}
+	if t, ok := n.(deliveryTracer); ok && f.DeliveryID != "" {
+		t.setDeliveryID(f.DeliveryID)
+	}
 	return n, err
 }
+
+// setChannelRoute renders the channel of the chat notifiers for each event.
+func (f Factory) setChannelRoute(provider string, n Interface) error {
+	r, ok := n.(channelRouter)
+	if !ok {
+		return fmt.Errorf("channel templates not supported by the %s provider", provider)
+	}
+	route, err := newChannelRoute(f.Channel, f.AllowedChannels)
+	if err != nil {
+		return err
+	}
+	r.setChannelRoute(route)
+	return nil
+}
+
+// setStatusContexts renders the commit status contexts of the git notifiers for each event.
+func (f Factory) setStatusContexts(provider string, n Interface) error {
+	c, ok := n.(statusContexter)
+	if !ok {
+		return fmt.Errorf("status contexts not supported by the %s provider", provider)
+	}
+	contexts, err := newStatusContexts(f.StatusContexts)
+	if err != nil {
+		return err
+	}
+	c.setStatusContexts(contexts)
+	return nil
+}
+
+// setStatusStates maps the events to the commit status states of the git notifiers.
+func (f Factory) setStatusStates(provider string, n Interface) error {
+	s, ok := n.(statusStater)
+	if !ok {
+		return fmt.Errorf("status states not supported by the %s provider", provider)
+	}
+	states, err := newStatusStates(f.StatusStates)
+	if err != nil {
+		return err
+	}
+	s.setStatusStates(states)
+	return nil
+}
@@ -18,17 +18,33 @@ package notifier
 
 import (
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"mime"
+	"net/http"
 	"net/url"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/fluxcd/pkg/runtime/events"
 
 	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/fluxcd/notification-controller/internal/templates"
 )
 
-// NotificationHeader is a header sent to identify requests from the
-// notification controller.
-const NotificationHeader = "gotk-component"
+const (
+	// NotificationHeader is a header sent to identify requests from the
+	// notification controller.
+	NotificationHeader = "gotk-component"
+
+	// CloudEventsContentType makes the forwarder wrap the events in CloudEvents.
+	CloudEventsContentType = "application/cloudevents+json"
+
+	// cloudEventType is the type of the CloudEvents sent by the notifiers.
+	cloudEventType = "com.fluxcd.event"
+)
 
 // Forwarder is an implementation of the notification Interface that posts the
 // body as an HTTP request using an optional proxy.
@@ -36,26 +52,181 @@ type Forwarder struct {
 	URL      string
 	ProxyURL string
 	CertPool *x509.CertPool
+
+	// Signer signs the payloads when set.
+	Signer Signer
+
+	// HMAC signs the payloads with a shared key when set.
+	HMAC *HMACSigner
+
+	// Method is the HTTP method of the requests, it defaults to POST.
+	Method string
+
+	// ContentType of the requests, it defaults to 'application/json'. The events
+	// are sent as JSON, wrapped in CloudEvents for 'application/cloudevents+json',
+	// and the text content types get the event message only.
+	ContentType string
+
+	// urlTemplate renders the URL of the addresses containing event fields.
+	urlTemplate *template.Template
+
+	requestConfig
 }
 
+// cloudEvent is a CloudEvent in the structured JSON format.
+type cloudEvent struct {
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject"`
+	Time            string       `json:"time"`
+	SpecVersion     string       `json:"specversion"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            events.Event `json:"data"`
+}
+
+// NewForwarder returns a notifier for the hook URL, the URL can contain
+// Go templates rendered with the event, e.g. 'https://example.com/{{ .InvolvedObject.Name }}'.
 func NewForwarder(hookURL string, proxyURL string, certPool *x509.CertPool) (*Forwarder, error) {
 	if _, err := url.ParseRequestURI(hookURL); err != nil {
 		return nil, fmt.Errorf("invalid hook URL %s: %w", hookURL, err)
 	}
 
+	var urlTemplate *template.Template
+	if strings.Contains(hookURL, "{{") {
+		var err error
+		if urlTemplate, err = templates.Parse("address", hookURL); err != nil {
+			return nil, fmt.Errorf("invalid hook URL template: %w", err)
+		}
+	}
+
 	return &Forwarder{
-		URL:      hookURL,
-		ProxyURL: proxyURL,
+		URL:         hookURL,
+		ProxyURL:    proxyURL,
+		CertPool:    certPool,
+		urlTemplate: urlTemplate,
 	}, nil
 }
 
+// setRequest sets the method and the content type of the requests.
+func (f *Forwarder) setRequest(method, contentType string) error {
+	switch method {
+	case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("unsupported method %s", method)
+	}
+	if contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return fmt.Errorf("invalid content type %s: %w", contentType, err)
+		}
+	}
+	f.Method = method
+	f.ContentType = contentType
+	return nil
+}
+
 func (f *Forwarder) Post(event events.Event) error {
-	err := postMessage(f.URL, f.ProxyURL, f.CertPool, event, func(req *retryablehttp.Request) {
+	payload, contentType, err := f.body(event)
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	signature := ""
+	if f.Signer != nil {
+		if signature, err = f.Signer.Sign(payload); err != nil {
+			return fmt.Errorf("signing notification payload failed: %w", err)
+		}
+	}
+	var hmacHeaders map[string]string
+	if f.HMAC != nil {
+		id := f.deliveryID
+		if id == "" {
+			id = NewDeliveryID()
+		}
+		hmacHeaders = f.HMAC.Headers(payload, id, time.Now())
+	}
+
+	address := f.URL
+	if f.urlTemplate != nil {
+		if address, err = templates.Render(f.urlTemplate, event); err != nil {
+			return err
+		}
+		if _, err := url.ParseRequestURI(address); err != nil {
+			return fmt.Errorf("invalid hook URL %s: %w", address, err)
+		}
+	}
+
+	method := f.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	err = f.transport.sendMessage(method, address, f.ProxyURL, f.CertPool, payload, func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", contentType)
 		req.Header.Set(NotificationHeader, event.ReportingController)
-	})
+		if signature != "" {
+			req.Header.Set(SignatureHeader, signature)
+		}
+		for k, v := range hmacHeaders {
+			req.Header.Set(k, v)
+		}
+	}, f.withCapture(), f.withDelivery(event))
 
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
 	return nil
 }
+
+// body returns the payload of the event for the content type of the forwarder.
+func (f *Forwarder) body(event events.Event) ([]byte, string, error) {
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid content type %s: %w", contentType, err)
+	}
+
+	if f.deliveryID != "" {
+		metadata := make(map[string]string, len(event.Metadata)+1)
+		for k, v := range event.Metadata {
+			metadata[k] = v
+		}
+		metadata[DeliveryIDMetadataKey] = f.deliveryID
+		event.Metadata = metadata
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return []byte(event.Message), contentType, nil
+	case mediaType == CloudEventsContentType:
+		timestamp := event.Timestamp.Time
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		obj := event.InvolvedObject
+		ce := cloudEvent{
+			ID:              sha1String(fmt.Sprintf("%s/%s/%s/%s/%d", obj.Kind, obj.Namespace, obj.Name, event.Message, timestamp.UnixNano())),
+			Source:          event.ReportingController,
+			Type:            cloudEventType,
+			Subject:         fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name),
+			Time:            timestamp.UTC().Format(time.RFC3339),
+			SpecVersion:     "1.0",
+			DataContentType: "application/json",
+			Data:            event,
+		}
+		if f.deliveryID != "" {
+			ce.ID = f.deliveryID
+		}
+		if ce.Source == "" {
+			ce.Source = KubernetesEventsComponent
+		}
+		payload, err := json.Marshal(ce)
+		return payload, contentType, err
+	default:
+		payload, err := json.Marshal(event)
+		return payload, contentType, err
+	}
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package notifier
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/fluxcd/pkg/runtime/events"
@@ -48,3 +51,88 @@ func TestForwarder_Post(t *testing.T) {
 	err = forwarder.Post(testEvent())
 	require.NoError(t, err)
 }
+
+func TestForwarder_PostSigned(t *testing.T) {
+	pub, key := ed25519Key(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		sig := r.Header.Get(SignatureHeader)
+		require.True(t, strings.HasPrefix(sig, "ed25519="))
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "ed25519="))
+		require.NoError(t, err)
+		require.True(t, ed25519.Verify(pub, b, raw))
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL, "", "", "", "", nil)
+	factory.SigningKey = key
+	forwarder, err := factory.Notifier("generic")
+	require.NoError(t, err)
+
+	err = forwarder.Post(testEvent())
+	require.NoError(t, err)
+}
+
+func TestForwarder_PostRequestOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/apps/webapp", r.URL.Path)
+		require.Equal(t, "info", r.URL.Query().Get("severity"))
+		require.Equal(t, "text/plain; charset=utf-8", r.Header.Get("Content-Type"))
+		require.Equal(t, "message", string(b))
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL+"/apps/{{ .InvolvedObject.Name | urlquery }}?severity={{ .Severity }}", "", "", "", "", nil)
+	factory.Method = http.MethodPut
+	factory.ContentType = "text/plain; charset=utf-8"
+	forwarder, err := factory.Notifier("generic")
+	require.NoError(t, err)
+
+	err = forwarder.Post(testEvent())
+	require.NoError(t, err)
+}
+
+func TestForwarder_PostCloudEvent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, CloudEventsContentType, r.Header.Get("Content-Type"))
+
+		var payload cloudEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		require.Equal(t, "1.0", payload.SpecVersion)
+		require.Equal(t, cloudEventType, payload.Type)
+		require.Equal(t, "source-controller", payload.Source)
+		require.Equal(t, "GitRepository/gitops-system/webapp", payload.Subject)
+		require.NotEmpty(t, payload.ID)
+		require.Equal(t, "metadata", payload.Data.Metadata["test"])
+	}))
+	defer ts.Close()
+
+	forwarder, err := NewForwarder(ts.URL, "", nil)
+	require.NoError(t, err)
+	forwarder.ContentType = CloudEventsContentType
+
+	err = forwarder.Post(testEvent())
+	require.NoError(t, err)
+}
+
+func TestForwarder_InvalidRequestOptions(t *testing.T) {
+	factory := NewFactory("https://example.com", "", "", "", "", nil)
+	factory.Method = http.MethodDelete
+	_, err := factory.Notifier("generic")
+	require.Error(t, err)
+
+	factory = NewFactory("https://example.com", "", "", "", "", nil)
+	factory.ContentType = "text/"
+	_, err = factory.Notifier("generic")
+	require.Error(t, err)
+
+	_, err = NewForwarder("https://example.com/{{ .InvolvedObject.Name", "", nil)
+	require.Error(t, err)
+}
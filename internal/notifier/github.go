@@ -18,7 +18,6 @@ package notifier
 
 import (
 	"context"
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -31,15 +30,20 @@ import (
 
 	"github.com/google/go-github/v32/github"
 	"golang.org/x/oauth2"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
 )
 
 type GitHub struct {
 	Owner  string
 	Repo   string
 	Client *github.Client
+
+	statusContexting
+	statusStating
 }
 
-func NewGitHub(addr string, token string, certPool *x509.CertPool) (*GitHub, error) {
+func NewGitHub(addr string, token string, certPool *x509.CertPool, transport *Transport) (*GitHub, error) {
 	if len(token) == 0 {
 		return nil, errors.New("github token cannot be empty")
 	}
@@ -60,20 +64,20 @@ func NewGitHub(addr string, token string, certPool *x509.CertPool) (*GitHub, err
 	}
 
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
+	ctx := context.Background()
+	if baseUrl.Host == "github.com" {
+		certPool = nil
+	}
+	if config := transport.tlsConfig(certPool); config != nil {
+		tr := &http.Transport{
+			TLSClientConfig: config,
+		}
+		hc := &http.Client{Transport: tr}
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, hc)
+	}
+	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
 	if baseUrl.Host != "github.com" {
-		if certPool != nil {
-			tr := &http.Transport{
-				TLSClientConfig: &tls.Config{
-					RootCAs: certPool,
-				},
-			}
-			hc := &http.Client{Transport: tr}
-			ctx := context.WithValue(context.Background(), oauth2.HTTPClient, hc)
-			tc = oauth2.NewClient(ctx, ts)
-		}
-
 		client, err = github.NewEnterpriseClient(host, host, tc)
 		if err != nil {
 			return nil, fmt.Errorf("could not create enterprise GitHub client: %v", err)
@@ -89,8 +93,11 @@ func NewGitHub(addr string, token string, certPool *x509.CertPool) (*GitHub, err
 
 // Post Github commit status
 func (g *GitHub) Post(event events.Event) error {
-	// Skip progressing events
-	if event.Reason == "Progressing" {
+	state, err := g.stateFor(event, v1beta1.FailureCommitState)
+	if err != nil {
+		return err
+	}
+	if state == v1beta1.SkipCommitState {
 		return nil
 	}
 
@@ -102,16 +109,14 @@ func (g *GitHub) Post(event events.Event) error {
 	if err != nil {
 		return err
 	}
-	state, err := toGitHubState(event.Severity)
+	ghState, err := toGitHubState(state)
 	if err != nil {
 		return err
 	}
-	name, desc := formatNameAndDescription(event)
-
-	status := &github.RepoStatus{
-		State:       &state,
-		Context:     &name,
-		Description: &desc,
+	_, desc := formatNameAndDescription(event)
+	names, err := g.contextsFor(event)
+	if err != nil {
+		return err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -122,24 +127,37 @@ func (g *GitHub) Post(event events.Event) error {
 	if err != nil {
 		return fmt.Errorf("could not list commit statuses: %v", err)
 	}
-	if duplicateGithubStatus(statuses, status) {
-		return nil
-	}
 
-	_, _, err = g.Client.Repositories.CreateStatus(ctx, g.Owner, g.Repo, rev, status)
-	if err != nil {
-		return fmt.Errorf("could not create commit status: %v", err)
+	for _, name := range names {
+		name := name
+		status := &github.RepoStatus{
+			State:       &ghState,
+			Context:     &name,
+			Description: &desc,
+		}
+		if duplicateGithubStatus(statuses, status) {
+			continue
+		}
+
+		_, _, err = g.Client.Repositories.CreateStatus(ctx, g.Owner, g.Repo, rev, status)
+		if err != nil {
+			return fmt.Errorf("could not create commit status: %v", err)
+		}
 	}
 
 	return nil
 }
 
-func toGitHubState(severity string) (string, error) {
-	switch severity {
-	case events.EventSeverityInfo:
+func toGitHubState(state string) (string, error) {
+	switch state {
+	case v1beta1.SuccessCommitState:
 		return "success", nil
-	case events.EventSeverityError:
+	case v1beta1.FailureCommitState:
 		return "failure", nil
+	case v1beta1.PendingCommitState:
+		return "pending", nil
+	case v1beta1.ErrorCommitState:
+		return "error", nil
 	default:
 		return "", errors.New("can't convert to github state")
 	}
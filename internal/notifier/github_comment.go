@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/google/go-github/v32/github"
+)
+
+// GitHubComment is a GitHub notifier, it maintains a rolled-up comment
+// on the pull requests of the revision of the event.
+type GitHubComment struct {
+	Owner  string
+	Repo   string
+	Client *github.Client
+}
+
+// NewGitHubComment returns a notifier for the repository address,
+// the token must be allowed to comment the pull requests.
+func NewGitHubComment(addr string, token string, certPool *x509.CertPool, transport *Transport) (*GitHubComment, error) {
+	g, err := NewGitHub(addr, token, certPool, transport)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubComment{
+		Owner:  g.Owner,
+		Repo:   g.Repo,
+		Client: g.Client,
+	}, nil
+}
+
+// Post rolls the event up in the comment of the pull requests of the revision.
+func (g *GitHubComment) Post(event events.Event) error {
+	revString, ok := event.Metadata["revision"]
+	if !ok {
+		return errors.New("missing revision metadata")
+	}
+	rev, err := parseRevision(revString)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	opts := &github.PullRequestListOptions{ListOptions: github.ListOptions{PerPage: 10}}
+	pulls, _, err := g.Client.PullRequests.ListPullRequestsWithCommit(ctx, g.Owner, g.Repo, rev, opts)
+	if err != nil {
+		return fmt.Errorf("could not list pull requests of commit: %v", err)
+	}
+
+	for _, pull := range pulls {
+		if err := g.rollup(ctx, pull.GetNumber(), rev, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitHubComment) rollup(ctx context.Context, number int, rev string, event events.Event) error {
+	var comment *github.IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for comment == nil {
+		comments, resp, err := g.Client.Issues.ListComments(ctx, g.Owner, g.Repo, number, opts)
+		if err != nil {
+			return fmt.Errorf("could not list comments of pull request %d: %v", number, err)
+		}
+		for _, c := range comments {
+			if isRollupComment(c.GetBody()) {
+				comment = c
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	body, err := rollupComment(comment.GetBody(), rev, event)
+	if err != nil {
+		return err
+	}
+
+	if comment == nil {
+		_, _, err = g.Client.Issues.CreateComment(ctx, g.Owner, g.Repo, number, &github.IssueComment{Body: &body})
+		if err != nil {
+			return fmt.Errorf("could not comment pull request %d: %v", number, err)
+		}
+		return nil
+	}
+	if body == comment.GetBody() {
+		return nil
+	}
+	_, _, err = g.Client.Issues.EditComment(ctx, g.Owner, g.Repo, comment.GetID(), &github.IssueComment{Body: &body})
+	if err != nil {
+		return fmt.Errorf("could not update comment of pull request %d: %v", number, err)
+	}
+	return nil
+}
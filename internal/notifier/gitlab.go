@@ -17,21 +17,25 @@ limitations under the License.
 package notifier
 
 import (
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"net/http"
 
 	"github.com/fluxcd/pkg/runtime/events"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
 )
 
 type GitLab struct {
 	Id     string
 	Client *gitlab.Client
+
+	statusContexting
+	statusStating
 }
 
-func NewGitLab(addr string, token string, certPool *x509.CertPool) (*GitLab, error) {
+func NewGitLab(addr string, token string, certPool *x509.CertPool, transport *Transport) (*GitLab, error) {
 	if len(token) == 0 {
 		return nil, errors.New("gitlab token cannot be empty")
 	}
@@ -42,11 +46,9 @@ func NewGitLab(addr string, token string, certPool *x509.CertPool) (*GitLab, err
 	}
 
 	opts := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(host)}
-	if certPool != nil {
+	if config := transport.tlsConfig(certPool); config != nil {
 		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: certPool,
-			},
+			TLSClientConfig: config,
 		}
 		hc := &http.Client{Transport: tr}
 		opts = append(opts, gitlab.WithHTTPClient(hc))
@@ -66,8 +68,11 @@ func NewGitLab(addr string, token string, certPool *x509.CertPool) (*GitLab, err
 
 // Post GitLab commit status
 func (g *GitLab) Post(event events.Event) error {
-	// Skip progressing events
-	if event.Reason == "Progressing" {
+	state, err := g.stateFor(event, v1beta1.FailureCommitState)
+	if err != nil {
+		return err
+	}
+	if state == v1beta1.SkipCommitState {
 		return nil
 	}
 
@@ -79,32 +84,42 @@ func (g *GitLab) Post(event events.Event) error {
 	if err != nil {
 		return err
 	}
-	state, err := toGitLabState(event.Severity)
+	glState, err := toGitLabState(state)
 	if err != nil {
 		return err
 	}
 
-	name, desc := formatNameAndDescription(event)
-	options := &gitlab.SetCommitStatusOptions{
-		Name:        &name,
-		Description: &desc,
-		State:       state,
-	}
-
-	_, _, err = g.Client.Commits.SetCommitStatus(g.Id, rev, options)
+	_, desc := formatNameAndDescription(event)
+	names, err := g.contextsFor(event)
 	if err != nil {
 		return err
 	}
 
+	for _, name := range names {
+		name := name
+		options := &gitlab.SetCommitStatusOptions{
+			Name:        &name,
+			Description: &desc,
+			State:       glState,
+		}
+
+		_, _, err = g.Client.Commits.SetCommitStatus(g.Id, rev, options)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func toGitLabState(severity string) (gitlab.BuildStateValue, error) {
-	switch severity {
-	case events.EventSeverityInfo:
+func toGitLabState(state string) (gitlab.BuildStateValue, error) {
+	switch state {
+	case v1beta1.SuccessCommitState:
 		return gitlab.Success, nil
-	case events.EventSeverityError:
+	case v1beta1.FailureCommitState, v1beta1.ErrorCommitState:
 		return gitlab.Failed, nil
+	case v1beta1.PendingCommitState:
+		return gitlab.Pending, nil
 	default:
 		return "", errors.New("can't convert to gitlab state")
 	}
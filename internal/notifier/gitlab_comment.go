@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/xanzy/go-gitlab"
+)
+
+// GitLabComment is a GitLab notifier, it maintains a rolled-up comment
+// on the merge requests of the revision of the event.
+type GitLabComment struct {
+	Id     string
+	Client *gitlab.Client
+}
+
+// NewGitLabComment returns a notifier for the project address,
+// the token must be allowed to comment the merge requests.
+func NewGitLabComment(addr string, token string, certPool *x509.CertPool, transport *Transport) (*GitLabComment, error) {
+	g, err := NewGitLab(addr, token, certPool, transport)
+	if err != nil {
+		return nil, err
+	}
+	return &GitLabComment{
+		Id:     g.Id,
+		Client: g.Client,
+	}, nil
+}
+
+// Post rolls the event up in the comment of the merge requests of the revision.
+func (g *GitLabComment) Post(event events.Event) error {
+	revString, ok := event.Metadata["revision"]
+	if !ok {
+		return errors.New("missing revision metadata")
+	}
+	rev, err := parseRevision(revString)
+	if err != nil {
+		return err
+	}
+
+	mrs, _, err := g.Client.Commits.GetMergeRequestsByCommit(g.Id, rev)
+	if err != nil {
+		return fmt.Errorf("could not list merge requests of commit: %v", err)
+	}
+
+	for _, mr := range mrs {
+		if err := g.rollup(mr.IID, rev, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitLabComment) rollup(iid int, rev string, event events.Event) error {
+	var note *gitlab.Note
+	opts := &gitlab.ListMergeRequestNotesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for note == nil {
+		notes, resp, err := g.Client.Notes.ListMergeRequestNotes(g.Id, iid, opts)
+		if err != nil {
+			return fmt.Errorf("could not list notes of merge request %d: %v", iid, err)
+		}
+		for _, n := range notes {
+			if isRollupComment(n.Body) {
+				note = n
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	var current string
+	if note != nil {
+		current = note.Body
+	}
+	body, err := rollupComment(current, rev, event)
+	if err != nil {
+		return err
+	}
+
+	if note == nil {
+		_, _, err = g.Client.Notes.CreateMergeRequestNote(g.Id, iid, &gitlab.CreateMergeRequestNoteOptions{Body: &body})
+		if err != nil {
+			return fmt.Errorf("could not comment merge request %d: %v", iid, err)
+		}
+		return nil
+	}
+	if body == note.Body {
+		return nil
+	}
+	_, _, err = g.Client.Notes.UpdateMergeRequestNote(g.Id, iid, note.ID, &gitlab.UpdateMergeRequestNoteOptions{Body: &body})
+	if err != nil {
+		return fmt.Errorf("could not update note of merge request %d: %v", iid, err)
+	}
+	return nil
+}
@@ -30,6 +30,8 @@ type GoogleChat struct {
 	ProxyURL string
 	Username string
 	Channel  string
+
+	requestConfig
 }
 
 // GoogleChatPayload holds the channel and attachments
@@ -141,7 +143,7 @@ func (s *GoogleChat) Post(event events.Event) error {
 		Cards: []GoogleChatCard{card},
 	}
 
-	err := postMessage(s.URL, s.ProxyURL, nil, payload)
+	err := s.transport.postMessage(s.URL, s.ProxyURL, nil, payload, s.withCapture(), s.withDelivery(event))
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
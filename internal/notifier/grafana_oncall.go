@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+// GrafanaOnCall is a Grafana OnCall notifier, it fires an alert on the error
+// events and resolves it on the following info events of the same object.
+type GrafanaOnCall struct {
+	// URL is the address of a formatted webhook integration.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	requestConfig
+}
+
+type grafanaOnCallPayload struct {
+	AlertUID string `json:"alert_uid"`
+	Title    string `json:"title"`
+	State    string `json:"state"`
+	Message  string `json:"message"`
+}
+
+// NewGrafanaOnCall returns a notifier for the address of a formatted webhook
+// integration, e.g. 'https://oncall.example.com/integrations/v1/formatted_webhook/<token>/'.
+func NewGrafanaOnCall(addr, proxyURL string, certPool *x509.CertPool) (*GrafanaOnCall, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Grafana OnCall integration URL %s: %w", addr, err)
+	}
+
+	return &GrafanaOnCall{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+	}, nil
+}
+
+// Post sends the alert of the involved object, alerting on the error events
+// and ok on the info events, the progressing events are skipped.
+func (g *GrafanaOnCall) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	state := "ok"
+	if event.Severity == events.EventSeverityError {
+		state = "alerting"
+	}
+	payload := grafanaOnCallPayload{
+		AlertUID: incidentKey(event),
+		Title:    incidentTitle(event),
+		State:    state,
+		Message:  incidentMessage(event),
+	}
+
+	err := g.transport.postMessage(g.URL, g.ProxyURL, g.CertPool, payload, g.withCapture(), g.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
+
+// incidentKey returns the key of the incidents of the involved object,
+// so that the info events resolve the incident opened by an error event.
+func incidentKey(event events.Event) string {
+	obj := event.InvolvedObject
+	return sha1String(fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name))
+}
+
+// incidentTitle returns the title of the incident, e.g. 'kustomization/apps: health check failed'.
+func incidentTitle(event events.Event) string {
+	name, desc := formatNameAndDescription(event)
+	return fmt.Sprintf("%s: %s", name, desc)
+}
+
+// incidentMessage returns the event message followed by the metadata.
+func incidentMessage(event events.Event) string {
+	var body strings.Builder
+	body.WriteString(event.Message)
+	if len(event.Metadata) > 0 {
+		keys := make([]string, 0, len(event.Metadata))
+		for key := range event.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		body.WriteString("\n")
+		for _, key := range keys {
+			fmt.Fprintf(&body, "\n%s: %s", key, event.Metadata[key])
+		}
+	}
+	return body.String()
+}
+
+// incidentTimelineEntry returns the timeline entry of the event, with the
+// involved object and its revision, e.g. 'Kustomization/flux-system/apps
+// at main/8a3f: health check failed'.
+func incidentTimelineEntry(event events.Event) string {
+	obj := event.InvolvedObject
+	entry := fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name)
+	if revision, ok := event.Metadata["revision"]; ok {
+		entry += " at " + revision
+	}
+	return fmt.Sprintf("%s: %s", entry, event.Message)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrafanaOnCall_Post(t *testing.T) {
+	var payloads []grafanaOnCallPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/integrations/v1/formatted_webhook/token/", r.URL.Path)
+		var payload grafanaOnCallPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer ts.Close()
+
+	oncall, err := NewGrafanaOnCall(ts.URL+"/integrations/v1/formatted_webhook/token/", "", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, oncall.Post(event))
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	require.NoError(t, oncall.Post(progressing))
+	require.NoError(t, oncall.Post(testEvent()))
+
+	require.Len(t, payloads, 2)
+	require.Equal(t, "alerting", payloads[0].State)
+	require.Equal(t, "gitrepository/webapp: reason", payloads[0].Title)
+	require.Equal(t, "message\n\ntest: metadata", payloads[0].Message)
+	require.Equal(t, "ok", payloads[1].State)
+	require.Equal(t, payloads[0].AlertUID, payloads[1].AlertUID)
+}
+
+func TestNewGrafanaOnCall(t *testing.T) {
+	_, err := NewGrafanaOnCall("oncall.example.com", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// standardWebhooksSecretPrefix prefixes the base64 encoded Standard Webhooks secrets.
+	standardWebhooksSecretPrefix = "whsec_"
+
+	// The headers of the Standard Webhooks signatures.
+	standardWebhooksIDHeader        = "webhook-id"
+	standardWebhooksTimestampHeader = "webhook-timestamp"
+	standardWebhooksSignatureHeader = "webhook-signature"
+)
+
+// HMACSigner signs the payloads of the generic-hmac provider with a shared key.
+type HMACSigner struct {
+	key       []byte
+	algorithm string
+	hash      func() hash.Hash
+	header    string
+	format    string
+}
+
+// NewHMACSigner returns a signer of the payloads with the key, the options
+// default to the hex encoded SHA256 signatures in the X-Signature header.
+// The Standard Webhooks keys can be prefixed with 'whsec_', in which case
+// the rest of the key is base64 decoded.
+func NewHMACSigner(key string, options *v1beta1.ProviderHMAC) (*HMACSigner, error) {
+	if key == "" {
+		return nil, errors.New("the HMAC key is empty, it's read from the 'token' key of the secret")
+	}
+	s := &HMACSigner{
+		key:       []byte(key),
+		algorithm: v1beta1.SHA256HMACAlgorithm,
+		format:    v1beta1.HexHMACFormat,
+	}
+	if options != nil {
+		if options.Algorithm != "" {
+			s.algorithm = options.Algorithm
+		}
+		if options.Format != "" {
+			s.format = options.Format
+		}
+		s.header = options.Header
+	}
+
+	switch s.algorithm {
+	case v1beta1.SHA1HMACAlgorithm:
+		s.hash = sha1.New
+	case v1beta1.SHA256HMACAlgorithm:
+		s.hash = sha256.New
+	case v1beta1.SHA512HMACAlgorithm:
+		s.hash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported HMAC algorithm %s", s.algorithm)
+	}
+
+	switch s.format {
+	case v1beta1.HexHMACFormat:
+		if s.header == "" {
+			s.header = SignatureHeader
+		}
+	case v1beta1.StandardWebhooksHMACFormat:
+		if s.algorithm != v1beta1.SHA256HMACAlgorithm {
+			return nil, fmt.Errorf("the %s format requires the %s algorithm", s.format, v1beta1.SHA256HMACAlgorithm)
+		}
+		if s.header == "" {
+			s.header = standardWebhooksSignatureHeader
+		}
+		if strings.HasPrefix(key, standardWebhooksSecretPrefix) {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key, standardWebhooksSecretPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Standard Webhooks secret: %w", err)
+			}
+			s.key = decoded
+		}
+	default:
+		return nil, fmt.Errorf("unsupported HMAC format %s", s.format)
+	}
+	return s, nil
+}
+
+// Headers returns the signature headers of the payload, the Standard Webhooks
+// signatures also cover the message ID and the timestamp sent in their headers.
+func (s *HMACSigner) Headers(payload []byte, id string, now time.Time) map[string]string {
+	if s.format == v1beta1.StandardWebhooksHMACFormat {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		signed := make([]byte, 0, len(id)+len(timestamp)+len(payload)+2)
+		signed = append(signed, id+"."+timestamp+"."...)
+		signed = append(signed, payload...)
+		return map[string]string{
+			standardWebhooksIDHeader:        id,
+			standardWebhooksTimestampHeader: timestamp,
+			s.header:                        "v1," + base64.StdEncoding.EncodeToString(s.sum(signed)),
+		}
+	}
+	return map[string]string{
+		s.header: s.algorithm + "=" + hex.EncodeToString(s.sum(payload)),
+	}
+}
+
+func (s *HMACSigner) sum(data []byte) []byte {
+	mac := hmac.New(s.hash, s.key)
+	_, _ = mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestHMACSigner_Headers(t *testing.T) {
+	s, err := NewHMACSigner("s3cr3t", nil)
+	require.NoError(t, err)
+	headers := s.Headers([]byte(`{"test":1}`), "id", time.Now())
+	require.Len(t, headers, 1)
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", headers[SignatureHeader])
+
+	s, err = NewHMACSigner("s3cr3t", &v1beta1.ProviderHMAC{Algorithm: "sha512", Header: "X-Hub-Signature"})
+	require.NoError(t, err)
+	mac := hmac.New(sha512.New, []byte("s3cr3t"))
+	_, _ = mac.Write([]byte(`{"test":1}`))
+	require.Equal(t, map[string]string{
+		"X-Hub-Signature": "sha512=" + hex.EncodeToString(mac.Sum(nil)),
+	}, s.Headers([]byte(`{"test":1}`), "id", time.Now()))
+
+	// the example of the Standard Webhooks specification
+	s, err = NewHMACSigner("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", &v1beta1.ProviderHMAC{Format: "standard-webhooks"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"webhook-id":        "msg_p5jXN8AQM9LWM0D4loKWxJek",
+		"webhook-timestamp": "1614265330",
+		"webhook-signature": "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+	}, s.Headers([]byte(`{"test": 2432232314}`), "msg_p5jXN8AQM9LWM0D4loKWxJek", time.Unix(1614265330, 0)))
+}
+
+func TestNewHMACSigner_Invalid(t *testing.T) {
+	_, err := NewHMACSigner("", nil)
+	require.Error(t, err)
+	_, err = NewHMACSigner("s3cr3t", &v1beta1.ProviderHMAC{Algorithm: "md5"})
+	require.Error(t, err)
+	_, err = NewHMACSigner("s3cr3t", &v1beta1.ProviderHMAC{Algorithm: "sha1", Format: "standard-webhooks"})
+	require.Error(t, err)
+	_, err = NewHMACSigner("whsec_!!!", &v1beta1.ProviderHMAC{Format: "standard-webhooks"})
+	require.Error(t, err)
+}
+
+func TestForwarder_PostHMAC(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		s, err := NewHMACSigner("s3cr3t", &v1beta1.ProviderHMAC{Format: "standard-webhooks"})
+		require.NoError(t, err)
+		require.NotEmpty(t, r.Header.Get("webhook-id"))
+		timestamp, err := strconv.ParseInt(r.Header.Get("webhook-timestamp"), 10, 64)
+		require.NoError(t, err)
+		expected := s.Headers(b, r.Header.Get("webhook-id"), time.Unix(timestamp, 0))
+		require.Equal(t, expected["webhook-signature"], r.Header.Get("webhook-signature"))
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL, "", "", "", "s3cr3t", nil)
+	factory.HMAC = &v1beta1.ProviderHMAC{Format: "standard-webhooks"}
+	forwarder, err := factory.Notifier("generic-hmac")
+	require.NoError(t, err)
+	require.NoError(t, forwarder.Post(testEvent()))
+
+	// the key is required
+	factory.Token = ""
+	_, err = factory.Notifier("generic-hmac")
+	require.Error(t, err)
+}
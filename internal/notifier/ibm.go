@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// IBMIAMTokenURL is the IBM Cloud IAM endpoint exchanging the API keys for access tokens.
+const IBMIAMTokenURL = "https://iam.cloud.ibm.com/identity/token"
+
+// ibmTokenRenewal is how long before its expiration an access token is renewed.
+const ibmTokenRenewal = 5 * time.Minute
+
+// ibmTokens holds the IAM access tokens of the notifiers, which are
+// created for every event, until shortly before they expire.
+var ibmTokens = &ibmTokenCache{tokens: make(map[string]ibmToken)}
+
+type ibmToken struct {
+	accessToken string
+	renewAt     time.Time
+}
+
+// ibmTokenCache holds the access tokens keyed by the IAM endpoint and
+// a hash of the API key.
+type ibmTokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]ibmToken
+}
+
+func (c *ibmTokenCache) get(key string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	token, ok := c.tokens[key]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(token.renewAt) {
+		delete(c.tokens, key)
+		return "", false
+	}
+	return token.accessToken, true
+}
+
+func (c *ibmTokenCache) set(key string, token ibmToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = token
+}
+
+// IBMEventNotifications is an implementation of the notification Interface
+// that sends the events to an IBM Cloud Event Notifications instance.
+type IBMEventNotifications struct {
+	// URL is the notifications endpoint of the instance.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// SourceID is the ID of the API source of the instance.
+	SourceID string
+	APIKey   string
+
+	// TokenURL is the IAM endpoint, it defaults to IBMIAMTokenURL.
+	TokenURL string
+
+	requestConfig
+}
+
+// ibmNotification is a CloudEvent with the Event Notifications extensions.
+type ibmNotification struct {
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Time            string       `json:"time"`
+	SpecVersion     string       `json:"specversion"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            events.Event `json:"data"`
+	Severity        string       `json:"ibmenseverity"`
+	SourceID        string       `json:"ibmensourceid"`
+	DefaultShort    string       `json:"ibmendefaultshort"`
+	DefaultLong     string       `json:"ibmendefaultlong"`
+}
+
+// NewIBMEventNotifications returns a notifier for the instance address, e.g.
+// 'https://us-south.event-notifications.cloud.ibm.com/event-notifications/v1/instances/<id>',
+// the channel is the ID of the API source and the token the IBM Cloud API key.
+func NewIBMEventNotifications(address, proxyURL, sourceID, apiKey string, certPool *x509.CertPool) (*IBMEventNotifications, error) {
+	if _, err := url.ParseRequestURI(address); err != nil {
+		return nil, fmt.Errorf("invalid IBM Event Notifications address %s: %w", address, err)
+	}
+	if sourceID == "" {
+		return nil, errors.New("IBM Event Notifications source id cannot be empty")
+	}
+	if apiKey == "" {
+		return nil, errors.New("IBM Cloud API key cannot be empty")
+	}
+
+	return &IBMEventNotifications{
+		URL:      strings.TrimSuffix(address, "/") + "/notifications",
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		SourceID: sourceID,
+		APIKey:   apiKey,
+		TokenURL: IBMIAMTokenURL,
+	}, nil
+}
+
+// Post sends the event as a CloudEvent, the error events have the HIGH severity
+// and the other events the LOW severity.
+func (i *IBMEventNotifications) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	token, err := i.accessToken()
+	if err != nil {
+		return err
+	}
+
+	timestamp := event.Timestamp.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	obj := event.InvolvedObject
+	payload := ibmNotification{
+		ID:              sha1String(fmt.Sprintf("%s/%s/%s/%s/%d", obj.Kind, obj.Namespace, obj.Name, event.Message, timestamp.UnixNano())),
+		Source:          event.ReportingController,
+		Type:            cloudEventType,
+		Time:            timestamp.UTC().Format(time.RFC3339),
+		SpecVersion:     "1.0",
+		DataContentType: "application/json",
+		Data:            event,
+		Severity:        ibmSeverity(event.Severity),
+		SourceID:        i.SourceID,
+		DefaultShort:    fmt.Sprintf("%s/%s.%s", obj.Kind, obj.Name, obj.Namespace),
+		DefaultLong:     event.Message,
+	}
+	if payload.Source == "" {
+		payload.Source = KubernetesEventsComponent
+	}
+
+	err = i.transport.postMessage(i.URL, i.ProxyURL, i.CertPool, payload, func(req *retryablehttp.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}, i.withCapture(), i.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
+
+// accessToken exchanges the API key for an IAM access token, the
+// token is reused until shortly before its expiration.
+func (i *IBMEventNotifications) accessToken() (string, error) {
+	key := i.TokenURL + "/" + sha1String(i.APIKey)
+	if token, ok := ibmTokens.get(key, time.Now()); ok {
+		return token, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {i.APIKey},
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		// Expiration is the expiration time of the token in seconds since the epoch.
+		Expiration int64 `json:"expiration"`
+	}
+	err := i.transport.sendRequest(http.MethodPost, i.TokenURL, i.ProxyURL, i.CertPool, []byte(form.Encode()), &token, func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get an IBM Cloud IAM token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("failed to get an IBM Cloud IAM token: empty access token")
+	}
+	if token.Expiration > 0 {
+		renewAt := time.Unix(token.Expiration, 0).Add(-ibmTokenRenewal)
+		if time.Now().Before(renewAt) {
+			ibmTokens.set(key, ibmToken{accessToken: token.AccessToken, renewAt: renewAt})
+		}
+	}
+	return token.AccessToken, nil
+}
+
+func ibmSeverity(severity string) string {
+	if severity == events.EventSeverityError {
+		return "HIGH"
+	}
+	return "LOW"
+}
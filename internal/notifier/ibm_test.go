@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIBMEventNotifications_Post(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/identity/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "urn:ibm:params:oauth:grant-type:apikey", r.Form.Get("grant_type"))
+			require.Equal(t, "api-key", r.Form.Get("apikey"))
+			fmt.Fprint(w, `{"access_token":"iam-token","token_type":"Bearer"}`)
+		case "/event-notifications/v1/instances/1234/notifications":
+			require.Equal(t, "Bearer iam-token", r.Header.Get("Authorization"))
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			var payload ibmNotification
+			require.NoError(t, json.Unmarshal(b, &payload))
+			require.Equal(t, "source-id", payload.SourceID)
+			require.Equal(t, "HIGH", payload.Severity)
+			require.Equal(t, "1.0", payload.SpecVersion)
+			require.Equal(t, "source-controller", payload.Source)
+			require.Equal(t, "GitRepository/webapp.gitops-system", payload.DefaultShort)
+			require.Equal(t, "webapp", payload.Data.InvolvedObject.Name)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	ibm, err := NewIBMEventNotifications(ts.URL+"/event-notifications/v1/instances/1234", "", "source-id", "api-key", nil)
+	require.NoError(t, err)
+	ibm.TokenURL = ts.URL + "/identity/token"
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, ibm.Post(event))
+}
+
+func TestIBMEventNotifications_PostTokenCache(t *testing.T) {
+	var tokens int
+	expiration := time.Now().Add(time.Hour)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/identity/token":
+			tokens++
+			fmt.Fprintf(w, `{"access_token":"iam-token-%d","expiration":%d}`, tokens, expiration.Unix())
+		default:
+			require.Equal(t, fmt.Sprintf("Bearer iam-token-%d", tokens), r.Header.Get("Authorization"))
+		}
+	}))
+	defer ts.Close()
+
+	// the IAM endpoint is reached with the CA of the provider
+	cert, err := x509.ParseCertificate(ts.TLS.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	certPool := x509.NewCertPool()
+	certPool.AddCert(cert)
+
+	newNotifier := func(apiKey string) *IBMEventNotifications {
+		ibm, err := NewIBMEventNotifications(ts.URL, "", "source-id", apiKey, certPool)
+		require.NoError(t, err)
+		ibm.TokenURL = ts.URL + "/identity/token"
+		return ibm
+	}
+
+	require.NoError(t, newNotifier("api-key-cache").Post(testEvent()))
+	require.NoError(t, newNotifier("api-key-cache").Post(testEvent()))
+	require.Equal(t, 1, tokens)
+
+	// the tokens are cached per API key
+	require.NoError(t, newNotifier("api-key-other").Post(testEvent()))
+	require.Equal(t, 2, tokens)
+
+	// the tokens about to expire are renewed
+	expiration = time.Now().Add(time.Minute)
+	require.NoError(t, newNotifier("api-key-renew").Post(testEvent()))
+	require.NoError(t, newNotifier("api-key-renew").Post(testEvent()))
+	require.Equal(t, 4, tokens)
+}
+
+func TestIBMEventNotifications_PostTokenError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	ibm, err := NewIBMEventNotifications(ts.URL, "", "source-id", "api-key", nil)
+	require.NoError(t, err)
+	ibm.TokenURL = ts.URL
+	require.Error(t, ibm.Post(testEvent()))
+
+	_, err = NewIBMEventNotifications(ts.URL, "", "", "api-key", nil)
+	require.Error(t, err)
+	_, err = NewIBMEventNotifications(ts.URL, "", "source-id", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// icingaCheckResultPath is the path of the Icinga 2 API action
+	// processing the passive check results.
+	icingaCheckResultPath = "/v1/actions/process-check-result"
+
+	passiveCheckOK       = 0
+	passiveCheckCritical = 2
+)
+
+// Icinga is an Icinga 2 notifier, it submits the events as passive check
+// results of the service of the involved object.
+type Icinga struct {
+	// URL is the address of the process-check-result action, e.g.
+	// 'https://icinga.example.com:5665/v1/actions/process-check-result'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Host is the name of the Icinga host of the services.
+	Host string
+
+	// Username and Password authenticate the requests of the API user.
+	Username string
+	Password string
+
+	channelRouting
+	requestConfig
+}
+
+type icingaCheckResult struct {
+	Type            string            `json:"type"`
+	Filter          string            `json:"filter"`
+	FilterVars      map[string]string `json:"filter_vars"`
+	ExitStatus      int               `json:"exit_status"`
+	PluginOutput    string            `json:"plugin_output"`
+	CheckSource     string            `json:"check_source"`
+	PerformanceData []string          `json:"performance_data,omitempty"`
+}
+
+// NewIcinga returns a notifier for the Icinga 2 API address, the channel is
+// the host name and the token the password of the API user.
+func NewIcinga(addr, proxyURL, host, username, password string, certPool *x509.CertPool) (*Icinga, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Icinga address %s: %w", addr, err)
+	}
+	if host == "" {
+		return nil, errors.New("icinga host cannot be empty")
+	}
+	if username == "" || password == "" {
+		return nil, errors.New("icinga username and password cannot be empty")
+	}
+
+	addr = strings.TrimSuffix(addr, "/")
+	if !strings.HasSuffix(addr, icingaCheckResultPath) {
+		addr += icingaCheckResultPath
+	}
+	return &Icinga{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Host:     host,
+		Username: username,
+		Password: password,
+	}, nil
+}
+
+// Post submits the check result of the event, the error events are critical
+// and the info events ok, the progressing events are skipped.
+func (i *Icinga) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	host, err := i.channelFor(event, i.Host)
+	if err != nil {
+		return err
+	}
+
+	status, output := passiveCheckResult(event)
+	payload := icingaCheckResult{
+		Type:   "Service",
+		Filter: "host.name==host_name && service.name==service_name",
+		FilterVars: map[string]string{
+			"host_name":    host,
+			"service_name": passiveCheckService(event),
+		},
+		ExitStatus:   status,
+		PluginOutput: output,
+		CheckSource:  "flux",
+	}
+
+	auth := func(req *retryablehttp.Request) {
+		req.SetBasicAuth(i.Username, i.Password)
+		req.Header.Set("Accept", "application/json")
+	}
+	if err := i.transport.postMessage(i.URL, i.ProxyURL, i.CertPool, payload, auth, i.withCapture(), i.withDelivery(event)); err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
+
+// passiveCheckService returns the service name of the involved object,
+// e.g. 'flux-kustomization-flux-system-apps'.
+func passiveCheckService(event events.Event) string {
+	obj := event.InvolvedObject
+	return strings.ToLower(fmt.Sprintf("flux-%s-%s-%s", obj.Kind, obj.Namespace, obj.Name))
+}
+
+// passiveCheckResult returns the exit status and the plugin output of the event,
+// e.g. 'CRITICAL - HealthCheckFailed: health check failed (main/8a3f)'.
+func passiveCheckResult(event events.Event) (int, string) {
+	status, state := passiveCheckOK, "OK"
+	if event.Severity == events.EventSeverityError {
+		status, state = passiveCheckCritical, "CRITICAL"
+	}
+
+	// the plugin output is a single line, the details follow it
+	message := strings.Replace(event.Message, "\n", " ", -1)
+	output := fmt.Sprintf("%s - %s: %s", state, event.Reason, message)
+	if revision, ok := event.Metadata["revision"]; ok {
+		output += fmt.Sprintf(" (%s)", revision)
+	}
+	return status, output
+}
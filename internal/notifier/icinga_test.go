@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIcinga_Post(t *testing.T) {
+	var results []icingaCheckResult
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, icingaCheckResultPath, r.URL.Path)
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "flux", username)
+		require.Equal(t, "s3cr3t", password)
+		var result icingaCheckResult
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&result))
+		results = append(results, result)
+	}))
+	defer ts.Close()
+
+	icinga, err := NewIcinga(ts.URL+"/", "", "k8s-prod", "flux", "s3cr3t", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, icinga.Post(event))
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	require.NoError(t, icinga.Post(progressing))
+	require.NoError(t, icinga.Post(testEvent()))
+
+	require.Len(t, results, 2)
+	require.Equal(t, "Service", results[0].Type)
+	require.Equal(t, map[string]string{
+		"host_name":    "k8s-prod",
+		"service_name": "flux-gitrepository-gitops-system-webapp",
+	}, results[0].FilterVars)
+	require.Equal(t, passiveCheckCritical, results[0].ExitStatus)
+	require.Equal(t, "CRITICAL - reason: message", results[0].PluginOutput)
+	require.Equal(t, passiveCheckOK, results[1].ExitStatus)
+}
+
+func TestNewIcinga(t *testing.T) {
+	_, err := NewIcinga("icinga.example.com", "", "k8s-prod", "flux", "s3cr3t", nil)
+	require.Error(t, err)
+	_, err = NewIcinga("https://icinga.example.com:5665", "", "", "flux", "s3cr3t", nil)
+	require.Error(t, err)
+	_, err = NewIcinga("https://icinga.example.com:5665", "", "k8s-prod", "flux", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Instatus is an Instatus notifier, it sets the status of a component to
+// partial outage on the error events and to operational on the other events.
+type Instatus struct {
+	// URL is the page endpoint, e.g. 'https://api.instatus.com/v1/<page-id>'.
+	URL         string
+	ProxyURL    string
+	CertPool    *x509.CertPool
+	ComponentID string
+	APIKey      string
+
+	requestConfig
+}
+
+// NewInstatus returns a notifier for the page address, the channel
+// is the component ID and the token an API key.
+func NewInstatus(addr, proxyURL, componentID, apiKey string, certPool *x509.CertPool) (*Instatus, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Instatus address %s: %w", addr, err)
+	}
+	if componentID == "" {
+		return nil, errors.New("instatus component id cannot be empty")
+	}
+	if apiKey == "" {
+		return nil, errors.New("instatus API key cannot be empty")
+	}
+
+	return &Instatus{
+		URL:         strings.TrimSuffix(addr, "/"),
+		ProxyURL:    proxyURL,
+		CertPool:    certPool,
+		ComponentID: componentID,
+		APIKey:      apiKey,
+	}, nil
+}
+
+// Post updates the status of the component.
+func (i *Instatus) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	status := "OPERATIONAL"
+	if event.Severity == events.EventSeverityError {
+		status = "PARTIALOUTAGE"
+	}
+	body, err := json.Marshal(map[string]string{"status": status})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	address := fmt.Sprintf("%s/components/%s", i.URL, url.PathEscape(i.ComponentID))
+	err = i.transport.sendRequest(http.MethodPut, address, i.ProxyURL, i.CertPool, body, nil, func(req *retryablehttp.Request) {
+		req.Header.Set("Authorization", "Bearer "+i.APIKey)
+	}, i.withCapture(), i.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("could not update Instatus component %s: %w", i.ComponentID, err)
+	}
+	return nil
+}
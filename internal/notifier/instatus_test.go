@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstatus_Post(t *testing.T) {
+	var statuses []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/v1/page/components/component", r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var payload struct {
+			Status string `json:"status"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		statuses = append(statuses, payload.Status)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	instatus, err := NewInstatus(ts.URL+"/v1/page", "", "component", "token", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, instatus.Post(event))
+	require.NoError(t, instatus.Post(testEvent()))
+	require.Equal(t, []string{"PARTIALOUTAGE", "OPERATIONAL"}, statuses)
+}
+
+func TestNewInstatus(t *testing.T) {
+	_, err := NewInstatus("https://api.instatus.com/v1/page", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewInstatus("https://api.instatus.com/v1/page", "", "component", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// KeptnEvaluationTimeframe is the duration of the evaluations, starting
+	// at the time of the event.
+	KeptnEvaluationTimeframe = "5m"
+
+	keptnSpecVersion = "0.2.1"
+)
+
+// Keptn is an implementation of the notification Interface that triggers
+// a Keptn evaluation after the successful reconciliations.
+type Keptn struct {
+	// URL is the event endpoint of the Keptn API.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+	Token    string
+
+	Project string
+	Stage   string
+
+	// Service is the Keptn service, it defaults to the name of the involved object.
+	Service string
+
+	requestConfig
+}
+
+type keptnEvent struct {
+	ID               string    `json:"id"`
+	Source           string    `json:"source"`
+	Type             string    `json:"type"`
+	Time             string    `json:"time"`
+	SpecVersion      string    `json:"specversion"`
+	KeptnSpecVersion string    `json:"shkeptnspecversion"`
+	DataContentType  string    `json:"datacontenttype"`
+	Data             keptnData `json:"data"`
+}
+
+type keptnData struct {
+	Project    string            `json:"project"`
+	Stage      string            `json:"stage"`
+	Service    string            `json:"service"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Evaluation keptnEvaluation   `json:"evaluation"`
+}
+
+type keptnEvaluation struct {
+	Start     string `json:"start"`
+	Timeframe string `json:"timeframe"`
+}
+
+// NewKeptn returns a notifier for the Keptn API address, e.g. 'https://keptn.example.com/api',
+// the channel is formatted as '<project>/<stage>[/<service>]' and the token is the Keptn API token.
+func NewKeptn(addr, proxyURL, channel, token string, certPool *x509.CertPool) (*Keptn, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Keptn API address %s: %w", addr, err)
+	}
+	if token == "" {
+		return nil, errors.New("keptn token cannot be empty")
+	}
+	comp := strings.Split(channel, "/")
+	if len(comp) < 2 || len(comp) > 3 || comp[0] == "" || comp[1] == "" {
+		return nil, fmt.Errorf("invalid Keptn channel %q, expected to be <project>/<stage>[/<service>]", channel)
+	}
+
+	k := &Keptn{
+		URL:      strings.TrimSuffix(addr, "/") + "/v1/event",
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Token:    token,
+		Project:  comp[0],
+		Stage:    comp[1],
+	}
+	if len(comp) == 3 {
+		k.Service = comp[2]
+	}
+	return k, nil
+}
+
+// Post sends a 'sh.keptn.event.<stage>.evaluation.triggered' CloudEvent for
+// the info events with a revision, the other events are skipped.
+func (k *Keptn) Post(event events.Event) error {
+	revision, ok := event.Metadata["revision"]
+	if !ok || event.Severity != events.EventSeverityInfo ||
+		event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	timestamp := event.Timestamp.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	obj := event.InvolvedObject
+	service := k.Service
+	if service == "" {
+		service = obj.Name
+	}
+	source := event.ReportingController
+	if source == "" {
+		source = KubernetesEventsComponent
+	}
+
+	payload := keptnEvent{
+		ID:               sha1String(fmt.Sprintf("%s/%s/%s/%s/%d", obj.Kind, obj.Namespace, obj.Name, revision, timestamp.UnixNano())),
+		Source:           source,
+		Type:             fmt.Sprintf("sh.keptn.event.%s.evaluation.triggered", k.Stage),
+		Time:             timestamp.UTC().Format(time.RFC3339),
+		SpecVersion:      "1.0",
+		KeptnSpecVersion: keptnSpecVersion,
+		DataContentType:  "application/json",
+		Data: keptnData{
+			Project: k.Project,
+			Stage:   k.Stage,
+			Service: service,
+			Labels: map[string]string{
+				"kind":      obj.Kind,
+				"name":      obj.Name,
+				"namespace": obj.Namespace,
+				"revision":  revision,
+			},
+			Evaluation: keptnEvaluation{
+				Start:     timestamp.UTC().Format(time.RFC3339),
+				Timeframe: KeptnEvaluationTimeframe,
+			},
+		},
+	}
+
+	err := k.transport.postMessage(k.URL, k.ProxyURL, k.CertPool, payload, func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+		req.Header.Set("x-token", k.Token)
+	}, k.withCapture(), k.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeptn_Post(t *testing.T) {
+	var received []keptnEvent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/event", r.URL.Path)
+		require.Equal(t, "keptn-token", r.Header.Get("x-token"))
+		require.Equal(t, "application/cloudevents+json", r.Header.Get("Content-Type"))
+		var payload keptnEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+	}))
+	defer ts.Close()
+
+	k, err := NewKeptn(ts.URL+"/api", "", "podinfo/production", "keptn-token", nil)
+	require.NoError(t, err)
+
+	event := commitStatusEvent()
+	require.NoError(t, k.Post(event))
+	require.Len(t, received, 1)
+	payload := received[0]
+	require.Equal(t, "sh.keptn.event.production.evaluation.triggered", payload.Type)
+	require.Equal(t, "source-controller", payload.Source)
+	require.Equal(t, "podinfo", payload.Data.Project)
+	require.Equal(t, "production", payload.Data.Stage)
+	require.Equal(t, "webapp", payload.Data.Service)
+	require.Equal(t, "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1", payload.Data.Labels["revision"])
+	require.Equal(t, KeptnEvaluationTimeframe, payload.Data.Evaluation.Timeframe)
+
+	// the failures and the events without revision are skipped
+	event.Severity = events.EventSeverityError
+	require.NoError(t, k.Post(event))
+	require.NoError(t, k.Post(testEvent()))
+	require.Len(t, received, 1)
+
+	k, err = NewKeptn(ts.URL+"/api", "", "podinfo/production/frontend", "keptn-token", nil)
+	require.NoError(t, err)
+	require.NoError(t, k.Post(commitStatusEvent()))
+	require.Equal(t, "frontend", received[1].Data.Service)
+}
+
+func TestNewKeptn(t *testing.T) {
+	for _, channel := range []string{"", "podinfo", "podinfo/", "podinfo/production/frontend/extra"} {
+		_, err := NewKeptn("https://keptn.example.com/api", "", channel, "keptn-token", nil)
+		require.Error(t, err, channel)
+	}
+	_, err := NewKeptn("https://keptn.example.com/api", "", "podinfo/production", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// KubernetesEventsComponent is the source component of the mirrored events.
+const KubernetesEventsComponent = "notification-controller"
+
+// KubernetesEvents is an implementation of the notification Interface that
+// re-emits the notifications as Kubernetes events.
+type KubernetesEvents struct {
+	Client client.Client
+
+	// Namespace is the namespace of the provider, the events are attached
+	// to the involved object only if it's in this namespace.
+	Namespace string
+
+	// Alert is the object the events are attached to when the involved
+	// object is in another namespace.
+	Alert *corev1.ObjectReference
+}
+
+// NewKubernetesEvents returns a notifier that creates the events in the provider namespace.
+func NewKubernetesEvents(kubeClient client.Client, namespace string, alert *corev1.ObjectReference) (*KubernetesEvents, error) {
+	if kubeClient == nil {
+		return nil, errors.New("kubernetes client cannot be nil")
+	}
+
+	return &KubernetesEvents{
+		Client:    kubeClient,
+		Namespace: namespace,
+		Alert:     alert,
+	}, nil
+}
+
+// Post creates a Kubernetes event attached to the involved object,
+// or to the alert if the object is in another namespace.
+func (k *KubernetesEvents) Post(event events.Event) error {
+	target := event.InvolvedObject
+	if target.Namespace != k.Namespace {
+		if k.Alert == nil {
+			return fmt.Errorf("involved object %s/%s is not in the provider namespace '%s'",
+				target.Kind, target.Name, k.Namespace)
+		}
+		target = *k.Alert
+	}
+
+	eventType := corev1.EventTypeNormal
+	if event.Severity == events.EventSeverityError {
+		eventType = corev1.EventTypeWarning
+	}
+
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = metav1.Now()
+	}
+
+	annotations := make(map[string]string, len(event.Metadata))
+	for key, value := range event.Metadata {
+		annotations[fmt.Sprintf("%s/%s", v1beta1.GroupVersion.Group, key)] = value
+	}
+
+	e := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%v.%x", target.Name, time.Now().UnixNano()),
+			Namespace:   k.Namespace,
+			Annotations: annotations,
+		},
+		InvolvedObject: target,
+		Reason:         event.Reason,
+		Message:        event.Message,
+		Type:           eventType,
+		Source: corev1.EventSource{
+			Component: KubernetesEventsComponent,
+		},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := k.Client.Create(ctx, e); err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestKubernetesEvents_Post(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	alert := &corev1.ObjectReference{Kind: "Alert", Name: "on-call", Namespace: "gitops-system"}
+	k, err := NewKubernetesEvents(kubeClient, "gitops-system", alert)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, k.Post(event))
+
+	var list corev1.EventList
+	require.NoError(t, kubeClient.List(context.TODO(), &list, client.InNamespace("gitops-system")))
+	require.Len(t, list.Items, 1)
+	e := list.Items[0]
+	require.Equal(t, event.InvolvedObject, e.InvolvedObject)
+	require.Equal(t, corev1.EventTypeWarning, e.Type)
+	require.Equal(t, event.Message, e.Message)
+	require.Equal(t, event.Reason, e.Reason)
+	require.Equal(t, KubernetesEventsComponent, e.Source.Component)
+	require.Equal(t, "metadata", e.Annotations["notification.toolkit.fluxcd.io/test"])
+
+	// the objects in other namespaces are replaced by the alert
+	event.InvolvedObject.Namespace = "apps"
+	require.NoError(t, k.Post(event))
+	require.NoError(t, kubeClient.List(context.TODO(), &list, client.InNamespace("gitops-system")))
+	require.Len(t, list.Items, 2)
+	var kinds []string
+	for _, item := range list.Items {
+		kinds = append(kinds, item.InvolvedObject.Kind)
+	}
+	require.Contains(t, kinds, "Alert")
+
+	k.Alert = nil
+	require.Error(t, k.Post(event))
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"errors"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/go-logr/logr"
+)
+
+// Log is an implementation of the notification Interface that writes
+// the notifications to the controller log, it's used by the 'log'
+// providers and by the alerts in dry run mode.
+type Log struct {
+	Logger logr.Logger
+}
+
+// NewLog returns a notifier writing the notifications with the logger.
+func NewLog(logger logr.Logger) (*Log, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	return &Log{Logger: logger}, nil
+}
+
+// Post writes the notification as a log line, with the involved object,
+// the severity, the reason and the metadata of the event as values.
+func (l *Log) Post(event events.Event) error {
+	l.Logger.Info(event.Message,
+		"kind", event.InvolvedObject.Kind,
+		"name", event.InvolvedObject.Name,
+		"namespace", event.InvolvedObject.Namespace,
+		"severity", event.Severity,
+		"reason", event.Reason,
+		"metadata", event.Metadata)
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger records the messages and the values of the info lines.
+type recordingLogger struct {
+	logr.Logger
+	lines []map[string]interface{}
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	line := map[string]interface{}{"msg": msg}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		line[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.lines = append(l.lines, line)
+}
+
+func TestLog_Post(t *testing.T) {
+	_, err := NewLog(nil)
+	require.Error(t, err)
+
+	logger := &recordingLogger{}
+	log, err := NewLog(logger)
+	require.NoError(t, err)
+	require.NoError(t, log.Post(testEvent()))
+
+	require.Len(t, logger.lines, 1)
+	line := logger.lines[0]
+	require.Equal(t, "message", line["msg"])
+	require.Equal(t, "GitRepository", line["kind"])
+	require.Equal(t, "webapp", line["name"])
+	require.Equal(t, "info", line["severity"])
+	require.Equal(t, testEvent().Metadata, line["metadata"])
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Nagios is a Nagios notifier, it submits the events as passive check results
+// of the service of the involved object with the NRDP API.
+type Nagios struct {
+	// URL is the address of the NRDP API, e.g. 'https://nagios.example.com/nrdp/'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Host is the name of the Nagios host of the services.
+	Host string
+
+	// Token is the NRDP token authorizing the check results.
+	Token string
+
+	channelRouting
+	requestConfig
+}
+
+type nagiosCheckResults struct {
+	CheckResults []nagiosCheckResult `json:"checkresults"`
+}
+
+type nagiosCheckResult struct {
+	CheckResult struct {
+		Type string `json:"type"`
+	} `json:"checkresult"`
+	Hostname    string `json:"hostname"`
+	ServiceName string `json:"servicename"`
+	State       string `json:"state"`
+	Output      string `json:"output"`
+}
+
+// NewNagios returns a notifier for the NRDP address, the channel is the
+// host name and the token the NRDP token.
+func NewNagios(addr, proxyURL, host, token string, certPool *x509.CertPool) (*Nagios, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid NRDP address %s: %w", addr, err)
+	}
+	if host == "" {
+		return nil, errors.New("nagios host cannot be empty")
+	}
+	if token == "" {
+		return nil, errors.New("NRDP token cannot be empty")
+	}
+
+	return &Nagios{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Host:     host,
+		Token:    token,
+	}, nil
+}
+
+// Post submits the check result of the event, the error events are critical
+// and the info events ok, the progressing events are skipped.
+func (n *Nagios) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	host, err := n.channelFor(event, n.Host)
+	if err != nil {
+		return err
+	}
+
+	status, output := passiveCheckResult(event)
+	result := nagiosCheckResult{
+		Hostname:    host,
+		ServiceName: passiveCheckService(event),
+		State:       strconv.Itoa(status),
+		Output:      output,
+	}
+	result.CheckResult.Type = "service"
+	data, err := json.Marshal(nagiosCheckResults{CheckResults: []nagiosCheckResult{result}})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("token", n.Token)
+	form.Set("cmd", "submitcheck")
+	form.Set("json", string(data))
+	contentType := func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if err := n.transport.sendMessage(http.MethodPost, n.URL, n.ProxyURL, n.CertPool, []byte(form.Encode()), contentType, n.withCapture(), n.withDelivery(event)); err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
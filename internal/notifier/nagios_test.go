@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNagios_Post(t *testing.T) {
+	var results []nagiosCheckResult
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "s3cr3t", r.PostForm.Get("token"))
+		require.Equal(t, "submitcheck", r.PostForm.Get("cmd"))
+		var payload nagiosCheckResults
+		require.NoError(t, json.Unmarshal([]byte(r.PostForm.Get("json")), &payload))
+		results = append(results, payload.CheckResults...)
+	}))
+	defer ts.Close()
+
+	nagios, err := NewNagios(ts.URL, "", "", "s3cr3t", nil)
+	require.Error(t, err)
+	nagios, err = NewNagios(ts.URL, "", "k8s-prod", "s3cr3t", nil)
+	require.NoError(t, err)
+
+	route, err := newChannelRoute(`{{ index .Metadata "cluster" | default "k8s-prod" }}`, []string{"k8s-*"})
+	require.NoError(t, err)
+	nagios.setChannelRoute(route)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	event.Metadata["cluster"] = "k8s-staging"
+	require.NoError(t, nagios.Post(event))
+
+	require.Len(t, results, 1)
+	require.Equal(t, "service", results[0].CheckResult.Type)
+	require.Equal(t, "k8s-staging", results[0].Hostname)
+	require.Equal(t, "flux-gitrepository-gitops-system-webapp", results[0].ServiceName)
+	require.Equal(t, "2", results[0].State)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// ociSignedHeaders are the headers of the OCI API request signatures.
+var ociSignedHeaders = []string{"date", "(request-target)", "host", "content-length", "content-type", "x-content-sha256"}
+
+// OCICredentials are the API signing key of an OCI user.
+type OCICredentials struct {
+	TenancyID   string
+	UserID      string
+	Fingerprint string
+	PrivateKey  []byte
+}
+
+// OCINotifications is an implementation of the notification Interface
+// that publishes the events to an OCI Notifications topic.
+type OCINotifications struct {
+	// URL is the messages endpoint of the topic.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	KeyID string
+	Key   *rsa.PrivateKey
+
+	requestConfig
+}
+
+type ociMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// NewOCINotifications returns a notifier for the topic address, e.g.
+// 'https://notification.eu-frankfurt-1.oci.oraclecloud.com/20181201/topics/<topic-ocid>'.
+func NewOCINotifications(address, proxyURL string, credentials OCICredentials, certPool *x509.CertPool) (*OCINotifications, error) {
+	if _, err := url.ParseRequestURI(address); err != nil {
+		return nil, fmt.Errorf("invalid OCI topic address %s: %w", address, err)
+	}
+	if credentials.TenancyID == "" || credentials.UserID == "" || credentials.Fingerprint == "" {
+		return nil, errors.New("OCI tenancy, user and fingerprint cannot be empty")
+	}
+	key, err := parseRSAPrivateKey(credentials.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCI private key: %w", err)
+	}
+
+	return &OCINotifications{
+		URL:      strings.TrimSuffix(address, "/") + "/messages",
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		KeyID:    strings.Join([]string{credentials.TenancyID, credentials.UserID, credentials.Fingerprint}, "/"),
+		Key:      key,
+	}, nil
+}
+
+// Post publishes the event with the severity in the title, e.g.
+// '[ERROR] Kustomization/apps.flux-system', and the message and
+// the metadata in the body.
+func (o *OCINotifications) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	obj := event.InvolvedObject
+	var body strings.Builder
+	body.WriteString(event.Message)
+	if len(event.Metadata) > 0 {
+		keys := make([]string, 0, len(event.Metadata))
+		for key := range event.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		body.WriteString("\n")
+		for _, key := range keys {
+			fmt.Fprintf(&body, "\n%s: %s", key, event.Metadata[key])
+		}
+	}
+	message := ociMessage{
+		Title: fmt.Sprintf("[%s] %s/%s.%s", strings.ToUpper(event.Severity), obj.Kind, obj.Name, obj.Namespace),
+		Body:  body.String(),
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	var signErr error
+	err = o.transport.postMessage(o.URL, o.ProxyURL, o.CertPool, json.RawMessage(data), func(req *retryablehttp.Request) {
+		req.Header.Set("messageType", "RAW_TEXT")
+		signErr = signOCIRequest(req.Request, data, o.KeyID, o.Key, time.Now())
+	}, o.withCapture(), o.withDelivery(event))
+	if signErr != nil {
+		return fmt.Errorf("signing OCI request failed: %w", signErr)
+	}
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
+
+// signOCIRequest signs the request with the OCI API signature,
+// a draft-cavage HTTP signature with the RSA-SHA256 algorithm.
+func signOCIRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	sum := sha256.Sum256(body)
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+
+	lines := make([]string, 0, len(ociSignedHeaders))
+	for _, name := range ociSignedHeaders {
+		switch name {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("%s: %s %s", name, strings.ToLower(req.Method), req.URL.RequestURI()))
+		case "host":
+			lines = append(lines, fmt.Sprintf("%s: %s", name, req.URL.Host))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", name, req.Header.Get(name)))
+		}
+	}
+
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(ociSignedHeaders, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded PKCS #1 or PKCS #8 RSA private key.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCINotifications_Post(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "/20181201/topics/ocid1.onstopic/messages", r.URL.Path)
+		require.Equal(t, "RAW_TEXT", r.Header.Get("messageType"))
+
+		sum := sha256.Sum256(b)
+		require.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), r.Header.Get("X-Content-Sha256"))
+
+		auth := regexp.MustCompile(`keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"`).
+			FindStringSubmatch(r.Header.Get("Authorization"))
+		require.Len(t, auth, 4)
+		require.Equal(t, "ocid1.tenancy/ocid1.user/20:3b:97", auth[1])
+		var lines []string
+		for _, name := range strings.Split(auth[2], " ") {
+			switch name {
+			case "(request-target)":
+				lines = append(lines, fmt.Sprintf("%s: post %s", name, r.URL.RequestURI()))
+			case "host":
+				lines = append(lines, fmt.Sprintf("%s: %s", name, r.Host))
+			default:
+				lines = append(lines, fmt.Sprintf("%s: %s", name, r.Header.Get(name)))
+			}
+		}
+		digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+		signature, err := base64.StdEncoding.DecodeString(auth[3])
+		require.NoError(t, err)
+		require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+		var message ociMessage
+		require.NoError(t, json.Unmarshal(b, &message))
+		require.Equal(t, "[INFO] GitRepository/webapp.gitops-system", message.Title)
+		require.Equal(t, "message\n\ntest: metadata", message.Body)
+	}))
+	defer ts.Close()
+
+	credentials := OCICredentials{
+		TenancyID:   "ocid1.tenancy",
+		UserID:      "ocid1.user",
+		Fingerprint: "20:3b:97",
+		PrivateKey:  keyPEM,
+	}
+	oci, err := NewOCINotifications(ts.URL+"/20181201/topics/ocid1.onstopic", "", credentials, nil)
+	require.NoError(t, err)
+	require.NoError(t, oci.Post(testEvent()))
+
+	credentials.PrivateKey = []byte("invalid")
+	_, err = NewOCINotifications(ts.URL, "", credentials, nil)
+	require.Error(t, err)
+}
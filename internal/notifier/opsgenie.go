@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// opsgenieAlertsPath is the path of the alerts API, served by Opsgenie
+	// and by the Jira Service Management operations integrations.
+	opsgenieAlertsPath = "/v2/alerts"
+
+	// opsgenieMessageLimit is the maximum length of the alert messages.
+	opsgenieMessageLimit = 130
+)
+
+// Opsgenie is an Opsgenie or Jira Service Management alerts notifier, it creates
+// an alert on the error events and closes it on the following info events.
+type Opsgenie struct {
+	// URL is the alerts API endpoint, e.g. 'https://api.eu.opsgenie.com/v2/alerts'
+	// or 'https://api.atlassian.com/jsm/ops/integration/v2/alerts'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+	APIKey   string
+
+	// Teams is the comma separated list of the teams the alerts are routed to,
+	// the routing rules of the integration are used when empty.
+	Teams string
+
+	channelRouting
+	requestConfig
+}
+
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags"`
+	Details     map[string]string   `json:"details,omitempty"`
+	Entity      string              `json:"entity"`
+	Source      string              `json:"source"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note"`
+}
+
+// NewOpsgenie returns a notifier for the alerts API address of the region, the
+// channel is the list of teams and the token the API key of the integration.
+func NewOpsgenie(addr, proxyURL, teams, apiKey string, certPool *x509.CertPool) (*Opsgenie, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Opsgenie address %s: %w", addr, err)
+	}
+	if apiKey == "" {
+		return nil, errors.New("opsgenie API key cannot be empty")
+	}
+
+	addr = strings.TrimSuffix(addr, "/")
+	if !strings.HasSuffix(addr, opsgenieAlertsPath) {
+		addr += opsgenieAlertsPath
+	}
+	return &Opsgenie{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		APIKey:   apiKey,
+		Teams:    teams,
+	}, nil
+}
+
+// Post creates the alert of the involved object on the error events and
+// closes it on the info events, the progressing events are skipped.
+func (o *Opsgenie) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	auth := func(req *retryablehttp.Request) {
+		req.Header.Set("Authorization", "GenieKey "+o.APIKey)
+	}
+	alias := incidentKey(event)
+
+	if event.Severity != events.EventSeverityError {
+		address := fmt.Sprintf("%s/%s/close?identifierType=alias", o.URL, url.PathEscape(alias))
+		payload := opsgenieClose{Source: "flux", Note: event.Message}
+		if err := o.transport.postMessage(address, o.ProxyURL, o.CertPool, payload, auth, o.withCapture(), o.withDelivery(event)); err != nil {
+			return fmt.Errorf("postMessage failed: %w", err)
+		}
+		return nil
+	}
+
+	teams, err := o.recipientsFor(event, o.Teams)
+	if err != nil {
+		return err
+	}
+
+	obj := event.InvolvedObject
+	message := incidentTitle(event)
+	if len(message) > opsgenieMessageLimit {
+		message = message[:opsgenieMessageLimit]
+	}
+	payload := opsgenieAlert{
+		Message:     message,
+		Alias:       alias,
+		Description: incidentMessage(event),
+		Tags:        []string{"flux", strings.ToLower(obj.Kind)},
+		Details:     event.Metadata,
+		Entity:      fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name),
+		Source:      "flux",
+	}
+	for _, team := range teams {
+		payload.Responders = append(payload.Responders, opsgenieResponder{Name: team, Type: "team"})
+	}
+
+	if err := o.transport.postMessage(o.URL, o.ProxyURL, o.CertPool, payload, auth, o.withCapture(), o.withDelivery(event)); err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpsgenie_Post(t *testing.T) {
+	var alerts []opsgenieAlert
+	var closed []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GenieKey s3cr3t", r.Header.Get("Authorization"))
+		if r.URL.Path != "/jsm/ops/integration/v2/alerts" {
+			require.Equal(t, "alias", r.URL.Query().Get("identifierType"))
+			closed = append(closed, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var alert opsgenieAlert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		alerts = append(alerts, alert)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	opsgenie, err := NewOpsgenie(ts.URL+"/jsm/ops/integration/", "", "", "s3cr3t", nil)
+	require.NoError(t, err)
+	require.Equal(t, ts.URL+"/jsm/ops/integration/v2/alerts", opsgenie.URL)
+
+	route, err := newChannelRoute(`{{ index .Metadata "team" | default "platform" }}`, []string{"payments", "platform"})
+	require.NoError(t, err)
+	opsgenie.setChannelRoute(route)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	event.Metadata["team"] = "payments"
+	require.NoError(t, opsgenie.Post(event))
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	require.NoError(t, opsgenie.Post(progressing))
+	require.NoError(t, opsgenie.Post(testEvent()))
+
+	require.Len(t, alerts, 1)
+	require.Equal(t, incidentKey(event), alerts[0].Alias)
+	require.Equal(t, "gitrepository/webapp: reason", alerts[0].Message)
+	require.Equal(t, []opsgenieResponder{{Name: "payments", Type: "team"}}, alerts[0].Responders)
+	require.Equal(t, "GitRepository/gitops-system/webapp", alerts[0].Entity)
+	require.Equal(t, "payments", alerts[0].Details["team"])
+	require.Len(t, closed, 1)
+	require.Contains(t, closed[0], "/jsm/ops/integration/v2/alerts/")
+}
+
+func TestNewOpsgenie(t *testing.T) {
+	_, err := NewOpsgenie("api.eu.opsgenie.com", "", "", "s3cr3t", nil)
+	require.Error(t, err)
+	_, err = NewOpsgenie("https://api.eu.opsgenie.com", "", "", "", nil)
+	require.Error(t, err)
+	opsgenie, err := NewOpsgenie("https://api.eu.opsgenie.com/v2/alerts", "", "sre", "s3cr3t", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://api.eu.opsgenie.com/v2/alerts", opsgenie.URL)
+}
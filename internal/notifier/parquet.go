@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// encodeParquet returns the events as a gzip compressed Parquet file,
+// with a column per event column.
+func encodeParquet(batch []events.Event) ([]byte, error) {
+	var file bytes.Buffer
+	w, err := writer.NewCSVWriterFromWriter(parquetSchema(), &file, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet writer: %w", err)
+	}
+	w.CompressionType = parquet.CompressionCodec_GZIP
+
+	for _, e := range batch {
+		row := make([]interface{}, len(eventColumns))
+		for i, column := range eventColumns {
+			if column.timestamp != nil {
+				row[i] = column.timestamp(e)
+				continue
+			}
+			row[i] = column.text(e)
+		}
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write Parquet row: %w", err)
+		}
+	}
+	if err := w.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	return file.Bytes(), nil
+}
+
+// parquetSchema returns the schema of the event columns, the timestamps
+// are stored as milliseconds and the other columns as UTF-8 strings.
+func parquetSchema() []string {
+	schema := make([]string, 0, len(eventColumns))
+	for _, column := range eventColumns {
+		if column.timestamp != nil {
+			schema = append(schema, fmt.Sprintf("name=%s, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=REQUIRED", column.name))
+			continue
+		}
+		schema = append(schema, fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED", column.name))
+	}
+	return schema
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// archivedEvent is a row of the Parquet archives.
+type archivedEvent struct {
+	Timestamp  int64  `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Severity   string `parquet:"name=severity, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Kind       string `parquet:"name=kind, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Namespace  string `parquet:"name=namespace, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Name       string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Reason     string `parquet:"name=reason, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Message    string `parquet:"name=message, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Revision   string `parquet:"name=revision, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Controller string `parquet:"name=controller, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Metadata   string `parquet:"name=metadata, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func TestEncodeParquet(t *testing.T) {
+	timestamp := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	batch := []events.Event{
+		{
+			InvolvedObject:      corev1.ObjectReference{Kind: "Kustomization", Namespace: "flux-system", Name: "apps"},
+			Severity:            events.EventSeverityInfo,
+			Timestamp:           metav1.NewTime(timestamp),
+			Message:             "Applied revision",
+			Reason:              "ReconciliationSucceeded",
+			Metadata:            map[string]string{"revision": "main/6ec1"},
+			ReportingController: "kustomize-controller",
+		},
+		{
+			InvolvedObject: corev1.ObjectReference{Kind: "HelmRelease", Namespace: "apps", Name: "webapp"},
+			Severity:       events.EventSeverityError,
+			Timestamp:      metav1.NewTime(timestamp.Add(time.Second)),
+			Message:        "install retries exhausted",
+		},
+	}
+
+	file, err := encodeParquet(batch)
+	require.NoError(t, err)
+
+	source, err := buffer.NewBufferFile(file)
+	require.NoError(t, err)
+	pr, err := reader.NewParquetReader(source, new(archivedEvent), 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	require.EqualValues(t, 2, pr.GetNumRows())
+	require.Len(t, pr.Footer.RowGroups, 1)
+	for _, chunk := range pr.Footer.RowGroups[0].Columns {
+		require.Equal(t, parquet.CompressionCodec_GZIP, chunk.MetaData.Codec)
+	}
+
+	rows := make([]archivedEvent, 2)
+	require.NoError(t, pr.Read(&rows))
+	require.Equal(t, []archivedEvent{
+		{
+			Timestamp:  timestamp.UnixNano() / 1e6,
+			Severity:   "info",
+			Kind:       "Kustomization",
+			Namespace:  "flux-system",
+			Name:       "apps",
+			Reason:     "ReconciliationSucceeded",
+			Message:    "Applied revision",
+			Revision:   "main/6ec1",
+			Controller: "kustomize-controller",
+			Metadata:   `{"revision":"main/6ec1"}`,
+		},
+		{
+			Timestamp: timestamp.Add(time.Second).UnixNano() / 1e6,
+			Severity:  "error",
+			Kind:      "HelmRelease",
+			Namespace: "apps",
+			Name:      "webapp",
+			Message:   "install retries exhausted",
+			Metadata:  "{}",
+		},
+	}, rows)
+}
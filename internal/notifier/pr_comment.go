@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+// rollupMarker starts the body of the rolled-up comments, followed
+// by the revision the comment reports, e.g. '<!-- flux-rollup: sha -->'.
+const rollupMarker = "<!-- flux-rollup: "
+
+// isRollupComment returns true if the comment is a rolled-up comment.
+func isRollupComment(body string) bool {
+	return strings.HasPrefix(body, rollupMarker)
+}
+
+// rollupComment returns the rolled-up comment of the revision with the outcome
+// of the event, the row of the involved object is replaced in place and the
+// rows of another revision are dropped.
+func rollupComment(body, rev string, event events.Event) (string, error) {
+	row, err := formatRollupRow(event)
+	if err != nil {
+		return "", err
+	}
+	key := rollupRowKey(event)
+
+	var rows []string
+	header := fmt.Sprintf("%s%s -->", rollupMarker, rev)
+	if strings.HasPrefix(body, header+"\n") {
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(line, "- <!-- ") {
+				rows = append(rows, line)
+			}
+		}
+	}
+
+	replaced := false
+	for i, r := range rows {
+		if strings.HasPrefix(r, key) {
+			rows[i] = row
+			replaced = true
+		}
+	}
+	if !replaced {
+		rows = append(rows, row)
+	}
+
+	short := rev
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return fmt.Sprintf("%s\n**Flux deployment of `%s`**\n\n%s\n", header, short, strings.Join(rows, "\n")), nil
+}
+
+// rollupRowKey returns the start of the row of the involved object.
+func rollupRowKey(event events.Event) string {
+	o := event.InvolvedObject
+	return strings.ToLower(fmt.Sprintf("- <!-- %s/%s/%s -->", o.Kind, o.Namespace, o.Name))
+}
+
+// formatRollupRow returns the outcome of the event, e.g.
+// ':x: **kustomization/apps** health check failed: timeout waiting for...'.
+func formatRollupRow(event events.Event) (string, error) {
+	var icon string
+	switch {
+	case event.Reason == "Progressing":
+		icon = ":hourglass_flowing_sand:"
+	case event.Severity == events.EventSeverityInfo:
+		icon = ":white_check_mark:"
+	case event.Severity == events.EventSeverityError:
+		icon = ":x:"
+	default:
+		return "", errors.New("can't convert to rollup state")
+	}
+	name, desc := formatNameAndDescription(event)
+	message := strings.Join(strings.Fields(event.Message), " ")
+	return fmt.Sprintf("%s %s **%s** %s: %s", rollupRowKey(event), icon, name, desc, message), nil
+}
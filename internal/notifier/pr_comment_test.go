@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/require"
+)
+
+const rollupRevision = "5394cb7f48332b2de7c17dd8b8384bbc84b7e738"
+
+func TestRollupComment(t *testing.T) {
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	progressing.Message = "applying\nrevision"
+
+	body, err := rollupComment("", rollupRevision, progressing)
+	require.NoError(t, err)
+	require.True(t, isRollupComment(body))
+	require.Equal(t, "<!-- flux-rollup: "+rollupRevision+" -->\n**Flux deployment of `5394cb7`**\n\n"+
+		"- <!-- gitrepository/gitops-system/webapp --> :hourglass_flowing_sand: **gitrepository/webapp** progressing: applying revision\n", body)
+
+	other := testEvent()
+	other.InvolvedObject.Name = "infra"
+	other.Severity = events.EventSeverityError
+	body, err = rollupComment(body, rollupRevision, other)
+	require.NoError(t, err)
+
+	// the row of the object is replaced in place
+	body, err = rollupComment(body, rollupRevision, testEvent())
+	require.NoError(t, err)
+	rows := strings.Split(strings.TrimSpace(body), "\n")[3:]
+	require.Equal(t, []string{
+		"- <!-- gitrepository/gitops-system/webapp --> :white_check_mark: **gitrepository/webapp** reason: message",
+		"- <!-- gitrepository/gitops-system/infra --> :x: **gitrepository/infra** reason: message",
+	}, rows)
+
+	// the rows of another revision are dropped
+	body, err = rollupComment(body, "a0e2f2f7b2a5b8e4c3f1d0e9a8b7c6d5e4f3a2b1", other)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(body), "\n"), 4)
+
+	invalid := testEvent()
+	invalid.Severity = "unknown"
+	_, err = rollupComment("", rollupRevision, invalid)
+	require.Error(t, err)
+}
+
+func TestGitHubComment_Post(t *testing.T) {
+	var created, edited []string
+	comments := `[{"id":7,"body":"LGTM"}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/org/repo/commits/"+rollupRevision+"/pulls":
+			w.Write([]byte(`[{"number":12}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/org/repo/issues/12/comments":
+			w.Write([]byte(comments))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/org/repo/issues/12/comments":
+			var c github.IssueComment
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&c))
+			created = append(created, c.GetBody())
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v3/repos/org/repo/issues/comments/8":
+			var c github.IssueComment
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&c))
+			edited = append(edited, c.GetBody())
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	gh, err := NewGitHubComment(ts.URL+"/org/repo", "token", nil, nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Metadata["revision"] = "main/" + rollupRevision
+	require.NoError(t, gh.Post(event))
+	require.Len(t, created, 1)
+	require.Empty(t, edited)
+
+	// the existing comment is updated, unless it's unchanged
+	b, err := json.Marshal([]github.IssueComment{{ID: github.Int64(8), Body: &created[0]}})
+	require.NoError(t, err)
+	comments = string(b)
+	require.NoError(t, gh.Post(event))
+	require.Empty(t, edited)
+
+	event.Severity = events.EventSeverityError
+	require.NoError(t, gh.Post(event))
+	require.Len(t, created, 1)
+	require.Len(t, edited, 1)
+	require.Contains(t, edited[0], ":x: **gitrepository/webapp**")
+
+	delete(event.Metadata, "revision")
+	require.Error(t, gh.Post(event))
+}
+
+func TestNewGitLabComment(t *testing.T) {
+	g, err := NewGitLabComment("https://gitlab.com/foo/bar", "foobar", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "foo/bar", g.Id)
+
+	_, err = NewGitLabComment("https://gitlab.com/foo/bar", "", nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// redisDefaultStream is the stream the events are appended to when the
+	// provider has no channel.
+	redisDefaultStream = "flux-events"
+
+	// redisDefaultMaxLen is the approximate number of entries the stream is
+	// trimmed to, it can be changed with the 'maxlen' address query parameter.
+	redisDefaultMaxLen = 10000
+
+	redisTimeout = 15 * time.Second
+)
+
+// RedisStream is an implementation of the notification Interface that
+// appends the events to a Redis or Valkey stream with XADD.
+type RedisStream struct {
+	// Address is the host and port of the server.
+	Address  string
+	Stream   string
+	Username string
+	Password string
+	DB       int
+
+	// MaxLen is the approximate length the stream is trimmed to,
+	// the stream is not trimmed when zero.
+	MaxLen int
+
+	// TLSConfig is set for the 'rediss' scheme.
+	TLSConfig *tls.Config
+
+	// Transport holds the egress policy of the connections.
+	Transport *Transport
+}
+
+// NewRedisStream parses an address formatted as
+// 'redis[s]://[[username]:password@]host[:port][/db][?maxlen=n]',
+// the username and password override the ones of the address when set.
+func NewRedisStream(address, stream, username, password string, certPool *x509.CertPool, transport *Transport) (*RedisStream, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis address %s: %w", address, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis address %s: no host", address)
+	}
+
+	r := &RedisStream{
+		Address:   u.Host,
+		Stream:    stream,
+		MaxLen:    redisDefaultMaxLen,
+		Transport: transport,
+	}
+	if u.Port() == "" {
+		r.Address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if r.Stream == "" {
+		r.Stream = redisDefaultStream
+	}
+
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.TLSConfig = transport.tlsConfig(certPool)
+		if r.TLSConfig == nil {
+			r.TLSConfig = &tls.Config{}
+		}
+		r.TLSConfig.ServerName = u.Hostname()
+	default:
+		return nil, fmt.Errorf("invalid Redis address %s: unsupported scheme '%s'", address, u.Scheme)
+	}
+
+	if u.User != nil {
+		r.Username = u.User.Username()
+		r.Password, _ = u.User.Password()
+	}
+	if username != "" {
+		r.Username = username
+	}
+	if password != "" {
+		r.Password = password
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.DB, err = strconv.Atoi(db); err != nil || r.DB < 0 {
+			return nil, fmt.Errorf("invalid Redis address %s: invalid database '%s'", address, db)
+		}
+	}
+	if maxLen := u.Query().Get("maxlen"); maxLen != "" {
+		if r.MaxLen, err = strconv.Atoi(maxLen); err != nil || r.MaxLen < 0 {
+			return nil, fmt.Errorf("invalid Redis address %s: invalid maxlen '%s'", address, maxLen)
+		}
+	}
+
+	return r, nil
+}
+
+// Post appends the event to the stream, the entry has the full event
+// encoded as JSON in the 'event' field and the main attributes of the
+// event in separate fields so that the consumers can filter on them.
+func (r *RedisStream) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	values := []string{
+		"kind", event.InvolvedObject.Kind,
+		"namespace", event.InvolvedObject.Namespace,
+		"name", event.InvolvedObject.Name,
+		"severity", event.Severity,
+		"reason", event.Reason,
+		"message", event.Message,
+		"timestamp", event.Timestamp.Format(time.RFC3339),
+	}
+	keys := make([]string, 0, len(event.Metadata))
+	for key := range event.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values = append(values, "metadata."+key, event.Metadata[key])
+	}
+	values = append(values, "event", string(data))
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     r.Address,
+		Username: r.Username,
+		Password: r.Password,
+		DB:       r.DB,
+		Dialer:   r.dial,
+		// an XADD retried after a timeout could append the event twice
+		MaxRetries: -1,
+		PoolSize:   1,
+	})
+	defer client.Close()
+
+	// the fields are kept in order, the ID is generated by the server
+	err = client.XAdd(ctx, &redis.XAddArgs{
+		Stream:       r.Stream,
+		MaxLenApprox: int64(r.MaxLen),
+		Values:       values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to append to Redis stream %s: %w", r.Stream, err)
+	}
+	return nil
+}
+
+// dial connects to the IPs permitted by the egress policy, over TLS
+// for the 'rediss' scheme.
+func (r *RedisStream) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := r.Transport.dialContext(&net.Dialer{Timeout: redisTimeout})(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if r.TLSConfig == nil {
+		return conn, nil
+	}
+
+	// the client sets the deadlines of the commands, only the handshake is bounded here
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConn := tls.Client(conn, r.TLSConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
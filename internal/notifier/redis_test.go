@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/internal/egress"
+)
+
+func TestRedisStream_Post(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+	server.RequireUserAuth("flux", "secret")
+
+	r, err := NewRedisStream(fmt.Sprintf("redis://default:secret@%s/2?maxlen=2", server.Addr()), "", "flux", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "flux-events", r.Stream)
+
+	event := testEvent()
+	require.NoError(t, r.Post(event))
+
+	entries, err := server.DB(2).Stream("flux-events")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	values := entries[0].Values
+	require.Equal(t, []string{"kind", event.InvolvedObject.Kind}, values[:2])
+	fields := map[string]string{}
+	for i := 0; i+1 < len(values); i += 2 {
+		fields[values[i]] = values[i+1]
+	}
+	require.Equal(t, event.InvolvedObject.Name, fields["name"])
+	require.Equal(t, event.Severity, fields["severity"])
+	require.Equal(t, event.Message, fields["message"])
+	require.Equal(t, "metadata", fields["metadata.test"])
+
+	var payload events.Event
+	require.NoError(t, json.Unmarshal([]byte(fields["event"]), &payload))
+	require.Equal(t, event.InvolvedObject, payload.InvolvedObject)
+
+	// the stream is trimmed to the maxlen
+	require.NoError(t, r.Post(event))
+	require.NoError(t, r.Post(event))
+	entries, err = server.DB(2).Stream("flux-events")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
+func TestRedisStream_PostError(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+	server.RequireAuth("secret")
+
+	r, err := NewRedisStream("redis://"+server.Addr(), "events", "", "wrong", nil, nil)
+	require.NoError(t, err)
+	err = r.Post(testEvent())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "WRONGPASS")
+	require.False(t, server.Exists("events"))
+}
+
+func TestRedisStream_PostEgressDenied(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	policy, err := egress.ParsePolicy(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	r, err := NewRedisStream("redis://"+server.Addr(), "events", "", "", nil, &Transport{EgressPolicy: policy})
+	require.NoError(t, err)
+	err = r.Post(testEvent())
+	require.True(t, errors.Is(err, egress.ErrDenied), "expected denied, got %v", err)
+	require.False(t, server.Exists("events"))
+}
+
+func TestNewRedisStream(t *testing.T) {
+	r, err := NewRedisStream("rediss://valkey.example.com", "events", "", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "valkey.example.com:6379", r.Address)
+	require.Equal(t, redisDefaultMaxLen, r.MaxLen)
+	require.NotNil(t, r.TLSConfig)
+	require.Equal(t, "valkey.example.com", r.TLSConfig.ServerName)
+
+	for _, address := range []string{
+		"http://redis.example.com",
+		"redis://",
+		"redis://redis.example.com/db",
+		"redis://redis.example.com?maxlen=-1",
+	} {
+		_, err := NewRedisStream(address, "", "", "", nil, nil)
+		require.Error(t, err, address)
+	}
+}
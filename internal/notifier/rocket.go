@@ -33,6 +33,9 @@ type Rocket struct {
 	Username string
 	Channel  string
 	CertPool *x509.CertPool
+
+	requestConfig
+	channelRouting
 }
 
 // NewRocket validates the Rocket URL and returns a Rocket object
@@ -66,8 +69,13 @@ func (s *Rocket) Post(event events.Event) error {
 		return nil
 	}
 
+	channel, err := s.channelFor(event, s.Channel)
+	if err != nil {
+		return err
+	}
+
 	payload := SlackPayload{
-		Channel:  s.Channel,
+		Channel:  channel,
 		Username: s.Username,
 	}
 
@@ -91,7 +99,7 @@ func (s *Rocket) Post(event events.Event) error {
 
 	payload.Attachments = []SlackAttachment{a}
 
-	err := postMessage(s.URL, s.ProxyURL, s.CertPool, payload)
+	err = s.transport.postMessage(s.URL, s.ProxyURL, s.CertPool, payload, s.withCapture(), s.withDelivery(event))
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
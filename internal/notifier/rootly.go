@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// rootlyIncidentLabel is the label holding the incident key of the
+// Rootly incidents, so that the following events annotate them.
+const rootlyIncidentLabel = "flux-incident"
+
+// Rootly is a Rootly notifier, it opens an incident on the first error event
+// of an object and adds the following error events to its timeline.
+type Rootly struct {
+	// URL is the Rootly API address, e.g. 'https://api.rootly.com'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+	Token    string
+
+	// Severity is the slug of the severity of the opened incidents,
+	// e.g. 'sev1', the Rootly default is used when empty.
+	Severity string
+
+	requestConfig
+}
+
+type rootlyResource struct {
+	ID         string      `json:"id,omitempty"`
+	Type       string      `json:"type"`
+	Attributes interface{} `json:"attributes"`
+}
+
+type rootlyIncident struct {
+	Title      string            `json:"title"`
+	Summary    string            `json:"summary"`
+	SeverityID string            `json:"severity_id,omitempty"`
+	Labels     map[string]string `json:"labels"`
+}
+
+type rootlyIncidentEvent struct {
+	Event      string `json:"event"`
+	Visibility string `json:"visibility"`
+}
+
+// NewRootly returns a notifier for the Rootly API address, the channel is
+// the severity of the incidents and the token an API key.
+func NewRootly(addr, proxyURL, severity, token string, certPool *x509.CertPool) (*Rootly, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Rootly API URL %s: %w", addr, err)
+	}
+	if token == "" {
+		return nil, errors.New("rootly API key cannot be empty")
+	}
+
+	return &Rootly{
+		URL:      strings.TrimSuffix(addr, "/"),
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Token:    token,
+		Severity: severity,
+	}, nil
+}
+
+// Post opens an incident for the involved object, or adds the event to the
+// timeline of its started incident, the events that aren't errors are skipped.
+func (r *Rootly) Post(event events.Event) error {
+	if event.Severity != events.EventSeverityError {
+		return nil
+	}
+
+	key := incidentKey(event)
+	id, err := r.startedIncident(key, event)
+	if err != nil {
+		return fmt.Errorf("could not list the Rootly incidents: %w", err)
+	}
+	if id == "" {
+		id, err = r.openIncident(key, event)
+		if err != nil {
+			return fmt.Errorf("could not open the Rootly incident: %w", err)
+		}
+	}
+
+	body, err := json.Marshal(map[string]rootlyResource{
+		"data": {
+			Type: "incident_events",
+			Attributes: rootlyIncidentEvent{
+				Event:      incidentTimelineEntry(event),
+				Visibility: "internal",
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+	address := fmt.Sprintf("%s/v1/incidents/%s/events", r.URL, url.PathEscape(id))
+	if err := r.send(http.MethodPost, address, body, nil, event); err != nil {
+		return fmt.Errorf("could not annotate the Rootly incident %s: %w", id, err)
+	}
+	return nil
+}
+
+// startedIncident returns the ID of the started incident with the key, if any.
+func (r *Rootly) startedIncident(key string, event events.Event) (string, error) {
+	query := url.Values{}
+	query.Set("filter[status]", "started")
+	query.Set("filter[labels]", rootlyIncidentLabel+":"+key)
+	var resp struct {
+		Data []rootlyResource `json:"data"`
+	}
+	if err := r.send(http.MethodGet, r.URL+"/v1/incidents?"+query.Encode(), nil, &resp, event); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", nil
+	}
+	return resp.Data[0].ID, nil
+}
+
+// openIncident opens an incident labeled with the key and returns its ID.
+func (r *Rootly) openIncident(key string, event events.Event) (string, error) {
+	body, err := json.Marshal(map[string]rootlyResource{
+		"data": {
+			Type: "incidents",
+			Attributes: rootlyIncident{
+				Title:      incidentTitle(event),
+				Summary:    incidentMessage(event),
+				SeverityID: r.Severity,
+				Labels:     map[string]string{rootlyIncidentLabel: key},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+	var resp struct {
+		Data rootlyResource `json:"data"`
+	}
+	if err := r.send(http.MethodPost, r.URL+"/v1/incidents", body, &resp, event); err != nil {
+		return "", err
+	}
+	if resp.Data.ID == "" {
+		return "", errors.New("the response has no incident ID")
+	}
+	return resp.Data.ID, nil
+}
+
+func (r *Rootly) send(method, address string, body []byte, out interface{}, event events.Event) error {
+	return r.transport.sendRequest(method, address, r.ProxyURL, r.CertPool, body, out, func(req *retryablehttp.Request) {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/vnd.api+json")
+		}
+	}, r.withCapture(), r.withDelivery(event))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootly_Post(t *testing.T) {
+	var incidents []rootlyIncident
+	var timeline []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/incidents":
+			require.Equal(t, "started", r.URL.Query().Get("filter[status]"))
+			if len(incidents) == 0 {
+				w.Write([]byte(`{"data":[]}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"id":"inc-1","type":"incidents"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/incidents":
+			var payload struct {
+				Data struct {
+					Attributes rootlyIncident `json:"attributes"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			incidents = append(incidents, payload.Data.Attributes)
+			w.Write([]byte(`{"data":{"id":"inc-1","type":"incidents"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/incidents/inc-1/events":
+			var payload struct {
+				Data struct {
+					Attributes rootlyIncidentEvent `json:"attributes"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			timeline = append(timeline, payload.Data.Attributes.Event)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	rootly, err := NewRootly(ts.URL, "", "sev1", "token", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	event.Metadata["revision"] = "main/8a3f"
+	require.NoError(t, rootly.Post(event))
+	require.NoError(t, rootly.Post(event))
+	require.NoError(t, rootly.Post(testEvent()))
+
+	require.Len(t, incidents, 1)
+	require.Equal(t, "gitrepository/webapp: reason", incidents[0].Title)
+	require.Equal(t, "sev1", incidents[0].SeverityID)
+	require.Equal(t, incidentKey(event), incidents[0].Labels[rootlyIncidentLabel])
+	require.Equal(t, []string{
+		"GitRepository/gitops-system/webapp at main/8a3f: message",
+		"GitRepository/gitops-system/webapp at main/8a3f: message",
+	}, timeline)
+}
+
+func TestNewRootly(t *testing.T) {
+	_, err := NewRootly("api.rootly.com", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewRootly("https://api.rootly.com", "", "", "", nil)
+	require.Error(t, err)
+}
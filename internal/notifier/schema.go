@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// SchemaVersion is the version of the payload schemas, it's incremented
+	// when a payload changes in a way that breaks its consumers, e.g. when
+	// a field is removed or its type is changed.
+	SchemaVersion = "v1"
+
+	// jsonSchemaDialect is the JSON Schema version of the payload schemas.
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+	// GenericCloudEventsSchema is the schema name of the payloads of the
+	// generic provider with the CloudEvents content type.
+	GenericCloudEventsSchema = v1beta1.GenericProvider + "-cloudevents"
+)
+
+// timeType is the type of the timestamps, marshalled as RFC 3339 strings.
+var timeType = reflect.TypeOf(metav1.Time{})
+
+// EventSchema returns the JSON Schema of the events, as received by the
+// event API and as sent by the generic provider.
+func EventSchema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(events.Event{}))
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = "Flux event " + SchemaVersion
+	return schema
+}
+
+// PayloadSchema returns the JSON Schema of the payloads sent by the provider type,
+// or of 'generic-cloudevents', false if the payloads are defined by the service
+// of the provider, e.g. the Slack messages.
+func PayloadSchema(provider string) (map[string]interface{}, bool) {
+	var schema map[string]interface{}
+	switch provider {
+	case v1beta1.GenericProvider, v1beta1.GenericHMACProvider, v1beta1.ArchiveProvider:
+		// the archives have an event per line in the ndjson format
+		schema = EventSchema()
+	case GenericCloudEventsSchema:
+		schema = cloudEventSchema()
+	case v1beta1.BigQueryProvider, v1beta1.ClickHouseProvider:
+		schema = rowSchema(false)
+	case v1beta1.PostgresProvider, v1beta1.MySQLProvider:
+		schema = rowSchema(true)
+	case v1beta1.RedisProvider:
+		schema = redisEntrySchema()
+	default:
+		return nil, false
+	}
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = "Flux " + provider + " payload " + SchemaVersion
+	return schema, true
+}
+
+// PayloadSchemaNames returns the names of the payload schemas, sorted.
+func PayloadSchemaNames() []string {
+	names := []string{
+		v1beta1.GenericProvider, v1beta1.GenericHMACProvider, GenericCloudEventsSchema, v1beta1.ArchiveProvider,
+		v1beta1.BigQueryProvider, v1beta1.ClickHouseProvider, v1beta1.PostgresProvider,
+		v1beta1.MySQLProvider, v1beta1.RedisProvider,
+	}
+	sort.Strings(names)
+	return names
+}
+
+// typeSchema returns the schema of the JSON encoding of the type, the fields
+// without the omitempty option are required.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			name := strings.Split(tag, ",")[0]
+			if name == "-" || field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type)
+			if !strings.Contains(tag, ",omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	}
+	return map[string]interface{}{}
+}
+
+// cloudEventSchema returns the schema of the structured CloudEvents
+// sent by the generic provider, the data is the event.
+func cloudEventSchema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(cloudEvent{}))
+	data := EventSchema()
+	delete(data, "$schema")
+	delete(data, "title")
+	schema["properties"].(map[string]interface{})["data"] = data
+	return schema
+}
+
+// rowSchema returns the schema of the rows of the warehouses and the
+// SQL tables, the columns of the events and the row ID for SQL.
+func rowSchema(withID bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	if withID {
+		properties["id"] = map[string]interface{}{"type": "string"}
+		required = append(required, "id")
+	}
+	for _, column := range eventColumns {
+		property := map[string]interface{}{"type": "string"}
+		switch {
+		case column.timestamp != nil:
+			property["pattern"] = `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}$`
+			property["description"] = "UTC timestamp"
+		case column.name == "metadata":
+			property["contentMediaType"] = "application/json"
+		}
+		properties[column.name] = property
+		required = append(required, column.name)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
+
+// redisEntrySchema returns the schema of the fields of the Redis stream
+// entries, the metadata of the events is flattened in 'metadata.<key>' fields.
+func redisEntrySchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	fields := []string{"kind", "namespace", "name", "severity", "reason", "message", "timestamp", "event"}
+	for _, field := range fields {
+		properties[field] = map[string]interface{}{"type": "string"}
+	}
+	properties["timestamp"] = map[string]interface{}{"type": "string", "format": "date-time"}
+	properties["event"] = map[string]interface{}{"type": "string", "contentMediaType": "application/json"}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"patternProperties":    map[string]interface{}{`^metadata\.`: map[string]interface{}{"type": "string"}},
+		"additionalProperties": false,
+		"required":             fields,
+	}
+}
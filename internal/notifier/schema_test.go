@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mapKeys(m map[string]interface{}) []string {
+	k := make([]string, 0, len(m))
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
+
+func TestEventSchema(t *testing.T) {
+	data, err := json.Marshal(testEvent())
+	require.NoError(t, err)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &event))
+
+	schema := EventSchema()
+	properties := schema["properties"].(map[string]interface{})
+	require.ElementsMatch(t, mapKeys(event), mapKeys(properties))
+	require.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, properties["timestamp"])
+	require.Contains(t, schema["required"], "severity")
+	require.NotContains(t, schema["required"], "metadata")
+
+	object := properties["involvedObject"].(map[string]interface{})
+	require.Contains(t, object["properties"], "kind")
+}
+
+func TestPayloadSchema(t *testing.T) {
+	for _, name := range PayloadSchemaNames() {
+		schema, ok := PayloadSchema(name)
+		require.True(t, ok, name)
+		require.Equal(t, "object", schema["type"], name)
+	}
+
+	_, ok := PayloadSchema("slack")
+	require.False(t, ok)
+
+	schema, _ := PayloadSchema("bigquery")
+	row := eventRow(testEvent())
+	require.ElementsMatch(t, mapKeys(row), mapKeys(schema["properties"].(map[string]interface{})))
+
+	schema, _ = PayloadSchema(GenericCloudEventsSchema)
+	data := schema["properties"].(map[string]interface{})["data"].(map[string]interface{})
+	require.Contains(t, data["properties"], "involvedObject")
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package notifier
 
 import (
-	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net/http"
@@ -32,13 +31,11 @@ type Sentry struct {
 }
 
 // NewSentry creates a Sentry client from the provided Data Source Name (DSN)
-func NewSentry(certPool *x509.CertPool, dsn string) (*Sentry, error) {
+func NewSentry(certPool *x509.CertPool, dsn string, transport *Transport) (*Sentry, error) {
 	var tr *http.Transport
-	if certPool != nil {
+	if config := transport.tlsConfig(certPool); config != nil {
 		tr = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: certPool,
-			},
+			TLSClientConfig: config,
 		}
 	}
 	client, err := sentry.NewClient(sentry.ClientOptions{
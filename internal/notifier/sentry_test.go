@@ -30,7 +30,7 @@ import (
 )
 
 func TestNewSentry(t *testing.T) {
-	s, err := NewSentry(nil, "https://test@localhost/1")
+	s, err := NewSentry(nil, "https://test@localhost/1", nil)
 	require.NoError(t, err)
 	assert.Equal(t, s.Client.Options().Dsn, "https://test@localhost/1")
 }
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// SignatureHeader carries the detached signature of the payload,
+// formatted as '<algorithm>=<base64 signature>'.
+const SignatureHeader = "X-Signature"
+
+// Signer returns the detached signature of a payload.
+type Signer interface {
+	Sign(payload []byte) (string, error)
+}
+
+// NewSigner returns a signer from an ASCII armored OpenPGP private key,
+// or from a PEM encoded PKCS #8 Ed25519 private key.
+// The passphrase decrypts the OpenPGP key, it is ignored otherwise.
+func NewSigner(key []byte, passphrase []byte) (Signer, error) {
+	if block, _ := pem.Decode(key); block != nil && block.Type == "PRIVATE KEY" {
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		privateKey, ok := k.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T, only Ed25519 keys are supported", k)
+		}
+		return ed25519Signer{key: privateKey}, nil
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenPGP key: %w", err)
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, errors.New("the OpenPGP key has no private key")
+	}
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("failed to decrypt OpenPGP key: %w", err)
+		}
+	}
+	return pgpSigner{entity: entity}, nil
+}
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func (s ed25519Signer) Sign(payload []byte) (string, error) {
+	return "ed25519=" + base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)), nil
+}
+
+type pgpSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s pgpSigner) Sign(payload []byte) (string, error) {
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, s.entity, bytes.NewReader(payload), nil); err != nil {
+		return "", err
+	}
+	return "pgp=" + base64.StdEncoding.EncodeToString(sig.Bytes()), nil
+}
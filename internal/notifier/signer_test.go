@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func ed25519Key(t *testing.T) (ed25519.PublicKey, []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	return pub, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func pgpKey(t *testing.T) (openpgp.EntityList, []byte) {
+	entity, err := openpgp.NewEntity("flux", "", "flux@example.com", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	return openpgp.EntityList{entity}, buf.Bytes()
+}
+
+func TestNewSigner_Ed25519(t *testing.T) {
+	pub, key := ed25519Key(t)
+	signer, err := NewSigner(key, nil)
+	require.NoError(t, err)
+
+	payload := []byte(`{"message":"hello"}`)
+	sig, err := signer.Sign(payload)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(sig, "ed25519="))
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "ed25519="))
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(pub, payload, raw))
+}
+
+func TestNewSigner_PGP(t *testing.T) {
+	keyring, key := pgpKey(t)
+	signer, err := NewSigner(key, nil)
+	require.NoError(t, err)
+
+	payload := []byte(`{"message":"hello"}`)
+	sig, err := signer.Sign(payload)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(sig, "pgp="))
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "pgp="))
+	require.NoError(t, err)
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(payload), bytes.NewReader(raw))
+	require.NoError(t, err)
+}
+
+func TestNewSigner_Invalid(t *testing.T) {
+	_, err := NewSigner([]byte("not a key"), nil)
+	require.Error(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+	_, err = NewSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil)
+	require.Error(t, err)
+}
@@ -31,6 +31,9 @@ type Slack struct {
 	ProxyURL string
 	Username string
 	Channel  string
+
+	requestConfig
+	channelRouting
 }
 
 // SlackPayload holds the channel and attachments
@@ -84,8 +87,13 @@ func (s *Slack) Post(event events.Event) error {
 		return nil
 	}
 
+	channel, err := s.channelFor(event, s.Channel)
+	if err != nil {
+		return err
+	}
+
 	payload := SlackPayload{
-		Channel:  s.Channel,
+		Channel:  channel,
 		Username: s.Username,
 	}
 	if payload.Username == "" {
@@ -112,7 +120,7 @@ func (s *Slack) Post(event events.Event) error {
 
 	payload.Attachments = []SlackAttachment{a}
 
-	err := postMessage(s.URL, s.ProxyURL, nil, payload)
+	err = s.transport.postMessage(s.URL, s.ProxyURL, nil, payload, s.withCapture(), s.withDelivery(event))
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// SlackBoard maintains a single Slack message with the Web API,
+// the message is posted once and then updated in place.
+type SlackBoard struct {
+	requestConfig
+
+	// APIURL is the Slack Web API address, e.g. 'https://slack.com/api'.
+	APIURL   string
+	ProxyURL string
+	CertPool *x509.CertPool
+	Channel  string
+	Token    string
+}
+
+type slackResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// NewSlackBoard returns a board for the Slack Web API address,
+// the token is a bot token with the 'chat:write' scope.
+func NewSlackBoard(apiURL, proxyURL, channel, token string, certPool *x509.CertPool) (*SlackBoard, error) {
+	if _, err := url.ParseRequestURI(apiURL); err != nil {
+		return nil, fmt.Errorf("invalid Slack API URL %s", apiURL)
+	}
+	if channel == "" {
+		return nil, errors.New("empty Slack channel")
+	}
+	if token == "" {
+		return nil, errors.New("slack bot token cannot be empty")
+	}
+
+	return &SlackBoard{
+		APIURL:   strings.TrimSuffix(apiURL, "/"),
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Channel:  channel,
+		Token:    token,
+	}, nil
+}
+
+// Publish updates the message with the given timestamp, or posts a new message
+// when the timestamp is empty or the message was deleted, and returns the
+// timestamp of the message.
+func (s *SlackBoard) Publish(ts, text string) (string, error) {
+	if ts != "" {
+		resp, err := s.call("chat.update", map[string]string{"channel": s.Channel, "ts": ts, "text": text})
+		if err != nil {
+			return "", err
+		}
+		if resp.OK {
+			return ts, nil
+		}
+		if resp.Error != "message_not_found" {
+			return "", fmt.Errorf("failed to update Slack message: %s", resp.Error)
+		}
+	}
+
+	resp, err := s.call("chat.postMessage", map[string]string{"channel": s.Channel, "text": text})
+	if err != nil {
+		return "", err
+	}
+	if !resp.OK {
+		return "", fmt.Errorf("failed to post Slack message: %s", resp.Error)
+	}
+	return resp.TS, nil
+}
+
+func (s *SlackBoard) call(method string, payload interface{}) (*slackResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	var resp slackResponse
+	err = s.transport.sendRequest(http.MethodPost, s.APIURL+"/"+method, s.ProxyURL, s.CertPool, body, &resp, func(req *retryablehttp.Request) {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Slack %s: %w", method, err)
+	}
+	return &resp, nil
+}
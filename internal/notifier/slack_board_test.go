@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackBoard_Publish(t *testing.T) {
+	var calls []string
+	deleted := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer xoxb-token", r.Header.Get("Authorization"))
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		require.Equal(t, "flux", payload["channel"])
+		calls = append(calls, r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/chat.postMessage":
+			fmt.Fprintf(w, `{"ok":true,"channel":"C123","ts":"%d.000200"}`, len(calls))
+		case "/api/chat.update":
+			if deleted {
+				fmt.Fprint(w, `{"ok":false,"error":"message_not_found"}`)
+				return
+			}
+			require.Equal(t, "1.000200", payload["ts"])
+			fmt.Fprintf(w, `{"ok":true,"channel":"C123","ts":"%s"}`, payload["ts"])
+		}
+	}))
+	defer ts.Close()
+
+	board, err := NewSlackBoard(ts.URL+"/api", "", "flux", "xoxb-token", nil)
+	require.NoError(t, err)
+
+	msgTS, err := board.Publish("", "status")
+	require.NoError(t, err)
+	require.Equal(t, "1.000200", msgTS)
+
+	msgTS, err = board.Publish(msgTS, "status")
+	require.NoError(t, err)
+	require.Equal(t, "1.000200", msgTS)
+
+	// the deleted message is posted again
+	deleted = true
+	msgTS, err = board.Publish(msgTS, "status")
+	require.NoError(t, err)
+	require.Equal(t, "4.000200", msgTS)
+	require.Equal(t, []string{"/api/chat.postMessage", "/api/chat.update", "/api/chat.update", "/api/chat.postMessage"}, calls)
+
+	_, err = NewSlackBoard(ts.URL+"/api", "", "flux", "", nil)
+	require.Error(t, err)
+}
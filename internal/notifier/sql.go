@@ -0,0 +1,292 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+const sqlTimeout = 15 * time.Second
+
+// sqlTable matches the table names, optionally qualified with the schema
+// or the database, which are quoted and interpolated in the statements.
+var sqlTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// sqlKeyColumns are the string columns of the events table
+// that are indexed, they're limited to the Kubernetes name length.
+var sqlKeyColumns = map[string]bool{"severity": true, "kind": true, "namespace": true, "name": true}
+
+// sqlMigrated records the tables created by this replica, by database
+// address and table name, the schema is applied once per table.
+var sqlMigrated sync.Map
+
+// sqlTransportKey is the context key of the transport
+// the connections of the statements are dialed with.
+type sqlTransportKey struct{}
+
+// sqlDialect is the SQL flavour and the database/sql driver of a database.
+type sqlDialect interface {
+	// open returns the database of the table, its connections are dialed
+	// with the transport of the table and authenticated with its credentials.
+	open(s *SQLTable) (*sql.DB, error)
+
+	// schema returns the statements creating the table, they must
+	// succeed when the table exists.
+	schema(table []string) []string
+
+	// insert returns the statement inserting a row with the values of the
+	// columns as placeholders, the rows with a known ID are ignored.
+	insert(table []string, columns []string) string
+}
+
+// SQLTable is an implementation of the notification Interface that inserts
+// the events into a PostgreSQL or MySQL table, the table is created with
+// the columns of the events when it doesn't exist.
+type SQLTable struct {
+	// Address is the host and port of the database server.
+	Address  string
+	Database string
+	Username string
+	Password string
+
+	// Table is the name of the table, e.g. 'flux.events'.
+	Table string
+
+	// TLSConfig is nil when TLS is disabled in the address.
+	TLSConfig *tls.Config
+
+	// Transport holds the egress policy of the connections.
+	Transport *Transport
+
+	dialect sqlDialect
+	channelRouting
+}
+
+// NewPostgres parses an address formatted as
+// 'postgres://[username[:password]@]host[:port]/database[?sslmode=disable]',
+// the channel is the name of the table, and the username and password override
+// the ones of the address when set. TLS is required unless the sslmode is 'disable'.
+func NewPostgres(address, table, username, password string, certPool *x509.CertPool, transport *Transport) (*SQLTable, error) {
+	s, err := newSQLTable(address, table, username, password, certPool, transport, []string{"postgres", "postgresql"}, "5432")
+	if err != nil {
+		return nil, err
+	}
+
+	u, _ := url.Parse(address)
+	switch mode := u.Query().Get("sslmode"); mode {
+	case "disable":
+		s.TLSConfig = nil
+	case "", "require", "verify-ca", "verify-full":
+	default:
+		return nil, fmt.Errorf("invalid SQL address %s: unsupported sslmode '%s'", address, mode)
+	}
+
+	dialect, err := newPostgresDialect(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL address: %w", err)
+	}
+	s.dialect = dialect
+	return s, nil
+}
+
+// NewMySQL parses an address formatted as
+// 'mysql://[username[:password]@]host[:port]/database[?tls=false]',
+// the channel is the name of the table, and the username and password override
+// the ones of the address when set. TLS is required unless tls is 'false'.
+func NewMySQL(address, table, username, password string, certPool *x509.CertPool, transport *Transport) (*SQLTable, error) {
+	s, err := newSQLTable(address, table, username, password, certPool, transport, []string{"mysql"}, "3306")
+	if err != nil {
+		return nil, err
+	}
+
+	u, _ := url.Parse(address)
+	switch mode := u.Query().Get("tls"); mode {
+	case "false":
+		s.TLSConfig = nil
+	case "", "true":
+	default:
+		return nil, fmt.Errorf("invalid SQL address %s: unsupported tls '%s'", address, mode)
+	}
+	s.dialect = mysqlDialect{}
+	return s, nil
+}
+
+func newSQLTable(address, table, username, password string, certPool *x509.CertPool, transport *Transport, schemes []string, defaultPort string) (*SQLTable, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL address: %w", err)
+	}
+	if !containsString(schemes, u.Scheme) {
+		return nil, fmt.Errorf("invalid SQL address: unsupported scheme '%s'", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid SQL address: no host")
+	}
+	if table == "" {
+		return nil, fmt.Errorf("sql table cannot be empty")
+	}
+
+	s := &SQLTable{
+		Address:   u.Host,
+		Database:  strings.Trim(u.Path, "/"),
+		Table:     table,
+		Transport: transport,
+	}
+	if u.Port() == "" {
+		s.Address = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	if s.Database == "" {
+		return nil, fmt.Errorf("invalid SQL address: no database")
+	}
+
+	if u.User != nil {
+		s.Username = u.User.Username()
+		s.Password, _ = u.User.Password()
+	}
+	if username != "" {
+		s.Username = username
+	}
+	if password != "" {
+		s.Password = password
+	}
+
+	s.TLSConfig = transport.tlsConfig(certPool)
+	if s.TLSConfig == nil {
+		s.TLSConfig = &tls.Config{}
+	}
+	s.TLSConfig.ServerName = u.Hostname()
+	return s, nil
+}
+
+// Post inserts a row with the columns of the event, the ID of the row is
+// the idempotency key of the event so that the retried events are ignored.
+func (s *SQLTable) Post(event events.Event) error {
+	// Skip any update events
+	if isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	table, err := s.channelFor(event, s.Table)
+	if err != nil {
+		return err
+	}
+	if !sqlTable.MatchString(table) {
+		return fmt.Errorf("invalid SQL table '%s'", table)
+	}
+	name := strings.Split(table, ".")
+
+	// the values are bound to the placeholders of the statement
+	args := []interface{}{idempotencyKey(event)}
+	for _, column := range eventColumns {
+		if column.timestamp != nil {
+			args = append(args, time.Unix(0, column.timestamp(event)*1e6).UTC())
+			continue
+		}
+		args = append(args, column.text(event))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, sqlTransportKey{}, s.Transport)
+
+	db, err := s.dialect.open(s)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the database %s: %w", s.Address, err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	key := s.Address + "/" + s.Database + "/" + table
+	if _, ok := sqlMigrated.Load(key); !ok {
+		for _, statement := range s.dialect.schema(name) {
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to create the table %s: %w", table, err)
+			}
+		}
+		sqlMigrated.Store(key, true)
+	}
+
+	if _, err := db.ExecContext(ctx, s.dialect.insert(name, sqlColumns()), args...); err != nil {
+		return fmt.Errorf("failed to insert into the table %s: %w", table, err)
+	}
+	return nil
+}
+
+// sqlColumnDefinitions returns the columns of the events table, the ID and the
+// event columns, the columns without a type in the dialect types are strings.
+func sqlColumnDefinitions(types map[string]string, quote string) string {
+	definitions := make([]string, 0, len(eventColumns)+1)
+	for _, name := range sqlColumns() {
+		columnType, ok := types[name]
+		if !ok {
+			columnType = "text NOT NULL"
+			if sqlKeyColumns[name] {
+				columnType = "varchar(253) NOT NULL"
+			}
+		}
+		definitions = append(definitions, sqlIdentifier([]string{name}, quote)+" "+columnType)
+	}
+	return strings.Join(definitions, ", ")
+}
+
+// sqlColumns returns the names of the columns of the events table.
+func sqlColumns() []string {
+	columns := []string{"id"}
+	for _, column := range eventColumns {
+		columns = append(columns, column.name)
+	}
+	return columns
+}
+
+// quoteColumns returns the quoted column names separated by commas.
+func quoteColumns(columns []string, quote string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqlIdentifier([]string{column}, quote)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// sqlIdentifier quotes the elements of a name with the quote character.
+func sqlIdentifier(name []string, quote string) string {
+	quoted := make([]string, len(name))
+	for i, element := range name {
+		quoted[i] = quote + strings.ReplaceAll(element, quote, quote+quote) + quote
+	}
+	return strings.Join(quoted, ".")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
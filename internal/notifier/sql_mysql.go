@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlNetwork is the network of the MySQL connections, the driver only
+// dials with the functions registered by network, the connections are
+// dialed with the transport carried by the context of the statements.
+const mysqlNetwork = "notification-controller"
+
+func init() {
+	mysql.RegisterDialContext(mysqlNetwork, func(ctx context.Context, address string) (net.Conn, error) {
+		transport, ok := ctx.Value(sqlTransportKey{}).(*Transport)
+		if !ok {
+			return nil, errors.New("no transport found in the context")
+		}
+		return transport.dialContext(&net.Dialer{Timeout: sqlTimeout})(ctx, "tcp", address)
+	})
+}
+
+// mysqlDialect connects to MySQL with the go-sql-driver driver.
+type mysqlDialect struct{}
+
+// mysqlColumnTypes are the types of the columns of the events table.
+var mysqlColumnTypes = map[string]string{
+	"id":        "varchar(40) NOT NULL PRIMARY KEY",
+	"timestamp": "datetime(3) NOT NULL",
+	"metadata":  "json NOT NULL",
+}
+
+func (mysqlDialect) open(s *SQLTable) (*sql.DB, error) {
+	config := mysql.NewConfig()
+	config.Net = mysqlNetwork
+	config.Addr = s.Address
+	config.DBName = s.Database
+	config.User = s.Username
+	config.Passwd = s.Password
+	config.TLS = s.TLSConfig
+	config.Timeout = sqlTimeout
+
+	connector, err := mysql.NewConnector(config)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+func (mysqlDialect) schema(table []string) []string {
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, INDEX `object_idx` (`namespace`, `name`, `timestamp`)) DEFAULT CHARSET=utf8mb4",
+			sqlIdentifier(table, "`"), sqlColumnDefinitions(mysqlColumnTypes, "`")),
+	}
+}
+
+func (mysqlDialect) insert(table []string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)",
+		sqlIdentifier(table, "`"), quoteColumns(columns, "`"), placeholders)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+)
+
+// postgresDialect connects to PostgreSQL with the pgx driver, the
+// settings of the address, e.g. the connect_timeout or the runtime
+// parameters, are passed to the driver.
+type postgresDialect struct {
+	config *pgx.ConnConfig
+}
+
+// postgresColumnTypes are the types of the columns of the events table.
+var postgresColumnTypes = map[string]string{
+	"id":        "varchar(40) PRIMARY KEY",
+	"timestamp": "timestamp(3) NOT NULL",
+	"metadata":  "jsonb NOT NULL",
+}
+
+func newPostgresDialect(address string) (postgresDialect, error) {
+	config, err := pgx.ParseConfig(address)
+	if err != nil {
+		return postgresDialect{}, err
+	}
+	return postgresDialect{config: config}, nil
+}
+
+func (d postgresDialect) open(s *SQLTable) (*sql.DB, error) {
+	host, port, err := net.SplitHostPort(s.Address)
+	if err != nil {
+		return nil, err
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port '%s'", port)
+	}
+
+	config := d.config.Copy()
+	config.Host = host
+	config.Port = uint16(portNumber)
+	config.Database = s.Database
+	config.User = s.Username
+	config.Password = s.Password
+	config.TLSConfig = s.TLSConfig
+	config.Fallbacks = nil
+	// the host is resolved by the dial function of the transport, so
+	// that the egress policy applies to the name of the database server
+	config.LookupFunc = func(_ context.Context, host string) ([]string, error) {
+		return []string{host}, nil
+	}
+	config.DialFunc = s.Transport.dialContext(&net.Dialer{Timeout: sqlTimeout})
+	return stdlib.OpenDB(*config), nil
+}
+
+func (postgresDialect) schema(table []string) []string {
+	name := sqlIdentifier(table, `"`)
+	index := sqlIdentifier([]string{table[len(table)-1] + "_object_idx"}, `"`)
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", name, sqlColumnDefinitions(postgresColumnTypes, `"`)),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s ("namespace", "name", "timestamp")`, index, name),
+	}
+}
+
+func (postgresDialect) insert(table []string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT ("id") DO NOTHING`,
+		sqlIdentifier(table, `"`), quoteColumns(columns, `"`), strings.Join(placeholders, ", "))
+}
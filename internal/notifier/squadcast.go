@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+// squadcastPriorities are the priorities of the Squadcast incidents.
+var squadcastPriorities = map[string]bool{"P1": true, "P2": true, "P3": true, "P4": true, "P5": true}
+
+// Squadcast is a Squadcast notifier, it triggers an incident on the error
+// events and resolves it on the following info events of the same object.
+type Squadcast struct {
+	// URL is the address of an incident webhook, e.g.
+	// 'https://api.squadcast.com/v2/incidents/api/<api-key>'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Priority is the priority of the triggered incidents, e.g. 'P1',
+	// the service default is used when empty.
+	Priority string
+
+	requestConfig
+}
+
+type squadcastPayload struct {
+	EventID     string            `json:"event_id"`
+	Status      string            `json:"status"`
+	Message     string            `json:"message"`
+	Description string            `json:"description"`
+	Priority    string            `json:"priority,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// NewSquadcast returns a notifier for the incident webhook address,
+// the channel is the priority of the incidents.
+func NewSquadcast(addr, proxyURL, priority string, certPool *x509.CertPool) (*Squadcast, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid Squadcast webhook URL %s: %w", addr, err)
+	}
+	if priority != "" && !squadcastPriorities[priority] {
+		return nil, fmt.Errorf("invalid Squadcast priority %q, expected to be one of P1 to P5", priority)
+	}
+
+	return &Squadcast{
+		URL:      addr,
+		ProxyURL: proxyURL,
+		CertPool: certPool,
+		Priority: priority,
+	}, nil
+}
+
+// Post triggers the incident of the involved object on the error events and
+// resolves it on the info events, the progressing events are skipped.
+func (s *Squadcast) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	obj := event.InvolvedObject
+	payload := squadcastPayload{
+		EventID:     incidentKey(event),
+		Status:      "resolve",
+		Message:     incidentTitle(event),
+		Description: incidentMessage(event),
+		Tags: map[string]string{
+			"kind":      obj.Kind,
+			"name":      obj.Name,
+			"namespace": obj.Namespace,
+			"severity":  event.Severity,
+		},
+	}
+	if event.Severity == events.EventSeverityError {
+		payload.Status = "trigger"
+		payload.Priority = s.Priority
+	}
+	if revision, ok := event.Metadata["revision"]; ok {
+		payload.Tags["revision"] = revision
+	}
+
+	err := s.transport.postMessage(s.URL, s.ProxyURL, s.CertPool, payload, s.withCapture(), s.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
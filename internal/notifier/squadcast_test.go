@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquadcast_Post(t *testing.T) {
+	var payloads []squadcastPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/incidents/api/key", r.URL.Path)
+		var payload squadcastPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer ts.Close()
+
+	squadcast, err := NewSquadcast(ts.URL+"/v2/incidents/api/key", "", "P2", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	event.Metadata["revision"] = "main/5394cb7"
+	require.NoError(t, squadcast.Post(event))
+	require.NoError(t, squadcast.Post(testEvent()))
+
+	require.Len(t, payloads, 2)
+	require.Equal(t, "trigger", payloads[0].Status)
+	require.Equal(t, "P2", payloads[0].Priority)
+	require.Equal(t, "gitrepository/webapp: reason", payloads[0].Message)
+	require.Equal(t, "main/5394cb7", payloads[0].Tags["revision"])
+	require.Equal(t, "resolve", payloads[1].Status)
+	require.Empty(t, payloads[1].Priority)
+	require.Equal(t, payloads[0].EventID, payloads[1].EventID)
+}
+
+func TestNewSquadcast(t *testing.T) {
+	_, err := NewSquadcast("https://api.squadcast.com/v2/incidents/api/key", "", "P0", nil)
+	require.Error(t, err)
+	_, err = NewSquadcast("squadcast", "", "", nil)
+	require.Error(t, err)
+}
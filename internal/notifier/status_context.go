@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+// statusContexts renders the contexts of the commit statuses published
+// for an event, e.g. 'flux/kustomization/apps' and 'flux/health'.
+type statusContexts struct {
+	templates []*template.Template
+}
+
+// newStatusContexts parses the context templates, the missing metadata
+// keys fail the rendering unless they are looked up with 'index'.
+func newStatusContexts(contexts []string) (*statusContexts, error) {
+	c := &statusContexts{}
+	for i, context := range contexts {
+		t, err := templates.Parse(fmt.Sprintf("context-%d", i), context)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status context template '%s': %w", context, err)
+		}
+		c.templates = append(c.templates, t.Option("missingkey=error"))
+	}
+	return c, nil
+}
+
+// render returns the contexts of the event, the contexts rendered
+// more than once are only returned once.
+func (c *statusContexts) render(event events.Event) ([]string, error) {
+	names := make([]string, 0, len(c.templates))
+	seen := make(map[string]bool, len(c.templates))
+	for _, t := range c.templates {
+		name, err := templates.Render(t, event)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("the status context template '" + t.Name() + "' rendered an empty context")
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// statusContexter is implemented by the git commit status notifiers.
+type statusContexter interface {
+	setStatusContexts(c *statusContexts)
+}
+
+// statusContexting is embedded by the git commit status notifiers to implement statusContexter.
+type statusContexting struct {
+	contexts *statusContexts
+}
+
+func (c *statusContexting) setStatusContexts(contexts *statusContexts) {
+	c.contexts = contexts
+}
+
+// contextsFor returns the contexts of the commit statuses of the event,
+// a single context named after the involved object when none is set.
+func (c *statusContexting) contextsFor(event events.Event) ([]string, error) {
+	if c.contexts == nil {
+		name, _ := formatNameAndDescription(event)
+		return []string{name}, nil
+	}
+	return c.contexts.render(event)
+}
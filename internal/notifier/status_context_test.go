@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestStatusContexts_render(t *testing.T) {
+	contexts, err := newStatusContexts([]string{
+		"flux/{{ .InvolvedObject.Kind | lower }}/{{ .InvolvedObject.Name }}",
+		"flux/health",
+		"flux/health",
+	})
+	require.NoError(t, err)
+
+	names, err := contexts.render(testEvent())
+	require.NoError(t, err)
+	require.Equal(t, []string{"flux/gitrepository/webapp", "flux/health"}, names)
+
+	contexts, err = newStatusContexts([]string{"flux/{{ .Metadata.missing }}"})
+	require.NoError(t, err)
+	_, err = contexts.render(testEvent())
+	require.Error(t, err)
+
+	_, err = newStatusContexts([]string{"flux/{{ .Metadata"})
+	require.Error(t, err)
+}
+
+func TestFactory_StatusContexts(t *testing.T) {
+	var contexts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/statuses"):
+			w.Write([]byte(`[{"state":"success","context":"flux/health","description":"reason"}]`))
+		case r.Method == http.MethodPost:
+			var status github.RepoStatus
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&status))
+			contexts = append(contexts, status.GetContext())
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL+"/org/repo", "", "", "", "token", nil)
+	factory.StatusContexts = []string{"flux/{{ .InvolvedObject.Kind | lower }}/{{ .InvolvedObject.Name }}", "flux/health"}
+	gh, err := factory.Notifier(v1beta1.GitHubProvider)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Metadata["revision"] = "main/5394cb7f48332b2de7c17dd8b8384bbc84b7e738"
+	require.NoError(t, gh.Post(event))
+	// the unchanged flux/health status isn't published again
+	require.Equal(t, []string{"flux/gitrepository/webapp"}, contexts)
+
+	_, err = factory.Notifier(v1beta1.SlackProvider)
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// statusStates maps the events to the generic states of the commit statuses,
+// the git notifiers convert them to the states of their provider.
+type statusStates struct {
+	rules []v1beta1.ProviderStatusState
+}
+
+// newStatusStates checks the states of the rules.
+func newStatusStates(rules []v1beta1.ProviderStatusState) (*statusStates, error) {
+	for _, rule := range rules {
+		switch rule.State {
+		case v1beta1.SuccessCommitState, v1beta1.FailureCommitState, v1beta1.PendingCommitState,
+			v1beta1.ErrorCommitState, v1beta1.SkipCommitState:
+		default:
+			return nil, fmt.Errorf("invalid commit status state '%s'", rule.State)
+		}
+		switch rule.Severity {
+		case "", events.EventSeverityInfo, events.EventSeverityError:
+		default:
+			return nil, fmt.Errorf("invalid commit status severity '%s'", rule.Severity)
+		}
+	}
+	return &statusStates{rules: rules}, nil
+}
+
+// match returns the state of the first rule matching the event.
+func (s *statusStates) match(event events.Event) (string, bool) {
+	for _, rule := range s.rules {
+		if rule.Severity != "" && rule.Severity != event.Severity {
+			continue
+		}
+		if len(rule.Reasons) > 0 && !containsString(rule.Reasons, event.Reason) {
+			continue
+		}
+		return rule.State, true
+	}
+	return "", false
+}
+
+// statusStater is implemented by the git commit status notifiers.
+type statusStater interface {
+	setStatusStates(s *statusStates)
+}
+
+// statusStating is embedded by the git commit status notifiers to implement statusStater.
+type statusStating struct {
+	states *statusStates
+}
+
+func (s *statusStating) setStatusStates(states *statusStates) {
+	s.states = states
+}
+
+// stateFor returns the generic state of the commit statuses of the event, the
+// failure state is used for the error events matching no rule. The 'Progressing'
+// events matching no rule are skipped.
+func (s *statusStating) stateFor(event events.Event, failure string) (string, error) {
+	if s.states != nil {
+		if state, ok := s.states.match(event); ok {
+			return state, nil
+		}
+	}
+
+	if event.Reason == "Progressing" {
+		return v1beta1.SkipCommitState, nil
+	}
+	switch event.Severity {
+	case events.EventSeverityInfo:
+		return v1beta1.SuccessCommitState, nil
+	case events.EventSeverityError:
+		return failure, nil
+	default:
+		return "", fmt.Errorf("can't convert severity '%s' to a commit status state", event.Severity)
+	}
+}
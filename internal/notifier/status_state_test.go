@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestStatusStating_stateFor(t *testing.T) {
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	healthFailed := testEvent()
+	healthFailed.Severity = events.EventSeverityError
+	healthFailed.Reason = "HealthCheckFailed"
+	failed := testEvent()
+	failed.Severity = events.EventSeverityError
+
+	var defaults statusStating
+	for _, tt := range []struct {
+		event events.Event
+		want  string
+	}{
+		{testEvent(), v1beta1.SuccessCommitState},
+		{failed, v1beta1.FailureCommitState},
+		{progressing, v1beta1.SkipCommitState},
+	} {
+		state, err := defaults.stateFor(tt.event, v1beta1.FailureCommitState)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, state)
+	}
+
+	states, err := newStatusStates([]v1beta1.ProviderStatusState{
+		{Reasons: []string{"Progressing"}, State: v1beta1.PendingCommitState},
+		{Reasons: []string{"HealthCheckFailed"}, Severity: events.EventSeverityError, State: v1beta1.ErrorCommitState},
+	})
+	require.NoError(t, err)
+	configured := statusStating{states: states}
+	for _, tt := range []struct {
+		event events.Event
+		want  string
+	}{
+		{testEvent(), v1beta1.SuccessCommitState},
+		{failed, v1beta1.FailureCommitState},
+		{progressing, v1beta1.PendingCommitState},
+		{healthFailed, v1beta1.ErrorCommitState},
+	} {
+		state, err := configured.stateFor(tt.event, v1beta1.FailureCommitState)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, state)
+	}
+
+	_, err = newStatusStates([]v1beta1.ProviderStatusState{{State: "unknown"}})
+	require.Error(t, err)
+	_, err = newStatusStates([]v1beta1.ProviderStatusState{{Severity: "warning", State: v1beta1.SkipCommitState}})
+	require.Error(t, err)
+}
+
+func TestFactory_StatusStates(t *testing.T) {
+	var states []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/statuses"):
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost:
+			var status github.RepoStatus
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&status))
+			states = append(states, status.GetState())
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	factory := NewFactory(ts.URL+"/org/repo", "", "", "", "token", nil)
+	factory.StatusStates = []v1beta1.ProviderStatusState{
+		{Reasons: []string{"Progressing"}, State: v1beta1.PendingCommitState},
+		{Severity: events.EventSeverityInfo, State: v1beta1.SkipCommitState},
+	}
+	gh, err := factory.Notifier(v1beta1.GitHubProvider)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Metadata["revision"] = "main/5394cb7f48332b2de7c17dd8b8384bbc84b7e738"
+	event.Reason = "Progressing"
+	require.NoError(t, gh.Post(event))
+	// the other info events are skipped
+	event.Reason = "ReconciliationSucceeded"
+	require.NoError(t, gh.Post(event))
+	require.Equal(t, []string{"pending"}, states)
+
+	_, err = factory.Notifier(v1beta1.SlackProvider)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatuspage_Post(t *testing.T) {
+	var statuses []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPatch, r.Method)
+		require.Equal(t, "/v1/pages/page/components/component", r.URL.Path)
+		require.Equal(t, "OAuth token", r.Header.Get("Authorization"))
+
+		var payload struct {
+			Component struct {
+				Status string `json:"status"`
+			} `json:"component"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		statuses = append(statuses, payload.Component.Status)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	statuspage, err := NewStatuspage(ts.URL+"/v1/pages/page/", "", "component", "token", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, statuspage.Post(event))
+	require.NoError(t, statuspage.Post(testEvent()))
+	require.Equal(t, []string{"partial_outage", "operational"}, statuses)
+}
+
+func TestStatuspage_PostFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	statuspage, err := NewStatuspage(ts.URL, "", "component", "token", nil)
+	require.NoError(t, err)
+	require.Error(t, statuspage.Post(testEvent()))
+}
+
+func TestNewStatuspage(t *testing.T) {
+	_, err := NewStatuspage("https://api.statuspage.io/v1/pages/page", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewStatuspage("https://api.statuspage.io/v1/pages/page", "", "component", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// TeamCity is a TeamCity notifier, it comments the builds of a build
+// configuration that ran on the revision of the event.
+type TeamCity struct {
+	requestConfig
+
+	URL         string
+	ProxyURL    string
+	CertPool    *x509.CertPool
+	BuildTypeID string
+	Token       string
+}
+
+// NewTeamCity returns a notifier for the server address, the channel is
+// the ID of the build configuration and the token an access token.
+func NewTeamCity(addr, proxyURL, buildTypeID, token string, certPool *x509.CertPool) (*TeamCity, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid TeamCity address %s: %w", addr, err)
+	}
+	if buildTypeID == "" {
+		return nil, errors.New("teamcity build configuration id cannot be empty")
+	}
+	if token == "" {
+		return nil, errors.New("teamcity token cannot be empty")
+	}
+
+	return &TeamCity{
+		URL:         strings.TrimSuffix(addr, "/"),
+		ProxyURL:    proxyURL,
+		CertPool:    certPool,
+		BuildTypeID: buildTypeID,
+		Token:       token,
+	}, nil
+}
+
+// Post sets the comment of the builds of the revision to the reconciliation outcome.
+func (t *TeamCity) Post(event events.Event) error {
+	// Skip progressing events
+	if event.Reason == "Progressing" {
+		return nil
+	}
+
+	revString, ok := event.Metadata["revision"]
+	if !ok {
+		return errors.New("missing revision metadata")
+	}
+	rev, err := parseRevision(revString)
+	if err != nil {
+		return err
+	}
+	comment, err := formatBuildComment(event)
+	if err != nil {
+		return err
+	}
+
+	locator := fmt.Sprintf("buildType:(id:%s),revision:%s,defaultFilter:false", t.BuildTypeID, rev)
+	var builds struct {
+		Build []struct {
+			ID int `json:"id"`
+		} `json:"build"`
+	}
+	address := fmt.Sprintf("%s/app/rest/builds?locator=%s&fields=build(id)", t.URL, url.QueryEscape(locator))
+	if err := t.transport.sendRequest(http.MethodGet, address, t.ProxyURL, t.CertPool, nil, &builds, t.authorize); err != nil {
+		return fmt.Errorf("could not list TeamCity builds: %w", err)
+	}
+	if len(builds.Build) == 0 {
+		return fmt.Errorf("no TeamCity build of %s found for revision %s", t.BuildTypeID, rev)
+	}
+
+	for _, build := range builds.Build {
+		address := fmt.Sprintf("%s/app/rest/builds/id:%d/comment", t.URL, build.ID)
+		err := t.transport.sendRequest(http.MethodPut, address, t.ProxyURL, t.CertPool, []byte(comment), nil, t.authorize, func(req *retryablehttp.Request) {
+			req.Header.Set("Content-Type", "text/plain")
+			req.Header.Set("Accept", "text/plain")
+		})
+		if err != nil {
+			return fmt.Errorf("could not comment TeamCity build %d: %w", build.ID, err)
+		}
+	}
+	return nil
+}
+
+func (t *TeamCity) authorize(req *retryablehttp.Request) {
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+}
+
+// formatBuildComment returns the outcome of the reconciliation,
+// e.g. 'flux kustomization/apps: success, reconciliation succeeded'.
+func formatBuildComment(event events.Event) (string, error) {
+	var state string
+	switch event.Severity {
+	case events.EventSeverityInfo:
+		state = "success"
+	case events.EventSeverityError:
+		state = "failure"
+	default:
+		return "", errors.New("can't convert to build state")
+	}
+	name, desc := formatNameAndDescription(event)
+	return fmt.Sprintf("flux %s: %s, %s", name, state, desc), nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func commitStatusEvent() events.Event {
+	event := testEvent()
+	event.InvolvedObject.Kind = "Kustomization"
+	event.Reason = "ReconciliationSucceeded"
+	event.Metadata = map[string]string{"revision": "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1"}
+	return event
+}
+
+func TestTeamCity_Post(t *testing.T) {
+	var comments []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/app/rest/builds":
+			require.Equal(t, "buildType:(id:Apps_Build),revision:731f7eaddfb6af01cb2173e18f0f75b0ba780ef1,defaultFilter:false",
+				r.URL.Query().Get("locator"))
+			fmt.Fprint(w, `{"build":[{"id":12},{"id":13}]}`)
+		case r.Method == http.MethodPut:
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			comments = append(comments, r.URL.Path+" "+string(b))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	tc, err := NewTeamCity(ts.URL, "", "Apps_Build", "token", nil)
+	require.NoError(t, err)
+	require.NoError(t, tc.Post(commitStatusEvent()))
+	require.Equal(t, []string{
+		"/app/rest/builds/id:12/comment flux kustomization/webapp: success, reconciliation succeeded",
+		"/app/rest/builds/id:13/comment flux kustomization/webapp: success, reconciliation succeeded",
+	}, comments)
+
+	event := commitStatusEvent()
+	delete(event.Metadata, "revision")
+	require.Error(t, tc.Post(event))
+}
+
+func TestNewTeamCity(t *testing.T) {
+	_, err := NewTeamCity("https://teamcity.example.com", "", "", "token", nil)
+	require.Error(t, err)
+	_, err = NewTeamCity("https://teamcity.example.com", "", "Apps_Build", "", nil)
+	require.Error(t, err)
+}
@@ -28,6 +28,8 @@ import (
 type MSTeams struct {
 	URL      string
 	ProxyURL string
+
+	requestConfig
 }
 
 // MSTeamsPayload holds the message card data
@@ -98,7 +100,7 @@ func (s *MSTeams) Post(event events.Event) error {
 		payload.ThemeColor = "FF0000"
 	}
 
-	err := postMessage(s.URL, s.ProxyURL, nil, payload)
+	err := s.transport.postMessage(s.URL, s.ProxyURL, nil, payload, s.withCapture(), s.withDelivery(event))
 	if err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
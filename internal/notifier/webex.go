@@ -30,6 +30,8 @@ type Webex struct {
 	URL      string
 	ProxyURL string
 	CertPool *x509.CertPool
+
+	requestConfig
 }
 
 // WebexPayload holds the message text
@@ -73,7 +75,7 @@ func (s *Webex) Post(event events.Event) error {
 		Markdown: markdown,
 	}
 
-	if err := postMessage(s.URL, s.ProxyURL, s.CertPool, payload); err != nil {
+	if err := s.transport.postMessage(s.URL, s.ProxyURL, s.CertPool, payload, s.withCapture(), s.withDelivery(event)); err != nil {
 		return fmt.Errorf("postMessage failed: %w", err)
 	}
 	return nil
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// XMatters is an xMatters notifier, it sends the events to the HTTP trigger
+// of a workflow, which notifies the recipients of the event.
+type XMatters struct {
+	// URL is the address of the HTTP trigger, e.g.
+	// 'https://<company>.xmatters.com/api/integration/1/functions/<id>/triggers'.
+	URL      string
+	ProxyURL string
+	CertPool *x509.CertPool
+
+	// Recipients is the comma separated list of the targets notified by the
+	// workflow, the ones of the workflow are used when empty.
+	Recipients string
+
+	// Username and Password authenticate the requests with basic auth, the
+	// triggers authenticated with an API key in the URL don't need them.
+	Username string
+	Password string
+
+	channelRouting
+	requestConfig
+}
+
+type xMattersRecipient struct {
+	TargetName string `json:"targetName"`
+}
+
+type xMattersPayload struct {
+	Priority   string              `json:"priority"`
+	Recipients []xMattersRecipient `json:"recipients,omitempty"`
+	Properties xMattersProperties  `json:"properties"`
+}
+
+type xMattersProperties struct {
+	Summary   string            `json:"summary"`
+	Message   string            `json:"message"`
+	Severity  string            `json:"severity"`
+	Reason    string            `json:"reason"`
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// NewXMatters returns a notifier for the HTTP trigger address, the channel is
+// the list of recipients and the token the password of the username.
+func NewXMatters(addr, proxyURL, recipients, username, password string, certPool *x509.CertPool) (*XMatters, error) {
+	if _, err := url.ParseRequestURI(addr); err != nil {
+		return nil, fmt.Errorf("invalid xMatters trigger URL %s: %w", addr, err)
+	}
+	if username != "" && password == "" {
+		return nil, errors.New("xMatters password cannot be empty")
+	}
+
+	return &XMatters{
+		URL:        addr,
+		ProxyURL:   proxyURL,
+		CertPool:   certPool,
+		Recipients: recipients,
+		Username:   username,
+		Password:   password,
+	}, nil
+}
+
+// Post triggers the workflow, the error events are sent with a high
+// priority and the other events with a low one.
+func (x *XMatters) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	recipients, err := x.recipientsFor(event, x.Recipients)
+	if err != nil {
+		return err
+	}
+
+	priority := "LOW"
+	if event.Severity == events.EventSeverityError {
+		priority = "HIGH"
+	}
+	payload := xMattersPayload{
+		Priority: priority,
+		Properties: xMattersProperties{
+			Summary:   incidentTitle(event),
+			Message:   event.Message,
+			Severity:  event.Severity,
+			Reason:    event.Reason,
+			Kind:      event.InvolvedObject.Kind,
+			Name:      event.InvolvedObject.Name,
+			Namespace: event.InvolvedObject.Namespace,
+			Metadata:  event.Metadata,
+		},
+	}
+	for _, recipient := range recipients {
+		payload.Recipients = append(payload.Recipients, xMattersRecipient{TargetName: recipient})
+	}
+
+	err = x.transport.postMessage(x.URL, x.ProxyURL, x.CertPool, payload, func(req *retryablehttp.Request) {
+		if x.Username != "" {
+			req.SetBasicAuth(x.Username, x.Password)
+		}
+	}, x.withCapture(), x.withDelivery(event))
+	if err != nil {
+		return fmt.Errorf("postMessage failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMatters_Post(t *testing.T) {
+	var payloads []xMattersPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "flux", username)
+		require.Equal(t, "s3cr3t", password)
+		var payload xMattersPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer ts.Close()
+
+	xmatters, err := NewXMatters(ts.URL, "", "platform-oncall, sre", "flux", "s3cr3t", nil)
+	require.NoError(t, err)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, xmatters.Post(event))
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	require.NoError(t, xmatters.Post(progressing))
+
+	route, err := newChannelRoute(`{{ index .Metadata "team" }}-oncall`, []string{"*-oncall"})
+	require.NoError(t, err)
+	xmatters.setChannelRoute(route)
+	event = testEvent()
+	event.Metadata["team"] = "payments"
+	require.NoError(t, xmatters.Post(event))
+
+	require.Len(t, payloads, 2)
+	require.Equal(t, "HIGH", payloads[0].Priority)
+	require.Equal(t, []xMattersRecipient{{TargetName: "platform-oncall"}, {TargetName: "sre"}}, payloads[0].Recipients)
+	require.Equal(t, "gitrepository/webapp: reason", payloads[0].Properties.Summary)
+	require.Equal(t, "webapp", payloads[0].Properties.Name)
+	require.Equal(t, "LOW", payloads[1].Priority)
+	require.Equal(t, []xMattersRecipient{{TargetName: "payments-oncall"}}, payloads[1].Recipients)
+
+	event.Metadata["team"] = "payments,everyone"
+	require.Error(t, xmatters.Post(event))
+}
+
+func TestNewXMatters(t *testing.T) {
+	_, err := NewXMatters("example.xmatters.com", "", "", "", "", nil)
+	require.Error(t, err)
+	_, err = NewXMatters("https://example.xmatters.com/api/integration/1/functions/id/triggers", "", "", "flux", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+const (
+	// zabbixDefaultPort is the port of the Zabbix server and proxy trapper.
+	zabbixDefaultPort = "10051"
+
+	// zabbixMaxResponse is the maximum size of the trapper responses read.
+	zabbixMaxResponse = 64 << 10
+
+	zabbixTimeout = 15 * time.Second
+)
+
+// zabbixHeader starts the packets of the Zabbix protocol, it's followed
+// by the data length and a reserved length, both 32 bits little endian.
+var zabbixHeader = []byte{'Z', 'B', 'X', 'D', 0x01}
+
+// zabbixFailed matches the failed values count in the trapper response info.
+var zabbixFailed = regexp.MustCompile(`failed: (\d+)`)
+
+// Zabbix is an implementation of the notification Interface that pushes
+// the events to the trapper items of a host with the Zabbix sender protocol.
+type Zabbix struct {
+	// Address is the host and port of the Zabbix server or proxy.
+	Address string
+
+	// Host is the name of the Zabbix host of the items.
+	Host string
+
+	// Transport holds the egress policy of the connections.
+	Transport *Transport
+
+	channelRouting
+}
+
+type zabbixValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+	NS    int    `json:"ns"`
+}
+
+type zabbixRequest struct {
+	Request string        `json:"request"`
+	Data    []zabbixValue `json:"data"`
+}
+
+type zabbixResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// NewZabbix parses an address formatted as 'zabbix://host[:port]',
+// the channel is the name of the Zabbix host of the items.
+func NewZabbix(address, host string, transport *Transport) (*Zabbix, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Zabbix address %s: %w", address, err)
+	}
+	if u.Scheme != "zabbix" {
+		return nil, fmt.Errorf("invalid Zabbix address %s: unsupported scheme '%s'", address, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Zabbix address %s: no host", address)
+	}
+	if host == "" {
+		return nil, errors.New("zabbix host cannot be empty")
+	}
+
+	z := &Zabbix{
+		Address:   u.Host,
+		Host:      host,
+		Transport: transport,
+	}
+	if u.Port() == "" {
+		z.Address = net.JoinHostPort(u.Hostname(), zabbixDefaultPort)
+	}
+	return z, nil
+}
+
+// Post sends the status and the event of the involved object to the
+// 'flux.status[kind,namespace,name]' and 'flux.event[kind,namespace,name]'
+// trapper items, the status is 1 for the error events and 0 for the info
+// events, the progressing events are skipped.
+func (z *Zabbix) Post(event events.Event) error {
+	// Skip progressing and any update events
+	if event.Reason == "Progressing" || isCommitStatus(event.Metadata, "update") {
+		return nil
+	}
+
+	host, err := z.channelFor(event, z.Host)
+	if err != nil {
+		return err
+	}
+
+	status := "0"
+	if event.Severity == events.EventSeverityError {
+		status = "1"
+	}
+	obj := event.InvolvedObject
+	params := fmt.Sprintf("[%s,%s,%s]", obj.Kind, obj.Namespace, obj.Name)
+	clock := event.Timestamp.Time
+	if clock.IsZero() {
+		clock = time.Now()
+	}
+	value := func(key, v string) zabbixValue {
+		return zabbixValue{Host: host, Key: key + params, Value: v, Clock: clock.Unix(), NS: clock.Nanosecond()}
+	}
+
+	message := fmt.Sprintf("%s: %s", event.Reason, event.Message)
+	if revision, ok := event.Metadata["revision"]; ok {
+		message += fmt.Sprintf(" (%s)", revision)
+	}
+	request := zabbixRequest{
+		Request: "sender data",
+		Data:    []zabbixValue{value("flux.status", status), value("flux.event", message)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), zabbixTimeout)
+	defer cancel()
+	response, err := z.send(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to send to Zabbix %s: %w", z.Address, err)
+	}
+	if response.Response != "success" {
+		return fmt.Errorf("zabbix %s rejected the values: %s", z.Address, response.Info)
+	}
+	if m := zabbixFailed.FindStringSubmatch(response.Info); m != nil && m[1] != "0" {
+		return fmt.Errorf("zabbix %s failed to process %s values of host '%s', check the trapper items: %s",
+			z.Address, m[1], host, response.Info)
+	}
+	return nil
+}
+
+// send writes the request as a Zabbix protocol packet and reads the response.
+func (z *Zabbix) send(ctx context.Context, request zabbixRequest) (*zabbixResponse, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling notification payload failed: %w", err)
+	}
+
+	conn, err := z.Transport.dialContext(&net.Dialer{Timeout: zabbixTimeout})(ctx, "tcp", z.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(zabbixPacket(data)); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(zabbixHeader)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read the response: %w", err)
+	}
+	if string(header[:4]) != string(zabbixHeader[:4]) {
+		return nil, errors.New("invalid response header")
+	}
+	size := binary.LittleEndian.Uint32(header[len(zabbixHeader):])
+	if size > zabbixMaxResponse {
+		return nil, fmt.Errorf("response of %d bytes is too large", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return nil, fmt.Errorf("failed to read the response: %w", err)
+	}
+
+	var response zabbixResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode the response: %w", err)
+	}
+	return &response, nil
+}
+
+// zabbixPacket returns the data prefixed with the protocol header.
+func zabbixPacket(data []byte) []byte {
+	packet := make([]byte, 0, len(zabbixHeader)+8+len(data))
+	packet = append(packet, zabbixHeader...)
+	length := make([]byte, 8)
+	binary.LittleEndian.PutUint32(length, uint32(len(data)))
+	packet = append(packet, length...)
+	return append(packet, data...)
+}
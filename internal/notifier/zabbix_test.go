@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeZabbix accepts a connection, records the sender request and replies
+// with the info.
+func fakeZabbix(t *testing.T, info string) (string, chan zabbixRequest) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	requests := make(chan zabbixRequest, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		header := make([]byte, 13)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, binary.LittleEndian.Uint32(header[5:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var request zabbixRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return
+		}
+		requests <- request
+
+		data, _ := json.Marshal(zabbixResponse{Response: "success", Info: info})
+		conn.Write(zabbixPacket(data))
+	}()
+	return ln.Addr().String(), requests
+}
+
+func TestZabbix_Post(t *testing.T) {
+	addr, requests := fakeZabbix(t, "processed: 2; failed: 0; total: 2; seconds spent: 0.000055")
+
+	zabbix, err := NewZabbix("zabbix://"+addr, "k8s-prod", nil)
+	require.NoError(t, err)
+	require.Equal(t, addr, zabbix.Address)
+
+	event := testEvent()
+	event.Severity = events.EventSeverityError
+	require.NoError(t, zabbix.Post(event))
+
+	request := <-requests
+	require.Equal(t, "sender data", request.Request)
+	require.Len(t, request.Data, 2)
+	require.Equal(t, "k8s-prod", request.Data[0].Host)
+	require.Equal(t, "flux.status[GitRepository,gitops-system,webapp]", request.Data[0].Key)
+	require.Equal(t, "1", request.Data[0].Value)
+	require.Equal(t, "flux.event[GitRepository,gitops-system,webapp]", request.Data[1].Key)
+	require.Equal(t, "reason: message", request.Data[1].Value)
+
+	progressing := testEvent()
+	progressing.Reason = "Progressing"
+	require.NoError(t, zabbix.Post(progressing))
+}
+
+func TestZabbix_PostFailed(t *testing.T) {
+	addr, _ := fakeZabbix(t, "processed: 0; failed: 2; total: 2; seconds spent: 0.000055")
+
+	zabbix, err := NewZabbix("zabbix://"+addr, "k8s-prod", nil)
+	require.NoError(t, err)
+	require.Error(t, zabbix.Post(testEvent()))
+}
+
+func TestNewZabbix(t *testing.T) {
+	zabbix, err := NewZabbix("zabbix://zabbix.example.com", "k8s-prod", nil)
+	require.NoError(t, err)
+	require.Equal(t, "zabbix.example.com:10051", zabbix.Address)
+
+	_, err = NewZabbix("https://zabbix.example.com", "k8s-prod", nil)
+	require.Error(t, err)
+	_, err = NewZabbix("zabbix://zabbix.example.com", "", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profiling
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	flagEnableProfiling    = "enable-profiling"
+	flagProfilingTokenFile = "profiling-token-file"
+	flagGOMAXPROCS         = "gomaxprocs"
+	flagMemoryBallast      = "memory-ballast"
+)
+
+// ballast is a heap allocation that is never used, it raises the heap size
+// at which the garbage collections are triggered. The pages are never
+// written, so that they don't count towards the resident memory.
+var ballast []byte
+
+// Options contains the profiling endpoints and the runtime tuning settings.
+type Options struct {
+	Enabled       bool
+	TokenFile     string
+	GOMAXPROCS    int
+	MemoryBallast string
+}
+
+// BindFlags will parse the given flagset for profiling option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enabled, flagEnableProfiling, false,
+		"Serve the pprof and expvar endpoints under '/debug/' on the metrics address, requires --"+flagProfilingTokenFile+".")
+	fs.StringVar(&o.TokenFile, flagProfilingTokenFile, "",
+		"The file containing the bearer token of the profiling and debug endpoints, the debug endpoints are disabled when empty.")
+	fs.IntVar(&o.GOMAXPROCS, flagGOMAXPROCS, 0,
+		"The maximum number of CPUs executing Go code simultaneously, the Go runtime default is used when set to zero.")
+	fs.StringVar(&o.MemoryBallast, flagMemoryBallast, "",
+		"The size of the heap ballast delaying the garbage collections, e.g. '256Mi', disabled when empty.")
+}
+
+// Apply sets the runtime settings, it must be called once at startup.
+func (o Options) Apply() error {
+	if o.GOMAXPROCS < 0 {
+		return fmt.Errorf("invalid --%s %d, it cannot be negative", flagGOMAXPROCS, o.GOMAXPROCS)
+	}
+	if o.GOMAXPROCS > 0 {
+		runtime.GOMAXPROCS(o.GOMAXPROCS)
+	}
+
+	if o.MemoryBallast != "" {
+		size, err := resource.ParseQuantity(o.MemoryBallast)
+		if err != nil {
+			return fmt.Errorf("invalid --%s '%s': %w", flagMemoryBallast, o.MemoryBallast, err)
+		}
+		if size.Sign() < 0 {
+			return fmt.Errorf("invalid --%s '%s', it cannot be negative", flagMemoryBallast, o.MemoryBallast)
+		}
+		ballast = make([]byte, size.Value())
+	}
+	return nil
+}
+
+// Handlers returns the profiling endpoints and the given debug endpoints by
+// path, guarded by the bearer token, they must be added to the metrics endpoint
+// so that they're not exposed with the receivers. The profiling endpoints are
+// returned when profiling is enabled, the debug endpoints when the token is set.
+func (o Options) Handlers(debug map[string]http.Handler) (map[string]http.Handler, error) {
+	if o.Enabled && o.TokenFile == "" {
+		return nil, fmt.Errorf("--%s requires --%s", flagEnableProfiling, flagProfilingTokenFile)
+	}
+	if o.TokenFile == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(o.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the profiling token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("the profiling token file %s is empty", o.TokenFile)
+	}
+
+	endpoints := make(map[string]http.Handler, len(debug)+6)
+	if o.Enabled {
+		endpoints["/debug/pprof/"] = http.HandlerFunc(pprof.Index)
+		endpoints["/debug/pprof/cmdline"] = http.HandlerFunc(pprof.Cmdline)
+		endpoints["/debug/pprof/profile"] = http.HandlerFunc(pprof.Profile)
+		endpoints["/debug/pprof/symbol"] = http.HandlerFunc(pprof.Symbol)
+		endpoints["/debug/pprof/trace"] = http.HandlerFunc(pprof.Trace)
+		endpoints["/debug/vars"] = expvar.Handler()
+	}
+	for path, h := range debug {
+		endpoints[path] = h
+	}
+	for path, h := range endpoints {
+		endpoints[path] = requireToken(token, h)
+	}
+	return endpoints, nil
+}
+
+// requireToken rejects the requests without the bearer token.
+func requireToken(token string, h http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profiling
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Handlers(t *testing.T) {
+	debug := map[string]http.Handler{
+		"/debug/providers/": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+
+	handlers, err := Options{}.Handlers(debug)
+	require.NoError(t, err)
+	require.Nil(t, handlers)
+
+	_, err = Options{Enabled: true}.Handlers(debug)
+	require.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600))
+
+	// the debug endpoints are served without profiling
+	handlers, err = Options{TokenFile: tokenFile}.Handlers(debug)
+	require.NoError(t, err)
+	require.Len(t, handlers, 1)
+	require.Contains(t, handlers, "/debug/providers/")
+
+	handlers, err = Options{Enabled: true, TokenFile: tokenFile}.Handlers(debug)
+	require.NoError(t, err)
+	require.Contains(t, handlers, "/debug/pprof/")
+	require.Contains(t, handlers, "/debug/vars")
+	require.Contains(t, handlers, "/debug/providers/")
+
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, path := range []string{"/debug/vars", "/debug/providers/"} {
+		require.Equal(t, http.StatusUnauthorized, get(path, ""))
+		require.Equal(t, http.StatusUnauthorized, get(path, "wrong"))
+		require.Equal(t, http.StatusOK, get(path, "s3cr3t"))
+	}
+
+	require.NoError(t, ioutil.WriteFile(tokenFile, nil, 0600))
+	_, err = Options{Enabled: true, TokenFile: tokenFile}.Handlers(debug)
+	require.Error(t, err)
+}
+
+func TestOptions_Apply(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	defer func() { ballast = nil }()
+
+	require.NoError(t, Options{GOMAXPROCS: 1, MemoryBallast: "1Mi"}.Apply())
+	require.Equal(t, 1, runtime.GOMAXPROCS(0))
+	require.Len(t, ballast, 1<<20)
+
+	require.Error(t, Options{GOMAXPROCS: -1}.Apply())
+	require.Error(t, Options{MemoryBallast: "lots"}.Apply())
+}
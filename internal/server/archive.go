@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// archiveResolution is the interval at which the batches are checked.
+	archiveResolution = 10 * time.Second
+
+	defaultArchiveInterval  = 15 * time.Minute
+	defaultArchiveMaxEvents = 1000
+
+	// archiveMaxPending bounds the events kept for a provider whose
+	// writes fail, the oldest events are dropped first.
+	archiveMaxPending = 100000
+)
+
+type archiveBatch struct {
+	events []events.Event
+	since  time.Time
+}
+
+// archiveBatches holds the events received by the archive providers,
+// the batches are written by the Archives runnable.
+type archiveBatches struct {
+	mu      sync.Mutex
+	batches map[types.NamespacedName]*archiveBatch
+}
+
+func newArchiveBatches() *archiveBatches {
+	return &archiveBatches{batches: make(map[types.NamespacedName]*archiveBatch)}
+}
+
+// record adds the event to the batch of the provider.
+func (b *archiveBatches) record(provider v1beta1.Provider, event events.Event, now time.Time) {
+	name := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[name]
+	if !ok {
+		batch = &archiveBatch{}
+		b.batches[name] = batch
+	}
+	if len(batch.events) == 0 {
+		batch.since = now
+	}
+	batch.events = append(batch.events, event)
+	if len(batch.events) > archiveMaxPending {
+		batch.events = batch.events[len(batch.events)-archiveMaxPending:]
+	}
+}
+
+// due returns and clears the events of the provider if the batch holds at least
+// maxEvents events or its first event was received at least an interval ago.
+// All the events are returned when flush is true.
+func (b *archiveBatches) due(name types.NamespacedName, interval time.Duration, maxEvents int, now time.Time, flush bool) []events.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[name]
+	if !ok || len(batch.events) == 0 {
+		return nil
+	}
+	if !flush && len(batch.events) < maxEvents && now.Sub(batch.since) < interval {
+		return nil
+	}
+	pending := batch.events
+	batch.events = nil
+	return pending
+}
+
+// failed puts back the events of a batch that couldn't be written,
+// so that they're written with the next batch.
+func (b *archiveBatches) failed(name types.NamespacedName, pending []events.Event, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[name]
+	if !ok {
+		return
+	}
+	batch.events = append(pending, batch.events...)
+	if len(batch.events) > archiveMaxPending {
+		batch.events = batch.events[len(batch.events)-archiveMaxPending:]
+	}
+	batch.since = now
+}
+
+func (b *archiveBatches) names() []types.NamespacedName {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := make([]types.NamespacedName, 0, len(b.batches))
+	for name := range b.batches {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (b *archiveBatches) remove(name types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.batches, name)
+}
+
+// Archives writes the batches of events of the archive providers to the object storage.
+type Archives struct {
+	logger     logr.Logger
+	kubeClient client.Client
+	providers  *Providers
+	batches    *archiveBatches
+	health     *HealthEvents
+}
+
+// NewArchives returns the archives writer, it must be added to the manager
+// so that it runs alongside the event server. The write failures are reported
+// with the health events, if not nil.
+func NewArchives(logger logr.Logger, kubeClient client.Client, providers *Providers, health *HealthEvents) *Archives {
+	return &Archives{
+		logger:     logger.WithName("archive"),
+		kubeClient: kubeClient,
+		providers:  providers,
+		batches:    newArchiveBatches(),
+		health:     health,
+	}
+}
+
+// WithArchives sets the archives writing the events of the archive providers.
+func (s *EventServer) WithArchives(archives *Archives) {
+	s.archives = archives
+}
+
+// record adds the event to the batch of the provider, the events
+// are discarded when the archives are not running.
+func (a *Archives) record(provider v1beta1.Provider, event events.Event, now time.Time) {
+	if a == nil {
+		return
+	}
+	a.batches.record(provider, event, now)
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// the batches hold the events received by this replica.
+func (a *Archives) NeedLeaderElection() bool {
+	return false
+}
+
+// Start writes the due batches until the context is cancelled, the pending
+// events are written on shutdown so that they're not lost.
+func (a *Archives) Start(ctx context.Context) error {
+	ticker := time.NewTicker(archiveResolution)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.write(context.Background(), time.Now(), true)
+			return nil
+		case <-ticker.C:
+			a.write(ctx, time.Now(), false)
+		}
+	}
+}
+
+func (a *Archives) write(ctx context.Context, now time.Time, flush bool) {
+	for _, name := range a.batches.names() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		a.writeBatch(ctx, name, now, flush)
+		cancel()
+	}
+}
+
+func (a *Archives) writeBatch(ctx context.Context, name types.NamespacedName, now time.Time, flush bool) {
+	var provider v1beta1.Provider
+	if err := a.kubeClient.Get(ctx, name, &provider); err != nil {
+		if apierrors.IsNotFound(err) {
+			a.batches.remove(name)
+			return
+		}
+		a.logger.Error(err, "failed to read provider",
+			"reconciler kind", v1beta1.ProviderKind,
+			"name", name.Name,
+			"namespace", name.Namespace)
+		return
+	}
+	if provider.Spec.Type != v1beta1.ArchiveProvider {
+		a.batches.remove(name)
+		return
+	}
+
+	interval, maxEvents := defaultArchiveInterval, defaultArchiveMaxEvents
+	if spec := provider.Spec.Archive; spec != nil {
+		if spec.Interval != nil && spec.Interval.Duration > 0 {
+			interval = spec.Interval.Duration
+		}
+		if spec.MaxEvents > 0 {
+			maxEvents = spec.MaxEvents
+		}
+	}
+
+	pending := a.batches.due(name, interval, maxEvents, now, flush)
+	if len(pending) == 0 {
+		return
+	}
+
+	object, err := a.send(ctx, provider, pending, now)
+	if err != nil {
+		a.batches.failed(name, pending, now)
+		a.health.record(healthProblem{reason: DeliveryFailureReason, provider: name})
+		a.logger.Error(err, fmt.Sprintf("failed to write %d events to the archive", len(pending)),
+			"reconciler kind", v1beta1.ProviderKind,
+			"name", name.Name,
+			"namespace", name.Namespace)
+		return
+	}
+	a.logger.V(1).Info(fmt.Sprintf("wrote %d events to the archive object %s", len(pending), object),
+		"reconciler kind", v1beta1.ProviderKind,
+		"name", name.Name,
+		"namespace", name.Namespace)
+}
+
+func (a *Archives) send(ctx context.Context, provider v1beta1.Provider, pending []events.Event, now time.Time) (string, error) {
+	factory, err := a.providers.factory(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+	archive, err := factory.Archive()
+	if err != nil {
+		return "", err
+	}
+	return archive.Write(pending, now)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestArchiveBatches(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	provider := v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: "flux-system"},
+		Spec:       v1beta1.ProviderSpec{Type: v1beta1.ArchiveProvider},
+	}
+	name := types.NamespacedName{Namespace: "flux-system", Name: "archive"}
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	batches := newArchiveBatches()
+	g.Expect(batches.due(name, time.Hour, 3, now, false)).To(gomega.BeEmpty())
+
+	batches.record(provider, events.Event{Message: "1"}, now)
+	batches.record(provider, events.Event{Message: "2"}, now.Add(time.Minute))
+	g.Expect(batches.due(name, time.Hour, 3, now.Add(time.Minute), false)).To(gomega.BeEmpty())
+
+	// the batch is written once it's full, or after the interval
+	batches.record(provider, events.Event{Message: "3"}, now.Add(2*time.Minute))
+	g.Expect(batches.due(name, time.Hour, 3, now.Add(2*time.Minute), false)).To(gomega.HaveLen(3))
+
+	batches.record(provider, events.Event{Message: "4"}, now.Add(3*time.Minute))
+	g.Expect(batches.due(name, time.Hour, 3, now.Add(time.Hour), false)).To(gomega.BeEmpty())
+	pending := batches.due(name, time.Hour, 3, now.Add(63*time.Minute), false)
+	g.Expect(pending).To(gomega.HaveLen(1))
+
+	// the events of a failed batch are written with the next one
+	batches.record(provider, events.Event{Message: "5"}, now.Add(64*time.Minute))
+	batches.failed(name, pending, now.Add(64*time.Minute))
+	pending = batches.due(name, time.Hour, 3, now.Add(64*time.Minute), true)
+	g.Expect(pending).To(gomega.HaveLen(2))
+	g.Expect(pending[0].Message).To(gomega.Equal("4"))
+}
+
+func TestArchives_write(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	uploads := make(chan string, 10)
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			uploads <- r.URL.Path
+		}
+	}))
+	defer ts.Close()
+
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: "flux-system"},
+		Spec: v1beta1.ProviderSpec{
+			Type:      v1beta1.ArchiveProvider,
+			Address:   ts.URL + "/audit/flux?region=eu-west-1",
+			SecretRef: &meta.LocalObjectReference{Name: "archive"},
+			Archive:   &v1beta1.ProviderArchive{MaxEvents: 2},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: "flux-system"},
+		Data: map[string][]byte{
+			"awsAccessKeyID":     []byte("AKIDEXAMPLE"),
+			"awsSecretAccessKey": []byte("secret"),
+		},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(provider, secret).Build()
+
+	providers := NewProviders(kubeClient, nil)
+	h := NewHealthEvents(time.Minute, 1, "flux-system", logf.Log, kubeClient, providers, nil)
+	name := types.NamespacedName{Namespace: "flux-system", Name: "archive"}
+	a := NewArchives(logf.Log, kubeClient, providers, h)
+	now := time.Now()
+
+	a.record(*provider, events.Event{Message: "1"}, now)
+	a.write(context.Background(), now, false)
+	g.Expect(uploads).To(gomega.BeEmpty())
+
+	status = http.StatusForbidden
+	a.record(*provider, events.Event{Message: "2"}, now)
+	a.write(context.Background(), now, false)
+	g.Expect(uploads).To(gomega.BeEmpty())
+	g.Expect(h.problems.collect(1)).To(gomega.HaveLen(1))
+
+	// the pending events are written on shutdown
+	status = http.StatusOK
+	a.write(context.Background(), now, true)
+	g.Expect(uploads).To(gomega.HaveLen(1))
+	g.Expect(<-uploads).To(gomega.HavePrefix("/audit/flux/"))
+	g.Expect(a.batches.due(name, time.Hour, 2, now, true)).To(gomega.BeEmpty())
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// defaultFingerprintFields are the fields of the rate limit fingerprint,
+// and of the alerts deduplication fingerprint when they don't select any.
+var defaultFingerprintFields = []v1beta1.DeduplicationField{
+	v1beta1.InvolvedObjectField,
+	v1beta1.MessageField,
+	v1beta1.RevisionField,
+}
+
+// eventDeduplicator records when an event fingerprint was last notified per alert.
+type eventDeduplicator struct {
+	mu       sync.Mutex
+	notified map[string]time.Time
+	expiry   map[string]time.Duration
+}
+
+func newEventDeduplicator() *eventDeduplicator {
+	return &eventDeduplicator{
+		notified: make(map[string]time.Time),
+		expiry:   make(map[string]time.Duration),
+	}
+}
+
+// duplicate returns true if an event with the same fingerprint was
+// notified by the alert within the deduplication interval, otherwise
+// the event is recorded as notified.
+func (d *eventDeduplicator) duplicate(alert v1beta1.Alert, event events.Event, now time.Time) bool {
+	if alert.Spec.Deduplication == nil {
+		return false
+	}
+	interval := alert.Spec.Deduplication.Interval.Duration
+	fields := alert.Spec.Deduplication.Fields
+	if len(fields) == 0 {
+		fields = defaultFingerprintFields
+	}
+	key := fmt.Sprintf("%s/%s/%s", alert.Namespace, alert.Name, eventFingerprint(event, fields))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// forget the fingerprints notified more than an interval ago
+	for k, t := range d.notified {
+		if now.Sub(t) >= d.expiry[k] {
+			delete(d.notified, k)
+			delete(d.expiry, k)
+		}
+	}
+
+	if t, ok := d.notified[key]; ok && now.Sub(t) < interval {
+		return true
+	}
+	d.notified[key] = now
+	d.expiry[key] = interval
+	return false
+}
+
+// eventFingerprint returns the digest of the event fields, the revision
+// is skipped when the event metadata doesn't have one.
+func eventFingerprint(event events.Event, fields []v1beta1.DeduplicationField) string {
+	comps := []string{"event"}
+	for _, field := range fields {
+		switch field {
+		case v1beta1.InvolvedObjectField:
+			object := event.InvolvedObject
+			comps = append(comps, object.Name, object.Namespace, object.Kind)
+		case v1beta1.SeverityField:
+			comps = append(comps, event.Severity)
+		case v1beta1.ReasonField:
+			comps = append(comps, event.Reason)
+		case v1beta1.MessageField:
+			comps = append(comps, event.Message)
+		case v1beta1.RevisionField:
+			if revision, ok := event.Metadata["revision"]; ok {
+				comps = append(comps, revision)
+			}
+		}
+	}
+	digest := sha256.Sum256([]byte(strings.Join(comps, "/")))
+	return fmt.Sprintf("%x", digest)
+}
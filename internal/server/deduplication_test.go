@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestEventDeduplicator_duplicate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec: v1beta1.AlertSpec{
+			Deduplication: &v1beta1.EventDeduplication{
+				Interval: metav1.Duration{Duration: 10 * time.Minute},
+				Fields:   []v1beta1.DeduplicationField{v1beta1.InvolvedObjectField, v1beta1.ReasonField},
+			},
+		},
+	}
+	newEvent := func(name, message string) events.Event {
+		return events.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: name, Namespace: "default"},
+			Severity:       events.EventSeverityError,
+			Reason:         "ReconciliationFailed",
+			Message:        message,
+		}
+	}
+
+	deduplicator := newEventDeduplicator()
+	now := time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC)
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.1:443: i/o timeout"), now)).To(gomega.BeFalse())
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.2:443: i/o timeout"), now.Add(time.Minute))).To(gomega.BeTrue())
+	g.Expect(deduplicator.duplicate(alert, newEvent("webapp", "dial tcp 10.0.0.1:443: i/o timeout"), now.Add(time.Minute))).To(gomega.BeFalse())
+
+	// the fingerprint is notified again once the interval elapsed
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.3:443: i/o timeout"), now.Add(10*time.Minute))).To(gomega.BeFalse())
+
+	// the default fields include the message
+	alert.Spec.Deduplication.Fields = nil
+	later := now.Add(time.Hour)
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.1:443: i/o timeout"), later)).To(gomega.BeFalse())
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.2:443: i/o timeout"), later)).To(gomega.BeFalse())
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.2:443: i/o timeout"), later)).To(gomega.BeTrue())
+
+	alert.Spec.Deduplication = nil
+	g.Expect(deduplicator.duplicate(alert, newEvent("podinfo", "dial tcp 10.0.0.2:443: i/o timeout"), later)).To(gomega.BeFalse())
+}
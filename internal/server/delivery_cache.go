@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// deliveryCache records the webhook delivery IDs handled within a time window,
+// so that the deliveries retried by the webhook providers are handled once.
+type deliveryCache struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func newDeliveryCache(window time.Duration) *deliveryCache {
+	return &deliveryCache{
+		window:  window,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// seen records the delivery and reports whether it was already recorded within the window.
+// A nil cache or an empty key never deduplicates.
+func (c *deliveryCache) seen(key string, now time.Time) bool {
+	if c == nil || key == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, t := range c.entries {
+		if now.Sub(t) >= c.window {
+			delete(c.entries, k)
+		}
+	}
+
+	if _, ok := c.entries[key]; ok {
+		return true
+	}
+	c.entries[key] = now
+	return false
+}
+
+// forget removes the delivery so that a retry of a failed delivery is handled.
+func (c *deliveryCache) forget(key string) {
+	if c == nil || key == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestDeliveryCache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now()
+	cache := newDeliveryCache(10 * time.Minute)
+
+	g.Expect(cache.seen("default/github/72d3162e", now)).To(gomega.BeFalse())
+	g.Expect(cache.seen("default/github/72d3162e", now.Add(time.Minute))).To(gomega.BeTrue())
+	g.Expect(cache.seen("default/gitlab/72d3162e", now.Add(time.Minute))).To(gomega.BeFalse())
+
+	// a delivery is handled again once the window has passed
+	g.Expect(cache.seen("default/github/72d3162e", now.Add(11*time.Minute))).To(gomega.BeFalse())
+
+	// a failed delivery is handled again when retried
+	cache.forget("default/github/72d3162e")
+	g.Expect(cache.seen("default/github/72d3162e", now.Add(12*time.Minute))).To(gomega.BeFalse())
+
+	g.Expect(cache.seen("", now)).To(gomega.BeFalse())
+	g.Expect(cache.seen("", now)).To(gomega.BeFalse())
+
+	var disabled *deliveryCache
+	g.Expect(disabled.seen("default/github/72d3162e", now)).To(gomega.BeFalse())
+	g.Expect(disabled.seen("default/github/72d3162e", now)).To(gomega.BeFalse())
+}
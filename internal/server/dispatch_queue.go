@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+// dispatchQueue sends the notifications with a fixed number of workers.
+// The error notifications are sent before the queued info notifications,
+// so that failures aren't delayed by a burst of informational events.
+type dispatchQueue struct {
+	logger    logr.Logger
+	maxQueued int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	errors []dispatchItem
+	infos  []dispatchItem
+	closed bool
+}
+
+type dispatchItem struct {
+	notifier   notifier.Interface
+	event      events.Event
+	deliveryID string
+}
+
+// newDispatchQueue returns a queue holding at most maxQueued info notifications
+// and maxQueued error notifications, so that a failing provider can't grow the
+// error backlog without bounds. A zero maxQueued doesn't limit the queue.
+func newDispatchQueue(logger logr.Logger, maxQueued int) *dispatchQueue {
+	q := &dispatchQueue{
+		logger:    logger,
+		maxQueued: maxQueued,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues the notification, it returns false if the backlog of its
+// severity is full or the queue is closed.
+func (q *dispatchQueue) push(n notifier.Interface, e events.Event, deliveryID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+
+	item := dispatchItem{notifier: n, event: e, deliveryID: deliveryID}
+	backlog := &q.infos
+	if e.Severity == events.EventSeverityError {
+		backlog = &q.errors
+	}
+	if q.maxQueued > 0 && len(*backlog) >= q.maxQueued {
+		return false
+	}
+	*backlog = append(*backlog, item)
+	q.cond.Signal()
+	return true
+}
+
+// pop blocks until a notification is queued, it returns false once the queue is closed.
+func (q *dispatchQueue) pop() (dispatchItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.errors) == 0 && len(q.infos) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return dispatchItem{}, false
+	}
+
+	var item dispatchItem
+	if len(q.errors) > 0 {
+		item, q.errors = q.errors[0], q.errors[1:]
+	} else {
+		item, q.infos = q.infos[0], q.infos[1:]
+	}
+	return item, true
+}
+
+// close stops the workers, the queued notifications are discarded.
+func (q *dispatchQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// run starts the workers and blocks until the queue is closed.
+func (q *dispatchQueue) run(workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, ok := q.pop()
+				if !ok {
+					return
+				}
+				if err := item.notifier.Post(item.event); err != nil {
+					q.logger.Error(err, "failed to send notification",
+						"reconciler kind", item.event.InvolvedObject.Kind,
+						"name", item.event.InvolvedObject.Name,
+						"namespace", item.event.InvolvedObject.Namespace,
+						"delivery id", item.deliveryID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// dispatch sends the notification through the queue, or in its own goroutine
+// when the server has no queue, the delivery ID is logged with the failures.
+func (s *EventServer) dispatch(n notifier.Interface, e events.Event, deliveryID string) {
+	if s.queue == nil {
+		go func() {
+			if err := n.Post(e); err != nil {
+				s.logger.Error(err, "failed to send notification",
+					"reconciler kind", e.InvolvedObject.Kind,
+					"name", e.InvolvedObject.Name,
+					"namespace", e.InvolvedObject.Namespace,
+					"delivery id", deliveryID)
+			}
+		}()
+		return
+	}
+
+	if !s.queue.push(n, e, deliveryID) {
+		s.health.record(healthProblem{reason: QueueOverflowReason})
+		s.logger.Info("Discarding notification, the dispatch queue is full",
+			"reconciler kind", e.InvolvedObject.Kind,
+			"name", e.InvolvedObject.Name,
+			"namespace", e.InvolvedObject.Namespace,
+			"delivery id", deliveryID)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/runtime/events"
+)
+
+type recordingNotifier struct {
+	posted chan events.Event
+}
+
+func (n *recordingNotifier) Post(event events.Event) error {
+	n.posted <- event
+	return nil
+}
+
+func TestDispatchQueue_priority(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	n := &recordingNotifier{posted: make(chan events.Event, 10)}
+	q := newDispatchQueue(logf.Log, 2)
+
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityInfo, Message: "info-1"}, "")).To(gomega.BeTrue())
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityInfo, Message: "info-2"}, "")).To(gomega.BeTrue())
+	// the info backlog is full, the errors are still queued
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityInfo, Message: "info-3"}, "")).To(gomega.BeFalse())
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityError, Message: "error-1"}, "")).To(gomega.BeTrue())
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityError, Message: "error-2"}, "")).To(gomega.BeTrue())
+	// the error backlog is bounded as well
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityError, Message: "error-3"}, "")).To(gomega.BeFalse())
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		item, ok := q.pop()
+		g.Expect(ok).To(gomega.BeTrue())
+		order = append(order, item.event.Message)
+	}
+	g.Expect(order).To(gomega.Equal([]string{"error-1", "error-2", "info-1", "info-2"}))
+
+	q.close()
+	_, ok := q.pop()
+	g.Expect(ok).To(gomega.BeFalse())
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityError}, "")).To(gomega.BeFalse())
+}
+
+func TestDispatchQueue_run(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	n := &recordingNotifier{posted: make(chan events.Event, 10)}
+	q := newDispatchQueue(logf.Log, 0)
+	done := make(chan struct{})
+	go func() {
+		q.run(2)
+		close(done)
+	}()
+
+	g.Expect(q.push(n, events.Event{Severity: events.EventSeverityError, Message: "failed"}, "")).To(gomega.BeTrue())
+	g.Eventually(n.posted).Should(gomega.Receive())
+
+	q.close()
+	g.Eventually(done, time.Second).Should(gomega.BeClosed())
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// objectEnricher copies the labels and annotations of the involved object
+// of an event to the notification metadata, the object metadata is fetched
+// from the cache once, when an alert first selects some keys.
+type objectEnricher struct {
+	kubeClient client.Client
+	event      *events.Event
+
+	object *metav1.PartialObjectMetadata
+	err    error
+}
+
+func newObjectEnricher(kubeClient client.Client, event *events.Event) *objectEnricher {
+	return &objectEnricher{kubeClient: kubeClient, event: event}
+}
+
+// enrich adds the labels and annotations of the involved object selected by
+// the alert to the notification metadata, the metadata of the event takes
+// precedence and the keys missing from the object are skipped.
+func (e *objectEnricher) enrich(ctx context.Context, selection *v1beta1.InvolvedObjectMetadata, notification *events.Event) error {
+	if selection == nil || (len(selection.Labels) == 0 && len(selection.Annotations) == 0) {
+		return nil
+	}
+
+	object, err := e.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	add := func(keys []string, values map[string]string) {
+		for _, key := range keys {
+			value, ok := values[key]
+			if !ok {
+				continue
+			}
+			if notification.Metadata == nil {
+				notification.Metadata = make(map[string]string)
+			}
+			if _, ok := notification.Metadata[key]; !ok {
+				notification.Metadata[key] = value
+			}
+		}
+	}
+	add(selection.Labels, object.Labels)
+	add(selection.Annotations, object.Annotations)
+	return nil
+}
+
+func (e *objectEnricher) get(ctx context.Context) (*metav1.PartialObjectMetadata, error) {
+	if e.object != nil || e.err != nil {
+		return e.object, e.err
+	}
+
+	ref := e.event.InvolvedObject
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		e.err = fmt.Errorf("invalid involved object API version %s: %w", ref.APIVersion, err)
+		return nil, e.err
+	}
+
+	object := &metav1.PartialObjectMetadata{}
+	object.SetGroupVersionKind(gv.WithKind(ref.Kind))
+	name := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if err := e.kubeClient.Get(ctx, name, object); err != nil {
+		e.err = fmt.Errorf("failed to get %s %s, error: %w", ref.Kind, name, err)
+		return nil, e.err
+	}
+	e.object = object
+	return object, nil
+}
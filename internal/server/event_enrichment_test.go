@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestObjectEnricher_enrich(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("kustomize.toolkit.fluxcd.io/v1beta1")
+	object.SetKind("Kustomization")
+	object.SetName("apps")
+	object.SetNamespace("flux-system")
+	object.SetLabels(map[string]string{"team": "payments", "tier": "backend"})
+	object.SetAnnotations(map[string]string{"owner": "payments@example.com"})
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(runtime.NewScheme()).
+		WithObjects(object).
+		Build()
+
+	event := &events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "kustomize.toolkit.fluxcd.io/v1beta1",
+			Kind:       "Kustomization",
+			Name:       "apps",
+			Namespace:  "flux-system",
+		},
+		Metadata: map[string]string{"tier": "frontend"},
+	}
+	enricher := newObjectEnricher(kubeClient, event)
+
+	notification := *event.DeepCopy()
+	err := enricher.enrich(context.TODO(), &v1beta1.InvolvedObjectMetadata{
+		Labels:      []string{"team", "tier", "missing"},
+		Annotations: []string{"owner"},
+	}, &notification)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(notification.Metadata).To(gomega.Equal(map[string]string{
+		"team":  "payments",
+		"tier":  "frontend",
+		"owner": "payments@example.com",
+	}))
+
+	// the alerts without a selection don't fetch the object
+	notification = *event.DeepCopy()
+	enricher = newObjectEnricher(kubeClient, &events.Event{})
+	g.Expect(enricher.enrich(context.TODO(), nil, &notification)).To(gomega.Succeed())
+	g.Expect(notification.Metadata).To(gomega.Equal(event.Metadata))
+
+	event.InvolvedObject.Name = "missing"
+	notification = *event.DeepCopy()
+	enricher = newObjectEnricher(kubeClient, event)
+	err = enricher.enrich(context.TODO(), &v1beta1.InvolvedObjectMetadata{Labels: []string{"team"}}, &notification)
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(notification.Metadata).To(gomega.Equal(event.Metadata))
+}
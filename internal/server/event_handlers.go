@@ -18,22 +18,21 @@ package server
 
 import (
 	"context"
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"regexp"
 	"time"
 
-	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
-	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/events"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/alerttemplate"
+	"github.com/fluxcd/notification-controller/internal/exclusions"
+	"github.com/fluxcd/notification-controller/internal/maintenance"
 	"github.com/fluxcd/notification-controller/internal/notifier"
 )
 
@@ -58,178 +57,323 @@ func (s *EventServer) handleEvent() func(w http.ResponseWriter, r *http.Request)
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
-		var allAlerts v1beta1.AlertList
-		err = s.kubeClient.List(ctx, &allAlerts)
-		if err != nil {
-			s.logger.Error(err, "listing alerts failed")
+		if err := s.Publish(ctx, *event); err != nil {
+			s.logger.Error(err, "dispatching the event failed")
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		// find matching alerts
-		alerts := make([]v1beta1.Alert, 0)
-	each_alert:
-		for _, alert := range allAlerts.Items {
-			// skip suspended and not ready alerts
-			isReady := apimeta.IsStatusConditionTrue(alert.Status.Conditions, meta.ReadyCondition)
-			if alert.Spec.Suspend || !isReady {
-				continue each_alert
-			}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
 
-			// skip alert if the message matches a regex from the exclusion list
-			if len(alert.Spec.ExclusionList) > 0 {
-				for _, exp := range alert.Spec.ExclusionList {
-					if r, err := regexp.Compile(exp); err == nil {
-						if r.Match([]byte(event.Message)) {
-							continue each_alert
-						}
-					} else {
-						s.logger.Error(err, fmt.Sprintf("failed to compile regex: %s", exp))
-					}
-				}
-			}
+// alertMatch is an alert matching an event, with the verdict
+// of its flap detector for the involved object of the event.
+type alertMatch struct {
+	alert v1beta1.Alert
+	flap  flapResult
+}
 
-			// filter alerts by object and severity
-			for _, source := range alert.Spec.EventSources {
-				if source.Namespace == "" {
-					source.Namespace = alert.Namespace
-				}
-				if (source.Name == "*" || event.InvolvedObject.Name == source.Name) &&
-					event.InvolvedObject.Namespace == source.Namespace &&
-					event.InvolvedObject.Kind == source.Kind {
-					if event.Severity == alert.Spec.EventSeverity ||
-						alert.Spec.EventSeverity == events.EventSeverityInfo {
-						alerts = append(alerts, alert)
-					}
+// Publish dispatches the event to the providers of the matching alerts,
+// or of the provider bindings of its namespace.
+func (s *EventServer) Publish(ctx context.Context, e events.Event) error {
+	event := &e
+	matches, err := s.filterAlerts(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) == 0 {
+		s.logger.Info("Discarding event, no alerts found for the involved object",
+			"reconciler kind", event.InvolvedObject.Kind,
+			"name", event.InvolvedObject.Name,
+			"namespace", event.InvolvedObject.Namespace)
+		return nil
+	}
+
+	s.logger.Info(fmt.Sprintf("Dispatching event: %s", event.Message),
+		"reconciler kind", event.InvolvedObject.Kind,
+		"name", event.InvolvedObject.Name,
+		"namespace", event.InvolvedObject.Namespace)
+
+	defaults := s.eventMetadata(ctx)
+	enricher := newObjectEnricher(s.kubeClient, event)
+	for _, match := range matches {
+		if s.isSuppressed(ctx, match.alert, *event) {
+			continue
+		}
+		notification, skipped, ok := s.sample(match, *event)
+		if !ok {
+			continue
+		}
+		s.dispatchAlert(ctx, enricher, defaults, match, *event, notification, skipped)
+	}
+	return nil
+}
+
+// filterAlerts returns the ready alerts matching the event, or the alerts of
+// the provider bindings of its namespace when none matches. The flap detectors
+// of the alerts observe the events of all severities, so that the alerts on
+// errors detect the flapping objects.
+func (s *EventServer) filterAlerts(ctx context.Context, event *events.Event) ([]alertMatch, error) {
+	var allAlerts v1beta1.AlertList
+	if err := s.kubeClient.List(ctx, &allAlerts); err != nil {
+		return nil, fmt.Errorf("listing alerts failed: %w", err)
+	}
+
+	var err error
+	matches := make([]alertMatch, 0)
+	sources := newSourceMatcher(s.kubeClient, event)
+each_alert:
+	for _, alert := range allAlerts.Items {
+		// skip suspended and not ready alerts
+		isReady := apimeta.IsStatusConditionTrue(alert.Status.Conditions, meta.ReadyCondition)
+		if alert.Spec.Suspend || !isReady {
+			continue each_alert
+		}
+
+		// apply the defaults of the alert template
+		alert, err = alerttemplate.Resolve(ctx, s.kubeClient, alert)
+		if err != nil {
+			s.logger.Error(err, "failed to resolve alert template",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace)
+			continue each_alert
+		}
+
+		// skip alert if the message matches a regex from the exclusion lists
+		if s.isExcluded(alert.Spec.ExclusionList, event.Message) ||
+			s.isExcludedByRef(ctx, alert, event.Message) {
+			continue each_alert
+		}
+
+		// filter alerts by object and severity
+		for _, source := range alert.Spec.EventSources {
+			matched, err := sources.matches(ctx, source, alert.Namespace)
+			if err != nil {
+				s.logger.Error(err, "failed to match event source",
+					"reconciler kind", v1beta1.AlertKind,
+					"name", alert.Name,
+					"namespace", alert.Namespace)
+				continue
+			}
+			if matched {
+				verdict, changes := s.flaps.observe(alert, *event, time.Now())
+				if verdict == flapStarted || event.Severity == alert.Spec.EventSeverity ||
+					alert.Spec.EventSeverity == events.EventSeverityInfo {
+					matches = append(matches, alertMatch{alert: alert, flap: flapResult{verdict: verdict, changes: changes}})
 				}
+				break
 			}
 		}
+	}
 
-		if len(alerts) == 0 {
-			s.logger.Info("Discarding event, no alerts found for the involved object",
+	// fall back to the provider bindings of the namespace
+	if len(matches) == 0 {
+		alerts, err := s.bindingAlerts(ctx, event)
+		if err != nil {
+			s.logger.Error(err, "failed to match provider bindings",
 				"reconciler kind", event.InvolvedObject.Kind,
 				"name", event.InvolvedObject.Name,
 				"namespace", event.InvolvedObject.Namespace)
-			w.WriteHeader(http.StatusAccepted)
-			return
 		}
+		for _, alert := range alerts {
+			matches = append(matches, alertMatch{alert: alert})
+		}
+	}
+	return matches, nil
+}
 
-		s.logger.Info(fmt.Sprintf("Dispatching event: %s", event.Message),
-			"reconciler kind", event.InvolvedObject.Kind,
-			"name", event.InvolvedObject.Name,
-			"namespace", event.InvolvedObject.Namespace)
+// isSuppressed returns true if a maintenance window of the alert is active,
+// or if the event is a duplicate of an event notified by the alert.
+func (s *EventServer) isSuppressed(ctx context.Context, alert v1beta1.Alert, event events.Event) bool {
+	window, err := maintenance.ActiveWindow(ctx, s.kubeClient, alert.Namespace, alert.Spec.MaintenanceWindowSelector, time.Now())
+	if err != nil {
+		s.logger.Error(err, "failed to evaluate maintenance windows",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+	} else if window != nil {
+		s.logger.Info(fmt.Sprintf("Discarding event, maintenance window '%s' is active", window.Name),
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+		return true
+	}
 
-		// dispatch notifications
-		for _, alert := range alerts {
-			var provider v1beta1.Provider
-			providerName := types.NamespacedName{Namespace: alert.Namespace, Name: alert.Spec.ProviderRef.Name}
+	if s.deduplicator.duplicate(alert, event, time.Now()) {
+		s.logger.V(1).Info("Discarding event, duplicate of a notified event",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+		return true
+	}
+	return false
+}
 
-			err = s.kubeClient.Get(ctx, providerName, &provider)
-			if err != nil {
-				s.logger.Error(err, "failed to read provider",
-					"reconciler kind", v1beta1.ProviderKind,
-					"name", providerName.Name,
-					"namespace", providerName.Namespace)
-				continue
-			}
+// sample returns the notification of the event, which is the flapping event
+// when the object started flapping, and the number of info events skipped by
+// the sampling of the alert. The notification isn't sent when the object is
+// flapping or when the event isn't part of the alert sample.
+func (s *EventServer) sample(match alertMatch, event events.Event) (events.Event, int, bool) {
+	alert := match.alert
+	notification := *event.DeepCopy()
+	switch match.flap.verdict {
+	case flapSuppressed:
+		s.logger.V(1).Info("Discarding event, the involved object is flapping",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+		return notification, 0, false
+	case flapStarted:
+		notification = flappingEvent(alert, event, match.flap.changes)
+	}
 
-			webhook := provider.Spec.Address
-			token := ""
-			if provider.Spec.SecretRef != nil {
-				var secret corev1.Secret
-				secretName := types.NamespacedName{Namespace: alert.Namespace, Name: provider.Spec.SecretRef.Name}
-
-				err = s.kubeClient.Get(ctx, secretName, &secret)
-				if err != nil {
-					s.logger.Error(err, "failed to read secret",
-						"reconciler kind", v1beta1.ProviderKind,
-						"name", providerName.Name,
-						"namespace", providerName.Namespace)
-					continue
-				}
+	send, skipped := s.sampler.sample(alert, notification)
+	if !send {
+		s.logger.V(1).Info("Discarding event, not part of the alert sample",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+		return notification, 0, false
+	}
+	return notification, skipped, true
+}
 
-				if address, ok := secret.Data["address"]; ok {
-					webhook = string(address)
-				}
+// dispatchAlert sends the notification to the providers of the alert
+// matching it, with the metadata of the alert and the default metadata.
+func (s *EventServer) dispatchAlert(ctx context.Context, enricher *objectEnricher, defaults map[string]string,
+	match alertMatch, event events.Event, notification events.Event, skipped int) {
+	alert := match.alert
+
+	// the labels and annotations of the involved object are added
+	// before the providers are selected, so that the conditions
+	// can route the notifications on them
+	if err := enricher.enrich(ctx, alert.Spec.InvolvedObjectMetadata, &notification); err != nil {
+		s.logger.Error(err, "failed to read the involved object metadata",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+	}
 
-				if t, ok := secret.Data["token"]; ok {
-					token = string(t)
-				}
-			}
+	// the providers of the alert matching the event, a fanout provider
+	// delivers the notification to each of its providers, the providers
+	// that can't be read don't block the others
+	providers, err := alertProviders(ctx, s.kubeClient, s.conditions, alert, notification)
+	if err != nil {
+		s.logger.Error(err, "failed to resolve alert providers",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+	}
 
-			var certPool *x509.CertPool
-			if provider.Spec.CertSecretRef != nil {
-				var secret corev1.Secret
-				secretName := types.NamespacedName{Namespace: alert.Namespace, Name: provider.Spec.CertSecretRef.Name}
-
-				err = s.kubeClient.Get(ctx, secretName, &secret)
-				if err != nil {
-					s.logger.Error(err, "failed to read secret",
-						"reconciler kind", v1beta1.ProviderKind,
-						"name", providerName.Name,
-						"namespace", providerName.Namespace)
-					continue
-				}
+	addAlertMetadata(alert, defaults, &notification)
 
-				caFile, ok := secret.Data["caFile"]
-				if !ok {
-					s.logger.Error(err, "failed to read secret key caFile",
-						"reconciler kind", v1beta1.ProviderKind,
-						"name", providerName.Name,
-						"namespace", providerName.Namespace)
-					continue
-				}
+	for _, provider := range providers {
+		// the providers with a status board summarise the events,
+		// the alerts in dry run mode log the notifications instead
+		if provider.Spec.StatusBoard != nil && !alert.Spec.DryRun {
+			s.statusBoards.record(provider, notification)
+			continue
+		}
+		// the archive providers write the events in batches
+		if provider.Spec.Type == v1beta1.ArchiveProvider && !alert.Spec.DryRun {
+			s.archives.record(provider, notification, time.Now())
+			continue
+		}
+		s.dispatchProvider(ctx, match, event, notification, provider, skipped)
+	}
+}
 
-				certPool = x509.NewCertPool()
-				ok = certPool.AppendCertsFromPEM(caFile)
-				if !ok {
-					s.logger.Error(err, "could not append to cert pool",
-						"reconciler kind", v1beta1.ProviderKind,
-						"name", providerName.Name,
-						"namespace", providerName.Namespace)
-					continue
-				}
-			}
+// dispatchProvider renders the notification for the provider and sends it.
+func (s *EventServer) dispatchProvider(ctx context.Context, match alertMatch, event events.Event,
+	notification events.Event, provider v1beta1.Provider, skipped int) {
+	alert := match.alert
+	deliveryID := notifier.NewDeliveryID()
+	sender, err := s.providers.notifier(ctx, provider, &alert, deliveryID)
+	if err != nil {
+		s.logger.Error(err, "failed to initialise provider",
+			"reconciler kind", v1beta1.ProviderKind,
+			"name", provider.Name,
+			"namespace", provider.Namespace)
+		return
+	}
 
-			if webhook == "" {
-				s.logger.Error(nil, "provider has no address",
-					"reconciler kind", v1beta1.ProviderKind,
-					"name", providerName.Name,
-					"namespace", providerName.Namespace)
-				continue
+	// each provider renders its own template, in its own locale
+	message := *notification.DeepCopy()
+	locale := provider.Spec.Locale
+	if match.flap.verdict == flapStarted {
+		message.Message = flappingMessage(locale, alert, event.InvolvedObject, match.flap.changes)
+	}
+	if message.Severity != events.EventSeverityError {
+		for k, v := range samplingMetadata(alert, skipped, locale) {
+			if message.Metadata == nil {
+				message.Metadata = make(map[string]string)
 			}
+			message.Metadata[k] = v
+		}
+	}
+	if err := renderMessage(ctx, s.kubeClient, s.templates, alert, provider, &message); err != nil {
+		s.logger.Error(err, "failed to render message template, sending the event message",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+	}
 
-			factory := notifier.NewFactory(webhook, provider.Spec.Proxy, provider.Spec.Username, provider.Spec.Channel, token, certPool)
-			sender, err := factory.Notifier(provider.Spec.Type)
-			if err != nil {
-				s.logger.Error(err, "failed to initialise provider",
-					"reconciler kind", v1beta1.ProviderKind,
-					"name", providerName.Name,
-					"namespace", providerName.Namespace)
-				continue
-			}
+	s.logger.V(1).Info(fmt.Sprintf("Dispatching notification to provider '%s/%s'", provider.Namespace, provider.Name),
+		"reconciler kind", event.InvolvedObject.Kind,
+		"name", event.InvolvedObject.Name,
+		"namespace", event.InvolvedObject.Namespace,
+		"delivery id", deliveryID)
+	s.dispatch(s.health.trackDelivery(provider, sender), message, deliveryID)
+}
 
-			notification := *event.DeepCopy()
-			if alert.Spec.Summary != "" {
-				if notification.Metadata == nil {
-					notification.Metadata = map[string]string{
-						"summary": alert.Spec.Summary,
-					}
-				} else {
-					notification.Metadata["summary"] = alert.Spec.Summary
-				}
+// addAlertMetadata adds the metadata and the summary of the alert, and the
+// default metadata, to the notification, the metadata of the event takes
+// precedence over the metadata of the alert, which takes precedence over
+// the default metadata.
+func addAlertMetadata(alert v1beta1.Alert, defaults map[string]string, notification *events.Event) {
+	for k, v := range alert.Spec.Metadata {
+		if notification.Metadata == nil {
+			notification.Metadata = make(map[string]string)
+		}
+		if _, ok := notification.Metadata[k]; !ok {
+			notification.Metadata[k] = v
+		}
+	}
+	for k, v := range defaults {
+		if notification.Metadata == nil {
+			notification.Metadata = make(map[string]string)
+		}
+		if _, ok := notification.Metadata[k]; !ok {
+			notification.Metadata[k] = v
+		}
+	}
+	if alert.Spec.Summary != "" {
+		if notification.Metadata == nil {
+			notification.Metadata = map[string]string{
+				"summary": alert.Spec.Summary,
 			}
-
-			go func(n notifier.Interface, e events.Event) {
-				if err := n.Post(e); err != nil {
-					s.logger.Error(err, "failed to send notification",
-						"reconciler kind", event.InvolvedObject.Kind,
-						"name", event.InvolvedObject.Name,
-						"namespace", event.InvolvedObject.Namespace)
-				}
-			}(sender, notification)
+		} else {
+			notification.Metadata["summary"] = alert.Spec.Summary
 		}
+	}
+}
 
-		w.WriteHeader(http.StatusAccepted)
+// isExcludedByRef returns true if the message matches the exclusion list
+// referenced by the alert, the list is ignored when it can't be loaded.
+func (s *EventServer) isExcludedByRef(ctx context.Context, alert v1beta1.Alert, message string) bool {
+	if alert.Spec.ExclusionListRef == nil {
+		return false
+	}
+	expressions, err := s.exclusions.Get(ctx, s.kubeClient, *alert.Spec.ExclusionListRef, alert.Namespace)
+	if err != nil {
+		s.logger.Error(err, "failed to load the exclusion list",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+		return false
 	}
+	return exclusions.Match(expressions, message)
 }
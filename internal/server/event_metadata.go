@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"os"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// envReference matches the '${NAME}' references to the environment variables
+// of the controller, e.g. the ones set from the Downward API.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// WithEventMetadata makes the server add the metadata to every notification,
+// from the flags and from the data of the ConfigMap, which takes precedence.
+// The values can reference the environment variables of the controller with
+// '${NAME}'. The ConfigMap is read for each event, it's ignored when its name
+// is empty. The metadata of the events and of the alerts takes precedence.
+func (s *EventServer) WithEventMetadata(static map[string]string, configMap types.NamespacedName) {
+	s.metadata = static
+	s.metadataConfigMap = configMap
+}
+
+// eventMetadata returns the metadata added by the controller to the notifications.
+func (s *EventServer) eventMetadata(ctx context.Context) map[string]string {
+	if len(s.metadata) == 0 && s.metadataConfigMap.Name == "" {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(s.metadata))
+	for k, v := range s.metadata {
+		metadata[k] = expandEnv(v)
+	}
+
+	if s.metadataConfigMap.Name != "" {
+		var cm corev1.ConfigMap
+		if err := s.kubeClient.Get(ctx, s.metadataConfigMap, &cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				s.logger.Error(err, "failed to read the event metadata ConfigMap",
+					"name", s.metadataConfigMap.Name,
+					"namespace", s.metadataConfigMap.Namespace)
+			}
+		}
+		for k, v := range cm.Data {
+			metadata[k] = expandEnv(v)
+		}
+	}
+	return metadata
+}
+
+// expandEnv replaces the '${NAME}' references with the value of the environment
+// variables, the other '$' characters are kept as is.
+func expandEnv(value string) string {
+	return envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		return os.Getenv(envReference.FindStringSubmatch(ref)[1])
+	})
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestEventServer_eventMetadata(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(os.Setenv("TEST_NODE_NAME", "node-1")).To(gomega.Succeed())
+	defer os.Unsetenv("TEST_NODE_NAME")
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "event-metadata", Namespace: "flux-system"},
+		Data: map[string]string{
+			"node":    "${TEST_NODE_NAME}",
+			"cluster": "production",
+		},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+
+	s := &EventServer{logger: logf.Log, kubeClient: kubeClient}
+	g.Expect(s.eventMetadata(context.TODO())).To(gomega.BeNil())
+
+	s.WithEventMetadata(map[string]string{"cluster": "staging", "version": "v0.13.0", "price": "$5"},
+		types.NamespacedName{Name: "event-metadata", Namespace: "flux-system"})
+	g.Expect(s.eventMetadata(context.TODO())).To(gomega.Equal(map[string]string{
+		"node":    "node-1",
+		"cluster": "production",
+		"version": "v0.13.0",
+		"price":   "$5",
+	}))
+
+	// a missing ConfigMap is ignored
+	s.WithEventMetadata(map[string]string{"version": "v0.13.0"}, types.NamespacedName{Name: "missing", Namespace: "flux-system"})
+	g.Expect(s.eventMetadata(context.TODO())).To(gomega.Equal(map[string]string{"version": "v0.13.0"}))
+}
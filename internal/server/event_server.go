@@ -19,13 +19,10 @@ package server
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -33,25 +30,69 @@ import (
 	"github.com/sethvargo/go-limiter/httplimit"
 	"github.com/slok/go-http-metrics/middleware"
 	"github.com/slok/go-http-metrics/middleware/std"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/exclusions"
+	"github.com/fluxcd/notification-controller/internal/templates"
 )
 
 // EventServer handles event POST requests
 type EventServer struct {
+	servingTLS
+
 	port       string
 	logger     logr.Logger
 	kubeClient client.Client
+	providers  *Providers
+	conditions *cel.Cache
+	templates  *templates.Library
+
+	// exclusions, flaps, deduplicator and sampler hold the state of
+	// the alerts filtering the events.
+	exclusions   *exclusions.Library
+	flaps        *flapDetector
+	deduplicator *eventDeduplicator
+	sampler      *eventSampler
+
+	// statusBoards, archives and health receive the events of the status
+	// board and archive providers, and the problems of the server.
+	statusBoards *StatusBoards
+	archives     *Archives
+	health       *HealthEvents
+
+	workers int
+	queue   *dispatchQueue
+
+	// metadata and metadataConfigMap are the metadata added to every notification.
+	metadata          map[string]string
+	metadataConfigMap types.NamespacedName
 }
 
-// NewEventServer returns an HTTP server that handles events
-func NewEventServer(port string, logger logr.Logger, kubeClient client.Client) *EventServer {
-	return &EventServer{
-		port:       port,
-		logger:     logger.WithName("event-server"),
-		kubeClient: kubeClient,
+// NewEventServer returns an HTTP server that handles events,
+// the notifications are sent by the given number of workers
+// which hold at most maxQueued info notifications.
+func NewEventServer(port string, logger logr.Logger, kubeClient client.Client, workers, maxQueued int) *EventServer {
+	s := &EventServer{
+		port:         port,
+		logger:       logger.WithName("event-server"),
+		kubeClient:   kubeClient,
+		providers:    NewProviders(kubeClient, nil),
+		conditions:   newConditionCache(),
+		templates:    templates.NewLibrary(false),
+		exclusions:   exclusions.NewLibrary(),
+		flaps:        newFlapDetector(),
+		deduplicator: newEventDeduplicator(),
+		sampler:      newEventSampler(),
+		workers:      workers,
+	}
+	if workers > 0 {
+		s.queue = newDispatchQueue(s.logger, maxQueued)
 	}
+	return s
 }
 
 // ListenAndServe starts the HTTP server on the specified port
@@ -62,6 +103,7 @@ func (s *EventServer) ListenAndServe(stopCh <-chan struct{}, mdlw middleware.Mid
 		os.Exit(1)
 	}
 	mux := http.NewServeMux()
+	mux.Handle(SchemaPath, SchemaHandler())
 	mux.Handle("/", s.logRateLimitMiddleware(limitMiddleware.Handle(http.HandlerFunc(s.handleEvent()))))
 	h := std.Handler("", mdlw, mux)
 	srv := &http.Server{
@@ -70,12 +112,17 @@ func (s *EventServer) ListenAndServe(stopCh <-chan struct{}, mdlw middleware.Mid
 	}
 
 	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		if err := s.listenAndServe(srv); err != http.ErrServerClosed {
 			s.logger.Error(err, "Event server crashed")
 			os.Exit(1)
 		}
 	}()
 
+	if s.queue != nil {
+		go s.queue.run(s.workers)
+		defer s.queue.close()
+	}
+
 	// wait for SIGTERM or SIGINT
 	<-stopCh
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -146,12 +193,5 @@ func eventKeyFunc(r *http.Request) (string, error) {
 
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-	comps := []string{"event", event.InvolvedObject.Name, event.InvolvedObject.Namespace, event.InvolvedObject.Kind, event.Message}
-	revString, ok := event.Metadata["revision"]
-	if ok {
-		comps = append(comps, revString)
-	}
-	val := strings.Join(comps, "/")
-	digest := sha256.Sum256([]byte(val))
-	return fmt.Sprintf("%x", digest), nil
+	return eventFingerprint(*event, defaultFingerprintFields), nil
 }
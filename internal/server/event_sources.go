@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// sourceMatcher matches the involved object of an event against the
+// alert event sources, the labels of the namespaces are only fetched when
+// a source has a namespace selector, and the source of the involved object
+// only when a source matches its consumers.
+type sourceMatcher struct {
+	kubeClient client.Client
+	event      *events.Event
+
+	namespaceLabels map[string]labels.Set
+	consumedSource  *corev1.ObjectReference
+	consumedErr     error
+}
+
+func newSourceMatcher(kubeClient client.Client, event *events.Event) *sourceMatcher {
+	return &sourceMatcher{kubeClient: kubeClient, event: event, namespaceLabels: make(map[string]labels.Set)}
+}
+
+// consumerSourceRefs are the paths to the source reference in the
+// spec of the kinds consuming the Flux sources.
+var consumerSourceRefs = map[string][]string{
+	"Kustomization": {"spec", "sourceRef"},
+	"HelmRelease":   {"spec", "chart", "spec", "sourceRef"},
+}
+
+// matches returns true if the involved object, or the source it consumes
+// when the source matches the consumers, is selected by the source.
+func (m *sourceMatcher) matches(ctx context.Context, source v1beta1.CrossNamespaceObjectReference, alertNamespace string) (bool, error) {
+	matched, err := m.matchesObject(ctx, source, alertNamespace, m.event.InvolvedObject)
+	if err != nil || matched || !source.Consumers {
+		return matched, err
+	}
+	if _, ok := consumerSourceRefs[m.event.InvolvedObject.Kind]; !ok {
+		return false, nil
+	}
+
+	consumed, err := m.consumed(ctx)
+	if err != nil {
+		return false, err
+	}
+	return m.matchesObject(ctx, source, alertNamespace, *consumed)
+}
+
+// matchesObject returns true if the object is selected by the source,
+// the source namespace defaults to the alert namespace.
+func (m *sourceMatcher) matchesObject(ctx context.Context, source v1beta1.CrossNamespaceObjectReference, alertNamespace string, object corev1.ObjectReference) (bool, error) {
+	if source.Kind != "" && source.Kind != "*" && source.Kind != object.Kind {
+		return false, nil
+	}
+	if source.Name != "" && source.Name != "*" && source.Name != object.Name {
+		return false, nil
+	}
+
+	if source.NamespaceSelector == nil {
+		if source.Namespace == "" {
+			source.Namespace = alertNamespace
+		}
+		return source.Namespace == object.Namespace, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(source.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	namespaceLabels, ok := m.namespaceLabels[object.Namespace]
+	if !ok {
+		var namespace corev1.Namespace
+		if err := m.kubeClient.Get(ctx, types.NamespacedName{Name: object.Namespace}, &namespace); err != nil {
+			return false, fmt.Errorf("failed to get namespace %s, error: %w", object.Namespace, err)
+		}
+		namespaceLabels = labels.Set(namespace.Labels)
+		if namespaceLabels == nil {
+			namespaceLabels = labels.Set{}
+		}
+		m.namespaceLabels[object.Namespace] = namespaceLabels
+	}
+	return selector.Matches(namespaceLabels), nil
+}
+
+// consumed returns the reference to the source consumed by the involved
+// object, the source namespace defaults to the involved object namespace.
+func (m *sourceMatcher) consumed(ctx context.Context) (*corev1.ObjectReference, error) {
+	if m.consumedSource != nil || m.consumedErr != nil {
+		return m.consumedSource, m.consumedErr
+	}
+
+	ref := m.event.InvolvedObject
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		m.consumedErr = fmt.Errorf("invalid involved object API version %s: %w", ref.APIVersion, err)
+		return nil, m.consumedErr
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gv.WithKind(ref.Kind))
+	name := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if err := m.kubeClient.Get(ctx, name, object); err != nil {
+		m.consumedErr = fmt.Errorf("failed to get %s %s, error: %w", ref.Kind, name, err)
+		return nil, m.consumedErr
+	}
+
+	sourceRef, ok, err := unstructured.NestedStringMap(object.Object, consumerSourceRefs[ref.Kind]...)
+	if err != nil || !ok {
+		m.consumedErr = fmt.Errorf("%s %s has no source reference", ref.Kind, name)
+		return nil, m.consumedErr
+	}
+	m.consumedSource = &corev1.ObjectReference{
+		Kind:      sourceRef["kind"],
+		Name:      sourceRef["name"],
+		Namespace: sourceRef["namespace"],
+	}
+	if m.consumedSource.Namespace == "" {
+		m.consumedSource.Namespace = ref.Namespace
+	}
+	return m.consumedSource, nil
+}
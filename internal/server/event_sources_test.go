@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestSourceMatcher_matches(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"tenant": "dev"}}}).
+		Build()
+
+	event := &events.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "HelmRelease", Name: "podinfo", Namespace: "apps"},
+	}
+	selector := func(tenant string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": tenant}}
+	}
+
+	tests := []struct {
+		name   string
+		source v1beta1.CrossNamespaceObjectReference
+		want   bool
+	}{
+		{name: "object", source: v1beta1.CrossNamespaceObjectReference{Kind: "HelmRelease", Name: "podinfo"}, want: true},
+		{name: "other kind", source: v1beta1.CrossNamespaceObjectReference{Kind: "Kustomization", Name: "podinfo"}},
+		{name: "other namespace", source: v1beta1.CrossNamespaceObjectReference{Kind: "HelmRelease", Name: "podinfo", Namespace: "default"}},
+		{name: "wildcard name", source: v1beta1.CrossNamespaceObjectReference{Kind: "HelmRelease", Name: "*"}, want: true},
+		{name: "wildcard kind", source: v1beta1.CrossNamespaceObjectReference{Kind: "*", Name: "*"}, want: true},
+		{name: "selector only", source: v1beta1.CrossNamespaceObjectReference{NamespaceSelector: selector("dev")}, want: true},
+		{name: "selector mismatch", source: v1beta1.CrossNamespaceObjectReference{NamespaceSelector: selector("ops")}},
+		{
+			name:   "selector takes precedence over the namespace",
+			source: v1beta1.CrossNamespaceObjectReference{Kind: "*", Namespace: "default", NamespaceSelector: selector("dev")},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			matched, err := newSourceMatcher(kubeClient, event).matches(context.TODO(), tt.source, "apps")
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(matched).To(gomega.Equal(tt.want))
+		})
+	}
+
+	event.InvolvedObject.Namespace = "missing"
+	_, err := newSourceMatcher(kubeClient, event).matches(context.TODO(),
+		v1beta1.CrossNamespaceObjectReference{NamespaceSelector: selector("dev")}, "apps")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestSourceMatcher_matchesConsumers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	newConsumer := func(apiVersion, kind, name string, sourceRef map[string]interface{}, path ...string) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		object.SetAPIVersion(apiVersion)
+		object.SetKind(kind)
+		object.SetName(name)
+		object.SetNamespace("apps")
+		g.Expect(unstructured.SetNestedMap(object.Object, sourceRef, path...)).To(gomega.Succeed())
+		return object
+	}
+	kustomization := newConsumer("kustomize.toolkit.fluxcd.io/v1beta1", "Kustomization", "webapp",
+		map[string]interface{}{"kind": "GitRepository", "name": "fleet", "namespace": "flux-system"}, "spec", "sourceRef")
+	release := newConsumer("helm.toolkit.fluxcd.io/v2beta1", "HelmRelease", "podinfo",
+		map[string]interface{}{"kind": "HelmRepository", "name": "charts"}, "spec", "chart", "spec", "sourceRef")
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(kustomization, release).
+		Build()
+
+	eventOf := func(object *unstructured.Unstructured) *events.Event {
+		return &events.Event{InvolvedObject: corev1.ObjectReference{
+			APIVersion: object.GetAPIVersion(),
+			Kind:       object.GetKind(),
+			Name:       object.GetName(),
+			Namespace:  object.GetNamespace(),
+		}}
+	}
+	fleet := v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "fleet", Namespace: "flux-system", Consumers: true}
+	charts := v1beta1.CrossNamespaceObjectReference{Kind: "HelmRepository", Name: "charts", Consumers: true}
+
+	tests := []struct {
+		name   string
+		event  *events.Event
+		source v1beta1.CrossNamespaceObjectReference
+		want   bool
+	}{
+		{name: "kustomization", event: eventOf(kustomization), source: fleet, want: true},
+		{name: "release in the source namespace", event: eventOf(release), source: charts, want: true},
+		{name: "other source", event: eventOf(release), source: fleet},
+		{
+			name:   "consumers not matched",
+			event:  eventOf(kustomization),
+			source: v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "fleet", Namespace: "flux-system"},
+		},
+		{
+			name: "source events",
+			event: &events.Event{InvolvedObject: corev1.ObjectReference{
+				Kind: "GitRepository", Name: "fleet", Namespace: "flux-system",
+			}},
+			source: fleet,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			matched, err := newSourceMatcher(kubeClient, tt.event).matches(context.TODO(), tt.source, "apps")
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(matched).To(gomega.Equal(tt.want))
+		})
+	}
+
+	missing := eventOf(kustomization)
+	missing.InvolvedObject.Name = "missing"
+	_, err := newSourceMatcher(kubeClient, missing).matches(context.TODO(), fleet, "apps")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
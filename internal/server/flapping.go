@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/i18n"
+)
+
+// FlappingReason is the reason of the notifications sent when an object starts flapping.
+const FlappingReason = "Flapping"
+
+// defaultFlapThreshold is the number of severity changes after which an object is flapping.
+const defaultFlapThreshold = 3
+
+// flapSweepInterval is the interval at which the objects which were stable
+// for a whole window are forgotten.
+const flapSweepInterval = time.Minute
+
+// flapVerdict tells how an event is handled by the flap detector.
+type flapVerdict int
+
+// flapResult is the verdict of the flap detector for an alert, along with
+// the number of severity changes within the window.
+type flapResult struct {
+	verdict flapVerdict
+	changes int
+}
+
+const (
+	// flapSend sends the event notification.
+	flapSend flapVerdict = iota
+	// flapStarted replaces the event notification with a flapping notification.
+	flapStarted
+	// flapSuppressed discards the event notification.
+	flapSuppressed
+)
+
+// flapDetector records the last severity notified per alert and involved object,
+// and the times at which it changed within the stabilization window.
+type flapDetector struct {
+	mu        sync.Mutex
+	objects   map[string]*flapState
+	lastSweep time.Time
+}
+
+type flapState struct {
+	severity    string
+	changes     []time.Time
+	flapping    bool
+	lastEventAt time.Time
+	window      time.Duration
+}
+
+func newFlapDetector() *flapDetector {
+	return &flapDetector{objects: make(map[string]*flapState)}
+}
+
+// observe records the event and returns whether its notification is sent,
+// replaced by a flapping notification or suppressed, along with the number
+// of severity changes within the window.
+func (d *flapDetector) observe(alert v1beta1.Alert, event events.Event, now time.Time) (flapVerdict, int) {
+	if alert.Spec.FlapSuppression == nil {
+		return flapSend, 0
+	}
+	window := alert.Spec.FlapSuppression.Window.Duration
+	threshold := alert.Spec.FlapSuppression.Threshold
+	if threshold < 2 {
+		threshold = defaultFlapThreshold
+	}
+	object := event.InvolvedObject
+	key := fmt.Sprintf("%s/%s/%s/%s/%s", alert.Namespace, alert.Name, object.Kind, object.Namespace, object.Name)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// forget the objects which were stable for a whole window
+	if now.Sub(d.lastSweep) >= flapSweepInterval {
+		for k, state := range d.objects {
+			if now.Sub(state.lastEventAt) >= state.window {
+				delete(d.objects, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	state, ok := d.objects[key]
+	if !ok || now.Sub(state.lastEventAt) >= state.window {
+		d.objects[key] = &flapState{severity: event.Severity, lastEventAt: now, window: window}
+		return flapSend, 0
+	}
+	state.lastEventAt = now
+	state.window = window
+
+	if event.Severity != state.severity {
+		state.severity = event.Severity
+		state.changes = append(state.changes, now)
+	}
+	changes := state.changes[:0]
+	for _, t := range state.changes {
+		if now.Sub(t) < window {
+			changes = append(changes, t)
+		}
+	}
+	state.changes = changes
+
+	switch {
+	case len(state.changes) >= threshold && !state.flapping:
+		state.flapping = true
+		return flapStarted, len(state.changes)
+	case len(state.changes) >= threshold:
+		return flapSuppressed, len(state.changes)
+	}
+	state.flapping = false
+	return flapSend, len(state.changes)
+}
+
+// flappingEvent returns the notification sent instead of the event
+// when the involved object starts flapping.
+func flappingEvent(alert v1beta1.Alert, event events.Event, changes int) events.Event {
+	notification := *event.DeepCopy()
+	object := event.InvolvedObject
+	notification.Severity = events.EventSeverityError
+	notification.Reason = FlappingReason
+	notification.Message = flappingMessage(i18n.DefaultLocale, alert, object, changes)
+	if notification.Metadata == nil {
+		notification.Metadata = make(map[string]string)
+	}
+	notification.Metadata["flapping"] = "true"
+	return notification
+}
+
+// flappingMessage returns the message of the flapping notification in the locale.
+func flappingMessage(locale string, alert v1beta1.Alert, object corev1.ObjectReference, changes int) string {
+	return i18n.Sprintf(locale, i18n.Flapping, object.Kind, object.Name, changes, alert.Spec.FlapSuppression.Window.Duration)
+}
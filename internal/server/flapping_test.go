@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestFlapDetector_observe(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec: v1beta1.AlertSpec{
+			FlapSuppression: &v1beta1.FlapSuppression{
+				Window:    metav1.Duration{Duration: 10 * time.Minute},
+				Threshold: 3,
+			},
+		},
+	}
+	newEvent := func(severity string) events.Event {
+		return events.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "podinfo", Namespace: "default"},
+			Severity:       severity,
+			Message:        "reconciliation " + severity,
+		}
+	}
+
+	detector := newFlapDetector()
+	now := time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC)
+	observe := func(severity string) flapVerdict {
+		now = now.Add(time.Minute)
+		verdict, _ := detector.observe(alert, newEvent(severity), now)
+		return verdict
+	}
+
+	g.Expect(observe(events.EventSeverityInfo)).To(gomega.Equal(flapSend))
+	g.Expect(observe(events.EventSeverityError)).To(gomega.Equal(flapSend))
+	g.Expect(observe(events.EventSeverityInfo)).To(gomega.Equal(flapSend))
+	g.Expect(observe(events.EventSeverityError)).To(gomega.Equal(flapStarted))
+	g.Expect(observe(events.EventSeverityInfo)).To(gomega.Equal(flapSuppressed))
+	g.Expect(observe(events.EventSeverityError)).To(gomega.Equal(flapSuppressed))
+
+	// the object is stable once the changes leave the window
+	now = now.Add(9 * time.Minute)
+	g.Expect(observe(events.EventSeverityError)).To(gomega.Equal(flapSend))
+
+	// the other alerts are not affected
+	other := alert
+	other.Name = "other"
+	other.Spec.FlapSuppression = nil
+	verdict, _ := detector.observe(other, newEvent(events.EventSeverityInfo), now)
+	g.Expect(verdict).To(gomega.Equal(flapSend))
+}
+
+func TestFlapDetector_expire(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec: v1beta1.AlertSpec{
+			FlapSuppression: &v1beta1.FlapSuppression{
+				Window:    metav1.Duration{Duration: 10 * time.Minute},
+				Threshold: 2,
+			},
+		},
+	}
+	newEvent := func(name, severity string) events.Event {
+		return events.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: name, Namespace: "default"},
+			Severity:       severity,
+		}
+	}
+
+	detector := newFlapDetector()
+	now := time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC)
+	detector.observe(alert, newEvent("podinfo", events.EventSeverityError), now)
+	detector.observe(alert, newEvent("webapp", events.EventSeverityError), now)
+	g.Expect(detector.objects).To(gomega.HaveLen(2))
+
+	// the state of an object stable for a whole window is reset on its next event,
+	// the other objects are only forgotten by the next sweep
+	now = now.Add(10 * time.Minute)
+	detector.lastSweep = now
+	verdict, changes := detector.observe(alert, newEvent("podinfo", events.EventSeverityInfo), now)
+	g.Expect(verdict).To(gomega.Equal(flapSend))
+	g.Expect(changes).To(gomega.Equal(0))
+	g.Expect(detector.objects).To(gomega.HaveLen(2))
+
+	now = now.Add(flapSweepInterval)
+	detector.observe(alert, newEvent("podinfo", events.EventSeverityInfo), now)
+	g.Expect(detector.objects).To(gomega.HaveLen(1))
+	g.Expect(detector.objects).To(gomega.HaveKey("default/apps/Kustomization/default/podinfo"))
+}
+
+func TestFlappingEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	alert := v1beta1.Alert{
+		Spec: v1beta1.AlertSpec{
+			FlapSuppression: &v1beta1.FlapSuppression{Window: metav1.Duration{Duration: 10 * time.Minute}},
+		},
+	}
+	event := events.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "HelmRelease", Name: "podinfo", Namespace: "default"},
+		Severity:       events.EventSeverityInfo,
+		Message:        "upgrade succeeded",
+	}
+
+	notification := flappingEvent(alert, event, 3)
+	g.Expect(notification.Severity).To(gomega.Equal(events.EventSeverityError))
+	g.Expect(notification.Reason).To(gomega.Equal(FlappingReason))
+	g.Expect(notification.Message).To(gomega.Equal("HelmRelease/podinfo is flapping, its status changed 3 times within 10m0s, " +
+		"the notifications are suppressed until it is stable"))
+	g.Expect(notification.Metadata).To(gomega.HaveKeyWithValue("flapping", "true"))
+	g.Expect(event.Metadata).To(gomega.BeNil())
+}
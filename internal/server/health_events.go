@@ -0,0 +1,366 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/alerttemplate"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/i18n"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+const (
+	// QueueOverflowReason is the reason of the health events reporting
+	// the notifications discarded by a full dispatch queue.
+	QueueOverflowReason = "DispatchQueueOverflow"
+
+	// DeliveryFailureReason is the reason of the health events reporting
+	// the notifications failing to be delivered to a provider.
+	DeliveryFailureReason = "ProviderDeliveryFailure"
+)
+
+// healthProblem is a problem of the controller, the provider
+// is empty for the problems of the dispatch queue.
+type healthProblem struct {
+	reason   string
+	provider types.NamespacedName
+}
+
+// healthReport is a problem that occurred at least the threshold number
+// of times within an interval, or that stopped occurring.
+type healthReport struct {
+	healthProblem
+	count    int
+	resolved bool
+}
+
+// healthTracker counts the problems hit by the event server of this replica,
+// the counts are reported and reset by the HealthEvents runnable.
+type healthTracker struct {
+	mu       sync.Mutex
+	counts   map[healthProblem]int
+	reported map[healthProblem]bool
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		counts:   make(map[healthProblem]int),
+		reported: make(map[healthProblem]bool),
+	}
+}
+
+func (t *healthTracker) record(problem healthProblem) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[problem]++
+}
+
+// collect resets the counts and returns the problems that occurred at least
+// threshold times, and the reported problems that no longer occur.
+func (t *healthTracker) collect(threshold int) []healthReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var reports []healthReport
+	for problem, count := range t.counts {
+		if count >= threshold {
+			reports = append(reports, healthReport{healthProblem: problem, count: count})
+			t.reported[problem] = true
+		}
+	}
+	for problem := range t.reported {
+		if t.counts[problem] == 0 {
+			reports = append(reports, healthReport{healthProblem: problem, resolved: true})
+			delete(t.reported, problem)
+		}
+	}
+	t.counts = make(map[healthProblem]int)
+
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if a.reason != b.reason {
+			return a.reason < b.reason
+		}
+		return a.provider.String() < b.provider.String()
+	})
+	return reports
+}
+
+// trackedNotifier records the notifications failing to be delivered to a provider.
+type trackedNotifier struct {
+	notifier.Interface
+	provider types.NamespacedName
+	health   *HealthEvents
+}
+
+func (n *trackedNotifier) Post(event events.Event) error {
+	err := n.Interface.Post(event)
+	if err != nil {
+		n.health.record(healthProblem{reason: DeliveryFailureReason, provider: n.provider})
+	}
+	return err
+}
+
+// HealthEvents sends the sustained problems of the controller, e.g. a full
+// dispatch queue or a failing provider, to the providers of the alerts with
+// health events enabled, so that the team operating the notifications is
+// alerted about the degradation of the notifier itself.
+type HealthEvents struct {
+	interval   time.Duration
+	threshold  int
+	namespace  string
+	logger     logr.Logger
+	kubeClient client.Client
+	providers  *Providers
+	conditions *cel.Cache
+	templates  *templates.Library
+	problems   *healthTracker
+}
+
+// NewHealthEvents returns the health events emitter, a problem is reported
+// when it occurs at least threshold times within the interval, and reported
+// as resolved after an interval without occurrences. The alerts in the
+// controller namespace receive all the problems, the alerts in the other
+// namespaces only the delivery failures of the providers of their namespace.
+func NewHealthEvents(interval time.Duration, threshold int, namespace string, logger logr.Logger,
+	kubeClient client.Client, providers *Providers, library *templates.Library) *HealthEvents {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &HealthEvents{
+		interval:   interval,
+		threshold:  threshold,
+		namespace:  namespace,
+		logger:     logger.WithName("health-events"),
+		kubeClient: kubeClient,
+		providers:  providers,
+		conditions: newConditionCache(),
+		templates:  library,
+		problems:   newHealthTracker(),
+	}
+}
+
+// WithHealthEvents sets the health events the problems of the
+// event server are reported with, they're not reported when nil.
+func (s *EventServer) WithHealthEvents(health *HealthEvents) {
+	s.health = health
+}
+
+// record counts a problem, the problems are ignored when
+// the health events are disabled.
+func (h *HealthEvents) record(problem healthProblem) {
+	if h == nil {
+		return
+	}
+	h.problems.record(problem)
+}
+
+// trackDelivery returns the notifier recording its delivery failures.
+func (h *HealthEvents) trackDelivery(provider v1beta1.Provider, n notifier.Interface) notifier.Interface {
+	if h == nil {
+		return n
+	}
+	return &trackedNotifier{
+		Interface: n,
+		provider:  types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name},
+		health:    h,
+	}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// each replica reports the problems of its own event server.
+func (h *HealthEvents) NeedLeaderElection() bool {
+	return false
+}
+
+// Start reports the problems at every interval until the context is cancelled.
+func (h *HealthEvents) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			h.report(ctx)
+		}
+	}
+}
+
+func (h *HealthEvents) report(ctx context.Context) {
+	reports := h.problems.collect(h.threshold)
+	if len(reports) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var alerts v1beta1.AlertList
+	if err := h.kubeClient.List(ctx, &alerts); err != nil {
+		h.logger.Error(err, "listing alerts failed")
+		return
+	}
+
+	for _, alert := range alerts.Items {
+		if !alert.Spec.HealthEvents || alert.Spec.Suspend ||
+			!apimeta.IsStatusConditionTrue(alert.Status.Conditions, meta.ReadyCondition) {
+			continue
+		}
+
+		alert, err := alerttemplate.Resolve(ctx, h.kubeClient, alert)
+		if err != nil {
+			h.logger.Error(err, "failed to resolve alert template",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace)
+			continue
+		}
+
+		for _, report := range reports {
+			if h.visible(alert, report) {
+				h.send(ctx, alert, report)
+			}
+		}
+	}
+}
+
+// visible returns whether the problem can be reported to the alert, so that
+// the tenants don't receive the problems of the other namespaces.
+func (h *HealthEvents) visible(alert v1beta1.Alert, report healthReport) bool {
+	if h.namespace != "" && alert.Namespace == h.namespace {
+		return true
+	}
+	return report.reason == DeliveryFailureReason && report.provider.Namespace == alert.Namespace
+}
+
+// send posts the health event to the providers of the alert directly,
+// so that the event isn't discarded by the full dispatch queue it reports.
+func (h *HealthEvents) send(ctx context.Context, alert v1beta1.Alert, report healthReport) {
+	event := healthEvent(alert, report, h.interval, i18n.DefaultLocale)
+	providers, err := alertProviders(ctx, h.kubeClient, h.conditions, alert, event)
+	if err != nil {
+		h.logger.Error(err, "failed to resolve alert providers",
+			"reconciler kind", v1beta1.AlertKind,
+			"name", alert.Name,
+			"namespace", alert.Namespace)
+	}
+
+	for _, provider := range providers {
+		// a failing provider isn't sent the report of its own failures
+		if !report.resolved && report.provider.Namespace == provider.Namespace &&
+			report.provider.Name == provider.Name {
+			continue
+		}
+
+		deliveryID := notifier.NewDeliveryID()
+		sender, err := h.providers.notifier(ctx, provider, &alert, deliveryID)
+		if err != nil {
+			h.logger.Error(err, "failed to initialise provider",
+				"reconciler kind", v1beta1.ProviderKind,
+				"name", provider.Name,
+				"namespace", provider.Namespace)
+			continue
+		}
+
+		message := healthEvent(alert, report, h.interval, provider.Spec.Locale)
+		if err := renderMessage(ctx, h.kubeClient, h.templates, alert, provider, &message); err != nil {
+			h.logger.Error(err, "failed to render message template, sending the health event message",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace)
+		}
+
+		if err := sender.Post(message); err != nil {
+			h.logger.Error(err, "failed to send health event",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace,
+				"delivery id", deliveryID)
+		}
+	}
+}
+
+// healthEvent returns the synthetic event reporting the problem, the problems
+// are error events and their resolution info events.
+func healthEvent(alert v1beta1.Alert, report healthReport, interval time.Duration, locale string) events.Event {
+	metadata := make(map[string]string, len(alert.Spec.Metadata)+3)
+	for k, v := range alert.Spec.Metadata {
+		metadata[k] = v
+	}
+	if alert.Spec.Summary != "" {
+		metadata["summary"] = alert.Spec.Summary
+	}
+
+	severity := events.EventSeverityError
+	if report.resolved {
+		severity = events.EventSeverityInfo
+	} else {
+		metadata["count"] = strconv.Itoa(report.count)
+		metadata["interval"] = interval.String()
+	}
+
+	var message string
+	switch report.reason {
+	case QueueOverflowReason:
+		message = i18n.Sprintf(locale, i18n.QueueOverflow, report.count, interval)
+		if report.resolved {
+			message = i18n.Sprintf(locale, i18n.QueueRecovered)
+		}
+	case DeliveryFailureReason:
+		metadata["provider"] = report.provider.String()
+		message = i18n.Sprintf(locale, i18n.DeliveryFailing, report.count, report.provider, interval)
+		if report.resolved {
+			message = i18n.Sprintf(locale, i18n.DeliveryRecovered, report.provider)
+		}
+	}
+
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: v1beta1.GroupVersion.String(),
+			Kind:       v1beta1.AlertKind,
+			Name:       alert.Name,
+			Namespace:  alert.Namespace,
+			UID:        alert.UID,
+		},
+		Severity:            severity,
+		Timestamp:           metav1.Now(),
+		Message:             message,
+		Reason:              report.reason,
+		Metadata:            metadata,
+		ReportingController: reportingController,
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+type failingNotifier struct{}
+
+func (failingNotifier) Post(events.Event) error {
+	return errors.New("unavailable")
+}
+
+func TestHealthTracker_collect(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	slack := types.NamespacedName{Namespace: "default", Name: "slack"}
+	overflow := healthProblem{reason: QueueOverflowReason}
+	failure := healthProblem{reason: DeliveryFailureReason, provider: slack}
+
+	tracker := newHealthTracker()
+	for i := 0; i < 3; i++ {
+		tracker.record(overflow)
+	}
+	tracker.record(failure)
+	g.Expect(tracker.collect(3)).To(gomega.Equal([]healthReport{{healthProblem: overflow, count: 3}}))
+
+	// the reported problems are resolved after an interval without occurrences
+	tracker.record(failure)
+	g.Expect(tracker.collect(3)).To(gomega.Equal([]healthReport{{healthProblem: overflow, resolved: true}}))
+	g.Expect(tracker.collect(3)).To(gomega.BeEmpty())
+}
+
+func TestHealthEvents_report(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	received := make(chan events.Event, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e events.Event
+		g.Expect(json.NewDecoder(r.Body).Decode(&e)).To(gomega.Succeed())
+		received <- e
+	}))
+	defer ts.Close()
+
+	newProvider := func(namespace, name string) *v1beta1.Provider {
+		return &v1beta1.Provider{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1beta1.ProviderSpec{
+				Type:    v1beta1.GenericProvider,
+				Address: ts.URL,
+			},
+		}
+	}
+	newAlert := func(namespace, name, provider string, healthEvents bool) *v1beta1.Alert {
+		alert := &v1beta1.Alert{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1beta1.AlertSpec{
+				ProviderRef:  meta.LocalObjectReference{Name: provider},
+				HealthEvents: healthEvents,
+			},
+		}
+		meta.SetResourceCondition(alert, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+		return alert
+	}
+
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newProvider("flux-system", "webhook"),
+			newProvider("apps", "webhook"),
+			newProvider("apps", "slack"),
+			newProvider("other", "webhook"),
+			newAlert("flux-system", "platform", "webhook", true),
+			newAlert("flux-system", "silent", "webhook", false),
+			newAlert("apps", "tenant", "webhook", true),
+			newAlert("apps", "self", "slack", true),
+			newAlert("other", "tenant", "webhook", true),
+		).
+		Build()
+
+	h := NewHealthEvents(time.Minute, 2, "flux-system", logf.Log, kubeClient, NewProviders(kubeClient, nil), templates.NewLibrary(false))
+	failing := &v1beta1.Provider{ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "apps"}}
+	sender := h.trackDelivery(*failing, failingNotifier{})
+	for i := 0; i < 2; i++ {
+		g.Expect(sender.Post(events.Event{})).NotTo(gomega.Succeed())
+		h.record(healthProblem{reason: QueueOverflowReason})
+	}
+
+	receivedBy := func() map[string]events.Event {
+		got := make(map[string]events.Event)
+		for len(received) > 0 {
+			e := <-received
+			got[e.InvolvedObject.Namespace+"/"+e.InvolvedObject.Name+"/"+e.Reason] = e
+		}
+		return got
+	}
+
+	h.report(context.Background())
+
+	// the tenants only receive the failures of their own providers,
+	// and the failing provider doesn't receive its own failures
+	got := receivedBy()
+	g.Expect(got).To(gomega.HaveLen(3))
+	g.Expect(got).To(gomega.HaveKey("flux-system/platform/" + QueueOverflowReason))
+	g.Expect(got).To(gomega.HaveKey("flux-system/platform/" + DeliveryFailureReason))
+	g.Expect(got).To(gomega.HaveKey("apps/tenant/" + DeliveryFailureReason))
+	e := got["apps/tenant/"+DeliveryFailureReason]
+	g.Expect(e.Severity).To(gomega.Equal(events.EventSeverityError))
+	g.Expect(e.Message).To(gomega.Equal("2 notifications to the provider apps/slack failed in the last 1m0s"))
+	g.Expect(e.Metadata).To(gomega.HaveKeyWithValue("provider", "apps/slack"))
+
+	h.report(context.Background())
+	got = receivedBy()
+	g.Expect(got).To(gomega.HaveLen(4))
+	e = got["apps/self/"+DeliveryFailureReason]
+	g.Expect(e.Severity).To(gomega.Equal(events.EventSeverityInfo))
+	g.Expect(e.Message).To(gomega.Equal("The notifications to the provider apps/slack are delivered again"))
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/alerttemplate"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/i18n"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+// HeartbeatReason is the reason of the synthetic heartbeat events.
+const HeartbeatReason = "Heartbeat"
+
+// Heartbeat periodically sends a synthetic event to the providers
+// of the alerts with heartbeats enabled, so that the recipients can
+// detect when the notification pipeline stops working.
+type Heartbeat struct {
+	interval   time.Duration
+	logger     logr.Logger
+	kubeClient client.Client
+	providers  *Providers
+	conditions *cel.Cache
+	templates  *templates.Library
+}
+
+// NewHeartbeat returns a heartbeat emitter, it must be added to the manager
+// so that it runs on the leader only.
+func NewHeartbeat(interval time.Duration, logger logr.Logger, kubeClient client.Client, providers *Providers,
+	library *templates.Library) *Heartbeat {
+	return &Heartbeat{
+		interval:   interval,
+		logger:     logger.WithName("heartbeat"),
+		kubeClient: kubeClient,
+		providers:  providers,
+		conditions: newConditionCache(),
+		templates:  library,
+	}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface.
+func (h *Heartbeat) NeedLeaderElection() bool {
+	return true
+}
+
+// Start sends the heartbeat events at every interval until the context is cancelled.
+func (h *Heartbeat) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			h.emit(ctx)
+		}
+	}
+}
+
+func (h *Heartbeat) emit(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var alerts v1beta1.AlertList
+	if err := h.kubeClient.List(ctx, &alerts); err != nil {
+		h.logger.Error(err, "listing alerts failed")
+		return
+	}
+
+	for _, alert := range alerts.Items {
+		if !alert.Spec.Heartbeat || alert.Spec.Suspend ||
+			!apimeta.IsStatusConditionTrue(alert.Status.Conditions, meta.ReadyCondition) {
+			continue
+		}
+
+		alert, err := alerttemplate.Resolve(ctx, h.kubeClient, alert)
+		if err != nil {
+			h.logger.Error(err, "failed to resolve alert template",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace)
+			continue
+		}
+
+		event := heartbeatEvent(alert, h.interval)
+		providers, err := alertProviders(ctx, h.kubeClient, h.conditions, alert, event)
+		if err != nil {
+			h.logger.Error(err, "failed to resolve alert providers",
+				"reconciler kind", v1beta1.AlertKind,
+				"name", alert.Name,
+				"namespace", alert.Namespace)
+		}
+
+		for _, provider := range providers {
+			deliveryID := notifier.NewDeliveryID()
+			sender, err := h.providers.notifier(ctx, provider, &alert, deliveryID)
+			if err != nil {
+				h.logger.Error(err, "failed to initialise provider",
+					"reconciler kind", v1beta1.ProviderKind,
+					"name", provider.Name,
+					"namespace", provider.Namespace)
+				continue
+			}
+
+			message := *event.DeepCopy()
+			message.Message = heartbeatMessage(provider.Spec.Locale, h.interval)
+			if err := renderMessage(ctx, h.kubeClient, h.templates, alert, provider, &message); err != nil {
+				h.logger.Error(err, "failed to render message template, sending the heartbeat message",
+					"reconciler kind", v1beta1.AlertKind,
+					"name", alert.Name,
+					"namespace", alert.Namespace)
+			}
+
+			if err := sender.Post(message); err != nil {
+				h.logger.Error(err, "failed to send heartbeat",
+					"reconciler kind", v1beta1.AlertKind,
+					"name", alert.Name,
+					"namespace", alert.Namespace,
+					"delivery id", deliveryID)
+			}
+		}
+	}
+}
+
+// heartbeatEvent returns the synthetic event sent to the alert provider.
+func heartbeatEvent(alert v1beta1.Alert, interval time.Duration) events.Event {
+	metadata := make(map[string]string, len(alert.Spec.Metadata)+2)
+	for k, v := range alert.Spec.Metadata {
+		metadata[k] = v
+	}
+	metadata["interval"] = interval.String()
+	if alert.Spec.Summary != "" {
+		metadata["summary"] = alert.Spec.Summary
+	}
+
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: v1beta1.GroupVersion.String(),
+			Kind:       v1beta1.AlertKind,
+			Name:       alert.Name,
+			Namespace:  alert.Namespace,
+			UID:        alert.UID,
+		},
+		Severity:            events.EventSeverityInfo,
+		Timestamp:           metav1.Now(),
+		Message:             heartbeatMessage(i18n.DefaultLocale, interval),
+		Reason:              HeartbeatReason,
+		Metadata:            metadata,
+		ReportingController: reportingController,
+	}
+}
+
+// heartbeatMessage returns the message of the heartbeat event in the locale.
+func heartbeatMessage(locale string, interval time.Duration) string {
+	return i18n.Sprintf(locale, i18n.Heartbeat, interval)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+func TestHeartbeat_emit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	received := make(chan events.Event, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e events.Event
+		g.Expect(json.NewDecoder(r.Body).Decode(&e)).To(gomega.Succeed())
+		received <- e
+	}))
+	defer ts.Close()
+
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Type:    v1beta1.GenericProvider,
+			Address: ts.URL,
+		},
+	}
+	newAlert := func(name string, heartbeat bool) *v1beta1.Alert {
+		alert := &v1beta1.Alert{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1beta1.AlertSpec{
+				ProviderRef: meta.LocalObjectReference{Name: "webhook"},
+				Heartbeat:   heartbeat,
+			},
+		}
+		meta.SetResourceCondition(alert, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+		return alert
+	}
+
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(provider, newAlert("with-heartbeat", true), newAlert("without-heartbeat", false)).
+		Build()
+
+	h := NewHeartbeat(time.Minute, logf.Log, kubeClient, NewProviders(kubeClient, nil), templates.NewLibrary(false))
+	h.emit(context.Background())
+
+	g.Expect(received).To(gomega.HaveLen(1))
+	e := <-received
+	g.Expect(e.Reason).To(gomega.Equal(HeartbeatReason))
+	g.Expect(e.InvolvedObject.Name).To(gomega.Equal("with-heartbeat"))
+	g.Expect(e.Metadata["interval"]).To(gomega.Equal("1m0s"))
+}
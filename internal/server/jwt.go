@@ -0,0 +1,254 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// jwtClaims holds the registered claims of a JSON Web Token along with
+// the Atlassian Connect query string hash and the Google account email.
+type jwtClaims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Audience      string `json:"aud"`
+	ExpiresAt     int64  `json:"exp"`
+	IssuedAt      int64  `json:"iat"`
+	QSH           string `json:"qsh"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+
+	// Audiences holds all the audiences when the audience claim
+	// is a list, the audience is the first one.
+	Audiences []string `json:"-"`
+}
+
+// UnmarshalJSON decodes the claims, the audience claim can be a string or a list.
+func (c *jwtClaims) UnmarshalJSON(data []byte) error {
+	type claims jwtClaims
+	aux := struct {
+		*claims
+		Audience json.RawMessage `json:"aud"`
+	}{claims: (*claims)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	c.Audience, c.Audiences = "", nil
+	if len(aux.Audience) == 0 || string(aux.Audience) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Audience, &c.Audience); err == nil {
+		c.Audiences = []string{c.Audience}
+		return nil
+	}
+	if err := json.Unmarshal(aux.Audience, &c.Audiences); err != nil {
+		return fmt.Errorf("invalid audience claim: %w", err)
+	}
+	if len(c.Audiences) > 0 {
+		c.Audience = c.Audiences[0]
+	}
+	return nil
+}
+
+// jwt is a decoded JSON Web Token, its signature isn't verified.
+type jwt struct {
+	alg       string
+	kid       string
+	signed    []byte
+	signature []byte
+	claims    jwtClaims
+}
+
+// parseJWT decodes the header, the claims and the signature of a JSON Web Token.
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT")
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode JWT header: %w", err)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, fmt.Errorf("unable to parse JWT header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode JWT signature: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode JWT claims: %w", err)
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("unable to parse JWT claims: %w", err)
+	}
+
+	return &jwt{
+		alg:       h.Alg,
+		kid:       h.Kid,
+		signed:    []byte(parts[0] + "." + parts[1]),
+		signature: signature,
+		claims:    claims,
+	}, nil
+}
+
+// checkExpiry returns an error if the token is expired.
+func (t *jwt) checkExpiry(now time.Time) error {
+	if t.claims.ExpiresAt != 0 && now.Unix() > t.claims.ExpiresAt {
+		return fmt.Errorf("JWT expired at %s", time.Unix(t.claims.ExpiresAt, 0).UTC())
+	}
+	return nil
+}
+
+// verifyHS256JWT checks the signature and expiry of a HS256 signed
+// JSON Web Token and returns its claims.
+func verifyHS256JWT(token string, key []byte, now time.Time) (*jwtClaims, error) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if t.alg != "HS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm '%s'", t.alg)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(t.signed)
+	if !hmac.Equal(t.signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("invalid JWT signature")
+	}
+
+	if err := t.checkExpiry(now); err != nil {
+		return nil, err
+	}
+	return &t.claims, nil
+}
+
+// verifyRS256JWT checks the signature and expiry of a RS256 signed JSON Web Token
+// with the public key identified by the 'kid' header, and returns its claims.
+func verifyRS256JWT(token string, key func(kid string) (*rsa.PublicKey, error), now time.Time) (*jwtClaims, error) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if t.alg != "RS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm '%s'", t.alg)
+	}
+
+	publicKey, err := key(t.kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(t.signed)
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], t.signature); err != nil {
+		return nil, fmt.Errorf("invalid JWT signature")
+	}
+
+	if err := t.checkExpiry(now); err != nil {
+		return nil, err
+	}
+	return &t.claims, nil
+}
+
+// verifyConnectJWT validates the Atlassian Connect JWT sent by Bitbucket Cloud
+// either in the 'Authorization: JWT <token>' header or in the 'jwt' query parameter.
+// The query string hash claim must match the request.
+func verifyConnectJWT(r *http.Request, sharedSecret []byte, now time.Time) (*jwtClaims, error) {
+	token := r.URL.Query().Get("jwt")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "JWT ") {
+		token = strings.TrimPrefix(auth, "JWT ")
+	}
+	if token == "" {
+		return nil, receivers.Errorf(receivers.MissingSignature, "the JWT is missing from the Authorization header and the query string")
+	}
+
+	claims, err := verifyHS256JWT(token, sharedSecret, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.QSH != connectQueryStringHash(r) {
+		return nil, fmt.Errorf("the JWT query string hash does not match the request")
+	}
+
+	return claims, nil
+}
+
+// connectQueryStringHash computes the Atlassian Connect 'qsh' claim of a request:
+// the hex encoded SHA-256 of 'METHOD&path&canonical-query'.
+func connectQueryStringHash(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	path = strings.ReplaceAll(path, "&", "%26")
+
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k != "jwt" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := make([]string, 0, len(query[k]))
+		for _, v := range query[k] {
+			values = append(values, connectEscape(v))
+		}
+		sort.Strings(values)
+		params = append(params, connectEscape(k)+"="+strings.Join(values, ","))
+	}
+
+	canonical := strings.Join([]string{strings.ToUpper(r.Method), path, strings.Join(params, "&")}, "&")
+	digest := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(digest[:])
+}
+
+// connectEscape percent-encodes a value as required by the Connect canonical request,
+// spaces are encoded as '%20' instead of '+'.
+func connectEscape(s string) string {
+	return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(url.QueryEscape(s))
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func signHS256(t *testing.T, claims jwtClaims, key string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	b, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHS256JWT(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "valid token",
+			token: signHS256(t, jwtClaims{Issuer: "bitbucket", ExpiresAt: now.Add(time.Minute).Unix()}, "secret"),
+		},
+		{
+			name:    "wrong key",
+			token:   signHS256(t, jwtClaims{Issuer: "bitbucket", ExpiresAt: now.Add(time.Minute).Unix()}, "other"),
+			wantErr: true,
+		},
+		{
+			name:    "expired token",
+			token:   signHS256(t, jwtClaims{Issuer: "bitbucket", ExpiresAt: now.Add(-time.Minute).Unix()}, "secret"),
+			wantErr: true,
+		},
+		{
+			name:    "malformed token",
+			token:   "not-a-jwt",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			_, err := verifyHS256JWT(tt.token, []byte("secret"), now)
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestVerifyConnectJWT(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now()
+
+	req := httptest.NewRequest("POST", "/hook/digest?b=2&a=1", nil)
+	qsh := connectQueryStringHash(req)
+	token := signHS256(t, jwtClaims{Issuer: "bitbucket", QSH: qsh, ExpiresAt: now.Add(time.Minute).Unix()}, "secret")
+
+	req.Header.Set("Authorization", "JWT "+token)
+	claims, err := verifyConnectJWT(req, []byte("secret"), now)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(claims.Issuer).To(gomega.Equal("bitbucket"))
+
+	// the jwt query parameter is excluded from the hash
+	req = httptest.NewRequest("POST", "/hook/digest?a=1&b=2&jwt="+token, nil)
+	_, err = verifyConnectJWT(req, []byte("secret"), now)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	req = httptest.NewRequest("POST", "/hook/digest?a=3", nil)
+	req.Header.Set("Authorization", "JWT "+token)
+	_, err = verifyConnectJWT(req, []byte("secret"), now)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	req = httptest.NewRequest("POST", "/hook/digest", nil)
+	_, err = verifyConnectJWT(req, []byte("secret"), now)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+// Providers creates the notifiers of the providers, it holds the transport
+// of their connections and the debug captures of their requests. It is shared
+// by the event and receiver servers and by the runnables sending notifications.
+type Providers struct {
+	kubeClient client.Client
+	transport  *notifier.Transport
+	captures   *captureRegistry
+}
+
+// NewProviders returns the notifiers factory of the providers, the transport
+// holds the TLS configuration and the egress policy of the connections.
+func NewProviders(kubeClient client.Client, transport *notifier.Transport) *Providers {
+	return &Providers{
+		kubeClient: kubeClient,
+		transport:  transport,
+		captures:   newCaptureRegistry(),
+	}
+}
+
+// WithProviders sets the notifiers factory of the providers.
+func (s *EventServer) WithProviders(providers *Providers) {
+	s.providers = providers
+}
+
+// WithProviders sets the notifiers factory of the providers of the receivers.
+func (s *ReceiverServer) WithProviders(providers *Providers) {
+	s.providers = providers
+}
+
+// alertProviders returns the providers the event is sent to by the alert, its
+// providerRef and the providerRefs whose condition matches the event, with the
+// fanout providers resolved. The providers that can't be read or whose condition
+// fails are skipped and their errors are returned.
+func alertProviders(ctx context.Context, kubeClient client.Client, conditions *cel.Cache, alert v1beta1.Alert, event events.Event) ([]v1beta1.Provider, error) {
+	var names []string
+	var errs []error
+	if alert.Spec.ProviderRef.Name != "" {
+		names = append(names, alert.Spec.ProviderRef.Name)
+	}
+	for _, ref := range alert.Spec.ProviderRefs {
+		matched, err := matchCondition(conditions, alert, ref.Condition, event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("condition of provider '%s' failed: %w", ref.Name, err))
+			continue
+		}
+		if matched {
+			names = append(names, ref.Name)
+		}
+	}
+
+	var providers []v1beta1.Provider
+	seen := make(map[string]bool)
+	for _, name := range names {
+		var provider v1beta1.Provider
+		providerName := types.NamespacedName{Namespace: alert.Namespace, Name: name}
+		if err := kubeClient.Get(ctx, providerName, &provider); err != nil {
+			errs = append(errs, fmt.Errorf("failed to read provider '%s', error: %w", providerName, err))
+			continue
+		}
+
+		resolved, err := resolveProviders(ctx, kubeClient, provider)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		// a provider selected more than once gets a single notification
+		for _, p := range resolved {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				providers = append(providers, p)
+			}
+		}
+	}
+	return providers, kerrors.NewAggregate(errs)
+}
+
+// newConditionCache returns the cache of the programs compiled
+// for the conditions of the provider references of the alerts.
+func newConditionCache() *cel.Cache {
+	return cel.NewCache("event")
+}
+
+// matchCondition evaluates the CEL condition of a provider reference
+// with the event, an empty condition matches all the events. The program
+// is compiled once per alert generation.
+func matchCondition(conditions *cel.Cache, alert v1beta1.Alert, condition string, event events.Event) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+	object := fmt.Sprintf("%s/%s/%s", v1beta1.AlertKind, alert.Namespace, alert.Name)
+	program, err := conditions.Program(object, alert.Generation, condition)
+	if err != nil {
+		return false, err
+	}
+	return program.EvalBool(map[string]interface{}{"event": event})
+}
+
+// resolveProviders returns the providers the notifications are sent to, the
+// providers referenced by a fanout provider or the provider itself. The referenced
+// providers that can't be read are skipped and their errors are returned.
+func resolveProviders(ctx context.Context, kubeClient client.Client, provider v1beta1.Provider) ([]v1beta1.Provider, error) {
+	if provider.Spec.Type != v1beta1.FanoutProvider {
+		return []v1beta1.Provider{provider}, nil
+	}
+
+	providers := make([]v1beta1.Provider, 0, len(provider.Spec.ProviderRefs))
+	var errs []error
+	for _, ref := range provider.Spec.ProviderRefs {
+		var child v1beta1.Provider
+		name := types.NamespacedName{Namespace: provider.Namespace, Name: ref.Name}
+		if err := kubeClient.Get(ctx, name, &child); err != nil {
+			errs = append(errs, fmt.Errorf("failed to read provider '%s', error: %w", name, err))
+			continue
+		}
+		if child.Spec.Type == v1beta1.FanoutProvider {
+			errs = append(errs, fmt.Errorf("provider '%s' is a fanout provider", name))
+			continue
+		}
+		providers = append(providers, child)
+	}
+	return providers, kerrors.NewAggregate(errs)
+}
+
+// notifier returns the provider notifier, the alert, if any, is
+// the fallback object of the kubernetes events, the notifications
+// of the alerts in dry run mode are logged instead.
+// The delivery ID is sent with the requests of the notifier.
+func (p *Providers) notifier(ctx context.Context, provider v1beta1.Provider, alert *v1beta1.Alert,
+	deliveryID string) (notifier.Interface, error) {
+	if alert != nil && alert.Spec.DryRun {
+		return dryRunNotifier(*alert, provider), nil
+	}
+	factory, err := p.factory(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	factory.DeliveryID = deliveryID
+	if alert != nil {
+		factory.Alert = &corev1.ObjectReference{
+			APIVersion: v1beta1.GroupVersion.String(),
+			Kind:       v1beta1.AlertKind,
+			Name:       alert.Name,
+			Namespace:  alert.Namespace,
+			UID:        alert.UID,
+		}
+	}
+	return factory.Notifier(provider.Spec.Type)
+}
+
+// factory reads the address, token and CA certificate of the
+// provider from its secrets and returns the notifier factory,
+// the transport is restricted to the egress allowlist of the provider.
+func (p *Providers) factory(ctx context.Context, provider v1beta1.Provider) (*notifier.Factory, error) {
+	webhook := provider.Spec.Address
+	token := ""
+	var signingKey, signingKeyPassphrase []byte
+	var awsCredentials notifier.AWSCredentials
+	var ociCredentials notifier.OCICredentials
+	if provider.Spec.SecretRef != nil {
+		var secret corev1.Secret
+		secretName := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Spec.SecretRef.Name}
+
+		if err := p.kubeClient.Get(ctx, secretName, &secret); err != nil {
+			return nil, fmt.Errorf("failed to read secret, error: %w", err)
+		}
+
+		if address, ok := secret.Data["address"]; ok {
+			webhook = string(address)
+		}
+
+		if t, ok := secret.Data["token"]; ok {
+			token = string(t)
+		}
+
+		signingKey = secret.Data["signingKey"]
+		signingKeyPassphrase = secret.Data["signingKeyPassphrase"]
+		awsCredentials = notifier.AWSCredentials{
+			AccessKeyID:     string(secret.Data["awsAccessKeyID"]),
+			SecretAccessKey: string(secret.Data["awsSecretAccessKey"]),
+			SessionToken:    string(secret.Data["awsSessionToken"]),
+		}
+		ociCredentials = notifier.OCICredentials{
+			TenancyID:   string(secret.Data["ociTenancy"]),
+			UserID:      string(secret.Data["ociUser"]),
+			Fingerprint: string(secret.Data["ociFingerprint"]),
+			PrivateKey:  secret.Data["ociPrivateKey"],
+		}
+	}
+
+	var certPool *x509.CertPool
+	if provider.Spec.CertSecretRef != nil {
+		var secret corev1.Secret
+		secretName := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Spec.CertSecretRef.Name}
+
+		if err := p.kubeClient.Get(ctx, secretName, &secret); err != nil {
+			return nil, fmt.Errorf("failed to read secret, error: %w", err)
+		}
+
+		caFile, ok := secret.Data["caFile"]
+		if !ok {
+			return nil, fmt.Errorf("no caFile found in secret %s", provider.Spec.CertSecretRef.Name)
+		}
+
+		certPool = x509.NewCertPool()
+		if ok := certPool.AppendCertsFromPEM(caFile); !ok {
+			return nil, fmt.Errorf("could not append to cert pool: invalid CA found in %s", provider.Spec.CertSecretRef.Name)
+		}
+	}
+
+	if webhook == "" && provider.Spec.Type != v1beta1.KubernetesProvider && provider.Spec.Type != v1beta1.LogProvider {
+		return nil, fmt.Errorf("provider has no address")
+	}
+
+	transport, err := p.transport.Restrict(provider.Spec.EgressAllowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid egress allowlist: %w", err)
+	}
+	if webhook != "" {
+		if err := transport.Check(ctx, webhook); err != nil {
+			return nil, fmt.Errorf("provider address rejected: %w", err)
+		}
+	}
+	if provider.Spec.Proxy != "" {
+		if err := transport.Check(ctx, provider.Spec.Proxy); err != nil {
+			return nil, fmt.Errorf("provider proxy rejected: %w", err)
+		}
+	}
+
+	factory := notifier.NewFactory(webhook, provider.Spec.Proxy, provider.Spec.Username, provider.Spec.Channel, token, certPool)
+	factory.Transport = transport
+	factory.SigningKey = signingKey
+	factory.SigningKeyPassphrase = signingKeyPassphrase
+	factory.Method = provider.Spec.Method
+	factory.ContentType = provider.Spec.ContentType
+	factory.HMAC = provider.Spec.HMAC
+	factory.Delivery = notifier.NewDelivery(provider.Spec.Delivery)
+	factory.AllowedChannels = provider.Spec.AllowedChannels
+	factory.StatusContexts = provider.Spec.StatusContexts
+	factory.StatusStates = provider.Spec.StatusStates
+	factory.AWSCredentials = awsCredentials
+	factory.OCICredentials = ociCredentials
+	factory.ArchiveOptions = notifier.NewArchiveOptions(provider.Spec.Archive)
+	factory.KubeClient = p.kubeClient
+	factory.Namespace = provider.Namespace
+	providerName := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name}
+	factory.Logger = logf.Log.WithName("notifications").WithValues("provider", providerName.String())
+	if provider.Spec.Debug {
+		factory.Capture = p.captures.get(providerName)
+	} else {
+		p.captures.remove(providerName)
+	}
+	return factory, nil
+}
+
+// dryRunNotifier returns the notifier logging the notifications the alert
+// would send to the provider, used instead of the provider in dry run mode.
+func dryRunNotifier(alert v1beta1.Alert, provider v1beta1.Provider) notifier.Interface {
+	return &notifier.Log{Logger: logf.Log.WithName("dry-run").WithValues(
+		"alert", types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name}.String(),
+		"provider", types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name}.String(),
+		"provider type", provider.Spec.Type)}
+}
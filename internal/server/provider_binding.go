@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// bindingAlerts returns an alert for each provider binding selecting the
+// namespace of the involved object. The alerts are in the namespace of the
+// bound provider and carry the summary and filters of the binding.
+func (s *EventServer) bindingAlerts(ctx context.Context, event *events.Event) ([]v1beta1.Alert, error) {
+	var bindings v1beta1.ProviderBindingList
+	if err := s.kubeClient.List(ctx, &bindings); err != nil {
+		return nil, fmt.Errorf("listing provider bindings failed: %w", err)
+	}
+	if len(bindings.Items) == 0 {
+		return nil, nil
+	}
+
+	var namespace corev1.Namespace
+	if err := s.kubeClient.Get(ctx, types.NamespacedName{Name: event.InvolvedObject.Namespace}, &namespace); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s, error: %w", event.InvolvedObject.Namespace, err)
+	}
+
+	alerts := make([]v1beta1.Alert, 0)
+	for _, binding := range bindings.Items {
+		// skip suspended and not ready bindings
+		isReady := apimeta.IsStatusConditionTrue(binding.Status.Conditions, meta.ReadyCondition)
+		if binding.Spec.Suspend || !isReady {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&binding.Spec.NamespaceSelector)
+		if err != nil {
+			s.logger.Error(err, "invalid namespace selector",
+				"reconciler kind", v1beta1.ProviderBindingKind,
+				"name", binding.Name)
+			continue
+		}
+		if !selector.Matches(labels.Set(namespace.Labels)) {
+			continue
+		}
+
+		if s.isExcluded(binding.Spec.ExclusionList, event.Message) {
+			continue
+		}
+
+		if event.Severity != binding.Spec.EventSeverity &&
+			binding.Spec.EventSeverity != events.EventSeverityInfo {
+			continue
+		}
+
+		alerts = append(alerts, v1beta1.Alert{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      binding.Name,
+				Namespace: binding.Spec.ProviderRef.Namespace,
+			},
+			Spec: v1beta1.AlertSpec{
+				ProviderRef:   meta.LocalObjectReference{Name: binding.Spec.ProviderRef.Name},
+				EventSeverity: binding.Spec.EventSeverity,
+				Summary:       binding.Spec.Summary,
+			},
+		})
+	}
+	return alerts, nil
+}
+
+// isExcluded returns true if the message matches a regex from the exclusion list.
+func (s *EventServer) isExcluded(exclusionList []string, message string) bool {
+	for _, exp := range exclusionList {
+		if r, err := regexp.Compile(exp); err == nil {
+			if r.Match([]byte(message)) {
+				return true
+			}
+		} else {
+			s.logger.Error(err, fmt.Sprintf("failed to compile regex: %s", exp))
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestEventServer_bindingAlerts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	newBinding := func(name string, team string, mutate func(*v1beta1.ProviderBinding)) *v1beta1.ProviderBinding {
+		binding := &v1beta1.ProviderBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.ProviderBindingSpec{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": team}},
+				ProviderRef:       meta.NamespacedObjectReference{Name: "slack", Namespace: "flux-system"},
+				EventSeverity:     events.EventSeverityInfo,
+				Summary:           "team " + team,
+			},
+		}
+		meta.SetResourceCondition(binding, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+		if mutate != nil {
+			mutate(binding)
+		}
+		return binding
+	}
+
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"team": "dev"}}},
+			newBinding("dev", "dev", nil),
+			newBinding("ops", "ops", nil),
+			newBinding("suspended", "dev", func(b *v1beta1.ProviderBinding) { b.Spec.Suspend = true }),
+			newBinding("errors", "dev", func(b *v1beta1.ProviderBinding) { b.Spec.EventSeverity = events.EventSeverityError }),
+			newBinding("excluded", "dev", func(b *v1beta1.ProviderBinding) { b.Spec.ExclusionList = []string{"^no-op"} }),
+		).
+		Build()
+
+	s := &EventServer{logger: logf.Log, kubeClient: kubeClient}
+	event := &events.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "podinfo", Namespace: "apps"},
+		Severity:       events.EventSeverityInfo,
+		Message:        "no-op",
+	}
+
+	alerts, err := s.bindingAlerts(context.TODO(), event)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(alerts).To(gomega.HaveLen(1))
+	g.Expect(alerts[0].Name).To(gomega.Equal("dev"))
+	g.Expect(alerts[0].Namespace).To(gomega.Equal("flux-system"))
+	g.Expect(alerts[0].Spec.ProviderRef.Name).To(gomega.Equal("slack"))
+	g.Expect(alerts[0].Spec.Summary).To(gomega.Equal("team dev"))
+
+	event.Severity = events.EventSeverityError
+	event.Message = "health check failed"
+	alerts, err = s.bindingAlerts(context.TODO(), event)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(alerts).To(gomega.HaveLen(3))
+
+	event.InvolvedObject.Namespace = "missing"
+	_, err = s.bindingAlerts(context.TODO(), event)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+const (
+	// ProviderCapturePath is the path prefix of the provider debug captures,
+	// the captures of a provider are served under '<prefix><namespace>/<name>'.
+	ProviderCapturePath = "/debug/providers/"
+
+	// debugCaptureSize is the number of requests captured per provider.
+	debugCaptureSize = 10
+)
+
+// captureRegistry holds the debug captures of the providers.
+type captureRegistry struct {
+	mu       sync.Mutex
+	captures map[types.NamespacedName]*notifier.Capture
+}
+
+func newCaptureRegistry() *captureRegistry {
+	return &captureRegistry{captures: make(map[types.NamespacedName]*notifier.Capture)}
+}
+
+// get returns the capture of the provider, it is created on first use.
+func (r *captureRegistry) get(name types.NamespacedName) *notifier.Capture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.captures[name]
+	if !ok {
+		c = notifier.NewCapture(debugCaptureSize)
+		r.captures[name] = c
+	}
+	return c
+}
+
+// remove drops the capture of a provider whose debug mode was turned off.
+func (r *captureRegistry) remove(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.captures, name)
+}
+
+// CaptureHandler serves the debug captures of the providers as JSON, it must
+// be added to the metrics endpoint so that it's not exposed with the receivers.
+func (p *Providers) CaptureHandler() http.Handler {
+	return p.captures
+}
+
+func (r *captureRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, ProviderCapturePath), "/")
+	if path == "" {
+		r.mu.Lock()
+		names := make([]string, 0, len(r.captures))
+		for name := range r.captures {
+			names = append(names, name.String())
+		}
+		r.mu.Unlock()
+		sort.Strings(names)
+		writeJSON(w, names)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	r.mu.Lock()
+	c, ok := r.captures[types.NamespacedName{Namespace: parts[0], Name: parts[1]}]
+	r.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, c.Exchanges())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+func TestCaptureRegistry_ServeHTTP(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	registry := newCaptureRegistry()
+	name := types.NamespacedName{Namespace: "flux-system", Name: "teams"}
+	g.Expect(registry.get(name)).To(gomega.BeIdenticalTo(registry.get(name)))
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ProviderCapturePath, nil))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	var names []string
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &names)).To(gomega.Succeed())
+	g.Expect(names).To(gomega.Equal([]string{"flux-system/teams"}))
+
+	rec = httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ProviderCapturePath+"flux-system/teams", nil))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	var exchanges []notifier.Exchange
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &exchanges)).To(gomega.Succeed())
+	g.Expect(exchanges).To(gomega.BeEmpty())
+
+	rec = httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ProviderCapturePath+"flux-system/slack", nil))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusNotFound))
+
+	registry.remove(name)
+	rec = httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ProviderCapturePath+"flux-system/teams", nil))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusNotFound))
+
+	rec = httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, ProviderCapturePath, nil))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusMethodNotAllowed))
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/egress"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+func TestNewProviderNotifier_Egress(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	policy, err := egress.ParsePolicy([]string{"hooks.slack.com"}, egress.DefaultBlocklist)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	transport := &notifier.Transport{EgressPolicy: policy}
+
+	provider := v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "flux-system"},
+		Spec: v1beta1.ProviderSpec{
+			Type:    v1beta1.SlackProvider,
+			Address: "https://hooks.slack.com/services/x",
+			Channel: "general",
+		},
+	}
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	provider.Spec.Proxy = "http://10.0.0.1:3128"
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(errors.Is(err, egress.ErrDenied)).To(gomega.BeTrue())
+
+	provider.Spec.Proxy = ""
+	provider.Spec.Address = "http://169.254.169.254/latest/meta-data"
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(errors.Is(err, egress.ErrDenied)).To(gomega.BeTrue())
+
+	// the provider allowlist restricts the controller policy
+	provider.Spec.Address = "https://hooks.slack.com/services/x"
+	provider.Spec.EgressAllowlist = []string{"chat.example.com"}
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(errors.Is(err, egress.ErrDenied)).To(gomega.BeTrue())
+
+	provider.Spec.EgressAllowlist = []string{"*.slack.com"}
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	provider.Spec.EgressAllowlist = []string{"hooks.*.com"}
+	_, err = NewProviders(kubeClient, transport).notifier(context.TODO(), provider, nil, "")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestNewProviderNotifier_DryRun(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// the provider secret isn't read in dry run mode
+	provider := v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "pagerduty", Namespace: "flux-system"},
+		Spec: v1beta1.ProviderSpec{
+			Type:      v1beta1.GenericProvider,
+			SecretRef: &meta.LocalObjectReference{Name: "pagerduty-address"},
+		},
+	}
+	alert := &v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "on-call", Namespace: "flux-system"},
+		Spec:       v1beta1.AlertSpec{DryRun: true},
+	}
+	sender, err := NewProviders(kubeClient, nil).notifier(context.TODO(), provider, alert, "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(sender).To(gomega.BeAssignableToTypeOf(&notifier.Log{}))
+	g.Expect(sender.Post(events.Event{Message: "test"})).To(gomega.Succeed())
+
+	alert.Spec.DryRun = false
+	_, err = NewProviders(kubeClient, nil).notifier(context.TODO(), provider, alert, "")
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// the log providers don't need an address
+	provider.Spec.Type = v1beta1.LogProvider
+	provider.Spec.SecretRef = nil
+	sender, err = NewProviders(kubeClient, nil).notifier(context.TODO(), provider, alert, "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(sender).To(gomega.BeAssignableToTypeOf(&notifier.Log{}))
+}
+
+func TestResolveProviders(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	newProvider := func(name, providerType string, refs ...string) *v1beta1.Provider {
+		provider := &v1beta1.Provider{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flux-system"},
+			Spec:       v1beta1.ProviderSpec{Type: providerType},
+		}
+		for _, ref := range refs {
+			provider.Spec.ProviderRefs = append(provider.Spec.ProviderRefs, meta.LocalObjectReference{Name: ref})
+		}
+		return provider
+	}
+	slack := newProvider("slack", v1beta1.SlackProvider)
+	teams := newProvider("teams", v1beta1.MSTeamsProvider)
+	nested := newProvider("nested", v1beta1.FanoutProvider, "slack")
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(slack, teams, nested).Build()
+
+	providers, err := resolveProviders(context.TODO(), kubeClient, *slack)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(providers).To(gomega.HaveLen(1))
+	g.Expect(providers[0].Name).To(gomega.Equal("slack"))
+
+	providers, err = resolveProviders(context.TODO(), kubeClient, *newProvider("all", v1beta1.FanoutProvider, "slack", "teams"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(providers).To(gomega.HaveLen(2))
+	g.Expect(providers[1].Spec.Type).To(gomega.Equal(v1beta1.MSTeamsProvider))
+
+	// the missing and the nested providers are skipped
+	providers, err = resolveProviders(context.TODO(), kubeClient, *newProvider("all", v1beta1.FanoutProvider, "missing", "nested", "teams"))
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(providers).To(gomega.HaveLen(1))
+	g.Expect(providers[0].Name).To(gomega.Equal("teams"))
+}
+
+func TestAlertProviders(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+
+	var objects []client.Object
+	for _, name := range []string{"slack", "pagerduty", "teams"} {
+		objects = append(objects, &v1beta1.Provider{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flux-system"},
+			Spec:       v1beta1.ProviderSpec{Type: v1beta1.GenericProvider},
+		})
+	}
+	objects = append(objects, &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "flux-system"},
+		Spec: v1beta1.ProviderSpec{
+			Type:         v1beta1.FanoutProvider,
+			ProviderRefs: []meta.LocalObjectReference{{Name: "slack"}, {Name: "teams"}},
+		},
+	})
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "flux-system"},
+		Spec: v1beta1.AlertSpec{
+			ProviderRefs: []v1beta1.ConditionalProviderReference{
+				{Name: "pagerduty", Condition: "event.severity == 'error'"},
+				{Name: "slack", Condition: "event.severity == 'info'"},
+				{Name: "all", Condition: "event.metadata.env == 'production'"},
+			},
+		},
+	}
+	names := func(providers []v1beta1.Provider) []string {
+		var out []string
+		for _, p := range providers {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	event := events.Event{Severity: events.EventSeverityError, Metadata: map[string]string{"env": "production"}}
+	providers, err := alertProviders(context.TODO(), kubeClient, newConditionCache(), alert, event)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names(providers)).To(gomega.Equal([]string{"pagerduty", "slack", "teams"}))
+
+	// the failing condition is reported and the other providers are used
+	event = events.Event{Severity: events.EventSeverityInfo}
+	providers, err = alertProviders(context.TODO(), kubeClient, newConditionCache(), alert, event)
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(names(providers)).To(gomega.Equal([]string{"slack"}))
+
+	alert.Spec.ProviderRef = meta.LocalObjectReference{Name: "teams"}
+	alert.Spec.ProviderRefs = alert.Spec.ProviderRefs[:2]
+	providers, err = alertProviders(context.TODO(), kubeClient, newConditionCache(), alert, event)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names(providers)).To(gomega.Equal([]string{"teams", "slack"}))
+}
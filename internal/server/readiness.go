@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// APIServerCheck returns a readiness check failing when the receivers can't be
+// listed from the Kubernetes API server within the timeout, so that the webhooks
+// and the events aren't routed to a replica unable to handle them. The reader must
+// not be cached, and the namespace is the watched one, all when empty.
+// The liveness check isn't affected, the replica isn't restarted.
+func APIServerCheck(reader client.Reader, namespace string, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+
+		var receivers v1beta1.ReceiverList
+		if err := reader.List(ctx, &receivers, client.InNamespace(namespace), client.Limit(1)); err != nil {
+			return fmt.Errorf("the Kubernetes API server is unavailable: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// WithTrustedProxies sets the IP blocks of the proxies in front of the server,
+// the X-Forwarded-For header is only honoured for the requests they send.
+func (s *ReceiverServer) WithTrustedProxies(blocks []string) error {
+	networks, err := trigger.ParseIPBlocks(blocks)
+	if err != nil {
+		return err
+	}
+	s.trustedProxies = networks
+	return nil
+}
+
+// checkSource rejects the requests sent from an address not in the
+// receiver IP blocks, all the addresses are allowed when none are set.
+func (s *ReceiverServer) checkSource(receiver v1beta1.Receiver, r *http.Request) error {
+	if receiver.Spec.AccessFrom == nil {
+		return nil
+	}
+	networks, err := trigger.ParseIPBlocks(receiver.Spec.AccessFrom.IPBlocks)
+	if err != nil {
+		return receivers.Errorf(receivers.VerificationFailed, "invalid accessFrom: %w", err)
+	}
+
+	ip := sourceIP(r, s.trustedProxies)
+	if ip == nil || !trigger.ContainsIP(networks, ip) {
+		return receivers.Errorf(receivers.SourceNotAllowed, "the source address '%s' is not allowed", ip)
+	}
+	return nil
+}
+
+// sourceIP returns the address of the caller, the peer address unless it's
+// a trusted proxy, in which case the X-Forwarded-For addresses are walked from
+// the last one, appended by the nearest proxy, to the first that isn't trusted.
+func sourceIP(r *http.Request, trustedProxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !trigger.ContainsIP(trustedProxies, ip) {
+		return ip
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			// the addresses before an invalid one can't be trusted
+			return ip
+		}
+		ip = hop
+		if !trigger.ContainsIP(trustedProxies, ip) {
+			return ip
+		}
+	}
+	return ip
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestSourceIP(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	trusted, err := trigger.ParseIPBlocks([]string{"10.0.0.0/8"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  []string
+		trusted    bool
+		expected   string
+	}{
+		{remoteAddr: "192.30.252.1:4242", expected: "192.30.252.1"},
+		{remoteAddr: "192.30.252.1:4242", forwarded: []string{"10.1.1.1"}, trusted: true, expected: "192.30.252.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"192.30.252.1"}, expected: "10.0.0.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"192.30.252.1"}, trusted: true, expected: "192.30.252.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"1.2.3.4, 192.30.252.1, 10.0.0.2"}, trusted: true, expected: "192.30.252.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"1.2.3.4", "192.30.252.1"}, trusted: true, expected: "192.30.252.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"192.30.252.1, unknown"}, trusted: true, expected: "10.0.0.1"},
+		{remoteAddr: "10.0.0.1:4242", forwarded: []string{"10.0.0.3"}, trusted: true, expected: "10.0.0.3"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, header := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+		proxies := trusted
+		if !tt.trusted {
+			proxies = nil
+		}
+		g.Expect(sourceIP(r, proxies).String()).To(gomega.Equal(tt.expected), "%+v", tt)
+	}
+}
+
+func TestReceiverServer_checkSource(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+	g.Expect(s.WithTrustedProxies([]string{"10.0.0.1"})).To(gomega.Succeed())
+	g.Expect(s.WithTrustedProxies([]string{"10.0.0.1/"})).NotTo(gomega.Succeed())
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "generic", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:       v1beta1.GenericReceiver,
+			SecretRef:  meta.LocalObjectReference{Name: "webhook-token"},
+			AccessFrom: &v1beta1.ReceiverAccessFrom{IPBlocks: []string{"192.30.252.0/22"}},
+		},
+	}
+	request := func(remoteAddr, forwarded string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/generic", bytes.NewReader([]byte(`{}`)))
+		r.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("192.30.252.10:4242", ""))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("10.0.0.1:4242", "192.30.252.10"))).To(gomega.Succeed())
+
+	err := s.validate(ctx, receiver, request("203.0.113.5:4242", ""))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.SourceNotAllowed))
+	// the header isn't honoured for the requests of untrusted peers
+	err = s.validate(ctx, receiver, request("203.0.113.5:4242", "192.30.252.10"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.SourceNotAllowed))
+
+	receiver.Spec.AccessFrom = nil
+	g.Expect(s.validate(ctx, receiver, request("203.0.113.5:4242", ""))).To(gomega.Succeed())
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// The client certificate modes of the receiver server.
+const (
+	// ClientCertsNone doesn't request the client certificates.
+	ClientCertsNone = "none"
+
+	// ClientCertsRequest requests the client certificates, they're required
+	// by the receivers with a certSecretRef only.
+	ClientCertsRequest = "request"
+
+	// ClientCertsRequire fails the TLS handshakes without a client certificate,
+	// and rejects the requests to the receivers without a certSecretRef.
+	ClientCertsRequire = "require"
+)
+
+// WithClientCertificates sets the client certificate mode of the server,
+// the certificates are verified with the CA of the receiver certSecretRef.
+// It must be called after WithTLS.
+func (s *ReceiverServer) WithClientCertificates(mode string) error {
+	clientAuth := tls.RequestClientCert
+	switch mode {
+	case "", ClientCertsNone:
+		return nil
+	case ClientCertsRequest:
+	case ClientCertsRequire:
+		clientAuth = tls.RequireAnyClientCert
+	default:
+		return fmt.Errorf("invalid client certificates mode '%s', can be one of %s, %s, %s",
+			mode, ClientCertsNone, ClientCertsRequest, ClientCertsRequire)
+	}
+	if s.certFile == "" {
+		return fmt.Errorf("the client certificates require the receiver server to listen on HTTPS")
+	}
+
+	// the config is shared with the event server
+	config := s.tlsConfig.Clone()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.ClientAuth = clientAuth
+	s.tlsConfig = config
+	s.requireClientCerts = mode == ClientCertsRequire
+	return nil
+}
+
+// checkClientCertificate rejects the requests without a client certificate
+// verified by the CA of the receiver, the requests to the receivers without
+// a certSecretRef are allowed unless the server requires the certificates.
+func (s *ReceiverServer) checkClientCertificate(ctx context.Context, receiver v1beta1.Receiver, r *http.Request) error {
+	certPool, err := trigger.ClientCAs(ctx, s.kubeClient, receiver)
+	if err != nil {
+		return err
+	}
+	if certPool == nil {
+		if s.requireClientCerts {
+			return fmt.Errorf("the receiver has no certSecretRef, it's required by the server")
+		}
+		return nil
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return receivers.Errorf(receivers.InvalidCertificate, "the request has no client certificate")
+	}
+	return verifyClientCertificate(r.TLS.PeerCertificates, certPool, receiver.Spec.ClientCertificate, time.Now())
+}
+
+// verifyClientCertificate verifies the chain sent by the client with the CA,
+// and matches the leaf certificate with the subjects and the SANs.
+func verifyClientCertificate(chain []*x509.Certificate, roots *x509.CertPool,
+	allowed *v1beta1.ReceiverClientCertificate, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := chain[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidCertificate, "the client certificate is invalid, err: %w", err)
+	}
+
+	if allowed == nil {
+		return nil
+	}
+	if subject := leaf.Subject.String(); !matchSubject(allowed.Subjects, subject) {
+		return receivers.Errorf(receivers.InvalidCertificate, "the client certificate subject '%s' is not allowed", subject)
+	}
+	if len(allowed.SANs) > 0 && !matchAnySAN(allowed.SANs, leaf) {
+		return receivers.Errorf(receivers.InvalidCertificate, "the client certificate SANs are not allowed")
+	}
+	return nil
+}
+
+// matchAnySAN returns true if one of the subject alternative names
+// of the certificate matches one of the patterns.
+func matchAnySAN(patterns []string, cert *x509.Certificate) bool {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, san := range sans {
+		if matchSubject(patterns, san) {
+			return true
+		}
+	}
+	return false
+}
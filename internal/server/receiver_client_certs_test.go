@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// testCertificate returns a certificate signed by the parent, self-signed without one.
+func testCertificate(g *gomega.WithT, template *x509.Certificate, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return cert, key
+}
+
+func TestReceiverServer_checkClientCertificate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	ca, caKey := testCertificate(g, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+	otherCA, otherKey := testCertificate(g, &x509.Certificate{Subject: pkix.Name{CommonName: "other"}}, nil, nil)
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/ci/sa/deployer")
+	client := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "deployer", Organization: []string{"platform"}},
+		URIs:        []*url.URL{spiffe},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, _ := testCertificate(g, client, ca, caKey)
+	untrusted, _ := testCertificate(g, client, otherCA, otherKey)
+	serverCert, _ := testCertificate(g, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "deployer"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	objects := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: "default"},
+			Data:       map[string][]byte{"caFile": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})},
+		},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "generic", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:          v1beta1.GenericReceiver,
+			SecretRef:     meta.LocalObjectReference{Name: "webhook-token"},
+			CertSecretRef: &meta.LocalObjectReference{Name: "client-ca"},
+		},
+	}
+	request := func(certs ...*x509.Certificate) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/generic", nil)
+		if len(certs) > 0 {
+			r.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		}
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request(cert))).To(gomega.Succeed())
+	for _, r := range []*http.Request{request(), request(untrusted), request(serverCert)} {
+		err := s.validate(ctx, receiver, r)
+		g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidCertificate))
+	}
+
+	receiver.Spec.ClientCertificate = &v1beta1.ReceiverClientCertificate{
+		Subjects: []string{"CN=deployer,O=platform"},
+		SANs:     []string{"spiffe://cluster.local/ns/ci/sa/*"},
+	}
+	g.Expect(s.validate(ctx, receiver, request(cert))).To(gomega.Succeed())
+	receiver.Spec.ClientCertificate.Subjects = []string{"CN=admin,*"}
+	g.Expect(receivers.Reason(s.validate(ctx, receiver, request(cert)))).To(gomega.Equal(receivers.InvalidCertificate))
+	receiver.Spec.ClientCertificate.Subjects = nil
+	receiver.Spec.ClientCertificate.SANs = []string{"spiffe://cluster.local/ns/prod/*"}
+	g.Expect(receivers.Reason(s.validate(ctx, receiver, request(cert)))).To(gomega.Equal(receivers.InvalidCertificate))
+
+	// the receivers without a certSecretRef are rejected when the server requires the certificates
+	receiver.Spec.CertSecretRef = nil
+	receiver.Spec.ClientCertificate = nil
+	g.Expect(s.validate(ctx, receiver, request())).To(gomega.Succeed())
+	g.Expect(s.WithClientCertificates(ClientCertsRequire)).NotTo(gomega.Succeed())
+	s.WithTLS(nil, "tls.crt", "tls.key")
+	g.Expect(s.WithClientCertificates("optional")).NotTo(gomega.Succeed())
+	g.Expect(s.WithClientCertificates(ClientCertsRequire)).To(gomega.Succeed())
+	g.Expect(s.tlsConfig.ClientAuth).To(gomega.Equal(tls.RequireAnyClientCert))
+	g.Expect(s.validate(ctx, receiver, request(cert))).NotTo(gomega.Succeed())
+}
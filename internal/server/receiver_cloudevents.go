@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/internal/webhook"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// cloudEventsContentType is the content type of the structured CloudEvents.
+	cloudEventsContentType = "application/cloudevents+json"
+
+	// cloudEventsHeaderPrefix is the prefix of the headers holding the
+	// context attributes of the binary CloudEvents.
+	cloudEventsHeaderPrefix = "Ce-"
+)
+
+// cloudEvent is a CloudEvent sent in the binary or the structured content mode,
+// the data is decoded according to its content type, or left as a string.
+type cloudEvent struct {
+	Attributes map[string]string `json:"attributes"`
+	Data       interface{}       `json:"data"`
+}
+
+// parseCloudEvent returns the CloudEvent sent in the request, nil if the
+// request is neither a binary nor a structured CloudEvent. The XML data
+// is decoded when parseXML is set.
+func parseCloudEvent(r *http.Request, body []byte, parseXML bool) (*cloudEvent, error) {
+	var ce *cloudEvent
+	var err error
+	switch {
+	case r.Header.Get(cloudEventsHeaderPrefix+"Specversion") != "":
+		ce = parseBinaryCloudEvent(r.Header, body, parseXML)
+	case isStructuredCloudEvent(r.Header.Get("Content-Type")):
+		ce, err = parseStructuredCloudEvent(body, parseXML)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	for _, attr := range []string{"specversion", "id", "source", "type"} {
+		if ce.Attributes[attr] == "" {
+			return nil, fmt.Errorf("the CloudEvent '%s' attribute is missing", attr)
+		}
+	}
+	return ce, nil
+}
+
+// parseBinaryCloudEvent reads the context attributes from the 'ce-' headers,
+// the request body is the event data.
+func parseBinaryCloudEvent(header http.Header, body []byte, parseXML bool) *cloudEvent {
+	ce := &cloudEvent{Attributes: make(map[string]string)}
+	for name, values := range header {
+		if !strings.HasPrefix(name, cloudEventsHeaderPrefix) || len(values) == 0 {
+			continue
+		}
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			value = values[0]
+		}
+		ce.Attributes[strings.ToLower(strings.TrimPrefix(name, cloudEventsHeaderPrefix))] = value
+	}
+	contentType := header.Get("Content-Type")
+	if contentType != "" {
+		ce.Attributes["datacontenttype"] = contentType
+	}
+	ce.Data = decodeCloudEventData(contentType, body, parseXML)
+	return ce
+}
+
+// parseStructuredCloudEvent decodes the JSON CloudEvent, the attributes
+// which aren't strings are kept in their JSON format.
+func parseStructuredCloudEvent(body []byte, parseXML bool) (*cloudEvent, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("cannot decode the CloudEvent: %w", err)
+	}
+
+	ce := &cloudEvent{Attributes: make(map[string]string, len(fields))}
+	for name, raw := range fields {
+		if name == "data" || name == "data_base64" {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		ce.Attributes[name] = value
+	}
+
+	data, err := structuredCloudEventData(fields)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		ce.Data = decodeCloudEventData(ce.Attributes["datacontenttype"], data, parseXML)
+	}
+	return ce, nil
+}
+
+// structuredCloudEventData returns the data of the JSON CloudEvent,
+// the binary data is decoded from base64.
+func structuredCloudEventData(fields map[string]json.RawMessage) ([]byte, error) {
+	if raw, ok := fields["data_base64"]; ok {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return nil, fmt.Errorf("cannot decode the CloudEvent data: %w", err)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode the CloudEvent data: %w", err)
+		}
+		return data, nil
+	}
+	if raw, ok := fields["data"]; ok {
+		return raw, nil
+	}
+	return nil, nil
+}
+
+// decodeCloudEventData decodes the data according to its content type,
+// the data that can't be decoded is returned as a string.
+func decodeCloudEventData(contentType string, data []byte, parseXML bool) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	if v, err := webhook.DecodePayload(contentType, data, parseXML); err == nil {
+		return v
+	}
+	return string(data)
+}
+
+func isStructuredCloudEvent(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mt == cloudEventsContentType
+}
+
+// match evaluates the CEL condition with the CloudEvent, and with the request
+// holding the decoded body and the headers.
+func (ce cloudEvent) match(r receivers.Request, condition string, body []byte) (bool, error) {
+	contentType := r.Header.Get("Content-Type")
+	variables := trigger.RequestVariables(decodeCloudEventData(contentType, body, r.Receiver.Spec.ParseXML), r.Header)
+	variables[trigger.ConditionVariable] = ce
+	return evalFilterCondition(r, condition, variables)
+}
+
+// filterGenericEvent checks the type of the CloudEvent sent to a generic receiver against
+// the receiver events and evaluates the filter condition. The other payloads are evaluated
+// as the data of an event without attributes.
+func filterGenericEvent(r receivers.Request, body []byte) error {
+	parseXML := r.Receiver.Spec.ParseXML
+	ce, err := parseCloudEvent(r.Request, body, parseXML)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "%w", err)
+	}
+
+	event := ""
+	if ce != nil {
+		event = ce.Attributes["type"]
+		if !receivers.EventAllowed(r.Receiver, event) {
+			return receivers.Errorf(receivers.EventNotAllowed, "the CloudEvent type '%s' is not authorised", event)
+		}
+	}
+
+	if filter := r.Receiver.Spec.Filter; filter != nil && filter.Condition != "" {
+		if ce == nil {
+			ce = &cloudEvent{
+				Attributes: map[string]string{},
+				Data:       decodeCloudEventData(r.Header.Get("Content-Type"), body, parseXML),
+			}
+		}
+		ok, err := ce.match(r, filter.Condition, body)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errEventFiltered, err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: the payload does not match the condition", errEventFiltered)
+		}
+	}
+
+	if event != "" {
+		r.SetEvent(event)
+	}
+	return nil
+}
+
+// cloudEventsVerifier unwraps the data of the structured CloudEvents
+// sent to the generic receivers, for the annotation expressions.
+type cloudEventsVerifier struct {
+	receivers.VerifierFunc
+}
+
+// Unwrap returns the data of the structured CloudEvent, the other payloads,
+// including the binary CloudEvents, are returned as is.
+func (cloudEventsVerifier) Unwrap(payload []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload
+	}
+	if _, ok := fields["specversion"]; !ok {
+		return payload
+	}
+	data, err := structuredCloudEventData(fields)
+	if err != nil || data == nil {
+		return payload
+	}
+	return data
+}
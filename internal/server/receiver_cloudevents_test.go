@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestParseCloudEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	binary := httptest.NewRequest(http.MethodPost, "/hook/ce", nil)
+	binary.Header.Set("Content-Type", "application/json")
+	binary.Header.Set("Ce-Specversion", "1.0")
+	binary.Header.Set("Ce-Id", "1")
+	binary.Header.Set("Ce-Source", "%2Fci%2Fwebapp")
+	binary.Header.Set("Ce-Type", "dev.cdevents.artifact.published.0.1.0")
+	body := []byte(`{"subject":{"id":"webapp"}}`)
+	ce, err := parseCloudEvent(binary, body, false)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ce.Attributes).To(gomega.HaveKeyWithValue("source", "/ci/webapp"))
+	g.Expect(ce.Attributes).To(gomega.HaveKeyWithValue("datacontenttype", "application/json"))
+	g.Expect(ce.match(receivers.Request{Request: binary}, "message.attributes.source == '/ci/webapp' && message.data.subject.id == 'webapp'", body)).To(gomega.BeTrue())
+	g.Expect(ce.match(receivers.Request{Request: binary}, "request.headers['ce-id'] == '1' && request.body.subject.id == 'webapp'", body)).To(gomega.BeTrue())
+
+	structured := httptest.NewRequest(http.MethodPost, "/hook/ce", nil)
+	structured.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	ce, err = parseCloudEvent(structured, []byte(`{"specversion":"1.0","id":"2","source":"/ci/webapp",`+
+		`"type":"dev.cdevents.build.finished.0.1.0","data_base64":"eyJzdWJqZWN0Ijp7ImlkIjoid2ViYXBwIn19"}`), false)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ce.Attributes).To(gomega.HaveKeyWithValue("type", "dev.cdevents.build.finished.0.1.0"))
+	g.Expect(ce.Attributes).NotTo(gomega.HaveKey("data_base64"))
+	g.Expect(ce.match(receivers.Request{Request: structured}, "message.data.subject.id == 'webapp'", nil)).To(gomega.BeTrue())
+
+	_, err = parseCloudEvent(structured, []byte(`{"specversion":"1.0","id":"3"}`), false)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	plain := httptest.NewRequest(http.MethodPost, "/hook/ce", nil)
+	plain.Header.Set("Content-Type", "application/json")
+	g.Expect(parseCloudEvent(plain, []byte(`{"specversion":"1.0"}`), false)).To(gomega.BeNil())
+}
+
+func TestCloudEventsVerifier_Unwrap(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var v cloudEventsVerifier
+	g.Expect(v.Unwrap([]byte(`{"specversion":"1.0","type":"build","data":{"id":"b1"}}`))).To(gomega.MatchJSON(`{"id":"b1"}`))
+	g.Expect(v.Unwrap([]byte(`{"id":"b1"}`))).To(gomega.MatchJSON(`{"id":"b1"}`))
+}
+
+func TestReceiverServer_validateCloudEvents(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "cdevents", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Events:    []string{"dev.cdevents.artifact.published.0.1.0"},
+			Filter:    &v1beta1.ReceiverFilter{Condition: "message.data.subject.id == 'webapp'"},
+		},
+	}
+
+	request := func(event, subject string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/cdevents", bytes.NewReader([]byte(`{"subject":{"id":"`+subject+`"}}`)))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Ce-Specversion", "1.0")
+		r.Header.Set("Ce-Id", "1")
+		r.Header.Set("Ce-Source", "/ci/webapp")
+		r.Header.Set("Ce-Type", event)
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("dev.cdevents.artifact.published.0.1.0", "webapp"))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("dev.cdevents.artifact.published.0.1.0", "other"))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request("dev.cdevents.build.finished.0.1.0", "webapp"))).NotTo(gomega.Succeed())
+
+	// the payloads that aren't CloudEvents are evaluated as the event data
+	plain := httptest.NewRequest(http.MethodPost, "/hook/cdevents", bytes.NewReader([]byte(`{"subject":{"id":"webapp"}}`)))
+	plain.Header.Set("Content-Type", "application/json")
+	g.Expect(s.validate(ctx, receiver, plain)).To(gomega.Succeed())
+}
+
+func TestReceiverServer_validateXML(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfs", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			ParseXML:  true,
+			Filter: &v1beta1.ReceiverFilter{
+				Condition: "request.body.event['-type'] == 'git.push' && request.headers['x-tfs-project'] == 'webapp'",
+			},
+		},
+	}
+
+	request := func(eventType string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/tfs", bytes.NewReader([]byte(`<event type="`+eventType+`"><ref>main</ref></event>`)))
+		r.Header.Set("Content-Type", "text/xml")
+		r.Header.Set("X-Tfs-Project", "webapp")
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("git.push"))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("build.complete"))).To(gomega.MatchError(errEventFiltered))
+
+	// without parseXML, the body is exposed as a string and the condition fails
+	receiver.Spec.ParseXML = false
+	g.Expect(s.validate(ctx, receiver, request("git.push"))).To(gomega.MatchError(errEventFiltered))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// coalescedTrigger is the last trigger of a resource received within the coalesce window.
+type coalescedTrigger struct {
+	receiver    v1beta1.Receiver
+	resource    v1beta1.CrossNamespaceObjectReference
+	annotations map[string]string
+	count       int
+}
+
+// coalescer holds the triggers of the resources until their coalesce window
+// elapses, so that a burst of triggers results in a single update per resource.
+type coalescer struct {
+	apply func(coalescedTrigger)
+
+	mu      sync.Mutex
+	pending map[string]*coalescedTrigger
+	timers  map[string]*time.Timer
+}
+
+func newCoalescer(apply func(coalescedTrigger)) *coalescer {
+	return &coalescer{
+		apply:   apply,
+		pending: make(map[string]*coalescedTrigger),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// add holds the trigger until the window elapses, the window starts with
+// the first trigger of the resource and the last trigger is applied.
+func (c *coalescer) add(t coalescedTrigger, window time.Duration) {
+	key := coalesceKey(t.receiver, t.resource)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pending[key]; ok {
+		t.count = p.count + 1
+		c.pending[key] = &t
+		return
+	}
+	t.count = 1
+	c.pending[key] = &t
+	c.timers[key] = time.AfterFunc(window, func() { c.flush(key) })
+}
+
+// flush applies the pending trigger of the resource.
+func (c *coalescer) flush(key string) {
+	c.mu.Lock()
+	t, ok := c.pending[key]
+	delete(c.pending, key)
+	delete(c.timers, key)
+	c.mu.Unlock()
+
+	if ok {
+		c.apply(*t)
+	}
+}
+
+// flushAll applies the pending triggers without waiting for their window, e.g. on shutdown.
+func (c *coalescer) flushAll() {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.pending))
+	for key, timer := range c.timers {
+		if timer.Stop() {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		c.flush(key)
+	}
+}
+
+// coalesceKey identifies the resource of a receiver, the resources
+// triggered by several receivers are coalesced per receiver.
+func coalesceKey(receiver v1beta1.Receiver, resource v1beta1.CrossNamespaceObjectReference) string {
+	namespace := resource.Namespace
+	if namespace == "" {
+		namespace = receiver.Namespace
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s", receiver.Namespace, receiver.Name, resource.Kind, namespace, resource.Name)
+}
+
+// coalesceWindow returns the coalesce window of the receiver, zero when the triggers aren't coalesced.
+func coalesceWindow(receiver v1beta1.Receiver) time.Duration {
+	if receiver.Spec.CoalesceWindow == nil {
+		return 0
+	}
+	return receiver.Spec.CoalesceWindow.Duration
+}
+
+// coalesce holds the triggers of the resources selected by the result until the
+// coalesce window of the receiver elapses, and returns the number of resources held.
+func (s *ReceiverServer) coalesce(receiver v1beta1.Receiver, result receivers.Result, annotations map[string]string) int {
+	window := coalesceWindow(receiver)
+	held := 0
+	for _, resource := range receiver.Spec.Resources {
+		if !result.Selects(resource) {
+			continue
+		}
+		hints := trigger.ImageHintAnnotations(receiver, resource, result.Tag, result.Digest)
+		s.coalescer.add(coalescedTrigger{
+			receiver:    receiver,
+			resource:    resource,
+			annotations: withAnnotations(annotations, hints),
+		}, window)
+		held++
+	}
+	return held
+}
+
+// applyCoalesced applies the action of the receiver to the resource once its coalesce window elapsed.
+func (s *ReceiverServer) applyCoalesced(t coalescedTrigger) {
+	logger := s.logger.WithValues(
+		"reconciler kind", v1beta1.ReceiverKind,
+		"name", t.receiver.Name,
+		"namespace", t.receiver.Namespace)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	action := t.receiver.GetAction()
+	err := trigger.Apply(ctx, s.kubeClient, t.resource, t.receiver.Namespace, action, t.annotations)
+	s.metrics.RecordAnnotationDuration(t.receiver, start)
+	if err != nil {
+		s.shedder.observe(err, time.Now())
+		logger.Error(err, fmt.Sprintf("unable to %s resource '%s/%s.%s' after coalescing %d triggers",
+			action, t.resource.Kind, t.resource.Name, t.resource.Namespace, t.count))
+		return
+	}
+	logger.Info(fmt.Sprintf("resource '%s/%s.%s' triggered with the %s action after coalescing %d triggers",
+		t.resource.Kind, t.resource.Name, t.resource.Namespace, action, t.count))
+}
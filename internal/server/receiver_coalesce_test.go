@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestCoalescer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var mu sync.Mutex
+	var applied []coalescedTrigger
+	c := newCoalescer(func(t coalescedTrigger) {
+		mu.Lock()
+		defer mu.Unlock()
+		applied = append(applied, t)
+	})
+	snapshot := func() []coalescedTrigger {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]coalescedTrigger(nil), applied...)
+	}
+
+	receiver := v1beta1.Receiver{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"}}
+	webapp := v1beta1.CrossNamespaceObjectReference{Kind: "ImageRepository", Name: "webapp"}
+	backend := v1beta1.CrossNamespaceObjectReference{Kind: "ImageRepository", Name: "backend"}
+	for _, tag := range []string{"amd64", "arm64", "1.0.0"} {
+		c.add(coalescedTrigger{receiver: receiver, resource: webapp, annotations: map[string]string{"tag": tag}}, 50*time.Millisecond)
+	}
+	c.add(coalescedTrigger{receiver: receiver, resource: backend}, time.Hour)
+
+	g.Eventually(snapshot).Should(gomega.HaveLen(1))
+	got := snapshot()[0]
+	g.Expect(got.resource).To(gomega.Equal(webapp))
+	g.Expect(got.count).To(gomega.Equal(3))
+	g.Expect(got.annotations).To(gomega.HaveKeyWithValue("tag", "1.0.0"))
+
+	// the pending triggers are applied on shutdown
+	c.flushAll()
+	g.Expect(snapshot()).To(gomega.HaveLen(2))
+	g.Expect(snapshot()[1].resource).To(gomega.Equal(backend))
+}
+
+func TestReceiverServer_coalesce(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "generic", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:           v1beta1.GenericReceiver,
+			SecretRef:      meta.LocalObjectReference{Name: "webhook-token"},
+			CoalesceWindow: &metav1.Duration{Duration: 100 * time.Millisecond},
+			Resources: []v1beta1.CrossNamespaceObjectReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "webapp"},
+			},
+		},
+		Status: v1beta1.ReceiverStatus{URL: "/hook/digest"},
+	}
+	meta.SetResourceCondition(receiver, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp", Namespace: "default"},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver, secret, configMap).Build()
+
+	configMapName := types.NamespacedName{Name: "webapp", Namespace: "default"}
+	var before corev1.ConfigMap
+	g.Expect(kubeClient.Get(context.TODO(), configMapName, &before)).To(gomega.Succeed())
+
+	s := NewReceiverServer(":0", logf.Log, kubeClient, NewReceiverMetrics(), 0)
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", nil)
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, r)
+		g.Expect(rec.Code).To(gomega.Equal(http.StatusAccepted))
+	}
+
+	annotated := func() corev1.ConfigMap {
+		var got corev1.ConfigMap
+		g.Expect(kubeClient.Get(context.TODO(), configMapName, &got)).To(gomega.Succeed())
+		return got
+	}
+	g.Eventually(func() map[string]string {
+		return annotated().Annotations
+	}).Should(gomega.HaveKey(meta.ReconcileRequestAnnotation))
+
+	// the three triggers result in a single update
+	g.Consistently(func() string {
+		return annotated().ResourceVersion
+	}, 300*time.Millisecond).Should(gomega.Equal(incrementResourceVersion(g, before.ResourceVersion)))
+}
+
+func incrementResourceVersion(g *gomega.WithT, version string) string {
+	v, err := strconv.Atoi(version)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return strconv.Itoa(v + 1)
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// dependencyBotBranchPrefixes are the prefixes of the branches of the pull
+// requests opened by Dependabot and Renovate.
+var dependencyBotBranchPrefixes = []string{"dependabot/", "renovate/"}
+
+// dependencyBotUsers are the GitHub users of Dependabot and Renovate.
+var dependencyBotUsers = map[string]bool{
+	"dependabot[bot]": true,
+	"renovate[bot]":   true,
+}
+
+// dependencyBotPayload is the union of the GitHub pull_request
+// and dependabot_alert payloads handled by the receiver.
+type dependencyBotPayload struct {
+	Action string `json:"action"`
+
+	// pull_request
+	PullRequest *struct {
+		Merged bool `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Head struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+
+	// dependabot_alert
+	Alert *struct {
+		Dependency struct {
+			Package struct {
+				Ecosystem string `json:"ecosystem"`
+				Name      string `json:"name"`
+			} `json:"package"`
+		} `json:"dependency"`
+	} `json:"alert"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// event returns the receiver event and the package manager of the payload,
+// the event is empty if the payload isn't handled.
+func (p dependencyBotPayload) event(githubEvent string) (string, string) {
+	switch {
+	case githubEvent == "pull_request" && p.PullRequest != nil:
+		pr := p.PullRequest
+		if p.Action != "closed" || !pr.Merged {
+			return "", ""
+		}
+		for _, prefix := range dependencyBotBranchPrefixes {
+			if strings.HasPrefix(pr.Head.Ref, prefix) {
+				return "merged", branchPackageManager(strings.TrimPrefix(pr.Head.Ref, prefix))
+			}
+		}
+		if dependencyBotUsers[pr.User.Login] {
+			return "merged", ""
+		}
+	case githubEvent == "dependabot_alert" && p.Alert != nil:
+		return "alert:" + p.Action, p.Alert.Dependency.Package.Ecosystem
+	}
+	return "", ""
+}
+
+// branchPackageManager returns the first element of the branch name after the bot
+// prefix, i.e. the ecosystem of the Dependabot branches and the manager of the
+// Renovate branches with the '{{manager}}/' additional branch prefix.
+func branchPackageManager(branch string) string {
+	if i := strings.Index(branch, "/"); i > 0 {
+		return branch[:i]
+	}
+	return ""
+}
+
+// verifyDependencyBot checks the GitHub signature and handles the merged pull
+// requests of Dependabot and Renovate and the Dependabot alerts. Only the
+// Git repositories of the receiver cloning the repository of the event
+// are triggered, along with the other resources of the receiver.
+func verifyDependencyBot(ctx context.Context, r receivers.Request) error {
+	if r.Header.Get("X-Hub-Signature") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the GitHub signature header is missing")
+	}
+	b, err := github.ValidatePayload(r.Request, []byte(r.Token))
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "the GitHub signature header is invalid, err: %w", err)
+	}
+	r.SetReplayGuard(time.Time{}, r.Header.Get("X-Hub-Signature"))
+
+	var p dependencyBotPayload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "unable to parse GitHub payload, err: %w", err)
+	}
+
+	githubEvent := github.WebHookType(r.Request)
+	event, manager := p.event(githubEvent)
+	if event == "" {
+		return fmt.Errorf("%w: the GitHub event '%s' is not a dependency update", errEventFiltered, githubEvent)
+	}
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the dependency bot event '%s' is not authorised", event)
+	}
+
+	repository := p.Repository.FullName
+	if filter := r.Receiver.Spec.Filter; filter != nil {
+		if !matchAny(filter.Repositories, repository) {
+			return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, repository)
+		}
+		if len(filter.PackageManagers) > 0 && (manager == "" || !matchAny(filter.PackageManagers, manager)) {
+			return fmt.Errorf("%w: package manager '%s' does not match", errEventFiltered, manager)
+		}
+	}
+
+	resources := dependencyBotResources(ctx, r, repository)
+	if len(resources) == 0 {
+		return fmt.Errorf("%w: no Git repository clones '%s'", errEventFiltered, repository)
+	}
+
+	r.SetEvent(event)
+	r.SetResources(resources)
+	r.Logger.Info(fmt.Sprintf("handling dependency bot event: %s from %s", event, repository))
+	return nil
+}
+
+// dependencyBotResources returns the names of the receiver resources triggered by an
+// event of the repository, the Git repositories are read to compare their URL, the
+// ones that can't be read are triggered. None are triggered when the receiver has
+// Git repositories and none of them clones the repository.
+func dependencyBotResources(ctx context.Context, r receivers.Request, repository string) []string {
+	var names []string
+	var gitRepositories, cloning int
+	for _, resource := range r.Receiver.Spec.Resources {
+		name := resource.Kind + "/" + resource.Name
+		if resource.Kind != "GitRepository" || r.KubeClient == nil {
+			names = append(names, name)
+			continue
+		}
+
+		gitRepositories++
+		u, err := trigger.Get(ctx, r.KubeClient, resource, r.Receiver.Namespace)
+		if err != nil {
+			r.Logger.Error(err, "unable to read the Git repository URL, triggering it")
+			names = append(names, name)
+			cloning++
+			continue
+		}
+		url, _, _ := unstructured.NestedString(u.Object, "spec", "url")
+		if gitURLClones(url, repository) {
+			names = append(names, name)
+			cloning++
+		}
+	}
+	if gitRepositories > 0 && cloning == 0 {
+		return nil
+	}
+	return names
+}
+
+// gitURLClones returns true if the Git URL is the one of the repository full name,
+// e.g. 'https://github.com/org/repo.git' or 'git@github.com:org/repo'.
+func gitURLClones(url, repository string) bool {
+	if repository == "" {
+		return false
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(url), "/"), ".git")
+	repository = strings.ToLower(repository)
+	return strings.HasSuffix(url, "/"+repository) || strings.HasSuffix(url, ":"+repository)
+}
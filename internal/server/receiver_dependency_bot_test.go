@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestGitURLClones(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(gitURLClones("https://github.com/org/webapp", "org/webapp")).To(gomega.BeTrue())
+	g.Expect(gitURLClones("https://github.com/Org/WebApp.git/", "org/webapp")).To(gomega.BeTrue())
+	g.Expect(gitURLClones("ssh://git@github.com/org/webapp", "org/webapp")).To(gomega.BeTrue())
+	g.Expect(gitURLClones("git@github.com:org/webapp.git", "org/webapp")).To(gomega.BeTrue())
+	g.Expect(gitURLClones("https://github.com/org/webapp-config", "org/webapp")).To(gomega.BeFalse())
+	g.Expect(gitURLClones("https://github.com/other-org/webapp", "org/webapp")).To(gomega.BeFalse())
+	g.Expect(gitURLClones("https://github.com/org/webapp", "")).To(gomega.BeFalse())
+}
+
+func TestReceiverServer_validateDependencyBot(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	newGitRepository := func(name, url string) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{}
+		object.SetAPIVersion("source.toolkit.fluxcd.io/v1beta1")
+		object.SetKind("GitRepository")
+		object.SetName(name)
+		object.SetNamespace("default")
+		g.Expect(unstructured.SetNestedField(object.Object, url, "spec", "url")).To(gomega.Succeed())
+		return object
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(secret,
+			newGitRepository("webapp", "https://github.com/org/webapp.git"),
+			newGitRepository("backend", "ssh://git@github.com/org/backend")).
+		Build()
+	s := NewReceiverServer(":0", logf.Log, kubeClient, nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "dependency-bot", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.DependencyBotReceiver,
+			Events:    []string{"merged", "alert:fixed"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Filter: &v1beta1.ReceiverFilter{
+				Repositories:    []string{"org/*"},
+				PackageManagers: []string{"npm*", "gomod", "go_modules"},
+			},
+			Resources: []v1beta1.CrossNamespaceObjectReference{
+				{Kind: "GitRepository", Name: "webapp"},
+				{Kind: "GitRepository", Name: "backend"},
+				{Kind: "Kustomization", Name: "apps"},
+			},
+		},
+	}
+	request := func(event, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/dependency-bot", bytes.NewReader([]byte(body)))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", event)
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		_, _ = mac.Write([]byte(body))
+		r.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return r
+	}
+	pullRequest := func(merged bool, login, ref, repository string) string {
+		return `{"action":"closed","pull_request":{"merged":` + map[bool]string{true: "true", false: "false"}[merged] +
+			`,"user":{"login":"` + login + `"},"head":{"ref":"` + ref + `"}},"repository":{"full_name":"` + repository + `"}}`
+	}
+
+	ctx := context.Background()
+	unsigned := request("pull_request", pullRequest(true, "dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "org/webapp"))
+	unsigned.Header.Del("X-Hub-Signature")
+	g.Expect(s.validate(ctx, receiver, unsigned)).NotTo(gomega.Succeed())
+
+	for _, body := range []string{
+		pullRequest(false, "dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "org/webapp"),
+		pullRequest(true, "jane", "feature/login", "org/webapp"),
+		pullRequest(true, "dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "infra/proxy"),
+		pullRequest(true, "dependabot[bot]", "dependabot/pip/requests-2.26.0", "org/webapp"),
+		pullRequest(true, "renovate[bot]", "renovate/lodash-4.x", "org/webapp"),
+		pullRequest(true, "dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "org/frontend"),
+	} {
+		g.Expect(s.validate(ctx, receiver, request("pull_request", body))).To(gomega.MatchError(errEventFiltered), body)
+	}
+	g.Expect(s.validate(ctx, receiver, request("push", `{"ref":"refs/heads/main"}`))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request("dependabot_alert",
+		`{"action":"created","alert":{"dependency":{"package":{"ecosystem":"npm"}}},"repository":{"full_name":"org/webapp"}}`))).
+		NotTo(gomega.Succeed())
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request("pull_request",
+		pullRequest(true, "dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "org/webapp")), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("merged"))
+	g.Expect(result.Resources).To(gomega.Equal([]string{"GitRepository/webapp", "Kustomization/apps"}))
+
+	result = receivers.Result{}
+	g.Expect(s.verify(ctx, receiver, request("pull_request",
+		pullRequest(true, "renovate[bot]", "renovate/gomod/golang.org-x-net-0.x", "org/backend")), &result)).To(gomega.Succeed())
+	g.Expect(result.Resources).To(gomega.Equal([]string{"GitRepository/backend", "Kustomization/apps"}))
+
+	result = receivers.Result{}
+	g.Expect(s.verify(ctx, receiver, request("dependabot_alert",
+		`{"action":"fixed","alert":{"dependency":{"package":{"ecosystem":"npm","name":"lodash"}}},"repository":{"full_name":"org/webapp"}}`),
+		&result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("alert:fixed"))
+	g.Expect(result.Resources).To(gomega.Equal([]string{"GitRepository/webapp", "Kustomization/apps"}))
+}
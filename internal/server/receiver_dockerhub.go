@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+var (
+	dockerHubClient = &http.Client{Timeout: 10 * time.Second}
+
+	// dockerHubCallbackHosts are the hosts of the DockerHub callback URLs.
+	dockerHubCallbackHosts = map[string]bool{"registry.hub.docker.com": true}
+)
+
+// dockerHubVerifier posts the outcome of the deliveries
+// to the callback URL of the DockerHub payloads.
+type dockerHubVerifier struct {
+	receivers.VerifierFunc
+}
+
+// dockerHubPayload is the payload of the DockerHub push webhooks.
+type dockerHubPayload struct {
+	CallbackURL string `json:"callback_url"`
+	PushData    struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		URL string `json:"repo_url"`
+	} `json:"repository"`
+}
+
+// dockerHubCallback is the body of the DockerHub callbacks, the state
+// is one of 'success', 'failure' or 'error'.
+type dockerHubCallback struct {
+	State       string `json:"state"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context,omitempty"`
+}
+
+// verifyDockerHub decodes the DockerHub payload and matches the pushed tag against the
+// tag patterns of the receiver events, DockerHub doesn't sign its requests.
+func verifyDockerHub(ctx context.Context, r receivers.Request) error {
+	var p dockerHubPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode DockerHub webhook payload")
+	}
+
+	if !trigger.MatchTag(r.Receiver.Spec.Events, p.PushData.Tag) {
+		return fmt.Errorf("%w: tag '%s' does not match", errEventFiltered, p.PushData.Tag)
+	}
+
+	// DockerHub only sends push events
+	r.SetEvent("push")
+	r.SetImage(p.PushData.Tag, "")
+	r.Logger.Info(fmt.Sprintf("handling DockerHub event from %s for tag %s", p.Repository.URL, p.PushData.Tag))
+	return nil
+}
+
+// Acknowledge posts the outcome of the delivery to the callback URL, so that
+// DockerHub marks the delivery as validated and runs the next webhook of the chain.
+func (dockerHubVerifier) Acknowledge(ctx context.Context, receiver v1beta1.Receiver, payload []byte, failed bool) error {
+	var p dockerHubPayload
+	if err := json.Unmarshal(payload, &p); err != nil || p.CallbackURL == "" {
+		return nil
+	}
+	if err := checkDockerHubURL(p.CallbackURL); err != nil {
+		return err
+	}
+
+	callback := dockerHubCallback{
+		State:       "success",
+		Description: fmt.Sprintf("Receiver %s/%s triggered the reconciliation of %s", receiver.Namespace, receiver.Name, p.PushData.Tag),
+		Context:     reportingController,
+	}
+	if failed {
+		callback.State = "failure"
+		callback.Description = fmt.Sprintf("Receiver %s/%s failed to trigger the reconciliation of %s", receiver.Namespace, receiver.Name, p.PushData.Tag)
+	}
+	body, err := json.Marshal(callback)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := dockerHubClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot post the DockerHub callback: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("cannot post the DockerHub callback, status: %s", resp.Status)
+	}
+	return nil
+}
+
+// checkDockerHubURL rejects the callback URLs not served by DockerHub over HTTPS,
+// so that a forged payload can't make the controller post to an arbitrary URL.
+func checkDockerHubURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid DockerHub callback URL '%s': %w", rawURL, err)
+	}
+	if u.Scheme != "https" || !dockerHubCallbackHosts[u.Host] {
+		return fmt.Errorf("the callback URL '%s' is not served by DockerHub", rawURL)
+	}
+	return nil
+}
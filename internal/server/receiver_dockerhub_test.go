@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestReceiverServer_validateDockerHub(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "dockerhub", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.DockerHubReceiver,
+			Events:    []string{"v1.*", `/^[0-9]+\.[0-9]+\.[0-9]+$/`},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+		},
+	}
+	request := func(tag string) *http.Request {
+		body := `{"push_data":{"tag":"` + tag + `"},"repository":{"repo_url":"https://hub.docker.com/r/org/webapp"}}`
+		return httptest.NewRequest(http.MethodPost, "/hook/dockerhub", bytes.NewReader([]byte(body)))
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("latest"))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request("1.2.0-rc.1"))).To(gomega.MatchError(errEventFiltered))
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request("1.2.0"), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("push"))
+	g.Expect(result.Tag).To(gomega.Equal("1.2.0"))
+	g.Expect(s.validate(ctx, receiver, request("v1.3"))).To(gomega.Succeed())
+}
+
+func TestDockerHubVerifier_Acknowledge(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	callbacks := make(chan dockerHubCallback, 10)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c dockerHubCallback
+		g.Expect(json.NewDecoder(r.Body).Decode(&c)).To(gomega.Succeed())
+		callbacks <- c
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer func(client *http.Client, hosts map[string]bool) {
+		dockerHubClient, dockerHubCallbackHosts = client, hosts
+	}(dockerHubClient, dockerHubCallbackHosts)
+	dockerHubClient = ts.Client()
+	dockerHubCallbackHosts = map[string]bool{u.Host: true}
+
+	receiver := v1beta1.Receiver{ObjectMeta: metav1.ObjectMeta{Name: "dockerhub", Namespace: "default"}}
+	payload := func(callbackURL string) []byte {
+		return []byte(`{"callback_url":"` + callbackURL + `","push_data":{"tag":"1.2.0"}}`)
+	}
+	v := dockerHubVerifier{receivers.VerifierFunc(verifyDockerHub)}
+	ctx := context.Background()
+
+	g.Expect(v.Acknowledge(ctx, receiver, payload(ts.URL+"/u/org/webapp/hook/2141b5bi5i5b02bec211i4eeih0242eg11000a/"), false)).To(gomega.Succeed())
+	g.Expect(<-callbacks).To(gomega.Equal(dockerHubCallback{
+		State:       "success",
+		Description: "Receiver default/dockerhub triggered the reconciliation of 1.2.0",
+		Context:     "notification-controller",
+	}))
+
+	g.Expect(v.Acknowledge(ctx, receiver, payload(ts.URL+"/hook"), true)).To(gomega.Succeed())
+	g.Expect((<-callbacks).State).To(gomega.Equal("failure"))
+
+	// the callback URLs not served by DockerHub are rejected
+	g.Expect(v.Acknowledge(ctx, receiver, payload("https://example.com/hook"), false)).NotTo(gomega.Succeed())
+	g.Expect(v.Acknowledge(ctx, receiver, payload("http://"+u.Host+"/hook"), false)).NotTo(gomega.Succeed())
+	g.Expect(callbacks).To(gomega.BeEmpty())
+
+	g.Expect(v.Acknowledge(ctx, receiver, []byte(`{"push_data":{"tag":"1.2.0"}}`), false)).To(gomega.Succeed())
+}
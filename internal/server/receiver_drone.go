@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// httpSignatureMaxSkew is the maximum difference between the signed
+// date of a request and the controller clock.
+const httpSignatureMaxSkew = 5 * time.Minute
+
+// httpSignature is the Signature header of a request signed with the
+// HTTP signatures draft, e.g. 'keyId="hmac-key",algorithm="hmac-sha256",
+// signature="...",headers="date digest"'.
+type httpSignature struct {
+	keyID     string
+	algorithm string
+	headers   []string
+	signature []byte
+}
+
+// parseHTTPSignature decodes the parameters of the Signature header,
+// the signed headers default to the date header.
+func parseHTTPSignature(header string) (httpSignature, error) {
+	var s httpSignature
+	params := make(map[string]string)
+	for _, param := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			return s, fmt.Errorf("invalid signature parameter '%s'", param)
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+
+	s.keyID = params["keyId"]
+	s.algorithm = params["algorithm"]
+	if s.algorithm != "hmac-sha256" {
+		return s, fmt.Errorf("unsupported signature algorithm '%s'", s.algorithm)
+	}
+	s.headers = []string{"date"}
+	if h := params["headers"]; h != "" {
+		s.headers = strings.Fields(strings.ToLower(h))
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(signature) == 0 {
+		return s, fmt.Errorf("invalid signature '%s'", params["signature"])
+	}
+	s.signature = signature
+	return s, nil
+}
+
+// signingString returns the signed headers of the request, a line per header.
+func (s httpSignature) signingString(r *http.Request) (string, error) {
+	lines := make([]string, 0, len(s.headers))
+	for _, h := range s.headers {
+		if h == "(request-target)" {
+			lines = append(lines, fmt.Sprintf("%s: %s %s", h, strings.ToLower(r.Method), r.URL.RequestURI()))
+			continue
+		}
+		values := r.Header.Values(h)
+		if len(values) == 0 {
+			return "", fmt.Errorf("the signed header '%s' is missing", h)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", h, strings.Join(values, ", ")))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// signs returns true if the header is part of the signature.
+func (s httpSignature) signs(header string) bool {
+	for _, h := range s.headers {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyHTTPSignature checks the HMAC SHA256 HTTP signature of the request,
+// along with the digest of the payload and the date when they're signed.
+func verifyHTTPSignature(r *http.Request, payload []byte, key []byte, now time.Time) error {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the Signature header is missing")
+	}
+	s, err := parseHTTPSignature(header)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "%w", err)
+	}
+	if !s.signs("digest") {
+		return receivers.Errorf(receivers.InvalidSignature, "the payload digest isn't signed")
+	}
+
+	signingString, err := s.signingString(r)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "%w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(signingString))
+	if !hmac.Equal(s.signature, mac.Sum(nil)) {
+		return receivers.Errorf(receivers.InvalidSignature, "the signature of key '%s' doesn't match", s.keyID)
+	}
+
+	digest := sha256.Sum256(payload)
+	expected := "SHA-256=" + base64.StdEncoding.EncodeToString(digest[:])
+	if !hmac.Equal([]byte(r.Header.Get("Digest")), []byte(expected)) {
+		return receivers.Errorf(receivers.InvalidSignature, "the payload digest doesn't match")
+	}
+
+	if s.signs("date") {
+		date, err := http.ParseTime(r.Header.Get("Date"))
+		if err != nil {
+			return receivers.Errorf(receivers.InvalidSignature, "invalid Date header, err: %w", err)
+		}
+		if skew := now.Sub(date); skew > httpSignatureMaxSkew || skew < -httpSignatureMaxSkew {
+			return receivers.Errorf(receivers.InvalidSignature, "the signed date %s is too far from the current time", date.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// httpSignatureDate returns the Date header of the request if it's part
+// of the HTTP signature, zero otherwise.
+func httpSignatureDate(r *http.Request) time.Time {
+	s, err := parseHTTPSignature(r.Header.Get("Signature"))
+	if err != nil || !s.signs("date") {
+		return time.Time{}
+	}
+	date, _ := http.ParseTime(r.Header.Get("Date"))
+	return date
+}
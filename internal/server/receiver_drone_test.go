@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestVerifyHTTPSignature(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"event":"build"}`)
+	digest := sha256.Sum256(payload)
+
+	request := func(key, headers string, date time.Time, body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/drone", bytes.NewReader(body))
+		r.Header.Set("Date", date.Format(http.TimeFormat))
+		r.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+
+		signingString := fmt.Sprintf("date: %s\ndigest: %s", r.Header.Get("Date"), r.Header.Get("Digest"))
+		if headers == "(request-target) digest" {
+			signingString = fmt.Sprintf("(request-target): post /hook/drone\ndigest: %s", r.Header.Get("Digest"))
+		}
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(signingString))
+		r.Header.Set("Signature", fmt.Sprintf(`keyId="hmac-key",algorithm="hmac-sha256",signature="%s",headers="%s"`,
+			base64.StdEncoding.EncodeToString(mac.Sum(nil)), headers))
+		return r
+	}
+
+	g.Expect(verifyHTTPSignature(request("s3cr3t", "date digest", now, payload), payload, []byte("s3cr3t"), now)).To(gomega.Succeed())
+	g.Expect(verifyHTTPSignature(request("s3cr3t", "(request-target) digest", now, payload), payload, []byte("s3cr3t"), now)).To(gomega.Succeed())
+
+	err := verifyHTTPSignature(request("forged", "date digest", now, payload), payload, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	tampered := []byte(`{"event":"repo"}`)
+	err = verifyHTTPSignature(request("s3cr3t", "date digest", now, tampered), tampered, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	err = verifyHTTPSignature(request("s3cr3t", "date digest", now.Add(-10*time.Minute), payload), payload, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	err = verifyHTTPSignature(request("s3cr3t", "date", now, payload), payload, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	unsigned := request("s3cr3t", "date digest", now, payload)
+	unsigned.Header.Del("Signature")
+	err = verifyHTTPSignature(unsigned, payload, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.MissingSignature))
+}
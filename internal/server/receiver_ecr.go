@@ -0,0 +1,287 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	snsNotification             = "Notification"
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+	snsUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+
+	// ecrEventSource is the source of the EventBridge events sent by ECR.
+	ecrEventSource = "aws.ecr"
+)
+
+// snsHost matches the hosts serving the SNS signing certificates
+// and the subscription confirmation URLs.
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+var (
+	snsClient = &http.Client{Timeout: 10 * time.Second}
+
+	// snsCertificates caches the SNS signing certificates by URL.
+	snsCertificates = newSNSCertificateCache(snsClient)
+)
+
+// snsMessage is an SNS message delivered to an HTTPS subscription.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// ecrEvent is an ECR image action delivered by EventBridge.
+type ecrEvent struct {
+	DetailType string `json:"detail-type"`
+	Source     string `json:"source"`
+	Region     string `json:"region"`
+	Detail     struct {
+		Result         string `json:"result"`
+		RepositoryName string `json:"repository-name"`
+		ImageDigest    string `json:"image-digest"`
+		ImageTag       string `json:"image-tag"`
+		ActionType     string `json:"action-type"`
+	} `json:"detail"`
+}
+
+// ecrVerifier unwraps the ECR events from their SNS notification.
+type ecrVerifier struct {
+	receivers.VerifierFunc
+}
+
+// Unwrap returns the EventBridge event held by the SNS notification.
+func (ecrVerifier) Unwrap(payload []byte) []byte {
+	var m snsMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return payload
+	}
+	return []byte(m.Message)
+}
+
+// parseSNSMessage decodes the SNS message and verifies its signature.
+func parseSNSMessage(body []byte) (snsMessage, error) {
+	var m snsMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, receivers.Errorf(receivers.InvalidPayload, "cannot decode SNS message: %w", err)
+	}
+	if m.Signature == "" {
+		return m, receivers.Errorf(receivers.MissingSignature, "the SNS message is not signed")
+	}
+	if err := m.verify(snsCertificates); err != nil {
+		return m, receivers.Errorf(receivers.InvalidSignature, "%w", err)
+	}
+	return m, nil
+}
+
+// verify checks the signature of the message with the SNS signing certificate.
+func (m snsMessage) verify(certificates *snsCertificateCache) error {
+	var hash crypto.Hash
+	switch m.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported SNS signature version '%s'", m.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("cannot decode SNS signature: %w", err)
+	}
+
+	cert, err := certificates.get(m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the SNS signing certificate doesn't hold an RSA key")
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(m.stringToSign()))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(m.stringToSign()))
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return fmt.Errorf("invalid SNS signature: %w", err)
+	}
+	return nil
+}
+
+// stringToSign returns the fields signed by SNS, in the order of the message type.
+func (m snsMessage) stringToSign() string {
+	var fields [][2]string
+	if m.Type == snsNotification {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"Subject", m.Subject},
+			{"Timestamp", m.Timestamp},
+			{"TopicArn", m.TopicArn},
+			{"Type", m.Type},
+		}
+	} else {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.Timestamp},
+			{"Token", m.Token},
+			{"TopicArn", m.TopicArn},
+			{"Type", m.Type},
+		}
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		// the subject is only signed when the notification has one
+		if f[0] == "Subject" && f[1] == "" {
+			continue
+		}
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+	return b.String()
+}
+
+// confirm visits the subscription URL, SNS delivers the notifications
+// to the receiver once the subscription is confirmed.
+func (m snsMessage) confirm(c *http.Client) error {
+	if err := checkSNSURL(m.SubscribeURL); err != nil {
+		return err
+	}
+	resp, err := c.Get(m.SubscribeURL)
+	if err != nil {
+		return fmt.Errorf("cannot confirm the SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot confirm the SNS subscription, status: %s", resp.Status)
+	}
+	return nil
+}
+
+// checkSNSURL rejects the URLs not served by SNS over HTTPS, so that a forged
+// message can't make the controller fetch an arbitrary URL.
+func checkSNSURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid SNS URL '%s': %w", rawURL, err)
+	}
+	if u.Scheme != "https" || !snsHost.MatchString(u.Host) {
+		return fmt.Errorf("the URL '%s' is not served by SNS", rawURL)
+	}
+	return nil
+}
+
+// parseECREvent decodes the EventBridge event held by an SNS notification.
+func parseECREvent(m snsMessage) (ecrEvent, error) {
+	var e ecrEvent
+	if err := json.Unmarshal([]byte(m.Message), &e); err != nil {
+		return e, fmt.Errorf("cannot decode ECR event: %w", err)
+	}
+	if e.Source != ecrEventSource {
+		return e, fmt.Errorf("the event source '%s' is not ECR", e.Source)
+	}
+	return e, nil
+}
+
+type snsCertificateCache struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newSNSCertificateCache(client *http.Client) *snsCertificateCache {
+	return &snsCertificateCache{
+		client: client,
+		certs:  make(map[string]*x509.Certificate),
+	}
+}
+
+// get returns the certificate, it's downloaded on first use.
+func (c *snsCertificateCache) get(certURL string) (*x509.Certificate, error) {
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cert, ok := c.certs[certURL]
+	c.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	resp, err := c.client.Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download the SNS signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download the SNS signing certificate, status: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download the SNS signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the SNS signing certificate is not PEM encoded")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the SNS signing certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.certs[certURL] = cert
+	c.mu.Unlock()
+	return cert, nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const testSigningCertURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// signingKey adds a signing certificate to the cache and returns its key.
+func signingKey(g *gomega.WithT) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	snsCertificates.mu.Lock()
+	snsCertificates.certs[testSigningCertURL] = cert
+	snsCertificates.mu.Unlock()
+	return key
+}
+
+func signedSNSMessage(g *gomega.WithT, key *rsa.PrivateKey, m snsMessage) []byte {
+	m.SignatureVersion = "2"
+	m.SigningCertURL = testSigningCertURL
+	digest := sha256.Sum256([]byte(m.stringToSign()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	b, err := json.Marshal(m)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return b
+}
+
+func TestReceiverServer_validateECR(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	key := signingKey(g)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "ecr", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.ECRReceiver,
+			Events:    []string{"PUSH"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Topics:    []string{"arn:aws:sns:us-east-1:123456789012:ecr"},
+			Filter:    &v1beta1.ReceiverFilter{Repositories: []string{"apps/*"}},
+			Annotation: &v1beta1.ReceiverAnnotation{
+				ValueFrom:  v1beta1.PayloadAnnotationValue,
+				Expression: "request.body.detail['image-tag']",
+			},
+		},
+	}
+
+	topicNotification := func(topic, repository, action, result string) []byte {
+		event := `{"detail-type":"ECR Image Action","source":"aws.ecr","region":"us-east-1","detail":{` +
+			`"result":"` + result + `","repository-name":"` + repository + `",` +
+			`"image-digest":"sha256:7f5b","image-tag":"1.2.3","action-type":"` + action + `"}}`
+		return signedSNSMessage(g, key, snsMessage{
+			Type:      snsNotification,
+			MessageID: "a1b2",
+			TopicArn:  topic,
+			Message:   event,
+			Timestamp: "2021-05-01T10:00:00.000Z",
+		})
+	}
+	notification := func(repository, action, result string) []byte {
+		return topicNotification("arn:aws:sns:us-east-1:123456789012:ecr", repository, action, result)
+	}
+	request := func(body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/ecr", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+		r.Header.Set("X-Amz-Sns-Message-Type", snsNotification)
+		return r
+	}
+
+	ctx := context.Background()
+	push := notification("apps/webapp", "PUSH", "SUCCESS")
+	g.Expect(s.validate(ctx, receiver, request(push))).To(gomega.Succeed())
+
+	value, err := trigger.AnnotationValue(nil, receiver, request(push), eventPayload(receiver, push), time.Now())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(value).To(gomega.Equal("1.2.3"))
+
+	err = s.validate(ctx, receiver, request(notification("infra/proxy", "PUSH", "SUCCESS")))
+	g.Expect(err).To(gomega.MatchError(errEventFiltered))
+	err = s.validate(ctx, receiver, request(notification("apps/webapp", "PUSH", "FAILURE")))
+	g.Expect(err).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request(notification("apps/webapp", "DELETE", "SUCCESS")))).NotTo(gomega.Succeed())
+
+	forged := bytes.Replace(push, []byte("1.2.3"), []byte("6.6.6"), 1)
+	g.Expect(s.validate(ctx, receiver, request(forged))).NotTo(gomega.Succeed())
+
+	var m snsMessage
+	g.Expect(json.Unmarshal(push, &m)).To(gomega.Succeed())
+	m.SigningCertURL = "https://example.com/SimpleNotificationService-test.pem"
+	b, err := json.Marshal(m)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(s.validate(ctx, receiver, request(b))).NotTo(gomega.Succeed())
+
+	// the subscription is confirmed without triggering the resources
+	confirmed := ""
+	snsClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		confirmed = r.URL.String()
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusOK)
+		return rec.Result(), nil
+	})
+	defer func() { snsClient.Transport = nil }()
+
+	subscribeURL := "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription&Token=2336"
+	confirmation := func(topic string) []byte {
+		return signedSNSMessage(g, key, snsMessage{
+			Type:         snsSubscriptionConfirmation,
+			MessageID:    "c3d4",
+			Token:        "2336",
+			TopicArn:     topic,
+			Message:      "You have chosen to subscribe to the topic",
+			SubscribeURL: subscribeURL,
+			Timestamp:    "2021-05-01T10:00:00.000Z",
+		})
+	}
+	err = s.validate(ctx, receiver, request(confirmation("arn:aws:sns:us-east-1:123456789012:ecr")))
+	g.Expect(err).To(gomega.MatchError(errEventFiltered))
+	g.Expect(confirmed).To(gomega.Equal(subscribeURL))
+
+	// the signed messages of the topics of other accounts are rejected,
+	// their subscriptions aren't confirmed
+	confirmed = ""
+	foreign := "arn:aws:sns:us-east-1:210987654321:ecr"
+	err = s.validate(ctx, receiver, request(confirmation(foreign)))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+	g.Expect(confirmed).To(gomega.BeEmpty())
+	err = s.validate(ctx, receiver, request(topicNotification(foreign, "apps/webapp", "PUSH", "SUCCESS")))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	// the receivers without topics reject all the messages
+	receiver.Spec.Topics = nil
+	g.Expect(s.validate(ctx, receiver, request(push))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request(confirmation("arn:aws:sns:us-east-1:123456789012:ecr")))).NotTo(gomega.Succeed())
+	g.Expect(confirmed).To(gomega.BeEmpty())
+}
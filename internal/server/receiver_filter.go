@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// filterGitHubEvent checks the parsed GitHub payload against the receiver filter.
+// The refs and paths filters are only applied to push events.
+func filterGitHubEvent(filter *v1beta1.ReceiverFilter, event interface{}) error {
+	if filter == nil {
+		return nil
+	}
+
+	switch e := event.(type) {
+	case *github.PushEvent:
+		if !matchAny(filter.Repositories, e.GetRepo().GetFullName()) {
+			return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, e.GetRepo().GetFullName())
+		}
+		if !matchAny(filter.Refs, e.GetRef()) {
+			return fmt.Errorf("%w: ref '%s' does not match", errEventFiltered, e.GetRef())
+		}
+		if len(filter.Paths) > 0 && !matchAnyPath(filter.Paths, changedFiles(e)) {
+			return fmt.Errorf("%w: no changed files match the paths filter", errEventFiltered)
+		}
+	case interface{ GetRepo() *github.Repository }:
+		if !matchAny(filter.Repositories, e.GetRepo().GetFullName()) {
+			return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, e.GetRepo().GetFullName())
+		}
+	}
+
+	return nil
+}
+
+// gitlabPayload holds the fields of GitLab project, group and system hook
+// payloads used for filtering.
+type gitlabPayload struct {
+	ObjectKind string `json:"object_kind"`
+	EventName  string `json:"event_name"`
+	Ref        string `json:"ref"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	// system hooks for project events carry the project path at the top level
+	PathWithNamespace string `json:"path_with_namespace"`
+	// group hooks for subgroup and member events don't carry a project
+	FullPath  string `json:"full_path"`
+	GroupPath string `json:"group_path"`
+	Commits   []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+	ObjectAttributes struct {
+		State string `json:"state"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+}
+
+// filterGitLabEvent checks the GitLab project, group or system hook payload against the receiver filter.
+// The refs and paths filters are only applied to push events.
+func filterGitLabEvent(filter *v1beta1.ReceiverFilter, body []byte) error {
+	if filter == nil {
+		return nil
+	}
+
+	var p gitlabPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "unable to decode GitLab payload, err: %w", err)
+	}
+
+	kind := p.ObjectKind
+	if kind == "" {
+		kind = p.EventName
+	}
+	if !matchAny(filter.ObjectKinds, kind) {
+		return fmt.Errorf("%w: object kind '%s' does not match", errEventFiltered, kind)
+	}
+
+	repository := p.Project.PathWithNamespace
+	if repository == "" {
+		repository = p.PathWithNamespace
+	}
+	if repository == "" {
+		repository = p.FullPath
+	}
+	if repository == "" {
+		repository = p.GroupPath
+	}
+	if !matchAny(filter.Repositories, repository) {
+		return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, repository)
+	}
+
+	switch kind {
+	case "push", "tag_push":
+		if !matchAny(filter.Refs, p.Ref) {
+			return fmt.Errorf("%w: ref '%s' does not match", errEventFiltered, p.Ref)
+		}
+		if len(filter.Paths) > 0 {
+			files := make([]string, 0)
+			for _, c := range p.Commits {
+				files = append(files, c.Added...)
+				files = append(files, c.Removed...)
+				files = append(files, c.Modified...)
+			}
+			if !matchAnyPath(filter.Paths, files) {
+				return fmt.Errorf("%w: no changed files match the paths filter", errEventFiltered)
+			}
+		}
+	case "merge_request":
+		if mr := filter.MergeRequest; mr != nil {
+			if !matchAny(mr.States, p.ObjectAttributes.State) {
+				return fmt.Errorf("%w: merge request state '%s' does not match", errEventFiltered, p.ObjectAttributes.State)
+			}
+			labels := make(map[string]bool, len(p.Labels))
+			for _, l := range p.Labels {
+				labels[l.Title] = true
+			}
+			for _, l := range mr.Labels {
+				if !labels[l] {
+					return fmt.Errorf("%w: merge request label '%s' is missing", errEventFiltered, l)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// changedFiles returns the files added, removed or modified by the push commits.
+func changedFiles(e *github.PushEvent) []string {
+	commits := e.Commits
+	if len(commits) == 0 && e.HeadCommit != nil {
+		commits = []*github.HeadCommit{e.HeadCommit}
+	}
+
+	files := make([]string, 0)
+	for _, c := range commits {
+		files = append(files, c.Added...)
+		files = append(files, c.Removed...)
+		files = append(files, c.Modified...)
+	}
+	return files
+}
+
+// matchAny returns true if the patterns list is empty
+// or if the value matches at least one pattern.
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchGlob(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyPath returns true if at least one of the files matches the patterns.
+func matchAnyPath(patterns []string, files []string) bool {
+	for _, f := range files {
+		for _, p := range patterns {
+			if matchGlob(p, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether name matches the slash separated glob pattern.
+// In addition to the path.Match syntax, a '**' element matches
+// zero or more path elements.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchFilterCondition evaluates the CEL condition of the receiver filter
+// with the message, e.g. a Pub/Sub message or a CloudEvent. The program
+// is compiled once per receiver generation.
+func matchFilterCondition(r receivers.Request, condition string, message interface{}) (bool, error) {
+	return evalFilterCondition(r, condition, map[string]interface{}{trigger.ConditionVariable: message})
+}
+
+// evalFilterCondition evaluates the CEL condition of the receiver filter with the variables.
+func evalFilterCondition(r receivers.Request, condition string, variables map[string]interface{}) (bool, error) {
+	program, err := trigger.Program(r.Programs, r.Receiver, condition)
+	if err != nil {
+		return false, err
+	}
+	return program.EvalBool(variables)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/onsi/gomega"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"refs/heads/main", "refs/heads/main", true},
+		{"refs/heads/*", "refs/heads/main", true},
+		{"refs/heads/*", "refs/heads/feature/foo", false},
+		{"refs/heads/**", "refs/heads/feature/foo", true},
+		{"org/*", "org/repo", true},
+		{"org/*", "other/repo", false},
+		{"apps/foo/**", "apps/foo/deployment.yaml", true},
+		{"apps/foo/**", "apps/foo/base/kustomization.yaml", true},
+		{"apps/foo/**", "apps/bar/deployment.yaml", false},
+		{"**/*.yaml", "apps/foo/deployment.yaml", true},
+		{"**/*.yaml", "README.md", false},
+		{"apps/**/kustomization.yaml", "apps/kustomization.yaml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			g.Expect(matchGlob(tt.pattern, tt.name)).To(gomega.Equal(tt.match))
+		})
+	}
+}
+
+func TestFilterGitHubEvent(t *testing.T) {
+	push := &github.PushEvent{
+		Ref: github.String("refs/heads/main"),
+		Repo: &github.PushEventRepository{
+			FullName: github.String("org/monorepo"),
+		},
+		Commits: []*github.HeadCommit{
+			{Modified: []string{"apps/foo/deployment.yaml"}},
+			{Added: []string{"docs/README.md"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   *v1beta1.ReceiverFilter
+		event    interface{}
+		filtered bool
+	}{
+		{
+			name:  "no filter",
+			event: push,
+		},
+		{
+			name: "matching filter",
+			filter: &v1beta1.ReceiverFilter{
+				Repositories: []string{"org/*"},
+				Refs:         []string{"refs/heads/main"},
+				Paths:        []string{"apps/foo/**"},
+			},
+			event: push,
+		},
+		{
+			name:     "repository mismatch",
+			filter:   &v1beta1.ReceiverFilter{Repositories: []string{"other/*"}},
+			event:    push,
+			filtered: true,
+		},
+		{
+			name:     "ref mismatch",
+			filter:   &v1beta1.ReceiverFilter{Refs: []string{"refs/tags/*"}},
+			event:    push,
+			filtered: true,
+		},
+		{
+			name:     "paths mismatch",
+			filter:   &v1beta1.ReceiverFilter{Paths: []string{"apps/bar/**"}},
+			event:    push,
+			filtered: true,
+		},
+		{
+			name:   "refs are ignored for non push events",
+			filter: &v1beta1.ReceiverFilter{Refs: []string{"refs/tags/*"}},
+			event: &github.PingEvent{
+				Hook: &github.Hook{},
+			},
+		},
+		{
+			name:   "repository is checked for non push events",
+			filter: &v1beta1.ReceiverFilter{Repositories: []string{"other/*"}},
+			event: &github.ReleaseEvent{
+				Repo: &github.Repository{FullName: github.String("org/monorepo")},
+			},
+			filtered: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			err := filterGitHubEvent(tt.filter, tt.event)
+			if tt.filtered {
+				g.Expect(errors.Is(err, errEventFiltered)).To(gomega.BeTrue())
+			} else {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestFilterGitLabEvent(t *testing.T) {
+	push := `{
+  "object_kind": "push",
+  "ref": "refs/heads/main",
+  "project": {"path_with_namespace": "group/monorepo"},
+  "commits": [{"added": [], "modified": ["apps/foo/deployment.yaml"], "removed": []}]
+}`
+	mergeRequest := `{
+  "object_kind": "merge_request",
+  "project": {"path_with_namespace": "group/monorepo"},
+  "object_attributes": {"state": "merged"},
+  "labels": [{"title": "deploy"}, {"title": "backend"}]
+}`
+	subgroup := `{
+  "event_name": "subgroup_create",
+  "full_path": "group/subgroup"
+}`
+
+	tests := []struct {
+		name     string
+		filter   *v1beta1.ReceiverFilter
+		payload  string
+		filtered bool
+	}{
+		{
+			name:    "push matches",
+			filter:  &v1beta1.ReceiverFilter{ObjectKinds: []string{"push"}, Refs: []string{"refs/heads/main"}, Paths: []string{"apps/foo/**"}},
+			payload: push,
+		},
+		{
+			name:     "push paths mismatch",
+			filter:   &v1beta1.ReceiverFilter{Paths: []string{"apps/bar/**"}},
+			payload:  push,
+			filtered: true,
+		},
+		{
+			name:     "object kind mismatch",
+			filter:   &v1beta1.ReceiverFilter{ObjectKinds: []string{"merge_request"}},
+			payload:  push,
+			filtered: true,
+		},
+		{
+			name: "merged merge request with labels",
+			filter: &v1beta1.ReceiverFilter{
+				ObjectKinds:  []string{"merge_request"},
+				MergeRequest: &v1beta1.MergeRequestFilter{States: []string{"merged"}, Labels: []string{"deploy"}},
+			},
+			payload: mergeRequest,
+		},
+		{
+			name: "merge request state mismatch",
+			filter: &v1beta1.ReceiverFilter{
+				MergeRequest: &v1beta1.MergeRequestFilter{States: []string{"opened"}},
+			},
+			payload:  mergeRequest,
+			filtered: true,
+		},
+		{
+			name: "merge request label missing",
+			filter: &v1beta1.ReceiverFilter{
+				MergeRequest: &v1beta1.MergeRequestFilter{Labels: []string{"frontend"}},
+			},
+			payload:  mergeRequest,
+			filtered: true,
+		},
+		{
+			name:    "group hook repository",
+			filter:  &v1beta1.ReceiverFilter{Repositories: []string{"group/*"}},
+			payload: subgroup,
+		},
+		{
+			name:    "system hook repository",
+			filter:  &v1beta1.ReceiverFilter{ObjectKinds: []string{"project_create"}, Repositories: []string{"group/*"}},
+			payload: `{"event_name": "project_create", "path_with_namespace": "group/webapp"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			err := filterGitLabEvent(tt.filter, []byte(tt.payload))
+			if tt.filtered {
+				g.Expect(errors.Is(err, errEventFiltered)).To(gomega.BeTrue())
+			} else {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+			}
+		})
+	}
+}
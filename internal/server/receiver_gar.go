@@ -0,0 +1,250 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// googleCertsURL serves the certificates signing the Google ID tokens.
+	googleCertsURL = "https://www.googleapis.com/oauth2/v1/certs"
+
+	// googleKeysTTL is the duration the signing keys are cached for,
+	// Google rotates them every few days.
+	googleKeysTTL = time.Hour
+
+	// googleKeysRefreshInterval limits the downloads of the signing keys
+	// caused by tokens with an unknown key ID.
+	googleKeysRefreshInterval = time.Minute
+)
+
+// googleIssuers are the issuers of the Google ID tokens.
+var googleIssuers = map[string]bool{
+	"accounts.google.com":         true,
+	"https://accounts.google.com": true,
+}
+
+var googleKeys = newGoogleKeyCache(&http.Client{Timeout: 10 * time.Second}, googleCertsURL)
+
+// pubsubPush is a Pub/Sub message delivered to a push subscription.
+type pubsubPush struct {
+	Message struct {
+		Data        string            `json:"data"`
+		Attributes  map[string]string `json:"attributes"`
+		MessageID   string            `json:"messageId"`
+		PublishTime time.Time         `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// garNotification is an Artifact Registry notification, the tag is
+// only set when the image is tagged.
+type garNotification struct {
+	Action string `json:"action"`
+	Digest string `json:"digest"`
+	Tag    string `json:"tag"`
+}
+
+// image returns the image name, without the tag or the digest,
+// e.g. 'us-docker.pkg.dev/project/repository/webapp'.
+func (n garNotification) image() string {
+	ref := n.Digest
+	if ref == "" {
+		ref = n.Tag
+	}
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
+
+// pubsubVerifier unwraps the data of the Pub/Sub messages, e.g. the
+// Artifact Registry notifications, for the annotation expressions.
+type pubsubVerifier struct {
+	receivers.VerifierFunc
+}
+
+// Unwrap returns the data held by the Pub/Sub message.
+func (pubsubVerifier) Unwrap(payload []byte) []byte {
+	data, err := pubsubData(payload)
+	if err != nil {
+		return payload
+	}
+	return data
+}
+
+// verifyPubSubJWT checks the Google signed ID token sent by Pub/Sub in the
+// Authorization header, the audience must be the URL of the receiver.
+func verifyPubSubJWT(r *http.Request, now time.Time) (*jwtClaims, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, receivers.Errorf(receivers.MissingSignature, "the Authorization header is missing or malformed")
+	}
+
+	claims, err := verifyRS256JWT(strings.TrimPrefix(auth, "Bearer "), func(kid string) (*rsa.PublicKey, error) {
+		return googleKeys.get(kid, now)
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !googleIssuers[claims.Issuer] {
+		return nil, fmt.Errorf("the JWT issuer '%s' is not Google", claims.Issuer)
+	}
+	// the path is compared as the receiver may be exposed under any host
+	audience, err := url.Parse(claims.Audience)
+	if err != nil || audience.Path != r.URL.Path {
+		return nil, fmt.Errorf("the JWT audience '%s' is not the receiver URL", claims.Audience)
+	}
+	return claims, nil
+}
+
+// verifyPushServiceAccount checks that the JWT is issued to a verified email of
+// the allowed service accounts, any Google account can sign a token for the
+// receiver URL.
+func verifyPushServiceAccount(claims *jwtClaims, accounts []string) error {
+	if len(accounts) == 0 {
+		return fmt.Errorf("the receiver has no push service accounts")
+	}
+	if !claims.EmailVerified {
+		return fmt.Errorf("the JWT email '%s' is not verified", claims.Email)
+	}
+	if !containsString(accounts, claims.Email) {
+		return fmt.Errorf("the JWT email '%s' is not a push service account of the receiver", claims.Email)
+	}
+	return nil
+}
+
+// pubsubData returns the decoded data of the Pub/Sub message.
+func pubsubData(body []byte) ([]byte, error) {
+	var p pubsubPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("cannot decode Pub/Sub message: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(p.Message.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode Pub/Sub message data: %w", err)
+	}
+	return data, nil
+}
+
+// parseGARNotification decodes the Artifact Registry notification held by the Pub/Sub message.
+func parseGARNotification(body []byte) (garNotification, error) {
+	var n garNotification
+	data, err := pubsubData(body)
+	if err != nil {
+		return n, err
+	}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return n, fmt.Errorf("cannot decode Artifact Registry notification: %w", err)
+	}
+	return n, nil
+}
+
+type googleKeyCache struct {
+	client *http.Client
+	url    string
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func newGoogleKeyCache(client *http.Client, url string) *googleKeyCache {
+	return &googleKeyCache{
+		client: client,
+		url:    url,
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// get returns the key, the keys are downloaded when they expire
+// or when the key ID is unknown.
+func (c *googleKeyCache) get(kid string, now time.Time) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.keys[kid]
+	expired := now.Sub(c.fetched) > googleKeysTTL
+	if ok && !expired {
+		return key, nil
+	}
+	if !expired && now.Sub(c.fetched) < googleKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown Google signing key '%s'", kid)
+	}
+
+	keys, err := c.fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	c.fetched = now
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown Google signing key '%s'", kid)
+}
+
+func (c *googleKeyCache) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download the Google signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download the Google signing keys, status: %s", resp.Status)
+	}
+
+	var certs map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return nil, fmt.Errorf("cannot decode the Google signing keys: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, data := range certs {
+		block, _ := pem.Decode([]byte(data))
+		if block == nil {
+			return nil, fmt.Errorf("the Google signing certificate '%s' is not PEM encoded", kid)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the Google signing certificate '%s': %w", kid, err)
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("the Google signing certificate '%s' doesn't hold an RSA key", kid)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
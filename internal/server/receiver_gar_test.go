@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func signRS256(g *gomega.WithT, key *rsa.PrivateKey, kid string, claims jwtClaims) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"` + kid + `","typ":"JWT"}`))
+	b, err := json.Marshal(claims)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	digest := sha256.Sum256([]byte(header + "." + payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestGARNotification_image(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	image := "us-docker.pkg.dev/project/apps/webapp"
+	g.Expect(garNotification{Digest: image + "@sha256:6ec1", Tag: image + ":1.2.3"}.image()).To(gomega.Equal(image))
+	g.Expect(garNotification{Tag: image + ":1.2.3"}.image()).To(gomega.Equal(image))
+	g.Expect(garNotification{Tag: "localhost:5000/webapp"}.image()).To(gomega.Equal("localhost:5000/webapp"))
+}
+
+func TestReceiverServer_validateGAR(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	now := time.Now()
+	googleKeys.mu.Lock()
+	googleKeys.keys = map[string]*rsa.PublicKey{"k1": &key.PublicKey}
+	googleKeys.fetched = now
+	googleKeys.mu.Unlock()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "gar", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:                v1beta1.GARReceiver,
+			Events:              []string{"INSERT"},
+			SecretRef:           meta.LocalObjectReference{Name: "webhook-token"},
+			PushServiceAccounts: []string{"pubsub@project.iam.gserviceaccount.com"},
+			Filter:              &v1beta1.ReceiverFilter{Repositories: []string{"us-docker.pkg.dev/project/apps/*"}},
+			Annotation: &v1beta1.ReceiverAnnotation{
+				ValueFrom:  v1beta1.PayloadAnnotationValue,
+				Expression: "request.body.tag",
+			},
+		},
+	}
+
+	push := func(action, image string) []byte {
+		data := `{"action":"` + action + `","digest":"` + image + `@sha256:6ec1","tag":"` + image + `:1.2.3"}`
+		return []byte(`{"message":{"data":"` + base64.StdEncoding.EncodeToString([]byte(data)) +
+			`","messageId":"2070443601311540"},"subscription":"projects/project/subscriptions/flux"}`)
+	}
+	validClaims := jwtClaims{
+		Issuer:        "https://accounts.google.com",
+		Audience:      "https://flux.example.com/hook/gar",
+		Email:         "pubsub@project.iam.gserviceaccount.com",
+		EmailVerified: true,
+		ExpiresAt:     now.Add(time.Hour).Unix(),
+	}
+	request := func(body []byte, kid string, claims jwtClaims) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/gar", bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+signRS256(g, key, kid, claims))
+		return r
+	}
+
+	ctx := context.Background()
+	body := push("INSERT", "us-docker.pkg.dev/project/apps/webapp")
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", validClaims))).To(gomega.Succeed())
+
+	value, err := trigger.AnnotationValue(nil, receiver, request(body, "k1", validClaims), eventPayload(receiver, body), now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(value).To(gomega.Equal("us-docker.pkg.dev/project/apps/webapp:1.2.3"))
+
+	err = s.validate(ctx, receiver, request(push("INSERT", "us-docker.pkg.dev/project/infra/proxy"), "k1", validClaims))
+	g.Expect(err).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request(push("DELETE", "us-docker.pkg.dev/project/apps/webapp"), "k1", validClaims))).NotTo(gomega.Succeed())
+
+	// the keys aren't downloaded again for an unknown key ID
+	g.Expect(s.validate(ctx, receiver, request(body, "k2", validClaims))).NotTo(gomega.Succeed())
+
+	otherAudience := validClaims
+	otherAudience.Audience = "https://flux.example.com/hook/other"
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", otherAudience))).NotTo(gomega.Succeed())
+
+	otherIssuer := validClaims
+	otherIssuer.Issuer = "https://example.com"
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", otherIssuer))).NotTo(gomega.Succeed())
+
+	expired := validClaims
+	expired.ExpiresAt = now.Add(-time.Minute).Unix()
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", expired))).NotTo(gomega.Succeed())
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/hook/gar", bytes.NewReader(body))
+	g.Expect(s.validate(ctx, receiver, unsigned)).NotTo(gomega.Succeed())
+
+	// the valid tokens of the other accounts are rejected
+	otherAccount := validClaims
+	otherAccount.Email = "attacker@other-project.iam.gserviceaccount.com"
+	err = s.validate(ctx, receiver, request(body, "k1", otherAccount))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	unverified := validClaims
+	unverified.EmailVerified = false
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", unverified))).NotTo(gomega.Succeed())
+
+	receiver.Spec.PushServiceAccounts = nil
+	g.Expect(s.validate(ctx, receiver, request(body, "k1", validClaims))).NotTo(gomega.Succeed())
+}
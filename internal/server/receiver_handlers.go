@@ -17,32 +17,39 @@ limitations under the License.
 package server
 
 import (
+	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha1"
-	"encoding/base64"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"net/http"
 	"net/url"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
 	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
-	"github.com/google/go-github/v32/github"
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/maintenance"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/internal/webhook"
+	"github.com/fluxcd/notification-controller/receivers"
 )
 
+// maxPayloadSize is the size limit of the decompressed webhook payloads,
+// it matches the largest payload sent by GitHub.
+const maxPayloadSize = 25 << 20
+
 func (s *ReceiverServer) handlePayload() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.Background()
@@ -50,278 +57,411 @@ func (s *ReceiverServer) handlePayload() func(w http.ResponseWriter, r *http.Req
 
 		s.logger.Info(fmt.Sprintf("handling request: %s", digest))
 
+		release, reason, retryAfter := s.shedder.admit(time.Now())
+		if release == nil {
+			s.logger.Info(fmt.Sprintf("request rejected, the receivers are %s", reason))
+			s.metrics.RecordShed(reason)
+			w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
 		var allReceivers v1beta1.ReceiverList
 		err := s.kubeClient.List(ctx, &allReceivers)
 		if err != nil {
 			s.logger.Error(err, "unable to list receivers")
+			if s.shedder.observe(err, time.Now()) {
+				s.metrics.RecordShed(ShedThrottled)
+				w.Header().Set("Retry-After", retryAfterSeconds(s.shedder.retryAfter(time.Now())))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		receivers := make([]v1beta1.Receiver, 0)
+		matching := make([]v1beta1.Receiver, 0)
 		for _, receiver := range allReceivers.Items {
 			if !receiver.Spec.Suspend &&
 				apimeta.IsStatusConditionTrue(receiver.Status.Conditions, meta.ReadyCondition) &&
 				receiver.Status.URL == fmt.Sprintf("/hook/%s", digest) {
-				receivers = append(receivers, receiver)
+				matching = append(matching, receiver)
 			}
 		}
 
-		if len(receivers) == 0 {
+		if len(matching) == 0 {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
+		// buffer the payload, it's read by each receiver verification and by the annotation expressions
+		payload, err := webhook.ReadBody(r, maxPayloadSize)
+		if err != nil {
+			s.logger.Error(err, "unable to read request body")
+			if errors.Is(err, webhook.ErrPayloadTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+		// the verifications are done against the decompressed payload
+		r.Header.Del("Content-Encoding")
+
+		var failure error
+		var triggered []v1beta1.CrossNamespaceObjectReference
 		withErrors := false
-		for _, receiver := range receivers {
+		withRejections := false
+		withDeferrals := false
+		withQueued := false
+		queueFull := false
+		throttled := false
+		for _, receiver := range matching {
+			if throttled {
+				// stop adding load, the sender retries the whole delivery
+				s.metrics.RecordRequest(receiver, http.StatusServiceUnavailable)
+				continue
+			}
+
 			logger := s.logger.WithValues(
 				"reconciler kind", v1beta1.ReceiverKind,
 				"name", receiver.Name,
 				"namespace", receiver.Namespace)
 
-			if err := s.validate(ctx, receiver, r); err != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(payload))
+			var result receivers.Result
+			if err := s.verify(ctx, receiver, r, &result); err != nil {
+				if errors.Is(err, errEventFiltered) {
+					logger.Info(err.Error())
+					s.metrics.RecordFilter(receiver, false)
+					s.metrics.RecordRequest(receiver, http.StatusOK)
+					continue
+				}
 				logger.Error(err, "unable to validate payload")
-				withErrors = true
+				s.metrics.RecordVerificationFailure(receiver)
+				s.metrics.RecordRequest(receiver, receivers.Reason(err).StatusCode())
+				if failure == nil {
+					failure = err
+				}
 				continue
 			}
-
-			for _, resource := range receiver.Spec.Resources {
-				if err := s.annotate(ctx, resource, receiver.Namespace); err != nil {
-					logger.Error(err, fmt.Sprintf("unable to annotate resource '%s/%s.%s'",
-						resource.Kind, resource.Name, resource.Namespace))
-					withErrors = true
-				} else {
-					logger.Info(fmt.Sprintf("resource '%s/%s.%s' annotated",
-						resource.Kind, resource.Name, resource.Namespace))
-				}
+			if receiver.Spec.Filter != nil {
+				s.metrics.RecordFilter(receiver, true)
 			}
-		}
 
-		if withErrors {
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-	}
-}
-
-func (s *ReceiverServer) validate(ctx context.Context, receiver v1beta1.Receiver, r *http.Request) error {
-	token, err := s.token(ctx, receiver)
-	if err != nil {
-		return fmt.Errorf("unable to read token, error: %w", err)
-	}
-
-	logger := s.logger.WithValues(
-		"reconciler kind", v1beta1.ReceiverKind,
-		"name", receiver.Name,
-		"namespace", receiver.Namespace)
-
-	switch receiver.Spec.Type {
-	case v1beta1.GenericReceiver:
-		return nil
-	case v1beta1.GenericHMACReceiver:
-		b, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return fmt.Errorf("unable to read request body: %s", err)
-		}
-
-		err = github.ValidateSignature(r.Header.Get("X-Signature"), b, []byte(token))
-		if err != nil {
-			return fmt.Errorf("unable to validate HMAC signature: %s", err)
-		}
-		return nil
-	case v1beta1.GitHubReceiver:
-		payload, err := github.ValidatePayload(r, []byte(token))
-		if err != nil {
-			return fmt.Errorf("the GitHub signature header is invalid, err: %w", err)
-		}
-
-		if _, err := github.ParseWebHook(github.WebHookType(r), payload); err != nil {
-			return fmt.Errorf("unable to parse GitHub payload, err: %w", err)
-		}
+			deliveryID := trigger.DeliveryID(r)
+			deliveryKey := ""
+			if deliveryID != "" {
+				deliveryKey = fmt.Sprintf("%s/%s/%s", receiver.Namespace, receiver.Name, deliveryID)
+			}
+			if s.deliveries.seen(deliveryKey, time.Now()) {
+				logger.Info(fmt.Sprintf("skipping duplicate delivery '%s'", deliveryID))
+				s.metrics.RecordRequest(receiver, http.StatusOK)
+				continue
+			}
 
-		event := github.WebHookType(r)
-		if len(receiver.Spec.Events) > 0 {
-			allowed := false
-			for _, e := range receiver.Spec.Events {
-				if strings.ToLower(event) == strings.ToLower(e) {
-					allowed = true
-					break
+			// the replays are rejected after the duplicate deliveries are
+			// skipped, so that the retried deliveries are still acknowledged
+			replayKey, err := s.replays.check(receiver, result, time.Now())
+			if err != nil {
+				logger.Error(err, "unable to validate payload")
+				s.deliveries.forget(deliveryKey)
+				s.metrics.RecordVerificationFailure(receiver)
+				s.metrics.RecordRequest(receiver, receivers.Reason(err).StatusCode())
+				if failure == nil {
+					failure = err
 				}
+				continue
 			}
-			if !allowed {
-				return fmt.Errorf("the GitHub event '%s' is not authorised", event)
+			// forget lets the sender retry a failed delivery
+			forget := func() {
+				s.deliveries.forget(deliveryKey)
+				s.replays.forget(replayKey)
 			}
-		}
 
-		logger.Info(fmt.Sprintf("handling GitHub event: %s", event))
-		return nil
-	case v1beta1.GitLabReceiver:
-		if r.Header.Get("X-Gitlab-Token") != token {
-			return fmt.Errorf("the X-Gitlab-Token header value does not match the receiver token")
-		}
+			if receiver.Spec.PublishEvent != nil {
+				if err := s.publishEvent(ctx, receiver, result.Event, r.Header.Get("Content-Type"), eventPayload(receiver, payload)); err != nil {
+					logger.Error(err, "unable to publish event")
+					forget()
+					s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.FailedTriggerResult, logger)
+					s.metrics.RecordRequest(receiver, http.StatusBadRequest)
+					withErrors = true
+					continue
+				}
+				logger.Info("event published")
+			}
 
-		event := r.Header.Get("X-Gitlab-Event")
-		if len(receiver.Spec.Events) > 0 {
-			allowed := false
-			for _, e := range receiver.Spec.Events {
-				if strings.ToLower(event) == strings.ToLower(e) {
-					allowed = true
-					break
+			window, err := maintenance.ActiveWindow(ctx, s.kubeClient, receiver.Namespace, receiver.Spec.MaintenanceWindowSelector, time.Now())
+			if err != nil {
+				logger.Error(err, "unable to evaluate maintenance windows")
+			} else if window != nil {
+				if window.GetReceiverAction() == v1beta1.DeferReceiverAction {
+					receiverName := types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Name}
+					if err := trigger.Defer(ctx, s.kubeClient, receiverName, receiver.Spec.Resources); err != nil {
+						logger.Error(err, "unable to defer trigger")
+						forget()
+						s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.FailedTriggerResult, logger)
+						s.metrics.RecordRequest(receiver, http.StatusBadRequest)
+						withErrors = true
+						continue
+					}
+					logger.Info(fmt.Sprintf("trigger deferred, maintenance window '%s' is active", window.Name))
+					s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.DeferredTriggerResult, logger)
+					s.metrics.RecordRequest(receiver, http.StatusAccepted)
+					withDeferrals = true
+				} else {
+					logger.Info(fmt.Sprintf("trigger rejected, maintenance window '%s' is active", window.Name))
+					forget()
+					s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.RejectedTriggerResult, logger)
+					s.metrics.RecordRequest(receiver, http.StatusServiceUnavailable)
+					withRejections = true
 				}
+				continue
 			}
-			if !allowed {
-				return fmt.Errorf("the GitLab event '%s' is not authorised", event)
+
+			annotationKey := trigger.AnnotationKey(receiver)
+			annotationValue, err := trigger.AnnotationValue(s.programs, receiver, r, eventPayload(receiver, payload), time.Now())
+			if err != nil {
+				logger.Error(err, "unable to compute the annotation value")
+				forget()
+				s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.FailedTriggerResult, logger)
+				s.metrics.RecordRequest(receiver, http.StatusBadRequest)
+				withErrors = true
+				continue
 			}
-		}
 
-		logger.Info(fmt.Sprintf("handling GitLab event: %s", event))
-		return nil
-	case v1beta1.BitbucketReceiver:
-		_, err := github.ValidatePayload(r, []byte(token))
-		if err != nil {
-			return fmt.Errorf("the Bitbucket server signature header is invalid, err: %w", err)
-		}
+			resourceAnnotations, err := trigger.ResourceAnnotations(s.programs, receiver, r.Header.Get("Content-Type"), eventPayload(receiver, payload))
+			if err != nil {
+				logger.Error(err, "unable to compute the resource annotations")
+				forget()
+				s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.FailedTriggerResult, logger)
+				s.metrics.RecordRequest(receiver, http.StatusBadRequest)
+				withErrors = true
+				continue
+			}
 
-		event := r.Header.Get("X-Event-Key")
-		if len(receiver.Spec.Events) > 0 {
-			allowed := false
-			for _, e := range receiver.Spec.Events {
-				if strings.ToLower(event) == strings.ToLower(e) {
-					allowed = true
-					break
-				}
+			annotations := map[string]string{annotationKey: annotationValue}
+			for k, v := range trigger.ProvenanceAnnotations(receiver, result.Event, payload) {
+				annotations[k] = v
 			}
-			if !allowed {
-				return fmt.Errorf("the Bitbucket server event '%s' is not authorised", event)
+			for k, v := range resourceAnnotations {
+				annotations[k] = v
 			}
-		}
 
-		logger.Info(fmt.Sprintf("handling Bitbucket server event: %s", event))
-		return nil
-	case v1beta1.QuayReceiver:
-		type payload struct {
-			DockerUrl   string   `json:"docker_url"`
-			UpdatedTags []string `json:"updated_tags"`
-		}
-
-		var p payload
-		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-			return fmt.Errorf("cannot decode Quay webhook payload")
-		}
+			if window := coalesceWindow(receiver); window > 0 {
+				held := s.coalesce(receiver, result, annotations)
+				logger.Info(fmt.Sprintf("trigger coalesced, %d resources held for %s", held, window))
+				s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.CoalescedTriggerResult, logger)
+				s.metrics.RecordRequest(receiver, http.StatusAccepted)
+				withQueued = true
+				continue
+			}
 
-		logger.Info(fmt.Sprintf("handling Quay event from %s", p.DockerUrl))
-		return nil
-	case v1beta1.HarborReceiver:
-		if r.Header.Get("Authorization") != token {
-			return fmt.Errorf("the Harbor Authorization header value does not match the receiver token")
-		}
+			if receiver.Spec.Async && s.triggers != nil {
+				job := triggerJob{
+					receiver:    receiver,
+					result:      result,
+					annotations: annotations,
+					payload:     payload,
+					forget:      forget,
+				}
+				if !s.triggers.push(job) {
+					logger.Info("trigger rejected, the asynchronous queue is full")
+					forget()
+					s.metrics.RecordShed(ShedQueueFull)
+					s.recordTrigger(receiver, result.Event, 0, 0, v1beta1.ThrottledTriggerResult, logger)
+					s.metrics.RecordRequest(receiver, http.StatusServiceUnavailable)
+					queueFull = true
+					continue
+				}
+				logger.Info("trigger queued")
+				s.metrics.RecordRequest(receiver, http.StatusAccepted)
+				withQueued = true
+				continue
+			}
 
-		logger.Info("handling Harbor event")
-		return nil
-	case v1beta1.DockerHubReceiver:
-		type payload struct {
-			PushData struct {
-				Tag string `json:"tag"`
-			} `json:"push_data"`
-			Repository struct {
-				URL string `json:"repo_url"`
-			} `json:"repository"`
-		}
-		var p payload
-		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-			return fmt.Errorf("cannot decode DockerHub webhook payload")
+			annotated, annotateErrors, shed := s.annotate(ctx, receiver, result, annotations, payload, logger)
+			throttled = shed
+			triggered = append(triggered, annotated...)
+			s.recordTrigger(receiver, result.Event, len(annotated), annotateErrors, triggerResult(annotateErrors, throttled), logger)
+
+			switch {
+			case throttled:
+				forget()
+				s.metrics.RecordShed(ShedThrottled)
+				s.metrics.RecordRequest(receiver, http.StatusServiceUnavailable)
+			case annotateErrors > 0:
+				forget()
+				s.metrics.RecordRequest(receiver, http.StatusBadRequest)
+				withErrors = true
+			default:
+				s.metrics.RecordRequest(receiver, http.StatusOK)
+			}
 		}
 
-		logger.Info(fmt.Sprintf("handling DockerHub event from %s for tag %s", p.Repository.URL, p.PushData.Tag))
-		return nil
-	case v1beta1.GCRReceiver:
-		const (
-			insert     = "insert"
-			tokenIndex = len("Bearer ")
-		)
-
-		type data struct {
-			Action string `json:"action"`
-			Digest string `json:"digest"`
-			Tag    string `json:"tag"`
+		switch {
+		case throttled:
+			w.Header().Set("Retry-After", retryAfterSeconds(s.shedder.retryAfter(time.Now())))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case failure != nil:
+			writeVerificationFailure(w, failure)
+		case withErrors:
+			w.WriteHeader(http.StatusBadRequest)
+		case withRejections:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case queueFull:
+			w.Header().Set("Retry-After", retryAfterSeconds(asyncRetryAfter))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case withDeferrals || withQueued:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			if responder := receiverResponder(matching); responder != nil {
+				responder.Respond(w, triggered)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
 		}
+	}
+}
 
-		type payload struct {
-			Message struct {
-				Data         string    `json:"data"`
-				MessageID    string    `json:"messageId"`
-				PublishTime  time.Time `json:"publishTime"`
-				Subscription string    `json:"subscription"`
-			} `json:"message"`
+// annotate sets the annotations on the resources of the receiver selected by the
+// result, notifies the provider and acknowledges the delivery. It returns the
+// annotated resources, the number of failures and true if the API server
+// throttled the controller, in which case the remaining resources are skipped.
+func (s *ReceiverServer) annotate(ctx context.Context, receiver v1beta1.Receiver, result receivers.Result,
+	annotations map[string]string, payload []byte, logger logr.Logger) ([]v1beta1.CrossNamespaceObjectReference, int, bool) {
+	annotateStart := time.Now()
+	annotateCtx, cancel := context.WithTimeout(ctx, annotateTimeout)
+	annotated := make([]v1beta1.CrossNamespaceObjectReference, 0, len(receiver.Spec.Resources))
+	annotateErrors := 0
+	throttled := false
+	action := receiver.GetAction()
+	for _, resource := range receiver.Spec.Resources {
+		if !result.Selects(resource) {
+			continue
+		}
+		hints := trigger.ImageHintAnnotations(receiver, resource, result.Tag, result.Digest)
+		if err := trigger.Apply(annotateCtx, s.kubeClient, resource, receiver.Namespace, action, withAnnotations(annotations, hints)); err != nil {
+			logger.Error(err, fmt.Sprintf("unable to %s resource '%s/%s.%s'",
+				action, resource.Kind, resource.Name, resource.Namespace))
+			annotateErrors++
+			if s.shedder.observe(err, time.Now()) {
+				throttled = true
+				break
+			}
+		} else {
+			logger.Info(fmt.Sprintf("resource '%s/%s.%s' triggered with the %s action",
+				resource.Kind, resource.Name, resource.Namespace, action))
+			annotated = append(annotated, resource)
 		}
+	}
+	cancel()
+	s.metrics.RecordAnnotationDuration(receiver, annotateStart)
 
-		err := authenticateGCRRequest(&http.Client{}, r.Header.Get("Authorization"), tokenIndex)
-		if err != nil {
-			return fmt.Errorf("cannot authenticate GCR request: %s", err)
-		}
+	if receiver.Spec.ProviderRef != nil {
+		go func(receiver v1beta1.Receiver, event events.Event) {
+			if err := s.notify(ctx, receiver, event); err != nil {
+				logger.Error(err, "failed to send notification")
+			}
+		}(receiver, receiverEvent(receiver, annotated, annotateErrors))
+	}
 
-		var p payload
-		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-			return fmt.Errorf("cannot decode GCR webhook payload")
-		}
+	if acknowledger := receiverAcknowledger(receiver); acknowledger != nil {
+		go func(receiver v1beta1.Receiver, failed bool) {
+			if err := acknowledger.Acknowledge(ctx, receiver, payload, failed); err != nil {
+				logger.Error(err, "failed to acknowledge the delivery")
+			}
+		}(receiver, throttled || annotateErrors > 0)
+	}
 
-		raw, _ := base64.StdEncoding.DecodeString(p.Message.Data)
+	return annotated, annotateErrors, throttled
+}
 
-		var d data
-		err = json.Unmarshal(raw, &d)
-		if err != nil {
-			return fmt.Errorf("cannot decode GCR webhook body")
-		}
+// withAnnotations returns the annotations merged with the extra ones.
+func withAnnotations(annotations, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return annotations
+	}
+	merged := make(map[string]string, len(annotations)+len(extra))
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
 
-		logger.Info(fmt.Sprintf("handling GCR event from %s for tag %s", d.Digest, d.Tag))
-		return nil
-	case v1beta1.NexusReceiver:
-		signature := r.Header.Get("X-Nexus-Webhook-Signature")
-		if len(signature) == 0 {
-			return fmt.Errorf("Nexus signature is missing from header")
-		}
+// verificationFailure is the body of the responses to the requests failing the verification.
+type verificationFailure struct {
+	Reason  receivers.FailureReason `json:"reason"`
+	Message string                  `json:"message"`
+}
 
-		b, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return fmt.Errorf("cannot read Nexus payload. error: %s", err)
-		}
+// writeVerificationFailure responds with the status code and the reason of the failure.
+func writeVerificationFailure(w http.ResponseWriter, err error) {
+	reason := receivers.Reason(err)
+	message := err.Error()
+	if reason == receivers.VerificationFailed {
+		// the other failures may hold the name of the receiver secret
+		message = "the request could not be verified"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(reason.StatusCode())
+	_ = json.NewEncoder(w).Encode(verificationFailure{Reason: reason, Message: message})
+}
 
-		if !verifyHmacSignature([]byte(token), signature, b) {
-			return fmt.Errorf("invalid Nexus signature")
-		}
-		type payload struct {
-			Action         string `json:"action"`
-			RepositoryName string `json:"repositoryName"`
-		}
-		var p payload
-		if err := json.Unmarshal(b, &p); err != nil {
-			return fmt.Errorf("cannot decode Nexus webhook payload: %s", err)
-		}
+// validate verifies the request with the verifier registered for the receiver type.
+func (s *ReceiverServer) validate(ctx context.Context, receiver v1beta1.Receiver, r *http.Request) error {
+	return s.verify(ctx, receiver, r, nil)
+}
 
-		logger.Info(fmt.Sprintf("handling Nexus event from %s", p.RepositoryName))
-		return nil
-	case v1beta1.ACRReceiver:
-		type target struct {
-			Repository string `json:"repository"`
-			Tag        string `json:"tag"`
-		}
+// verify verifies the request and fills the result with the details found by the verifier.
+func (s *ReceiverServer) verify(ctx context.Context, receiver v1beta1.Receiver, r *http.Request, result *receivers.Result) error {
+	verifier, ok := receivers.Lookup(receiver.Spec.Type)
+	if !ok {
+		return fmt.Errorf("receiver type '%s' not supported", receiver.Spec.Type)
+	}
 
-		type payload struct {
-			Action string `json:"action"`
-			Target target `json:"target"`
-		}
+	if err := s.checkSource(receiver, r); err != nil {
+		return err
+	}
 
-		var p payload
-		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-			return fmt.Errorf("cannot decode ACR webhook payload: %s", err)
-		}
+	if err := s.checkClientCertificate(ctx, receiver, r); err != nil {
+		return err
+	}
 
-		logger.Info(fmt.Sprintf("handling ACR event from %s for tag %s", p.Target.Repository, p.Target.Tag))
-		return nil
+	token, err := s.token(ctx, receiver)
+	if err != nil {
+		return fmt.Errorf("unable to read token, error: %w", err)
 	}
 
-	return fmt.Errorf("recevier type '%s' not supported", receiver.Spec.Type)
+	return verifier.Verify(ctx, receivers.Request{
+		Request:  r,
+		Receiver: receiver,
+		Token:    token,
+		Logger: s.logger.WithValues(
+			"reconciler kind", v1beta1.ReceiverKind,
+			"name", receiver.Name,
+			"namespace", receiver.Namespace),
+		KubeClient: s.kubeClient,
+		Programs:   s.programs,
+		Result:     result,
+	})
+}
+
+// eventPayload returns the payload the annotation expressions are evaluated over,
+// the events wrapped in an envelope are unwrapped by the verifier of the receiver type.
+func eventPayload(receiver v1beta1.Receiver, payload []byte) []byte {
+	verifier, ok := receivers.Lookup(receiver.Spec.Type)
+	if !ok {
+		return payload
+	}
+	if u, ok := verifier.(receivers.PayloadUnwrapper); ok {
+		return u.Unwrap(payload)
+	}
+	return payload
 }
 
 func (s *ReceiverServer) token(ctx context.Context, receiver v1beta1.Receiver) (string, error) {
@@ -346,57 +486,6 @@ func (s *ReceiverServer) token(ctx context.Context, receiver v1beta1.Receiver) (
 	return token, nil
 }
 
-func (s *ReceiverServer) annotate(ctx context.Context, resource v1beta1.CrossNamespaceObjectReference, defaultNamespace string) error {
-	namespace := defaultNamespace
-	if resource.Namespace != "" {
-		namespace = resource.Namespace
-	}
-	objectKey := client.ObjectKey{
-		Namespace: namespace,
-		Name:      resource.Name,
-	}
-
-	apiVersionMap := map[string]string{
-		"Bucket":          "source.toolkit.fluxcd.io/v1beta1",
-		"HelmRepository":  "source.toolkit.fluxcd.io/v1beta1",
-		"GitRepository":   "source.toolkit.fluxcd.io/v1beta1",
-		"ImageRepository": "image.toolkit.fluxcd.io/v1alpha1",
-	}
-
-	apiVersion := resource.APIVersion
-	if apiVersion == "" {
-		if apiVersionMap[resource.Kind] == "" {
-			return fmt.Errorf("apiVersion must be specified for kind '%s'", resource.Kind)
-		}
-		apiVersion = apiVersionMap[resource.Kind]
-	}
-
-	group, version := getGroupVersion(apiVersion)
-
-	u := &unstructured.Unstructured{}
-	u.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   group,
-		Kind:    resource.Kind,
-		Version: version,
-	})
-
-	if err := s.kubeClient.Get(ctx, objectKey, u); err != nil {
-		return fmt.Errorf("unable to read %s '%s' error: %w", resource.Kind, objectKey, err)
-	}
-
-	sourceAnnotations := u.GetAnnotations()
-	if sourceAnnotations == nil {
-		sourceAnnotations = make(map[string]string)
-	}
-	sourceAnnotations[meta.ReconcileRequestAnnotation] = metav1.Now().String()
-	u.SetAnnotations(sourceAnnotations)
-	if err := s.kubeClient.Update(ctx, u); err != nil {
-		return fmt.Errorf("unable to annotate %s '%s' error: %w", resource.Kind, objectKey, err)
-	}
-
-	return nil
-}
-
 func authenticateGCRRequest(c *http.Client, bearer string, tokenIndex int) (err error) {
 	type auth struct {
 		Aud string `json:"aud"`
@@ -429,11 +518,37 @@ func verifyHmacSignature(key []byte, signature string, payload []byte) bool {
 	return hmac.Equal([]byte(signature), []byte(expectedMAC))
 }
 
-func getGroupVersion(s string) (string, string) {
-	slice := strings.Split(s, "/")
-	if len(slice) == 1 {
-		return "", slice[0]
+// verifyHmacSHA256Signature checks the hex encoded HMAC SHA256 signature of the payload.
+func verifyHmacSHA256Signature(key []byte, signature string, payload []byte) bool {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(payload)
+	expectedMAC := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(strings.ToLower(signature)), []byte(expectedMAC))
+}
+
+// receiverResponder returns the responder of the first receiver
+// with a type expecting a body in the response, if any.
+func receiverResponder(matching []v1beta1.Receiver) receivers.Responder {
+	for _, receiver := range matching {
+		verifier, ok := receivers.Lookup(receiver.Spec.Type)
+		if !ok {
+			continue
+		}
+		if r, ok := verifier.(receivers.Responder); ok {
+			return r
+		}
 	}
+	return nil
+}
 
-	return slice[0], slice[1]
+// receiverAcknowledger returns the acknowledger of the receiver type, if any.
+func receiverAcknowledger(receiver v1beta1.Receiver) receivers.Acknowledger {
+	verifier, ok := receivers.Lookup(receiver.Spec.Type)
+	if !ok {
+		return nil
+	}
+	if a, ok := verifier.(receivers.Acknowledger); ok {
+		return a
+	}
+	return nil
 }
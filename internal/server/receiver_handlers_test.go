@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestReceiverServer_validateGitea(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "gitea", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GiteaReceiver,
+			Events:    []string{"push"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+		},
+	}
+
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(payload)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	request := func(signatureHeader, signature, eventHeader, event string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/gitea", bytes.NewReader(payload))
+		r.Header.Set(signatureHeader, signature)
+		r.Header.Set(eventHeader, event)
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("X-Gitea-Signature", signature, "X-Gitea-Event", "push"))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("X-Forgejo-Signature", signature, "X-Forgejo-Event", "push"))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("X-Gitea-Signature", "invalid", "X-Gitea-Event", "push"))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("X-Gitea-Signature", signature, "X-Gitea-Event", "release"))).NotTo(gomega.Succeed())
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request("X-Gitea-Signature", signature, "X-Gitea-Event", "push"), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("push"))
+}
+
+func TestReceiverServer_validateJenkins(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "jenkins", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.JenkinsReceiver,
+			Events:    []string{"FINALIZED"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Filter:    &v1beta1.ReceiverFilter{Jobs: []string{"apps/*"}},
+		},
+	}
+
+	request := func(token, job, phase, status string) *http.Request {
+		body := fmt.Sprintf(`{"name":"%s","build":{"number":42,"phase":"%s","status":"%s"}}`, job, phase, status)
+		r := httptest.NewRequest(http.MethodPost, "/hook/jenkins", bytes.NewReader([]byte(body)))
+		r.Header.Set("X-Jenkins-Token", token)
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "apps/webapp", "FINALIZED", "SUCCESS"))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("forged", "apps/webapp", "FINALIZED", "SUCCESS"))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "apps/webapp", "STARTED", ""))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "infra/proxy", "FINALIZED", "SUCCESS"))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "apps/webapp", "FINALIZED", "FAILURE"))).To(gomega.MatchError(errEventFiltered))
+
+	receiver.Spec.Filter.Results = []string{"SUCCESS", "UNSTABLE"}
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "apps/webapp", "FINALIZED", "UNSTABLE"))).To(gomega.Succeed())
+}
+
+func TestReceiverServer_validateGitLabSystem(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "gitlab", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GitLabSystemReceiver,
+			Events:    []string{"push", "project_create"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Filter:    &v1beta1.ReceiverFilter{Repositories: []string{"apps/*"}},
+		},
+	}
+
+	request := func(token, hook, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/gitlab", bytes.NewReader([]byte(body)))
+		r.Header.Set("X-Gitlab-Token", token)
+		r.Header.Set("X-Gitlab-Event", hook)
+		return r
+	}
+	push := `{"object_kind":"push","event_name":"push","ref":"refs/heads/main","project":{"path_with_namespace":"apps/webapp"}}`
+	projectCreate := `{"event_name":"project_create","path_with_namespace":"apps/api"}`
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "System Hook", push))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("forged", "System Hook", push))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "Push Hook", push))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "System Hook", `{"event_name":"user_create"}`))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", "System Hook",
+		`{"event_name":"project_create","path_with_namespace":"infra/proxy"}`))).To(gomega.MatchError(errEventFiltered))
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request("s3cr3t", "System Hook", projectCreate), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("project_create"))
+}
+
+func TestReceiverTypesRegistered(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	for _, receiverType := range []string{
+		v1beta1.GenericReceiver, v1beta1.GenericHMACReceiver, v1beta1.GitHubReceiver,
+		v1beta1.GitLabReceiver, v1beta1.GitLabSystemReceiver, v1beta1.GiteaReceiver, v1beta1.BitbucketReceiver,
+		v1beta1.BitbucketServerReceiver, v1beta1.BitbucketCloudReceiver, v1beta1.HarborReceiver,
+		v1beta1.DockerHubReceiver, v1beta1.QuayReceiver, v1beta1.GCRReceiver, v1beta1.GARReceiver,
+		v1beta1.NexusReceiver, v1beta1.ACRReceiver, v1beta1.ECRReceiver, v1beta1.JenkinsReceiver,
+		v1beta1.DroneReceiver, v1beta1.PubSubReceiver, v1beta1.ServiceAccountReceiver, v1beta1.SlackReceiver,
+		v1beta1.DependencyBotReceiver,
+	} {
+		g.Expect(receivers.Validate(receiverType)).To(gomega.Succeed())
+	}
+}
+
+func TestWriteVerificationFailure(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	w := httptest.NewRecorder()
+	err := fmt.Errorf("the Pub/Sub JWT is invalid, err: %w",
+		receivers.Errorf(receivers.MissingSignature, "the Authorization header is missing"))
+	writeVerificationFailure(w, err)
+	g.Expect(w.Code).To(gomega.Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(gomega.MatchJSON(
+		`{"reason":"MissingSignature","message":"the Pub/Sub JWT is invalid, err: the Authorization header is missing"}`))
+
+	w = httptest.NewRecorder()
+	writeVerificationFailure(w, receivers.Errorf(receivers.InvalidPayload, "cannot decode Quay webhook payload"))
+	g.Expect(w.Code).To(gomega.Equal(http.StatusUnprocessableEntity))
+
+	w = httptest.NewRecorder()
+	writeVerificationFailure(w, errors.New("unable to read token from secret 'default/webhook-token'"))
+	g.Expect(w.Code).To(gomega.Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(gomega.MatchJSON(
+		`{"reason":"VerificationFailed","message":"the request could not be verified"}`))
+}
+
+func TestImageReference(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	for ref, expected := range map[string][2]string{
+		"ghcr.io/org/app:1.0.0":                {"1.0.0", ""},
+		"ghcr.io/org/app@sha256:6ec1":          {"", "sha256:6ec1"},
+		"localhost:5000/app:1.0.0@sha256:6ec1": {"1.0.0", "sha256:6ec1"},
+		"localhost:5000/app":                   {"", ""},
+	} {
+		g.Expect(imageTag(ref)).To(gomega.Equal(expected[0]), ref)
+		g.Expect(imageDigest(ref)).To(gomega.Equal(expected[1]), ref)
+	}
+}
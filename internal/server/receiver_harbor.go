@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// harborEvent is the payload of the Harbor 2.x webhooks, e.g. PUSH_ARTIFACT,
+// DELETE_ARTIFACT or SCANNING_COMPLETED events.
+type harborEvent struct {
+	Type      string `json:"type"`
+	OccurAt   int64  `json:"occur_at"`
+	Operator  string `json:"operator"`
+	EventData struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			Name         string `json:"name"`
+			Namespace    string `json:"namespace"`
+			RepoFullName string `json:"repo_full_name"`
+			RepoType     string `json:"repo_type"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+// harborMessage is the Harbor event the CEL condition of the receiver filter
+// is evaluated with, the fields of the first resource are surfaced along
+// with the repository, the event data is the decoded JSON value.
+type harborMessage struct {
+	Type        string      `json:"type"`
+	Operator    string      `json:"operator"`
+	Repository  string      `json:"repository"`
+	Namespace   string      `json:"namespace"`
+	Name        string      `json:"name"`
+	Tag         string      `json:"tag"`
+	Digest      string      `json:"digest"`
+	ResourceURL string      `json:"resourceURL"`
+	Data        interface{} `json:"data"`
+}
+
+// parseHarborEvent decodes the Harbor payload and the message of the filter condition.
+func parseHarborEvent(body []byte) (harborEvent, harborMessage, error) {
+	var e harborEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return e, harborMessage{}, err
+	}
+	var raw struct {
+		EventData interface{} `json:"event_data"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return e, harborMessage{}, err
+	}
+
+	repository := e.EventData.Repository
+	m := harborMessage{
+		Type:       e.Type,
+		Operator:   e.Operator,
+		Repository: repository.RepoFullName,
+		Namespace:  repository.Namespace,
+		Name:       repository.Name,
+		Data:       raw.EventData,
+	}
+	if len(e.EventData.Resources) > 0 {
+		resource := e.EventData.Resources[0]
+		m.Tag = resource.Tag
+		m.Digest = resource.Digest
+		m.ResourceURL = resource.ResourceURL
+	}
+	return e, m, nil
+}
+
+// match evaluates the CEL condition with the message.
+func (m harborMessage) match(r receivers.Request, condition string) (bool, error) {
+	return matchFilterCondition(r, condition, m)
+}
+
+// verifyHarbor checks the Harbor authentication header, and matches the event type
+// against the receiver events and the repository against the receiver filter.
+func verifyHarbor(ctx context.Context, r receivers.Request) error {
+	if r.Header.Get("Authorization") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the Harbor Authorization header is missing")
+	}
+	if r.Header.Get("Authorization") != r.Token {
+		return receivers.Errorf(receivers.InvalidSignature, "the Harbor Authorization header value does not match the receiver token")
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Harbor payload, err: %w", err)
+	}
+	e, m, err := parseHarborEvent(b)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "unable to decode Harbor payload, err: %w", err)
+	}
+
+	if !receivers.EventAllowed(r.Receiver, e.Type) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Harbor event '%s' is not authorised", e.Type)
+	}
+
+	if filter := r.Receiver.Spec.Filter; filter != nil {
+		if !matchAny(filter.Repositories, m.Repository) {
+			return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, m.Repository)
+		}
+		if filter.Condition != "" {
+			ok, err := m.match(r, filter.Condition)
+			if err != nil {
+				return fmt.Errorf("%w: %s", errEventFiltered, err)
+			}
+			if !ok {
+				return fmt.Errorf("%w: the Harbor event does not match the condition", errEventFiltered)
+			}
+		}
+	}
+
+	r.SetEvent(e.Type)
+	if m.Tag != "" || m.Digest != "" {
+		r.SetImage(m.Tag, m.Digest)
+	}
+	r.Logger.Info(fmt.Sprintf("handling Harbor event: %s", e.Type))
+	return nil
+}
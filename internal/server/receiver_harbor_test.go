@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const harborPushArtifact = `{
+  "type": "PUSH_ARTIFACT",
+  "occur_at": 1680501893,
+  "operator": "robot$ci",
+  "event_data": {
+    "resources": [{
+      "digest": "sha256:954b378c375d852eb3c63ab88978f640b4348b01c1b3456a024a81536dafbbf4",
+      "tag": "v1.2.0",
+      "resource_url": "harbor.example.com/apps/webapp:v1.2.0"
+    }],
+    "repository": {
+      "date_created": 1680501893,
+      "name": "webapp",
+      "namespace": "apps",
+      "repo_full_name": "apps/webapp",
+      "repo_type": "private"
+    }
+  }
+}`
+
+func TestParseHarborEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	e, m, err := parseHarborEvent([]byte(harborPushArtifact))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(e.Type).To(gomega.Equal("PUSH_ARTIFACT"))
+	g.Expect(m.Repository).To(gomega.Equal("apps/webapp"))
+	g.Expect(m.Namespace).To(gomega.Equal("apps"))
+	g.Expect(m.Tag).To(gomega.Equal("v1.2.0"))
+	g.Expect(m.Operator).To(gomega.Equal("robot$ci"))
+
+	ok, err := m.match(receivers.Request{}, "message.tag.startsWith('v') && message.data.repository.repo_type == 'private'")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ok).To(gomega.BeTrue())
+
+	_, _, err = parseHarborEvent([]byte(`{"type":`))
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestReceiverServer_validateHarbor(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "harbor", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.HarborReceiver,
+			Events:    []string{"PUSH_ARTIFACT"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Filter: &v1beta1.ReceiverFilter{
+				Repositories: []string{"apps/*"},
+				Condition:    "message.tag.startsWith('v')",
+			},
+		},
+	}
+
+	request := func(token, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/harbor", bytes.NewReader([]byte(body)))
+		r.Header.Set("Authorization", token)
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", harborPushArtifact))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("forged", harborPushArtifact))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t", `{"type":"DELETE_ARTIFACT"}`))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t",
+		`{"type":"PUSH_ARTIFACT","event_data":{"repository":{"repo_full_name":"infra/proxy"}}}`))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, request("s3cr3t",
+		`{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"tag":"latest"}],"repository":{"repo_full_name":"apps/webapp"}}}`))).To(gomega.MatchError(errEventFiltered))
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request("s3cr3t", harborPushArtifact), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("PUSH_ARTIFACT"))
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+)
+
+// WithTriggerHistory records the outcome of the last size verified requests
+// in the status of the receivers, the status isn't updated when size is zero.
+func (s *ReceiverServer) WithTriggerHistory(size int) {
+	s.historySize = size
+}
+
+// recordTrigger counts the outcome of a verified request and records it in the
+// receiver status. The status is updated in the background, so that the response
+// to the sender isn't delayed by an additional write to the API server.
+func (s *ReceiverServer) recordTrigger(receiver v1beta1.Receiver, event string, annotated, failed int, result string, logger logr.Logger) {
+	now := metav1.Now()
+	s.metrics.RecordTrigger(receiver, result, now.Time)
+	if s.historySize <= 0 {
+		return
+	}
+
+	receiverName := types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Name}
+	go func(t v1beta1.ReceiverTrigger) {
+		if err := trigger.RecordTrigger(context.Background(), s.kubeClient, receiverName, t, s.historySize); err != nil {
+			logger.Error(err, "unable to record the trigger outcome")
+		}
+	}(v1beta1.ReceiverTrigger{
+		Time:      now,
+		Event:     event,
+		Annotated: annotated,
+		Failed:    failed,
+		Result:    result,
+	})
+}
+
+// triggerResult returns the result of the annotation of the receiver resources.
+func triggerResult(failed int, throttled bool) string {
+	switch {
+	case throttled:
+		return v1beta1.ThrottledTriggerResult
+	case failed > 0:
+		return v1beta1.FailedTriggerResult
+	default:
+		return v1beta1.SucceededTriggerResult
+	}
+}
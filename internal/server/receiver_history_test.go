@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestReceiverServer_history(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "generic", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Resources: []v1beta1.CrossNamespaceObjectReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "webapp"},
+			},
+		},
+		Status: v1beta1.ReceiverStatus{URL: "/hook/digest"},
+	}
+	meta.SetResourceCondition(receiver, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp", Namespace: "default"},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver, secret, configMap).Build()
+
+	metrics := NewReceiverMetrics()
+	s := NewReceiverServer(":0", logf.Log, kubeClient, metrics, 0)
+	s.WithTriggerHistory(2)
+
+	post := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", nil)
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, r)
+		return rec.Code
+	}
+
+	receiverName := types.NamespacedName{Name: "generic", Namespace: "default"}
+	history := func() []v1beta1.ReceiverTrigger {
+		var got v1beta1.Receiver
+		g.Expect(kubeClient.Get(context.TODO(), receiverName, &got)).To(gomega.Succeed())
+		return got.Status.History
+	}
+
+	g.Expect(post()).To(gomega.Equal(http.StatusOK))
+	g.Eventually(history).Should(gomega.HaveLen(1))
+	g.Expect(post()).To(gomega.Equal(http.StatusOK))
+	g.Eventually(history).Should(gomega.HaveLen(2))
+	g.Expect(post()).To(gomega.Equal(http.StatusOK))
+	g.Eventually(func() float64 {
+		return testutil.ToFloat64(metrics.triggerCounter.WithLabelValues("generic", "default", v1beta1.SucceededTriggerResult))
+	}).Should(gomega.Equal(float64(3)))
+
+	// the history is trimmed to the configured size
+	g.Consistently(history).Should(gomega.HaveLen(2))
+	var got v1beta1.Receiver
+	g.Expect(kubeClient.Get(context.TODO(), receiverName, &got)).To(gomega.Succeed())
+	g.Expect(got.Status.LastTrigger).NotTo(gomega.BeNil())
+	g.Expect(got.Status.LastTrigger.Result).To(gomega.Equal(v1beta1.SucceededTriggerResult))
+	g.Expect(got.Status.LastTrigger.Annotated).To(gomega.Equal(1))
+	g.Expect(testutil.ToFloat64(metrics.lastTriggerGauge.WithLabelValues("generic", "default"))).To(gomega.BeNumerically(">", 0))
+}
+
+func TestTriggerResult(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(triggerResult(0, false)).To(gomega.Equal(v1beta1.SucceededTriggerResult))
+	g.Expect(triggerResult(1, false)).To(gomega.Equal(v1beta1.FailedTriggerResult))
+	g.Expect(triggerResult(1, true)).To(gomega.Equal(v1beta1.ThrottledTriggerResult))
+}
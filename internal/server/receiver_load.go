@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+const (
+	// ShedThrottled is the reason of the webhook requests rejected while
+	// the API server is throttling the controller.
+	ShedThrottled = "throttled"
+
+	// ShedSaturated is the reason of the webhook requests rejected when
+	// the maximum number of requests are already being handled.
+	ShedSaturated = "saturated"
+)
+
+// annotateTimeout bounds the time the resources annotation waits for the
+// client-side rate limiter, so that a saturated limiter fails fast instead
+// of piling up the webhook requests, see NewReceiverClient.
+const annotateTimeout = 30 * time.Second
+
+// loadShedder rejects the webhook requests while the API server is under
+// pressure, the receivers respond with a 503 and a Retry-After header
+// instead of queueing work the API server can't take.
+type loadShedder struct {
+	inFlight chan struct{}
+	cooldown time.Duration
+
+	mu             sync.Mutex
+	throttledUntil time.Time
+}
+
+// newLoadShedder returns a shedder admitting at most maxInFlight concurrent
+// requests and rejecting all the requests for the cooldown duration after
+// the API server throttled the controller. The limits are disabled when zero.
+func newLoadShedder(maxInFlight int, cooldown time.Duration) *loadShedder {
+	l := &loadShedder{cooldown: cooldown}
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// admit returns the function releasing the admitted request, or the reason
+// the request is shed and the time after which it can be retried.
+func (l *loadShedder) admit(now time.Time) (func(), string, time.Duration) {
+	if l == nil {
+		return func() {}, "", 0
+	}
+
+	l.mu.Lock()
+	until := l.throttledUntil
+	l.mu.Unlock()
+	if now.Before(until) {
+		return nil, ShedThrottled, until.Sub(now)
+	}
+
+	if l.inFlight == nil {
+		return func() {}, "", 0
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, "", 0
+	default:
+		return nil, ShedSaturated, time.Second
+	}
+}
+
+// observe returns true if the error reports the throttling of the controller,
+// either by the API server or by the client-side rate limiter, and starts
+// the cooldown.
+func (l *loadShedder) observe(err error, now time.Time) bool {
+	if l == nil || l.cooldown <= 0 || !isThrottled(err) {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := now.Add(l.cooldown); until.After(l.throttledUntil) {
+		l.throttledUntil = until
+	}
+	return true
+}
+
+// retryAfter returns the remaining cooldown.
+func (l *loadShedder) retryAfter(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.throttledUntil.Sub(now)
+}
+
+// isThrottled returns true if the API server rejected the request with a 429,
+// or if the request didn't complete before the annotation deadline, including
+// the requests refused by the client-side rate limiter of the receivers.
+func isThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	return apierrors.IsTooManyRequests(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// NewReceiverClient returns the client of the receiver server, it reads from
+// the cache of the cluster and its client-side rate limiter, shared by the
+// requests of all the kinds, reports its refusals as deadline errors.
+func NewReceiverClient(config *rest.Config, c cluster.Cluster) (client.Client, error) {
+	config = rest.CopyConfig(config)
+	limiter := config.RateLimiter
+	if limiter == nil {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(config.QPS, config.Burst)
+	}
+	config.RateLimiter = deadlineRateLimiter{limiter}
+
+	kubeClient, err := client.New(config, client.Options{Scheme: c.GetScheme(), Mapper: c.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader: c.GetCache(),
+		Client:      kubeClient,
+	})
+}
+
+// deadlineRateLimiter wraps the errors of the rate limiter with
+// context.DeadlineExceeded, the limiter of golang.org/x/time/rate
+// refuses the waits that would exceed the context deadline with
+// an untyped error before the deadline is reached.
+type deadlineRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+// Wait returns nil if a token is taken before the context deadline.
+func (l deadlineRateLimiter) Wait(ctx context.Context) error {
+	err := l.RateLimiter.Wait(ctx)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+// retryAfterSeconds formats the Retry-After header value, rounded up to the second.
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
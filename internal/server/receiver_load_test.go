@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// throttledClient fails the list calls as an API server under pressure.
+type throttledClient struct {
+	client.Client
+}
+
+func (throttledClient) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return apierrors.NewTooManyRequests("the server has received too many requests", 1)
+}
+
+func TestLoadShedder_admit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now()
+
+	l := newLoadShedder(1, time.Minute)
+	release, reason, _ := l.admit(now)
+	g.Expect(release).NotTo(gomega.BeNil())
+	g.Expect(reason).To(gomega.BeEmpty())
+
+	_, reason, retryAfter := l.admit(now)
+	g.Expect(reason).To(gomega.Equal(ShedSaturated))
+	g.Expect(retryAfter).To(gomega.Equal(time.Second))
+
+	release()
+	release, _, _ = l.admit(now)
+	g.Expect(release).NotTo(gomega.BeNil())
+	release()
+
+	g.Expect(l.observe(errors.New("not found"), now)).To(gomega.BeFalse())
+	g.Expect(l.observe(fmt.Errorf("unable to annotate: %w", context.DeadlineExceeded), now)).To(gomega.BeTrue())
+	_, reason, retryAfter = l.admit(now.Add(10 * time.Second))
+	g.Expect(reason).To(gomega.Equal(ShedThrottled))
+	g.Expect(retryAfter).To(gomega.Equal(50 * time.Second))
+
+	release, _, _ = l.admit(now.Add(time.Minute))
+	g.Expect(release).NotTo(gomega.BeNil())
+
+	// a nil shedder admits all the requests
+	var disabled *loadShedder
+	release, _, _ = disabled.admit(now)
+	g.Expect(release).NotTo(gomega.BeNil())
+	g.Expect(disabled.observe(apierrors.NewTooManyRequests("", 1), now)).To(gomega.BeFalse())
+}
+
+func TestDeadlineRateLimiter_Wait(t *testing.T) {
+	g := gomega.NewWithT(t)
+	limiter := deadlineRateLimiter{flowcontrol.NewTokenBucketRateLimiter(0.01, 1)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	g.Expect(limiter.Wait(ctx)).To(gomega.Succeed())
+
+	// the limiter refuses the wait before the deadline is reached
+	err := limiter.Wait(ctx)
+	g.Expect(ctx.Err()).To(gomega.BeNil())
+	g.Expect(errors.Is(err, context.DeadlineExceeded)).To(gomega.BeTrue())
+	g.Expect(isThrottled(fmt.Errorf("unable to annotate: %w", err))).To(gomega.BeTrue())
+
+	cancel()
+	g.Expect(limiter.Wait(ctx)).To(gomega.MatchError(context.Canceled))
+}
+
+func TestReceiverServer_loadShedding(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := throttledClient{fake.NewClientBuilder().WithScheme(scheme).Build()}
+
+	metrics := NewReceiverMetrics()
+	s := NewReceiverServer(":0", logf.Log, kubeClient, metrics, 0)
+	s.WithLoadShedding(0, 30*time.Second)
+
+	post := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, httptest.NewRequest(http.MethodPost, "/hook/digest", nil))
+		return rec
+	}
+
+	// the throttled list starts the cooldown
+	rec := post()
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(rec.Header().Get("Retry-After")).To(gomega.Equal("30"))
+
+	// the requests are rejected before calling the API server
+	s.kubeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+	rec = post()
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(rec.Header().Get("Retry-After")).NotTo(gomega.BeEmpty())
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// ReceiverMetrics records the per Receiver webhook handling metrics.
+type ReceiverMetrics struct {
+	requestsCounter     *prometheus.CounterVec
+	verificationCounter *prometheus.CounterVec
+	filterCounter       *prometheus.CounterVec
+	annotationHistogram *prometheus.HistogramVec
+	shedCounter         *prometheus.CounterVec
+	triggerCounter      *prometheus.CounterVec
+	lastTriggerGauge    *prometheus.GaugeVec
+}
+
+// NewReceiverMetrics returns the receiver metrics collectors,
+// they must be registered with a Prometheus registry.
+func NewReceiverMetrics() *ReceiverMetrics {
+	return &ReceiverMetrics{
+		requestsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gotk_receiver_requests_total",
+				Help: "The total number of webhook requests handled by a Receiver, partitioned by status code.",
+			},
+			[]string{"name", "namespace", "type", "code"},
+		),
+		verificationCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gotk_receiver_verification_failures_total",
+				Help: "The total number of webhook requests that failed the Receiver verification.",
+			},
+			[]string{"name", "namespace", "type"},
+		),
+		filterCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gotk_receiver_filter_total",
+				Help: "The total number of verified webhook requests evaluated by the Receiver filters, partitioned by result.",
+			},
+			[]string{"name", "namespace", "result"},
+		),
+		annotationHistogram: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gotk_receiver_annotation_duration_seconds",
+				Help:    "The duration in seconds of the reconcile request annotation of the Receiver resources.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"name", "namespace"},
+		),
+		shedCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gotk_receiver_shed_requests_total",
+				Help: "The total number of webhook requests rejected to protect the API server, partitioned by reason.",
+			},
+			[]string{"reason"},
+		),
+		triggerCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gotk_receiver_triggers_total",
+				Help: "The total number of verified webhook requests handled by a Receiver, partitioned by result.",
+			},
+			[]string{"name", "namespace", "result"},
+		),
+		lastTriggerGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gotk_receiver_last_trigger_timestamp_seconds",
+				Help: "The timestamp of the last verified webhook request handled by a Receiver.",
+			},
+			[]string{"name", "namespace"},
+		),
+	}
+}
+
+// Collectors returns the metrics collectors.
+func (m *ReceiverMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requestsCounter, m.verificationCounter, m.filterCounter, m.annotationHistogram, m.shedCounter,
+		m.triggerCounter, m.lastTriggerGauge}
+}
+
+// RecordRequest increments the requests counter of the receiver for the given status code.
+func (m *ReceiverMetrics) RecordRequest(receiver v1beta1.Receiver, code int) {
+	if m == nil {
+		return
+	}
+	m.requestsCounter.WithLabelValues(receiver.Name, receiver.Namespace, receiver.Spec.Type, strconv.Itoa(code)).Inc()
+}
+
+// RecordVerificationFailure increments the verification failures counter of the receiver.
+func (m *ReceiverMetrics) RecordVerificationFailure(receiver v1beta1.Receiver) {
+	if m == nil {
+		return
+	}
+	m.verificationCounter.WithLabelValues(receiver.Name, receiver.Namespace, receiver.Spec.Type).Inc()
+}
+
+// RecordFilter increments the filter counter of the receiver with a 'pass' or 'fail' result.
+func (m *ReceiverMetrics) RecordFilter(receiver v1beta1.Receiver, passed bool) {
+	if m == nil {
+		return
+	}
+	result := "fail"
+	if passed {
+		result = "pass"
+	}
+	m.filterCounter.WithLabelValues(receiver.Name, receiver.Namespace, result).Inc()
+}
+
+// RecordAnnotationDuration observes the time spent annotating the receiver resources.
+func (m *ReceiverMetrics) RecordAnnotationDuration(receiver v1beta1.Receiver, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.annotationHistogram.WithLabelValues(receiver.Name, receiver.Namespace).Observe(time.Since(start).Seconds())
+}
+
+// RecordShed increments the shed requests counter for the given reason.
+func (m *ReceiverMetrics) RecordShed(reason string) {
+	if m == nil {
+		return
+	}
+	m.shedCounter.WithLabelValues(reason).Inc()
+}
+
+// RecordTrigger increments the triggers counter of the receiver for the given
+// result and sets the timestamp of its last trigger.
+func (m *ReceiverMetrics) RecordTrigger(receiver v1beta1.Receiver, result string, t time.Time) {
+	if m == nil {
+		return
+	}
+	m.triggerCounter.WithLabelValues(receiver.Name, receiver.Namespace, result).Inc()
+	m.lastTriggerGauge.WithLabelValues(receiver.Name, receiver.Namespace).Set(float64(t.Unix()))
+}
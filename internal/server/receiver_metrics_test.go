@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestReceiverMetrics(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := v1beta1.Receiver{}
+	receiver.Name = "webapp"
+	receiver.Namespace = "default"
+	receiver.Spec.Type = v1beta1.GitHubReceiver
+
+	m := NewReceiverMetrics()
+	m.RecordRequest(receiver, http.StatusOK)
+	m.RecordRequest(receiver, http.StatusOK)
+	m.RecordRequest(receiver, http.StatusBadRequest)
+	m.RecordVerificationFailure(receiver)
+	m.RecordFilter(receiver, true)
+	m.RecordFilter(receiver, false)
+	m.RecordAnnotationDuration(receiver, time.Now())
+	m.RecordShed(ShedThrottled)
+	m.RecordTrigger(receiver, v1beta1.SucceededTriggerResult, time.Unix(1600000000, 0))
+
+	g.Expect(testutil.ToFloat64(m.requestsCounter.WithLabelValues("webapp", "default", "github", "200"))).To(gomega.Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(m.requestsCounter.WithLabelValues("webapp", "default", "github", "400"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.verificationCounter.WithLabelValues("webapp", "default", "github"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.filterCounter.WithLabelValues("webapp", "default", "pass"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.filterCounter.WithLabelValues("webapp", "default", "fail"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.CollectAndCount(m.annotationHistogram)).To(gomega.Equal(1))
+	g.Expect(testutil.ToFloat64(m.shedCounter.WithLabelValues("throttled"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.triggerCounter.WithLabelValues("webapp", "default", "Succeeded"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.lastTriggerGauge.WithLabelValues("webapp", "default"))).To(gomega.Equal(float64(1600000000)))
+
+	// a nil recorder is a no-op
+	var nilMetrics *ReceiverMetrics
+	nilMetrics.RecordRequest(receiver, http.StatusOK)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+const (
+	// reportingController is the name of the controller
+	// set on the events issued by the notification-controller.
+	reportingController = "notification-controller"
+
+	// ReceiverTriggeredReason is the event reason used to notify
+	// that a receiver triggered the reconciliation of its resources.
+	ReceiverTriggeredReason = "ReceiverTriggered"
+)
+
+// receiverEvent returns the event describing the resources annotated by the receiver.
+func receiverEvent(receiver v1beta1.Receiver, annotated []v1beta1.CrossNamespaceObjectReference, failed int) events.Event {
+	names := make([]string, 0, len(annotated))
+	for _, resource := range annotated {
+		namespace := resource.Namespace
+		if namespace == "" {
+			namespace = receiver.Namespace
+		}
+		names = append(names, fmt.Sprintf("%s/%s.%s", resource.Kind, resource.Name, namespace))
+	}
+
+	message := fmt.Sprintf("webhook from %s triggered reconcile of %d resources: %s",
+		receiver.Spec.Type, len(annotated), strings.Join(names, ", "))
+	severity := events.EventSeverityInfo
+	if failed > 0 {
+		message = fmt.Sprintf("%s (failed to annotate %d resources)", message, failed)
+		severity = events.EventSeverityError
+	}
+
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: v1beta1.GroupVersion.String(),
+			Kind:       v1beta1.ReceiverKind,
+			Name:       receiver.Name,
+			Namespace:  receiver.Namespace,
+			UID:        receiver.UID,
+		},
+		Severity:            severity,
+		Timestamp:           metav1.Now(),
+		Message:             message,
+		Reason:              ReceiverTriggeredReason,
+		ReportingController: reportingController,
+	}
+}
+
+// notify sends the event using the provider referenced by the receiver,
+// or using each of the providers of a fanout provider.
+func (s *ReceiverServer) notify(ctx context.Context, receiver v1beta1.Receiver, event events.Event) error {
+	var provider v1beta1.Provider
+	providerName := types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Spec.ProviderRef.Name}
+	if err := s.kubeClient.Get(ctx, providerName, &provider); err != nil {
+		return fmt.Errorf("failed to read provider '%s', error: %w", providerName, err)
+	}
+
+	providers, err := resolveProviders(ctx, s.kubeClient, provider)
+	errs := []error{err}
+	for _, provider := range providers {
+		name := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name}
+		deliveryID := notifier.NewDeliveryID()
+		sender, err := s.providers.notifier(ctx, provider, nil, deliveryID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to initialise provider '%s', error: %w", name, err))
+			continue
+		}
+		if err := sender.Post(event); err != nil {
+			errs = append(errs, fmt.Errorf("delivery '%s' to provider '%s' failed: %w", deliveryID, name, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
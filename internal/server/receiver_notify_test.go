@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestReceiverEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := v1beta1.Receiver{}
+	receiver.Name = "github-receiver"
+	receiver.Namespace = "flux-system"
+	receiver.Spec.Type = v1beta1.GitHubReceiver
+
+	annotated := []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "GitRepository", Name: "webapp"},
+		{Kind: "GitRepository", Name: "backend", Namespace: "apps"},
+	}
+
+	event := receiverEvent(receiver, annotated, 0)
+	g.Expect(event.InvolvedObject.Kind).To(gomega.Equal(v1beta1.ReceiverKind))
+	g.Expect(event.InvolvedObject.Name).To(gomega.Equal("github-receiver"))
+	g.Expect(event.Severity).To(gomega.Equal(events.EventSeverityInfo))
+	g.Expect(event.Reason).To(gomega.Equal(ReceiverTriggeredReason))
+	g.Expect(event.Message).To(gomega.Equal(
+		"webhook from github triggered reconcile of 2 resources: GitRepository/webapp.flux-system, GitRepository/backend.apps"))
+
+	event = receiverEvent(receiver, annotated[:1], 1)
+	g.Expect(event.Severity).To(gomega.Equal(events.EventSeverityError))
+	g.Expect(event.Message).To(gomega.ContainSubstring("failed to annotate 1 resources"))
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/egress"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// oidcKeysTTL is the duration the signing keys of the issuers are cached for.
+	oidcKeysTTL = time.Hour
+
+	// oidcKeysRefreshInterval limits the downloads of the signing keys
+	// caused by tokens with an unknown key ID.
+	oidcKeysRefreshInterval = time.Minute
+
+	// oidcMaxResponseSize limits the size of the OpenID configurations
+	// and of the key sets read from the issuers.
+	oidcMaxResponseSize = 1 << 20
+)
+
+var oidcKeys = newOIDCKeyCache(newOIDCClient(nil))
+
+// WithEgressPolicy restricts the addresses the signing keys of the
+// OIDC issuers are downloaded from, the issuers are set by the tenants.
+func (s *ReceiverServer) WithEgressPolicy(policy *egress.Policy) {
+	oidcKeys = newOIDCKeyCache(newOIDCClient(policy))
+}
+
+// newOIDCClient returns a client connecting only to the IPs permitted
+// by the egress policy, over HTTPS.
+func newOIDCClient(policy *egress.Policy) *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         policy.DialContext(&net.Dialer{Timeout: 10 * time.Second}),
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to the non-HTTPS URL '%s'", req.URL)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// verifyOIDCToken checks the JWT bearer token of the request against the
+// issuer, the audience and the subjects of the receiver, and returns its claims.
+func verifyOIDCToken(r *http.Request, oidc *v1beta1.ReceiverOIDC, now time.Time) (*jwtClaims, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, receivers.Errorf(receivers.MissingSignature, "the Authorization header is missing or malformed")
+	}
+
+	claims, err := verifyRS256JWT(strings.TrimPrefix(auth, "Bearer "), func(kid string) (*rsa.PublicKey, error) {
+		return oidcKeys.get(oidc.Issuer, oidc.JWKSURL, kid, now)
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("the JWT has no expiry")
+	}
+	if strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(oidc.Issuer, "/") {
+		return nil, fmt.Errorf("the JWT issuer '%s' is not '%s'", claims.Issuer, oidc.Issuer)
+	}
+	if !containsString(claims.Audiences, oidc.Audience) {
+		return nil, fmt.Errorf("the JWT audience '%s' is not '%s'", strings.Join(claims.Audiences, ", "), oidc.Audience)
+	}
+	// any workload of a shared issuer can get a token for the audience
+	if len(oidc.Subjects) == 0 {
+		return nil, fmt.Errorf("the receiver allows no JWT subject")
+	}
+	if !matchSubject(oidc.Subjects, claims.Subject) {
+		return nil, fmt.Errorf("the JWT subject '%s' is not allowed", claims.Subject)
+	}
+	return claims, nil
+}
+
+// matchSubject returns true if the subject matches one of the patterns, or if there
+// are none. The '*' wildcard matches any sequence, including the '/' separators of
+// the subjects, e.g. 'repo:org/webapp:*' matches 'repo:org/webapp:ref:refs/heads/main'.
+func matchSubject(patterns []string, subject string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchWildcard(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// oidcKeySet holds the signing keys of an issuer.
+type oidcKeySet struct {
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// oidcKeyCache caches the signing keys of the issuers, the keys are
+// downloaded from the JWKS URL, discovered when not configured.
+type oidcKeyCache struct {
+	client *http.Client
+
+	mu   sync.Mutex
+	sets map[string]*oidcKeySet
+}
+
+func newOIDCKeyCache(client *http.Client) *oidcKeyCache {
+	return &oidcKeyCache{
+		client: client,
+		sets:   make(map[string]*oidcKeySet),
+	}
+}
+
+// get returns the key, the keys are downloaded when they expire
+// or when the key ID is unknown.
+func (c *oidcKeyCache) get(issuer, jwksURL, kid string, now time.Time) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cacheKey := issuer + " " + jwksURL
+	set, ok := c.sets[cacheKey]
+	if !ok {
+		set = &oidcKeySet{}
+		c.sets[cacheKey] = set
+	}
+
+	key, ok := set.keys[kid]
+	expired := now.Sub(set.fetched) > oidcKeysTTL
+	if ok && !expired {
+		return key, nil
+	}
+	if !expired && now.Sub(set.fetched) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key '%s' of the issuer '%s'", kid, issuer)
+	}
+
+	keys, err := c.fetch(issuer, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	set.keys = keys
+	set.fetched = now
+
+	if key, ok := set.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key '%s' of the issuer '%s'", kid, issuer)
+}
+
+// fetch downloads the RSA signing keys of the issuer, the other keys are ignored.
+func (c *oidcKeyCache) fetch(issuer, jwksURL string) (map[string]*rsa.PublicKey, error) {
+	if jwksURL == "" {
+		var config struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := c.getJSON(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &config); err != nil {
+			return nil, fmt.Errorf("cannot discover the OpenID configuration of '%s': %w", issuer, err)
+		}
+		if config.JWKSURI == "" {
+			return nil, fmt.Errorf("the OpenID configuration of '%s' has no JWKS URI", issuer)
+		}
+		jwksURL = config.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.getJSON(jwksURL, &jwks); err != nil {
+		return nil, fmt.Errorf("cannot download the signing keys of '%s': %w", issuer, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of the signing key '%s': %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of the signing key '%s': %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// getJSON downloads and decodes the JSON document, the URL must be HTTPS.
+func (c *oidcKeyCache) getJSON(rawURL string, out interface{}) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("the URL '%s' is not HTTPS", rawURL)
+	}
+
+	resp, err := c.client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status %s", rawURL, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(out)
+}
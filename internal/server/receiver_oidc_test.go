@@ -0,0 +1,188 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/egress"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestJWTClaims_audiences(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var claims jwtClaims
+	g.Expect(json.Unmarshal([]byte(`{"sub":"ci","aud":"flux"}`), &claims)).To(gomega.Succeed())
+	g.Expect(claims.Subject).To(gomega.Equal("ci"))
+	g.Expect(claims.Audiences).To(gomega.Equal([]string{"flux"}))
+
+	g.Expect(json.Unmarshal([]byte(`{"aud":["api","flux"]}`), &claims)).To(gomega.Succeed())
+	g.Expect(claims.Audience).To(gomega.Equal("api"))
+	g.Expect(claims.Audiences).To(gomega.Equal([]string{"api", "flux"}))
+
+	g.Expect(json.Unmarshal([]byte(`{"aud":42}`), &claims)).NotTo(gomega.Succeed())
+}
+
+func TestReceiverServer_validateOIDC(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	var issuer string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"kid": "k1",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	issuer = ts.URL
+	oidcKeys = newOIDCKeyCache(ts.Client())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "oidc", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			OIDC: &v1beta1.ReceiverOIDC{
+				Issuer:   issuer,
+				Audience: "flux",
+				Subjects: []string{"repo:org/webapp:*"},
+			},
+		},
+	}
+
+	claims := jwtClaims{
+		Issuer:    issuer,
+		Subject:   "repo:org/webapp:ref:refs/heads/main",
+		Audience:  "flux",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+	request := func(claims jwtClaims, kid string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/oidc", bytes.NewReader([]byte(`{}`)))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer "+signRS256(g, key, kid, claims))
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request(claims, "k1"))).To(gomega.Succeed())
+
+	otherAudience := claims
+	otherAudience.Audience = "other"
+	g.Expect(s.validate(ctx, receiver, request(otherAudience, "k1"))).NotTo(gomega.Succeed())
+
+	otherIssuer := claims
+	otherIssuer.Issuer = "https://example.com"
+	g.Expect(s.validate(ctx, receiver, request(otherIssuer, "k1"))).NotTo(gomega.Succeed())
+
+	otherSubject := claims
+	otherSubject.Subject = "repo:org/backend:ref:refs/heads/main"
+	g.Expect(s.validate(ctx, receiver, request(otherSubject, "k1"))).NotTo(gomega.Succeed())
+
+	// the tokens are rejected when no subject is allowed
+	anySubject := receiver.DeepCopy()
+	anySubject.Spec.OIDC.Subjects = nil
+	g.Expect(s.validate(ctx, *anySubject, request(claims, "k1"))).NotTo(gomega.Succeed())
+
+	expired := claims
+	expired.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	g.Expect(s.validate(ctx, receiver, request(expired, "k1"))).NotTo(gomega.Succeed())
+
+	g.Expect(s.validate(ctx, receiver, request(claims, "k2"))).NotTo(gomega.Succeed())
+
+	unsigned := request(claims, "k1")
+	unsigned.Header.Del("Authorization")
+	err = s.validate(ctx, receiver, unsigned)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.MissingSignature))
+
+	// the keys aren't downloaded over plain HTTP
+	receiver.Spec.OIDC.JWKSURL = "http://" + ts.Listener.Addr().String() + "/keys"
+	g.Expect(s.validate(ctx, receiver, request(claims, "k1"))).NotTo(gomega.Succeed())
+}
+
+func TestOIDCKeyCache_egressPolicy(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var requests int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []interface{}{}})
+	}))
+	defer ts.Close()
+
+	policy, err := egress.ParsePolicy(nil, []string{"127.0.0.0/8", "::1/128"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	cache := newOIDCKeyCache(newOIDCClient(policy))
+
+	_, err = cache.get(ts.URL, ts.URL+"/keys", "k1", time.Now())
+	g.Expect(err).To(gomega.MatchError(egress.ErrDenied))
+	g.Expect(requests).To(gomega.BeZero())
+}
+
+func TestMatchSubject(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(matchSubject(nil, "repo:org/webapp:ref:refs/heads/main")).To(gomega.BeTrue())
+	g.Expect(matchSubject([]string{"repo:org/webapp:*"}, "repo:org/webapp:ref:refs/heads/main")).To(gomega.BeTrue())
+	g.Expect(matchSubject([]string{"repo:org/*:ref:refs/heads/main"}, "repo:org/webapp:ref:refs/heads/main")).To(gomega.BeTrue())
+	g.Expect(matchSubject([]string{"repo:org/*:ref:refs/heads/main"}, "repo:org/webapp:ref:refs/heads/dev")).To(gomega.BeFalse())
+	g.Expect(matchSubject([]string{"system:serviceaccount:ci:runner"}, "system:serviceaccount:ci:runner")).To(gomega.BeTrue())
+	g.Expect(matchSubject([]string{"system:serviceaccount:ci:runner"}, "system:serviceaccount:ci:runner2")).To(gomega.BeFalse())
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+)
+
+// EventPublisher dispatches the events published by the receivers to the alerts.
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// WithEventPublisher makes the receivers with spec.publishEvent dispatch the
+// events mapped from their payloads to the alerts with the publisher.
+func (s *ReceiverServer) WithEventPublisher(publisher EventPublisher) {
+	s.publisher = publisher
+}
+
+// publishEvent maps the payload to an event involving the receiver,
+// and dispatches it to the alerts.
+func (s *ReceiverServer) publishEvent(ctx context.Context, receiver v1beta1.Receiver, webhookEvent, contentType string, payload []byte) error {
+	if s.publisher == nil {
+		return fmt.Errorf("the receiver server has no event publisher")
+	}
+
+	event, err := trigger.PublishedEvent(s.programs, receiver, contentType, payload)
+	if err != nil {
+		return err
+	}
+	event.InvolvedObject = corev1.ObjectReference{
+		APIVersion: v1beta1.GroupVersion.String(),
+		Kind:       v1beta1.ReceiverKind,
+		Name:       receiver.Name,
+		Namespace:  receiver.Namespace,
+		UID:        receiver.UID,
+	}
+	event.Timestamp = metav1.Now()
+	event.ReportingController = reportingController
+	if webhookEvent != "" {
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]string, 1)
+		}
+		if _, ok := event.Metadata["event"]; !ok {
+			event.Metadata["event"] = webhookEvent
+		}
+	}
+	return s.publisher.Publish(ctx, event)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestReceiverServer_publishEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			PublishEvent: &v1beta1.ReceiverEvent{
+				Message:  "'build ' + string(request.body.build) + ' ' + request.body.status",
+				Severity: "request.body.status",
+			},
+		},
+		Status: v1beta1.ReceiverStatus{URL: "/hook/digest"},
+	}
+	meta.SetResourceCondition(receiver, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver, secret).Build()
+
+	publisher := &recordingPublisher{}
+	s := NewReceiverServer(":0", logf.Log, kubeClient, NewReceiverMetrics(), 0)
+	s.WithEventPublisher(publisher)
+
+	post := func(payload string) int {
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", bytes.NewReader([]byte(payload)))
+		r.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, r)
+		return rec.Code
+	}
+
+	g.Expect(post(`{"build":42,"status":"error"}`)).To(gomega.Equal(http.StatusOK))
+	g.Expect(publisher.events).To(gomega.HaveLen(1))
+	event := publisher.events[0]
+	g.Expect(event.Message).To(gomega.Equal("build 42 error"))
+	g.Expect(event.Severity).To(gomega.Equal(events.EventSeverityError))
+	g.Expect(event.InvolvedObject.Kind).To(gomega.Equal(v1beta1.ReceiverKind))
+	g.Expect(event.InvolvedObject.Name).To(gomega.Equal("ci"))
+	g.Expect(event.ReportingController).To(gomega.Equal(reportingController))
+
+	// the payloads that can't be mapped are rejected
+	g.Expect(post(`{"build":`)).To(gomega.Equal(http.StatusBadRequest))
+	g.Expect(publisher.events).To(gomega.HaveLen(1))
+}
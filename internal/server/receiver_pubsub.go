@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// pubsubMessage is a Pub/Sub message with its data decoded, the data is
+// the decoded JSON value, or the string when it's not JSON.
+type pubsubMessage struct {
+	Data         interface{}       `json:"data"`
+	Attributes   map[string]string `json:"attributes"`
+	MessageID    string            `json:"messageId"`
+	PublishTime  time.Time         `json:"publishTime"`
+	Subscription string            `json:"subscription"`
+}
+
+// parsePubSubMessage decodes the Pub/Sub message pushed to the receiver.
+func parsePubSubMessage(body []byte) (pubsubMessage, error) {
+	var p pubsubPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return pubsubMessage{}, fmt.Errorf("cannot decode Pub/Sub message: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(p.Message.Data)
+	if err != nil {
+		return pubsubMessage{}, fmt.Errorf("cannot decode Pub/Sub message data: %w", err)
+	}
+
+	m := pubsubMessage{
+		Data:         string(data),
+		Attributes:   p.Message.Attributes,
+		MessageID:    p.Message.MessageID,
+		PublishTime:  p.Message.PublishTime,
+		Subscription: p.Subscription,
+	}
+	if m.Attributes == nil {
+		m.Attributes = map[string]string{}
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err == nil {
+		m.Data = v
+	}
+	return m, nil
+}
+
+// match evaluates the CEL condition with the message.
+func (m pubsubMessage) match(r receivers.Request, condition string) (bool, error) {
+	return matchFilterCondition(r, condition, m)
+}
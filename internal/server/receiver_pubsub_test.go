@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestParsePubSubMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	push := func(data string) []byte {
+		return []byte(`{"message":{"data":"` + base64.StdEncoding.EncodeToString([]byte(data)) +
+			`","attributes":{"status":"SUCCESS"},"messageId":"1"},"subscription":"projects/project/subscriptions/flux"}`)
+	}
+
+	m, err := parsePubSubMessage(push(`{"id":"b1","substitutions":{"BRANCH_NAME":"main"}}`))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(m.Attributes).To(gomega.HaveKeyWithValue("status", "SUCCESS"))
+	g.Expect(m.match(receivers.Request{}, "message.data.substitutions.BRANCH_NAME == 'main' && message.attributes.status == 'SUCCESS'")).To(gomega.BeTrue())
+	g.Expect(m.match(receivers.Request{}, "message.subscription.endsWith('/other')")).To(gomega.BeFalse())
+
+	m, err = parsePubSubMessage(push("plain text"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(m.match(receivers.Request{}, "message.data == 'plain text'")).To(gomega.BeTrue())
+
+	_, err = parsePubSubMessage([]byte(`{"message":{"data":"!"}}`))
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestReceiverServer_validatePubSub(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	now := time.Now()
+	googleKeys.mu.Lock()
+	googleKeys.keys = map[string]*rsa.PublicKey{"k1": &key.PublicKey}
+	googleKeys.fetched = now
+	googleKeys.mu.Unlock()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "pubsub", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:                v1beta1.PubSubReceiver,
+			SecretRef:           meta.LocalObjectReference{Name: "webhook-token"},
+			PushServiceAccounts: []string{"pubsub@project.iam.gserviceaccount.com"},
+			Filter:              &v1beta1.ReceiverFilter{Condition: "message.attributes.status == 'SUCCESS'"},
+		},
+	}
+
+	push := func(status string) []byte {
+		return []byte(`{"message":{"data":"` + base64.StdEncoding.EncodeToString([]byte(`{"id":"b1"}`)) +
+			`","attributes":{"status":"` + status + `"},"messageId":"1"},"subscription":"projects/project/subscriptions/flux"}`)
+	}
+	claims := jwtClaims{
+		Issuer:        "https://accounts.google.com",
+		Audience:      "https://flux.example.com/hook/pubsub",
+		Email:         "pubsub@project.iam.gserviceaccount.com",
+		EmailVerified: true,
+		ExpiresAt:     now.Add(time.Hour).Unix(),
+	}
+	request := func(body []byte, claims jwtClaims) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/pubsub", bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+signRS256(g, key, "k1", claims))
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), claims))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request(push("FAILURE"), claims))).To(gomega.MatchError(errEventFiltered))
+
+	otherAudience := claims
+	otherAudience.Audience = "https://flux.example.com/hook/other"
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), otherAudience))).NotTo(gomega.Succeed())
+
+	otherIssuer := claims
+	otherIssuer.Issuer = "https://example.com"
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), otherIssuer))).NotTo(gomega.Succeed())
+
+	// the valid tokens of the other accounts are rejected
+	otherAccount := claims
+	otherAccount.Email = "attacker@other-project.iam.gserviceaccount.com"
+	err = s.validate(ctx, receiver, request(push("SUCCESS"), otherAccount))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	unverified := claims
+	unverified.EmailVerified = false
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), unverified))).NotTo(gomega.Succeed())
+
+	// the messages without the attribute don't match
+	receiver.Spec.Filter.Condition = "message.attributes.buildId == 'b1'"
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), claims))).To(gomega.MatchError(errEventFiltered))
+
+	receiver.Spec.PushServiceAccounts = nil
+	g.Expect(s.validate(ctx, receiver, request(push("SUCCESS"), claims))).NotTo(gomega.Succeed())
+}
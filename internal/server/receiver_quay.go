@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// quayRepoPush is the Quay notification of the pushed tags.
+	quayRepoPush = "repo_push"
+
+	// quayVulnerabilityFound is the Quay notification of a vulnerability
+	// found by the security scanner in the tags of a repository.
+	quayVulnerabilityFound = "vulnerability_found"
+
+	// quayBuildSuccess is the Quay notification of a completed build.
+	quayBuildSuccess = "build_success"
+
+	// quayBuildFailure is the Quay notification of a failed build.
+	quayBuildFailure = "build_failure"
+)
+
+// quayEvent is the union of the Quay notification payloads, Quay doesn't send
+// the notification kind, it's derived from the fields of the payload.
+type quayEvent struct {
+	Repository string `json:"repository"`
+	DockerUrl  string `json:"docker_url"`
+
+	// repo_push
+	UpdatedTags []string `json:"updated_tags"`
+
+	// vulnerability_found
+	Tags          []string `json:"tags"`
+	Vulnerability *struct {
+		ID       string `json:"id"`
+		Priority string `json:"priority"`
+	} `json:"vulnerability"`
+
+	// build_*
+	BuildID         string   `json:"build_id"`
+	DockerTags      []string `json:"docker_tags"`
+	ImageID         string   `json:"image_id"`
+	ManifestDigests []string `json:"manifest_digests"`
+	ErrorMessage    string   `json:"error_message"`
+}
+
+// kind returns the Quay notification kind, the payloads of the builds that
+// are queued or started are returned as an empty kind. The payloads without
+// a kind specific field are repository pushes, e.g. the pushes of no tags.
+func (e quayEvent) kind() string {
+	switch {
+	case e.Vulnerability != nil:
+		return quayVulnerabilityFound
+	case e.BuildID != "" && e.ErrorMessage != "":
+		return quayBuildFailure
+	case e.BuildID != "" && (e.ImageID != "" || len(e.ManifestDigests) > 0):
+		return quayBuildSuccess
+	case e.BuildID != "":
+		return ""
+	default:
+		return quayRepoPush
+	}
+}
+
+// image returns the tag and the digest of the image of the notification.
+func (e quayEvent) image() (string, string) {
+	var tags []string
+	var digest string
+	switch e.kind() {
+	case quayRepoPush:
+		tags = e.UpdatedTags
+	case quayVulnerabilityFound:
+		tags = e.Tags
+	case quayBuildSuccess, quayBuildFailure:
+		tags = e.DockerTags
+		if len(e.ManifestDigests) > 0 {
+			digest = imageDigest(e.ManifestDigests[0])
+		}
+	}
+	if len(tags) > 0 {
+		return tags[0], digest
+	}
+	return "", digest
+}
+
+// verifyQuay decodes the Quay payload and matches the notification kind against
+// the receiver events and the repository against the receiver filter, Quay
+// doesn't sign its requests.
+func verifyQuay(ctx context.Context, r receivers.Request) error {
+	var e quayEvent
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode Quay webhook payload")
+	}
+
+	kind := e.kind()
+	if kind == "" {
+		return receivers.Errorf(receivers.EventNotAllowed,
+			"the Quay build notifications are only handled on success or failure")
+	}
+	if !receivers.EventAllowed(r.Receiver, kind) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Quay event '%s' is not authorised", kind)
+	}
+
+	if filter := r.Receiver.Spec.Filter; filter != nil && !matchAny(filter.Repositories, e.Repository) {
+		return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, e.Repository)
+	}
+
+	r.SetEvent(kind)
+	if tag, digest := e.image(); tag != "" || digest != "" {
+		r.SetImage(tag, digest)
+	}
+	r.Logger.Info(fmt.Sprintf("handling Quay event: %s from %s", kind, e.DockerUrl))
+	return nil
+}
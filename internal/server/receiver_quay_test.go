@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	quayVulnerability = `{
+  "repository": "apps/webapp",
+  "namespace": "apps",
+  "name": "webapp",
+  "docker_url": "quay.io/apps/webapp",
+  "tags": ["1.2.0", "latest"],
+  "vulnerability": {"id": "CVE-2021-3449", "priority": "High", "has_fix": true}
+}`
+	quayBuild = `{
+  "build_id": "296ec063-5f86-4706-a469-f0a400bf9df2",
+  "repository": "apps/webapp",
+  "docker_url": "quay.io/apps/webapp",
+  "docker_tags": ["1.2.0"],
+  "image_id": "1245657346",
+  "manifest_digests": ["quay.io/apps/webapp@sha256:954b378c375d"]
+}`
+)
+
+func TestQuayEvent_kind(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	for body, expected := range map[string]string{
+		`{"repository":"apps/webapp","updated_tags":["1.2.0"]}`:   quayRepoPush,
+		`{"repository":"apps/webapp"}`:                            quayRepoPush,
+		quayVulnerability:                                         quayVulnerabilityFound,
+		quayBuild:                                                 quayBuildSuccess,
+		`{"build_id":"296ec063","error_message":"no Dockerfile"}`: quayBuildFailure,
+		`{"build_id":"296ec063","docker_tags":["1.2.0"]}`:         "",
+	} {
+		var e quayEvent
+		g.Expect(json.Unmarshal([]byte(body), &e)).To(gomega.Succeed())
+		g.Expect(e.kind()).To(gomega.Equal(expected), body)
+	}
+}
+
+func TestReceiverServer_validateQuay(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "quay", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.QuayReceiver,
+			Events:    []string{"vulnerability_found", "build_success"},
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Filter:    &v1beta1.ReceiverFilter{Repositories: []string{"apps/*"}},
+		},
+	}
+	request := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/hook/quay", bytes.NewReader([]byte(body)))
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request(`{"repository":"apps/webapp","updated_tags":["1.2.0"]}`))).NotTo(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, request(`{"repository":"infra/proxy","vulnerability":{"id":"CVE-2021-3449"}}`))).
+		To(gomega.MatchError(errEventFiltered))
+
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, request(quayVulnerability), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("vulnerability_found"))
+	g.Expect(result.Tag).To(gomega.Equal("1.2.0"))
+
+	result = receivers.Result{}
+	g.Expect(s.verify(ctx, receiver, request(quayBuild), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("build_success"))
+	g.Expect(result.Tag).To(gomega.Equal("1.2.0"))
+	g.Expect(result.Digest).To(gomega.Equal("sha256:954b378c375d"))
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/trigger"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// ShedQueueFull is the reason of the webhook requests to the asynchronous
+// receivers rejected when the trigger queue is full.
+const ShedQueueFull = "queue-full"
+
+// asyncRetryAfter is the delay advertised to the senders of the requests
+// rejected by a full trigger queue.
+const asyncRetryAfter = 10 * time.Second
+
+// triggerJob is a verified request to an asynchronous receiver, the annotations
+// are computed from the request before it's queued.
+type triggerJob struct {
+	receiver    v1beta1.Receiver
+	result      receivers.Result
+	annotations map[string]string
+	payload     []byte
+
+	// forget lets the sender retry the delivery if the annotation fails.
+	forget func()
+}
+
+// triggerQueue annotates the resources of the asynchronous receivers
+// with a fixed number of workers.
+type triggerQueue struct {
+	workers   int
+	maxQueued int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   []triggerJob
+	closed bool
+}
+
+// newTriggerQueue returns a queue holding at most maxQueued requests,
+// a zero maxQueued doesn't limit the queue.
+func newTriggerQueue(workers, maxQueued int) *triggerQueue {
+	q := &triggerQueue{
+		workers:   workers,
+		maxQueued: maxQueued,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues the request, it returns false if the queue is full or closed.
+func (q *triggerQueue) push(job triggerJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || (q.maxQueued > 0 && len(q.jobs) >= q.maxQueued) {
+		return false
+	}
+	q.jobs = append(q.jobs, job)
+	q.cond.Signal()
+	return true
+}
+
+// pop blocks until a request is queued, it returns false once the queue is closed.
+func (q *triggerQueue) pop() (triggerJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.jobs) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return triggerJob{}, false
+	}
+
+	var job triggerJob
+	job, q.jobs = q.jobs[0], q.jobs[1:]
+	return job, true
+}
+
+// close stops the workers, the queued requests are discarded.
+func (q *triggerQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// run starts the workers handling the requests and blocks until the queue is closed.
+func (q *triggerQueue) run(handle func(triggerJob)) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := q.pop()
+				if !ok {
+					return
+				}
+				handle(job)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// WithAsyncWorkers makes the server respond with a 202 to the verified requests
+// to the receivers with spec.async, the resources are annotated by the workers.
+// A request is rejected with a 503 when queueSize requests are waiting for a worker.
+// The asynchronous receivers are handled synchronously when workers is zero.
+func (s *ReceiverServer) WithAsyncWorkers(workers, queueSize int) {
+	if workers > 0 {
+		s.triggers = newTriggerQueue(workers, queueSize)
+	}
+}
+
+// processTrigger annotates the resources of a queued request and records
+// the outcome in the receiver status.
+func (s *ReceiverServer) processTrigger(job triggerJob) {
+	ctx := context.Background()
+	receiver := job.receiver
+	logger := s.logger.WithValues(
+		"reconciler kind", v1beta1.ReceiverKind,
+		"name", receiver.Name,
+		"namespace", receiver.Namespace)
+
+	annotated, annotateErrors, throttled := s.annotate(ctx, receiver, job.result, job.annotations, job.payload, logger)
+	if throttled || annotateErrors > 0 {
+		job.forget()
+	}
+	if throttled {
+		s.metrics.RecordShed(ShedThrottled)
+	}
+
+	result := triggerResult(annotateErrors, throttled)
+	now := metav1.Now()
+	s.metrics.RecordTrigger(receiver, result, now.Time)
+
+	receiverName := types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Name}
+	if err := trigger.RecordTrigger(ctx, s.kubeClient, receiverName, v1beta1.ReceiverTrigger{
+		Time:      now,
+		Event:     job.result.Event,
+		Annotated: len(annotated),
+		Failed:    annotateErrors,
+		Result:    result,
+	}, s.historySize); err != nil {
+		logger.Error(err, "unable to record the trigger outcome")
+		return
+	}
+	logger.Info(fmt.Sprintf("trigger completed, %d resources annotated, %d failed", len(annotated), annotateErrors))
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestTriggerQueue(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	q := newTriggerQueue(1, 2)
+	g.Expect(q.push(triggerJob{payload: []byte("1")})).To(gomega.BeTrue())
+	g.Expect(q.push(triggerJob{payload: []byte("2")})).To(gomega.BeTrue())
+	g.Expect(q.push(triggerJob{payload: []byte("3")})).To(gomega.BeFalse())
+
+	job, ok := q.pop()
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(string(job.payload)).To(gomega.Equal("1"))
+
+	q.close()
+	_, ok = q.pop()
+	g.Expect(ok).To(gomega.BeFalse())
+	g.Expect(q.push(triggerJob{})).To(gomega.BeFalse())
+}
+
+func TestReceiverServer_async(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "generic", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Async:     true,
+			Resources: []v1beta1.CrossNamespaceObjectReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "webapp"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "missing"},
+			},
+		},
+		Status: v1beta1.ReceiverStatus{URL: "/hook/digest"},
+	}
+	meta.SetResourceCondition(receiver, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp", Namespace: "default"},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver, secret, configMap).Build()
+
+	s := NewReceiverServer(":0", logf.Log, kubeClient, NewReceiverMetrics(), 0)
+	s.WithAsyncWorkers(1, 1)
+
+	post := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", nil)
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, r)
+		return rec.Code
+	}
+
+	// the workers aren't started, the second request finds the queue full
+	g.Expect(post()).To(gomega.Equal(http.StatusAccepted))
+	g.Expect(post()).To(gomega.Equal(http.StatusServiceUnavailable))
+
+	go s.triggers.run(s.processTrigger)
+	defer s.triggers.close()
+
+	receiverName := types.NamespacedName{Name: "generic", Namespace: "default"}
+	g.Eventually(func() *v1beta1.ReceiverTrigger {
+		var got v1beta1.Receiver
+		g.Expect(kubeClient.Get(context.TODO(), receiverName, &got)).To(gomega.Succeed())
+		return got.Status.LastTrigger
+	}).ShouldNot(gomega.BeNil())
+
+	var got v1beta1.Receiver
+	g.Expect(kubeClient.Get(context.TODO(), receiverName, &got)).To(gomega.Succeed())
+	g.Expect(got.Status.LastTrigger.Annotated).To(gomega.Equal(1))
+	g.Expect(got.Status.LastTrigger.Failed).To(gomega.Equal(1))
+
+	var annotated corev1.ConfigMap
+	g.Expect(kubeClient.Get(context.TODO(), types.NamespacedName{Name: "webapp", Namespace: "default"}, &annotated)).To(gomega.Succeed())
+	g.Expect(annotated.Annotations).To(gomega.HaveKey(meta.ReconcileRequestAnnotation))
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// replayGuard rejects the signed requests with a timestamp outside the window,
+// and records the nonces of the signed requests to reject their replays.
+type replayGuard struct {
+	window time.Duration
+	nonces *deliveryCache
+}
+
+// WithReplayProtection makes the server reject the requests whose signed
+// timestamp is more than the window away from the controller clock, and the
+// replays of the signed requests handled within the window.
+func (s *ReceiverServer) WithReplayProtection(window time.Duration) {
+	if window > 0 {
+		s.replays = &replayGuard{window: window, nonces: newDeliveryCache(window)}
+	}
+}
+
+// check returns the key of the request nonce, forgotten if the request fails,
+// or a ReplayedRequest error. A nil guard accepts all requests.
+func (g *replayGuard) check(receiver v1beta1.Receiver, result receivers.Result, now time.Time) (string, error) {
+	if g == nil {
+		return "", nil
+	}
+
+	if !result.SignedAt.IsZero() {
+		if skew := now.Sub(result.SignedAt); skew > g.window || skew < -g.window {
+			return "", receivers.Errorf(receivers.ReplayedRequest,
+				"the request was signed at %s, outside the replay window of %s",
+				result.SignedAt.UTC().Format(time.RFC3339), g.window)
+		}
+	}
+
+	if result.Nonce == "" {
+		return "", nil
+	}
+	key := fmt.Sprintf("%s/%s/%s", receiver.Namespace, receiver.Name, result.Nonce)
+	if g.nonces.seen(key, now) {
+		return "", receivers.Errorf(receivers.ReplayedRequest, "the request replays a request handled in the last %s", g.window)
+	}
+	return key, nil
+}
+
+// forget removes the nonce so that a retry of a failed request is handled.
+func (g *replayGuard) forget(key string) {
+	if g != nil {
+		g.nonces.forget(key)
+	}
+}
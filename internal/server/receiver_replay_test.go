@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func TestReplayGuard_check(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now()
+	receiver := v1beta1.Receiver{ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"}}
+
+	s := NewReceiverServer(":0", logf.Log, nil, nil, 0)
+	s.WithReplayProtection(5 * time.Minute)
+
+	key, err := s.replays.check(receiver, receivers.Result{SignedAt: now.Add(-time.Minute), Nonce: "v0=abc"}, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = s.replays.check(receiver, receivers.Result{SignedAt: now.Add(-time.Minute), Nonce: "v0=abc"}, now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.ReplayedRequest))
+
+	// a failed request can be retried
+	s.replays.forget(key)
+	_, err = s.replays.check(receiver, receivers.Result{SignedAt: now.Add(-time.Minute), Nonce: "v0=abc"}, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	_, err = s.replays.check(receiver, receivers.Result{SignedAt: now.Add(-10 * time.Minute), Nonce: "v0=def"}, now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.ReplayedRequest))
+	_, err = s.replays.check(receiver, receivers.Result{SignedAt: now.Add(10 * time.Minute), Nonce: "v0=def"}, now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.ReplayedRequest))
+
+	// the nonces expire with the window
+	_, err = s.replays.check(receiver, receivers.Result{Nonce: "v0=abc"}, now.Add(6*time.Minute))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// the requests without a nonce, and all requests when disabled, are accepted
+	_, err = s.replays.check(receiver, receivers.Result{}, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var disabled *replayGuard
+	_, err = disabled.check(receiver, receivers.Result{SignedAt: now.Add(-time.Hour), Nonce: "v0=abc"}, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+}
+
+func TestReceiverServer_replayProtection(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "hmac", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.GenericHMACReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+		},
+		Status: v1beta1.ReceiverStatus{URL: "/hook/digest"},
+	}
+	meta.SetResourceCondition(receiver, meta.ReadyCondition, metav1.ConditionTrue, v1beta1.InitializedReason, "")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(v1beta1.AddToScheme(scheme)).To(gomega.Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver, secret).Build()
+
+	s := NewReceiverServer(":0", logf.Log, kubeClient, NewReceiverMetrics(), 0)
+	s.WithReplayProtection(5 * time.Minute)
+
+	post := func(payload string) int {
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write([]byte(payload))
+		r := httptest.NewRequest(http.MethodPost, "/hook/digest", bytes.NewReader([]byte(payload)))
+		r.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		s.handlePayload()(rec, r)
+		return rec.Code
+	}
+
+	g.Expect(post(`{"id":1}`)).To(gomega.Equal(http.StatusOK))
+	g.Expect(post(`{"id":1}`)).To(gomega.Equal(http.StatusConflict))
+	g.Expect(post(`{"id":2}`)).To(gomega.Equal(http.StatusOK))
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -30,36 +31,87 @@ import (
 	"github.com/slok/go-http-metrics/middleware"
 	"github.com/slok/go-http-metrics/middleware/std"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/trigger"
 )
 
 // ReceiverServer handles webhook POST requests
 type ReceiverServer struct {
+	servingTLS
+
 	port       string
 	logger     logr.Logger
 	kubeClient client.Client
+	providers  *Providers
+	metrics    *ReceiverMetrics
+	deliveries *deliveryCache
+	replays    *replayGuard
+	shedder    *loadShedder
+	triggers   *triggerQueue
+	publisher  EventPublisher
+	coalescer  *coalescer
+	programs   *cel.Cache
+
+	// historySize is the number of requests recorded in the receiver status.
+	historySize int
+
+	// trustedProxies are the proxies whose X-Forwarded-For header is honoured.
+	trustedProxies []*net.IPNet
+
+	// requireClientCerts rejects the requests to the receivers without a certSecretRef.
+	requireClientCerts bool
 }
 
-// NewEventServer returns an HTTP server that handles webhooks
-func NewReceiverServer(port string, logger logr.Logger, kubeClient client.Client) *ReceiverServer {
-	return &ReceiverServer{
+// NewEventServer returns an HTTP server that handles webhooks,
+// the deliveries retried within the idempotency window are ignored.
+func NewReceiverServer(port string, logger logr.Logger, kubeClient client.Client, metrics *ReceiverMetrics,
+	idempotencyWindow time.Duration) *ReceiverServer {
+	s := &ReceiverServer{
 		port:       port,
 		logger:     logger.WithName("receiver-server"),
 		kubeClient: kubeClient,
+		providers:  NewProviders(kubeClient, nil),
+		metrics:    metrics,
+		programs:   trigger.NewPrograms(),
+	}
+	s.coalescer = newCoalescer(s.applyCoalesced)
+	if idempotencyWindow > 0 {
+		s.deliveries = newDeliveryCache(idempotencyWindow)
 	}
+	return s
+}
+
+// WithLoadShedding makes the server reject the webhook requests with a 503
+// when more than maxInFlight requests are being handled, and for the cooldown
+// duration after the API server throttled the controller.
+func (s *ReceiverServer) WithLoadShedding(maxInFlight int, cooldown time.Duration) {
+	if maxInFlight > 0 || cooldown > 0 {
+		s.shedder = newLoadShedder(maxInFlight, cooldown)
+	}
+}
+
+// Handler returns the handler of the webhook requests sent to '/hook/<digest>'.
+func (s *ReceiverServer) Handler() http.Handler {
+	return http.HandlerFunc(s.handlePayload())
 }
 
 // ListenAndServe starts the HTTP server on the specified port
 func (s *ReceiverServer) ListenAndServe(stopCh <-chan struct{}, mdlw middleware.Middleware) {
 	mux := http.DefaultServeMux
-	mux.Handle("/hook/", http.HandlerFunc(s.handlePayload()))
+	mux.Handle("/hook/", s.Handler())
 	h := std.Handler("", mdlw, mux)
 	srv := &http.Server{
 		Addr:    s.port,
 		Handler: h,
 	}
 
+	if s.triggers != nil {
+		go s.triggers.run(s.processTrigger)
+	}
+
 	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		if err := s.listenAndServe(srv); err != http.ErrServerClosed {
 			s.logger.Error(err, "Receiver server crashed")
 			os.Exit(1)
 		}
@@ -75,6 +127,11 @@ func (s *ReceiverServer) ListenAndServe(stopCh <-chan struct{}, mdlw middleware.
 	} else {
 		s.logger.Info("Receiver server stopped")
 	}
+
+	if s.triggers != nil {
+		s.triggers.close()
+	}
+	s.coalescer.flushAll()
 }
 
 func receiverKeyFunc(r *http.Request) (string, error) {
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// serviceAccountPrefix is the prefix of the service account usernames,
+// e.g. 'system:serviceaccount:ci:runner'.
+const serviceAccountPrefix = "system:serviceaccount:"
+
+// serviceAccountAudience returns the audience the tokens sent to the receiver must be issued for.
+func serviceAccountAudience(receiver v1beta1.Receiver) string {
+	if sa := receiver.Spec.ServiceAccounts; sa != nil && sa.Audience != "" {
+		return sa.Audience
+	}
+	return v1beta1.DefaultServiceAccountAudience
+}
+
+// serviceAccountNames returns the patterns of the service accounts allowed to call
+// the receiver, the service accounts of the receiver namespace by default.
+func serviceAccountNames(receiver v1beta1.Receiver) []string {
+	if sa := receiver.Spec.ServiceAccounts; sa != nil && len(sa.Names) > 0 {
+		return sa.Names
+	}
+	return []string{receiver.Namespace + "/*"}
+}
+
+// reviewServiceAccountToken authenticates the token with the TokenReview API,
+// it returns the service account of the token in the 'namespace/name' format.
+func reviewServiceAccountToken(ctx context.Context, kubeClient client.Client, token, audience string) (string, error) {
+	review := &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{audience},
+		},
+	}
+	if err := kubeClient.Create(ctx, review); err != nil {
+		return "", fmt.Errorf("unable to review the token, err: %w", err)
+	}
+
+	status := review.Status
+	if !status.Authenticated {
+		return "", receivers.Errorf(receivers.InvalidSignature, "the token is not authenticated: %s", status.Error)
+	}
+	if !containsString(status.Audiences, audience) {
+		return "", receivers.Errorf(receivers.InvalidSignature, "the token is not issued for the audience '%s'", audience)
+	}
+	if !strings.HasPrefix(status.User.Username, serviceAccountPrefix) {
+		return "", receivers.Errorf(receivers.InvalidSignature, "the user '%s' is not a service account", status.User.Username)
+	}
+	name := strings.TrimPrefix(status.User.Username, serviceAccountPrefix)
+	return strings.Replace(name, ":", "/", 1), nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// reviewingClient authenticates the tokens in the 'audience/username' format.
+type reviewingClient struct {
+	client.Client
+}
+
+func (c reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authv1.TokenReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	parts := strings.SplitN(review.Spec.Token, "/", 2)
+	if len(parts) != 2 || !containsString(review.Spec.Audiences, parts[0]) {
+		review.Status.Error = "invalid bearer token"
+		return nil
+	}
+	review.Status.Authenticated = true
+	review.Status.Audiences = []string{parts[0]}
+	review.Status.User.Username = parts[1]
+	return nil
+}
+
+func TestReceiverServer_validateServiceAccount(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "apps"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := reviewingClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()}
+	s := NewReceiverServer(":0", logf.Log, kubeClient, nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "jobs", Namespace: "apps"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.ServiceAccountReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+		},
+	}
+
+	request := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook/jobs", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+
+	ctx := context.Background()
+	g.Expect(s.validate(ctx, receiver, request("notification-controller/system:serviceaccount:apps:deployer"))).To(gomega.Succeed())
+
+	err := s.validate(ctx, receiver, request(""))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.MissingSignature))
+	err = s.validate(ctx, receiver, request("kubernetes/system:serviceaccount:apps:deployer"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+	err = s.validate(ctx, receiver, request("notification-controller/jane"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+	err = s.validate(ctx, receiver, request("notification-controller/system:serviceaccount:ci:runner"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.EventNotAllowed))
+
+	receiver.Spec.ServiceAccounts = &v1beta1.ReceiverServiceAccounts{
+		Audience: "flux-hooks",
+		Names:    []string{"ci/*"},
+	}
+	g.Expect(s.validate(ctx, receiver, request("flux-hooks/system:serviceaccount:ci:runner"))).To(gomega.Succeed())
+	err = s.validate(ctx, receiver, request("notification-controller/system:serviceaccount:ci:runner"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+	err = s.validate(ctx, receiver, request("flux-hooks/system:serviceaccount:apps:deployer"))
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.EventNotAllowed))
+}
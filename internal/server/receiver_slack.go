@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// slackSignatureVersion is the version of the Slack request signatures.
+	slackSignatureVersion = "v0"
+
+	// slackReconcileCommand is the subcommand triggering the resources, e.g. '/flux reconcile webapp'.
+	slackReconcileCommand = "reconcile"
+)
+
+// slackVerifier answers the Slack slash commands with
+// the resources that were triggered.
+type slackVerifier struct {
+	receivers.VerifierFunc
+}
+
+// slackResponse is a slash command response, the ephemeral
+// responses are only visible to the user of the command.
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// verifySlackSignature checks the X-Slack-Signature header, the HMAC-SHA256 of the
+// version, the X-Slack-Request-Timestamp header and the body, signed with the secret.
+func verifySlackSignature(r *http.Request, body []byte, secret []byte, now time.Time) error {
+	signature := r.Header.Get("X-Slack-Signature")
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	if signature == "" || timestamp == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the X-Slack-Signature or X-Slack-Request-Timestamp header is missing")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "invalid Slack request timestamp '%s'", timestamp)
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > httpSignatureMaxSkew || skew < -httpSignatureMaxSkew {
+		return receivers.Errorf(receivers.InvalidSignature, "the Slack request timestamp is %s away from the controller clock", skew.Round(time.Second))
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s:%s:%s", slackSignatureVersion, timestamp, body)
+	expected := slackSignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return receivers.Errorf(receivers.InvalidSignature, "the Slack signature is invalid")
+	}
+	return nil
+}
+
+// verifySlack checks the Slack signature and parses the slash command, the
+// resources named after the subcommand are the only ones triggered.
+func verifySlack(ctx context.Context, r receivers.Request) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Slack payload, err: %w", err)
+	}
+	if err := verifySlackSignature(r.Request, b, []byte(r.Token), time.Now()); err != nil {
+		return err
+	}
+	ts, _ := strconv.ParseInt(r.Header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	r.SetReplayGuard(time.Unix(ts, 0), r.Header.Get("X-Slack-Signature"))
+
+	form, err := url.ParseQuery(string(b))
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode the Slack command: %s", err)
+	}
+	args := strings.Fields(form.Get("text"))
+	if len(args) == 0 || args[0] != slackReconcileCommand {
+		return fmt.Errorf("%w: the Slack command '%s %s' is not supported", errEventFiltered, form.Get("command"), form.Get("text"))
+	}
+	if !receivers.EventAllowed(r.Receiver, slackReconcileCommand) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Slack command '%s' is not authorised", slackReconcileCommand)
+	}
+
+	names := args[1:]
+	for _, name := range names {
+		if !hasResourceNamed(r.Receiver, name) {
+			return fmt.Errorf("%w: the resource '%s' is not triggered by the receiver", errEventFiltered, name)
+		}
+	}
+
+	r.SetEvent(slackReconcileCommand)
+	r.SetResources(names)
+	r.Logger.Info(fmt.Sprintf("handling Slack command '%s %s' of %s in %s",
+		form.Get("command"), form.Get("text"), form.Get("user_name"), form.Get("team_domain")))
+	return nil
+}
+
+func hasResourceNamed(receiver v1beta1.Receiver, name string) bool {
+	for _, resource := range receiver.Spec.Resources {
+		if receivers.ResourceNamed(resource, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Respond acknowledges the command in the channel with the triggered resources.
+func (slackVerifier) Respond(w http.ResponseWriter, triggered []v1beta1.CrossNamespaceObjectReference) {
+	resp := slackResponse{ResponseType: "ephemeral", Text: "No resources were triggered"}
+	if len(triggered) > 0 {
+		lines := make([]string, 0, len(triggered)+1)
+		lines = append(lines, "Triggered the reconciliation of:")
+		for _, resource := range triggered {
+			line := fmt.Sprintf("• `%s/%s`", resource.Kind, resource.Name)
+			if resource.Namespace != "" {
+				line = fmt.Sprintf("• `%s/%s` in `%s`", resource.Kind, resource.Name, resource.Namespace)
+			}
+			lines = append(lines, line)
+		}
+		resp = slackResponse{ResponseType: "in_channel", Text: strings.Join(lines, "\n")}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
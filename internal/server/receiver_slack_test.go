@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+func slackRequest(secret, text string, ts time.Time) *http.Request {
+	body := "command=%2Fflux&text=" + text + "&user_name=jane"
+	r := httptest.NewRequest(http.MethodPost, "/hook/slack", bytes.NewReader([]byte(body)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	timestamp := fmt.Sprintf("%d", ts.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte("command=%2Fflux&text=reconcile&user_name=jane")
+
+	g.Expect(verifySlackSignature(slackRequest("s3cr3t", "reconcile", now), body, []byte("s3cr3t"), now)).To(gomega.Succeed())
+
+	err := verifySlackSignature(slackRequest("forged", "reconcile", now), body, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	err = verifySlackSignature(slackRequest("s3cr3t", "reconcile", now.Add(-10*time.Minute)), body, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.InvalidSignature))
+
+	unsigned := slackRequest("s3cr3t", "reconcile", now)
+	unsigned.Header.Del("X-Slack-Signature")
+	err = verifySlackSignature(unsigned, body, []byte("s3cr3t"), now)
+	g.Expect(receivers.Reason(err)).To(gomega.Equal(receivers.MissingSignature))
+}
+
+func TestReceiverServer_validateSlack(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	s := NewReceiverServer(":0", logf.Log, fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(), nil, 0)
+
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      v1beta1.SlackReceiver,
+			SecretRef: meta.LocalObjectReference{Name: "webhook-token"},
+			Resources: []v1beta1.CrossNamespaceObjectReference{
+				{Kind: "GitRepository", Name: "webapp"},
+				{Kind: "Kustomization", Name: "webapp"},
+				{Kind: "GitRepository", Name: "backend"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	var result receivers.Result
+	g.Expect(s.verify(ctx, receiver, slackRequest("s3cr3t", "reconcile+webapp", time.Now()), &result)).To(gomega.Succeed())
+	g.Expect(result.Event).To(gomega.Equal("reconcile"))
+	g.Expect(result.Resources).To(gomega.Equal([]string{"webapp"}))
+	g.Expect(result.Selects(receiver.Spec.Resources[1])).To(gomega.BeTrue())
+	g.Expect(result.Selects(receiver.Spec.Resources[2])).To(gomega.BeFalse())
+
+	g.Expect(s.validate(ctx, receiver, slackRequest("s3cr3t", "reconcile+Kustomization%2Fwebapp", time.Now()))).To(gomega.Succeed())
+	g.Expect(s.validate(ctx, receiver, slackRequest("s3cr3t", "reconcile+frontend", time.Now()))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, slackRequest("s3cr3t", "suspend+webapp", time.Now()))).To(gomega.MatchError(errEventFiltered))
+	g.Expect(s.validate(ctx, receiver, slackRequest("forged", "reconcile", time.Now()))).NotTo(gomega.Succeed())
+}
+
+func TestSlackVerifier_Respond(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var resp slackResponse
+	w := httptest.NewRecorder()
+	slackVerifier{}.Respond(w, []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "GitRepository", Name: "webapp"},
+		{Kind: "Kustomization", Name: "webapp", Namespace: "apps"},
+	})
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(gomega.Succeed())
+	g.Expect(resp.ResponseType).To(gomega.Equal("in_channel"))
+	g.Expect(resp.Text).To(gomega.Equal("Triggered the reconciliation of:\n• `GitRepository/webapp`\n• `Kustomization/webapp` in `apps`"))
+
+	w = httptest.NewRecorder()
+	slackVerifier{}.Respond(w, nil)
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(gomega.Succeed())
+	g.Expect(resp.ResponseType).To(gomega.Equal("ephemeral"))
+}
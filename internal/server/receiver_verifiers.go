@@ -0,0 +1,675 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/webhook"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+// errEventFiltered is returned when an authentic payload
+// doesn't match the receiver filters.
+var errEventFiltered = receivers.ErrEventFiltered
+
+func init() {
+	receivers.Register(v1beta1.GenericReceiver, cloudEventsVerifier{receivers.VerifierFunc(verifyGeneric)})
+	receivers.Register(v1beta1.GenericHMACReceiver, cloudEventsVerifier{receivers.VerifierFunc(verifyGenericHMAC)})
+	receivers.Register(v1beta1.GitHubReceiver, receivers.VerifierFunc(verifyGitHub))
+	receivers.Register(v1beta1.GitLabReceiver, receivers.VerifierFunc(verifyGitLab))
+	receivers.Register(v1beta1.GitLabSystemReceiver, receivers.VerifierFunc(verifyGitLabSystem))
+	receivers.Register(v1beta1.GiteaReceiver, receivers.VerifierFunc(verifyGitea))
+	receivers.Register(v1beta1.BitbucketReceiver, receivers.VerifierFunc(verifyBitbucketServer))
+	receivers.Register(v1beta1.BitbucketServerReceiver, receivers.VerifierFunc(verifyBitbucketServer))
+	receivers.Register(v1beta1.BitbucketCloudReceiver, receivers.VerifierFunc(verifyBitbucketCloud))
+	receivers.Register(v1beta1.QuayReceiver, receivers.VerifierFunc(verifyQuay))
+	receivers.Register(v1beta1.HarborReceiver, receivers.VerifierFunc(verifyHarbor))
+	receivers.Register(v1beta1.DockerHubReceiver, dockerHubVerifier{receivers.VerifierFunc(verifyDockerHub)})
+	receivers.Register(v1beta1.GCRReceiver, receivers.VerifierFunc(verifyGCR))
+	receivers.Register(v1beta1.GARReceiver, pubsubVerifier{receivers.VerifierFunc(verifyGAR)})
+	receivers.Register(v1beta1.NexusReceiver, receivers.VerifierFunc(verifyNexus))
+	receivers.Register(v1beta1.ACRReceiver, receivers.VerifierFunc(verifyACR))
+	receivers.Register(v1beta1.ECRReceiver, ecrVerifier{receivers.VerifierFunc(verifyECR)})
+	receivers.Register(v1beta1.JenkinsReceiver, receivers.VerifierFunc(verifyJenkins))
+	receivers.Register(v1beta1.DroneReceiver, receivers.VerifierFunc(verifyDrone))
+	receivers.Register(v1beta1.PubSubReceiver, pubsubVerifier{receivers.VerifierFunc(verifyPubSub)})
+	receivers.Register(v1beta1.ServiceAccountReceiver, receivers.VerifierFunc(verifyServiceAccount))
+	receivers.Register(v1beta1.SlackReceiver, slackVerifier{receivers.VerifierFunc(verifySlack)})
+	receivers.Register(v1beta1.DependencyBotReceiver, receivers.VerifierFunc(verifyDependencyBot))
+}
+
+// verifyGeneric accepts all requests, unless the receiver verifies the OIDC tokens
+// of the callers. The CloudEvents are checked against the receiver events and filter.
+func verifyGeneric(ctx context.Context, r receivers.Request) error {
+	if oidc := r.Receiver.Spec.OIDC; oidc != nil {
+		claims, err := verifyOIDCToken(r.Request, oidc, time.Now())
+		if err != nil {
+			return receivers.Errorf(signatureFailure(err), "the OIDC token is invalid, err: %w", err)
+		}
+		r.Logger.Info(fmt.Sprintf("handling request of '%s' authenticated by '%s'", claims.Subject, claims.Issuer))
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read request body: %s", err)
+	}
+	return filterGenericEvent(r, b)
+}
+
+// verifyGenericHMAC checks the HMAC signature of the payload in the X-Signature header.
+func verifyGenericHMAC(ctx context.Context, r receivers.Request) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read request body: %s", err)
+	}
+
+	if r.Header.Get("X-Signature") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the X-Signature header is missing")
+	}
+	err = github.ValidateSignature(r.Header.Get("X-Signature"), b, []byte(r.Token))
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "unable to validate HMAC signature: %s", err)
+	}
+	r.SetReplayGuard(time.Time{}, r.Header.Get("X-Signature"))
+	return filterGenericEvent(r, b)
+}
+
+// verifyGitHub checks the GitHub signature and applies the receiver filter to the event.
+func verifyGitHub(ctx context.Context, r receivers.Request) error {
+	if r.Header.Get("X-Hub-Signature") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the GitHub signature header is missing")
+	}
+	payload, err := github.ValidatePayload(r.Request, []byte(r.Token))
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "the GitHub signature header is invalid, err: %w", err)
+	}
+	r.SetReplayGuard(time.Time{}, r.Header.Get("X-Hub-Signature"))
+
+	parsed, err := github.ParseWebHook(github.WebHookType(r.Request), payload)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "unable to parse GitHub payload, err: %w", err)
+	}
+
+	event := github.WebHookType(r.Request)
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the GitHub event '%s' is not authorised", event)
+	}
+
+	if err := filterGitHubEvent(r.Receiver.Spec.Filter, parsed); err != nil {
+		return err
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling GitHub event: %s", event))
+	return nil
+}
+
+// verifyGitLab checks the GitLab token and applies the receiver filter to the event.
+func verifyGitLab(ctx context.Context, r receivers.Request) error {
+	if err := verifyGitLabToken(r); err != nil {
+		return err
+	}
+
+	event := r.Header.Get("X-Gitlab-Event")
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the GitLab event '%s' is not authorised", event)
+	}
+
+	if r.Receiver.Spec.Filter != nil {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("unable to read GitLab payload, err: %w", err)
+		}
+		b, err = webhook.PayloadJSON(r.Header.Get("Content-Type"), b)
+		if err != nil {
+			return receivers.Errorf(receivers.InvalidPayload, "unable to read GitLab payload, err: %w", err)
+		}
+		if err := filterGitLabEvent(r.Receiver.Spec.Filter, b); err != nil {
+			return err
+		}
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling GitLab event: %s", event))
+	return nil
+}
+
+// verifyGitLabSystem checks the GitLab token of a system hook, the events of
+// all the projects of the instance are matched by their event name.
+func verifyGitLabSystem(ctx context.Context, r receivers.Request) error {
+	if err := verifyGitLabToken(r); err != nil {
+		return err
+	}
+	if hook := r.Header.Get("X-Gitlab-Event"); hook != "System Hook" {
+		return receivers.Errorf(receivers.InvalidPayload, "the GitLab event '%s' is not a system hook", hook)
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read GitLab payload, err: %w", err)
+	}
+	var p gitlabPayload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "unable to decode GitLab payload, err: %w", err)
+	}
+
+	// the merge request system hooks only carry the object kind
+	event := p.EventName
+	if event == "" {
+		event = p.ObjectKind
+	}
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the GitLab system event '%s' is not authorised", event)
+	}
+	if err := filterGitLabEvent(r.Receiver.Spec.Filter, b); err != nil {
+		return err
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling GitLab system event: %s", event))
+	return nil
+}
+
+// verifyGitLabToken checks the secret token of a GitLab webhook.
+func verifyGitLabToken(r receivers.Request) error {
+	if r.Header.Get("X-Gitlab-Token") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the X-Gitlab-Token header is missing")
+	}
+	if r.Header.Get("X-Gitlab-Token") != r.Token {
+		return receivers.Errorf(receivers.InvalidSignature, "the X-Gitlab-Token header value does not match the receiver token")
+	}
+	return nil
+}
+
+// verifyGitea checks the Gitea and Forgejo HMAC SHA256 signature.
+func verifyGitea(ctx context.Context, r receivers.Request) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Gitea payload, err: %w", err)
+	}
+
+	// Forgejo sends its own headers along with the Gitea ones
+	signature := r.Header.Get("X-Gitea-Signature")
+	if signature == "" {
+		signature = r.Header.Get("X-Forgejo-Signature")
+	}
+	if signature == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the Gitea signature header is missing")
+	}
+	if !verifyHmacSHA256Signature([]byte(r.Token), signature, b) {
+		return receivers.Errorf(receivers.InvalidSignature, "the Gitea signature header is invalid")
+	}
+	r.SetReplayGuard(time.Time{}, signature)
+
+	event := r.Header.Get("X-Gitea-Event")
+	if event == "" {
+		event = r.Header.Get("X-Forgejo-Event")
+	}
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Gitea event '%s' is not authorised", event)
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling Gitea event: %s", event))
+	return nil
+}
+
+// verifyBitbucketServer checks the Bitbucket Server and Data Center HMAC signature.
+func verifyBitbucketServer(ctx context.Context, r receivers.Request) error {
+	// Bitbucket Data Center sends a charset with the JSON content type,
+	// the signature is verified against the raw body
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Bitbucket server payload, err: %w", err)
+	}
+	if r.Header.Get("X-Hub-Signature") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the Bitbucket server signature header is missing")
+	}
+	if err := github.ValidateSignature(r.Header.Get("X-Hub-Signature"), b, []byte(r.Token)); err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "the Bitbucket server signature header is invalid, err: %w", err)
+	}
+	r.SetReplayGuard(time.Time{}, r.Header.Get("X-Hub-Signature"))
+
+	event := r.Header.Get("X-Event-Key")
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Bitbucket server event '%s' is not authorised", event)
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling Bitbucket server event: %s", event))
+	return nil
+}
+
+// verifyBitbucketCloud checks the Atlassian Connect JWT sent by Bitbucket Cloud.
+func verifyBitbucketCloud(ctx context.Context, r receivers.Request) error {
+	claims, err := verifyConnectJWT(r.Request, []byte(r.Token), time.Now())
+	if err != nil {
+		return receivers.Errorf(signatureFailure(err), "the Bitbucket Cloud JWT is invalid, err: %w", err)
+	}
+
+	event := r.Header.Get("X-Event-Key")
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Bitbucket Cloud event '%s' is not authorised", event)
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling Bitbucket Cloud event: %s from %s", event, claims.Issuer))
+	return nil
+}
+
+// verifyGCR checks the Pub/Sub bearer token with the Google token info API.
+func verifyGCR(ctx context.Context, r receivers.Request) error {
+	const (
+		insert     = "insert"
+		tokenIndex = len("Bearer ")
+	)
+
+	type data struct {
+		Action string `json:"action"`
+		Digest string `json:"digest"`
+		Tag    string `json:"tag"`
+	}
+
+	type payload struct {
+		Message struct {
+			Data         string    `json:"data"`
+			MessageID    string    `json:"messageId"`
+			PublishTime  time.Time `json:"publishTime"`
+			Subscription string    `json:"subscription"`
+		} `json:"message"`
+	}
+
+	if r.Header.Get("Authorization") == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the GCR Authorization header is missing")
+	}
+	err := authenticateGCRRequest(&http.Client{}, r.Header.Get("Authorization"), tokenIndex)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "cannot authenticate GCR request: %s", err)
+	}
+
+	var p payload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode GCR webhook payload")
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(p.Message.Data)
+
+	var d data
+	err = json.Unmarshal(raw, &d)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode GCR webhook body")
+	}
+
+	r.SetEvent(d.Action)
+	r.SetImage(imageTag(d.Tag), imageDigest(d.Digest))
+	r.Logger.Info(fmt.Sprintf("handling GCR event from %s for tag %s", d.Digest, d.Tag))
+	return nil
+}
+
+// verifyGAR checks the Pub/Sub JWT and applies the receiver filter to the Artifact Registry notification.
+func verifyGAR(ctx context.Context, r receivers.Request) error {
+	claims, err := verifyPubSubJWT(r.Request, time.Now())
+	if err != nil {
+		return receivers.Errorf(signatureFailure(err), "the Pub/Sub JWT is invalid, err: %w", err)
+	}
+	if err := verifyPushServiceAccount(claims, r.Receiver.Spec.PushServiceAccounts); err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "the Pub/Sub JWT is invalid, err: %w", err)
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Pub/Sub payload, err: %w", err)
+	}
+	n, err := parseGARNotification(b)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "%w", err)
+	}
+
+	event := n.Action
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Artifact Registry event '%s' is not authorised", event)
+	}
+	if r.Receiver.Spec.Filter != nil && !matchAny(r.Receiver.Spec.Filter.Repositories, n.image()) {
+		return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, n.image())
+	}
+
+	r.SetEvent(event)
+	r.SetImage(imageTag(n.Tag), imageDigest(n.Digest))
+	r.Logger.Info(fmt.Sprintf("handling Artifact Registry event from %s for %s, sent by %s", n.image(), n.Tag, claims.Email))
+	return nil
+}
+
+// verifyPubSub checks the Pub/Sub JWT and evaluates the receiver filter
+// condition with the message attributes and its decoded data.
+func verifyPubSub(ctx context.Context, r receivers.Request) error {
+	claims, err := verifyPubSubJWT(r.Request, time.Now())
+	if err != nil {
+		return receivers.Errorf(signatureFailure(err), "the Pub/Sub JWT is invalid, err: %w", err)
+	}
+	if err := verifyPushServiceAccount(claims, r.Receiver.Spec.PushServiceAccounts); err != nil {
+		return receivers.Errorf(receivers.InvalidSignature, "the Pub/Sub JWT is invalid, err: %w", err)
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Pub/Sub payload, err: %w", err)
+	}
+	m, err := parsePubSubMessage(b)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "%w", err)
+	}
+
+	if filter := r.Receiver.Spec.Filter; filter != nil && filter.Condition != "" {
+		// the evaluation errors are filtered out so that Pub/Sub doesn't retry the delivery
+		ok, err := m.match(r, filter.Condition)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errEventFiltered, err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: message %s does not match the condition", errEventFiltered, m.MessageID)
+		}
+	}
+
+	r.Logger.Info(fmt.Sprintf("handling Pub/Sub message %s of %s, sent by %s", m.MessageID, m.Subscription, claims.Email))
+	return nil
+}
+
+// verifyServiceAccount reviews the projected service account token of the
+// in-cluster caller and checks the service account against the allowed ones.
+func verifyServiceAccount(ctx context.Context, r receivers.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return receivers.Errorf(receivers.MissingSignature, "the Authorization header is missing or malformed")
+	}
+	if r.KubeClient == nil {
+		return fmt.Errorf("the service account tokens can't be reviewed without a Kubernetes client")
+	}
+
+	name, err := reviewServiceAccountToken(ctx, r.KubeClient, strings.TrimPrefix(auth, "Bearer "), serviceAccountAudience(r.Receiver))
+	if err != nil {
+		return err
+	}
+	if !matchAny(serviceAccountNames(r.Receiver), name) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the service account '%s' is not authorised", name)
+	}
+
+	r.Logger.Info(fmt.Sprintf("handling request of service account %s", name))
+	return nil
+}
+
+// verifyNexus checks the Nexus HMAC signature and the webhook ID,
+// e.g. 'rm:repository:component', against the receiver events.
+func verifyNexus(ctx context.Context, r receivers.Request) error {
+	signature := r.Header.Get("X-Nexus-Webhook-Signature")
+	if len(signature) == 0 {
+		return receivers.Errorf(receivers.MissingSignature, "Nexus signature is missing from header")
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read Nexus payload. error: %s", err)
+	}
+
+	if !verifyHmacSignature([]byte(r.Token), signature, b) {
+		return receivers.Errorf(receivers.InvalidSignature, "invalid Nexus signature")
+	}
+	r.SetReplayGuard(time.Time{}, signature)
+
+	event := r.Header.Get("X-Nexus-Webhook-Id")
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Nexus webhook '%s' is not authorised", event)
+	}
+
+	type component struct {
+		Format  string `json:"format"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	type payload struct {
+		Action         string     `json:"action"`
+		RepositoryName string     `json:"repositoryName"`
+		Component      *component `json:"component"`
+	}
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode Nexus webhook payload: %s", err)
+	}
+
+	r.SetEvent(event)
+	if p.Component == nil {
+		r.Logger.Info(fmt.Sprintf("handling Nexus %s event from %s", p.Action, p.RepositoryName))
+		return nil
+	}
+	if p.Component.Format == "docker" {
+		r.SetImage(p.Component.Version, "")
+	}
+	r.Logger.Info(fmt.Sprintf("handling Nexus %s event from %s for %s:%s",
+		p.Action, p.RepositoryName, p.Component.Name, p.Component.Version))
+	return nil
+}
+
+// verifyACR decodes the ACR payload, Azure doesn't sign its requests.
+func verifyACR(ctx context.Context, r receivers.Request) error {
+	type target struct {
+		Repository string `json:"repository"`
+		Tag        string `json:"tag"`
+		Digest     string `json:"digest"`
+	}
+
+	type payload struct {
+		Action string `json:"action"`
+		Target target `json:"target"`
+	}
+
+	var p payload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode ACR webhook payload: %s", err)
+	}
+
+	r.SetEvent(p.Action)
+	r.SetImage(p.Target.Tag, p.Target.Digest)
+	r.Logger.Info(fmt.Sprintf("handling ACR event from %s for tag %s", p.Target.Repository, p.Target.Tag))
+	return nil
+}
+
+// verifyECR checks the SNS signature and topic, confirms the SNS subscriptions and applies the receiver filter to the ECR event.
+func verifyECR(ctx context.Context, r receivers.Request) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read SNS payload, err: %w", err)
+	}
+	m, err := parseSNSMessage(b)
+	if err != nil {
+		return fmt.Errorf("the SNS message is invalid, err: %w", err)
+	}
+	// any AWS account can subscribe the receiver to its topics
+	if !containsString(r.Receiver.Spec.Topics, m.TopicArn) {
+		return receivers.Errorf(receivers.InvalidSignature, "the SNS topic '%s' is not in the receiver topics", m.TopicArn)
+	}
+
+	switch m.Type {
+	case snsNotification:
+	case snsSubscriptionConfirmation:
+		if err := m.confirm(snsClient); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: the SNS subscription to '%s' is confirmed", errEventFiltered, m.TopicArn)
+	default:
+		return fmt.Errorf("%w: SNS message type '%s'", errEventFiltered, m.Type)
+	}
+
+	e, err := parseECREvent(m)
+	if err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "%w", err)
+	}
+	event := e.Detail.ActionType
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the ECR event '%s' is not authorised", event)
+	}
+	if e.Detail.Result != "SUCCESS" {
+		return fmt.Errorf("%w: the ECR %s action result is '%s'", errEventFiltered, event, e.Detail.Result)
+	}
+	if r.Receiver.Spec.Filter != nil && !matchAny(r.Receiver.Spec.Filter.Repositories, e.Detail.RepositoryName) {
+		return fmt.Errorf("%w: repository '%s' does not match", errEventFiltered, e.Detail.RepositoryName)
+	}
+
+	r.SetEvent(event)
+	r.SetImage(e.Detail.ImageTag, e.Detail.ImageDigest)
+	r.Logger.Info(fmt.Sprintf("handling ECR event from %s for tag %s", e.Detail.RepositoryName, e.Detail.ImageTag))
+	return nil
+}
+
+// verifyJenkins checks the Jenkins token and filters the builds on their
+// job and result, the payload is sent by the Jenkins notification plugin.
+func verifyJenkins(ctx context.Context, r receivers.Request) error {
+	token := r.Header.Get("X-Jenkins-Token")
+	if token == "" {
+		return receivers.Errorf(receivers.MissingSignature, "the X-Jenkins-Token header is missing")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		return receivers.Errorf(receivers.InvalidSignature, "the X-Jenkins-Token header value does not match the receiver token")
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Jenkins payload, err: %w", err)
+	}
+	type build struct {
+		Number int    `json:"number"`
+		Phase  string `json:"phase"`
+		Status string `json:"status"`
+	}
+	type payload struct {
+		Name  string `json:"name"`
+		Build build  `json:"build"`
+	}
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode Jenkins webhook payload: %s", err)
+	}
+
+	event := p.Build.Phase
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Jenkins build phase '%s' is not authorised", event)
+	}
+
+	results := []string{"SUCCESS"}
+	if filter := r.Receiver.Spec.Filter; filter != nil {
+		if !matchAny(filter.Jobs, p.Name) {
+			return fmt.Errorf("%w: job '%s' does not match", errEventFiltered, p.Name)
+		}
+		if len(filter.Results) > 0 {
+			results = filter.Results
+		}
+	}
+	if !containsFold(results, p.Build.Status) {
+		return fmt.Errorf("%w: the build result of job '%s' is '%s'", errEventFiltered, p.Name, p.Build.Status)
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling Jenkins build %d of %s: %s", p.Build.Number, p.Name, p.Build.Status))
+	return nil
+}
+
+// containsFold returns true if the value is in the list, case insensitively.
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyDrone checks the HMAC SHA256 HTTP signature of Drone, with the payload digest and date it signs.
+func verifyDrone(ctx context.Context, r receivers.Request) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Drone payload, err: %w", err)
+	}
+	if err := verifyHTTPSignature(r.Request, b, []byte(r.Token), time.Now()); err != nil {
+		return err
+	}
+	r.SetReplayGuard(httpSignatureDate(r.Request), r.Header.Get("Signature"))
+
+	event := r.Header.Get("X-Drone-Event")
+	if !receivers.EventAllowed(r.Receiver, event) {
+		return receivers.Errorf(receivers.EventNotAllowed, "the Drone event '%s' is not authorised", event)
+	}
+
+	type repo struct {
+		Slug string `json:"slug"`
+	}
+	type build struct {
+		Number int    `json:"number"`
+		Status string `json:"status"`
+	}
+	type payload struct {
+		Action string `json:"action"`
+		Repo   repo   `json:"repo"`
+		Build  build  `json:"build"`
+	}
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return receivers.Errorf(receivers.InvalidPayload, "cannot decode Drone webhook payload: %s", err)
+	}
+
+	r.SetEvent(event)
+	r.Logger.Info(fmt.Sprintf("handling Drone %s %s of %s, build %d: %s", event, p.Action, p.Repo.Slug, p.Build.Number, p.Build.Status))
+	return nil
+}
+
+// signatureFailure returns the reason of a failed signature verification,
+// the verifications report the missing signatures.
+func signatureFailure(err error) receivers.FailureReason {
+	if receivers.Reason(err) == receivers.MissingSignature {
+		return receivers.MissingSignature
+	}
+	return receivers.InvalidSignature
+}
+
+// imageTag returns the tag of an image reference, e.g. '1.0.0' for 'ghcr.io/org/app:1.0.0'.
+func imageTag(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[i+1:]
+	}
+	return ""
+}
+
+// imageDigest returns the digest of an image reference,
+// e.g. 'sha256:6ec1' for 'ghcr.io/org/app@sha256:6ec1'.
+func imageDigest(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/i18n"
+)
+
+// eventSampler sends the first of every given number of info events
+// per alert and counts the skipped ones.
+type eventSampler struct {
+	mu     sync.Mutex
+	counts map[types.NamespacedName]int
+}
+
+func newEventSampler() *eventSampler {
+	return &eventSampler{counts: make(map[types.NamespacedName]int)}
+}
+
+// sample returns whether the event is sent and, if so, the number of
+// events skipped since the previous one. The error events are always sent.
+func (s *eventSampler) sample(alert v1beta1.Alert, event events.Event) (bool, int) {
+	name := types.NamespacedName{Namespace: alert.Namespace, Name: alert.Name}
+	if alert.Spec.Sampling == nil || alert.Spec.Sampling.Every <= 1 {
+		s.remove(name)
+		return true, 0
+	}
+	if event.Severity == events.EventSeverityError {
+		return true, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := s.counts[name]
+	if count == 0 || count >= alert.Spec.Sampling.Every {
+		s.counts[name] = 1
+		if count > 0 {
+			return true, count - 1
+		}
+		return true, 0
+	}
+	s.counts[name] = count + 1
+	return false, 0
+}
+
+func (s *eventSampler) remove(name types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counts, name)
+}
+
+// samplingMetadata returns the metadata describing the sample an info notification
+// belongs to, in the locale of the provider.
+func samplingMetadata(alert v1beta1.Alert, skipped int, locale string) map[string]string {
+	if alert.Spec.Sampling == nil || alert.Spec.Sampling.Every <= 1 {
+		return nil
+	}
+	metadata := map[string]string{
+		"sampling": i18n.Sprintf(locale, i18n.Sampling, alert.Spec.Sampling.Every),
+	}
+	if skipped > 0 {
+		metadata["skipped"] = strconv.Itoa(skipped)
+	}
+	return metadata
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestEventSampler_sample(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	alert := v1beta1.Alert{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec: v1beta1.AlertSpec{
+			Sampling: &v1beta1.EventSampling{Every: 3},
+		},
+	}
+	info := events.Event{Severity: events.EventSeverityInfo}
+	failure := events.Event{Severity: events.EventSeverityError}
+
+	sampler := newEventSampler()
+	var sent []bool
+	var skipped []int
+	for i := 0; i < 7; i++ {
+		send, n := sampler.sample(alert, info)
+		sent = append(sent, send)
+		skipped = append(skipped, n)
+
+		// the errors are always sent and don't change the sample
+		send, n = sampler.sample(alert, failure)
+		g.Expect(send).To(gomega.BeTrue())
+		g.Expect(n).To(gomega.Equal(0))
+	}
+	g.Expect(sent).To(gomega.Equal([]bool{true, false, false, true, false, false, true}))
+	g.Expect(skipped).To(gomega.Equal([]int{0, 0, 0, 2, 0, 0, 2}))
+
+	g.Expect(samplingMetadata(alert, 2, "")).To(gomega.Equal(map[string]string{
+		"sampling": "1 of 3 info events",
+		"skipped":  "2",
+	}))
+	g.Expect(samplingMetadata(alert, 2, "fr")).To(gomega.HaveKeyWithValue("sampling", "1 événement info sur 3"))
+	g.Expect(samplingMetadata(alert, 0, "")).NotTo(gomega.HaveKey("skipped"))
+
+	// disabling the sampling resets the count
+	alert.Spec.Sampling = nil
+	send, _ := sampler.sample(alert, info)
+	g.Expect(send).To(gomega.BeTrue())
+	g.Expect(samplingMetadata(alert, 0, "")).To(gomega.BeNil())
+	alert.Spec.Sampling = &v1beta1.EventSampling{Every: 3}
+	send, _ = sampler.sample(alert, info)
+	g.Expect(send).To(gomega.BeTrue())
+}
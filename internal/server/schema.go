@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fluxcd/notification-controller/internal/notifier"
+)
+
+// SchemaPath is the path of the JSON Schemas of the event API and of the
+// payloads sent by the providers, served by the event server.
+const SchemaPath = "/schemas/"
+
+// schemaIndexEntry is a schema listed by the index, the digest changes
+// with the schema so that the consumers can detect the changes.
+type schemaIndexEntry struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// schemaIndex lists the schemas of the current version.
+type schemaIndex struct {
+	Version   string                      `json:"version"`
+	Event     schemaIndexEntry            `json:"event"`
+	Providers map[string]schemaIndexEntry `json:"providers"`
+}
+
+// SchemaHandler serves the index of the schemas on SchemaPath, the event
+// schema on '/schemas/<version>/event.json' and the payload schemas on
+// '/schemas/<version>/providers/<type>.json'.
+func SchemaHandler() http.Handler {
+	documents := map[string][]byte{}
+	add := func(path string, schema map[string]interface{}) schemaIndexEntry {
+		schema["$id"] = path
+		data, _ := json.MarshalIndent(schema, "", "  ")
+		documents[path] = data
+		return schemaIndexEntry{Path: path, Digest: fmt.Sprintf("sha256:%x", sha256.Sum256(data))}
+	}
+
+	prefix := SchemaPath + notifier.SchemaVersion + "/"
+	index := schemaIndex{
+		Version:   notifier.SchemaVersion,
+		Event:     add(prefix+"event.json", notifier.EventSchema()),
+		Providers: map[string]schemaIndexEntry{},
+	}
+	for _, name := range notifier.PayloadSchemaNames() {
+		schema, _ := notifier.PayloadSchema(name)
+		index.Providers[name] = add(prefix+"providers/"+name+".json", schema)
+	}
+	indexData, _ := json.MarshalIndent(index, "", "  ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		contentType := "application/schema+json"
+		data, ok := documents[r.URL.Path]
+		if strings.TrimSuffix(r.URL.Path, "/")+"/" == SchemaPath {
+			contentType, data, ok = "application/json", indexData, true
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(data))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestSchemaHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := SchemaHandler()
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(SchemaPath, "")
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	var index schemaIndex
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &index)).To(gomega.Succeed())
+	g.Expect(index.Version).To(gomega.Equal("v1"))
+	g.Expect(index.Event.Path).To(gomega.Equal("/schemas/v1/event.json"))
+	g.Expect(index.Providers).To(gomega.HaveKey("generic-cloudevents"))
+	g.Expect(index.Providers).NotTo(gomega.HaveKey("slack"))
+
+	rec = get(index.Providers["postgres"].Path, "")
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(rec.Header().Get("Content-Type")).To(gomega.Equal("application/schema+json"))
+	var schema map[string]interface{}
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &schema)).To(gomega.Succeed())
+	g.Expect(schema).To(gomega.HaveKeyWithValue("$id", "/schemas/v1/providers/postgres.json"))
+	g.Expect(schema["properties"]).To(gomega.HaveKey("id"))
+
+	rec = get(index.Event.Path, rec.Header().Get("ETag"))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	rec = get(index.Event.Path, rec.Header().Get("ETag"))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusNotModified))
+
+	g.Expect(get("/schemas/v0/event.json", "").Code).To(gomega.Equal(http.StatusNotFound))
+	g.Expect(get("/schemas/v1/providers/slack.json", "").Code).To(gomega.Equal(http.StatusNotFound))
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/i18n"
+)
+
+const (
+	// statusBoardResolution is the interval at which the boards are checked for updates.
+	statusBoardResolution = 10 * time.Second
+
+	defaultRecentDeployments = 10
+)
+
+type statusBoard struct {
+	failing     map[string]events.Event
+	deployments []events.Event
+	changed     bool
+	published   time.Time
+
+	// ts is the timestamp of the Slack message.
+	ts string
+}
+
+// statusBoards holds the summary of the events received by the providers
+// with a status board, the summary messages are updated by the StatusBoards
+// runnable.
+type statusBoards struct {
+	mu     sync.Mutex
+	boards map[types.NamespacedName]*statusBoard
+}
+
+func newStatusBoards() *statusBoards {
+	return &statusBoards{boards: make(map[types.NamespacedName]*statusBoard)}
+}
+
+// record updates the board of the provider, the error events mark their object
+// as failing, the other events clear it and, if they have a revision, are
+// listed as recent deployments.
+func (b *statusBoards) record(provider v1beta1.Provider, event events.Event) {
+	name := types.NamespacedName{Namespace: provider.Namespace, Name: provider.Name}
+	obj := event.InvolvedObject
+	key := fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	board, ok := b.boards[name]
+	if !ok {
+		board = &statusBoard{failing: make(map[string]events.Event)}
+		b.boards[name] = board
+	}
+	board.changed = true
+
+	if event.Severity == events.EventSeverityError {
+		board.failing[key] = event
+		return
+	}
+	delete(board.failing, key)
+
+	revision, ok := event.Metadata["revision"]
+	if !ok {
+		return
+	}
+	for _, d := range board.deployments {
+		if d.InvolvedObject == obj && d.Metadata["revision"] == revision {
+			return
+		}
+	}
+	max := provider.Spec.StatusBoard.RecentDeployments
+	if max <= 0 {
+		max = defaultRecentDeployments
+	}
+	board.deployments = append([]events.Event{event}, board.deployments...)
+	if len(board.deployments) > max {
+		board.deployments = board.deployments[:max]
+	}
+}
+
+// due returns the summary of the board in the locale if it changed since
+// it was published at least an interval ago, and marks it as published.
+func (b *statusBoards) due(name types.NamespacedName, interval time.Duration, locale string, now time.Time) (text, ts string, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	board, found := b.boards[name]
+	if !found || !board.changed || now.Sub(board.published) < interval {
+		return "", "", false
+	}
+	board.changed = false
+	board.published = now
+	return board.render(locale, now), board.ts, true
+}
+
+// published records the timestamp of the message, or marks the
+// board as changed so that it's published again when it failed.
+func (b *statusBoards) published(name types.NamespacedName, ts string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if board, ok := b.boards[name]; ok {
+		if err != nil {
+			board.changed = true
+			return
+		}
+		board.ts = ts
+	}
+}
+
+func (b *statusBoards) names() []types.NamespacedName {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := make([]types.NamespacedName, 0, len(b.boards))
+	for name := range b.boards {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (b *statusBoards) remove(name types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.boards, name)
+}
+
+// render returns the Slack mrkdwn summary of the board in the locale.
+func (board *statusBoard) render(locale string, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(i18n.Sprintf(locale, i18n.StatusBoardTitle, now.UTC().Format("2006-01-02 15:04 MST")))
+	sb.WriteString("\n")
+
+	keys := make([]string, 0, len(board.failing))
+	for key := range board.failing {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		fmt.Fprintf(&sb, "\n:white_check_mark: %s\n", i18n.Sprintf(locale, i18n.StatusBoardHealthy))
+	} else {
+		fmt.Fprintf(&sb, "\n:x: *%s*\n", i18n.Sprintf(locale, i18n.StatusBoardFailing, len(keys)))
+		for _, key := range keys {
+			e := board.failing[key]
+			fmt.Fprintf(&sb, "• %s: %s\n", objectName(e), firstLine(e.Message))
+		}
+	}
+
+	if len(board.deployments) > 0 {
+		fmt.Fprintf(&sb, "\n:rocket: *%s*\n", i18n.Sprintf(locale, i18n.StatusBoardDeployments))
+		for _, e := range board.deployments {
+			fmt.Fprintf(&sb, "• %s\n", i18n.Sprintf(locale, i18n.StatusBoardDeployment, objectName(e), e.Metadata["revision"],
+				e.Timestamp.UTC().Format("2006-01-02 15:04 MST")))
+		}
+	}
+	return sb.String()
+}
+
+func objectName(e events.Event) string {
+	return fmt.Sprintf("%s/%s.%s", strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name, e.InvolvedObject.Namespace)
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// StatusBoards publishes the summary messages of the providers with a status board.
+type StatusBoards struct {
+	logger     logr.Logger
+	kubeClient client.Client
+	providers  *Providers
+	boards     *statusBoards
+}
+
+// NewStatusBoards returns the status boards publisher, it must be added
+// to the manager so that it runs alongside the event server.
+func NewStatusBoards(logger logr.Logger, kubeClient client.Client, providers *Providers) *StatusBoards {
+	return &StatusBoards{
+		logger:     logger.WithName("status-board"),
+		kubeClient: kubeClient,
+		providers:  providers,
+		boards:     newStatusBoards(),
+	}
+}
+
+// WithStatusBoards sets the status boards summarising the events
+// of the providers with a status board.
+func (s *EventServer) WithStatusBoards(boards *StatusBoards) {
+	s.statusBoards = boards
+}
+
+// record updates the board of the provider, the events are
+// discarded when the status boards are not running.
+func (s *StatusBoards) record(provider v1beta1.Provider, event events.Event) {
+	if s == nil {
+		return
+	}
+	s.boards.record(provider, event)
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// the boards hold the events received by this replica.
+func (s *StatusBoards) NeedLeaderElection() bool {
+	return false
+}
+
+// Start publishes the boards that changed until the context is cancelled.
+func (s *StatusBoards) Start(ctx context.Context) error {
+	ticker := time.NewTicker(statusBoardResolution)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.publish(ctx, time.Now())
+		}
+	}
+}
+
+func (s *StatusBoards) publish(ctx context.Context, now time.Time) {
+	for _, name := range s.boards.names() {
+		ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		s.publishBoard(ctx, name, now)
+		cancel()
+	}
+}
+
+func (s *StatusBoards) publishBoard(ctx context.Context, name types.NamespacedName, now time.Time) {
+	var provider v1beta1.Provider
+	if err := s.kubeClient.Get(ctx, name, &provider); err != nil {
+		if apierrors.IsNotFound(err) {
+			s.boards.remove(name)
+			return
+		}
+		s.logger.Error(err, "failed to read provider",
+			"reconciler kind", v1beta1.ProviderKind,
+			"name", name.Name,
+			"namespace", name.Namespace)
+		return
+	}
+	if provider.Spec.StatusBoard == nil {
+		s.boards.remove(name)
+		return
+	}
+
+	text, ts, ok := s.boards.due(name, provider.Spec.StatusBoard.Interval.Duration, provider.Spec.Locale, now)
+	if !ok {
+		return
+	}
+
+	ts, err := s.send(ctx, provider, ts, text)
+	s.boards.published(name, ts, err)
+	if err != nil {
+		s.logger.Error(err, "failed to publish status board",
+			"reconciler kind", v1beta1.ProviderKind,
+			"name", name.Name,
+			"namespace", name.Namespace)
+	}
+}
+
+func (s *StatusBoards) send(ctx context.Context, provider v1beta1.Provider, ts, text string) (string, error) {
+	factory, err := s.providers.factory(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+	board, err := factory.SlackBoard()
+	if err != nil {
+		return "", err
+	}
+	return board.Publish(ts, text)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestStatusBoards(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	provider := v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "flux-system"},
+		Spec: v1beta1.ProviderSpec{
+			Type:        v1beta1.SlackProvider,
+			StatusBoard: &v1beta1.StatusBoard{Interval: metav1.Duration{Duration: time.Minute}, RecentDeployments: 2},
+		},
+	}
+	name := types.NamespacedName{Namespace: "flux-system", Name: "slack"}
+	now := time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC)
+	event := func(obj, severity, message, revision string) events.Event {
+		e := events.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: obj, Namespace: "flux-system"},
+			Severity:       severity,
+			Message:        message,
+			Timestamp:      metav1.NewTime(now),
+		}
+		if revision != "" {
+			e.Metadata = map[string]string{"revision": revision}
+		}
+		return e
+	}
+
+	boards := newStatusBoards()
+	_, _, ok := boards.due(name, time.Minute, "", now)
+	g.Expect(ok).To(gomega.BeFalse())
+
+	boards.record(provider, event("apps", events.EventSeverityError, "health check failed\ntimeout", ""))
+	boards.record(provider, event("infra", events.EventSeverityInfo, "applied", "main/1"))
+	boards.record(provider, event("infra", events.EventSeverityInfo, "applied", "main/1"))
+	boards.record(provider, event("infra", events.EventSeverityInfo, "applied", "main/2"))
+	boards.record(provider, event("infra", events.EventSeverityInfo, "applied", "main/3"))
+
+	text, ts, ok := boards.due(name, time.Minute, "", now)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(ts).To(gomega.BeEmpty())
+	g.Expect(text).To(gomega.Equal("*Flux status* (updated 2021-05-04 10:00 UTC)\n" +
+		"\n:x: *Failing objects (1)*\n" +
+		"• kustomization/apps.flux-system: health check failed\n" +
+		"\n:rocket: *Recent deployments*\n" +
+		"• kustomization/infra.flux-system main/3 at 2021-05-04 10:00 UTC\n" +
+		"• kustomization/infra.flux-system main/2 at 2021-05-04 10:00 UTC\n"))
+	boards.published(name, "1.0002", nil)
+
+	// the board is published at most once per interval
+	boards.record(provider, event("apps", events.EventSeverityInfo, "applied", ""))
+	_, _, ok = boards.due(name, time.Minute, "", now.Add(30*time.Second))
+	g.Expect(ok).To(gomega.BeFalse())
+	text, ts, ok = boards.due(name, time.Minute, "de", now.Add(time.Minute))
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(ts).To(gomega.Equal("1.0002"))
+	g.Expect(text).To(gomega.HavePrefix("*Flux-Status* (aktualisiert 2021-05-04 10:01 UTC)\n"))
+	g.Expect(text).To(gomega.ContainSubstring("Keine fehlerhaften Objekte"))
+
+	// the failed publications are retried
+	boards.published(name, "", errors.New("rate limited"))
+	_, ts, ok = boards.due(name, time.Minute, "", now.Add(2*time.Minute))
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(ts).To(gomega.Equal("1.0002"))
+	_, _, ok = boards.due(name, time.Minute, "", now.Add(3*time.Minute))
+	g.Expect(ok).To(gomega.BeFalse())
+
+	g.Expect(boards.names()).To(gomega.ConsistOf(name))
+	boards.remove(name)
+	g.Expect(boards.names()).To(gomega.BeEmpty())
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+// WithTemplates sets the library of the message templates, the
+// cross-namespace template references are rejected by default.
+func (s *EventServer) WithTemplates(library *templates.Library) {
+	s.templates = library
+}
+
+// renderMessage replaces the message of the notification with the template
+// of the alert, or with the template of the provider, if any, in the locale
+// of the provider.
+func renderMessage(ctx context.Context, kubeClient client.Client, library *templates.Library, alert v1beta1.Alert, provider v1beta1.Provider, notification *events.Event) error {
+	ref, namespace := alert.Spec.TemplateRef, alert.Namespace
+	if ref == nil {
+		ref, namespace = provider.Spec.TemplateRef, provider.Namespace
+	}
+	if ref == nil {
+		return nil
+	}
+
+	tmpl, err := library.GetLocalized(ctx, kubeClient, *ref, namespace, provider.Spec.Locale)
+	if err != nil {
+		return err
+	}
+	message, err := templates.Render(tmpl, *notification)
+	if err != nil {
+		return err
+	}
+	notification.Message = message
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+func TestRenderMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "flux-system"},
+		Data: map[string]string{
+			"alert":    `alert: {{ .Message }}`,
+			"provider": `provider: {{ .Message }}`,
+		},
+	}).Build()
+
+	alert := v1beta1.Alert{ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "apps"}}
+	provider := v1beta1.Provider{ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "apps"}}
+
+	library := templates.NewLibrary(true)
+	event := events.Event{Message: "reconciled"}
+	g.Expect(renderMessage(context.TODO(), kubeClient, library, alert, provider, &event)).To(gomega.Succeed())
+	g.Expect(event.Message).To(gomega.Equal("reconciled"))
+
+	provider.Spec.TemplateRef = &v1beta1.TemplateReference{Name: "templates", Namespace: "flux-system", Key: "provider"}
+	g.Expect(renderMessage(context.TODO(), kubeClient, library, alert, provider, &event)).To(gomega.Succeed())
+	g.Expect(event.Message).To(gomega.Equal("provider: reconciled"))
+
+	event.Message = "reconciled"
+	alert.Spec.TemplateRef = &v1beta1.TemplateReference{Name: "templates", Namespace: "flux-system", Key: "alert"}
+	g.Expect(renderMessage(context.TODO(), kubeClient, library, alert, provider, &event)).To(gomega.Succeed())
+	g.Expect(event.Message).To(gomega.Equal("alert: reconciled"))
+
+	alert.Spec.TemplateRef.Key = "missing"
+	g.Expect(renderMessage(context.TODO(), kubeClient, library, alert, provider, &event)).NotTo(gomega.Succeed())
+	g.Expect(event.Message).To(gomega.Equal("alert: reconciled"))
+
+	// the cross-namespace references are rejected unless allowed
+	alert.Spec.TemplateRef.Key = "alert"
+	event.Message = "reconciled"
+	g.Expect(renderMessage(context.TODO(), kubeClient, templates.NewLibrary(false), alert, provider, &event)).NotTo(gomega.Succeed())
+	g.Expect(event.Message).To(gomega.Equal("reconciled"))
+}
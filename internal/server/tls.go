@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// servingTLS holds the HTTPS configuration of the event and receiver servers.
+type servingTLS struct {
+	tlsConfig *tls.Config
+	certFile  string
+	keyFile   string
+}
+
+// WithTLS makes the server listen on HTTPS with the certificate and key files,
+// the config restricts the TLS versions and cipher suites, it can be nil.
+func (t *servingTLS) WithTLS(config *tls.Config, certFile, keyFile string) {
+	t.tlsConfig = config
+	t.certFile = certFile
+	t.keyFile = keyFile
+}
+
+// listenAndServe starts the server on HTTPS if a certificate is set, on HTTP otherwise.
+func (t *servingTLS) listenAndServe(srv *http.Server) error {
+	if t.certFile == "" {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = t.tlsConfig
+	return srv.ListenAndServeTLS(t.certFile, t.keyFile)
+}
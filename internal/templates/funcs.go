@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// shortSHALength is the length of the abbreviated commit SHAs.
+const shortSHALength = 7
+
+// Revision is a source revision split into its branch or tag and its commit SHA.
+type Revision struct {
+	Branch string
+	SHA    string
+}
+
+// funcMap returns the functions available to the templates, the functions
+// shared with sprig have the same names and argument order, so that they
+// can be used in pipelines, e.g. '{{ .Message | trunc 80 }}'.
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		"parseRevision": parseRevision,
+		"shortSHA":      shortSHA,
+		"trunc":         trunc,
+		"upper":         strings.ToUpper,
+		"lower":         strings.ToLower,
+		"toUpper":       strings.ToUpper,
+		"toLower":       strings.ToLower,
+		"trim":          strings.TrimSpace,
+		"trimPrefix":    func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":    func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":       func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":      func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":     func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":     func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"quote":         strconv.Quote,
+		"default":       defaultValue,
+		"date":          date,
+		"now":           time.Now,
+	}
+}
+
+// parseRevision splits a revision formatted as '<branch>@<algorithm>:<sha>',
+// '<branch>/<sha>' or '<sha>'.
+func parseRevision(revision string) Revision {
+	if i := strings.LastIndex(revision, "@"); i >= 0 {
+		digest := revision[i+1:]
+		if j := strings.Index(digest, ":"); j >= 0 {
+			digest = digest[j+1:]
+		}
+		return Revision{Branch: revision[:i], SHA: digest}
+	}
+	if i := strings.LastIndex(revision, "/"); i >= 0 {
+		return Revision{Branch: revision[:i], SHA: revision[i+1:]}
+	}
+	if i := strings.Index(revision, ":"); i >= 0 {
+		return Revision{SHA: revision[i+1:]}
+	}
+	return Revision{SHA: revision}
+}
+
+// shortSHA returns the abbreviated commit SHA of a revision.
+func shortSHA(revision string) string {
+	sha := parseRevision(revision).SHA
+	if len(sha) > shortSHALength {
+		return sha[:shortSHALength]
+	}
+	return sha
+}
+
+// trunc returns the first n characters of s, or the last -n characters if n is negative.
+func trunc(n int, s string) string {
+	runes := []rune(s)
+	switch {
+	case n >= 0 && len(runes) > n:
+		return string(runes[:n])
+	case n < 0 && len(runes) > -n:
+		return string(runes[len(runes)+n:])
+	}
+	return s
+}
+
+// defaultValue returns value unless it's empty, in which case it returns def.
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || value[0] == nil {
+		return def
+	}
+	v := reflect.ValueOf(value[0])
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return def
+		}
+	}
+	return value[0]
+}
+
+// date formats a time with a Go layout, e.g. '{{ date "2006-01-02" .Timestamp }}'.
+func date(layout string, t interface{}) (string, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case metav1.Time:
+		return v.Format(layout), nil
+	case *metav1.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", err
+		}
+		return parsed.Format(layout), nil
+	}
+	return "", fmt.Errorf("unsupported time type %T", t)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRevision(t *testing.T) {
+	tests := []struct {
+		revision string
+		want     Revision
+	}{
+		{revision: "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1", want: Revision{Branch: "main", SHA: "731f7eaddfb6af01cb2173e18f0f75b0ba780ef1"}},
+		{revision: "feature/login/731f7ea", want: Revision{Branch: "feature/login", SHA: "731f7ea"}},
+		{revision: "main@sha1:731f7eaddfb6af01cb2173e18f0f75b0ba780ef1", want: Revision{Branch: "main", SHA: "731f7eaddfb6af01cb2173e18f0f75b0ba780ef1"}},
+		{revision: "v1.0.0@sha1:731f7ea", want: Revision{Branch: "v1.0.0", SHA: "731f7ea"}},
+		{revision: "sha256:9c5e4a", want: Revision{SHA: "9c5e4a"}},
+		{revision: "731f7eaddfb6", want: Revision{SHA: "731f7eaddfb6"}},
+		{revision: "", want: Revision{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.revision, func(t *testing.T) {
+			require.Equal(t, tt.want, parseRevision(tt.revision))
+		})
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	timestamp := metav1.NewTime(time.Date(2021, 5, 7, 12, 30, 0, 0, time.UTC))
+	data := map[string]interface{}{
+		"revision":  "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1",
+		"message":   "Health check failed after 2m0s",
+		"timestamp": timestamp,
+		"empty":     "",
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: `{{ shortSHA .revision }}`, want: "731f7ea"},
+		{tmpl: `{{ (parseRevision .revision).Branch }}`, want: "main"},
+		{tmpl: `{{ .message | trunc 12 }}`, want: "Health check"},
+		{tmpl: `{{ .message | trunc -4 }}`, want: "2m0s"},
+		{tmpl: `{{ .message | trunc 100 }}`, want: "Health check failed after 2m0s"},
+		{tmpl: `{{ .message | toUpper }}`, want: "HEALTH CHECK FAILED AFTER 2M0S"},
+		{tmpl: `{{ .message | lower }}`, want: "health check failed after 2m0s"},
+		{tmpl: `{{ .message | replace "failed" "passed" }}`, want: "Health check passed after 2m0s"},
+		{tmpl: `{{ .message | trimPrefix "Health " }}`, want: "check failed after 2m0s"},
+		{tmpl: `{{ if .message | contains "failed" }}yes{{ end }}`, want: "yes"},
+		{tmpl: `{{ if .revision | hasPrefix "main/" }}yes{{ end }}`, want: "yes"},
+		{tmpl: `{{ .empty | default "none" }}`, want: "none"},
+		{tmpl: `{{ .missing | default "none" }}`, want: "none"},
+		{tmpl: `{{ .message | default "none" | quote }}`, want: `"Health check failed after 2m0s"`},
+		{tmpl: `{{ date "2006-01-02 15:04" .timestamp }}`, want: "2021-05-07 12:30"},
+		{tmpl: `{{ date "Jan 2" "2021-05-07T12:30:00Z" }}`, want: "May 7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			tmpl, err := template.New("test").Option("missingkey=zero").Funcs(funcMap()).Parse(tt.tmpl)
+			require.NoError(t, err)
+			var out strings.Builder
+			require.NoError(t, tmpl.Execute(&out, data))
+			require.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// Library loads the templates from the ConfigMaps, the parsed templates
+// are cached until the ConfigMaps change.
+type Library struct {
+	allowCrossNamespace bool
+
+	mu    sync.Mutex
+	cache map[types.NamespacedName]entry
+}
+
+type entry struct {
+	resourceVersion string
+	templates       *template.Template
+}
+
+// NewLibrary returns an empty template library, the references to the
+// ConfigMaps of another namespace are rejected unless allowCrossNamespace is set.
+func NewLibrary(allowCrossNamespace bool) *Library {
+	return &Library{
+		allowCrossNamespace: allowCrossNamespace,
+		cache:               make(map[types.NamespacedName]entry),
+	}
+}
+
+// Get returns the referenced template, the namespace is used
+// when the reference has none.
+func (l *Library) Get(ctx context.Context, kubeClient client.Client, ref v1beta1.TemplateReference, namespace string) (*template.Template, error) {
+	return l.GetLocalized(ctx, kubeClient, ref, namespace, "")
+}
+
+// GetLocalized returns the template of the reference key suffixed with
+// the locale, e.g. 'message.de', or the referenced template when the
+// ConfigMap has no such key.
+func (l *Library) GetLocalized(ctx context.Context, kubeClient client.Client, ref v1beta1.TemplateReference, namespace, locale string) (*template.Template, error) {
+	cm, err := getConfigMap(ctx, kubeClient, ref, namespace, l.allowCrossNamespace)
+	if err != nil {
+		return nil, err
+	}
+	name := types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.cache[name]
+	if !ok || e.resourceVersion != cm.ResourceVersion {
+		t, err := parse(cm)
+		if err != nil {
+			delete(l.cache, name)
+			return nil, err
+		}
+		e = entry{resourceVersion: cm.ResourceVersion, templates: t}
+		l.cache[name] = e
+	}
+
+	return lookup(e.templates, ref, locale)
+}
+
+// Load returns the referenced template without caching it.
+func Load(ctx context.Context, kubeClient client.Client, ref v1beta1.TemplateReference, namespace string, allowCrossNamespace bool) (*template.Template, error) {
+	cm, err := getConfigMap(ctx, kubeClient, ref, namespace, allowCrossNamespace)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parse(cm)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(t, ref, "")
+}
+
+// Parse parses a standalone template, e.g. a provider address,
+// with the functions available to the ConfigMap templates.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Funcs(funcMap()).Parse(text)
+}
+
+// Render executes the template with the event as data.
+func Render(t *template.Template, event events.Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render template '%s': %w", t.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+func getConfigMap(ctx context.Context, kubeClient client.Client, ref v1beta1.TemplateReference, namespace string, allowCrossNamespace bool) (*corev1.ConfigMap, error) {
+	name := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if name.Namespace == "" {
+		name.Namespace = namespace
+	}
+	if name.Namespace != namespace && !allowCrossNamespace {
+		return nil, fmt.Errorf("template ConfigMap %s is in another namespace than %s, cross-namespace template references are disabled", name, namespace)
+	}
+
+	var cm corev1.ConfigMap
+	if err := kubeClient.Get(ctx, name, &cm); err != nil {
+		return nil, fmt.Errorf("failed to get template ConfigMap %s, error: %w", name, err)
+	}
+	return &cm, nil
+}
+
+// parse returns a template set with a template per key of the ConfigMap,
+// so that the templates can include each other.
+func parse(cm *corev1.ConfigMap) (*template.Template, error) {
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := template.New(cm.Name).Option("missingkey=zero").Funcs(funcMap())
+	for _, key := range keys {
+		if _, err := root.New(key).Parse(cm.Data[key]); err != nil {
+			return nil, fmt.Errorf("failed to parse template '%s' of ConfigMap %s/%s: %w", key, cm.Namespace, cm.Name, err)
+		}
+	}
+	return root, nil
+}
+
+func lookup(t *template.Template, ref v1beta1.TemplateReference, locale string) (*template.Template, error) {
+	if locale != "" {
+		if named := t.Lookup(ref.Key + "." + locale); named != nil {
+			return named, nil
+		}
+	}
+	named := t.Lookup(ref.Key)
+	if named == nil {
+		return nil, fmt.Errorf("template '%s' not found in ConfigMap %s", ref.Key, ref.Name)
+	}
+	return named, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func testEvent() events.Event {
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Kustomization",
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+		Severity: events.EventSeverityError,
+		Message:  "health check failed",
+		Metadata: map[string]string{
+			"revision": "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1",
+		},
+	}
+}
+
+func TestLibrary_Get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "flux-system"},
+		Data: map[string]string{
+			"object":  `{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}`,
+			"message": `[{{ .Severity }}] {{ template "object" . }}: {{ .Message }} ({{ .Metadata.revision }}{{ .Metadata.missing }})`,
+		},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+
+	// the cross-namespace references are rejected unless allowed
+	ref := v1beta1.TemplateReference{Name: "templates", Namespace: "flux-system", Key: "message"}
+	_, err := NewLibrary(false).Get(context.TODO(), kubeClient, ref, "apps")
+	require.Error(t, err)
+	_, err = Load(context.TODO(), kubeClient, ref, "apps", false)
+	require.Error(t, err)
+	_, err = Load(context.TODO(), kubeClient, ref, "flux-system", false)
+	require.NoError(t, err)
+
+	library := NewLibrary(true)
+	tmpl, err := library.Get(context.TODO(), kubeClient, ref, "apps")
+	require.NoError(t, err)
+	out, err := Render(tmpl, testEvent())
+	require.NoError(t, err)
+	require.Equal(t, "[error] Kustomization/apps: health check failed (main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1)", out)
+
+	// the template is reloaded when the ConfigMap changes
+	cm.Data["message"] = `{{ .Message }}`
+	require.NoError(t, kubeClient.Update(context.TODO(), cm))
+	tmpl, err = library.Get(context.TODO(), kubeClient, ref, "apps")
+	require.NoError(t, err)
+	out, err = Render(tmpl, testEvent())
+	require.NoError(t, err)
+	require.Equal(t, "health check failed", out)
+
+	// the localized keys are used over the referenced one
+	cm.Data["message.de"] = `Fehler: {{ .Message }}`
+	require.NoError(t, kubeClient.Update(context.TODO(), cm))
+	tmpl, err = library.GetLocalized(context.TODO(), kubeClient, ref, "apps", "de")
+	require.NoError(t, err)
+	require.Equal(t, "message.de", tmpl.Name())
+	tmpl, err = library.GetLocalized(context.TODO(), kubeClient, ref, "apps", "fr")
+	require.NoError(t, err)
+	require.Equal(t, "message", tmpl.Name())
+
+	_, err = library.Get(context.TODO(), kubeClient, v1beta1.TemplateReference{Name: "templates", Key: "message"}, "apps")
+	require.Error(t, err)
+
+	_, err = library.Get(context.TODO(), kubeClient, v1beta1.TemplateReference{Name: "templates", Key: "missing"}, "flux-system")
+	require.Error(t, err)
+
+	cm.Data["invalid"] = `{{ .Message `
+	require.NoError(t, kubeClient.Update(context.TODO(), cm))
+	_, err = library.Get(context.TODO(), kubeClient, ref, "apps")
+	require.Error(t, err)
+	_, err = Load(context.TODO(), kubeClient, ref, "apps", true)
+	require.Error(t, err)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagTLSMinVersion   = "tls-min-version"
+	flagTLSCipherSuites = "tls-cipher-suites"
+	flagTLSCertFile     = "tls-cert-file"
+	flagTLSKeyFile      = "tls-key-file"
+)
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Options contains the TLS configuration of the notifier clients
+// and of the event and receiver servers.
+type Options struct {
+	MinVersion   string
+	CipherSuites []string
+	CertFile     string
+	KeyFile      string
+}
+
+// BindFlags will parse the given flagset for TLS option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.MinVersion, flagTLSMinVersion, "",
+		"The minimum TLS version of the notifier clients and the servers. Can be one of '1.0', '1.1', '1.2', '1.3'.")
+	fs.StringSliceVar(&o.CipherSuites, flagTLSCipherSuites, nil,
+		"The TLS 1.0-1.2 cipher suites permitted for the notifier clients and the servers, e.g. "+
+			"'TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256'. The Go defaults are used when empty.")
+	fs.StringVar(&o.CertFile, flagTLSCertFile, "",
+		"The certificate file of the event and receiver servers, the servers listen on HTTP when empty.")
+	fs.StringVar(&o.KeyFile, flagTLSKeyFile, "",
+		"The private key file of the event and receiver servers.")
+}
+
+// Config returns the TLS configuration with the minimum version and the
+// cipher suites set, it returns nil when neither are set.
+func (o Options) Config() (*tls.Config, error) {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, fmt.Errorf("both --%s and --%s must be set", flagTLSCertFile, flagTLSKeyFile)
+	}
+	if o.MinVersion == "" && len(o.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+	if o.MinVersion != "" {
+		version, ok := versions[o.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS version '%s', can be one of %s", o.MinVersion, strings.Join(keys(versions), ", "))
+		}
+		config.MinVersion = version
+	}
+
+	if len(o.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range o.CipherSuites {
+			id, ok := suites[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unsupported or insecure cipher suite '%s'", name)
+			}
+			config.CipherSuites = append(config.CipherSuites, id)
+		}
+	}
+
+	return config, nil
+}
+
+func keys(m map[string]uint16) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	sort.Strings(k)
+	return k
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Config(t *testing.T) {
+	config, err := Options{}.Config()
+	require.NoError(t, err)
+	require.Nil(t, config)
+
+	var o Options
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.BindFlags(fs)
+	require.NoError(t, fs.Parse([]string{
+		"--tls-min-version=1.2",
+		"--tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	}))
+	config, err = o.Config()
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	require.Equal(t, []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	}, config.CipherSuites)
+
+	_, err = Options{MinVersion: "1.4"}.Config()
+	require.Error(t, err)
+
+	_, err = Options{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}.Config()
+	require.Error(t, err)
+
+	_, err = Options{CertFile: "tls.crt"}.Config()
+	require.Error(t, err)
+}
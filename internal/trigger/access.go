@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// ValidateAccessFrom checks the IP blocks of the receiver source addresses.
+func ValidateAccessFrom(receiver v1beta1.Receiver) error {
+	if receiver.Spec.AccessFrom == nil {
+		return nil
+	}
+	if _, err := ParseIPBlocks(receiver.Spec.AccessFrom.IPBlocks); err != nil {
+		return fmt.Errorf("invalid accessFrom: %w", err)
+	}
+	return nil
+}
+
+// ParseIPBlocks parses a list of CIDR blocks, an IP address is parsed
+// as a block of that address only.
+func ParseIPBlocks(blocks []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(blocks))
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if ip := net.ParseIP(block); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP block '%s'", block)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ContainsIP returns true if the IP is in one of the networks.
+func ContainsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientCAs returns the CA bundle of the receiver certSecretRef verifying the
+// client certificates of the callers, nil when the receiver has no certSecretRef.
+func ClientCAs(ctx context.Context, kubeClient client.Client, receiver v1beta1.Receiver) (*x509.CertPool, error) {
+	if receiver.Spec.CertSecretRef == nil {
+		return nil, nil
+	}
+
+	var secret corev1.Secret
+	secretName := types.NamespacedName{Namespace: receiver.Namespace, Name: receiver.Spec.CertSecretRef.Name}
+	if err := kubeClient.Get(ctx, secretName, &secret); err != nil {
+		return nil, fmt.Errorf("unable to read CA from secret '%s' error: %w", secretName, err)
+	}
+
+	caFile, ok := secret.Data["caFile"]
+	if !ok {
+		return nil, fmt.Errorf("no caFile found in secret '%s'", secretName)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caFile) {
+		return nil, fmt.Errorf("invalid CA found in secret '%s'", secretName)
+	}
+	return certPool, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestParseIPBlocks(t *testing.T) {
+	networks, err := ParseIPBlocks([]string{"192.30.252.0/22", " 10.0.0.5", "2a0a:a440::/29"})
+	require.NoError(t, err)
+	require.Len(t, networks, 3)
+	require.True(t, ContainsIP(networks, net.ParseIP("192.30.253.4")))
+	require.True(t, ContainsIP(networks, net.ParseIP("10.0.0.5")))
+	require.False(t, ContainsIP(networks, net.ParseIP("10.0.0.6")))
+	require.True(t, ContainsIP(networks, net.ParseIP("2a0a:a440::1")))
+
+	_, err = ParseIPBlocks([]string{"192.30.252.0/33"})
+	require.Error(t, err)
+	_, err = ParseIPBlocks([]string{"github.com"})
+	require.Error(t, err)
+}
+
+func TestValidateAccessFrom(t *testing.T) {
+	receiver := v1beta1.Receiver{}
+	require.NoError(t, ValidateAccessFrom(receiver))
+
+	receiver.Spec.AccessFrom = &v1beta1.ReceiverAccessFrom{IPBlocks: []string{"192.30.252.0/22"}}
+	require.NoError(t, ValidateAccessFrom(receiver))
+
+	receiver.Spec.AccessFrom.IPBlocks = append(receiver.Spec.AccessFrom.IPBlocks, "192.30.252.0/")
+	require.Error(t, ValidateAccessFrom(receiver))
+}
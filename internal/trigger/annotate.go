@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// ValidateResources checks that the receiver resources reference single objects,
+// the wildcards and the namespace selectors are only supported by the alerts.
+func ValidateResources(receiver v1beta1.Receiver) error {
+	for _, resource := range receiver.Spec.Resources {
+		if resource.Kind == "" || resource.Kind == "*" {
+			return fmt.Errorf("a kind is required for the receiver resources")
+		}
+		if resource.Name == "" || resource.Name == "*" {
+			return fmt.Errorf("a name is required for the %s receiver resources", resource.Kind)
+		}
+		if resource.NamespaceSelector != nil {
+			return fmt.Errorf("namespace selectors are not supported for the receiver resources")
+		}
+	}
+	return nil
+}
+
+// Annotate sets the reconcile request annotation key to the given value on the resource,
+// the receiver namespace is used when the resource has no namespace.
+func Annotate(ctx context.Context, kubeClient client.Client, resource v1beta1.CrossNamespaceObjectReference, defaultNamespace, key, value string) error {
+	return SetAnnotations(ctx, kubeClient, resource, defaultNamespace, map[string]string{key: value})
+}
+
+// SetAnnotations sets the annotations on the resource in a single update,
+// the receiver namespace is used when the resource has no namespace.
+func SetAnnotations(ctx context.Context, kubeClient client.Client, resource v1beta1.CrossNamespaceObjectReference, defaultNamespace string, annotations map[string]string) error {
+	return Apply(ctx, kubeClient, resource, defaultNamespace, v1beta1.ReconcileAction, annotations)
+}
+
+// Apply applies the receiver action to the resource in a single update, the resumed
+// resources get their spec.suspend field cleared and the reconciled resources get the
+// annotations. The receiver namespace is used when the resource has no namespace.
+func Apply(ctx context.Context, kubeClient client.Client, resource v1beta1.CrossNamespaceObjectReference, defaultNamespace, action string, annotations map[string]string) error {
+	u, err := Get(ctx, kubeClient, resource, defaultNamespace)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if action == v1beta1.ResumeAction || action == v1beta1.ResumeAndReconcileAction {
+		suspended, _, err := unstructured.NestedBool(u.Object, "spec", "suspend")
+		if err != nil {
+			return fmt.Errorf("unable to resume %s '%s/%s' error: %w", resource.Kind, u.GetNamespace(), u.GetName(), err)
+		}
+		if suspended {
+			if err := unstructured.SetNestedField(u.Object, false, "spec", "suspend"); err != nil {
+				return fmt.Errorf("unable to resume %s '%s/%s' error: %w", resource.Kind, u.GetNamespace(), u.GetName(), err)
+			}
+			changed = true
+		}
+	}
+
+	if action != v1beta1.ResumeAction {
+		sourceAnnotations := u.GetAnnotations()
+		if sourceAnnotations == nil {
+			sourceAnnotations = make(map[string]string)
+		}
+		for key, value := range annotations {
+			sourceAnnotations[key] = value
+		}
+		u.SetAnnotations(sourceAnnotations)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := kubeClient.Update(ctx, u); err != nil {
+		return fmt.Errorf("unable to %s %s '%s/%s' error: %w", actionVerb(action), resource.Kind, u.GetNamespace(), u.GetName(), err)
+	}
+
+	return nil
+}
+
+// actionVerb returns the verb describing the action in the errors.
+func actionVerb(action string) string {
+	if action == v1beta1.ResumeAction || action == v1beta1.ResumeAndReconcileAction {
+		return "resume"
+	}
+	return "annotate"
+}
+
+// Get reads the resource, the receiver namespace is used when the resource has no
+// namespace, and the API version of the Flux sources is defaulted when empty.
+func Get(ctx context.Context, kubeClient client.Client, resource v1beta1.CrossNamespaceObjectReference, defaultNamespace string) (*unstructured.Unstructured, error) {
+	namespace := defaultNamespace
+	if resource.Namespace != "" {
+		namespace = resource.Namespace
+	}
+	objectKey := client.ObjectKey{
+		Namespace: namespace,
+		Name:      resource.Name,
+	}
+
+	apiVersionMap := map[string]string{
+		"Bucket":          "source.toolkit.fluxcd.io/v1beta1",
+		"HelmRepository":  "source.toolkit.fluxcd.io/v1beta1",
+		"GitRepository":   "source.toolkit.fluxcd.io/v1beta1",
+		"ImageRepository": "image.toolkit.fluxcd.io/v1alpha1",
+	}
+
+	apiVersion := resource.APIVersion
+	if apiVersion == "" {
+		if apiVersionMap[resource.Kind] == "" {
+			return nil, fmt.Errorf("apiVersion must be specified for kind '%s'", resource.Kind)
+		}
+		apiVersion = apiVersionMap[resource.Kind]
+	}
+
+	group, version := getGroupVersion(apiVersion)
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   group,
+		Kind:    resource.Kind,
+		Version: version,
+	})
+
+	if err := kubeClient.Get(ctx, objectKey, u); err != nil {
+		return nil, fmt.Errorf("unable to read %s '%s' error: %w", resource.Kind, objectKey, err)
+	}
+	return u, nil
+}
+
+func getGroupVersion(s string) (string, string) {
+	slice := strings.Split(s, "/")
+	if len(slice) == 1 {
+		return "", slice[0]
+	}
+
+	return slice[0], slice[1]
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource v1beta1.CrossNamespaceObjectReference
+		wantErr  bool
+	}{
+		{name: "single object", resource: v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "webapp"}},
+		{name: "wildcard kind", resource: v1beta1.CrossNamespaceObjectReference{Kind: "*", Name: "webapp"}, wantErr: true},
+		{name: "no kind", resource: v1beta1.CrossNamespaceObjectReference{Name: "webapp"}, wantErr: true},
+		{name: "wildcard name", resource: v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "*"}, wantErr: true},
+		{name: "no name", resource: v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository"}, wantErr: true},
+		{
+			name: "namespace selector",
+			resource: v1beta1.CrossNamespaceObjectReference{
+				Kind:              "GitRepository",
+				Name:              "webapp",
+				NamespaceSelector: &metav1.LabelSelector{},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := v1beta1.Receiver{
+				Spec: v1beta1.ReceiverSpec{
+					Resources: []v1beta1.CrossNamespaceObjectReference{tt.resource},
+				},
+			}
+			err := ValidateResources(receiver)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1beta1", Kind: "GitRepository"}
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+
+	newRepository := func(name string, suspend bool) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetNamespace("default")
+		require.NoError(t, unstructured.SetNestedField(u.Object, suspend, "spec", "suspend"))
+		return u
+	}
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(newRepository("resume", true), newRepository("both", true), newRepository("reconcile", true)).
+		Build()
+
+	annotations := map[string]string{"reconcile.fluxcd.io/requestedAt": "now"}
+	for name, action := range map[string]string{
+		"resume":    v1beta1.ResumeAction,
+		"both":      v1beta1.ResumeAndReconcileAction,
+		"reconcile": v1beta1.ReconcileAction,
+	} {
+		resource := v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: name}
+		require.NoError(t, Apply(context.TODO(), kubeClient, resource, "default", action, annotations))
+	}
+
+	tests := []struct {
+		name          string
+		wantSuspend   bool
+		wantAnnotated bool
+	}{
+		{name: "resume", wantSuspend: false, wantAnnotated: false},
+		{name: "both", wantSuspend: false, wantAnnotated: true},
+		{name: "reconcile", wantSuspend: true, wantAnnotated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := Get(context.TODO(), kubeClient, v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: tt.name}, "default")
+			require.NoError(t, err)
+			suspend, _, err := unstructured.NestedBool(u.Object, "spec", "suspend")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSuspend, suspend)
+			_, annotated := u.GetAnnotations()["reconcile.fluxcd.io/requestedAt"]
+			require.Equal(t, tt.wantAnnotated, annotated)
+		})
+	}
+}
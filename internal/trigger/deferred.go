@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// Defer queues the resources in the receiver status, the resources
+// already queued are not added twice.
+func Defer(ctx context.Context, kubeClient client.Client, receiverName types.NamespacedName, resources []v1beta1.CrossNamespaceObjectReference) error {
+	return updateStatus(ctx, kubeClient, receiverName, func(status *v1beta1.ReceiverStatus) {
+		status.DeferredResources = MergeDeferred(status.DeferredResources, resources, receiverName.Namespace)
+	})
+}
+
+// Release removes the resources from the receiver deferred queue.
+func Release(ctx context.Context, kubeClient client.Client, receiverName types.NamespacedName, resources []v1beta1.CrossNamespaceObjectReference) error {
+	return updateStatus(ctx, kubeClient, receiverName, func(status *v1beta1.ReceiverStatus) {
+		status.DeferredResources = RemoveDeferred(status.DeferredResources, resources, receiverName.Namespace)
+	})
+}
+
+// RecordTrigger sets the outcome of a request in the receiver status, and prepends it
+// to the history of the receiver, which is trimmed to the limit when greater than zero.
+func RecordTrigger(ctx context.Context, kubeClient client.Client, receiverName types.NamespacedName, trigger v1beta1.ReceiverTrigger, limit int) error {
+	return updateStatus(ctx, kubeClient, receiverName, func(status *v1beta1.ReceiverStatus) {
+		status.LastTrigger = &trigger
+		status.History = AppendHistory(status.History, trigger, limit)
+	})
+}
+
+// AppendHistory prepends the trigger to the history, keeping at most limit
+// entries, the history is cleared when the limit is zero.
+func AppendHistory(history []v1beta1.ReceiverTrigger, trigger v1beta1.ReceiverTrigger, limit int) []v1beta1.ReceiverTrigger {
+	if limit <= 0 {
+		return nil
+	}
+	history = append([]v1beta1.ReceiverTrigger{trigger}, history...)
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history
+}
+
+// MergeDeferred appends the resources to the deferred queue, skipping the ones already queued.
+// The resources are stored with their namespace defaulted to the receiver namespace.
+func MergeDeferred(deferred, resources []v1beta1.CrossNamespaceObjectReference, defaultNamespace string) []v1beta1.CrossNamespaceObjectReference {
+	queued := make(map[string]bool, len(deferred))
+	for _, resource := range deferred {
+		queued[resourceKey(resource, defaultNamespace)] = true
+	}
+
+	for _, resource := range resources {
+		key := resourceKey(resource, defaultNamespace)
+		if queued[key] {
+			continue
+		}
+		if resource.Namespace == "" {
+			resource.Namespace = defaultNamespace
+		}
+		deferred = append(deferred, resource)
+		queued[key] = true
+	}
+	return deferred
+}
+
+// RemoveDeferred returns the deferred queue without the given resources.
+func RemoveDeferred(deferred, resources []v1beta1.CrossNamespaceObjectReference, defaultNamespace string) []v1beta1.CrossNamespaceObjectReference {
+	released := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		released[resourceKey(resource, defaultNamespace)] = true
+	}
+
+	var remaining []v1beta1.CrossNamespaceObjectReference
+	for _, resource := range deferred {
+		if !released[resourceKey(resource, defaultNamespace)] {
+			remaining = append(remaining, resource)
+		}
+	}
+	return remaining
+}
+
+func resourceKey(resource v1beta1.CrossNamespaceObjectReference, defaultNamespace string) string {
+	namespace := defaultNamespace
+	if resource.Namespace != "" {
+		namespace = resource.Namespace
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", resource.APIVersion, resource.Kind, namespace, resource.Name)
+}
+
+// updateStatus patches the receiver status with optimistic locking,
+// as the webhook handlers and the reconciler can update it concurrently.
+func updateStatus(ctx context.Context, kubeClient client.Client, receiverName types.NamespacedName,
+	update func(*v1beta1.ReceiverStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var receiver v1beta1.Receiver
+		if err := kubeClient.Get(ctx, receiverName, &receiver); err != nil {
+			return err
+		}
+
+		patch := client.MergeFromWithOptions(receiver.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		update(&receiver.Status)
+
+		return kubeClient.Status().Patch(ctx, &receiver, patch)
+	})
+}
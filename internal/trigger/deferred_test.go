@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestMergeDeferred(t *testing.T) {
+	deferred := []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "GitRepository", Name: "webapp", Namespace: "default"},
+	}
+	resources := []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "GitRepository", Name: "webapp"},
+		{Kind: "ImageRepository", Name: "webapp"},
+		{Kind: "ImageRepository", Name: "webapp"},
+	}
+
+	merged := MergeDeferred(deferred, resources, "default")
+	require.Equal(t, []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "GitRepository", Name: "webapp", Namespace: "default"},
+		{Kind: "ImageRepository", Name: "webapp", Namespace: "default"},
+	}, merged)
+
+	remaining := RemoveDeferred(merged, resources[:1], "default")
+	require.Equal(t, []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "ImageRepository", Name: "webapp", Namespace: "default"},
+	}, remaining)
+
+	require.Empty(t, RemoveDeferred(remaining, remaining, "default"))
+}
+
+func TestDeferAndRelease(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver).Build()
+	receiverName := types.NamespacedName{Name: "registry", Namespace: "default"}
+	resources := []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "ImageRepository", Name: "webapp"},
+		{Kind: "ImageRepository", Name: "backend"},
+	}
+
+	// a second push during the window is queued only once
+	require.NoError(t, Defer(context.TODO(), kubeClient, receiverName, resources))
+	require.NoError(t, Defer(context.TODO(), kubeClient, receiverName, resources))
+
+	var got v1beta1.Receiver
+	require.NoError(t, kubeClient.Get(context.TODO(), receiverName, &got))
+	require.Len(t, got.Status.DeferredResources, 2)
+
+	require.NoError(t, Release(context.TODO(), kubeClient, receiverName, resources[:1]))
+	require.NoError(t, kubeClient.Get(context.TODO(), receiverName, &got))
+	require.Equal(t, []v1beta1.CrossNamespaceObjectReference{
+		{Kind: "ImageRepository", Name: "backend", Namespace: "default"},
+	}, got.Status.DeferredResources)
+}
+
+func TestRecordTrigger(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+		Status: v1beta1.ReceiverStatus{
+			DeferredResources: []v1beta1.CrossNamespaceObjectReference{{Kind: "ImageRepository", Name: "webapp"}},
+		},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(receiver).Build()
+	receiverName := types.NamespacedName{Name: "registry", Namespace: "default"}
+
+	require.NoError(t, RecordTrigger(context.TODO(), kubeClient, receiverName, v1beta1.ReceiverTrigger{
+		Event:     "push",
+		Annotated: 2,
+		Failed:    1,
+		Result:    v1beta1.FailedTriggerResult,
+	}, 2))
+
+	var got v1beta1.Receiver
+	require.NoError(t, kubeClient.Get(context.TODO(), receiverName, &got))
+	require.NotNil(t, got.Status.LastTrigger)
+	require.Equal(t, "push", got.Status.LastTrigger.Event)
+	require.Equal(t, 2, got.Status.LastTrigger.Annotated)
+	require.Equal(t, 1, got.Status.LastTrigger.Failed)
+	require.Len(t, got.Status.DeferredResources, 1)
+	require.Len(t, got.Status.History, 1)
+
+	for _, event := range []string{"tag", "ping"} {
+		require.NoError(t, RecordTrigger(context.TODO(), kubeClient, receiverName, v1beta1.ReceiverTrigger{
+			Event:  event,
+			Result: v1beta1.SucceededTriggerResult,
+		}, 2))
+	}
+	require.NoError(t, kubeClient.Get(context.TODO(), receiverName, &got))
+	require.Equal(t, "ping", got.Status.LastTrigger.Event)
+	require.Len(t, got.Status.History, 2)
+	require.Equal(t, "ping", got.Status.History[0].Event)
+	require.Equal(t, "tag", got.Status.History[1].Event)
+}
+
+func TestAppendHistory(t *testing.T) {
+	var history []v1beta1.ReceiverTrigger
+	for i := 1; i <= 3; i++ {
+		history = AppendHistory(history, v1beta1.ReceiverTrigger{Annotated: i}, 2)
+	}
+	require.Equal(t, []v1beta1.ReceiverTrigger{{Annotated: 3}, {Annotated: 2}}, history)
+	require.Nil(t, AppendHistory(history, v1beta1.ReceiverTrigger{}, 0))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/webhook"
+)
+
+// WebhookReceivedReason is the default reason of the events published by the receivers.
+const WebhookReceivedReason = "WebhookReceived"
+
+// ValidateEvent checks the CEL expressions of the event published by the receiver.
+func ValidateEvent(receiver v1beta1.Receiver) error {
+	e := receiver.Spec.PublishEvent
+	if e == nil {
+		return nil
+	}
+	if e.Message == "" {
+		return fmt.Errorf("a message is required to publish events")
+	}
+
+	expressions := map[string]string{"message": e.Message, "reason": e.Reason, "severity": e.Severity}
+	for k, v := range e.Metadata {
+		expressions["metadata "+k] = v
+	}
+	for name, expression := range expressions {
+		if expression == "" {
+			continue
+		}
+		if _, err := cel.Compile(expression, RequestVariable); err != nil {
+			return fmt.Errorf("invalid event %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// PublishedEvent returns the event mapped from the payload by the receiver,
+// the programs of the expressions are taken from the cache.
+func PublishedEvent(programs *cel.Cache, receiver v1beta1.Receiver, contentType string, body []byte) (events.Event, error) {
+	e := receiver.Spec.PublishEvent
+	data, err := webhook.DecodePayload(contentType, body, receiver.Spec.ParseXML)
+	if err != nil {
+		return events.Event{}, err
+	}
+
+	variables := RequestVariables(data, nil)
+	render := func(expression string) (string, error) {
+		program, err := Program(programs, receiver, expression)
+		if err != nil {
+			return "", err
+		}
+		value, err := program.EvalString(variables)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(value), nil
+	}
+
+	event := events.Event{
+		Severity: events.EventSeverityInfo,
+		Reason:   WebhookReceivedReason,
+	}
+	if event.Message, err = render(e.Message); err != nil {
+		return events.Event{}, err
+	}
+	if event.Message == "" {
+		return events.Event{}, fmt.Errorf("event message '%s' returned an empty value", e.Message)
+	}
+	if e.Reason != "" {
+		reason, err := render(e.Reason)
+		if err != nil {
+			return events.Event{}, err
+		}
+		if reason != "" {
+			event.Reason = reason
+		}
+	}
+	if e.Severity != "" {
+		severity, err := render(e.Severity)
+		if err != nil {
+			return events.Event{}, err
+		}
+		if strings.EqualFold(severity, events.EventSeverityError) {
+			event.Severity = events.EventSeverityError
+		}
+	}
+
+	keys := make([]string, 0, len(e.Metadata))
+	for k := range e.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := render(e.Metadata[k])
+		if err != nil {
+			return events.Event{}, err
+		}
+		if value == "" {
+			continue
+		}
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]string, len(keys))
+		}
+		event.Metadata[k] = value
+	}
+	return event, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestPublishedEvent(t *testing.T) {
+	receiver := v1beta1.Receiver{
+		Spec: v1beta1.ReceiverSpec{
+			PublishEvent: &v1beta1.ReceiverEvent{
+				Message:  "'build ' + string(request.body.build.number) + ' of ' + request.body.repository + ' ' + request.body.build.status",
+				Severity: "request.body.build.status",
+				Metadata: map[string]string{
+					"revision": "has(request.body.build.commit) ? request.body.build.commit : ''",
+					"number":   "request.body.build.number",
+				},
+			},
+		},
+	}
+	payload := []byte(`{"repository":"webapp","build":{"number":42,"status":"error","commit":"abc123"}}`)
+
+	require.NoError(t, ValidateEvent(receiver))
+	event, err := PublishedEvent(nil, receiver, "application/json", payload)
+	require.NoError(t, err)
+	require.Equal(t, "build 42 of webapp error", event.Message)
+	require.Equal(t, events.EventSeverityError, event.Severity)
+	require.Equal(t, WebhookReceivedReason, event.Reason)
+	require.Equal(t, map[string]string{"revision": "abc123", "number": "42"}, event.Metadata)
+
+	payload = []byte(`{"repository":"webapp","build":{"number":43,"status":"success"}}`)
+	event, err = PublishedEvent(nil, receiver, "application/json", payload)
+	require.NoError(t, err)
+	require.Equal(t, events.EventSeverityInfo, event.Severity)
+	require.Equal(t, map[string]string{"number": "43"}, event.Metadata)
+
+	receiver.Spec.PublishEvent.Message = "request.body.missing"
+	_, err = PublishedEvent(nil, receiver, "application/json", payload)
+	require.Error(t, err)
+}
+
+func TestValidateEvent(t *testing.T) {
+	require.NoError(t, ValidateEvent(v1beta1.Receiver{}))
+
+	receiver := v1beta1.Receiver{
+		Spec: v1beta1.ReceiverSpec{
+			PublishEvent: &v1beta1.ReceiverEvent{},
+		},
+	}
+	require.Error(t, ValidateEvent(receiver))
+
+	receiver.Spec.PublishEvent.Message = "'build ' + request.body.build"
+	require.NoError(t, ValidateEvent(receiver))
+
+	receiver.Spec.PublishEvent.Message = "'build ' +"
+	require.Error(t, ValidateEvent(receiver))
+
+	receiver.Spec.PublishEvent.Message = "'build ' + request.body.build"
+	receiver.Spec.PublishEvent.Metadata = map[string]string{"revision": "message.build.commit"}
+	require.Error(t, ValidateEvent(receiver))
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/cel"
+)
+
+// ConditionVariable is the variable holding the Pub/Sub message, the
+// CloudEvent or the Harbor event in the CEL condition of the receiver filter.
+const ConditionVariable = "message"
+
+// RequestVariable is the variable holding the request in the CEL expressions
+// of the receiver, its 'body' field holds the decoded payload.
+const RequestVariable = "request"
+
+// NewPrograms returns the cache of the programs compiled for the CEL
+// expressions of the receivers.
+func NewPrograms() *cel.Cache {
+	return cel.NewCache(ConditionVariable, RequestVariable)
+}
+
+// Program returns the program of the receiver expression, compiled once per
+// receiver generation. The expression is compiled without caching when the
+// cache is nil.
+func Program(programs *cel.Cache, receiver v1beta1.Receiver, expr string) (*cel.Program, error) {
+	if programs == nil {
+		return cel.Compile(expr, ConditionVariable, RequestVariable)
+	}
+	object := fmt.Sprintf("%s/%s/%s", v1beta1.ReceiverKind, receiver.Namespace, receiver.Name)
+	return programs.Program(object, receiver.Generation, expr)
+}
+
+// ValidateFilter checks the CEL condition of the receiver filter, the
+// tag patterns of the DockerHub receivers and the XML parsing option.
+func ValidateFilter(receiver v1beta1.Receiver) error {
+	if receiver.Spec.Type == v1beta1.DockerHubReceiver {
+		if err := ValidateTagPatterns(receiver.Spec.Events); err != nil {
+			return err
+		}
+	}
+
+	generic := receiver.Spec.Type == v1beta1.GenericReceiver || receiver.Spec.Type == v1beta1.GenericHMACReceiver
+	if receiver.Spec.ParseXML && !generic {
+		return fmt.Errorf("parseXML is not supported by the %s receiver type", receiver.Spec.Type)
+	}
+
+	filter := receiver.Spec.Filter
+	if filter == nil || filter.Condition == "" {
+		return nil
+	}
+	variables := []string{ConditionVariable}
+	switch {
+	case generic:
+		// the generic receivers also expose the request body and headers
+		variables = append(variables, RequestVariable)
+	case receiver.Spec.Type == v1beta1.PubSubReceiver, receiver.Spec.Type == v1beta1.HarborReceiver:
+	default:
+		return fmt.Errorf("the filter condition is not supported by the %s receiver type", receiver.Spec.Type)
+	}
+	if _, err := cel.Compile(filter.Condition, variables...); err != nil {
+		return fmt.Errorf("invalid filter condition: %w", err)
+	}
+	return nil
+}
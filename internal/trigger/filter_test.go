@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestValidateFilter(t *testing.T) {
+	receiver := v1beta1.Receiver{Spec: v1beta1.ReceiverSpec{Type: v1beta1.PubSubReceiver}}
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Filter = &v1beta1.ReceiverFilter{Condition: "message.attributes.status == 'SUCCESS'"}
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Filter.Condition = "event.severity == 'error'"
+	require.Error(t, ValidateFilter(receiver))
+
+	receiver.Spec.Filter.Condition = "message.attributes.status == "
+	require.Error(t, ValidateFilter(receiver))
+
+	receiver.Spec.Type = v1beta1.GenericReceiver
+	receiver.Spec.Filter.Condition = "message.attributes.type.startsWith('dev.cdevents.')"
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Filter.Condition = "request.body.event.type == 'push'"
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.ParseXML = true
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Type = v1beta1.HarborReceiver
+	require.Error(t, ValidateFilter(receiver))
+
+	receiver.Spec.ParseXML = false
+	require.Error(t, ValidateFilter(receiver))
+
+	receiver.Spec.Filter.Condition = "message.tag.startsWith('v')"
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Type = v1beta1.GitHubReceiver
+	receiver.Spec.Filter.Condition = "message.attributes.status == 'SUCCESS'"
+	require.Error(t, ValidateFilter(receiver))
+
+	receiver = v1beta1.Receiver{Spec: v1beta1.ReceiverSpec{Type: v1beta1.DockerHubReceiver}}
+	receiver.Spec.Events = []string{"v1.*", "/^v[0-9]+$/"}
+	require.NoError(t, ValidateFilter(receiver))
+
+	receiver.Spec.Events = []string{"/^v[0-9+$/"}
+	require.Error(t, ValidateFilter(receiver))
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// PushedTagAnnotation is the annotation holding the tag of the image pushed
+	// to the container registry, set on the ImageRepository and ImagePolicy resources.
+	PushedTagAnnotation = "image.toolkit.fluxcd.io/pushed-tag"
+
+	// PushedDigestAnnotation is the annotation holding the digest of the image pushed
+	// to the container registry, set on the ImageRepository and ImagePolicy resources.
+	PushedDigestAnnotation = "image.toolkit.fluxcd.io/pushed-digest"
+)
+
+// imageHintKinds are the kinds of the resources the image hints are set on.
+var imageHintKinds = map[string]bool{
+	"ImageRepository": true,
+	"ImagePolicy":     true,
+}
+
+// ImageHintAnnotations returns the annotations holding the tag and the digest of the
+// pushed image, or nil if the receiver doesn't write the image hints, the resource
+// isn't an image automation object or the receiver type didn't find the image.
+// Both annotations are set, so that a hint of a previous push isn't mixed with this one.
+func ImageHintAnnotations(receiver v1beta1.Receiver, resource v1beta1.CrossNamespaceObjectReference, tag, digest string) map[string]string {
+	if !receiver.Spec.ImageHints || !imageHintKinds[resource.Kind] || (tag == "" && digest == "") {
+		return nil
+	}
+	return map[string]string{
+		PushedTagAnnotation:    tag,
+		PushedDigestAnnotation: digest,
+	}
+}
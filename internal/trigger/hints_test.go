@@ -0,0 +1,41 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestImageHintAnnotations(t *testing.T) {
+	policy := v1beta1.CrossNamespaceObjectReference{Kind: "ImagePolicy", Name: "app"}
+	source := v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "app"}
+
+	receiver := v1beta1.Receiver{}
+	require.Nil(t, ImageHintAnnotations(receiver, policy, "1.0.0", ""))
+
+	receiver.Spec.ImageHints = true
+	require.Equal(t, map[string]string{
+		PushedTagAnnotation:    "1.0.0",
+		PushedDigestAnnotation: "",
+	}, ImageHintAnnotations(receiver, policy, "1.0.0", ""))
+	require.Nil(t, ImageHintAnnotations(receiver, source, "1.0.0", ""))
+	require.Nil(t, ImageHintAnnotations(receiver, policy, "", ""))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+const (
+	// DefaultProvenancePrefix is the prefix of the provenance annotation keys.
+	DefaultProvenancePrefix = "notification.toolkit.fluxcd.io/"
+
+	// ProvenanceReceiverKey is the key suffix of the receiver namespace and name.
+	ProvenanceReceiverKey = "receiver"
+
+	// ProvenanceEventKey is the key suffix of the event type, empty when
+	// the receiver type doesn't send one.
+	ProvenanceEventKey = "event"
+
+	// ProvenanceDigestKey is the key suffix of the SHA-256 digest of the payload.
+	ProvenanceDigestKey = "payload-digest"
+)
+
+// provenancePrefix returns the key prefix of the provenance annotations.
+func provenancePrefix(receiver v1beta1.Receiver) string {
+	if receiver.Spec.Provenance.KeyPrefix == "" {
+		return DefaultProvenancePrefix
+	}
+	return receiver.Spec.Provenance.KeyPrefix
+}
+
+// ProvenanceAnnotations returns the annotations recording the receiver that triggered
+// the reconciliation, the event type and the payload digest, or nil if the receiver
+// doesn't record the provenance.
+func ProvenanceAnnotations(receiver v1beta1.Receiver, event string, payload []byte) map[string]string {
+	if receiver.Spec.Provenance == nil {
+		return nil
+	}
+
+	prefix := provenancePrefix(receiver)
+	return map[string]string{
+		prefix + ProvenanceReceiverKey: fmt.Sprintf("%s/%s", receiver.Namespace, receiver.Name),
+		prefix + ProvenanceEventKey:    event,
+		prefix + ProvenanceDigestKey:   fmt.Sprintf("sha256:%x", sha256.Sum256(payload)),
+	}
+}
+
+// validateProvenance checks that the key prefix forms valid annotation keys.
+func validateProvenance(receiver v1beta1.Receiver) error {
+	if receiver.Spec.Provenance == nil {
+		return nil
+	}
+
+	key := provenancePrefix(receiver) + ProvenanceDigestKey
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid provenance key prefix '%s': %s", receiver.Spec.Provenance.KeyPrefix, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestProvenanceAnnotations(t *testing.T) {
+	receiver := v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "flux-system"},
+	}
+	require.Nil(t, ProvenanceAnnotations(receiver, "push", []byte(`{}`)))
+
+	receiver.Spec.Provenance = &v1beta1.ReceiverProvenance{}
+	require.Equal(t, map[string]string{
+		"notification.toolkit.fluxcd.io/receiver":       "flux-system/github",
+		"notification.toolkit.fluxcd.io/event":          "push",
+		"notification.toolkit.fluxcd.io/payload-digest": "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	}, ProvenanceAnnotations(receiver, "push", []byte(`{}`)))
+
+	receiver.Spec.Provenance.KeyPrefix = "example.com/trigger-"
+	require.Contains(t, ProvenanceAnnotations(receiver, "push", []byte(`{}`)), "example.com/trigger-receiver")
+	require.NoError(t, ValidateAnnotation(receiver))
+
+	receiver.Spec.Provenance.KeyPrefix = "example.com/with/slashes/"
+	require.Error(t, ValidateAnnotation(receiver))
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MatchTag returns true if the image tag matches at least one of the patterns,
+// or if there are no patterns. The patterns are globs, e.g. 'v1.*', or regular
+// expressions when enclosed in slashes, e.g. '/^v[0-9]+\.[0-9]+\.[0-9]+$/'.
+// The invalid patterns match no tag.
+func MatchTag(patterns []string, tag string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, err := matchTag(p, tag); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTagPatterns checks the globs and the regular expressions of the tag patterns.
+func ValidateTagPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := matchTag(p, ""); err != nil {
+			return fmt.Errorf("invalid tag pattern '%s': %w", p, err)
+		}
+	}
+	return nil
+}
+
+func matchTag(pattern, tag string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(tag), nil
+	}
+	return path.Match(pattern, tag)
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchTag(t *testing.T) {
+	require.True(t, MatchTag(nil, "latest"))
+
+	patterns := []string{"v1.*", `/^[0-9]+\.[0-9]+\.[0-9]+$/`}
+	require.True(t, MatchTag(patterns, "v1.2"))
+	require.True(t, MatchTag(patterns, "1.2.0"))
+	require.False(t, MatchTag(patterns, "1.2.0-rc.1"))
+	require.False(t, MatchTag(patterns, "latest"))
+
+	// the invalid patterns match no tag
+	require.False(t, MatchTag([]string{"[", "/(/"}, "["))
+	require.Error(t, ValidateTagPatterns([]string{"["}))
+	require.Error(t, ValidateTagPatterns([]string{"/(/"}))
+	require.NoError(t, ValidateTagPatterns(patterns))
+}
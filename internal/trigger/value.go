@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/cel"
+	"github.com/fluxcd/notification-controller/internal/webhook"
+)
+
+// requestIDHeaders are the delivery ID headers sent by the webhook providers,
+// in order of precedence.
+var requestIDHeaders = []string{
+	"X-GitHub-Delivery",
+	"X-Gitlab-Event-UUID",
+	"X-Gitea-Delivery",
+	"X-Request-UUID",
+	"X-Request-Id",
+}
+
+// DeliveryID returns the delivery ID sent by the webhook provider, or an empty string if there is none.
+func DeliveryID(r *http.Request) string {
+	for _, header := range requestIDHeaders {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// AnnotationKey returns the annotation key set by the receiver.
+func AnnotationKey(receiver v1beta1.Receiver) string {
+	if receiver.Spec.Annotation == nil || receiver.Spec.Annotation.Key == "" {
+		return meta.ReconcileRequestAnnotation
+	}
+	return receiver.Spec.Annotation.Key
+}
+
+// AnnotationValue returns the annotation value set by the receiver for the given request and payload,
+// the programs of the payload expressions are taken from the cache.
+func AnnotationValue(programs *cel.Cache, receiver v1beta1.Receiver, r *http.Request, body []byte, now time.Time) (string, error) {
+	if receiver.Spec.Annotation == nil {
+		return now.String(), nil
+	}
+
+	switch receiver.Spec.Annotation.ValueFrom {
+	case v1beta1.RequestIDAnnotationValue:
+		if id := DeliveryID(r); id != "" {
+			return id, nil
+		}
+		return uuid.New().String(), nil
+	case v1beta1.PayloadAnnotationValue:
+		return evaluate(programs, receiver, receiver.Spec.Annotation.Expression, r.Header.Get("Content-Type"), body)
+	default:
+		return now.String(), nil
+	}
+}
+
+// ResourceAnnotations returns the additional annotations of the receiver,
+// with their values evaluated over the payload.
+func ResourceAnnotations(programs *cel.Cache, receiver v1beta1.Receiver, contentType string, body []byte) (map[string]string, error) {
+	if len(receiver.Spec.ResourceAnnotations) == 0 {
+		return nil, nil
+	}
+
+	annotations := make(map[string]string, len(receiver.Spec.ResourceAnnotations))
+	for key, expression := range receiver.Spec.ResourceAnnotations {
+		value, err := evaluate(programs, receiver, expression, contentType, body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute the annotation '%s': %w", key, err)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// ValidateAnnotation checks the annotation key, the payload expression,
+// the additional annotations and the provenance key prefix of the receiver.
+func ValidateAnnotation(receiver v1beta1.Receiver) error {
+	if err := validateProvenance(receiver); err != nil {
+		return err
+	}
+
+	for key, expression := range receiver.Spec.ResourceAnnotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid resource annotation key '%s': %s", key, strings.Join(errs, ", "))
+		}
+		if _, err := cel.Compile(expression, RequestVariable); err != nil {
+			return fmt.Errorf("invalid resource annotation '%s': %w", key, err)
+		}
+	}
+
+	if receiver.Spec.Annotation == nil {
+		return nil
+	}
+
+	key := AnnotationKey(receiver)
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid annotation key '%s': %s", key, strings.Join(errs, ", "))
+	}
+
+	if receiver.Spec.Annotation.ValueFrom == v1beta1.PayloadAnnotationValue {
+		if receiver.Spec.Annotation.Expression == "" {
+			return fmt.Errorf("an expression is required when the annotation value is taken from the payload")
+		}
+		if _, err := cel.Compile(receiver.Spec.Annotation.Expression, RequestVariable); err != nil {
+			return fmt.Errorf("invalid annotation expression: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// evaluate evaluates the CEL expression with the request holding the decoded
+// payload, the result must not be empty.
+func evaluate(programs *cel.Cache, receiver v1beta1.Receiver, expression, contentType string, body []byte) (string, error) {
+	data, err := webhook.DecodePayload(contentType, body, receiver.Spec.ParseXML)
+	if err != nil {
+		return "", err
+	}
+
+	program, err := Program(programs, receiver, expression)
+	if err != nil {
+		return "", err
+	}
+	value, err := program.EvalString(RequestVariables(data, nil))
+	if err != nil {
+		return "", err
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("annotation expression '%s' returned an empty value", expression)
+	}
+	return value, nil
+}
+
+// RequestVariables returns the request variable of the CEL expressions holding
+// the decoded payload and, when they are known, the request headers.
+func RequestVariables(body interface{}, header http.Header) map[string]interface{} {
+	request := map[string]interface{}{"body": body}
+	if header != nil {
+		headers := make(map[string]string, len(header))
+		for k := range header {
+			headers[strings.ToLower(k)] = header.Get(k)
+		}
+		request["headers"] = headers
+	}
+	return map[string]interface{}{RequestVariable: request}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestAnnotationValue(t *testing.T) {
+	now := time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"ref":"refs/heads/main","head_commit":{"id":"a1b2c3"}}`)
+
+	tests := []struct {
+		name       string
+		annotation *v1beta1.ReceiverAnnotation
+		parseXML   bool
+		header     http.Header
+		body       []byte
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "default timestamp",
+			want: now.String(),
+		},
+		{
+			name:       "GitHub delivery ID",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.RequestIDAnnotationValue},
+			header:     http.Header{"X-Github-Delivery": []string{"72d3162e"}},
+			want:       "72d3162e",
+		},
+		{
+			name:       "payload expression",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.head_commit.id"},
+			want:       "a1b2c3",
+		},
+		{
+			name:       "form-encoded payload expression",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.head_commit.id"},
+			header:     http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			body:       []byte("payload=" + url.QueryEscape(string(payload))),
+			want:       "a1b2c3",
+		},
+		{
+			name:       "XML payload expression",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.event.component.version + '-' + request.body.event['-type']"},
+			parseXML:   true,
+			header:     http.Header{"Content-Type": []string{"application/xml"}},
+			body:       []byte(`<event type="CREATED"><component><version>1.2.0</version></component></event>`),
+			want:       "1.2.0-CREATED",
+		},
+		{
+			name:       "XML payload without parseXML",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.event['-type']"},
+			header:     http.Header{"Content-Type": []string{"application/xml"}},
+			body:       []byte(`<event type="CREATED"></event>`),
+			wantErr:    true,
+		},
+		{
+			name:       "numeric payload expression",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.build.number + 1"},
+			body:       []byte(`{"build":{"number":41}}`),
+			want:       "42",
+		},
+		{
+			name:       "missing payload field",
+			annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue, Expression: "request.body.after"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := v1beta1.Receiver{Spec: v1beta1.ReceiverSpec{Annotation: tt.annotation, ParseXML: tt.parseXML}}
+			r := httptest.NewRequest(http.MethodPost, "/hook/", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+
+			body := payload
+			if tt.body != nil {
+				body = tt.body
+			}
+
+			value, err := AnnotationValue(nil, receiver, r, body, now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, value)
+		})
+	}
+}
+
+func TestAnnotationValue_GeneratedRequestID(t *testing.T) {
+	receiver := v1beta1.Receiver{Spec: v1beta1.ReceiverSpec{
+		Annotation: &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.RequestIDAnnotationValue},
+	}}
+	r := httptest.NewRequest(http.MethodPost, "/hook/", nil)
+
+	value, err := AnnotationValue(nil, receiver, r, nil, time.Now())
+	require.NoError(t, err)
+	require.Len(t, value, 36)
+}
+
+func TestValidateAnnotation(t *testing.T) {
+	receiver := v1beta1.Receiver{}
+	require.NoError(t, ValidateAnnotation(receiver))
+	require.Equal(t, meta.ReconcileRequestAnnotation, AnnotationKey(receiver))
+
+	receiver.Spec.Annotation = &v1beta1.ReceiverAnnotation{Key: "example.com/revision"}
+	require.NoError(t, ValidateAnnotation(receiver))
+	require.Equal(t, "example.com/revision", AnnotationKey(receiver))
+
+	receiver.Spec.Annotation.Key = "not a key"
+	require.Error(t, ValidateAnnotation(receiver))
+
+	receiver.Spec.Annotation = &v1beta1.ReceiverAnnotation{ValueFrom: v1beta1.PayloadAnnotationValue}
+	require.Error(t, ValidateAnnotation(receiver))
+
+	receiver.Spec.Annotation.Expression = "request.body.head_commit.id +"
+	require.Error(t, ValidateAnnotation(receiver))
+}
+
+func TestResourceAnnotations(t *testing.T) {
+	receiver := v1beta1.Receiver{}
+	annotations, err := ResourceAnnotations(nil, receiver, "", nil)
+	require.NoError(t, err)
+	require.Nil(t, annotations)
+
+	receiver.Spec.ResourceAnnotations = map[string]string{
+		meta.ReconcileRequestAnnotation: "request.body.push_data.tag",
+		"example.com/image":             "request.body.repository.repo_name + ':' + request.body.push_data.tag",
+	}
+	payload := []byte(`{"push_data":{"tag":"1.2.0"},"repository":{"repo_name":"org/webapp"}}`)
+	annotations, err = ResourceAnnotations(nil, receiver, "application/json", payload)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		meta.ReconcileRequestAnnotation: "1.2.0",
+		"example.com/image":             "org/webapp:1.2.0",
+	}, annotations)
+
+	_, err = ResourceAnnotations(nil, receiver, "application/json", []byte(`{"push_data":{}}`))
+	require.Error(t, err)
+}
+
+func TestValidateAnnotation_ResourceAnnotations(t *testing.T) {
+	receiver := v1beta1.Receiver{}
+	receiver.Spec.ResourceAnnotations = map[string]string{"example.com/tag": "request.body.push_data.tag"}
+	require.NoError(t, ValidateAnnotation(receiver))
+
+	receiver.Spec.ResourceAnnotations = map[string]string{"not a key": "request.body.push_data.tag"}
+	require.Error(t, ValidateAnnotation(receiver))
+
+	receiver.Spec.ResourceAnnotations = map[string]string{"example.com/tag": "message.push_data.tag"}
+	require.Error(t, ValidateAnnotation(receiver))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrPayloadTooLarge is returned when the decompressed request body exceeds the size limit.
+var ErrPayloadTooLarge = errors.New("the payload exceeds the size limit")
+
+// ReadBody reads the request body up to the given number of bytes,
+// decompressing it according to the Content-Encoding header.
+// The size limit applies to the decompressed body.
+func ReadBody(r *http.Request, limit int64) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return readLimited(r.Body, limit)
+	}
+
+	compressed, err := readLimited(r.Body, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress gzip payload: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		// the deflate content coding is zlib wrapped, some senders omit the wrapper
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			reader = flate.NewReader(bytes.NewReader(compressed))
+		} else {
+			defer zr.Close()
+			reader = zr
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+
+	body, err := readLimited(reader, limit)
+	if err != nil {
+		if errors.Is(err, ErrPayloadTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to decompress %s payload: %w", encoding, err)
+	}
+	return body, nil
+}
+
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrPayloadTooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		w = fw
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReadBody(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		limit    int64
+		want     []byte
+		wantErr  error
+	}{
+		{name: "identity", body: payload, limit: 1024, want: payload},
+		{name: "gzip", encoding: "gzip", body: compress(t, "gzip", payload), limit: 1024, want: payload},
+		{name: "deflate", encoding: "deflate", body: compress(t, "zlib", payload), limit: 1024, want: payload},
+		{name: "raw deflate", encoding: "deflate", body: compress(t, "flate", payload), limit: 1024, want: payload},
+		{name: "too large", body: payload, limit: 8, wantErr: ErrPayloadTooLarge},
+		{
+			name:     "decompression bomb",
+			encoding: "gzip",
+			body:     compress(t, "gzip", []byte(strings.Repeat("0", 1<<20))),
+			limit:    1 << 16,
+			wantErr:  ErrPayloadTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/hook/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			got, err := ReadBody(r, tt.limit)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/hook/", bytes.NewReader(payload))
+	r.Header.Set("Content-Encoding", "br")
+	_, err := ReadBody(r, 1024)
+	require.Error(t, err)
+
+	r = httptest.NewRequest(http.MethodPost, "/hook/", bytes.NewReader(payload))
+	r.Header.Set("Content-Encoding", "gzip")
+	_, err = ReadBody(r, 1024)
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	formContentType    = "application/x-www-form-urlencoded"
+	ndjsonContentType  = "application/x-ndjson"
+	jsonlContentType   = "application/jsonl"
+	xmlContentType     = "application/xml"
+	textXMLContentType = "text/xml"
+
+	// formPayloadField is the form field holding the JSON document
+	// sent by GitHub, Bitbucket POST services and other legacy senders.
+	formPayloadField = "payload"
+)
+
+// DecodePayload parses a webhook request body according to its content type:
+//
+//   - a form-encoded body with a 'payload' field is decoded from the JSON document
+//     held by the field, other form-encoded bodies are decoded to a map of fields
+//   - a newline-delimited JSON body is decoded to a list of documents
+//   - an XML body is decoded to a map keyed by the root element name when
+//     parseXML is set, and rejected otherwise
+//   - any other body is decoded as a JSON document, if it holds more
+//     than one document, it is decoded to a list of documents
+func DecodePayload(contentType string, body []byte, parseXML bool) (interface{}, error) {
+	switch mediaType(contentType) {
+	case formContentType:
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse form-encoded payload: %w", err)
+		}
+		if _, ok := form[formPayloadField]; ok {
+			return decodeJSON([]byte(form.Get(formPayloadField)))
+		}
+		fields := make(map[string]interface{}, len(form))
+		for k := range form {
+			fields[k] = form.Get(k)
+		}
+		return fields, nil
+	case xmlContentType, textXMLContentType:
+		if !parseXML {
+			return nil, fmt.Errorf("the XML payloads are only decoded when the receiver has parseXML set")
+		}
+		return decodeXML(body)
+	case ndjsonContentType, jsonlContentType:
+		docs, err := decodeJSONStream(body)
+		if err != nil {
+			return nil, err
+		}
+		return docs, nil
+	default:
+		return decodeJSON(body)
+	}
+}
+
+// PayloadJSON returns the JSON document of a webhook request body, the document
+// is extracted from the 'payload' field of a form-encoded body.
+func PayloadJSON(contentType string, body []byte) ([]byte, error) {
+	if mediaType(contentType) != formContentType {
+		return body, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse form-encoded payload: %w", err)
+	}
+	if _, ok := form[formPayloadField]; !ok {
+		return nil, fmt.Errorf("the form-encoded payload has no '%s' field", formPayloadField)
+	}
+	return []byte(form.Get(formPayloadField)), nil
+}
+
+// mediaType returns the media type of the content type,
+// the structured syntax suffix '+xml' is reduced to 'application/xml'.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if strings.HasSuffix(mt, "+xml") {
+		return xmlContentType
+	}
+	return mt
+}
+
+// decodeJSON decodes a JSON document, or a list when the body holds several documents.
+func decodeJSON(body []byte) (interface{}, error) {
+	docs, err := decodeJSONStream(body)
+	if err != nil {
+		return nil, err
+	}
+	switch len(docs) {
+	case 0:
+		return nil, fmt.Errorf("the payload is empty")
+	case 1:
+		return docs[0], nil
+	default:
+		return docs, nil
+	}
+}
+
+func decodeJSONStream(body []byte) ([]interface{}, error) {
+	var docs []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to decode the payload as JSON: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePayload(t *testing.T) {
+	form := url.Values{"payload": []string{`{"repository":{"name":"webapp"}}`}}.Encode()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        interface{}
+		wantErr     bool
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"ref":"refs/heads/main"}`,
+			want:        map[string]interface{}{"ref": "refs/heads/main"},
+		},
+		{
+			name:        "form-encoded payload field",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			body:        form,
+			want:        map[string]interface{}{"repository": map[string]interface{}{"name": "webapp"}},
+		},
+		{
+			name:        "form-encoded fields",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "ref=refs%2Fheads%2Fmain&user=jdoe",
+			want:        map[string]interface{}{"ref": "refs/heads/main", "user": "jdoe"},
+		},
+		{
+			name:        "newline-delimited JSON",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\n{\"id\":2}\n",
+			want:        []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}},
+		},
+		{
+			name:        "single document stream",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\n",
+			want:        []interface{}{map[string]interface{}{"id": float64(1)}},
+		},
+		{
+			name: "concatenated JSON without content type",
+			body: "{\"id\":1}\n{\"id\":2}",
+			want: []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}},
+		},
+		{
+			name:        "invalid JSON",
+			contentType: "application/json",
+			body:        `{"id":`,
+			wantErr:     true,
+		},
+		{
+			name:        "empty body",
+			contentType: "application/json",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePayload(tt.contentType, []byte(tt.body), false)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPayloadJSON(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	got, err := PayloadJSON("application/json", body)
+	require.NoError(t, err)
+	require.Equal(t, body, got)
+
+	form := url.Values{"payload": []string{`{"id":1}`}}.Encode()
+	got, err = PayloadJSON("application/x-www-form-urlencoded", []byte(form))
+	require.NoError(t, err)
+	require.Equal(t, body, got)
+
+	_, err = PayloadJSON("application/x-www-form-urlencoded", []byte("id=1"))
+	require.Error(t, err)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// xmlAttributePrefix is prepended to the attribute names of an element.
+	xmlAttributePrefix = "-"
+
+	// xmlTextKey holds the text of an element that has attributes or children.
+	xmlTextKey = "#text"
+)
+
+// decodeXML converts an XML document to a map keyed by the root element name.
+// An element with neither attributes nor children is converted to its text,
+// other elements are converted to a map holding the attributes prefixed with '-',
+// the children and the text under '#text'. Repeated children are converted to a list.
+func decodeXML(body []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("the payload is empty")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the payload as XML: %w", err)
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode the payload as XML: %w", err)
+			}
+			return map[string]interface{}{start.Name.Local: root}, nil
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		fields[xmlAttributePrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := fields[name].(type) {
+			case nil:
+				fields[name] = child
+			case []interface{}:
+				fields[name] = append(existing, child)
+			default:
+				fields[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return value, nil
+			}
+			if value != "" {
+				fields[xmlTextKey] = value
+			}
+			return fields, nil
+		}
+	}
+}
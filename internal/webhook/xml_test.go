@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePayload_XML(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<event type="CREATED">
+  <repositoryName>releases</repositoryName>
+  <component format="maven2">
+    <name>webapp</name>
+    <version>1.2.0</version>
+  </component>
+  <tag>stable</tag>
+  <tag>latest</tag>
+  <note lang="en">Deployed</note>
+</event>`
+
+	got, err := DecodePayload("text/xml; charset=utf-8", []byte(body), true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"event": map[string]interface{}{
+			"-type":          "CREATED",
+			"repositoryName": "releases",
+			"component": map[string]interface{}{
+				"-format": "maven2",
+				"name":    "webapp",
+				"version": "1.2.0",
+			},
+			"tag": []interface{}{"stable", "latest"},
+			"note": map[string]interface{}{
+				"-lang": "en",
+				"#text": "Deployed",
+			},
+		},
+	}, got)
+
+	got, err = DecodePayload("application/atom+xml", []byte(`<feed><id>1</id></feed>`), true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"feed": map[string]interface{}{"id": "1"}}, got)
+
+	_, err = DecodePayload("application/xml", []byte(`<event><name>webapp</event>`), true)
+	require.Error(t, err)
+
+	_, err = DecodePayload("application/xml", nil, true)
+	require.Error(t, err)
+
+	// the XML payloads are opt-in
+	_, err = DecodePayload("application/xml", []byte(`<feed><id>1</id></feed>`), false)
+	require.Error(t, err)
+}
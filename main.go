@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/slok/go-http-metrics/middleware"
 	flag "github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -34,12 +36,16 @@ import (
 	"github.com/fluxcd/pkg/runtime/leaderelection"
 	"github.com/fluxcd/pkg/runtime/logger"
 	"github.com/fluxcd/pkg/runtime/metrics"
-	"github.com/fluxcd/pkg/runtime/pprof"
 	"github.com/fluxcd/pkg/runtime/probes"
 
 	"github.com/fluxcd/notification-controller/api/v1beta1"
 	"github.com/fluxcd/notification-controller/controllers"
+	"github.com/fluxcd/notification-controller/internal/egress"
+	"github.com/fluxcd/notification-controller/internal/notifier"
+	"github.com/fluxcd/notification-controller/internal/profiling"
 	"github.com/fluxcd/notification-controller/internal/server"
+	"github.com/fluxcd/notification-controller/internal/templates"
+	"github.com/fluxcd/notification-controller/internal/tlsconfig"
 	"github.com/sethvargo/go-limiter/memorystore"
 	// +kubebuilder:scaffold:imports
 )
@@ -67,7 +73,29 @@ func main() {
 		concurrent            int
 		watchAllNamespaces    bool
 		rateLimitInterval     time.Duration
+		heartbeatInterval     time.Duration
+		healthEventsInterval  time.Duration
+		healthEventsThreshold int
+		idempotencyWindow     time.Duration
+		replayWindow          time.Duration
+		receiverMaxInFlight   int
+		receiverShedCooldown  time.Duration
+		trustedProxies        []string
+		receiverClientCerts   string
+		asyncWorkers          int
+		asyncQueueSize        int
+		receiverHistorySize   int
+		readinessAPITimeout   time.Duration
+		dispatchWorkers       int
+		dispatchQueueSize     int
+		eventMetadata         map[string]string
+		eventMetadataCM       string
+		egressAllowlist       []string
+		egressBlocklist       []string
+		crossNSTemplates      bool
 		clientOptions         client.Options
+		tlsOptions            tlsconfig.Options
+		profilingOptions      profiling.Options
 		logOptions            logger.Options
 		leaderElectionOptions leaderelection.Options
 	)
@@ -75,12 +103,71 @@ func main() {
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&eventsAddr, "events-addr", ":9090", "The address the event endpoint binds to.")
 	flag.StringVar(&healthAddr, "health-addr", ":9440", "The address the health endpoint binds to.")
+	flag.DurationVar(&readinessAPITimeout, "readiness-api-timeout", 0,
+		"Experimental, the timeout of the readiness check listing the receivers from the Kubernetes API server, "+
+			"the replica isn't ready while the check fails, its liveness isn't affected. Disabled when set to zero.")
 	flag.StringVar(&receiverAddr, "receiverAddr", ":9292", "The address the webhook receiver endpoint binds to.")
 	flag.IntVar(&concurrent, "concurrent", 4, "The number of concurrent notification reconciles.")
 	flag.BoolVar(&watchAllNamespaces, "watch-all-namespaces", true,
 		"Watch for custom resources in all namespaces, if set to false it will only watch the runtime namespace.")
 	flag.DurationVar(&rateLimitInterval, "rate-limit-interval", 5*time.Minute, "Interval in which rate limit has effect.")
+	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", 0,
+		"Interval at which heartbeat events are sent to the alerts with heartbeats enabled, disabled when set to zero.")
+	flag.DurationVar(&healthEventsInterval, "health-events-interval", 5*time.Minute,
+		"Interval at which the sustained problems of the controller are sent to the alerts with health events enabled, "+
+			"disabled when set to zero.")
+	flag.IntVar(&healthEventsThreshold, "health-events-threshold", 10,
+		"The number of occurrences of a problem within the health events interval after which it is reported.")
+	flag.IntVar(&dispatchWorkers, "dispatch-workers", 0,
+		"The number of workers sending the notifications, the error notifications are sent first. "+
+			"When set to zero, each notification is sent in its own goroutine.")
+	flag.IntVar(&dispatchQueueSize, "dispatch-queue-size", 10000,
+		"The maximum number of info notifications, and of error notifications, waiting for a dispatch worker, "+
+			"the notifications are discarded when the queue is full.")
+	flag.StringToStringVar(&eventMetadata, "event-metadata", nil,
+		"The metadata added to every notification, e.g. 'cluster=production,node=$(NODE_NAME)'. "+
+			"The metadata of the events and of the alerts takes precedence.")
+	flag.StringVar(&eventMetadataCM, "event-metadata-configmap", "",
+		"The name of the ConfigMap in the controller namespace whose data is added to the metadata of every notification, "+
+			"the values can reference the environment variables of the controller with '${NAME}'. "+
+			"It takes precedence over --event-metadata.")
+	flag.DurationVar(&idempotencyWindow, "receiver-idempotency-window", 0,
+		"Window in which the webhook deliveries retried with the same delivery ID are ignored, disabled when set to zero.")
+	flag.DurationVar(&replayWindow, "receiver-replay-window", 0,
+		"Window outside of which the signed webhook timestamps are rejected, and within which the replays of the signed "+
+			"webhook requests are rejected, disabled when set to zero.")
+	flag.IntVar(&receiverMaxInFlight, "receiver-max-inflight", 100,
+		"The maximum number of webhook requests handled concurrently, the requests above it are rejected with a 503. "+
+			"Unlimited when set to zero.")
+	flag.DurationVar(&receiverShedCooldown, "receiver-shed-cooldown", 30*time.Second,
+		"Duration for which the webhook requests are rejected with a 503 after the API server throttled the controller, "+
+			"disabled when set to zero.")
+	flag.StringSliceVar(&trustedProxies, "receiver-trusted-proxies", nil,
+		"The CIDRs of the proxies in front of the webhook receiver, whose X-Forwarded-For header is honoured "+
+			"to find the source address of the requests. The header is ignored when empty.")
+	flag.StringVar(&receiverClientCerts, "receiver-client-certs", server.ClientCertsNone,
+		"The client certificate mode of the webhook receiver, one of 'none', 'request' or 'require'. The certificates "+
+			"are verified with the CA of the receivers certSecretRef, 'require' rejects the requests without a certificate.")
+	flag.IntVar(&asyncWorkers, "receiver-async-workers", 4,
+		"The number of workers annotating the resources of the receivers with spec.async. "+
+			"When set to zero, the asynchronous receivers are handled synchronously.")
+	flag.IntVar(&asyncQueueSize, "receiver-async-queue-size", 1000,
+		"The maximum number of asynchronous webhook requests waiting for a worker, the requests are rejected "+
+			"with a 503 when the queue is full. Unlimited when set to zero.")
+	flag.IntVar(&receiverHistorySize, "receiver-history-size", 10,
+		"The number of verified webhook requests recorded in the status of the receivers, "+
+			"the status isn't updated on every request when set to zero.")
+	flag.StringSliceVar(&egressAllowlist, "provider-egress-allowlist", nil,
+		"The hostnames and CIDRs the providers and the OIDC issuers of the receivers are permitted to contact, "+
+			"all addresses are permitted when empty.")
+	flag.StringSliceVar(&egressBlocklist, "provider-egress-blocklist", egress.DefaultBlocklist,
+		"The CIDRs the providers and the OIDC issuers of the receivers are not permitted to contact, defaults to the link-local and cloud metadata addresses. "+
+			"Set to an empty string to permit them.")
+	flag.BoolVar(&crossNSTemplates, "allow-cross-namespace-templates", false,
+		"Allow the alerts and providers to reference the template ConfigMaps of another namespace.")
 	clientOptions.BindFlags(flag.CommandLine)
+	tlsOptions.BindFlags(flag.CommandLine)
+	profilingOptions.BindFlags(flag.CommandLine)
 	logOptions.BindFlags(flag.CommandLine)
 	leaderElectionOptions.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -91,6 +178,27 @@ func main() {
 	metricsRecorder := metrics.NewRecorder()
 	crtlmetrics.Registry.MustRegister(metricsRecorder.Collectors()...)
 
+	egressPolicy, err := egress.ParsePolicy(egressAllowlist, egressBlocklist)
+	if err != nil {
+		setupLog.Error(err, "invalid provider egress policy")
+		os.Exit(1)
+	}
+
+	if err := profilingOptions.Apply(); err != nil {
+		setupLog.Error(err, "invalid runtime tuning options")
+		os.Exit(1)
+	}
+
+	tlsConfig, err := tlsOptions.Config()
+	if err != nil {
+		setupLog.Error(err, "invalid TLS options")
+		os.Exit(1)
+	}
+	notifierTransport := &notifier.Transport{
+		TLSConfig:    tlsConfig,
+		EgressPolicy: egressPolicy,
+	}
+
 	watchNamespace := ""
 	if !watchAllNamespaces {
 		watchNamespace = os.Getenv("RUNTIME_NAMESPACE")
@@ -117,20 +225,28 @@ func main() {
 	}
 
 	probes.SetupChecks(mgr, setupLog)
-	pprof.SetupHandlers(mgr, setupLog)
+	if readinessAPITimeout > 0 {
+		if err := mgr.AddReadyzCheck("kube-api", server.APIServerCheck(mgr.GetAPIReader(), watchNamespace, readinessAPITimeout)); err != nil {
+			setupLog.Error(err, "unable to create ready check")
+			os.Exit(1)
+		}
+	}
 
 	if err = (&controllers.ProviderReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		MetricsRecorder: metricsRecorder,
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		MetricsRecorder:              metricsRecorder,
+		EgressPolicy:                 egressPolicy,
+		AllowCrossNamespaceTemplates: crossNSTemplates,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Provider")
 		os.Exit(1)
 	}
 	if err = (&controllers.AlertReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		MetricsRecorder: metricsRecorder,
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		MetricsRecorder:              metricsRecorder,
+		AllowCrossNamespaceTemplates: crossNSTemplates,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Alert")
 		os.Exit(1)
@@ -143,8 +259,77 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Receiver")
 		os.Exit(1)
 	}
+	if err = (&controllers.MaintenanceWindowReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MaintenanceWindow")
+		os.Exit(1)
+	}
+	if err = (&controllers.ProviderBindingReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProviderBinding")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
+	messageTemplates := templates.NewLibrary(crossNSTemplates)
+
+	providers := server.NewProviders(mgr.GetClient(), notifierTransport)
+
+	if heartbeatInterval > 0 {
+		if err = mgr.Add(server.NewHeartbeat(heartbeatInterval, log, mgr.GetClient(), providers, messageTemplates)); err != nil {
+			setupLog.Error(err, "unable to add heartbeat")
+			os.Exit(1)
+		}
+	}
+
+	var healthEvents *server.HealthEvents
+	if healthEventsInterval > 0 {
+		healthEvents = server.NewHealthEvents(healthEventsInterval, healthEventsThreshold,
+			os.Getenv("RUNTIME_NAMESPACE"), log, mgr.GetClient(), providers, messageTemplates)
+		if err = mgr.Add(healthEvents); err != nil {
+			setupLog.Error(err, "unable to add health events")
+			os.Exit(1)
+		}
+	}
+
+	specValidator := controllers.NewSpecValidator(mgr.GetClient(), egressPolicy, crossNSTemplates,
+		mgr.GetEventRecorderFor("notification-controller"), log)
+	crtlmetrics.Registry.MustRegister(specValidator.Collectors()...)
+	if err = mgr.Add(specValidator); err != nil {
+		setupLog.Error(err, "unable to add spec validator")
+		os.Exit(1)
+	}
+
+	debugHandlers, err := profilingOptions.Handlers(map[string]http.Handler{
+		server.ProviderCapturePath:     providers.CaptureHandler(),
+		controllers.SpecValidationPath: specValidator,
+	})
+	if err != nil {
+		setupLog.Error(err, "invalid profiling options")
+		os.Exit(1)
+	}
+	for path, handler := range debugHandlers {
+		if err := mgr.AddMetricsExtraHandler(path, handler); err != nil {
+			setupLog.Error(err, "unable to add debug handler")
+		}
+	}
+
+	statusBoards := server.NewStatusBoards(log, mgr.GetClient(), providers)
+	if err = mgr.Add(statusBoards); err != nil {
+		setupLog.Error(err, "unable to add status boards")
+		os.Exit(1)
+	}
+
+	archives := server.NewArchives(log, mgr.GetClient(), providers, healthEvents)
+	if err = mgr.Add(archives); err != nil {
+		setupLog.Error(err, "unable to add archives")
+		os.Exit(1)
+	}
+
 	ctx := ctrl.SetupSignalHandler()
 	store, err := memorystore.New(&memorystore.Config{
 		Interval: rateLimitInterval,
@@ -157,11 +342,44 @@ func main() {
 			Registry: crtlmetrics.Registry,
 		}),
 	})
-	eventServer := server.NewEventServer(eventsAddr, log, mgr.GetClient())
+	eventServer := server.NewEventServer(eventsAddr, log, mgr.GetClient(), dispatchWorkers, dispatchQueueSize)
+	eventServer.WithTLS(tlsConfig, tlsOptions.CertFile, tlsOptions.KeyFile)
+	eventServer.WithProviders(providers)
+	eventServer.WithStatusBoards(statusBoards)
+	eventServer.WithArchives(archives)
+	eventServer.WithHealthEvents(healthEvents)
+	eventServer.WithTemplates(messageTemplates)
+	eventServer.WithEventMetadata(eventMetadata, types.NamespacedName{
+		Namespace: os.Getenv("RUNTIME_NAMESPACE"),
+		Name:      eventMetadataCM,
+	})
 	go eventServer.ListenAndServe(ctx.Done(), eventMdlw, store)
 
 	setupLog.Info("starting webhook receiver server", "addr", receiverAddr)
-	receiverServer := server.NewReceiverServer(receiverAddr, log, mgr.GetClient())
+	receiverMetrics := server.NewReceiverMetrics()
+	crtlmetrics.Registry.MustRegister(receiverMetrics.Collectors()...)
+	receiverClient, err := server.NewReceiverClient(restConfig, mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create receiver client")
+		os.Exit(1)
+	}
+	receiverServer := server.NewReceiverServer(receiverAddr, log, receiverClient, receiverMetrics, idempotencyWindow)
+	receiverServer.WithTLS(tlsConfig, tlsOptions.CertFile, tlsOptions.KeyFile)
+	receiverServer.WithProviders(providers)
+	receiverServer.WithEgressPolicy(egressPolicy)
+	receiverServer.WithLoadShedding(receiverMaxInFlight, receiverShedCooldown)
+	receiverServer.WithReplayProtection(replayWindow)
+	receiverServer.WithAsyncWorkers(asyncWorkers, asyncQueueSize)
+	receiverServer.WithEventPublisher(eventServer)
+	receiverServer.WithTriggerHistory(receiverHistorySize)
+	if err := receiverServer.WithTrustedProxies(trustedProxies); err != nil {
+		setupLog.Error(err, "invalid receiver trusted proxies")
+		os.Exit(1)
+	}
+	if err := receiverServer.WithClientCertificates(receiverClientCerts); err != nil {
+		setupLog.Error(err, "invalid receiver client certificates mode")
+		os.Exit(1)
+	}
 	receiverMdlw := middleware.New(middleware.Config{
 		Recorder: prommetrics.NewRecorder(prommetrics.Config{
 			Prefix:   "gotk_receiver",
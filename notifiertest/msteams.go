@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifiertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+// msTeamsMaxPayloadSize is the size limit of the connector messages.
+const msTeamsMaxPayloadSize = 28 << 10
+
+var messageCardContext = regexp.MustCompile(`^https?://schema\.org/extensions$`)
+
+type messageCard struct {
+	Type       string               `json:"@type"`
+	Context    string               `json:"@context"`
+	ThemeColor string               `json:"themeColor"`
+	Summary    string               `json:"summary"`
+	Text       string               `json:"text"`
+	Sections   []messageCardSection `json:"sections"`
+}
+
+type messageCardSection struct {
+	ActivityTitle    string            `json:"activityTitle"`
+	ActivitySubtitle string            `json:"activitySubtitle"`
+	Text             string            `json:"text"`
+	Facts            []messageCardFact `json:"facts"`
+}
+
+type messageCardFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewMSTeamsServer returns a fake Microsoft Teams incoming webhook, it
+// rejects the payloads that aren't valid message cards, or that exceed
+// the size limit of the connectors.
+func NewMSTeamsServer() *Server {
+	return newServer("1", validateMSTeams)
+}
+
+func validateMSTeams(header http.Header, body []byte) *rejection {
+	if len(body) > msTeamsMaxPayloadSize {
+		return reject(http.StatusRequestEntityTooLarge,
+			"Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 413")
+	}
+	if !isJSON(header) {
+		return reject(http.StatusBadRequest, "Invalid webhook request - Content-Type must be application/json")
+	}
+	var card messageCard
+	if err := json.Unmarshal(body, &card); err != nil {
+		return reject(http.StatusBadRequest, "Invalid webhook request - Payload is not valid JSON")
+	}
+
+	if card.Type != "MessageCard" {
+		return reject(http.StatusBadRequest, "Invalid webhook request - @type must be MessageCard")
+	}
+	if !messageCardContext.MatchString(card.Context) {
+		return reject(http.StatusBadRequest, "Invalid webhook request - @context must be schema.org/extensions")
+	}
+	if card.Summary == "" && card.Text == "" {
+		return reject(http.StatusBadRequest, "Summary or Text is required.")
+	}
+	if card.ThemeColor != "" && !hexColor.MatchString(card.ThemeColor) {
+		return reject(http.StatusBadRequest, "Invalid webhook request - themeColor must be a hex color")
+	}
+	for _, section := range card.Sections {
+		for _, fact := range section.Facts {
+			if fact.Name == "" {
+				return reject(http.StatusBadRequest, "Invalid webhook request - fact name is required")
+			}
+		}
+	}
+	return nil
+}
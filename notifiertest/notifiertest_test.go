@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifiertest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/runtime/events"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func testEvent() events.Event {
+	return events.Event{
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "GitRepository",
+			Namespace: "gitops-system",
+			Name:      "webapp",
+		},
+		Severity:            events.EventSeverityInfo,
+		Message:             "Fetched revision: main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1",
+		Reason:              "info",
+		ReportingController: "source-controller",
+		Metadata:            map[string]string{"revision": "main/731f7eaddfb6af01cb2173e18f0f75b0ba780ef1"},
+	}
+}
+
+func TestSlackServer(t *testing.T) {
+	s := NewSlackServer()
+	defer s.Close()
+
+	require.NoError(t, Post("slack", s, testEvent(), `{{ .InvolvedObject.Name }} at {{ index .Metadata "revision" | shortSHA }}`))
+	require.Len(t, s.Received(), 1)
+	require.Contains(t, string(s.Received()[0].Body), "webapp at 731f7ea")
+
+	err := Post("slack", s, testEvent(), strings.Repeat("x", slackMaxTextLength+1))
+	require.EqualError(t, err, "400 msg_too_long")
+	require.Len(t, s.Errors(), 1)
+
+	_, err = http.Post(s.URL, "application/json", strings.NewReader(`{"channel":"general"}`))
+	require.NoError(t, err)
+	require.EqualError(t, s.Errors()[1], "400 no_text")
+}
+
+func TestMSTeamsServer(t *testing.T) {
+	s := NewMSTeamsServer()
+	defer s.Close()
+
+	require.NoError(t, Post("msteams", s, testEvent(), ""))
+
+	err := Post("msteams", s, testEvent(), strings.Repeat("x", msTeamsMaxPayloadSize))
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "413 "))
+
+	_, err = http.Post(s.URL, "application/json", strings.NewReader(`{"@type":"MessageCard","@context":"https://schema.org/extensions"}`))
+	require.NoError(t, err)
+	require.EqualError(t, s.Errors()[1], "400 Summary or Text is required.")
+}
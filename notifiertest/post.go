@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifiertest
+
+import (
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/events"
+
+	"github.com/fluxcd/notification-controller/internal/notifier"
+	"github.com/fluxcd/notification-controller/internal/templates"
+)
+
+// Post renders the event message with the message template, if not empty,
+// and sends the event with the notifier of the provider type to the server.
+// It returns the validation error of the payload, if any.
+func Post(providerType string, s *Server, event events.Event, messageTemplate string) error {
+	if messageTemplate != "" {
+		tmpl, err := templates.Parse("message", messageTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid message template: %w", err)
+		}
+		if event.Message, err = templates.Render(tmpl, event); err != nil {
+			return err
+		}
+	}
+
+	n, err := notifier.NewFactory(s.URL, "", "", "general", "", nil).Notifier(providerType)
+	if err != nil {
+		return err
+	}
+
+	sent := len(s.Received())
+	if err := n.Post(event); err != nil {
+		return err
+	}
+	received := s.Received()
+	if len(received) == sent {
+		return fmt.Errorf("the %s notifier sent no request", providerType)
+	}
+	return received[len(received)-1].Err
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notifiertest provides fake provider endpoints validating the
+// payloads they receive against the rules of the real services, so that
+// the message templates can be tested end to end without a workspace.
+package notifiertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Received is a request received by a fake server.
+type Received struct {
+	Header http.Header
+	Body   []byte
+
+	// Err is the validation error, nil if the payload was accepted.
+	Err error
+}
+
+// Server is a fake provider endpoint, the payloads failing the validation
+// are rejected with the status code and the error of the real service.
+type Server struct {
+	*httptest.Server
+
+	validate func(header http.Header, body []byte) *rejection
+	accepted string
+
+	mu       sync.Mutex
+	received []Received
+}
+
+// rejection is the response of the service to an invalid payload.
+type rejection struct {
+	code    int
+	message string
+}
+
+func (r *rejection) Error() string {
+	return fmt.Sprintf("%d %s", r.code, r.message)
+}
+
+func reject(code int, format string, args ...interface{}) *rejection {
+	return &rejection{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func newServer(accepted string, validate func(http.Header, []byte) *rejection) *Server {
+	s := &Server{
+		validate: validate,
+		accepted: accepted,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	received := Received{Header: r.Header.Clone(), Body: body}
+	var rejected *rejection
+	switch {
+	case r.Method != http.MethodPost:
+		rejected = reject(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	default:
+		rejected = s.validate(r.Header, body)
+	}
+
+	s.mu.Lock()
+	if rejected != nil {
+		received.Err = rejected
+	}
+	s.received = append(s.received, received)
+	s.mu.Unlock()
+
+	if rejected != nil {
+		http.Error(w, rejected.message, rejected.code)
+		return
+	}
+	_, _ = w.Write([]byte(s.accepted))
+}
+
+// Received returns the requests received by the server.
+func (s *Server) Received() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Received(nil), s.received...)
+}
+
+// Errors returns the validation errors of the rejected payloads.
+func (s *Server) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, r := range s.received {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// isJSON returns true if the content type is application/json.
+func isJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifiertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	slackMaxTextLength  = 40000
+	slackMaxAttachments = 100
+)
+
+var hexColor = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+type slackMessage struct {
+	Channel     *string           `json:"channel"`
+	Username    *string           `json:"username"`
+	Text        string            `json:"text"`
+	Blocks      []json.RawMessage `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Fallback string       `json:"fallback"`
+	Text     string       `json:"text"`
+	Pretext  string       `json:"pretext"`
+	Title    string       `json:"title"`
+	Fields   []slackField `json:"fields"`
+	MrkdwnIn []string     `json:"mrkdwn_in"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// NewSlackServer returns a fake Slack incoming webhook, it rejects the
+// messages without text, too long or with malformed attachments with
+// the errors returned by Slack.
+func NewSlackServer() *Server {
+	return newServer("ok", validateSlack)
+}
+
+func validateSlack(header http.Header, body []byte) *rejection {
+	if !isJSON(header) {
+		return reject(http.StatusBadRequest, "invalid_payload")
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return reject(http.StatusBadRequest, "invalid_payload")
+	}
+
+	if msg.Text == "" && len(msg.Blocks) == 0 && len(msg.Attachments) == 0 {
+		return reject(http.StatusBadRequest, "no_text")
+	}
+	if utf8.RuneCountInString(msg.Text) > slackMaxTextLength {
+		return reject(http.StatusBadRequest, "msg_too_long")
+	}
+	if len(msg.Attachments) > slackMaxAttachments {
+		return reject(http.StatusBadRequest, "too_many_attachments")
+	}
+	for _, a := range msg.Attachments {
+		if a.Text == "" && a.Fallback == "" && a.Pretext == "" && a.Title == "" && len(a.Fields) == 0 {
+			return reject(http.StatusBadRequest, "invalid_attachments")
+		}
+		switch a.Color {
+		case "", "good", "warning", "danger":
+		default:
+			if !hexColor.MatchString(a.Color) {
+				return reject(http.StatusBadRequest, "invalid_attachments")
+			}
+		}
+		if utf8.RuneCountInString(a.Text) > slackMaxTextLength {
+			return reject(http.StatusBadRequest, "msg_too_long")
+		}
+		for _, m := range a.MrkdwnIn {
+			switch m {
+			case "pretext", "text", "fields":
+			default:
+				return reject(http.StatusBadRequest, "invalid_attachments")
+			}
+		}
+	}
+	return nil
+}
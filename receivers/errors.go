@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receivers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// FailureReason is the category of a verification failure, it's sent
+// to the webhook sender along with the failure message.
+type FailureReason string
+
+const (
+	// MissingSignature is the failure of the requests without a signature or a token.
+	MissingSignature FailureReason = "MissingSignature"
+
+	// InvalidSignature is the failure of the requests with a signature
+	// or a token that doesn't match the receiver token.
+	InvalidSignature FailureReason = "InvalidSignature"
+
+	// EventNotAllowed is the failure of the authentic requests
+	// with an event not in the receiver events.
+	EventNotAllowed FailureReason = "EventNotAllowed"
+
+	// InvalidPayload is the failure of the requests with a payload
+	// that can't be decoded as the sender schema.
+	InvalidPayload FailureReason = "InvalidPayload"
+
+	// SourceNotAllowed is the failure of the requests sent from
+	// an address not in the receiver IP blocks.
+	SourceNotAllowed FailureReason = "SourceNotAllowed"
+
+	// InvalidCertificate is the failure of the requests without a client certificate,
+	// or with one not signed by the receiver CA or not matching the receiver subjects.
+	InvalidCertificate FailureReason = "InvalidCertificate"
+
+	// ReplayedRequest is the failure of the authentic requests with a signed
+	// timestamp outside the replay window, or replaying a handled request.
+	ReplayedRequest FailureReason = "ReplayedRequest"
+
+	// VerificationFailed is the failure of the requests that couldn't be verified
+	// for another reason, e.g. the receiver token couldn't be read.
+	VerificationFailed FailureReason = "VerificationFailed"
+)
+
+// StatusCode returns the HTTP status code of the failure reason.
+func (r FailureReason) StatusCode() int {
+	switch r {
+	case MissingSignature:
+		return http.StatusBadRequest
+	case InvalidSignature, InvalidCertificate:
+		return http.StatusUnauthorized
+	case EventNotAllowed, SourceNotAllowed:
+		return http.StatusForbidden
+	case InvalidPayload:
+		return http.StatusUnprocessableEntity
+	case ReplayedRequest:
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// VerificationError is a verification failure with its category.
+type VerificationError struct {
+	Reason FailureReason
+	Err    error
+}
+
+// Errorf returns a verification error formatted according to the format specifier.
+func Errorf(reason FailureReason, format string, args ...interface{}) error {
+	return &VerificationError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the category of the verification failure,
+// VerificationFailed if the error isn't a VerificationError.
+func Reason(err error) FailureReason {
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return verr.Reason
+	}
+	return VerificationFailed
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package receivers holds the registry of the receiver types, a receiver type
+// verifies the authenticity of the webhook requests and filters their events.
+//
+// The built-in types are registered by the receiver server, the downstream
+// builds can register their own types from an init function:
+//
+//	func init() {
+//		receivers.Register("my-sender", receivers.VerifierFunc(verifyMySender))
+//	}
+package receivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/cel"
+)
+
+// ErrEventFiltered is returned, wrapped, by the verifiers when an authentic
+// request holds an event not handled by the receiver, the request is
+// acknowledged without triggering the reconciliation of the resources.
+var ErrEventFiltered = errors.New("event filtered out")
+
+// Request is a webhook request sent to a receiver, the body is buffered
+// by the receiver server, it can be read once by the verifier.
+type Request struct {
+	*http.Request
+
+	// Receiver is the receiver the request is sent to.
+	Receiver v1beta1.Receiver
+
+	// Token is the token of the receiver secret.
+	Token string
+
+	// Logger logs with the receiver name and namespace.
+	Logger logr.Logger
+
+	// KubeClient is the client of the controller, e.g. to review
+	// the service account tokens of the in-cluster callers.
+	KubeClient client.Client
+
+	// Programs caches the CEL programs compiled for the filter conditions,
+	// the conditions are compiled without caching when it's nil.
+	Programs *cel.Cache
+
+	// Result is filled by the verifier, it can be nil.
+	Result *Result
+}
+
+// Result holds the details of the request found by the verifier.
+type Result struct {
+	// Event is the type of the event, e.g. 'push'.
+	Event string
+
+	// Tag is the tag of the pushed image, set by the container registry types.
+	Tag string
+
+	// Digest is the digest of the pushed image, set by the container registry types.
+	Digest string
+
+	// Resources restricts the triggered resources to the ones named, as 'name'
+	// or 'kind/name', e.g. by a chat command, all are triggered when empty.
+	Resources []string
+
+	// SignedAt is the signed timestamp of the request, zero if it has none.
+	SignedAt time.Time
+
+	// Nonce identifies the signed request, e.g. its signature, the requests
+	// with the nonce of a handled request are replays.
+	Nonce string
+}
+
+// Selects returns true if the resource is triggered by the request.
+func (r Result) Selects(resource v1beta1.CrossNamespaceObjectReference) bool {
+	if len(r.Resources) == 0 {
+		return true
+	}
+	for _, name := range r.Resources {
+		if ResourceNamed(resource, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceNamed returns true if the resource has the name, given as 'name'
+// or 'kind/name', the kind is compared case insensitively.
+func ResourceNamed(resource v1beta1.CrossNamespaceObjectReference, name string) bool {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return strings.EqualFold(resource.Kind, name[:i]) && resource.Name == name[i+1:]
+	}
+	return resource.Name == name
+}
+
+// SetImage records the tag and the digest of the pushed image, either can be empty.
+func (r Request) SetImage(tag, digest string) {
+	if r.Result != nil {
+		r.Result.Tag = tag
+		r.Result.Digest = digest
+	}
+}
+
+// SetEvent records the type of the event, e.g. 'push', in the request result.
+func (r Request) SetEvent(event string) {
+	if r.Result != nil {
+		r.Result.Event = event
+	}
+}
+
+// SetReplayGuard records the signed timestamp of the request, zero if it has
+// none, and the nonce identifying it, e.g. its signature, so that the server
+// can reject the stale requests and the replays when replay protection is on.
+func (r Request) SetReplayGuard(signedAt time.Time, nonce string) {
+	if r.Result != nil {
+		r.Result.SignedAt = signedAt
+		r.Result.Nonce = nonce
+	}
+}
+
+// SetResources restricts the resources triggered by the request.
+func (r Request) SetResources(names []string) {
+	if r.Result != nil {
+		r.Result.Resources = names
+	}
+}
+
+// Verifier verifies the webhook requests of a receiver type.
+type Verifier interface {
+	// Verify returns an error if the request is not authentic, or
+	// a wrapped ErrEventFiltered if the event is not handled.
+	Verify(ctx context.Context, r Request) error
+}
+
+// VerifierFunc is a function implementing the Verifier interface.
+type VerifierFunc func(ctx context.Context, r Request) error
+
+// Verify calls f(ctx, r).
+func (f VerifierFunc) Verify(ctx context.Context, r Request) error {
+	return f(ctx, r)
+}
+
+// PayloadUnwrapper is implemented by the verifiers of the senders wrapping
+// the events in an envelope, e.g. an SNS or Pub/Sub message, the annotation
+// expressions are evaluated over the unwrapped event.
+type PayloadUnwrapper interface {
+	Unwrap(payload []byte) []byte
+}
+
+// Responder is implemented by the verifiers of the senders expecting a body
+// in the response, e.g. the chat commands, it writes the response of the
+// requests handled successfully with the resources that were triggered.
+type Responder interface {
+	Respond(w http.ResponseWriter, triggered []v1beta1.CrossNamespaceObjectReference)
+}
+
+// Acknowledger is implemented by the verifiers of the senders expecting the
+// outcome of a delivery to be posted back, e.g. to the DockerHub callback URL,
+// it's called once the resources of the receiver are annotated.
+type Acknowledger interface {
+	Acknowledge(ctx context.Context, receiver v1beta1.Receiver, payload []byte, failed bool) error
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Verifier)
+)
+
+// Register adds a receiver type, it panics if the type is already registered.
+func Register(receiverType string, verifier Verifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if receiverType == "" || verifier == nil {
+		panic("receivers: Register called with an empty type or a nil verifier")
+	}
+	if _, ok := registry[receiverType]; ok {
+		panic(fmt.Sprintf("receivers: Register called twice for the type '%s'", receiverType))
+	}
+	registry[receiverType] = verifier
+}
+
+// Lookup returns the verifier of the receiver type.
+func Lookup(receiverType string) (Verifier, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	verifier, ok := registry[receiverType]
+	return verifier, ok
+}
+
+// Types returns the registered receiver types, sorted.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate returns an error if the receiver type is not registered.
+func Validate(receiverType string) error {
+	if _, ok := Lookup(receiverType); !ok {
+		return fmt.Errorf("the receiver type '%s' is not supported, the supported types are: %s",
+			receiverType, strings.Join(Types(), ", "))
+	}
+	return nil
+}
+
+// EventAllowed returns true if the receiver handles all events,
+// or if the event is in its list, case insensitively.
+func EventAllowed(receiver v1beta1.Receiver, event string) bool {
+	if len(receiver.Spec.Events) == 0 {
+		return true
+	}
+	for _, e := range receiver.Spec.Events {
+		if strings.EqualFold(event, e) {
+			return true
+		}
+	}
+	return false
+}
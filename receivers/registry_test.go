@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receivers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+func TestRegister(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	verifier := VerifierFunc(func(ctx context.Context, r Request) error {
+		return nil
+	})
+	Register("test-sender", verifier)
+	defer func() {
+		mu.Lock()
+		delete(registry, "test-sender")
+		mu.Unlock()
+	}()
+
+	_, ok := Lookup("test-sender")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(Types()).To(gomega.ContainElement("test-sender"))
+	g.Expect(Validate("test-sender")).To(gomega.Succeed())
+	g.Expect(Validate("unknown-sender")).To(gomega.MatchError(gomega.ContainSubstring("test-sender")))
+
+	g.Expect(func() { Register("test-sender", verifier) }).To(gomega.Panic())
+	g.Expect(func() { Register("", verifier) }).To(gomega.Panic())
+}
+
+func TestEventAllowed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	receiver := v1beta1.Receiver{}
+	g.Expect(EventAllowed(receiver, "push")).To(gomega.BeTrue())
+
+	receiver.Spec.Events = []string{"Push Hook"}
+	g.Expect(EventAllowed(receiver, "push hook")).To(gomega.BeTrue())
+	g.Expect(EventAllowed(receiver, "Tag Push Hook")).To(gomega.BeFalse())
+}
+
+func TestResult_Selects(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	resource := v1beta1.CrossNamespaceObjectReference{Kind: "GitRepository", Name: "webapp"}
+	g.Expect(Result{}.Selects(resource)).To(gomega.BeTrue())
+	g.Expect(Result{Resources: []string{"webapp"}}.Selects(resource)).To(gomega.BeTrue())
+	g.Expect(Result{Resources: []string{"gitrepository/webapp"}}.Selects(resource)).To(gomega.BeTrue())
+	g.Expect(Result{Resources: []string{"Kustomization/webapp", "backend"}}.Selects(resource)).To(gomega.BeFalse())
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package receivertest provides signed webhook fixtures for the receiver
+// types and a harness driving the receiver server with them, so that the
+// receiver types, including the ones added by forks, can be verified
+// against the same contract.
+package receivertest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+)
+
+// Fixture is a canned webhook request of a receiver type.
+type Fixture struct {
+	// Type is the receiver type handling the request.
+	Type string
+
+	// Event is the event type sent in the request headers or payload,
+	// empty for the senders without event types.
+	Event string
+
+	// Authenticated is true if the receiver type rejects
+	// the requests signed with another token.
+	Authenticated bool
+
+	// Header and Body are the request headers and payload.
+	Header http.Header
+	Body   []byte
+
+	// Sign authenticates the request with the receiver token, if not nil.
+	Sign func(r *http.Request, body []byte, token string) error
+}
+
+// Request returns the webhook request sent to the target, e.g. the
+// receiver URL '/hook/<digest>', signed with the token.
+func (f Fixture) Request(target, token string) (*http.Request, error) {
+	r := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(f.Body))
+	for k, values := range f.Header {
+		for _, v := range values {
+			r.Header.Add(k, v)
+		}
+	}
+	if f.Sign != nil {
+		if err := f.Sign(r, f.Body, token); err != nil {
+			return nil, fmt.Errorf("unable to sign the %s request: %w", f.Type, err)
+		}
+	}
+	return r, nil
+}
+
+// Fixtures returns a fixture for each receiver type, except for 'gcr', 'gar'
+// and 'ecr' whose requests are authenticated by Google and AWS.
+func Fixtures() []Fixture {
+	return []Fixture{
+		{
+			Type:   v1beta1.GenericReceiver,
+			Header: jsonHeader(),
+			Body:   []byte(`{}`),
+		},
+		{
+			Type:          v1beta1.GenericHMACReceiver,
+			Authenticated: true,
+			Header:        jsonHeader(),
+			Body:          []byte(`{"app":"webapp"}`),
+			Sign:          hmacHeader("X-Signature", sha256.New, "sha256="),
+		},
+		{
+			Type:          v1beta1.GitHubReceiver,
+			Event:         "push",
+			Authenticated: true,
+			Header:        eventHeader("X-GitHub-Event", "push"),
+			Body:          mustJSON(githubPush),
+			Sign:          hmacHeader("X-Hub-Signature", sha256.New, "sha256="),
+		},
+		{
+			Type:          v1beta1.GitLabReceiver,
+			Event:         "Push Hook",
+			Authenticated: true,
+			Header:        eventHeader("X-Gitlab-Event", "Push Hook"),
+			Body:          mustJSON(gitlabPush),
+			Sign: func(r *http.Request, _ []byte, token string) error {
+				r.Header.Set("X-Gitlab-Token", token)
+				return nil
+			},
+		},
+		{
+			Type:          v1beta1.GitLabSystemReceiver,
+			Event:         "push",
+			Authenticated: true,
+			Header:        eventHeader("X-Gitlab-Event", "System Hook"),
+			Body:          mustJSON(gitlabPush),
+			Sign: func(r *http.Request, _ []byte, token string) error {
+				r.Header.Set("X-Gitlab-Token", token)
+				return nil
+			},
+		},
+		{
+			Type:          v1beta1.GiteaReceiver,
+			Event:         "push",
+			Authenticated: true,
+			Header:        eventHeader("X-Gitea-Event", "push"),
+			Body:          mustJSON(githubPush),
+			Sign:          hmacHeader("X-Gitea-Signature", sha256.New, ""),
+		},
+		{
+			Type:          v1beta1.BitbucketReceiver,
+			Event:         "repo:refs_changed",
+			Authenticated: true,
+			Header:        eventHeader("X-Event-Key", "repo:refs_changed"),
+			Body:          []byte(`{"eventKey":"repo:refs_changed","repository":{"slug":"webapp"}}`),
+			Sign:          hmacHeader("X-Hub-Signature", sha256.New, "sha256="),
+		},
+		{
+			Type:          v1beta1.BitbucketServerReceiver,
+			Event:         "pr:merged",
+			Authenticated: true,
+			Header: http.Header{
+				"Content-Type": []string{"application/json; charset=utf-8"},
+				"X-Event-Key":  []string{"pr:merged"},
+			},
+			Body: []byte(`{"eventKey":"pr:merged","pullRequest":{"toRef":{"displayId":"main"}}}`),
+			Sign: hmacHeader("X-Hub-Signature", sha256.New, "sha256="),
+		},
+		{
+			Type:          v1beta1.BitbucketCloudReceiver,
+			Event:         "repo:push",
+			Authenticated: true,
+			Header:        eventHeader("X-Event-Key", "repo:push"),
+			Body:          []byte(`{"push":{"changes":[]},"repository":{"full_name":"org/webapp"}}`),
+			Sign:          signConnectJWT,
+		},
+		{
+			Type:          v1beta1.HarborReceiver,
+			Authenticated: true,
+			Header:        jsonHeader(),
+			Body:          []byte(`{"type":"PUSH_ARTIFACT","event_data":{"repository":{"repo_full_name":"library/webapp"}}}`),
+			Sign: func(r *http.Request, _ []byte, token string) error {
+				r.Header.Set("Authorization", token)
+				return nil
+			},
+		},
+		{
+			Type:   v1beta1.DockerHubReceiver,
+			Header: jsonHeader(),
+			Body:   []byte(`{"push_data":{"tag":"1.0.0"},"repository":{"repo_url":"https://hub.docker.com/r/org/webapp"}}`),
+		},
+		{
+			Type:   v1beta1.QuayReceiver,
+			Event:  "repo_push",
+			Header: jsonHeader(),
+			Body:   []byte(`{"docker_url":"quay.io/org/webapp","updated_tags":["1.0.0"]}`),
+		},
+		{
+			Type:          v1beta1.NexusReceiver,
+			Event:         "rm:repository:component",
+			Authenticated: true,
+			Header:        eventHeader("X-Nexus-Webhook-Id", "rm:repository:component"),
+			Body:          []byte(`{"action":"CREATED","repositoryName":"docker-hosted","component":{"format":"docker","name":"webapp","version":"1.0.0"}}`),
+			Sign:          hmacHeader("X-Nexus-Webhook-Signature", sha1.New, ""),
+		},
+		{
+			Type:          v1beta1.JenkinsReceiver,
+			Event:         "COMPLETED",
+			Authenticated: true,
+			Header:        jsonHeader(),
+			Body:          []byte(`{"name":"apps/webapp","build":{"number":42,"phase":"COMPLETED","status":"SUCCESS"}}`),
+			Sign: func(r *http.Request, _ []byte, token string) error {
+				r.Header.Set("X-Jenkins-Token", token)
+				return nil
+			},
+		},
+		{
+			Type:          v1beta1.DroneReceiver,
+			Event:         "build",
+			Authenticated: true,
+			Header:        eventHeader("X-Drone-Event", "build"),
+			Body:          []byte(`{"event":"build","action":"updated","repo":{"slug":"org/webapp"},"build":{"number":42,"status":"success"}}`),
+			Sign:          signHTTPSignature,
+		},
+		{
+			Type:          v1beta1.SlackReceiver,
+			Event:         "reconcile",
+			Authenticated: true,
+			Header:        http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			Body:          []byte("command=%2Fflux&text=reconcile&user_name=jane&team_domain=example"),
+			Sign:          signSlack,
+		},
+		{
+			Type:          v1beta1.DependencyBotReceiver,
+			Event:         "merged",
+			Authenticated: true,
+			Header:        eventHeader("X-GitHub-Event", "pull_request"),
+			Body:          mustJSON(dependabotMerge),
+			Sign:          hmacHeader("X-Hub-Signature", sha256.New, "sha256="),
+		},
+		{
+			Type:   v1beta1.ACRReceiver,
+			Header: jsonHeader(),
+			Body:   []byte(`{"action":"push","target":{"repository":"webapp","tag":"1.0.0"}}`),
+		},
+	}
+}
+
+var githubPush = map[string]interface{}{
+	"ref":    "refs/heads/main",
+	"after":  "731f7eaddfb6af01cb2173e18f0f75b0ba780ef1",
+	"before": "0000000000000000000000000000000000000000",
+	"repository": map[string]interface{}{
+		"name":      "webapp",
+		"full_name": "org/webapp",
+	},
+	"commits": []interface{}{},
+}
+
+var dependabotMerge = map[string]interface{}{
+	"action": "closed",
+	"pull_request": map[string]interface{}{
+		"merged": true,
+		"user":   map[string]interface{}{"login": "dependabot[bot]"},
+		"head":   map[string]interface{}{"ref": "dependabot/npm_and_yarn/lodash-4.17.21"},
+	},
+	"repository": githubPush["repository"],
+}
+
+var gitlabPush = map[string]interface{}{
+	"object_kind": "push",
+	"ref":         "refs/heads/main",
+	"project": map[string]interface{}{
+		"path_with_namespace": "org/webapp",
+	},
+	"commits": []interface{}{},
+}
+
+func mustJSON(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func jsonHeader() http.Header {
+	return http.Header{"Content-Type": []string{"application/json"}}
+}
+
+func eventHeader(key, event string) http.Header {
+	h := jsonHeader()
+	h.Set(key, event)
+	return h
+}
+
+// hmacHeader signs the body with the token and sets
+// the hex encoded signature, with the prefix, in the header.
+func hmacHeader(header string, h func() hash.Hash, prefix string) func(*http.Request, []byte, string) error {
+	return func(r *http.Request, body []byte, token string) error {
+		mac := hmac.New(h, []byte(token))
+		_, _ = mac.Write(body)
+		r.Header.Set(header, prefix+hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	}
+}
+
+// signConnectJWT sets the Atlassian Connect JWT sent by Bitbucket Cloud,
+// the query string hash covers the method and the path of the request.
+func signConnectJWT(r *http.Request, _ []byte, token string) error {
+	if r.URL.RawQuery != "" {
+		return fmt.Errorf("the fixture requests have no query string")
+	}
+	qsh := sha256.Sum256([]byte(strings.ToUpper(r.Method) + "&" + r.URL.EscapedPath() + "&"))
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString(mustJSON(map[string]interface{}{
+		"iss": "receivertest",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+		"qsh": hex.EncodeToString(qsh[:]),
+	}))
+	mac := hmac.New(sha256.New, []byte(token))
+	_, _ = mac.Write([]byte(header + "." + claims))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	r.Header.Set("Authorization", "JWT "+header+"."+claims+"."+signature)
+	return nil
+}
+
+// signHTTPSignature sets the HTTP signature sent by Drone,
+// the signature covers the date and the digest of the body.
+func signHTTPSignature(r *http.Request, body []byte, token string) error {
+	digest := sha256.Sum256(body)
+	r.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	mac := hmac.New(sha256.New, []byte(token))
+	_, _ = mac.Write([]byte("date: " + r.Header.Get("Date") + "\ndigest: " + r.Header.Get("Digest")))
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="hmac-key",algorithm="hmac-sha256",signature="%s",headers="date digest"`,
+		base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+	return nil
+}
+
+// signSlack sets the Slack request signature, the signature
+// covers the version, the request timestamp and the body.
+func signSlack(r *http.Request, body []byte, token string) error {
+	ts := fmt.Sprintf("%d", time.Now().Unix())
+	mac := hmac.New(sha256.New, []byte(token))
+	_, _ = mac.Write([]byte("v0:" + ts + ":" + string(body)))
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receivertest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	"github.com/fluxcd/notification-controller/api/v1beta1"
+	"github.com/fluxcd/notification-controller/internal/server"
+	"github.com/fluxcd/notification-controller/receivers"
+)
+
+const (
+	// Namespace is the namespace of the receivers created by the harness.
+	Namespace = "receivertest"
+
+	// ResourceName is the name of the GitRepository annotated by the receivers.
+	ResourceName = "webapp"
+)
+
+// Harness drives the webhook handler of the receiver server,
+// backed by a fake Kubernetes client.
+type Harness struct {
+	Client  client.Client
+	handler http.Handler
+}
+
+// NewHarness returns a harness whose client holds the objects, and the
+// GitRepository annotated by the receivers, cloning 'org/webapp'.
+func NewHarness(objects ...client.Object) (*Harness, error) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("source.toolkit.fluxcd.io/v1beta1")
+	resource.SetKind("GitRepository")
+	resource.SetName(ResourceName)
+	resource.SetNamespace(Namespace)
+	if err := unstructured.SetNestedField(resource.Object, "https://github.com/org/webapp", "spec", "url"); err != nil {
+		return nil, err
+	}
+
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(objects, resource)...).
+		Build()
+	s := server.NewReceiverServer("", logf.Log, kubeClient, nil, 0)
+	return &Harness{
+		Client:  kubeClient,
+		handler: s.Handler(),
+	}, nil
+}
+
+// Receiver creates a ready receiver of the type handling the events, with
+// the secret holding its token, the receiver annotates the GitRepository.
+func (h *Harness) Receiver(ctx context.Context, name, receiverType string, events []string, token string) (*v1beta1.Receiver, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-token", Namespace: Namespace},
+		Data:       map[string][]byte{"token": []byte(token)},
+	}
+	if err := h.Client.Create(ctx, secret); err != nil {
+		return nil, fmt.Errorf("unable to create the token secret: %w", err)
+	}
+
+	receiver := &v1beta1.Receiver{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: Namespace},
+		Spec: v1beta1.ReceiverSpec{
+			Type:      receiverType,
+			Events:    events,
+			SecretRef: meta.LocalObjectReference{Name: secret.Name},
+			Resources: []v1beta1.CrossNamespaceObjectReference{{
+				APIVersion: "source.toolkit.fluxcd.io/v1beta1",
+				Kind:       "GitRepository",
+				Name:       ResourceName,
+			}},
+		},
+	}
+	url := fmt.Sprintf("/hook/%x", sha256.Sum256([]byte(token+name+Namespace)))
+	*receiver = v1beta1.ReceiverReady(*receiver, v1beta1.InitializedReason, "Receiver initialised with URL: "+url, url)
+	if err := h.Client.Create(ctx, receiver); err != nil {
+		return nil, fmt.Errorf("unable to create the receiver: %w", err)
+	}
+	return receiver, nil
+}
+
+// Serve sends the fixture, signed with the token, to the receiver.
+func (h *Harness) Serve(receiver *v1beta1.Receiver, f Fixture, token string) (*httptest.ResponseRecorder, error) {
+	r, err := f.Request(receiver.Status.URL, token)
+	if err != nil {
+		return nil, err
+	}
+	w := httptest.NewRecorder()
+	h.handler.ServeHTTP(w, r)
+	return w, nil
+}
+
+// Triggered returns true if the GitRepository was annotated since the
+// previous call, the reconcile request annotation is removed.
+func (h *Harness) Triggered(ctx context.Context) (bool, error) {
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("source.toolkit.fluxcd.io/v1beta1")
+	resource.SetKind("GitRepository")
+	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: Namespace, Name: ResourceName}, resource); err != nil {
+		return false, err
+	}
+
+	annotations := resource.GetAnnotations()
+	if _, ok := annotations[meta.ReconcileRequestAnnotation]; !ok {
+		return false, nil
+	}
+	delete(annotations, meta.ReconcileRequestAnnotation)
+	resource.SetAnnotations(annotations)
+	return true, h.Client.Update(ctx, resource)
+}
+
+// Verify checks the receiver contract with the fixture:
+//   - the request signed with the receiver token triggers the reconciliation
+//   - the request signed with another token is rejected with an InvalidSignature failure,
+//     for the authenticated types
+//   - the request of an event the receiver doesn't handle is rejected with
+//     an EventNotAllowed failure
+func Verify(t testing.TB, f Fixture) {
+	t.Helper()
+	ctx := context.Background()
+
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expect serves the request, the failure reason is empty for the accepted requests
+	expect := func(receiver *v1beta1.Receiver, token string, failure receivers.FailureReason, triggered bool, msg string) {
+		t.Helper()
+		w, err := h.Serve(receiver, f, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := http.StatusOK
+		if failure != "" {
+			code = failure.StatusCode()
+			var body struct {
+				Reason receivers.FailureReason `json:"reason"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Reason != failure {
+				t.Errorf("%s: %s request got the failure '%s', expected '%s'", f.Type, msg, w.Body.String(), failure)
+			}
+		}
+		if w.Code != code {
+			t.Errorf("%s: %s request got status %d, expected %d", f.Type, msg, w.Code, code)
+		}
+		ok, err := h.Triggered(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != triggered {
+			t.Errorf("%s: %s request triggered the reconciliation: %v, expected %v", f.Type, msg, ok, triggered)
+		}
+	}
+
+	var events []string
+	if f.Event != "" {
+		events = []string{f.Event}
+	}
+	receiver, err := h.Receiver(ctx, "valid", f.Type, events, "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(receiver, "s3cr3t", "", true, "valid")
+
+	if f.Authenticated {
+		// the receiver URL is known but the signature was computed with another token
+		expect(receiver, "wrong-token", receivers.InvalidSignature, false, "forged")
+	}
+
+	if f.Event != "" {
+		receiver, err := h.Receiver(ctx, "filtered", f.Type, []string{"unhandled"}, "s3cr3t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(receiver, "s3cr3t", receivers.EventNotAllowed, false, "unhandled event")
+	}
+}